@@ -0,0 +1,139 @@
+package devstats
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Cross-project aggregation merge operations, how values of the same series from many projects' databases are combined
+const (
+	MergeSum = "sum" // values are added, default
+	MergeMax = "max" // the highest value is taken
+	MergeMin = "min" // the lowest value is taken
+)
+
+// CheckMergeOp returns error when `op` is not a known merge operation
+func CheckMergeOp(op string) error {
+	switch op {
+	case MergeSum, MergeMax, MergeMin:
+		return nil
+	}
+	return fmt.Errorf("unknown merge operation '%s', use: sum, max or min", op)
+}
+
+// AggregateDBs returns sorted Postgres databases of all enabled projects, `exclude` (aggregate project's own database) is skipped
+// Projects sharing a database are queried once
+func AggregateDBs(projects *AllProjects, exclude string) (dbs []string) {
+	seen := make(map[string]struct{})
+	for _, proj := range projects.Projects {
+		if proj.Disabled || proj.PDB == "" || proj.PDB == exclude {
+			continue
+		}
+		if _, ok := seen[proj.PDB]; ok {
+			continue
+		}
+		seen[proj.PDB] = struct{}{}
+		dbs = append(dbs, proj.PDB)
+	}
+	sort.Strings(dbs)
+	return
+}
+
+// RowsMerger merges results of the same metric query executed in many databases, it is safe for concurrent use
+// Single column rows are merged into one value, other rows by their first column (series name), NULL values are skipped
+type RowsMerger struct {
+	op      string
+	columns int
+	values  map[string][]*float64
+	mtx     sync.Mutex
+}
+
+// NewRowsMerger returns merger combining values with merge operation `op`
+func NewRowsMerger(op string) (*RowsMerger, error) {
+	if err := CheckMergeOp(op); err != nil {
+		return nil, err
+	}
+	return &RowsMerger{op: op, values: make(map[string][]*float64)}, nil
+}
+
+// Add merges `row` into result, all rows must have the same number of columns and numeric values
+func (m *RowsMerger) Add(row []*string) error {
+	if len(row) == 0 {
+		return fmt.Errorf("empty row")
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.columns == 0 {
+		m.columns = len(row)
+	} else if m.columns != len(row) {
+		return fmt.Errorf("row has %d columns, previous rows have %d", len(row), m.columns)
+	}
+	key, values := "", row
+	if len(row) > 1 {
+		if row[0] != nil {
+			key = *row[0]
+		}
+		values = row[1:]
+	}
+	merged, ok := m.values[key]
+	if !ok {
+		merged = make([]*float64, len(values))
+		m.values[key] = merged
+	}
+	for i, pValue := range values {
+		if pValue == nil {
+			continue
+		}
+		value, err := strconv.ParseFloat(*pValue, 64)
+		if err != nil {
+			return fmt.Errorf("non-numeric value '%s' of '%s'", *pValue, key)
+		}
+		switch {
+		case merged[i] == nil:
+			merged[i] = &value
+		case m.op == MergeSum:
+			*merged[i] += value
+		case m.op == MergeMax && value > *merged[i], m.op == MergeMin && value < *merged[i]:
+			*merged[i] = value
+		}
+	}
+	return nil
+}
+
+// Columns returns number of columns of merged rows, 0 when nothing was added
+func (m *RowsMerger) Columns() int {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.columns
+}
+
+// Rows returns merged rows sorted by series name, values that were NULL everywhere are nil
+func (m *RowsMerger) Rows() [][]*string {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	keys := []string{}
+	for key := range m.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	rows := [][]*string{}
+	for _, key := range keys {
+		row := []*string{}
+		if m.columns > 1 {
+			name := key
+			row = append(row, &name)
+		}
+		for _, pValue := range m.values[key] {
+			if pValue == nil {
+				row = append(row, nil)
+				continue
+			}
+			value := strconv.FormatFloat(*pValue, 'f', -1, 64)
+			row = append(row, &value)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
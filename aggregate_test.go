@@ -0,0 +1,97 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestAggregateDBs(t *testing.T) {
+	projects := lib.AllProjects{
+		Projects: map[string]lib.Project{
+			"kubernetes":  {PDB: "gha"},
+			"prometheus":  {PDB: "prometheus"},
+			"opentracing": {PDB: "opentracing", Disabled: true},
+			"fluentd":     {PDB: "fluentd"},
+			"fluentbit":   {PDB: "fluentd"},
+			"cncf":        {PDB: "cncf"},
+			"nodb":        {},
+		},
+	}
+	expected := []string{"fluentd", "gha", "prometheus"}
+	got := lib.AggregateDBs(&projects, "cncf")
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestRowsMerger(t *testing.T) {
+	s := func(values ...string) (row []*string) {
+		for _, value := range values {
+			if value == "null" {
+				row = append(row, nil)
+				continue
+			}
+			v := value
+			row = append(row, &v)
+		}
+		return
+	}
+
+	// Test cases
+	var testCases = []struct {
+		op       string
+		rows     [][]*string
+		expected [][]*string
+		err      bool
+	}{
+		{op: lib.MergeSum, rows: nil, expected: [][]*string{}},
+		{op: lib.MergeSum, rows: [][]*string{s("1"), s("2.5"), s("null")}, expected: [][]*string{s("3.5")}},
+		{op: lib.MergeMax, rows: [][]*string{s("1"), s("7"), s("3")}, expected: [][]*string{s("7")}},
+		{op: lib.MergeMin, rows: [][]*string{s("null"), s("7"), s("3")}, expected: [][]*string{s("3")}},
+		{op: lib.MergeSum, rows: [][]*string{s("null")}, expected: [][]*string{s("null")}},
+		{
+			op: lib.MergeSum,
+			rows: [][]*string{
+				s("prs,b", "1", "2"),
+				s("prs,a", "3", "null"),
+				s("prs,b", "10", "20"),
+				s("prs,a", "null", "null"),
+			},
+			expected: [][]*string{s("prs,a", "3", "null"), s("prs,b", "11", "22")},
+		},
+		{
+			op:       lib.MergeMax,
+			rows:     [][]*string{s("x", "1"), s("x", "-1"), s("y", "2")},
+			expected: [][]*string{s("x", "1"), s("y", "2")},
+		},
+		{op: lib.MergeSum, rows: [][]*string{s("x", "1"), s("1")}, err: true},
+		{op: lib.MergeSum, rows: [][]*string{s("x", "abc")}, err: true},
+		{op: "avg", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		merger, err := lib.NewRowsMerger(test.op)
+		for _, row := range test.rows {
+			if err != nil {
+				break
+			}
+			err = merger.Add(row)
+		}
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got none", index+1)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		got := merger.Rows()
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
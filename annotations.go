@@ -1,16 +1,13 @@
 package devstats
 
 import (
-	"context"
 	"fmt"
-	"io/ioutil"
 	"regexp"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/github"
-	"golang.org/x/oauth2"
 )
 
 // Annotations contain list of annotations
@@ -55,26 +52,8 @@ func GetAnnotations(ctx *Ctx, orgRepo, annoRegexp string) (annotations Annotatio
 		re = regexp.MustCompile(annoRegexp)
 	}
 
-	// Get GitHub OAuth from env or from file
-	oAuth := ctx.GitHubOAuth
-	if strings.Contains(ctx.GitHubOAuth, "/") {
-		bytes, err := ioutil.ReadFile(ctx.GitHubOAuth)
-		FatalOnError(err)
-		oAuth = strings.TrimSpace(string(bytes))
-	}
-
-	// GitHub authentication or use public access
-	ghCtx := context.Background()
-	var client *github.Client
-	if oAuth == "-" {
-		client = github.NewClient(nil)
-	} else {
-		ts := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: oAuth},
-		)
-		tc := oauth2.NewClient(ghCtx, ts)
-		client = github.NewClient(tc)
-	}
+	// Get GitHub API client
+	ghCtx, client := GHClient(ctx)
 
 	// Get Tags list
 	opt := &github.ListOptions{PerPage: 1000}
@@ -163,7 +142,7 @@ func ProcessAnnotations(ctx *Ctx, annotations *Annotations, joinDate *time.Time)
 	// Join CNCF (additional annotation not used in quick ranges)
 	if joinDate != nil {
 		fields := map[string]interface{}{
-			"title":       "CNCF join Date",
+			"title":       JoinDateTitle,
 			"description": ToYMDDate(*joinDate) + " - joined CNCF",
 		}
 		// Add batch point
@@ -230,7 +209,7 @@ func ProcessAnnotations(ctx *Ctx, annotations *Annotations, joinDate *time.Time)
 			sfx := fmt.Sprintf("anno_%d_now", index)
 			tags[tagName+"_suffix"] = sfx
 			tags[tagName+"_name"] = fmt.Sprintf("%s - now", annotation.Name)
-			tags[tagName+"_data"] = fmt.Sprintf("%s;;%s;%s", sfx, ToYMDHMSDate(annotation.Date), ToYMDHMSDate(NextDayStart(time.Now())))
+			tags[tagName+"_data"] = fmt.Sprintf("%s;;%s;%s", sfx, ToYMDHMSDate(annotation.Date), ToYMDHMSDate(InTimeZone(NextDayStart, ctx.Location())(time.Now())))
 			if ctx.Debug > 0 {
 				Printf(
 					"Series: %v: %+v\n",
@@ -0,0 +1,125 @@
+package devstats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnomaliesSeries - InfluxDB series with anomalies found in computed series, tagged by `series` and `field`
+// Points have "title" and "description" like "annotations", so Grafana can show them as annotations
+const AnomaliesSeries = "anomalies"
+
+// AnomalyWindow - default number of previous points a point is compared with
+const AnomalyWindow = 28
+
+// AnomalyMinHistory - points with fewer previous points are not checked, there is not enough history to tell
+const AnomalyMinHistory = 7
+
+// Anomaly detection methods
+const (
+	AnomalyZScore = "zscore" // value is more than Threshold standard deviations from the mean of previous points
+	AnomalyIQR    = "iqr"    // value is more than Threshold interquartile ranges below Q1 or above Q3 of previous points
+)
+
+// AnomalyDetector - compares points with `Window` previous points of the same series
+type AnomalyDetector struct {
+	Method    string
+	Threshold float64
+	Window    int
+}
+
+// Anomaly - point whose value is outside of range expected from previous points
+type Anomaly struct {
+	Time  time.Time
+	Field string
+	Value float64
+	Low   float64
+	High  float64
+}
+
+// ParseAnomalyDetector parses detector like "zscore3" or "iqr1.5", `window` 0 means AnomalyWindow
+func ParseAnomalyDetector(s string, window int) (AnomalyDetector, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	d := AnomalyDetector{Window: window}
+	if d.Window == 0 {
+		d.Window = AnomalyWindow
+	}
+	if d.Window < AnomalyMinHistory {
+		return d, fmt.Errorf("anomaly window must be at least %d points, got %d", AnomalyMinHistory, window)
+	}
+	for _, method := range []string{AnomalyZScore, AnomalyIQR} {
+		if !strings.HasPrefix(s, method) {
+			continue
+		}
+		threshold, err := strconv.ParseFloat(s[len(method):], 64)
+		if err != nil || threshold <= 0 || math.IsInf(threshold, 0) {
+			return d, fmt.Errorf("invalid anomaly detector '%s', threshold must be a positive number, like %s3", s, method)
+		}
+		d.Method = method
+		d.Threshold = threshold
+		return d, nil
+	}
+	return d, fmt.Errorf("invalid anomaly detector '%s', use zscoreN (N standard deviations) or iqrK (K interquartile ranges)", s)
+}
+
+// String returns detector as it is parsed, like "zscore3"
+func (d AnomalyDetector) String() string {
+	return d.Method + strconv.FormatFloat(d.Threshold, 'f', -1, 64)
+}
+
+// Range returns range of values expected after `history`, ok is false when history is shorter than AnomalyMinHistory
+func (d AnomalyDetector) Range(history []float64) (low, high float64, ok bool) {
+	n := len(history)
+	if n < AnomalyMinHistory {
+		return
+	}
+	if d.Method == AnomalyIQR {
+		sorted := append([]float64{}, history...)
+		sort.Float64s(sorted)
+		q1, q3 := Percentile(sorted, 25), Percentile(sorted, 75)
+		return q1 - d.Threshold*(q3-q1), q3 + d.Threshold*(q3-q1), true
+	}
+	mean := 0.0
+	for _, value := range history {
+		mean += value
+	}
+	mean /= float64(n)
+	variance := 0.0
+	for _, value := range history {
+		variance += (value - mean) * (value - mean)
+	}
+	std := math.Sqrt(variance / float64(n))
+	return mean - d.Threshold*std, mean + d.Threshold*std, true
+}
+
+// Detect returns anomalies of points at or after `from`, every field is compared with its previous Window values
+// Points before `from` are only history, `times` are ordered and `fields` values are aligned with them
+func (d AnomalyDetector) Detect(times []time.Time, fields map[string][]float64, from time.Time) (anomalies []Anomaly) {
+	columns := []string{}
+	for column := range fields {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for i, dt := range times {
+		if dt.Before(from) {
+			continue
+		}
+		start := i - d.Window
+		if start < 0 {
+			start = 0
+		}
+		for _, column := range columns {
+			values := fields[column]
+			low, high, ok := d.Range(values[start:i])
+			if !ok || (values[i] >= low && values[i] <= high) {
+				continue
+			}
+			anomalies = append(anomalies, Anomaly{Time: dt, Field: column, Value: values[i], Low: low, High: high})
+		}
+	}
+	return
+}
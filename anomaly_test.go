@@ -0,0 +1,102 @@
+package devstats
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestParseAnomalyDetector(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		s        string
+		window   int
+		expected lib.AnomalyDetector
+		str      string
+		err      bool
+	}{
+		{s: "zscore3", expected: lib.AnomalyDetector{Method: lib.AnomalyZScore, Threshold: 3, Window: lib.AnomalyWindow}, str: "zscore3"},
+		{s: " IQR1.5 ", window: 14, expected: lib.AnomalyDetector{Method: lib.AnomalyIQR, Threshold: 1.5, Window: 14}, str: "iqr1.5"},
+		{s: "zscore", err: true},
+		{s: "zscore0", err: true},
+		{s: "iqr-1", err: true},
+		{s: "mad3", err: true},
+		{s: "zscore3", window: 3, err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.ParseAnomalyDetector(test.s, test.window)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error for '%s', got %+v", index+1, test.s, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if got != test.expected || got.String() != test.str {
+			t.Errorf("test number %d, expected %+v (%s), got %+v (%s)", index+1, test.expected, test.str, got, got.String())
+		}
+	}
+}
+
+func TestAnomalyRange(t *testing.T) {
+	zscore := lib.AnomalyDetector{Method: lib.AnomalyZScore, Threshold: 2, Window: lib.AnomalyWindow}
+	iqr := lib.AnomalyDetector{Method: lib.AnomalyIQR, Threshold: 1.5, Window: lib.AnomalyWindow}
+
+	// Test cases
+	var testCases = []struct {
+		detector lib.AnomalyDetector
+		history  []float64
+		low      float64
+		high     float64
+		ok       bool
+	}{
+		{detector: zscore, history: []float64{1, 2, 3}},
+		{detector: zscore, history: []float64{2, 4, 4, 4, 5, 5, 7, 9}, low: 1, high: 9, ok: true},
+		{detector: zscore, history: []float64{5, 5, 5, 5, 5, 5, 5}, low: 5, high: 5, ok: true},
+		{detector: iqr, history: []float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, low: -3, high: 13, ok: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		low, high, ok := test.detector.Range(test.history)
+		if ok != test.ok || math.Abs(low-test.low) > 1e-9 || math.Abs(high-test.high) > 1e-9 {
+			t.Errorf("test number %d, expected (%v, %v, %v), got (%v, %v, %v)", index+1, test.low, test.high, test.ok, low, high, ok)
+		}
+	}
+}
+
+func TestAnomalyDetect(t *testing.T) {
+	ft := testlib.YMDHMS
+	detector := lib.AnomalyDetector{Method: lib.AnomalyZScore, Threshold: 3, Window: 7}
+	times := []time.Time{}
+	for day := 1; day <= 12; day++ {
+		times = append(times, ft(2018, 3, day))
+	}
+	fields := map[string][]float64{
+		"value":  {10, 11, 10, 9, 10, 11, 10, 9, 10, 0, 10, 11},
+		"merged": {5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5},
+	}
+	// Drop on March 10th is found, points before March 9th are only history
+	expected := []lib.Anomaly{{Time: ft(2018, 3, 10), Field: "value", Value: 0}}
+	got := detector.Detect(times, fields, ft(2018, 3, 9))
+	if len(got) != 1 {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+	got[0].Low, got[0].High = 0, 0
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	// Not enough history for the first points
+	got = detector.Detect(times[:7], map[string][]float64{"value": {1, 100, 1, 100, 1, 100, 1}}, ft(2018))
+	if len(got) != 0 {
+		t.Errorf("expected no anomalies, got %+v", got)
+	}
+}
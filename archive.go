@@ -0,0 +1,188 @@
+package devstats
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// GHA archive formats detected by ArchiveFormat
+const (
+	ArchiveGzip    = "gzip"
+	ArchiveZstd    = "zstd"
+	ArchivePlain   = "json"
+	ArchiveUnknown = ""
+)
+
+// ArchiveExtensions - GHA archive file name extensions, in the order local files are looked up
+var ArchiveExtensions = []string{".json.gz", ".json.zst", ".json"}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ArchiveFormat detects GHA archive format by its magic bytes (not by file name)
+// Plain archives are JSON lines, they start with "{" (leading white space is allowed)
+func ArchiveFormat(data []byte) string {
+	if bytes.HasPrefix(data, gzipMagic) {
+		return ArchiveGzip
+	}
+	if bytes.HasPrefix(data, zstdMagic) {
+		return ArchiveZstd
+	}
+	if bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("{")) {
+		return ArchivePlain
+	}
+	return ArchiveUnknown
+}
+
+// zstdReader - streams output of `zstd` binary, Close waits for the process
+type zstdReader struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	stdErr *bytes.Buffer
+}
+
+// Close releases decompressor, reports `zstd` errors (like truncated input)
+func (z *zstdReader) Close() error {
+	_ = z.ReadCloser.Close()
+	if err := z.cmd.Wait(); err != nil {
+		return fmt.Errorf("zstd: %v: %s", err, z.stdErr.String())
+	}
+	return nil
+}
+
+// ArchiveReader returns streaming reader of JSON lines from gzip, zstd or plain GHA archive
+// Data is decompressed while it is read, so whole decompressed hour is never kept in memory
+// There is no zstd decoder in Go standard library, `zstd` binary is used for it
+func ArchiveReader(data []byte) (io.ReadCloser, error) {
+	switch ArchiveFormat(data) {
+	case ArchiveGzip:
+		return gzip.NewReader(bytes.NewReader(data))
+	case ArchiveZstd:
+		stdErr := &bytes.Buffer{}
+		cmd := exec.Command("zstd", "-d", "-c", "-q")
+		cmd.Stdin = bytes.NewReader(data)
+		cmd.Stderr = stdErr
+		stdOut, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		err = cmd.Start()
+		if err != nil {
+			return nil, fmt.Errorf("zstd: %v", err)
+		}
+		return &zstdReader{ReadCloser: stdOut, cmd: cmd, stdErr: stdErr}, nil
+	case ArchivePlain:
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	n := len(data)
+	if n > 16 {
+		n = 16
+	}
+	return nil, fmt.Errorf("unknown archive format, starts with: %q", data[:n])
+}
+
+// DecompressArchive returns JSON lines from gzip, zstd or plain GHA archive
+func DecompressArchive(data []byte) ([]byte, error) {
+	reader, err := ArchiveReader(data)
+	if err != nil {
+		return nil, err
+	}
+	out, err := ioutil.ReadAll(reader)
+	cerr := reader.Close()
+	if err != nil {
+		return nil, err
+	}
+	if cerr != nil {
+		return nil, cerr
+	}
+	return out, nil
+}
+
+// lastByte - writer remembering the last non white space byte written
+type lastByte struct {
+	b byte
+}
+
+func (l *lastByte) Write(p []byte) (int, error) {
+	if t := bytes.TrimRight(p, " \t\r\n"); len(t) > 0 {
+		l.b = t[len(t)-1]
+	}
+	return len(p), nil
+}
+
+// ValidateArchive checks that GHA archive is complete before it is parsed
+// Compressed archives are decompressed (gzip CRC and size are verified, zstd checks its own frames)
+// and the last JSON line must not be truncated
+// Published `checksum` (hex encoded MD5 or SHA256 of the archive) is compared too, when it is not empty
+func ValidateArchive(data []byte, checksum string) error {
+	if checksum != "" {
+		var sum []byte
+		switch len(checksum) {
+		case 2 * md5.Size:
+			s := md5.Sum(data)
+			sum = s[:]
+		case 2 * sha256.Size:
+			s := sha256.Sum256(data)
+			sum = s[:]
+		default:
+			return fmt.Errorf("unsupported checksum %q, only MD5 and SHA256 are supported", checksum)
+		}
+		if got := hex.EncodeToString(sum); got != strings.ToLower(checksum) {
+			return fmt.Errorf("checksum mismatch: expected %s, got %s", checksum, got)
+		}
+	}
+	reader, err := ArchiveReader(data)
+	if err != nil {
+		return err
+	}
+	var last lastByte
+	_, err = io.Copy(&last, reader)
+	cerr := reader.Close()
+	if err != nil {
+		return fmt.Errorf("%s archive is broken: %v", ArchiveFormat(data), err)
+	}
+	if cerr != nil {
+		return fmt.Errorf("%s archive is broken: %v", ArchiveFormat(data), cerr)
+	}
+	if last.b != 0 && last.b != '}' {
+		return fmt.Errorf("%s archive is truncated, last JSON line is incomplete", ArchiveFormat(data))
+	}
+	return nil
+}
+
+// ArchiveChecksum downloads published checksum of GHA archive from `url`
+// Checksum file has "sha256sum" or "md5sum" format (checksum is its first field)
+// Returns empty checksum when it is not published (HTTP 404)
+func ArchiveChecksum(url string) (string, error) {
+	response, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: HTTP %d", url, response.StatusCode)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("%s: empty checksum file", url)
+	}
+	return fields[0], nil
+}
@@ -0,0 +1,167 @@
+package devstats
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestArchiveFormat(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		data     []byte
+		expected string
+	}{
+		{data: []byte{0x1f, 0x8b, 0x08, 0x00}, expected: lib.ArchiveGzip},
+		{data: []byte{0x28, 0xb5, 0x2f, 0xfd, 0x04}, expected: lib.ArchiveZstd},
+		{data: []byte(`{"id":"1"}` + "\n"), expected: lib.ArchivePlain},
+		{data: []byte("\n  {\"id\":\"1\"}"), expected: lib.ArchivePlain},
+		{data: []byte("<html>Not found</html>"), expected: lib.ArchiveUnknown},
+		{data: []byte{0x1f}, expected: lib.ArchiveUnknown},
+		{data: []byte{}, expected: lib.ArchiveUnknown},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.ArchiveFormat(test.data)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %q, got %q", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestDecompressArchive(t *testing.T) {
+	jsons := []byte(`{"id":"1"}` + "\n" + `{"id":"2"}` + "\n")
+	var gz bytes.Buffer
+	writer := gzip.NewWriter(&gz)
+	_, _ = writer.Write(jsons)
+	_ = writer.Close()
+
+	// Test cases
+	var testCases = []struct {
+		data []byte
+		err  bool
+	}{
+		{data: gz.Bytes()},
+		{data: jsons},
+		{data: []byte("Not found"), err: true},
+		{data: gz.Bytes()[:10], err: true},
+	}
+	// zstd is only tested when binary is available
+	if _, err := exec.LookPath("zstd"); err == nil {
+		cmd := exec.Command("zstd", "-c", "-q")
+		cmd.Stdin = bytes.NewReader(jsons)
+		zst, err := cmd.Output()
+		if err != nil {
+			t.Errorf("zstd compression failed: %v", err)
+		}
+		testCases = append(testCases, []struct {
+			data []byte
+			err  bool
+		}{{data: zst}, {data: zst[:len(zst)-4], err: true}}...)
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.DecompressArchive(test.data)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got %q", index+1, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if !bytes.Equal(got, jsons) {
+			t.Errorf("test number %d, expected %q, got %q", index+1, jsons, got)
+		}
+	}
+}
+
+func TestValidateArchive(t *testing.T) {
+	gzipped := func(data []byte) []byte {
+		var gz bytes.Buffer
+		writer := gzip.NewWriter(&gz)
+		_, _ = writer.Write(data)
+		_ = writer.Close()
+		return gz.Bytes()
+	}
+	jsons := []byte(`{"id":"1"}` + "\n" + `{"id":"2"}` + "\n")
+	gz := gzipped(jsons)
+	md5Sum := md5.Sum(gz)
+	sha256Sum := sha256.Sum256(gz)
+
+	// Test cases
+	var testCases = []struct {
+		data     []byte
+		checksum string
+		err      bool
+	}{
+		{data: gz},
+		{data: jsons},
+		{data: gzipped(nil)},
+		{data: gz, checksum: hex.EncodeToString(md5Sum[:])},
+		{data: gz, checksum: hex.EncodeToString(sha256Sum[:])},
+		{data: jsons, checksum: hex.EncodeToString(md5Sum[:]), err: true},
+		{data: gz, checksum: "abc", err: true},
+		{data: gz[:len(gz)-4], err: true},
+		{data: gz[:len(gz)/2], err: true},
+		{data: gzipped(jsons[:15]), err: true},
+		{data: jsons[:15], err: true},
+		{data: []byte("<html>Not found</html>"), err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		err := lib.ValidateArchive(test.data, test.checksum)
+		if (err != nil) != test.err {
+			t.Errorf("test number %d, expected error %v, got %v", index+1, test.err, err)
+		}
+	}
+}
+
+func TestArchiveChecksum(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2017-08-01-0.json.gz.sha256":
+			_, _ = w.Write([]byte("ABCDEF  2017-08-01-0.json.gz\n"))
+		case "/2017-08-01-1.json.gz.sha256":
+			_, _ = w.Write([]byte("\n"))
+		case "/2017-08-01-2.json.gz.sha256":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Test cases
+	var testCases = []struct {
+		hour     string
+		expected string
+		err      bool
+	}{
+		{hour: "2017-08-01-0", expected: "ABCDEF"},
+		{hour: "2017-08-01-1", err: true},
+		{hour: "2017-08-01-2", err: true},
+		{hour: "2017-08-01-3", expected: ""},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.ArchiveChecksum(server.URL + "/" + test.hour + ".json.gz.sha256")
+		if (err != nil) != test.err {
+			t.Errorf("test number %d, expected error %v, got %v", index+1, test.err, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("test number %d, expected %q, got %q", index+1, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package devstats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bigQueryNameRe - org and repo names allowed in BigQuery filters, they are put directly into SQL
+var bigQueryNameRe = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// bigQueryLike - returns "repo.name like 'pattern' or ..." for given names, "false" when there are none
+func bigQueryLike(names []string, pattern string) string {
+	conds := []string{}
+	for _, name := range names {
+		conds = append(conds, "repo.name like '"+strings.Replace(pattern, "%s", name, -1)+"'")
+	}
+	if len(conds) == 0 {
+		return "false"
+	}
+	return "(" + strings.Join(conds, " or ") + ")"
+}
+
+// BigQueryDayQuery returns standard SQL query reading one day of events from public githubarchive BigQuery dataset
+// Orgs/repos filter is only a prefilter with the same meaning as in RepoHit, events still need to be checked by RepoHit
+func BigQueryDayQuery(day time.Time, forg, frepo map[string]struct{}) (string, error) {
+	fullNames, orgs, repos := []string{}, []string{}, []string{}
+	for _, name := range StringsSetKeys(forg) {
+		ary := strings.Split(name, "/")
+		for _, part := range ary {
+			if !bigQueryNameRe.MatchString(part) || len(ary) > 2 {
+				return "", fmt.Errorf("invalid org or repo name for BigQuery: '%s'", name)
+			}
+		}
+		if len(ary) > 1 {
+			fullNames = append(fullNames, "'"+name+"'")
+		} else {
+			orgs = append(orgs, name)
+		}
+	}
+	for _, name := range StringsSetKeys(frepo) {
+		if !bigQueryNameRe.MatchString(name) {
+			return "", fmt.Errorf("invalid repo name for BigQuery: '%s'", name)
+		}
+		repos = append(repos, name)
+	}
+	query := fmt.Sprintf(
+		"select id, type, public, created_at, actor, repo, org, payload from `githubarchive.day.%04d%02d%02d`",
+		day.Year(), day.Month(), day.Day(),
+	)
+	if len(forg) == 0 && len(frepo) == 0 {
+		return query, nil
+	}
+	orgCond, repoCond := "true", "true"
+	if len(forg) > 0 {
+		orgCond = bigQueryLike(orgs, "%s/%")
+	}
+	if len(frepo) > 0 {
+		repoCond = bigQueryLike(repos, "%/%s")
+	}
+	cond := orgCond + " and " + repoCond
+	if len(fullNames) > 0 {
+		cond = "repo.name in (" + strings.Join(fullNames, ", ") + ") or (" + cond + ")"
+	}
+	return query + " where " + cond, nil
+}
+
+// bigQueryTime - parses timestamp from `bq` JSON output, depending on version it is a date or unix time
+func bigQueryTime(value string) (time.Time, error) {
+	for _, format := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04:05 MST", time.RFC3339} {
+		dt, err := time.Parse(format, value)
+		if err == nil {
+			return dt.UTC(), nil
+		}
+	}
+	unix, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid BigQuery timestamp: '%s'", value)
+	}
+	return time.Unix(int64(unix), 0).UTC(), nil
+}
+
+// bigQueryRecord - converts actor/repo/org record, `bq` outputs all numbers as strings
+func bigQueryRecord(value interface{}) (map[string]interface{}, error) {
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	if id, ok := record["id"].(string); ok {
+		i, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, err
+		}
+		record["id"] = i
+	}
+	return record, nil
+}
+
+// BigQueryRowsToJSONs converts `bq query --format=json` output into GHA JSON lines, grouped by hour of day
+func BigQueryRowsToJSONs(data []byte) (map[int][]byte, error) {
+	var rows []map[string]interface{}
+	err := json.Unmarshal(data, &rows)
+	if err != nil {
+		return nil, err
+	}
+	hours := make(map[int]*bytes.Buffer)
+	for _, row := range rows {
+		createdAt, _ := row["created_at"].(string)
+		dt, err := bigQueryTime(createdAt)
+		if err != nil {
+			return nil, err
+		}
+		ev := map[string]interface{}{
+			"id":         row["id"],
+			"type":       row["type"],
+			"public":     row["public"] == "true" || row["public"] == true,
+			"created_at": dt.Format(time.RFC3339),
+		}
+		for _, key := range []string{"actor", "repo", "org"} {
+			record, err := bigQueryRecord(row[key])
+			if err != nil {
+				return nil, err
+			}
+			if record != nil {
+				ev[key] = record
+			}
+		}
+		// Payload is stored as a JSON string
+		payload, _ := row["payload"].(string)
+		if payload == "" {
+			payload = "{}"
+		}
+		ev["payload"] = json.RawMessage(payload)
+		line, err := json.Marshal(ev)
+		if err != nil {
+			return nil, err
+		}
+		hour := dt.Hour()
+		if _, ok := hours[hour]; !ok {
+			hours[hour] = &bytes.Buffer{}
+		}
+		hours[hour].Write(line)
+		hours[hour].WriteByte('\n')
+	}
+	jsons := make(map[int][]byte)
+	for hour, buffer := range hours {
+		jsons[hour] = buffer.Bytes()
+	}
+	return jsons, nil
+}
@@ -0,0 +1,103 @@
+package devstats
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestBigQueryDayQuery(t *testing.T) {
+	day := time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC)
+	prefix := "select id, type, public, created_at, actor, repo, org, payload from `githubarchive.day.20180102`"
+	// Test cases
+	var testCases = []struct {
+		orgs     []string
+		repos    []string
+		expected string
+		err      bool
+	}{
+		{expected: prefix},
+		{
+			orgs:     []string{"kubernetes", "kubernetes-incubator"},
+			expected: prefix + " where (repo.name like 'kubernetes/%' or repo.name like 'kubernetes-incubator/%') and true",
+		},
+		{
+			orgs:     []string{"kubernetes/kubernetes", "helm"},
+			repos:    []string{"charts"},
+			expected: prefix + " where repo.name in ('kubernetes/kubernetes') or ((repo.name like 'helm/%') and (repo.name like '%/charts'))",
+		},
+		{
+			orgs:     []string{"cncf/devstats"},
+			expected: prefix + " where repo.name in ('cncf/devstats') or (false and true)",
+		},
+		{orgs: []string{"a' or 1=1 --"}, err: true},
+		{orgs: []string{"a/b/c"}, err: true},
+		{repos: []string{"%"}, err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		forg := lib.StringsMapToSet(func(x string) string { return x }, test.orgs)
+		frepo := lib.StringsMapToSet(func(x string) string { return x }, test.repos)
+		got, err := lib.BigQueryDayQuery(day, forg, frepo)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got %s", index+1, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("test number %d, expected:\n%s\ngot:\n%s", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestBigQueryRowsToJSONs(t *testing.T) {
+	data := []byte(`[
+  {"id": "7", "type": "PushEvent", "public": "true", "created_at": "2018-01-02 03:04:05",
+   "actor": {"id": "1", "login": "lukaszgryglicki"}, "repo": {"id": "2", "name": "cncf/devstats"},
+   "org": {"id": "3", "login": "cncf"}, "payload": "{\"push_id\": 5}"},
+  {"id": "8", "type": "WatchEvent", "public": "true", "created_at": "1.514865845E9",
+   "actor": {"id": "4", "login": "someone"}, "repo": {"id": "2", "name": "cncf/devstats"},
+   "org": null, "payload": "{\"action\": \"started\"}"}
+]`)
+	jsons, err := lib.BigQueryRowsToJSONs(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(jsons) != 2 || len(jsons[3]) == 0 || len(jsons[4]) == 0 {
+		t.Fatalf("expected events in hours 3 and 4, got %d hours", len(jsons))
+	}
+	// Test cases
+	var testCases = []struct {
+		hour  int
+		id    string
+		actor int
+		org   bool
+		dt    time.Time
+	}{
+		{hour: 3, id: "7", actor: 1, org: true, dt: time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{hour: 4, id: "8", actor: 4, org: false, dt: time.Date(2018, 1, 2, 4, 4, 5, 0, time.UTC)},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		var ev lib.Event
+		err := json.Unmarshal(jsons[test.hour], &ev)
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if ev.ID != test.id || ev.Actor.ID != test.actor || (ev.Org != nil) != test.org || !ev.CreatedAt.Equal(test.dt) || !ev.Public {
+			t.Errorf("test number %d, unexpected event: %+v", index+1, ev)
+		}
+	}
+	_, err = lib.BigQueryRowsToJSONs([]byte(`[{"created_at": "yesterday"}]`))
+	if err == nil {
+		t.Errorf("expected invalid timestamp error")
+	}
+}
@@ -0,0 +1,108 @@
+package devstats
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// BotsConfig - bots definition: explicit logins and login regexp patterns
+// Global list is read from bots.yaml, projects can add their own in projects.yaml `bots` entry
+type BotsConfig struct {
+	Logins   []string `yaml:"logins"`
+	Patterns []string `yaml:"patterns"`
+}
+
+// BotDetector - decides if a given actor login belongs to a bot
+// Logins and patterns are case insensitive, nil *BotDetector detects no bots
+type BotDetector struct {
+	logins   map[string]struct{}
+	patterns []*regexp.Regexp
+}
+
+// NewBotDetector creates detector from all given configs (nil configs are skipped)
+// Returns nil when configs define no bots at all
+func NewBotDetector(configs ...*BotsConfig) (*BotDetector, error) {
+	b := &BotDetector{logins: make(map[string]struct{})}
+	for _, config := range configs {
+		if config == nil {
+			continue
+		}
+		for _, login := range config.Logins {
+			login = strings.ToLower(strings.TrimSpace(login))
+			if login != "" {
+				b.logins[login] = struct{}{}
+			}
+		}
+		for _, pattern := range config.Patterns {
+			if strings.TrimSpace(pattern) == "" {
+				continue
+			}
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid bot pattern '%s': %v", pattern, err)
+			}
+			b.patterns = append(b.patterns, re)
+		}
+	}
+	if len(b.logins) == 0 && len(b.patterns) == 0 {
+		return nil, nil
+	}
+	return b, nil
+}
+
+// IsBot returns true when login is on the bots list or matches any bot pattern
+func (b *BotDetector) IsBot(login string) bool {
+	if b == nil {
+		return false
+	}
+	if _, ok := b.logins[strings.ToLower(login)]; ok {
+		return true
+	}
+	for _, re := range b.patterns {
+		if re.MatchString(login) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProjectBotDetector creates detector using global `ctx.BotsYaml` list (if file exists)
+// and `ctx.Project` specific bots from `ctx.ProjectsYaml` (if project is set)
+func ProjectBotDetector(ctx *Ctx) (*BotDetector, error) {
+	// Local or cron mode?
+	dataPrefix := DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+	var global BotsConfig
+	data, err := ioutil.ReadFile(dataPrefix + ctx.BotsYaml)
+	if err == nil {
+		err = yaml.Unmarshal(data, &global)
+		if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	var project *BotsConfig
+	if ctx.Project != "" {
+		data, err = ioutil.ReadFile(dataPrefix + ctx.ProjectsYaml)
+		if err != nil {
+			return nil, err
+		}
+		var projects AllProjects
+		err = yaml.Unmarshal(data, &projects)
+		if err != nil {
+			return nil, err
+		}
+		if proj, ok := projects.Projects[ctx.Project]; ok {
+			project = proj.Bots
+		}
+	}
+	return NewBotDetector(&global, project)
+}
@@ -0,0 +1,115 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestBotDetector(t *testing.T) {
+	global := &lib.BotsConfig{
+		Logins:   []string{"googlebot", " Coveralls "},
+		Patterns: []string{"-bot$", `\[bot\]`, "^k8s-"},
+	}
+	project := &lib.BotsConfig{Logins: []string{"thelinuxfoundation"}}
+
+	// Test cases
+	var testCases = []struct {
+		configs  []*lib.BotsConfig
+		login    string
+		expected bool
+	}{
+		{configs: nil, login: "k8s-ci-robot", expected: false},
+		{configs: []*lib.BotsConfig{nil}, login: "googlebot", expected: false},
+		{configs: []*lib.BotsConfig{global}, login: "googlebot", expected: true},
+		{configs: []*lib.BotsConfig{global}, login: "GoogleBot", expected: true},
+		{configs: []*lib.BotsConfig{global}, login: "coveralls", expected: true},
+		{configs: []*lib.BotsConfig{global}, login: "dependabot[bot]", expected: true},
+		{configs: []*lib.BotsConfig{global}, login: "fejta-BOT", expected: true},
+		{configs: []*lib.BotsConfig{global}, login: "k8s-merge-robot", expected: true},
+		{configs: []*lib.BotsConfig{global}, login: "bottomsup", expected: false},
+		{configs: []*lib.BotsConfig{global}, login: "lukaszgryglicki", expected: false},
+		{configs: []*lib.BotsConfig{global}, login: "thelinuxfoundation", expected: false},
+		{configs: []*lib.BotsConfig{global, project}, login: "thelinuxfoundation", expected: true},
+		{configs: []*lib.BotsConfig{global, project}, login: "fejta-bot", expected: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		bots, err := lib.NewBotDetector(test.configs...)
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		got := bots.IsBot(test.login)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v, login: %s", index+1, test.expected, got, test.login)
+		}
+	}
+}
+
+func TestBotDetectorInvalidPattern(t *testing.T) {
+	_, err := lib.NewBotDetector(&lib.BotsConfig{Patterns: []string{"[bot"}})
+	if err == nil {
+		t.Errorf("expected error for invalid pattern")
+	}
+}
+
+func TestProjectBotDetector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devstats_bots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	// Files are read relative to current directory in local mode
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(pwd) }()
+	err = ioutil.WriteFile("bots.yaml", []byte("logins:\n  - googlebot\npatterns:\n  - '-bot$'\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(
+		"projects.yaml",
+		[]byte("projects:\n  kubernetes:\n    bots:\n      logins:\n        - k8s-reviewable\n  prometheus:\n    psql_db: prometheus\n"),
+		0644,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Test cases
+	var testCases = []struct {
+		botsYaml string
+		project  string
+		login    string
+		expected bool
+	}{
+		{botsYaml: "bots.yaml", project: "", login: "googlebot", expected: true},
+		{botsYaml: "bots.yaml", project: "", login: "k8s-reviewable", expected: false},
+		{botsYaml: "bots.yaml", project: "kubernetes", login: "k8s-reviewable", expected: true},
+		{botsYaml: "bots.yaml", project: "kubernetes", login: "fejta-bot", expected: true},
+		{botsYaml: "bots.yaml", project: "prometheus", login: "k8s-reviewable", expected: false},
+		{botsYaml: "missing.yaml", project: "", login: "googlebot", expected: false},
+		{botsYaml: "missing.yaml", project: "kubernetes", login: "k8s-reviewable", expected: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		ctx := lib.Ctx{Local: true, BotsYaml: test.botsYaml, ProjectsYaml: "projects.yaml", Project: test.project}
+		bots, err := lib.ProjectBotDetector(&ctx)
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		got := bots.IsBot(test.login)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v, login: %s", index+1, test.expected, got, test.login)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package devstats
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArchiveBucket - S3 or GCS bucket with GHA files, objects are named like GHA files (for example "2017-08-01-0.json.gz")
+// Many clusters and parallel backfills can read hours from the bucket instead of downloading them from gharchive.org
+// Requests are signed with AWS signature version 4 (GCS XML API accepts it with HMAC keys), public buckets are read without keys
+// nil *ArchiveBucket means no bucket
+type ArchiveBucket struct {
+	client   *http.Client
+	endpoint string
+	prefix   string
+	region   string
+	keyID    string
+	secret   string
+}
+
+// NewArchiveBucket creates bucket from GHA2DB_ARCHIVE_BUCKET URL like "s3://bucket/prefix" or "gs://bucket/prefix"
+// Returns nil (no bucket) when bucket is not set
+func NewArchiveBucket(ctx *Ctx) (*ArchiveBucket, error) {
+	if ctx.ArchiveBucket == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(ctx.ArchiveBucket)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%s: no bucket name", ctx.ArchiveBucket)
+	}
+	b := &ArchiveBucket{
+		client:   &http.Client{},
+		endpoint: strings.TrimRight(ctx.BucketEndpoint, "/"),
+		region:   ctx.BucketRegion,
+		keyID:    ctx.BucketKeyID,
+		secret:   ctx.BucketSecret,
+	}
+	switch u.Scheme {
+	case "s3":
+		if b.region == "" {
+			b.region = "us-east-1"
+		}
+		if b.endpoint == "" {
+			b.endpoint = "https://s3." + b.region + ".amazonaws.com"
+		}
+	case "gs":
+		if b.region == "" {
+			b.region = "auto"
+		}
+		if b.endpoint == "" {
+			b.endpoint = "https://storage.googleapis.com"
+		}
+	default:
+		return nil, fmt.Errorf("%s: only s3:// and gs:// buckets are supported", ctx.ArchiveBucket)
+	}
+	// Path style URLs work for S3, GCS and S3 compatible servers (like MinIO)
+	b.prefix = "/" + u.Host + "/"
+	if p := strings.Trim(u.Path, "/"); p != "" {
+		b.prefix += p + "/"
+	}
+	return b, nil
+}
+
+// String returns bucket URL, used in logs
+func (b *ArchiveBucket) String() string {
+	return b.endpoint + b.prefix
+}
+
+// Get returns contents of GHA file `name` (URL or file name) stored in the bucket
+// Returns ErrNotFound when the bucket doesn't have it
+func (b *ArchiveBucket) Get(name string) ([]byte, error) {
+	if b == nil {
+		return nil, ErrNotFound
+	}
+	return b.do("GET", name, nil)
+}
+
+// Put stores GHA file `name` (URL or file name) in the bucket
+func (b *ArchiveBucket) Put(name string, data []byte) error {
+	if b == nil {
+		return nil
+	}
+	_, err := b.do("PUT", name, data)
+	return err
+}
+
+// do - sends signed request for object `name`, server errors are retried
+func (b *ArchiveBucket) do(method, name string, data []byte) ([]byte, error) {
+	key := b.prefix + path.Base(name)
+	sum := sha256.Sum256(data)
+	payloadHash := hex.EncodeToString(sum[:])
+	wait := time.Second
+	for try := 0; ; try++ {
+		req, err := http.NewRequest(method, b.endpoint, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.URL.Path = key
+		req.URL.RawPath = awsURIEncode(key, false)
+		if b.keyID != "" {
+			SignAWSV4(req, payloadHash, b.region, b.keyID, b.secret, time.Now())
+		}
+		resp, err := b.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= 500 && try < restRetries {
+			Printf("%s %s: HTTP %d, retrying in %v\n", method, b.endpoint+key, resp.StatusCode, wait)
+			time.Sleep(wait)
+			wait *= 2
+			continue
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s %s: HTTP %d: %s", method, b.endpoint+key, resp.StatusCode, string(body))
+		}
+		return body, nil
+	}
+}
+
+// SignAWSV4 signs S3 request with AWS signature version 4, headers already set on the request are signed too
+// `payloadHash` is hex encoded SHA256 of the request body
+func SignAWSV4(req *http.Request, payloadHash, region, keyID, secret string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	day := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// Header names are lower case and sorted, values trimmed
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders bytes.Buffer
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	// Query parameters are sorted by name
+	params := []string{}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			params = append(params, awsURIEncode(name, true)+"="+awsURIEncode(value, true))
+		}
+	}
+	sort.Strings(params)
+
+	canonicalRequest := strings.Join(
+		[]string{
+			req.Method,
+			req.URL.EscapedPath(),
+			strings.Join(params, "&"),
+			canonicalHeaders.String(),
+			signedHeaders,
+			payloadHash,
+		},
+		"\n",
+	)
+	scope := day + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	key := []byte("AWS4" + secret)
+	for _, part := range []string{day, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set(
+		"Authorization",
+		fmt.Sprintf(
+			"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+			keyID, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign)),
+		),
+	)
+}
+
+// hmacSHA256 - returns HMAC-SHA256 of data using given key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsURIEncode - percent encodes everything except unreserved characters (and "/" unless `encodeSlash` is set)
+func awsURIEncode(s string, encodeSlash bool) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			buf.WriteByte(c)
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("%%%02X", c))
+	}
+	return buf.String()
+}
@@ -0,0 +1,182 @@
+package devstats
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// maxBindParams - Postgres limit of bind parameters in a single statement
+const maxBindParams = 65535
+
+// bulkTable - rows waiting to be saved into a single table
+type bulkTable struct {
+	name    string
+	columns []string
+	rows    [][]interface{}
+}
+
+// BulkWriter - batches rows per table and saves them using `COPY FROM STDIN`
+// All queued tables are saved in a single transaction, so batch is saved as a whole or not at all
+// When COPY fails (for example on a duplicate key) batch is saved using multi-row INSERT that skips conflicting rows
+// It is not safe for concurrent use, every worker should have its own writer
+type BulkWriter struct {
+	con       *sql.DB
+	ctx       *Ctx
+	batchSize int
+	tables    []*bulkTable
+	byName    map[string]*bulkTable
+}
+
+// NewBulkWriter creates writer, Full() reports true when any table has at least `batchSize` rows
+func NewBulkWriter(con *sql.DB, ctx *Ctx, batchSize int) *BulkWriter {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return &BulkWriter{con: con, ctx: ctx, batchSize: batchSize, byName: make(map[string]*bulkTable)}
+}
+
+// Insert queues single row, `columns` is a comma separated list like "id, event_id, name"
+// Tables are saved in the order they were first used, so parent rows can be queued first
+func (b *BulkWriter) Insert(table, columns string, row ...interface{}) {
+	t, ok := b.byName[table]
+	if !ok {
+		t = &bulkTable{name: table}
+		for _, column := range strings.Split(columns, ",") {
+			t.columns = append(t.columns, strings.TrimSpace(column))
+		}
+		b.tables = append(b.tables, t)
+		b.byName[table] = t
+	}
+	if len(row) != len(t.columns) {
+		FatalOnError(fmt.Errorf("%s: %d values given for %d columns: %s", table, len(row), len(t.columns), columns))
+	}
+	t.rows = append(t.rows, row)
+}
+
+// Rows returns number of queued rows (all tables)
+func (b *BulkWriter) Rows() (n int) {
+	for _, t := range b.tables {
+		n += len(t.rows)
+	}
+	return
+}
+
+// Full returns true when any table has at least batch size rows queued
+func (b *BulkWriter) Full() bool {
+	for _, t := range b.tables {
+		if len(t.rows) >= b.batchSize {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush saves all queued rows, using COPY and falling back to multi-row INSERT
+func (b *BulkWriter) Flush() {
+	if b.Rows() == 0 {
+		return
+	}
+	err := b.save(b.copyTable)
+	if err != nil {
+		if b.ctx.Debug > 0 {
+			Printf("COPY failed, falling back to INSERT: %v\n", err)
+		}
+		FatalOnError(b.save(b.insertTable))
+	}
+	for _, t := range b.tables {
+		t.rows = nil
+	}
+}
+
+// save - saves all tables in a single transaction using given method
+func (b *BulkWriter) save(method func(*sql.Tx, *bulkTable) error) error {
+	tx, err := b.con.Begin()
+	if err != nil {
+		return err
+	}
+	for _, t := range b.tables {
+		if len(t.rows) == 0 {
+			continue
+		}
+		err = method(tx, t)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("%s: %v", t.name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// copyTable - saves table's rows using `COPY FROM STDIN`
+func (b *BulkWriter) copyTable(tx *sql.Tx, t *bulkTable) error {
+	if b.ctx.QOut {
+		queryOut(fmt.Sprintf("copy %s(%s) from stdin: %d rows", t.name, strings.Join(t.columns, ", "), len(t.rows)))
+	}
+	stmt, err := tx.Prepare(pq.CopyIn(t.name, t.columns...))
+	if err != nil {
+		return err
+	}
+	for _, row := range t.rows {
+		_, err = stmt.Exec(row...)
+		if err != nil {
+			_ = stmt.Close()
+			return err
+		}
+	}
+	_, err = stmt.Exec()
+	if err != nil {
+		_ = stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
+
+// insertTable - saves table's rows using multi-row INSERT, rows that already exist are skipped
+func (b *BulkWriter) insertTable(tx *sql.Tx, t *bulkTable) error {
+	nCols := len(t.columns)
+	perStmt := maxBindParams / nCols
+	for from := 0; from < len(t.rows); from += perStmt {
+		to := from + perStmt
+		if to > len(t.rows) {
+			to = len(t.rows)
+		}
+		query, args := MultiRowInsert(t.name, t.columns, t.rows[from:to])
+		if b.ctx.QOut {
+			queryOut(query)
+		}
+		_, err := tx.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MultiRowInsert returns "insert into table(columns) values($1, ..), (..) on conflict do nothing" and its arguments
+func MultiRowInsert(table string, columns []string, rows [][]interface{}) (string, []interface{}) {
+	var (
+		query bytes.Buffer
+		args  []interface{}
+	)
+	query.WriteString("into " + table + "(" + strings.Join(columns, ", ") + ") values")
+	for i, row := range rows {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for j, value := range row {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			args = append(args, value)
+			query.WriteString("$" + strconv.Itoa(len(args)))
+		}
+		query.WriteString(")")
+	}
+	return InsertIgnore(query.String()), args
+}
@@ -0,0 +1,62 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestMultiRowInsert(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		table         string
+		columns       []string
+		rows          [][]interface{}
+		expectedQuery string
+		expectedArgs  []interface{}
+	}{
+		{
+			table:         "gha_pages",
+			columns:       []string{"sha", "event_id"},
+			rows:          [][]interface{}{{"a", 1}},
+			expectedQuery: "insert into gha_pages(sha, event_id) values($1, $2) on conflict do nothing",
+			expectedArgs:  []interface{}{"a", 1},
+		},
+		{
+			table:         "gha_events",
+			columns:       []string{"id", "type", "org_id"},
+			rows:          [][]interface{}{{1, "PushEvent", nil}, {2, "IssuesEvent", 3}},
+			expectedQuery: "insert into gha_events(id, type, org_id) values($1, $2, $3), ($4, $5, $6) on conflict do nothing",
+			expectedArgs:  []interface{}{1, "PushEvent", nil, 2, "IssuesEvent", 3},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		query, args := lib.MultiRowInsert(test.table, test.columns, test.rows)
+		if query != test.expectedQuery {
+			t.Errorf("test number %d, expected query %q, got %q", index+1, test.expectedQuery, query)
+		}
+		if !reflect.DeepEqual(args, test.expectedArgs) {
+			t.Errorf("test number %d, expected args %+v, got %+v", index+1, test.expectedArgs, args)
+		}
+	}
+}
+
+func TestBulkWriterBatches(t *testing.T) {
+	// Rows are only queued, nothing is saved until Flush
+	var ctx lib.Ctx
+	bw := lib.NewBulkWriter(nil, &ctx, 2)
+	if bw.Full() || bw.Rows() != 0 {
+		t.Errorf("expected empty writer, got %d rows", bw.Rows())
+	}
+	bw.Insert("gha_events", "id, type", 1, "PushEvent")
+	bw.Insert("gha_payloads", "event_id", 1)
+	if bw.Full() || bw.Rows() != 2 {
+		t.Errorf("expected 2 rows and not full, got %d rows, full: %v", bw.Rows(), bw.Full())
+	}
+	bw.Insert("gha_events", "id, type", 2, "IssuesEvent")
+	if !bw.Full() || bw.Rows() != 3 {
+		t.Errorf("expected 3 rows and full, got %d rows, full: %v", bw.Rows(), bw.Full())
+	}
+}
@@ -0,0 +1,100 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArchiveCache - on disk cache of downloaded GHA files, shared by all tools (and processes) using the same directory
+// Least recently used files are removed when cache grows above its maximum size
+// nil *ArchiveCache means no cache
+type ArchiveCache struct {
+	mtx     sync.Mutex
+	dir     string
+	maxSize int64
+}
+
+// NewArchiveCache creates cache in `dir` limited to `maxSizeMB` megabytes (0 - no limit)
+// Returns nil (no cache) when dir is empty
+func NewArchiveCache(dir string, maxSizeMB int) *ArchiveCache {
+	if dir == "" {
+		return nil
+	}
+	FatalOnError(os.MkdirAll(dir, 0755))
+	return &ArchiveCache{dir: dir, maxSize: int64(maxSizeMB) << 20}
+}
+
+// Get returns cached file contents, file's access time is updated for LRU eviction
+func (c *ArchiveCache) Get(name string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	path := filepath.Join(c.dir, filepath.Base(name))
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return data, true
+}
+
+// Put saves file in cache and removes least recently used files when cache is too big
+// File is written under a temporary name first, so other processes never read partial files
+func (c *ArchiveCache) Put(name string, data []byte) error {
+	if c == nil {
+		return nil
+	}
+	path := filepath.Join(c.dir, filepath.Base(name))
+	tmp := path + ".tmp" + strconv.Itoa(os.Getpid())
+	err := ioutil.WriteFile(tmp, data, 0644)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return c.evict()
+}
+
+// evict - removes least recently used files until cache fits its maximum size
+func (c *ArchiveCache) evict() error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	size := int64(0)
+	cached := []os.FileInfo{}
+	for _, file := range files {
+		if !file.Mode().IsRegular() || strings.Contains(file.Name(), ".tmp") {
+			continue
+		}
+		size += file.Size()
+		cached = append(cached, file)
+	}
+	sort.Slice(cached, func(i, j int) bool { return cached[i].ModTime().Before(cached[j].ModTime()) })
+	for _, file := range cached {
+		if size <= c.maxSize {
+			break
+		}
+		// File can already be removed by other process using the same cache
+		err := os.Remove(filepath.Join(c.dir, file.Name()))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		size -= file.Size()
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package devstats
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestArchiveCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devstats_cache")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// No directory means no cache
+	noCache := lib.NewArchiveCache("", 1)
+	if err := noCache.Put("a.json.gz", []byte("a")); err != nil {
+		t.Errorf("expected nil cache put to succeed, got %v", err)
+	}
+	if _, ok := noCache.Get("a.json.gz"); ok {
+		t.Errorf("expected nil cache to have no files")
+	}
+
+	// 1MB cache, each file takes 400KB, so only 2 files fit
+	cache := lib.NewArchiveCache(dir, 1)
+	data := bytes.Repeat([]byte("x"), 400<<10)
+	// Test cases
+	var testCases = []struct {
+		put      string
+		get      string
+		expected []string
+	}{
+		{put: "1.json.gz", expected: []string{"1.json.gz"}},
+		{put: "2.json.gz", expected: []string{"1.json.gz", "2.json.gz"}},
+		{put: "3.json.gz", expected: []string{"2.json.gz", "3.json.gz"}},
+		{get: "2.json.gz", put: "4.json.gz", expected: []string{"2.json.gz", "4.json.gz"}},
+		{put: "http://data.githubarchive.org/5.json.gz", expected: []string{"4.json.gz", "5.json.gz"}},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		// Make sure modification times differ
+		time.Sleep(10 * time.Millisecond)
+		if test.get != "" {
+			got, ok := cache.Get(test.get)
+			if !ok || !bytes.Equal(got, data) {
+				t.Errorf("test number %d, expected to get %s from cache", index+1, test.get)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err := cache.Put(test.put, data); err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+		}
+		got := []string{}
+		for _, file := range files {
+			got = append(got, file.Name())
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package devstats
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CalculatorPrefix - metric's SQL argument with this prefix names a registered Go calculator instead of SQL file
+const CalculatorPrefix = "calc:"
+
+// CalculatorRow - row computed by metric calculator, like a row returned by metric's SQL
+// `Name` is the first column (like "prefix,row_name"), "" for calculators returning a single value
+type CalculatorRow struct {
+	Name   string
+	Values []float64
+}
+
+// Calculator - Go function computing metric's rows in [from, to) from project's database, for logic that is awkward in SQL
+// Its queries should use `qctx` (metric's timeout) and can use `macros` (like "exclude_bots"), see ExpandMacros
+type Calculator func(qctx context.Context, con *sql.DB, ctx *Ctx, macros Macros, from, to time.Time) ([]CalculatorRow, error)
+
+// calculators - registered calculators by name
+var (
+	calculators    = make(map[string]Calculator)
+	calculatorsMtx sync.Mutex
+)
+
+func init() {
+	RegisterCalculator("bus_factor", busFactor)
+}
+
+// RegisterCalculator makes calculator available to metrics as `name`, it panics when the name is already registered
+func RegisterCalculator(name string, calc Calculator) {
+	calculatorsMtx.Lock()
+	defer calculatorsMtx.Unlock()
+	if _, ok := calculators[name]; ok {
+		panic("calculator '" + name + "' is already registered")
+	}
+	calculators[name] = calc
+}
+
+// GetCalculator returns calculator registered as `name`
+func GetCalculator(name string) (Calculator, error) {
+	calculatorsMtx.Lock()
+	defer calculatorsMtx.Unlock()
+	calc, ok := calculators[name]
+	if !ok {
+		names := []string{}
+		for name := range calculators {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown calculator '%s', registered: %v", name, names)
+	}
+	return calc, nil
+}
+
+// Strings returns row as nullable strings, the way metric's SQL rows are processed
+func (r CalculatorRow) Strings() []*string {
+	row := []*string{}
+	if r.Name != "" {
+		name := r.Name
+		row = append(row, &name)
+	}
+	for _, value := range r.Values {
+		s := strconv.FormatFloat(value, 'f', -1, 64)
+		row = append(row, &s)
+	}
+	return row
+}
+
+// BusFactor returns the smallest number of authors who together made at least half of all commits, `counts` are their commits
+// Returns 0 when there are no commits
+func BusFactor(counts []int) int {
+	sorted := append([]int{}, counts...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	total := 0
+	for _, count := range sorted {
+		total += count
+	}
+	sum := 0
+	for i, count := range sorted {
+		sum += count
+		if 2*sum >= total {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// busFactor computes bus factor (see BusFactor) of commits in [from, to), bots are excluded
+// Returns "bus_factor,All" row and "bus_factor,group" rows for every repo group, use it with `multi_row_single_column`
+func busFactor(qctx context.Context, con *sql.DB, ctx *Ctx, macros Macros, from, to time.Time) ([]CalculatorRow, error) {
+	query, err := ExpandMacros(
+		"select coalesce(r.repo_group, ''), c.dup_actor_login, count(distinct c.sha) "+
+			"from gha_commits c, gha_repos r "+
+			"where c.dup_repo_id = r.id and c.dup_created_at >= "+NValue(1)+" and c.dup_created_at < "+NValue(2)+" "+
+			"and (c.dup_actor_login {{exclude_bots}}) "+
+			"group by 1, 2",
+		macros,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := QuerySQLContext(qctx, con, ctx, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	groups := make(map[string]map[string]int)
+	all := make(map[string]int)
+	var (
+		group, login string
+		commits      int
+	)
+	for rows.Next() {
+		err = rows.Scan(&group, &login, &commits)
+		if err != nil {
+			return nil, err
+		}
+		all[login] += commits
+		if group == "" {
+			continue
+		}
+		if _, ok := groups[group]; !ok {
+			groups[group] = make(map[string]int)
+		}
+		groups[group][login] += commits
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	counts := func(authors map[string]int) (result []int) {
+		for _, commits := range authors {
+			result = append(result, commits)
+		}
+		return
+	}
+	result := []CalculatorRow{{Name: "bus_factor,All", Values: []float64{float64(BusFactor(counts(all)))}}}
+	names := []string{}
+	for group := range groups {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+	for _, group := range names {
+		result = append(result, CalculatorRow{Name: "bus_factor," + group, Values: []float64{float64(BusFactor(counts(groups[group])))}})
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package devstats
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestBusFactor(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		counts   []int
+		expected int
+	}{
+		{counts: nil, expected: 0},
+		{counts: []int{5}, expected: 1},
+		{counts: []int{1, 1, 1, 1}, expected: 2},
+		{counts: []int{1, 8, 1, 1, 1}, expected: 1},
+		{counts: []int{3, 1, 4, 1, 5, 9, 2, 6}, expected: 3},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.BusFactor(test.counts)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %d, got %d", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestCalculators(t *testing.T) {
+	calc := func(qctx context.Context, con *sql.DB, ctx *lib.Ctx, macros lib.Macros, from, to time.Time) ([]lib.CalculatorRow, error) {
+		return []lib.CalculatorRow{{Values: []float64{to.Sub(from).Hours()}}}, nil
+	}
+	lib.RegisterCalculator("test_hours", calc)
+	got, err := lib.GetCalculator("test_hours")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows, err := got(context.Background(), nil, nil, nil, time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(rows) != 1 || rows[0].Values[0] != 24 {
+		t.Errorf("expected 24 hours, got %+v, %v", rows, err)
+	}
+	if _, err := lib.GetCalculator("bus_factor"); err != nil {
+		t.Errorf("bus_factor calculator is not registered: %v", err)
+	}
+	if _, err := lib.GetCalculator("unknown"); err == nil {
+		t.Errorf("expected error for unknown calculator")
+	}
+
+	// Registering the same name again panics
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected panic when registering calculator twice")
+			}
+		}()
+		lib.RegisterCalculator("test_hours", calc)
+	}()
+}
+
+func TestCalculatorRowStrings(t *testing.T) {
+	s := func(values ...string) (row []*string) {
+		for _, value := range values {
+			v := value
+			row = append(row, &v)
+		}
+		return
+	}
+
+	// Test cases
+	var testCases = []struct {
+		row      lib.CalculatorRow
+		expected []*string
+	}{
+		{row: lib.CalculatorRow{Values: []float64{2.5}}, expected: s("2.5")},
+		{row: lib.CalculatorRow{Name: "bus_factor,All", Values: []float64{3}}, expected: s("bus_factor,All", "3")},
+		{row: lib.CalculatorRow{Name: "a;b;x,y", Values: []float64{1, 0.25}}, expected: s("a;b;x,y", "1", "0.25")},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := test.row.Strings()
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package devstats
+
+import (
+	"time"
+)
+
+// Catch-up batches: the range is split into catchUpParts batches, each of them from catchUpMinBatch to catchUpMaxBatch long
+const (
+	catchUpParts    = 8
+	catchUpMinBatch = 6 * time.Hour
+	catchUpMaxBatch = 7 * 24 * time.Hour
+)
+
+// CatchUpBatch - GHA hours ingested by a single `gha2db` call in catch-up mode (both From and To hours are included)
+type CatchUpBatch struct {
+	From time.Time
+	To   time.Time
+}
+
+// CatchUpBatches splits GHA hours `from` - `to` (both included) into batches `gha2db_sync` ingests one by one after downtime
+// The further behind the project is, the bigger batches are, so a long outage doesn't need too many `gha2db` runs
+// and a failure in a short one doesn't lose much
+func CatchUpBatches(from, to time.Time) []CatchUpBatch {
+	from, to = HourStart(from), HourStart(to)
+	if to.Before(from) {
+		return []CatchUpBatch{}
+	}
+	hours := int64(to.Sub(from)/time.Hour) + 1
+	size := time.Duration((hours+catchUpParts-1)/catchUpParts) * time.Hour
+	if size < catchUpMinBatch {
+		size = catchUpMinBatch
+	}
+	if size > catchUpMaxBatch {
+		size = catchUpMaxBatch
+	}
+	batches := []CatchUpBatch{}
+	for dt := from; !dt.After(to); dt = dt.Add(size) {
+		end := dt.Add(size - time.Hour)
+		if end.After(to) {
+			end = to
+		}
+		batches = append(batches, CatchUpBatch{From: dt, To: end})
+	}
+	return batches
+}
@@ -0,0 +1,63 @@
+package devstats
+
+import (
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestCatchUpBatches(t *testing.T) {
+	ft := func(day, hour int) time.Time { return time.Date(2017, 8, day, hour, 0, 0, 0, time.UTC) }
+
+	// Test cases
+	var testCases = []struct {
+		from      time.Time
+		to        time.Time
+		batches   int
+		firstTo   time.Time
+		lastFrom  time.Time
+		sizeHours int
+	}{
+		{from: ft(2, 10), to: ft(2, 9), batches: 0},
+		{from: ft(2, 10), to: ft(2, 10), batches: 1, firstTo: ft(2, 10), lastFrom: ft(2, 10)},
+		{from: ft(2, 10).Add(5 * time.Minute), to: ft(2, 20).Add(30 * time.Minute), batches: 2, firstTo: ft(2, 15), lastFrom: ft(2, 16), sizeHours: 6},
+		{from: ft(1, 0), to: ft(4, 23), batches: 8, firstTo: ft(1, 11), lastFrom: ft(4, 12), sizeHours: 12},
+		{from: ft(1, 0), to: ft(31, 23), batches: 8, firstTo: ft(4, 20), lastFrom: ft(28, 3), sizeHours: 93},
+		{
+			from:      ft(1, 0),
+			to:        time.Date(2017, 9, 30, 23, 0, 0, 0, time.UTC),
+			batches:   9,
+			firstTo:   ft(7, 23),
+			lastFrom:  time.Date(2017, 9, 26, 0, 0, 0, 0, time.UTC),
+			sizeHours: 168,
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.CatchUpBatches(test.from, test.to)
+		if len(got) != test.batches {
+			t.Errorf("test number %d, expected %d batches, got %d: %+v", index+1, test.batches, len(got), got)
+			continue
+		}
+		if test.batches == 0 {
+			continue
+		}
+		if !got[0].From.Equal(lib.HourStart(test.from)) || !got[0].To.Equal(test.firstTo) {
+			t.Errorf("test number %d, expected first batch %v - %v, got %+v", index+1, lib.HourStart(test.from), test.firstTo, got[0])
+		}
+		last := got[len(got)-1]
+		if !last.From.Equal(test.lastFrom) || !last.To.Equal(lib.HourStart(test.to)) {
+			t.Errorf("test number %d, expected last batch %v - %v, got %+v", index+1, test.lastFrom, lib.HourStart(test.to), last)
+		}
+		// Batches are contiguous
+		for i := 1; i < len(got); i++ {
+			if !got[i].From.Equal(got[i-1].To.Add(time.Hour)) {
+				t.Errorf("test number %d, batch %d doesn't follow batch %d: %+v", index+1, i, i-1, got)
+			}
+		}
+		if test.sizeHours > 0 && got[0].To.Sub(got[0].From) != time.Duration(test.sizeHours-1)*time.Hour {
+			t.Errorf("test number %d, expected batches of %d hours, got %+v", index+1, test.sizeHours, got[0])
+		}
+	}
+}
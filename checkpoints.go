@@ -0,0 +1,66 @@
+package devstats
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HourRange - continuous range of GHA hours, both ends included
+type HourRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Hours returns number of hours in the range
+func (r HourRange) Hours() int {
+	return int(r.To.Sub(r.From)/time.Hour) + 1
+}
+
+// CheckpointKey - orgs/repos filter identifying `gha_checkpoints` rows, the same hour can be ingested with different filters
+func CheckpointKey(forg, frepo map[string]struct{}) string {
+	return strings.Join(StringsSetKeys(forg), ",") + "|" + strings.Join(StringsSetKeys(frepo), ",")
+}
+
+// GerritCheckpointKey - Gerrit changes of GitHub mirrors are ingested with the same orgs/repos filter as GHA events, so they need own checkpoints
+func GerritCheckpointKey(forg, frepo map[string]struct{}) string {
+	return OriginGerrit + ":" + CheckpointKey(forg, frepo)
+}
+
+// FinishedHours returns hours (unix timestamps) already ingested for a given orgs/repos filter
+func FinishedHours(con *sql.DB, ctx *Ctx, key string) map[int64]bool {
+	rows := QuerySQLWithErr(
+		con,
+		ctx,
+		fmt.Sprintf("select dt from gha_checkpoints where orgs_repos = %s and finished is not null", NValue(1)),
+		key,
+	)
+	defer func() { FatalOnError(rows.Close()) }()
+	finished := make(map[int64]bool)
+	var dt time.Time
+	for rows.Next() {
+		FatalOnError(rows.Scan(&dt))
+		finished[dt.Unix()] = true
+	}
+	FatalOnError(rows.Err())
+	return finished
+}
+
+// MissingHours returns continuous ranges of hours between `from` and `to` (both included) that are not `finished`
+func MissingHours(from, to time.Time, finished map[int64]bool) (ranges []HourRange) {
+	var current *HourRange
+	for dt := HourStart(from); !dt.After(to); dt = dt.Add(time.Hour) {
+		if finished[dt.Unix()] {
+			current = nil
+			continue
+		}
+		if current == nil {
+			ranges = append(ranges, HourRange{From: dt, To: dt})
+			current = &ranges[len(ranges)-1]
+			continue
+		}
+		current.To = dt
+	}
+	return
+}
@@ -0,0 +1,110 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestCheckpointKey(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		orgs     []string
+		repos    []string
+		expected string
+	}{
+		{orgs: nil, repos: nil, expected: "|"},
+		{orgs: []string{"kubernetes"}, repos: nil, expected: "kubernetes|"},
+		{orgs: []string{"kubernetes-helm", "kubernetes", "kubernetes-client"}, repos: nil, expected: "kubernetes,kubernetes-client,kubernetes-helm|"},
+		{orgs: []string{"cncf"}, repos: []string{"linkerd", "fluent/fluentd"}, expected: "cncf|fluent/fluentd,linkerd"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		var forg, frepo map[string]struct{}
+		if test.orgs != nil {
+			forg = lib.StringsMapToSet(func(x string) string { return x }, test.orgs)
+		}
+		if test.repos != nil {
+			frepo = lib.StringsMapToSet(func(x string) string { return x }, test.repos)
+		}
+		got := lib.CheckpointKey(forg, frepo)
+		if got != test.expected {
+			t.Errorf("test number %d, expected '%s', got '%s'", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestMissingHours(t *testing.T) {
+	hour := func(h int) time.Time {
+		return time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(h) * time.Hour)
+	}
+	finished := func(hours ...int) map[int64]bool {
+		m := make(map[int64]bool)
+		for _, h := range hours {
+			m[hour(h).Unix()] = true
+		}
+		return m
+	}
+
+	// Test cases
+	var testCases = []struct {
+		from     time.Time
+		to       time.Time
+		finished map[int64]bool
+		expected []lib.HourRange
+	}{
+		{
+			from:     hour(0),
+			to:       hour(3),
+			finished: finished(),
+			expected: []lib.HourRange{{From: hour(0), To: hour(3)}},
+		},
+		{
+			from:     hour(0),
+			to:       hour(3),
+			finished: finished(0, 1, 2, 3),
+			expected: nil,
+		},
+		{
+			from:     hour(0),
+			to:       hour(5),
+			finished: finished(0, 2, 3),
+			expected: []lib.HourRange{{From: hour(1), To: hour(1)}, {From: hour(4), To: hour(5)}},
+		},
+		{
+			from:     hour(0),
+			to:       hour(2),
+			finished: finished(1, 7),
+			expected: []lib.HourRange{{From: hour(0), To: hour(0)}, {From: hour(2), To: hour(2)}},
+		},
+		{
+			from:     hour(0).Add(30 * time.Minute),
+			to:       hour(1),
+			finished: finished(),
+			expected: []lib.HourRange{{From: hour(0), To: hour(1)}},
+		},
+		{
+			from:     hour(3),
+			to:       hour(2),
+			finished: finished(),
+			expected: nil,
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.MissingHours(test.from, test.to, test.finished)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %+v, got %+v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestHourRangeHours(t *testing.T) {
+	from := time.Date(2017, 8, 1, 10, 0, 0, 0, time.UTC)
+	got := lib.HourRange{From: from, To: from.Add(23 * time.Hour)}.Hours()
+	if got != 24 {
+		t.Errorf("expected 24 hours, got %d", got)
+	}
+}
@@ -1,17 +1,24 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	lib "devstats"
 
 	client "github.com/influxdata/influxdb/client/v2"
+	yaml "gopkg.in/yaml.v2"
 )
 
 // valueDescription - return string description for given float value
@@ -142,10 +149,264 @@ func roundF2I(val float64) int {
 	return int(val + 0.5)
 }
 
-func workerThread(ch chan bool, ctx *lib.Ctx, seriesNameOrFunc, sqlQuery, excludeBots, period, desc string, multivalue, escapeValueName bool, nIntervals int, dt, from, to time.Time) {
+// querySQL executes metric's SQL query, it is cancelled when metric's timeout (`qctx` deadline) passes
+// Queries that only read run on a replica that is up to date (PG_REPLICAS) when there is one, ones that write
+// anything (like metrics creating temporary tables) run on primary
+func querySQL(qctx context.Context, ctx *lib.Ctx, sqlQuery string) *sql.Rows {
+	sqlc := lib.PgQueryConn(ctx, sqlQuery)
+	if _, ok := qctx.Deadline(); !ok {
+		return lib.QuerySQLWithErr(sqlc, ctx, sqlQuery)
+	}
+	rows, err := lib.QuerySQLContext(qctx, sqlc, ctx, sqlQuery)
+	checkTimeout(qctx, err)
+	lib.FatalOnError(err)
+	return rows
+}
+
+// checkTimeout exits with lib.MetricTimeoutExitCode when query failed because metric's timeout passed
+// Caller (`gha2db_sync`) records the timeout and continues with the next metric
+func checkTimeout(qctx context.Context, err error) {
+	if err != nil && qctx.Err() == context.DeadlineExceeded {
+		lib.Printf("Metric query timed out: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Metric query timed out: %v\n", err)
+		os.Exit(lib.MetricTimeoutExitCode)
+	}
+}
+
+// acquireDB takes database's query slot (GHA2DB_DB_PARALLEL), time spent waiting for it counts into metric's timeout
+func acquireDB(qctx context.Context, ctx *lib.Ctx) func() {
+	release, err := lib.DBSemaphore(ctx).Acquire(qctx)
+	checkTimeout(qctx, err)
+	lib.FatalOnError(err)
+	return release
+}
+
+// metricCache - how `db2influx` uses `gha_metric_cache`
+type metricCache struct {
+	read      bool          // Use cached results, false saves new results only (GHA2DB_RESETIDB, GHA2DB_FORCE_COMPUTE)
+	lastEvent time.Time     // Periods ending before the last event are closed, their cached results never expire
+	ttl       time.Duration // How long cached result of the still open period is used (GHA2DB_METRIC_CACHE_TTL)
+}
+
+// newMetricCache returns metric cache settings, nil when the cache is disabled or database has no `gha_metric_cache` table yet
+func newMetricCache(ctx *lib.Ctx) *metricCache {
+	if ctx.SkipMetricCache {
+		return nil
+	}
+	sqlc := lib.PgConn(ctx)
+	var (
+		lastEvent *time.Time
+		n         int
+	)
+	err := lib.QueryRowSQL(sqlc, ctx, "select count(*) from (select 1 from gha_metric_cache limit 1) c").Scan(&n)
+	if err != nil {
+		lib.Printf("Metric cache is not used: %v\n", err)
+		return nil
+	}
+	lib.FatalOnError(lib.QueryRowSQL(sqlc, ctx, "select max(created_at) from gha_events").Scan(&lastEvent))
+	cache := &metricCache{read: !ctx.ResetIDB && !ctx.ForceCompute, ttl: time.Duration(ctx.MetricCacheTTL) * time.Second}
+	if lastEvent != nil {
+		cache.lastEvent = *lastEvent
+	}
+	return cache
+}
+
+// parseValue returns numeric value of a result column, NULL is 0
+func parseValue(pValue *string) float64 {
+	if pValue == nil {
+		return 0
+	}
+	value, _ := strconv.ParseFloat(*pValue, 64)
+	return value
+}
+
+// metricRows calls `onRow` for every row of query result and returns number of columns and rows, NULL values are nil
+// Result is taken from `cache` when it has a valid one, otherwise rows are streamed from the query and saved there
+// (unless there are more than IDB_MAXMEMPOINTS of them). Cache failures are only logged, the query is executed (or its result is not cached) then
+// Executed query that is slower than GHA2DB_EXPLAIN_SLOW is explained and saved as `sqlName`
+func metricRows(qctx context.Context, sqlc *sql.DB, ctx *lib.Ctx, cache *metricCache, sqlName, sqlQuery, period string, from, to time.Time, onRow func([]*string)) (int, int) {
+	key := lib.QueryHash(sqlQuery)
+	if cache != nil && cache.read {
+		res, err := lib.GetMetricCache(sqlc, ctx, key, period, from, to)
+		if err != nil {
+			lib.Printf("Cannot read metric cache: %v\n", err)
+		} else if res != nil {
+			if ctx.Debug > 0 {
+				lib.Printf("%v - %v: cached result, %d rows\n", from, to, len(res.Rows))
+			}
+			for _, row := range res.Rows {
+				onRow(row)
+			}
+			return res.Columns, len(res.Rows)
+		}
+	}
+
+	// Execute SQL query, when GHA2DB_DB_PARALLEL queries already run against this database wait for one of them
+	release := acquireDB(qctx, ctx)
+	dtStart := time.Now()
+	rows := querySQL(qctx, ctx, sqlQuery)
+	columns, err := rows.Columns()
+	lib.FatalOnError(err)
+	var res *lib.MetricCacheResult
+	if cache != nil && !ctx.SkipIDB {
+		res = &lib.MetricCacheResult{Columns: len(columns), Rows: [][]*string{}}
+	}
+	pValues := make([]interface{}, len(columns))
+	for i := range columns {
+		pValues[i] = new(sql.RawBytes)
+	}
+	rowCount := 0
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(pValues...))
+		row := make([]*string, len(columns))
+		for i, pValue := range pValues {
+			// RawBytes are only valid until next row, NULL is nil
+			if raw := *pValue.(*sql.RawBytes); raw != nil {
+				value := string(raw)
+				row[i] = &value
+			}
+		}
+		onRow(row)
+		rowCount++
+		if res != nil {
+			if ctx.IDBMaxMemPoints > 0 && rowCount > ctx.IDBMaxMemPoints {
+				lib.Printf("%v - %v: more than %d rows, result is not cached\n", from, to, ctx.IDBMaxMemPoints)
+				res = nil
+				continue
+			}
+			res.Rows = append(res.Rows, row)
+		}
+	}
+	checkTimeout(qctx, rows.Err())
+	lib.FatalOnError(rows.Err())
+	lib.FatalOnError(rows.Close())
+	lib.ExplainIfSlow(sqlc, ctx, "db2influx", sqlName, sqlQuery, time.Now().Sub(dtStart))
+	release()
+
+	if res != nil {
+		if expires, ok := lib.MetricCacheExpires(to, cache.lastEvent, time.Now(), cache.ttl); ok {
+			err = lib.SetMetricCache(sqlc, ctx, key, period, from, to, res, expires)
+			if err != nil {
+				lib.Printf("Cannot save metric cache: %v\n", err)
+			}
+		}
+	}
+	return len(columns), rowCount
+}
+
+// aggregation - cross-project metric: its query runs in every enabled project's database, results are merged with `merge`
+type aggregation struct {
+	dbs   []string
+	merge string
+}
+
+// aggregateRows executes query in all aggregated databases in parallel and calls `onRow` for every merged row
+// Returns number of columns and merged rows like metricRows, results are not cached
+func aggregateRows(qctx context.Context, ctx *lib.Ctx, agg *aggregation, sqlName, sqlQuery, period string, from, to time.Time, onRow func([]*string)) (int, int) {
+	merger, err := lib.NewRowsMerger(agg.merge)
+	lib.FatalOnError(err)
+	var wg sync.WaitGroup
+	for _, db := range agg.dbs {
+		wg.Add(1)
+		go func(db string) {
+			defer wg.Done()
+			// Project's context differs in database only, so its GHA2DB_DB_PARALLEL slots are used
+			pctx := *ctx
+			pctx.PgDB = db
+			sqlc := lib.PgConn(&pctx)
+			metricRows(qctx, sqlc, &pctx, nil, sqlName, sqlQuery, period, from, to, func(row []*string) {
+				if err := merger.Add(row); err != nil {
+					lib.FatalOnError(fmt.Errorf("%s: %v", db, err))
+				}
+			})
+		}(db)
+	}
+	wg.Wait()
+	rows := merger.Rows()
+	if ctx.Debug > 0 {
+		lib.Printf("%v - %v: %d rows merged from %d databases\n", from, to, len(rows), len(agg.dbs))
+	}
+	for _, row := range rows {
+		onRow(row)
+	}
+	return merger.Columns(), len(rows)
+}
+
+// calculator - metric computed by registered Go calculator (lib.CalculatorPrefix) instead of SQL
+type calculator struct {
+	name   string
+	calc   lib.Calculator
+	macros lib.Macros
+}
+
+// readCalculator returns calculator registered as `name` with macros of the current project (see lib.ReadMacros)
+func readCalculator(ctx *lib.Ctx, dataPrefix, name string) *calculator {
+	calc, err := lib.GetCalculator(name)
+	lib.FatalOnError(err)
+	metricsDir := dataPrefix + "metrics"
+	if ctx.Project != "" {
+		metricsDir += "/" + ctx.Project
+	}
+	macros, err := lib.ReadMacros(dataPrefix, filepath.Join(metricsDir, name+".sql"))
+	lib.FatalOnError(err)
+	return &calculator{name: name, calc: calc, macros: macros}
+}
+
+// calculatorRows computes period with `calc` and calls `onRow` for every row, returns number of columns and rows like metricRows
+// Calculator's queries take one query slot (GHA2DB_DB_PARALLEL), its results are not cached
+func calculatorRows(qctx context.Context, sqlc *sql.DB, ctx *lib.Ctx, calc *calculator, from, to time.Time, onRow func([]*string)) (int, int) {
+	release := acquireDB(qctx, ctx)
+	dtStart := time.Now()
+	rows, err := calc.calc(qctx, sqlc, ctx, calc.macros, from, to)
+	release()
+	checkTimeout(qctx, err)
+	if err != nil {
+		lib.FatalOnError(fmt.Errorf("calculator %s: %v", calc.name, err))
+	}
+	if ctx.Debug > 0 {
+		lib.Printf("%v - %v: calculator %s returned %d rows in %v\n", from, to, calc.name, len(rows), time.Now().Sub(dtStart))
+	}
+	nColumns := 0
+	for _, row := range rows {
+		values := row.Strings()
+		if nColumns == 0 {
+			nColumns = len(values)
+		} else if len(values) != nColumns {
+			lib.FatalOnError(fmt.Errorf("calculator %s returned rows with %d and %d columns", calc.name, nColumns, len(values)))
+		}
+		onRow(values)
+	}
+	return nColumns, len(rows)
+}
+
+// readAggregateDBs returns databases of all enabled projects from projects.yaml (GHA2DB_PROJECTS_YAML), except the current one
+func readAggregateDBs(ctx *lib.Ctx, dataPrefix string) []string {
+	data, err := ioutil.ReadFile(dataPrefix + ctx.ProjectsYaml)
+	lib.FatalOnError(err)
+	var projects lib.AllProjects
+	lib.FatalOnError(yaml.Unmarshal(data, &projects))
+	dbs := lib.AggregateDBs(&projects, ctx.PgDB)
+	if len(dbs) == 0 {
+		lib.FatalOnError(fmt.Errorf("no enabled projects' databases to aggregate in %s", ctx.ProjectsYaml))
+	}
+	return dbs
+}
+
+// prepareQuery substitutes period's parameters in metric's SQL
+func prepareQuery(sqlQuery string, nIntervals int, from, to time.Time) string {
+	sqlQuery = strings.Replace(sqlQuery, "{{from}}", lib.ToYMDHMSDate(from), -1)
+	sqlQuery = strings.Replace(sqlQuery, "{{to}}", lib.ToYMDHMSDate(to), -1)
+	return strings.Replace(sqlQuery, "{{n}}", strconv.Itoa(nIntervals)+".0", -1)
+}
+
+// workerThread computes metric for a single period, adds number of rows returned to `nRows`
+// Query result is taken from `cache` when it has one (nil - cache is not used), `sqlName` identifies metric's SQL in gha_slow_queries
+// Names of written series are added to `written` (nil - they are not needed), points are checked by `valid` (nil - not checked)
+// With `agg` (nil - none) query runs in all aggregated projects' databases and their merged results are written
+// With `calc` (nil - none) rows are computed by Go calculator instead of the query
+func workerThread(ch chan bool, qctx context.Context, ctx *lib.Ctx, cache *metricCache, agg *aggregation, calc *calculator, written *seriesSet, valid *validator, sqlName, seriesNameOrFunc, sqlQuery, period, desc string, multivalue, escapeValueName bool, nIntervals int, dt, from, to time.Time, nRows *int64) {
 	// Connect to Postgres DB
 	sqlc := lib.PgConn(ctx)
-	defer func() { lib.FatalOnError(sqlc.Close()) }()
 
 	// Connect to InfluxDB
 	ic := lib.IDBConn(ctx)
@@ -158,41 +419,84 @@ func workerThread(ch chan bool, ctx *lib.Ctx, seriesNameOrFunc, sqlQuery, exclud
 	pts.Points = &bp
 
 	// Prepare SQL query
-	sFrom := lib.ToYMDHMSDate(from)
-	sTo := lib.ToYMDHMSDate(to)
-	sqlQuery = strings.Replace(sqlQuery, "{{from}}", sFrom, -1)
-	sqlQuery = strings.Replace(sqlQuery, "{{to}}", sTo, -1)
-	sqlQuery = strings.Replace(sqlQuery, "{{n}}", strconv.Itoa(nIntervals)+".0", -1)
-	sqlQuery = strings.Replace(sqlQuery, "{{exclude_bots}}", excludeBots, -1)
-
-	// Execute SQL query
-	rows := lib.QuerySQLWithErr(sqlc, ctx, sqlQuery)
-	defer func() { lib.FatalOnError(rows.Close()) }()
-
-	// Get Number of columns
-	// We support either query returnign single row with single numeric value
-	// Or multiple rows, each containing string (series name) and its numeric value(s)
-	columns, err := rows.Columns()
-	lib.FatalOnError(err)
-	nColumns := len(columns)
+	sqlQuery = prepareQuery(sqlQuery, nIntervals, from, to)
 
 	// Use value descriptions?
 	useDesc := desc != ""
 
 	// Metric Results, assume they're floats
+	// We support either query returnign single row with single numeric value
+	// Or multiple rows, each containing string (series name) and its numeric value(s)
+	// Rows are streamed from query (or cache) to InfluxDB points, only multivalue series are kept until all rows are read
 	var (
-		pValue *float64
-		value  float64
-		name   string
+		value     float64
+		name      string
+		lastValue *string
 	)
-	// Single row & single column result
-	if nColumns == 1 {
-		rowCount := 0
-		for rows.Next() {
-			lib.FatalOnError(rows.Scan(&pValue))
-			rowCount++
+	allFields := make(map[string]map[string]interface{})
+	onRow := func(row []*string) {
+		// Single row & single column result
+		if len(row) == 1 {
+			lastValue = row[0]
+			return
 		}
-		lib.FatalOnError(rows.Err())
+		// Multiple rows, each with (series name, value(s))
+		// Get first column name, and using it all series names
+		// First column should contain nColumns - 1 names separated by ","
+		name := ""
+		if row[0] != nil {
+			name = *row[0]
+		}
+		names := nameForMetricsRow(seriesNameOrFunc, name, period, multivalue, escapeValueName)
+		if len(names) == 0 {
+			return
+		}
+		// Iterate values
+		for idx, pVal := range row[1:] {
+			value = parseValue(pVal)
+			if multivalue {
+				nameArr := strings.Split(names[idx], ";")
+				seriesName := nameArr[0]
+				seriesValueName := nameArr[1]
+				if ctx.Debug > 0 {
+					lib.Printf("%v - %v -> %v: %v[%v], %v\n", from, to, idx, seriesName, seriesValueName, value)
+				}
+				if _, ok := allFields[seriesName]; !ok {
+					allFields[seriesName] = make(map[string]interface{})
+				}
+				allFields[seriesName][seriesValueName] = value
+			} else {
+				name = names[idx]
+				if ctx.Debug > 0 {
+					lib.Printf("%v - %v -> %v: %v, %v\n", from, to, idx, name, value)
+				}
+				// Add batch point
+				fields := map[string]interface{}{"value": value}
+				if useDesc {
+					fields["descr"] = valueDescription(desc, value)
+				}
+				if valid.allowed(sqlc, ic, ctx, name, fields, dt) {
+					pt := lib.IDBNewPointWithErr(name, nil, fields, dt)
+					lib.IDBAddPointN(ctx, &ic, &pts, pt)
+					written.add(name)
+				}
+			}
+		}
+	}
+
+	// Get result from cache or execute SQL query (in all aggregated databases), or compute it with calculator
+	var nColumns, rowCount int
+	switch {
+	case calc != nil:
+		nColumns, rowCount = calculatorRows(qctx, lib.PgReadConn(ctx), ctx, calc, from, to, onRow)
+	case agg != nil:
+		nColumns, rowCount = aggregateRows(qctx, ctx, agg, sqlName, sqlQuery, period, from, to, onRow)
+	default:
+		nColumns, rowCount = metricRows(qctx, sqlc, ctx, cache, sqlName, sqlQuery, period, from, to, onRow)
+	}
+	atomic.AddInt64(nRows, int64(rowCount))
+
+	if nColumns == 1 {
 		if rowCount != 1 {
 			lib.Printf(
 				"Error:\nQuery should return either single value or "+
@@ -202,9 +506,7 @@ func workerThread(ch chan bool, ctx *lib.Ctx, seriesNameOrFunc, sqlQuery, exclud
 			)
 		}
 		// Handle nulls
-		if pValue != nil {
-			value = *pValue
-		}
+		value = parseValue(lastValue)
 		// In this simplest case 1 row, 1 column - series name is taken directly from YAML (metrics.yaml)
 		// It usually uses `add_period_to_name: true` to have _period suffix, period{=h,d,w,m,q,y}
 		name = seriesNameOrFunc
@@ -216,70 +518,265 @@ func workerThread(ch chan bool, ctx *lib.Ctx, seriesNameOrFunc, sqlQuery, exclud
 		if useDesc {
 			fields["descr"] = valueDescription(desc, value)
 		}
-		pt := lib.IDBNewPointWithErr(name, nil, fields, dt)
-		lib.IDBAddPointN(ctx, &ic, &pts, pt)
-	} else if nColumns >= 2 {
-		// Multiple rows, each with (series name, value(s))
-		// Number of columns
-		columns, err := rows.Columns()
-		lib.FatalOnError(err)
-		nColumns := len(columns)
-		// Alocate nColumns numeric values (first is series name)
-		pValues := make([]interface{}, nColumns)
-		for i := range columns {
-			pValues[i] = new(sql.RawBytes)
+		if valid.allowed(sqlc, ic, ctx, name, fields, dt) {
+			pt := lib.IDBNewPointWithErr(name, nil, fields, dt)
+			lib.IDBAddPointN(ctx, &ic, &pts, pt)
+			written.add(name)
 		}
-		allFields := make(map[string]map[string]interface{})
-		for rows.Next() {
-			// Get row values
-			lib.FatalOnError(rows.Scan(pValues...))
-			// Get first column name, and using it all series names
-			// First column should contain nColumns - 1 names separated by ","
-			name := string(*pValues[0].(*sql.RawBytes))
-			names := nameForMetricsRow(seriesNameOrFunc, name, period, multivalue, escapeValueName)
-			if len(names) > 0 {
-				// Iterate values
-				pFloats := pValues[1:]
-				for idx, pVal := range pFloats {
-					if pVal != nil {
-						value, _ = strconv.ParseFloat(string(*pVal.(*sql.RawBytes)), 64)
-					} else {
-						value = 0.0
-					}
-					if multivalue {
-						nameArr := strings.Split(names[idx], ";")
-						seriesName := nameArr[0]
-						seriesValueName := nameArr[1]
-						if ctx.Debug > 0 {
-							lib.Printf("%v - %v -> %v: %v[%v], %v\n", from, to, idx, seriesName, seriesValueName, value)
-						}
-						if _, ok := allFields[seriesName]; !ok {
-							allFields[seriesName] = make(map[string]interface{})
-						}
-						allFields[seriesName][seriesValueName] = value
-					} else {
-						name = names[idx]
-						if ctx.Debug > 0 {
-							lib.Printf("%v - %v -> %v: %v, %v\n", from, to, idx, name, value)
-						}
-						// Add batch point
-						fields := map[string]interface{}{"value": value}
-						if useDesc {
-							fields["descr"] = valueDescription(desc, value)
-						}
-						pt := lib.IDBNewPointWithErr(name, nil, fields, dt)
-						lib.IDBAddPointN(ctx, &ic, &pts, pt)
-					}
-				}
+	}
+	// Multivalue series if any
+	for seriesName, seriesValues := range allFields {
+		if valid.allowed(sqlc, ic, ctx, seriesName, seriesValues, dt) {
+			pt := lib.IDBNewPointWithErr(seriesName, nil, seriesValues, dt)
+			lib.IDBAddPointN(ctx, &ic, &pts, pt)
+			written.add(seriesName)
+		}
+	}
+	// Write the batch
+	if !ctx.SkipIDB {
+		lib.FatalOnError(lib.IDBWritePointsN(ctx, &ic, &pts))
+	} else if ctx.Debug > 0 {
+		lib.Printf("Skipping series write\n")
+	}
+
+	// Synchronize go routine
+	if ch != nil {
+		ch <- true
+	}
+}
+
+// operand - derived metric's operand: SQL query or existing series (lib.DerivedSeriesPrefix)
+type operand struct {
+	series   string
+	sqlName  string
+	sqlQuery string
+}
+
+// readOperand returns operand given as SQL file name or "series:name"
+func readOperand(dataPrefix, arg string) operand {
+	if strings.HasPrefix(arg, lib.DerivedSeriesPrefix) {
+		return operand{series: arg[len(lib.DerivedSeriesPrefix):]}
+	}
+	return operand{sqlName: getPathIndependentKey(arg), sqlQuery: readSQL(dataPrefix, arg)}
+}
+
+// readSQL returns metric's SQL from `sqlFile` with macros expanded (util_sql/macros.yaml and project's macros.yaml)
+func readSQL(dataPrefix, sqlFile string) string {
+	bytes, err := ioutil.ReadFile(sqlFile)
+	lib.FatalOnError(err)
+	macros, err := lib.ReadMacros(dataPrefix, sqlFile)
+	lib.FatalOnError(err)
+	sqlQuery, err := lib.ExpandMacros(string(bytes), macros)
+	if err != nil {
+		lib.FatalOnError(fmt.Errorf("%s: %v", sqlFile, err))
+	}
+	return sqlQuery
+}
+
+// seriesValue returns value of existing `series` at `dt`, 0 when there is none
+func seriesValue(ic client.Client, ctx *lib.Ctx, series string, dt time.Time) float64 {
+	query := fmt.Sprintf("select value from \"%s\" where time = %d", series, dt.UnixNano())
+	res := lib.QueryIDB(ic, ctx, query)
+	if len(res) < 1 || len(res[0].Series) < 1 || len(res[0].Series[0].Values) < 1 {
+		return 0
+	}
+	number, ok := res[0].Series[0].Values[0][1].(json.Number)
+	if !ok {
+		return 0
+	}
+	value, _ := number.Float64()
+	return value
+}
+
+// operandValues returns operand's values in a single period by row name, "" for a single value (and for series)
+// SQL must return either single value or multiple rows, each containing name and value
+func operandValues(qctx context.Context, sqlc *sql.DB, ic client.Client, ctx *lib.Ctx, op operand, nIntervals int, dt, from, to time.Time, nRows *int64) map[string]float64 {
+	if op.series != "" {
+		return map[string]float64{"": seriesValue(ic, ctx, op.series, dt)}
+	}
+	sqlQuery := prepareQuery(op.sqlQuery, nIntervals, from, to)
+	release := acquireDB(qctx, ctx)
+	dtStart := time.Now()
+	rows := querySQL(qctx, ctx, sqlQuery)
+	defer func() {
+		lib.FatalOnError(rows.Close())
+		release()
+	}()
+	columns, err := rows.Columns()
+	lib.FatalOnError(err)
+	if len(columns) > 2 {
+		lib.FatalOnError(fmt.Errorf("derived metric's query should return single value or rows with name and value, got %d columns\nQuery:%s", len(columns), sqlQuery))
+	}
+	values := make(map[string]float64)
+	var (
+		name   string
+		pValue *float64
+	)
+	for rows.Next() {
+		if len(columns) == 1 {
+			lib.FatalOnError(rows.Scan(&pValue))
+			name = ""
+		} else {
+			lib.FatalOnError(rows.Scan(&name, &pValue))
+		}
+		// Handle nulls
+		if pValue != nil {
+			values[name] += *pValue
+		}
+	}
+	checkTimeout(qctx, rows.Err())
+	lib.FatalOnError(rows.Err())
+	lib.ExplainIfSlow(sqlc, ctx, "db2influx", op.sqlName, sqlQuery, time.Now().Sub(dtStart))
+	atomic.AddInt64(nRows, int64(len(values)))
+	return values
+}
+
+// derivedWorkerThread computes derived metric (`derived` operation of operands `a` and `b`) for a single period
+func derivedWorkerThread(ch chan bool, qctx context.Context, ctx *lib.Ctx, written *seriesSet, valid *validator, seriesNameOrFunc, derived string, a, b operand, period, desc string, nIntervals int, dt, from, to time.Time, nRows *int64) {
+	// Connect to Postgres DB
+	sqlc := lib.PgConn(ctx)
+
+	// Connect to InfluxDB
+	ic := lib.IDBConn(ctx)
+	defer func() { lib.FatalOnError(ic.Close()) }()
+
+	// Get BatchPoints
+	var pts lib.IDBBatchPointsN
+	bp := lib.IDBBatchPoints(ctx, &ic)
+	pts.NPoints = 0
+	pts.Points = &bp
+
+	// Operands are computed one after another, each takes its own query slot
+	aValues := operandValues(qctx, sqlc, ic, ctx, a, nIntervals, dt, from, to, nRows)
+	bValues := operandValues(qctx, sqlc, ic, ctx, b, nIntervals, dt, from, to, nRows)
+	values, err := lib.CombineDerived(derived, aValues, bValues)
+	lib.FatalOnError(err)
+	if _, single := values[""]; single && len(values) > 1 {
+		lib.FatalOnError(fmt.Errorf("derived metric's operands should both return single value or both return rows, got %v and %v", aValues, bValues))
+	}
+	for rowName, value := range values {
+		// Single value is saved as `series_name_or_func`, rows use it as function, like other metrics
+		name := seriesNameOrFunc
+		if rowName != "" {
+			names := nameForMetricsRow(seriesNameOrFunc, rowName, period, false, false)
+			if len(names) == 0 {
+				continue
 			}
+			name = names[0]
 		}
-		// Multivalue series if any
-		for seriesName, seriesValues := range allFields {
-			pt := lib.IDBNewPointWithErr(seriesName, nil, seriesValues, dt)
+		if ctx.Debug > 0 {
+			lib.Printf("%v - %v -> %v: %v %s %v = %v\n", from, to, name, aValues[rowName], derived, bValues[rowName], value)
+		}
+		fields := map[string]interface{}{"value": value}
+		if desc != "" {
+			fields["descr"] = valueDescription(desc, value)
+		}
+		if valid.allowed(sqlc, ic, ctx, name, fields, dt) {
+			pt := lib.IDBNewPointWithErr(name, nil, fields, dt)
 			lib.IDBAddPointN(ctx, &ic, &pts, pt)
+			written.add(name)
 		}
-		lib.FatalOnError(rows.Err())
 	}
+
+	// Write the batch
+	if !ctx.SkipIDB {
+		lib.FatalOnError(lib.IDBWritePointsN(ctx, &ic, &pts))
+	} else if ctx.Debug > 0 {
+		lib.Printf("Skipping series write\n")
+	}
+
+	// Synchronize go routine
+	if ch != nil {
+		ch <- true
+	}
+}
+
+// percentileWorkerThread computes percentiles of values (like durations) returned by metric's SQL for a single period
+// SQL returns rows with a single value or rows with name and value, percentiles are computed for every name then
+// Writes series `series_name_or_func`_pNN_period or (for rows with names) prefix_name_pNN_period
+func percentileWorkerThread(ch chan bool, qctx context.Context, ctx *lib.Ctx, written *seriesSet, valid *validator, sqlName, seriesNameOrFunc, sqlQuery, period, desc string, percentiles []float64, nIntervals int, dt, from, to time.Time, nRows *int64) {
+	// Connect to Postgres DB
+	sqlc := lib.PgConn(ctx)
+
+	// Connect to InfluxDB
+	ic := lib.IDBConn(ctx)
+	defer func() { lib.FatalOnError(ic.Close()) }()
+
+	// Get BatchPoints
+	var pts lib.IDBBatchPointsN
+	bp := lib.IDBBatchPoints(ctx, &ic)
+	pts.NPoints = 0
+	pts.Points = &bp
+
+	// Execute SQL query, when GHA2DB_DB_PARALLEL queries already run against this database wait for one of them
+	sqlQuery = prepareQuery(sqlQuery, nIntervals, from, to)
+	release := acquireDB(qctx, ctx)
+	defer release()
+	dtStart := time.Now()
+	rows := querySQL(qctx, ctx, sqlQuery)
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	columns, err := rows.Columns()
+	lib.FatalOnError(err)
+	nColumns := len(columns)
+	if nColumns > 2 {
+		lib.FatalOnError(fmt.Errorf("percentiles metric's query should return values or rows with name and value, got %d columns\nQuery:%s", nColumns, sqlQuery))
+	}
+
+	// Values by row name, "" when query returns values only (there is always a point then, 0 when no values)
+	values := make(map[string][]float64)
+	if nColumns == 1 {
+		values[""] = []float64{}
+	}
+	var (
+		name   string
+		pValue *float64
+	)
+	rowCount := 0
+	for rows.Next() {
+		if nColumns == 1 {
+			lib.FatalOnError(rows.Scan(&pValue))
+		} else {
+			lib.FatalOnError(rows.Scan(&name, &pValue))
+		}
+		rowCount++
+		// Skip nulls, they are not durations
+		if pValue != nil {
+			values[name] = append(values[name], *pValue)
+		}
+	}
+	checkTimeout(qctx, rows.Err())
+	lib.FatalOnError(rows.Err())
+	lib.ExplainIfSlow(sqlc, ctx, "db2influx", sqlName, sqlQuery, time.Now().Sub(dtStart))
+	atomic.AddInt64(nRows, int64(rowCount))
+
+	for rowName, rowValues := range values {
+		sort.Float64s(rowValues)
+		for _, p := range percentiles {
+			suffix := lib.PercentileSuffix(p) + "_" + period
+			name := seriesNameOrFunc + "_" + suffix
+			if rowName != "" {
+				names := multiRowSingleColumn(rowName, suffix, false, false)
+				if len(names) == 0 {
+					continue
+				}
+				name = names[0]
+			}
+			value := lib.Percentile(rowValues, p)
+			if ctx.Debug > 0 {
+				lib.Printf("%v - %v -> %v: %v (%d values)\n", from, to, name, value, len(rowValues))
+			}
+			fields := map[string]interface{}{"value": value}
+			if desc != "" {
+				fields["descr"] = valueDescription(desc, value)
+			}
+			if valid.allowed(sqlc, ic, ctx, name, fields, dt) {
+				pt := lib.IDBNewPointWithErr(name, nil, fields, dt)
+				lib.IDBAddPointN(ctx, &ic, &pts, pt)
+				written.add(name)
+			}
+		}
+	}
+
 	// Write the batch
 	if !ctx.SkipIDB {
 		lib.FatalOnError(lib.IDBWritePointsN(ctx, &ic, &pts))
@@ -345,10 +842,51 @@ func setAlreadyComputed(ic client.Client, ctx *lib.Ctx, pts *lib.IDBBatchPointsN
 	}
 }
 
-func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, excludeBots, interval, intervalAbbr string, nIntervals int, annotationsRanges, skipPast bool) {
+// histogramKey - key of histogram in `computed_hash` series, the same SQL can be used for many series and periods
+func histogramKey(seriesNameOrFunc, sqlFile, intervalAbbr string) string {
+	return getPathIndependentKey(sqlFile) + ";" + seriesNameOrFunc + ";" + intervalAbbr
+}
+
+// histogramHash returns hash of final histogram query and the last event date, result cannot change while it is the same
+func histogramHash(sqlc *sql.DB, ctx *lib.Ctx, seriesNameOrFunc, sqlQuery string) string {
+	var lastEvent *time.Time
+	lib.FatalOnError(lib.QueryRowSQL(sqlc, ctx, "select max(created_at) from gha_events").Scan(&lastEvent))
+	last := ""
+	if lastEvent != nil {
+		last = lib.ToYMDHMSDate(*lastEvent)
+	}
+	return lib.QueryHash(sqlQuery, seriesNameOrFunc, last)
+}
+
+// getComputedHash returns hash saved when given histogram was computed the last time (or empty string)
+func getComputedHash(ic client.Client, ctx *lib.Ctx, key string) string {
+	query := fmt.Sprintf("select last(hash) from computed_hash where computed_key = '%s'", key)
+	res := lib.QueryIDB(ic, ctx, query)
+	if len(res) < 1 || len(res[0].Series) < 1 || len(res[0].Series[0].Values) < 1 {
+		return ""
+	}
+	hash, _ := res[0].Series[0].Values[0][1].(string)
+	return hash
+}
+
+// setComputedHash saves hash of computed histogram
+// All points have the same timestamp, so the new point replaces the previous one
+// Should be called inside: if !ctx.SkipIDB { ... }
+func setComputedHash(ic client.Client, ctx *lib.Ctx, pts *lib.IDBBatchPointsN, key, hash string) {
+	fields := map[string]interface{}{"hash": hash}
+	tags := map[string]string{"computed_key": key}
+	pt := lib.IDBNewPointWithErr("computed_hash", tags, fields, time.Unix(0, 0))
+	lib.IDBAddPointN(ctx, &ic, pts, pt)
+	if ctx.Debug > 0 {
+		lib.Printf("Histogram '%s' computed with hash %s\n", key, hash)
+	}
+}
+
+// db2influxHistogram computes histogram metric, returns number of rows returned and false when it was skipped
+// `twoDim` histogram has a secondary dimension (column) for every name
+func db2influxHistogram(qctx context.Context, ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, interval, intervalAbbr string, nIntervals int, annotationsRanges, skipPast, twoDim bool) (int64, bool) {
 	// Connect to Postgres DB
 	sqlc := lib.PgConn(ctx)
-	defer func() { lib.FatalOnError(sqlc.Close()) }()
 
 	// Connect to InfluxDB
 	ic := lib.IDBConn(ctx)
@@ -384,11 +922,10 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 					prevHour := lib.PrevHourStart(time.Now())
 					if dtTo.Before(prevHour) && isAlreadyComputed(ic, ctx, sqlFile, from) {
 						lib.Printf("Skipping past quick range: %v (already computed)\n", from)
-						return
+						return 0, false
 					}
 				}
 				sqlQuery = lib.PrepareQuickRangeQuery(sqlQuery, period, from, to)
-				sqlQuery = strings.Replace(sqlQuery, "{{exclude_bots}}", excludeBots, -1)
 				if period == "" {
 					dtTo := lib.TimeParseAny(to)
 					prevHour := lib.PrevHourStart(time.Now())
@@ -410,11 +947,24 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 		}
 		sqlQuery = strings.Replace(sqlQuery, "{{period}}", dbInterval, -1)
 		sqlQuery = strings.Replace(sqlQuery, "{{n}}", strconv.Itoa(nIntervals)+".0", -1)
-		sqlQuery = strings.Replace(sqlQuery, "{{exclude_bots}}", excludeBots, -1)
+	}
+
+	// Skip histogram if neither its query nor data changed since it was computed
+	hashKey := histogramKey(seriesNameOrFunc, sqlFile, intervalAbbr)
+	hash := ""
+	if !ctx.SkipIDB {
+		hash = histogramHash(sqlc, ctx, seriesNameOrFunc, sqlQuery)
+		if !ctx.ForceCompute && !ctx.ResetIDB && getComputedHash(ic, ctx, hashKey) == hash {
+			lib.Printf("Skipping histogram %s: query and data unchanged (hash %s)\n", hashKey, hash)
+			return 0, false
+		}
 	}
 
 	// Execute SQL query
-	rows := lib.QuerySQLWithErr(sqlc, ctx, sqlQuery)
+	release := acquireDB(qctx, ctx)
+	defer release()
+	dtStart := time.Now()
+	rows := querySQL(qctx, ctx, sqlQuery)
 	defer func() { lib.FatalOnError(rows.Close()) }()
 
 	// Get number of columns, for histograms there should be exactly 2 columns
@@ -427,7 +977,72 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 		value float64
 		name  string
 	)
-	if nColumns == 2 {
+	rowCount := 0
+	if twoDim {
+		// Rows with name, column (secondary dimension, like company) and value, column is saved as a tag
+		// so Grafana tables can pivot by it, or show a single column (drill-down) using `where "column" = '$column'`
+		if nColumns != 3 {
+			lib.FatalOnError(fmt.Errorf("2D histogram's query should return rows with name, column and value, got %d columns\nQuery:%s", nColumns, sqlQuery))
+		}
+		columnsSeries := seriesNameOrFunc + "_columns"
+		if !ctx.SkipIDB {
+			// Drop existing data
+			lib.QueryIDB(ic, ctx, "drop measurement "+seriesNameOrFunc)
+			lib.QueryIDB(ic, ctx, "drop measurement "+columnsSeries)
+			if ctx.Debug > 0 {
+				lib.Printf("Dropped measurements %s, %s\n", seriesNameOrFunc, columnsSeries)
+			}
+		}
+
+		// Add new data, every column has its own points (times), like 1 dimension histogram
+		var column string
+		totals := make(map[string]float64)
+		times := make(map[string]time.Time)
+		now := time.Now()
+		for rows.Next() {
+			lib.FatalOnError(rows.Scan(&name, &column, &value))
+			if ctx.Debug > 0 {
+				lib.Printf("hist2d %v, %v %v -> %v, %v, %v\n", seriesNameOrFunc, nIntervals, interval, name, column, value)
+			}
+			tm, ok := times[column]
+			if ok {
+				tm = tm.Add(-time.Hour)
+			} else {
+				tm = now
+			}
+			times[column] = tm
+			// Add batch point
+			fields := map[string]interface{}{"name": name, "value": value}
+			pt := lib.IDBNewPointWithErr(seriesNameOrFunc, map[string]string{"column": column}, fields, tm)
+			lib.IDBAddPointN(ctx, &ic, &pts, pt)
+			totals[column] += value
+			rowCount++
+		}
+		checkTimeout(qctx, rows.Err())
+		lib.FatalOnError(rows.Err())
+
+		// Columns ordered by their totals, Grafana uses them as table columns or drill-down variable values
+		columnNames := []string{}
+		for column := range totals {
+			columnNames = append(columnNames, column)
+		}
+		sort.Slice(columnNames, func(i, j int) bool {
+			if totals[columnNames[i]] == totals[columnNames[j]] {
+				return columnNames[i] < columnNames[j]
+			}
+			return totals[columnNames[i]] > totals[columnNames[j]]
+		})
+		tm := now
+		for _, column := range columnNames {
+			fields := map[string]interface{}{"name": column, "value": totals[column]}
+			pt := lib.IDBNewPointWithErr(columnsSeries, nil, fields, tm)
+			lib.IDBAddPointN(ctx, &ic, &pts, pt)
+			tm = tm.Add(-time.Hour)
+		}
+		if ctx.Debug > 0 {
+			lib.Printf("hist2d %v, %v %v: %v rows, %v columns\n", seriesNameOrFunc, nIntervals, interval, rowCount, len(columnNames))
+		}
+	} else if nColumns == 2 {
 		if !ctx.SkipIDB {
 			// Drop existing data
 			lib.QueryIDB(ic, ctx, "drop measurement "+seriesNameOrFunc)
@@ -438,7 +1053,6 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 
 		// Add new data
 		tm := time.Now()
-		rowCount := 0
 		for rows.Next() {
 			lib.FatalOnError(rows.Scan(&name, &value))
 			if ctx.Debug > 0 {
@@ -454,6 +1068,7 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 		if ctx.Debug > 0 {
 			lib.Printf("hist %v, %v %v: %v rows\n", seriesNameOrFunc, nIntervals, interval, rowCount)
 		}
+		checkTimeout(qctx, rows.Err())
 		lib.FatalOnError(rows.Err())
 	} else if nColumns >= 3 {
 		var (
@@ -471,6 +1086,7 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 		for rows.Next() {
 			// Get row values
 			lib.FatalOnError(rows.Scan(pValues...))
+			rowCount++
 			name := string(*pValues[0].(*sql.RawBytes))
 			names := nameForMetricsRow(seriesNameOrFunc, name, intervalAbbr, false, false)
 			nNames := len(names)
@@ -499,6 +1115,13 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 					} else {
 						tm = time.Now()
 						seriesToClear[name] = tm
+						// Drop existing data before the first point, because points can be written before all rows are read
+						if !ctx.SkipIDB {
+							lib.QueryIDB(ic, ctx, "drop series from "+name)
+							if ctx.Debug > 0 {
+								lib.Printf("Dropped series: %s\n", name)
+							}
+						}
 					}
 					// Add batch point
 					fields := map[string]interface{}{"name": sValue, "value": fValue}
@@ -507,32 +1130,321 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 				}
 			}
 		}
+		checkTimeout(qctx, rows.Err())
 		lib.FatalOnError(rows.Err())
-		if len(seriesToClear) > 0 && !ctx.SkipIDB {
-			allSeries := "/^("
-			for series := range seriesToClear {
-				allSeries += series + "|"
-			}
-			allSeries = allSeries[0:len(allSeries)-1] + ")$/"
-			lib.QueryIDB(ic, ctx, "drop series from "+allSeries)
-			if ctx.Debug > 0 {
-				lib.Printf("Dropped series regexp: %s\n", allSeries)
-			}
-		}
 	}
+	lib.ExplainIfSlow(sqlc, ctx, "db2influx", getPathIndependentKey(sqlFile), sqlQuery, time.Now().Sub(dtStart))
 	// Write the batch
 	if !ctx.SkipIDB {
 		// Mark this metric & period as already computed if this is a QR period
 		if qrFrom != nil {
 			setAlreadyComputed(ic, ctx, &pts, sqlFile, *qrFrom)
 		}
+		setComputedHash(ic, ctx, &pts, hashKey, hash)
 		lib.FatalOnError(lib.IDBWritePointsN(ctx, &ic, &pts))
 	} else if ctx.Debug > 0 {
 		lib.Printf("Skipping series write\n")
 	}
+	return int64(rowCount), true
+}
+
+// releasePeriods returns periods between releases that need computing (see lib.ReleaseRanges): start of the first one,
+// end of the last one (now) and function returning end of the period starting at given release
+func releasePeriods(ctx *lib.Ctx, from time.Time) (dFrom, dTo time.Time, nextRelease func(time.Time) time.Time) {
+	ic := lib.IDBConn(ctx)
+	defer func() { lib.FatalOnError(ic.Close()) }()
+	ranges := lib.ReleaseRanges(lib.GetReleaseDates(ic, ctx), from, time.Now())
+	lib.Printf("%d periods between releases to compute\n", len(ranges))
+	ends := make(map[int64]time.Time)
+	for _, r := range ranges {
+		ends[r.From.UnixNano()] = r.To
+	}
+	if len(ranges) > 0 {
+		dFrom, dTo = ranges[0].From, ranges[len(ranges)-1].To
+	}
+	nextRelease = func(dt time.Time) time.Time {
+		return ends[dt.UnixNano()]
+	}
+	return
+}
+
+// seriesSet - names of series written by worker threads, nil set ignores them
+type seriesSet struct {
+	mtx   sync.Mutex
+	names map[string]struct{}
+}
+
+// add adds series `name`
+func (s *seriesSet) add(name string) {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	s.names[name] = struct{}{}
+	s.mtx.Unlock()
 }
 
-func db2influx(seriesNameOrFunc, sqlFile, from, to, intervalAbbr string, hist, multivalue, escapeValueName, annotationsRanges, skipPast bool, desc string) {
+// sorted returns series names sorted
+func (s *seriesSet) sorted() []string {
+	names := []string{}
+	for name := range s.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validator - checks metric's values before they are written (metrics.yaml `validate`), violations are saved in gha_metric_violations
+// With `block` points violating any rule are not written
+type validator struct {
+	rules   []lib.ValidationRule
+	block   bool
+	sqlName string
+	period  string
+}
+
+// allowed checks numeric `fields` of point of `series` at `dt`, returns false when the point must not be written (nil validator allows all)
+// Rules comparing values with the previous ones use the last point of the series before `dt`
+func (v *validator) allowed(sqlc *sql.DB, ic client.Client, ctx *lib.Ctx, series string, fields map[string]interface{}, dt time.Time) bool {
+	if v == nil {
+		return true
+	}
+	var prevFields map[string][]float64
+	for _, rule := range v.rules {
+		if rule.NeedsPrevious() {
+			_, prevFields = seriesFields(
+				ic,
+				ctx,
+				fmt.Sprintf("select * from \"%s\" where time < %d order by time desc limit 1", series, dt.UnixNano()),
+			)
+			break
+		}
+	}
+	violated := false
+	for field, fieldValue := range fields {
+		value, ok := fieldValue.(float64)
+		if !ok {
+			continue
+		}
+		var prev *float64
+		if prevValues := prevFields[field]; len(prevValues) > 0 {
+			prev = &prevValues[0]
+		}
+		for _, rule := range v.rules {
+			violation := rule.Check(value, prev)
+			if violation == "" {
+				continue
+			}
+			violated = true
+			lib.Printf("Validation failed: %s[%s] at %v: %s (%s), blocked: %v\n", series, field, dt, violation, rule.String(), v.block)
+			_, err := lib.ExecSQL(
+				sqlc,
+				ctx,
+				"insert into gha_metric_violations(sql, series, period, point_dt, field, rule, value, prev, blocked, dt) "+lib.NValues(10),
+				v.sqlName,
+				lib.TruncToBytes(series, 200),
+				v.period,
+				dt,
+				lib.TruncToBytes(field, 200),
+				rule.String(),
+				value,
+				prev,
+				v.block,
+				time.Now(),
+			)
+			if err != nil {
+				lib.Printf("Cannot save validation violation: %v\n", err)
+			}
+		}
+	}
+	return !(violated && v.block)
+}
+
+// seriesFields returns numeric fields of `series` points (ordered by time) returned by InfluxDB `query`
+// Fields missing in some points (nulls) are 0 there, other values (like "descr") are skipped
+func seriesFields(ic client.Client, ctx *lib.Ctx, query string) (times []time.Time, fields map[string][]float64) {
+	fields = make(map[string][]float64)
+	res := lib.QueryIDB(ic, ctx, query)
+	if len(res) < 1 || len(res[0].Series) < 1 {
+		return
+	}
+	series := res[0].Series[0]
+	for i, row := range series.Values {
+		times = append(times, lib.TimeParseIDB(row[0].(string)))
+		for j, column := range series.Columns[1:] {
+			number, ok := row[j+1].(json.Number)
+			if !ok {
+				continue
+			}
+			value, _ := number.Float64()
+			if _, ok := fields[column]; !ok {
+				fields[column] = make([]float64, i)
+			}
+			fields[column] = append(fields[column], value)
+		}
+		// Fields that this point doesn't have
+		for column, values := range fields {
+			if len(values) <= i {
+				fields[column] = append(values, 0)
+			}
+		}
+	}
+	return
+}
+
+// smoothSeries writes smoothed companions (`series`_ma7, `series`_ema0_3, ...) of `series` points in [from, to)
+// Moving average also reads points before `from` it needs, EMA continues from its last point before `from`
+func smoothSeries(ic client.Client, ctx *lib.Ctx, series string, smoothings []lib.Smoothing, from, to time.Time) {
+	var pts lib.IDBBatchPointsN
+	bp := lib.IDBBatchPoints(ctx, &ic)
+	pts.NPoints = 0
+	pts.Points = &bp
+	for _, smoothing := range smoothings {
+		name := series + "_" + smoothing.Suffix()
+		start := from
+		if smoothing.Points > 1 {
+			times, _ := seriesFields(
+				ic,
+				ctx,
+				fmt.Sprintf("select * from \"%s\" where time < %d order by time desc limit %d", series, from.UnixNano(), smoothing.Points-1),
+			)
+			if len(times) > 0 {
+				start = times[len(times)-1]
+			}
+		}
+		times, fields := seriesFields(
+			ic,
+			ctx,
+			fmt.Sprintf("select * from \"%s\" where time >= %d and time < %d", series, start.UnixNano(), to.UnixNano()),
+		)
+		var prevFields map[string][]float64
+		if smoothing.Alpha > 0 {
+			_, prevFields = seriesFields(
+				ic,
+				ctx,
+				fmt.Sprintf("select * from \"%s\" where time < %d order by time desc limit 1", name, from.UnixNano()),
+			)
+		}
+		smoothed := make(map[string][]float64)
+		for column, values := range fields {
+			var prev *float64
+			if prevValues := prevFields[column]; len(prevValues) > 0 {
+				prev = &prevValues[0]
+			}
+			smoothed[column] = smoothing.Smooth(values, prev)
+		}
+		for i, dt := range times {
+			if dt.Before(from) {
+				continue
+			}
+			values := make(map[string]interface{})
+			for column := range smoothed {
+				values[column] = smoothed[column][i]
+			}
+			if ctx.Debug > 0 {
+				lib.Printf("%v -> %v: %v\n", dt, name, values)
+			}
+			lib.IDBAddPointN(ctx, &ic, &pts, lib.IDBNewPointWithErr(name, nil, values, dt))
+		}
+	}
+	lib.FatalOnError(lib.IDBWritePointsN(ctx, &ic, &pts))
+}
+
+// detectAnomalies compares `series` points in [from, to) with their previous points and writes anomalies found to
+// lib.AnomaliesSeries, anomalies previously found in this range are deleted first (the range is recomputed). Returns number of anomalies
+func detectAnomalies(ic client.Client, ctx *lib.Ctx, series string, detector lib.AnomalyDetector, from, to time.Time) int {
+	start := from
+	times, _ := seriesFields(
+		ic,
+		ctx,
+		fmt.Sprintf("select * from \"%s\" where time < %d order by time desc limit %d", series, from.UnixNano(), detector.Window),
+	)
+	if len(times) > 0 {
+		start = times[len(times)-1]
+	}
+	times, fields := seriesFields(
+		ic,
+		ctx,
+		fmt.Sprintf("select * from \"%s\" where time >= %d and time < %d", series, start.UnixNano(), to.UnixNano()),
+	)
+	lib.QueryIDB(
+		ic,
+		ctx,
+		fmt.Sprintf("delete from \"%s\" where \"series\" = '%s' and time >= %d and time < %d", lib.AnomaliesSeries, series, from.UnixNano(), to.UnixNano()),
+	)
+	anomalies := detector.Detect(times, fields, from)
+	if len(anomalies) == 0 {
+		return 0
+	}
+	var pts lib.IDBBatchPointsN
+	bp := lib.IDBBatchPoints(ctx, &ic)
+	pts.NPoints = 0
+	pts.Points = &bp
+	for _, anomaly := range anomalies {
+		description := fmt.Sprintf(
+			"%s = %v is outside of expected range [%.2f, %.2f] (%s of previous %d points)",
+			anomaly.Field, anomaly.Value, anomaly.Low, anomaly.High, detector.String(), detector.Window,
+		)
+		lib.Printf("Anomaly: %s at %v: %s\n", series, anomaly.Time, description)
+		tags := map[string]string{"series": series, "field": anomaly.Field}
+		fields := map[string]interface{}{
+			"title":       "Anomaly in " + series,
+			"description": description,
+			"value":       anomaly.Value,
+			"low":         anomaly.Low,
+			"high":        anomaly.High,
+		}
+		lib.IDBAddPointN(ctx, &ic, &pts, lib.IDBNewPointWithErr(lib.AnomaliesSeries, tags, fields, anomaly.Time))
+	}
+	lib.FatalOnError(lib.IDBWritePointsN(ctx, &ic, &pts))
+	return len(anomalies)
+}
+
+// writeUnits saves `unit` of series `names` in "series_units", failure is only logged (metric's values are already written)
+func writeUnits(ctx *lib.Ctx, names []string, unit lib.SeriesUnit) {
+	ic := lib.IDBConn(ctx)
+	defer func() { lib.FatalOnError(ic.Close()) }()
+	err := lib.WriteSeriesUnits(ic, ctx, names, unit)
+	if err != nil {
+		lib.Printf("Cannot save units of %d series: %v\n", len(names), err)
+		return
+	}
+	if ctx.Debug > 0 {
+		lib.Printf("Saved unit %s (%s) of %d series\n", unit.Unit, unit.ValueType, len(names))
+	}
+}
+
+// saveMetricRun saves metric's run into gha_metric_runs (dashboards show metrics that are getting slower)
+// Failing to save it doesn't fail the metric
+func saveMetricRun(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, intervalAbbr string, hist bool, nRows int64, took time.Duration) {
+	sqlc := lib.PgConn(ctx)
+	_, err := lib.ExecSQL(
+		sqlc,
+		ctx,
+		"insert into gha_metric_runs(sql, series, period, hist, rows, took_ms, dt) "+lib.NValues(7),
+		strings.TrimSuffix(filepath.Base(sqlFile), ".sql"),
+		lib.TruncToBytes(seriesNameOrFunc, 200),
+		intervalAbbr,
+		hist,
+		nRows,
+		int64(took/time.Millisecond),
+		time.Now(),
+	)
+	if err != nil {
+		lib.Printf("Cannot save metric run: %v\n", err)
+	}
+}
+
+// db2influx computes metric, `derived` metrics combine `sqlFile` (first operand) and `operand2` results with given operation
+// Metrics with `percentiles` write given percentiles of values returned by `sqlFile`
+// With `smoothings` every written series gets smoothed companions, computed when all periods are written
+// With `unit` (nil - none) it is saved for every written series in "series_units"
+// With `allProjects` merge operation ("" - none) metric's query runs in all enabled projects' databases, merged results are
+// written to the current (aggregate) project's InfluxDB
+// With `anomalies` detector (nil - none) every written series is checked for anomalies, when all periods are written
+// With validation `rules` values are checked before they are written, `block` skips writing points that violate them
+// Metric's SQL (and derived operands' SQL) gets events `weight` mode as {{weight}}, see lib.ApplyWeight
+func db2influx(seriesNameOrFunc, sqlFile, from, to, intervalAbbr string, hist, twoDim, multivalue, escapeValueName, annotationsRanges, skipPast bool, desc string, timeout int, derived, operand2 string, percentiles []float64, smoothings []lib.Smoothing, unit *lib.SeriesUnit, allProjects string, anomalies *lib.AnomalyDetector, rules []lib.ValidationRule, block bool, weight string) {
 	// Environment context parse
 	var ctx lib.Ctx
 	ctx.Init()
@@ -543,32 +1455,109 @@ func db2influx(seriesNameOrFunc, sqlFile, from, to, intervalAbbr string, hist, m
 		dataPrefix = "./"
 	}
 
-	// Read SQL file.
-	bytes, err := ioutil.ReadFile(sqlFile)
-	lib.FatalOnError(err)
-	sqlQuery := string(bytes)
+	// Read SQL file (or derived metric's operands)
+	var (
+		sqlQuery string
+		a, b     operand
+	)
+	if len(percentiles) > 0 && (hist || annotationsRanges || multivalue || derived != "") {
+		lib.FatalOnError(fmt.Errorf("percentiles metric cannot be histogram, multivalue or derived"))
+	}
+	if len(smoothings) > 0 && (hist || annotationsRanges) {
+		lib.FatalOnError(fmt.Errorf("histogram and annotations ranges metrics cannot be smoothed"))
+	}
+	if anomalies != nil && (hist || annotationsRanges) {
+		lib.FatalOnError(fmt.Errorf("histogram and annotations ranges metrics cannot be checked for anomalies"))
+	}
+	if len(rules) > 0 && (hist || annotationsRanges) {
+		lib.FatalOnError(fmt.Errorf("histogram and annotations ranges metrics cannot be validated"))
+	}
+	// Metric computed by Go calculator ("calc:name" instead of SQL file)
+	var calc *calculator
+	if strings.HasPrefix(sqlFile, lib.CalculatorPrefix) {
+		if hist || annotationsRanges || derived != "" || len(percentiles) > 0 || allProjects != "" {
+			lib.FatalOnError(fmt.Errorf("calculator metric cannot be histogram, derived, percentiles or all projects"))
+		}
+		calc = readCalculator(&ctx, dataPrefix, strings.TrimPrefix(sqlFile, lib.CalculatorPrefix))
+	}
+	var agg *aggregation
+	if allProjects != "" {
+		lib.FatalOnError(lib.CheckMergeOp(allProjects))
+		if hist || annotationsRanges || derived != "" || len(percentiles) > 0 {
+			lib.FatalOnError(fmt.Errorf("all projects metric cannot be histogram, derived or percentiles"))
+		}
+		agg = &aggregation{dbs: readAggregateDBs(&ctx, dataPrefix), merge: allProjects}
+		lib.Printf("Aggregating %d projects' databases (%s): %s\n", len(agg.dbs), allProjects, strings.Join(agg.dbs, ", "))
+	}
+	if derived != "" {
+		lib.FatalOnError(lib.CheckDerivedOp(derived))
+		if hist || annotationsRanges || multivalue || operand2 == "" {
+			lib.FatalOnError(fmt.Errorf("derived metric needs two operands and cannot be histogram or multivalue"))
+		}
+		a = readOperand(dataPrefix, sqlFile)
+		b = readOperand(dataPrefix, operand2)
+	} else if calc == nil {
+		sqlQuery = readSQL(dataPrefix, sqlFile)
+	}
+	for _, query := range []*string{&sqlQuery, &a.sqlQuery, &b.sqlQuery} {
+		var err error
+		*query, err = lib.ApplyWeight(*query, weight)
+		lib.FatalOnError(err)
+	}
 
-	// Read bots exclusion partial SQL
-	bytes, err = ioutil.ReadFile(dataPrefix + "util_sql/exclude_bots.sql")
-	lib.FatalOnError(err)
-	excludeBots := string(bytes)
+	// Process interval, periods between releases are not calendar intervals (see releasePeriods)
+	// Calendar intervals start in project's time zone (GHA2DB_TIMEZONE)
+	releases := intervalAbbr == lib.ReleasePeriod
+	if releases && (hist || annotationsRanges) {
+		lib.FatalOnError(fmt.Errorf("histogram and annotations ranges metrics cannot use periods between releases"))
+	}
+	var (
+		interval                                            string
+		nIntervals                                          int
+		intervalStart, nextIntervalStart, prevIntervalStart func(time.Time) time.Time
+	)
+	if releases {
+		interval, nIntervals = "release", 1
+	} else {
+		interval, nIntervals, intervalStart, nextIntervalStart, prevIntervalStart = lib.GetIntervalFunctionsIn(intervalAbbr, annotationsRanges, ctx.Location())
+	}
+
+	// SIGINT/SIGTERM doesn't interrupt periods being written (histogram is always finished), no new periods are started
+	runCtx, cancel := lib.SignalContext()
+	defer cancel()
 
-	// Process interval
-	interval, nIntervals, intervalStart, nextIntervalStart, prevIntervalStart := lib.GetIntervalFunctions(intervalAbbr, annotationsRanges)
+	// Metric's run (time it took and number of rows) is saved in gha_metric_runs when it finishes
+	dtStart := time.Now()
+	var nRows int64
+
+	// All metric's queries are cancelled when its timeout (in seconds) passes
+	qctx := context.Background()
+	if timeout > 0 {
+		var qcancel context.CancelFunc
+		qctx, qcancel = context.WithTimeout(qctx, time.Duration(timeout)*time.Second)
+		defer qcancel()
+	}
 
 	if hist {
-		db2influxHistogram(
+		histRows, computed := db2influxHistogram(
+			qctx,
 			&ctx,
 			seriesNameOrFunc,
 			sqlFile,
 			sqlQuery,
-			excludeBots,
 			interval,
 			intervalAbbr,
 			nIntervals,
 			annotationsRanges,
 			skipPast,
+			twoDim,
 		)
+		if computed {
+			saveMetricRun(&ctx, seriesNameOrFunc, sqlFile, intervalAbbr, true, histRows, time.Now().Sub(dtStart))
+			if unit != nil && !ctx.SkipIDB {
+				writeUnits(&ctx, []string{seriesNameOrFunc}, *unit)
+			}
+		}
 		return
 	}
 
@@ -576,20 +1565,56 @@ func db2influx(seriesNameOrFunc, sqlFile, from, to, intervalAbbr string, hist, m
 	dFrom := lib.TimeParseAny(from)
 	dTo := lib.TimeParseAny(to)
 
-	// Round dates to the given interval
-	dFrom = intervalStart(dFrom)
-	dTo = nextIntervalStart(dTo)
+	if releases {
+		dFrom, dTo, nextIntervalStart = releasePeriods(&ctx, dFrom)
+	} else {
+		// Round dates to the given interval
+		dFrom = intervalStart(dFrom)
+		dTo = nextIntervalStart(dTo)
+	}
 
 	// Get number of CPUs available
 	thrN := lib.GetThreadsNum(&ctx)
 
+	// Results of regular metrics' queries are cached in gha_metric_cache, aggregated results are not
+	var cache *metricCache
+	if derived == "" && len(percentiles) == 0 && agg == nil && calc == nil {
+		cache = newMetricCache(&ctx)
+	}
+
+	// Series to smooth (to check for anomalies and to save their unit) when all periods are written
+	var written *seriesSet
+	if (len(smoothings) > 0 || unit != nil || anomalies != nil) && !ctx.SkipIDB {
+		written = &seriesSet{names: make(map[string]struct{})}
+	}
+
+	// Values checked before they are written
+	var valid *validator
+	if len(rules) > 0 {
+		valid = &validator{rules: rules, block: block, sqlName: strings.TrimSuffix(filepath.Base(sqlFile), ".sql"), period: intervalAbbr}
+	}
+
+	// Computes a single period
+	sqlName := getPathIndependentKey(sqlFile)
+	compute := func(ch chan bool, dt, from, to time.Time) {
+		if derived != "" {
+			derivedWorkerThread(ch, qctx, &ctx, written, valid, seriesNameOrFunc, derived, a, b, intervalAbbr, desc, nIntervals, dt, from, to, &nRows)
+			return
+		}
+		if len(percentiles) > 0 {
+			percentileWorkerThread(ch, qctx, &ctx, written, valid, sqlName, seriesNameOrFunc, sqlQuery, intervalAbbr, desc, percentiles, nIntervals, dt, from, to, &nRows)
+			return
+		}
+		workerThread(ch, qctx, &ctx, cache, agg, calc, written, valid, sqlName, seriesNameOrFunc, sqlQuery, intervalAbbr, desc, multivalue, escapeValueName, nIntervals, dt, from, to, &nRows)
+	}
+
 	// Run
 	lib.Printf("db2influx.go: Running (on %d CPUs): %v - %v with interval %s, descriptions '%s', multivalue: %v, escape_value_name: %v\n", thrN, dFrom, dTo, interval, desc, multivalue, escapeValueName)
 	dt := dFrom
 	var pDt time.Time
 	if thrN > 1 {
 		chanPool := []chan bool{}
-		for dt.Before(dTo) {
+		for dt.Before(dTo) && runCtx.Err() == nil {
 			ch := make(chan bool)
 			chanPool = append(chanPool, ch)
 			nDt := nextIntervalStart(dt)
@@ -598,21 +1623,7 @@ func db2influx(seriesNameOrFunc, sqlFile, from, to, intervalAbbr string, hist, m
 			} else {
 				pDt = lib.AddNIntervals(dt, 1-nIntervals, nextIntervalStart, prevIntervalStart)
 			}
-			go workerThread(
-				ch,
-				&ctx,
-				seriesNameOrFunc,
-				sqlQuery,
-				excludeBots,
-				intervalAbbr,
-				desc,
-				multivalue,
-				escapeValueName,
-				nIntervals,
-				dt,
-				pDt,
-				nDt,
-			)
+			go compute(ch, dt, pDt, nDt)
 			dt = nDt
 			if len(chanPool) == thrN {
 				ch = chanPool[0]
@@ -626,31 +1637,52 @@ func db2influx(seriesNameOrFunc, sqlFile, from, to, intervalAbbr string, hist, m
 		}
 	} else {
 		lib.Printf("Using single threaded version\n")
-		for dt.Before(dTo) {
+		for dt.Before(dTo) && runCtx.Err() == nil {
 			nDt := nextIntervalStart(dt)
 			if nIntervals <= 1 {
 				pDt = dt
 			} else {
 				pDt = lib.AddNIntervals(dt, 1-nIntervals, nextIntervalStart, prevIntervalStart)
 			}
-			workerThread(
-				nil,
-				&ctx,
-				seriesNameOrFunc,
-				sqlQuery,
-				excludeBots,
-				intervalAbbr,
-				desc,
-				multivalue,
-				escapeValueName,
-				nIntervals,
-				dt,
-				pDt,
-				nDt,
-			)
+			compute(nil, dt, pDt, nDt)
 			dt = nDt
 		}
 	}
+	if runCtx.Err() != nil {
+		lib.Printf("Interrupted before %v, periods up to it were written\n", dt)
+		lib.FatalOnError(lib.ErrInterrupted)
+	}
+	if written != nil && len(smoothings) > 0 {
+		ic := lib.IDBConn(&ctx)
+		names := written.sorted()
+		for _, name := range names {
+			smoothSeries(ic, &ctx, name, smoothings, dFrom, dTo)
+		}
+		lib.FatalOnError(ic.Close())
+		lib.Printf("Smoothed %d series\n", len(names))
+	}
+	if written != nil && anomalies != nil {
+		ic := lib.IDBConn(&ctx)
+		names := written.sorted()
+		nAnomalies := 0
+		for _, name := range names {
+			nAnomalies += detectAnomalies(ic, &ctx, name, *anomalies, dFrom, dTo)
+		}
+		lib.FatalOnError(ic.Close())
+		lib.Printf("Checked %d series for anomalies (%s), found %d\n", len(names), anomalies.String(), nAnomalies)
+	}
+	if written != nil && unit != nil {
+		// Smoothed companions have the same unit
+		names := []string{}
+		for _, name := range written.sorted() {
+			names = append(names, name)
+			for _, smoothing := range smoothings {
+				names = append(names, name+"_"+smoothing.Suffix())
+			}
+		}
+		writeUnits(&ctx, names, *unit)
+	}
+	saveMetricRun(&ctx, seriesNameOrFunc, sqlFile, intervalAbbr, false, nRows, time.Now().Sub(dtStart))
 	// Finished
 	lib.Printf("All done.\n")
 }
@@ -662,6 +1694,16 @@ func main() {
 			"Required series name, SQL file name, from, to, period " +
 				"[series_name_or_func some.sql '2015-08-03' '2017-08-21' h|d|w|m|q|y [hist,desc:time_diff_as_string]]\n",
 		)
+		lib.Printf("2D histogram (SQL returns name, column, value): series_name_or_func some.sql from to period hist2d\n")
+		lib.Printf("Percentiles of values returned by SQL: series_name_or_func some.sql from to period percentiles:50;90;99\n")
+		lib.Printf("Metric computed by registered Go calculator instead of SQL: series_name_or_func calc:bus_factor from to period\n")
+		lib.Printf("Derived metrics: series_name_or_func a.sql|series:name from to period derived:ratio|difference|sum,operand2:b.sql|series:name\n")
+		lib.Printf("Smoothed companions of series (moving average, EMA): series_name_or_func some.sql from to period smooth:ma7;ema0.3\n")
+		lib.Printf("Unit and value type of written series: series_name_or_func some.sql from to period unit:seconds,value_type:integer\n")
+		lib.Printf("Merged results of all enabled projects' databases: series_name_or_func some.sql from to period all_projects:sum|max|min\n")
+		lib.Printf("Anomalies of written series (z-score or IQR): series_name_or_func some.sql from to period anomalies:zscore3,anomaly_window:28\n")
+		lib.Printf("Events weighted by size in SQL using {{weight}}: series_name_or_func some.sql from to period weight:count|lines|log_lines|files\n")
+		lib.Printf("Validation of values before writing: series_name_or_func some.sql from to period validate:non_negative;monotonic;max_delta:50%%[,validate_block]\n")
 		lib.Printf(
 			"Series name (series_name_or_func) will become exact series name if " +
 				"query return just single numeric value\n",
@@ -671,16 +1713,30 @@ func main() {
 		os.Exit(1)
 	}
 	hist := false
+	twoDim := false
 	multivalue := false
 	escapeValueName := false
 	annotationsRanges := false
 	skipPast := false
 	desc := ""
+	timeout := 0
+	derived := ""
+	operand2 := ""
+	allProjects := ""
+	block := false
+	weight := ""
+	var (
+		percentiles []float64
+		smoothings  []lib.Smoothing
+		unit        *lib.SeriesUnit
+		anomalies   *lib.AnomalyDetector
+		rules       []lib.ValidationRule
+	)
 	if len(os.Args) > 6 {
 		opts := strings.Split(os.Args[6], ",")
 		optMap := make(map[string]string)
 		for _, opt := range opts {
-			optArr := strings.Split(opt, ":")
+			optArr := strings.SplitN(opt, ":", 2)
 			optName := optArr[0]
 			optVal := ""
 			if len(optArr) > 1 {
@@ -691,6 +1747,11 @@ func main() {
 		if _, ok := optMap["hist"]; ok {
 			hist = true
 		}
+		// Histogram with a secondary dimension, SQL returns name, column and value
+		if _, ok := optMap["hist2d"]; ok {
+			hist = true
+			twoDim = true
+		}
 		if _, ok := optMap["multivalue"]; ok {
 			multivalue = true
 		}
@@ -706,6 +1767,66 @@ func main() {
 		if d, ok := optMap["desc"]; ok {
 			desc = d
 		}
+		if t, ok := optMap["timeout"]; ok {
+			var err error
+			timeout, err = strconv.Atoi(t)
+			lib.FatalOnError(err)
+		}
+		if d, ok := optMap["derived"]; ok {
+			derived = d
+		}
+		if o, ok := optMap["operand2"]; ok {
+			operand2 = o
+		}
+		// Percentiles are separated by ";" here, "," separates options
+		if p, ok := optMap["percentiles"]; ok {
+			var err error
+			percentiles, err = lib.ParsePercentiles(strings.Replace(p, ";", ",", -1))
+			lib.FatalOnError(err)
+		}
+		// The same for smoothings
+		if s, ok := optMap["smooth"]; ok {
+			var err error
+			smoothings, err = lib.ParseSmoothing(strings.Replace(s, ";", ",", -1))
+			lib.FatalOnError(err)
+		}
+		u, uOK := optMap["unit"]
+		vt, vtOK := optMap["value_type"]
+		if uOK || vtOK {
+			seriesUnit, err := lib.ParseSeriesUnit(u, vt)
+			lib.FatalOnError(err)
+			unit = &seriesUnit
+		}
+		// Cross-project aggregation, "all_projects" alone sums values
+		if m, ok := optMap["all_projects"]; ok {
+			allProjects = m
+			if allProjects == "" {
+				allProjects = lib.MergeSum
+			}
+		}
+		if a, ok := optMap["anomalies"]; ok {
+			window := 0
+			if w, ok := optMap["anomaly_window"]; ok {
+				var err error
+				window, err = strconv.Atoi(w)
+				lib.FatalOnError(err)
+			}
+			detector, err := lib.ParseAnomalyDetector(a, window)
+			lib.FatalOnError(err)
+			anomalies = &detector
+		}
+		// Validation rules are separated by ";" too
+		if v, ok := optMap["validate"]; ok {
+			var err error
+			rules, err = lib.ParseValidationRules(strings.Replace(v, ";", ",", -1))
+			lib.FatalOnError(err)
+		}
+		if _, ok := optMap["validate_block"]; ok {
+			block = true
+		}
+		if w, ok := optMap["weight"]; ok {
+			weight = w
+		}
 	}
 	db2influx(
 		os.Args[1],
@@ -714,11 +1835,23 @@ func main() {
 		os.Args[4],
 		os.Args[5],
 		hist,
+		twoDim,
 		multivalue,
 		escapeValueName,
 		annotationsRanges,
 		skipPast,
 		desc,
+		timeout,
+		derived,
+		operand2,
+		percentiles,
+		smoothings,
+		unit,
+		allProjects,
+		anomalies,
+		rules,
+		block,
+		weight,
 	)
 	dtEnd := time.Now()
 	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	lib "devstats"
+)
+
+// Removes duplicated events and all their event specific rows (in one transaction)
+func dedupEvents() {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Connect to Postgres DB
+	con := lib.PgConn(&ctx)
+
+	tx, err := con.Begin()
+	lib.FatalOnError(err)
+	lib.ExecSQLTxWithErr(tx, &ctx, "create temp table dup_events on commit drop as "+lib.DupEventsQuery())
+	dups := 0
+	rows := lib.QuerySQLTxWithErr(tx, &ctx, "select count(*) from dup_events")
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&dups))
+	}
+	lib.FatalOnError(rows.Err())
+	lib.FatalOnError(rows.Close())
+	lib.Printf("Found %d duplicated events\n", dups)
+	if dups == 0 || ctx.DryRun {
+		lib.FatalOnError(tx.Rollback())
+		return
+	}
+
+	// Event specific rows first, events at the end
+	for _, table := range lib.EventTables {
+		res := lib.ExecSQLTxWithErr(
+			tx,
+			&ctx,
+			fmt.Sprintf("delete from %s t using dup_events d where t.event_id = d.id", table),
+		)
+		removed, err := res.RowsAffected()
+		lib.FatalOnError(err)
+		if removed > 0 {
+			lib.Printf("%s: removed %d rows\n", table, removed)
+		}
+	}
+	res := lib.ExecSQLTxWithErr(tx, &ctx, "delete from gha_events e using dup_events d where e.id = d.id")
+	removed, err := res.RowsAffected()
+	lib.FatalOnError(err)
+	lib.FatalOnError(tx.Commit())
+	lib.Printf("Removed %d duplicated events\n", removed)
+
+	// Metrics counted duplicated events, their cached results are no longer valid
+	cleared, err := lib.ClearMetricCache(con, &ctx, nil, nil)
+	if err != nil {
+		lib.Printf("Cannot clear metric cache: %v\n", err)
+	} else {
+		lib.Printf("Cleared %d cached metric results\n", cleared)
+	}
+}
+
+func main() {
+	dtStart := time.Now()
+	dedupEvents()
+	dtEnd := time.Now()
+	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
+}
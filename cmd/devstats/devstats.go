@@ -2,20 +2,163 @@ package main
 
 import (
 	lib "devstats"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	yaml "gopkg.in/yaml.v2"
 )
 
+// syncedFile - last sync times of all projects, used by `sync_schedule`
+const syncedFile = "/tmp/devstats_synced.json"
+
+// readSynced returns last sync time of every project, missing or broken file means no project was synced yet
+func readSynced() map[string]time.Time {
+	synced := make(map[string]time.Time)
+	data, err := ioutil.ReadFile(syncedFile)
+	if os.IsNotExist(err) {
+		return synced
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &synced)
+	}
+	if err != nil {
+		lib.Printf("Cannot read last sync times from '%s', syncing all projects: %v\n", syncedFile, err)
+		return make(map[string]time.Time)
+	}
+	return synced
+}
+
+// writeSynced saves last sync time of every project
+func writeSynced(synced map[string]time.Time) {
+	data, err := json.Marshal(synced)
+	lib.FatalOnError(err)
+	lib.FatalOnError(ioutil.WriteFile(syncedFile, data, 0644))
+}
+
+// timingsFile - durations of the last syncs of all projects (and of git repos update), used by `--plan` estimates
+const timingsFile = "/tmp/devstats_timings.json"
+
+// reposTimings - timings key of git repos update
+const reposTimings = "get_repos"
+
+// nTimings - number of the last durations kept for every key
+const nTimings = 5
+
+// readTimings returns the last durations of every project sync, missing or broken file means there are no timings
+func readTimings() map[string][]time.Duration {
+	timings := make(map[string][]time.Duration)
+	data, err := ioutil.ReadFile(timingsFile)
+	if err != nil || json.Unmarshal(data, &timings) != nil {
+		return make(map[string][]time.Duration)
+	}
+	return timings
+}
+
+// addTiming saves duration of `key` (project or git repos update) sync, keeps nTimings last durations
+func addTiming(timings map[string][]time.Duration, key string, took time.Duration) {
+	durations := append(timings[key], took)
+	if len(durations) > nTimings {
+		durations = durations[len(durations)-nTimings:]
+	}
+	timings[key] = durations
+	data, err := json.Marshal(timings)
+	lib.FatalOnError(err)
+	lib.FatalOnError(ioutil.WriteFile(timingsFile, data, 0644))
+}
+
+// estimate returns average of the last durations of `key`, false if there are none
+func estimate(timings map[string][]time.Duration, key string) (time.Duration, bool) {
+	durations := timings[key]
+	if len(durations) == 0 {
+		return 0, false
+	}
+	sum := time.Duration(0)
+	for _, took := range durations {
+		sum += took
+	}
+	return sum / time.Duration(len(durations)), true
+}
+
+// parseProjects returns all projects from "projects.yaml" `data`, enabled project names sorted by "order" and their sync schedules
+func parseProjects(data []byte) (*lib.AllProjects, []string, map[string]*lib.SyncSchedule, error) {
+	var projects lib.AllProjects
+	err := yaml.Unmarshal(data, &projects)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Parse sync schedules
+	names := []string{}
+	schedules := make(map[string]*lib.SyncSchedule)
+	for name, proj := range projects.Projects {
+		if proj.Disabled {
+			continue
+		}
+		schedule, err := lib.ParseSyncSchedule(proj.SyncSchedule)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("project '%s': %v", name, err)
+		}
+		schedules[name] = schedule
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return projects.Projects[names[i]].Order < projects.Projects[names[j]].Order })
+	return &projects, names, schedules, nil
+}
+
+// readProjects returns all projects from "projects.yaml", enabled project names sorted by "order" and their sync schedules
+func readProjects(ctx *lib.Ctx, dataPrefix string) (*lib.AllProjects, []string, map[string]*lib.SyncSchedule) {
+	data, err := ioutil.ReadFile(dataPrefix + ctx.ProjectsYaml)
+	lib.FatalOnError(err)
+	projects, names, schedules, err := parseProjects(data)
+	lib.FatalOnError(err)
+	return projects, names, schedules
+}
+
+// lastEvents returns time of the last GHA event in every project's database
+// Databases that cannot be queried are only logged, their projects are treated as the most stale
+func lastEvents(ctx *lib.Ctx, projects *lib.AllProjects, names []string) map[string]time.Time {
+	byDB := make(map[string]*time.Time)
+	last := make(map[string]time.Time)
+	for _, name := range names {
+		db := projects.Projects[name].PDB
+		dt, ok := byDB[db]
+		if !ok {
+			// Gerrit changes are read up to now, GHA events are behind them, so they are not used here
+			con := lib.PgConnDB(ctx, db)
+			err := lib.QueryRowSQL(
+				con,
+				ctx,
+				"select max(created_at) from gha_events where origin <> "+lib.NValue(1),
+				lib.OriginGerrit,
+			).Scan(&dt)
+			if err != nil {
+				lib.Printf("Cannot get last event of %s from %s: %v\n", name, db, err)
+			}
+			byDB[db] = dt
+		}
+		if dt != nil {
+			last[name] = *dt
+		}
+	}
+	return last
+}
+
 // Sync all projects from "projects.yaml", calling `gha2db_sync` for all of them
-func syncAllProjects() bool {
+// Projects with `sync_schedule` are only synced when they are due
+// `projectsData` is "projects.yaml" contents used by `devstats --daemon`, nil means read it
+func syncAllProjects(projectsData []byte) bool {
+	runStart := time.Now()
 	// Environment context parse
 	var ctx lib.Ctx
 	ctx.Init()
+	defer lib.NotifyOnPanic(&ctx, "devstats", runStart)
 
 	// Set non-fatal exec mode, we want to run sync for next project(s) if current fails
 	ctx.ExecFatal = false
@@ -29,11 +172,19 @@ func syncAllProjects() bool {
 	}
 
 	// Read defined projects
-	data, err := ioutil.ReadFile(dataPrefix + ctx.ProjectsYaml)
+	if projectsData == nil {
+		data, err := ioutil.ReadFile(dataPrefix + ctx.ProjectsYaml)
+		lib.FatalOnError(err)
+		projectsData = data
+	}
+	projects, names, schedules, err := parseProjects(projectsData)
 	lib.FatalOnError(err)
 
-	var projects lib.AllProjects
-	lib.FatalOnError(yaml.Unmarshal(data, &projects))
+	// Projects paused or forced by `devstats pause|force`, when they cannot be read "projects.yaml" is used as it is
+	controls, err := lib.ProjectControls(&ctx)
+	if err != nil {
+		lib.Printf("Cannot read projects control: %v\n", err)
+	}
 
 	// Create PID file (if not exists)
 	// If PID file exists, exit
@@ -50,18 +201,6 @@ func syncAllProjects() bool {
 	// Schedule remove PID file when finished
 	defer func() { lib.FatalOnError(os.Remove(pidFile)) }()
 
-	// Sort projects by "order"
-	orders := []int{}
-	projectsMap := make(map[int]string)
-	for name, proj := range projects.Projects {
-		if proj.Disabled {
-			continue
-		}
-		orders = append(orders, proj.Order)
-		projectsMap[proj.Order] = name
-	}
-	sort.Ints(orders)
-
 	// Only run clone/pull part here
 	// Remaining commit analysis in"gha2db_sync"
 	// after new commits are fetched from GHA
@@ -83,41 +222,619 @@ func syncAllProjects() bool {
 	if res != nil {
 		lib.Printf("Error updating git repos (took %v): %+v\n", dtEnd.Sub(dtStart), res)
 		fmt.Fprintf(os.Stderr, "%v: Error updating git repos (took %v): %+v\n", dtEnd, dtEnd.Sub(dtStart), res)
+		notify(&ctx, runStart, false, "Error updating git repos", []string{res.Error()})
 		return false
 	}
 	lib.Printf("Updated git repos, took: %v\n", dtEnd.Sub(dtStart))
+	timings := readTimings()
+	addTiming(timings, reposTimings, dtEnd.Sub(dtStart))
+
+	// Sync all projects that are due, the same time is used for all of them, so syncs of previous projects don't delay schedules
+	now := time.Now()
+	synced := readSynced()
+	due := []string{}
+	for _, name := range names {
+		control, ok := controls[name]
+		if ok && control.State == lib.ProjectPaused {
+			lib.Printf("Skipping #%d %s, paused at %s: %s\n", projects.Projects[name].Order, name, lib.ToYMDHMSDate(control.Dt), control.Reason)
+			continue
+		}
+		if ok && control.State == lib.ProjectForced {
+			lib.Printf("Forcing #%d %s, forced at %s: %s\n", projects.Projects[name].Order, name, lib.ToYMDHMSDate(control.Dt), control.Reason)
+			due = append(due, name)
+			continue
+		}
+		schedule := schedules[name]
+		if !schedule.Due(synced[name], now) {
+			lib.Printf("Skipping #%d %s, synced at %s, schedule: %s\n", projects.Projects[name].Order, name, lib.ToYMDHMSDate(synced[name]), schedule)
+			continue
+		}
+		due = append(due, name)
+	}
+
+	// The most stale projects sync first, so they catch up first when the host falls behind
+	if !ctx.SyncByOrder && len(due) > 1 {
+		last := lastEvents(&ctx, projects, due)
+		due = lib.OrderByStaleness(due, last)
+		info := []string{}
+		for _, name := range due {
+			dt := "unknown"
+			if _, ok := last[name]; ok {
+				dt = lib.ToYMDHDate(last[name])
+			}
+			info = append(info, name+" ("+dt+")")
+		}
+		lib.Printf("Sync order by last event: %s\n", strings.Join(info, ", "))
+	}
+	plan, err := lib.NewSyncPlan(projects, due)
+	lib.FatalOnError(err)
 
-	// Sync all projects
-	for _, order := range orders {
-		name := projectsMap[order]
+	// Independent projects are synced in parallel (up to GHA2DB_SYNC_PARALLEL), in the order above
+	parallel := ctx.SyncParallel
+	if thrN := lib.GetThreadsNum(&ctx); parallel > thrN {
+		parallel = thrN
+	}
+	var mtx sync.Mutex
+	errs := []string{}
+	nSynced := 0
+	plan.Run(parallel, func(name string) {
 		proj := projects.Projects[name]
-		lib.Printf("Syncing #%d %s\n", order, name)
+		if deps := plan.Deps(name); len(deps) > 0 {
+			lib.Printf("Syncing #%d %s (after %s)\n", proj.Order, name, strings.Join(deps, ", "))
+		} else {
+			lib.Printf("Syncing #%d %s\n", proj.Order, name)
+		}
 		dtStart := time.Now()
 		_, res := lib.ExecCommand(
 			&ctx,
 			[]string{
 				cmdPrefix + "gha2db_sync",
 			},
-			map[string]string{
-				"GHA2DB_PROJECT": name,
-				"PG_DB":          proj.PDB,
-				"IDB_DB":         proj.IDB,
-			},
+			// Failures are reported once for all projects below
+			lib.NotifyEnvOff(
+				map[string]string{
+					"GHA2DB_PROJECT": name,
+					"PG_DB":          proj.PDB,
+					"IDB_DB":         proj.IDB,
+				},
+			),
 		)
 		dtEnd := time.Now()
 		if res != nil {
 			lib.Printf("Error result for %s (took %v): %+v\n", name, dtEnd.Sub(dtStart), res)
 			fmt.Fprintf(os.Stderr, "%v: Error result for %s (took %v): %+v\n", dtEnd, name, dtEnd.Sub(dtStart), res)
-			continue
+			mtx.Lock()
+			errs = append(errs, fmt.Sprintf("%s: %v", name, res))
+			mtx.Unlock()
+			return
 		}
 		lib.Printf("Synced %s, took: %v\n", name, dtEnd.Sub(dtStart))
-	}
+		if controls[name].State == lib.ProjectForced {
+			err := lib.ClearProjectControl(&ctx, name, lib.ProjectForced)
+			if err != nil {
+				lib.Printf("Cannot clear forced sync of %s: %v\n", name, err)
+			}
+		}
+		mtx.Lock()
+		synced[name] = now
+		writeSynced(synced)
+		addTiming(timings, name, dtEnd.Sub(dtStart))
+		nSynced++
+		mtx.Unlock()
+	})
+	notify(
+		&ctx,
+		runStart,
+		len(errs) == 0,
+		fmt.Sprintf("Synced %d projects, %d failed", nSynced, len(errs)),
+		errs,
+	)
 	return true
 }
 
+// planMetric - metric from project's "metrics.yaml", only fields needed by `--plan`
+type planMetric struct {
+	Name              string   `yaml:"name"`
+	MetricSQL         string   `yaml:"sql"`
+	Periods           string   `yaml:"periods"`
+	Aggregate         string   `yaml:"aggregate"`
+	Skip              string   `yaml:"skip"`
+	Histogram         bool     `yaml:"histogram"`
+	AnnotationsRanges bool     `yaml:"annotations_ranges"`
+	Tags              []string `yaml:"tags"`
+}
+
+// planPeriods returns periods (with aggregate suffixes) `gha2db_sync` computes for metric at `now`, like it does
+// In catch-up mode all periods are computed
+func planPeriods(ctx *lib.Ctx, metric planMetric, now time.Time, catchUp bool) []string {
+	if metric.AnnotationsRanges {
+		return []string{"quick ranges"}
+	}
+	aggregate := metric.Aggregate
+	if aggregate == "" {
+		aggregate = "1"
+	}
+	skipMap := make(map[string]struct{})
+	for _, skip := range strings.Split(metric.Skip, ",") {
+		skipMap[skip] = struct{}{}
+	}
+	periods := []string{}
+	for _, aggrStr := range strings.Split(aggregate, ",") {
+		aggrSuffix := aggrStr
+		if aggrSuffix == "1" {
+			aggrSuffix = ""
+		}
+		for _, period := range strings.Split(metric.Periods, ",") {
+			if _, found := skipMap[period+aggrSuffix]; found {
+				continue
+			}
+			if ctx.ResetIDB || catchUp || lib.ComputePeriodAtThisDate(period, now) {
+				periods = append(periods, period+aggrSuffix)
+			}
+		}
+	}
+	return periods
+}
+
+// Outputs what `devstats` would do now: projects in sync order, GHA ranges to ingest, metrics and periods to compute
+// and durations estimated from the last syncs, nothing is run and no database is accessed
+// Ranges start at the last sync done by `devstats` on this host (metrics can still skip unchanged histograms and past quick ranges)
+func syncPlan() {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+	projects, names, schedules := readProjects(&ctx, dataPrefix)
+	now := time.Now()
+	synced := readSynced()
+	timings := readTimings()
+	estimated := func(key string) string {
+		if took, ok := estimate(timings, key); ok {
+			return took.Round(time.Second).String()
+		}
+		return "unknown"
+	}
+
+	fmt.Printf("Plan at %s (nothing is run)\n", lib.ToYMDHMSDate(now))
+	fmt.Printf("Update git repos of all projects, estimated %s\n", estimated(reposTimings))
+	due := []string{}
+	for _, name := range names {
+		if schedules[name].Due(synced[name], now) {
+			due = append(due, name)
+		}
+	}
+	plan, err := lib.NewSyncPlan(projects, due)
+	lib.FatalOnError(err)
+	total, unknown := time.Duration(0), 0
+	if took, ok := estimate(timings, reposTimings); ok {
+		total += took
+	}
+	for _, name := range names {
+		proj := projects.Projects[name]
+		if !schedules[name].Due(synced[name], now) {
+			fmt.Printf("#%d %s: skipped, synced at %s, schedule: %s\n", proj.Order, name, lib.ToYMDHMSDate(synced[name]), schedules[name])
+			continue
+		}
+		after := ""
+		if deps := plan.Deps(name); len(deps) > 0 {
+			after = " after " + strings.Join(deps, ", ")
+		}
+		fmt.Printf("#%d %s: sync%s, estimated %s\n", proj.Order, name, after, estimated(name))
+		if took, ok := estimate(timings, name); ok {
+			total += took
+		} else {
+			unknown++
+		}
+
+		// GHA hours since the last sync
+		from, since := ctx.DefaultStartDate, "start date"
+		if proj.StartDate != nil {
+			from = *proj.StartDate
+		}
+		if last, ok := synced[name]; ok {
+			from, since = last, "last sync"
+		}
+		from = lib.HourStart(from)
+		catchUp := ctx.CatchUpHours > 0 && now.Sub(from) > time.Duration(ctx.CatchUpHours)*time.Hour
+		mode := ""
+		if catchUp {
+			mode = fmt.Sprintf(", catch-up mode, %d batches", len(lib.CatchUpBatches(from, now)))
+		}
+		fmt.Printf(
+			"    ingest: %s - %s (%d hours since %s%s)\n",
+			lib.ToYMDHDate(from), lib.ToYMDHDate(now), int(lib.HourStart(now).Sub(from)/time.Hour)+1, since, mode,
+		)
+
+		// Metrics selected by GHA2DB_METRICS with periods due now
+		metricsYaml := dataPrefix + "metrics/" + name + "/metrics.yaml"
+		data, err := ioutil.ReadFile(metricsYaml)
+		var allMetrics struct {
+			Metrics []planMetric `yaml:"metrics"`
+		}
+		if err == nil {
+			err = yaml.Unmarshal(data, &allMetrics)
+		}
+		if err != nil {
+			fmt.Printf("    metrics: cannot read %s: %v\n", metricsYaml, err)
+			continue
+		}
+		// Periods are due by project's time zone clock
+		loc, err := time.LoadLocation(proj.TimeZone)
+		if err != nil {
+			fmt.Printf("    metrics: invalid time zone: %v\n", err)
+			continue
+		}
+		filter := lib.NewMetricFilter(ctx.OnlyMetrics)
+		lines := []string{}
+		for _, metric := range allMetrics.Metrics {
+			if !filter.Selected(metric.Name, metric.MetricSQL, metric.Tags) {
+				continue
+			}
+			periods := planPeriods(&ctx, metric, now.In(loc), catchUp)
+			if len(periods) == 0 {
+				continue
+			}
+			kind := ""
+			if metric.Histogram {
+				kind = " (histogram)"
+			}
+			lines = append(lines, fmt.Sprintf("      %s%s: %s", metric.Name, kind, strings.Join(periods, ", ")))
+		}
+		fmt.Printf("    metrics: %d/%d to compute\n", len(lines), len(allMetrics.Metrics))
+		for _, line := range lines {
+			fmt.Printf("%s\n", line)
+		}
+	}
+	parallel := ctx.SyncParallel
+	if thrN := lib.GetThreadsNum(&ctx); parallel > thrN {
+		parallel = thrN
+	}
+	fmt.Printf("%d/%d projects to sync (up to %d at once), estimated %s one by one", len(due), len(names), parallel, total.Round(time.Second))
+	if unknown > 0 {
+		fmt.Printf(" plus %d projects never synced by devstats here", unknown)
+	}
+	fmt.Printf("\n")
+}
+
+// notify - reports result of syncing all projects, notification errors are only logged
+func notify(ctx *lib.Ctx, dtStart time.Time, success bool, summary string, errs []string) {
+	err := lib.Notify(ctx, lib.NewNotification(ctx, "devstats", dtStart, success, summary, errs))
+	if err != nil {
+		lib.Printf("%v\n", err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+}
+
+// Outputs sync status of all projects (from `gha_sync_status` table in `devstats` database), as a table or as JSON
+// Enabled projects from "projects.yaml" that were never synced are listed too
+func syncStatus(asJSON bool) {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+
+	// Read defined projects
+	data, err := ioutil.ReadFile(dataPrefix + ctx.ProjectsYaml)
+	lib.FatalOnError(err)
+	var projects lib.AllProjects
+	lib.FatalOnError(yaml.Unmarshal(data, &projects))
+
+	statuses, err := lib.SyncStatuses(&ctx)
+	lib.FatalOnError(err)
+	known := make(map[string]struct{})
+	for _, status := range statuses {
+		known[status.Project] = struct{}{}
+	}
+	for name, proj := range projects.Projects {
+		if _, ok := known[name]; ok || proj.Disabled {
+			continue
+		}
+		statuses = append(statuses, lib.SyncStatus{Project: name})
+	}
+	controls, err := lib.ProjectControls(&ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot read projects control: %v\n", err)
+	}
+	for i := range statuses {
+		if control, ok := controls[statuses[i].Project]; ok {
+			statuses[i].Control = &control
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Project < statuses[j].Project })
+
+	if asJSON {
+		data, err := json.MarshalIndent(statuses, "", "  ")
+		lib.FatalOnError(err)
+		fmt.Printf("%s\n", data)
+		return
+	}
+	dt := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return lib.ToYMDHMSDate(*t)
+	}
+	fmt.Printf("%-20s %-8s %-19s %-19s %-12s %-10s %s\n", "Project", "State", "Last start", "Last success", "Took", "Events", "Last error")
+	for _, status := range statuses {
+		state := "ok"
+		switch {
+		case status.LastStart == nil:
+			state = "never"
+		case status.Running:
+			state = "running"
+		case status.Control != nil:
+			state = status.Control.State
+		case status.LastErrorDt != nil && (status.LastSuccess == nil || status.LastErrorDt.After(*status.LastSuccess)):
+			state = "failed"
+		}
+		took, events, lastError := "-", "-", ""
+		if status.TookMs != nil {
+			took = (time.Duration(*status.TookMs) * time.Millisecond).String()
+		}
+		if status.Events != nil {
+			events = fmt.Sprintf("%d", *status.Events)
+		}
+		if status.LastError != nil {
+			lastError = dt(status.LastErrorDt) + ": " + lib.TruncToBytes(strings.Replace(*status.LastError, "\n", " ", -1), 0x80)
+		} else if status.Timeouts != nil {
+			lastError = "timed out: " + lib.TruncToBytes(*status.Timeouts, 0x80)
+		}
+		if status.Control != nil && status.Control.Reason != "" {
+			lastError = status.Control.State + ": " + status.Control.Reason + " " + lastError
+		}
+		fmt.Printf(
+			"%-20s %-8s %-19s %-19s %-12s %-10s %s\n",
+			status.Project, state, dt(status.LastStart), dt(status.LastSuccess), took, events, lastError,
+		)
+	}
+}
+
+// controlProject pauses, forces or resumes project defined in "projects.yaml" (`gha_projects_control` table in `devstats` database)
+// Paused project is skipped by `devstats` until it is resumed, forced project is synced on the next run even if it isn't due
+func controlProject(action, project, reason string) {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+
+	// Read defined projects
+	projects, _, _ := readProjects(&ctx, dataPrefix)
+	proj, ok := projects.Projects[project]
+	if !ok {
+		lib.FatalOnError(fmt.Errorf("project '%s' is not defined in %s", project, ctx.ProjectsYaml))
+	}
+	if proj.Disabled {
+		fmt.Printf("Warning: project %s is disabled in %s, `devstats` doesn't sync it anyway\n", project, ctx.ProjectsYaml)
+	}
+	switch action {
+	case "pause":
+		lib.FatalOnError(lib.SetProjectControl(&ctx, project, lib.ProjectPaused, reason))
+		fmt.Printf("Paused %s\n", project)
+	case "force":
+		lib.FatalOnError(lib.SetProjectControl(&ctx, project, lib.ProjectForced, reason))
+		fmt.Printf("Forced %s, it will be synced on the next run\n", project)
+	case "resume":
+		lib.FatalOnError(lib.ClearProjectControl(&ctx, project, ""))
+		fmt.Printf("Resumed %s\n", project)
+	}
+}
+
+// migrateProjects migrates databases of all enabled projects and `devstats` database (or of `only` projects)
+// to schema version `target`, -1 - the latest
+// Database is migrated holding its sync lock (waits up to GHA2DB_LOCK_TIMEOUT), so tables used by a running sync don't change
+// With `status` it only outputs schema version of every database, returns false when any database failed
+func migrateProjects(status bool, target int, only []string) bool {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+
+	// Read defined projects
+	projects, names, _ := readProjects(&ctx, dataPrefix)
+	if len(only) > 0 {
+		names = only
+	}
+	if target < 0 {
+		target = lib.LatestSchemaVersion()
+	}
+	ok := true
+	// Databases by project name, `devstats` database (logs, sync status) is migrated with all projects
+	dbs := make(map[string]string)
+	for _, name := range names {
+		proj, defined := projects.Projects[name]
+		if !defined {
+			lib.Printf("Project '%s' is not defined in %s\n", name, ctx.ProjectsYaml)
+			ok = false
+			continue
+		}
+		dbs[name] = proj.PDB
+	}
+	if len(only) == 0 {
+		names = append(names, "devstats")
+		dbs["devstats"] = "devstats"
+	}
+	migrated := make(map[string]struct{})
+	for _, name := range names {
+		db, defined := dbs[name]
+		if !defined {
+			continue
+		}
+		// Projects can share database
+		if _, done := migrated[db]; done {
+			continue
+		}
+		migrated[db] = struct{}{}
+		pctx := ctx
+		pctx.PgDB = db
+		con := lib.PgConn(&pctx)
+		if status {
+			version, err := lib.SchemaVersion(con, &pctx)
+			if err != nil {
+				fmt.Printf("%-20s %-20s %v\n", name, db, err)
+				ok = false
+				continue
+			}
+			fmt.Printf("%-20s %-20s %d/%d\n", name, db, version, lib.LatestSchemaVersion())
+			continue
+		}
+		lock, err := lib.AcquireLock(con, &pctx, lib.SyncLock)
+		if err != nil {
+			lib.Printf("%s: %v\n", name, err)
+			ok = false
+			continue
+		}
+		from, to, err := lib.Migrate(con, &pctx, target)
+		lib.FatalOnError(lock.Unlock())
+		if err != nil {
+			lib.Printf("%s: %v\n", name, err)
+			ok = false
+		}
+		lib.Printf("%s: schema version %d -> %d\n", name, from, to)
+	}
+	return ok
+}
+
+// loadProjects reads "projects.yaml" and checks it can be synced: it parses, schedules are valid and dependencies have no cycles
+func loadProjects(ctx *lib.Ctx, dataPrefix string) ([]byte, *lib.AllProjects, error) {
+	data, err := ioutil.ReadFile(dataPrefix + ctx.ProjectsYaml)
+	if err != nil {
+		return nil, nil, err
+	}
+	projects, names, _, err := parseProjects(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = lib.NewSyncPlan(projects, names)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, projects, nil
+}
+
+// syncDaemon syncs all projects every GHA2DB_DAEMON_INTERVAL minutes until SIGINT/SIGTERM (current run is finished first)
+// "projects.yaml" is re-read before every run, so added, removed and disabled projects are picked up without restart
+// When it is broken the last good one is used, so a bad edit doesn't stop syncs
+func syncDaemon() {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+
+	runCtx, cancel := lib.SignalContext()
+	defer cancel()
+	interval := time.Duration(ctx.DaemonInterval) * time.Minute
+	lib.Printf("Running devstats daemon, syncing every %v\n", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var (
+		good     []byte
+		goodHash string
+		current  *lib.AllProjects
+	)
+	for {
+		// Projects are only parsed again when the file changed
+		data, err := ioutil.ReadFile(dataPrefix + ctx.ProjectsYaml)
+		if err == nil && lib.QueryHash(string(data)) != goodHash {
+			var projects *lib.AllProjects
+			data, projects, err = loadProjects(&ctx, dataPrefix)
+			if err == nil {
+				if current != nil {
+					if changes := lib.ProjectChanges(current, projects); len(changes) > 0 {
+						lib.Printf("%s changed: %s\n", ctx.ProjectsYaml, strings.Join(changes, ", "))
+					}
+				}
+				good, goodHash, current = data, lib.QueryHash(string(data)), projects
+			}
+		}
+		if err != nil {
+			// Without any good "projects.yaml" there is nothing to sync
+			if good == nil {
+				lib.FatalOnError(err)
+			}
+			lib.Printf("Cannot reload %s, using the last good one: %v\n", ctx.ProjectsYaml, err)
+			fmt.Fprintf(os.Stderr, "%v: Cannot reload %s, using the last good one: %v\n", time.Now(), ctx.ProjectsYaml, err)
+		}
+		dtStart := time.Now()
+		if syncAllProjects(good) {
+			lib.Printf("Synced all projects in: %v\n", time.Now().Sub(dtStart))
+		}
+		select {
+		case <-ticker.C:
+		case <-runCtx.Done():
+			lib.Printf("Stopping devstats daemon\n")
+			return
+		}
+	}
+}
+
 func main() {
+	// `devstats status [--json]` outputs sync status of all projects
+	if len(os.Args) > 1 && os.Args[1] == "status" {
+		syncStatus(len(os.Args) > 2 && os.Args[2] == "--json")
+		return
+	}
+	// `devstats pause|force project [reason]` and `devstats resume project` control project without editing "projects.yaml"
+	if len(os.Args) > 1 && (os.Args[1] == "pause" || os.Args[1] == "force" || os.Args[1] == "resume") {
+		if len(os.Args) < 3 {
+			fmt.Printf("Required project name: devstats %s project [reason]\n", os.Args[1])
+			os.Exit(1)
+		}
+		controlProject(os.Args[1], os.Args[2], strings.Join(os.Args[3:], " "))
+		return
+	}
+	// `devstats migrate [status|version] [project ...]` migrates project databases to the latest (or given) schema version
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		args := os.Args[2:]
+		status, target := false, -1
+		if len(args) > 0 && args[0] == "status" {
+			status, args = true, args[1:]
+		} else if len(args) > 0 {
+			if version, err := strconv.Atoi(args[0]); err == nil {
+				target, args = version, args[1:]
+			}
+		}
+		if !migrateProjects(status, target, args) {
+			os.Exit(1)
+		}
+		return
+	}
+	// `devstats --plan` outputs what would be synced now, without running anything
+	if len(os.Args) > 1 && os.Args[1] == "--plan" {
+		syncPlan()
+		return
+	}
+	// `devstats --daemon` syncs all projects every GHA2DB_DAEMON_INTERVAL minutes instead of being run by cron
+	if len(os.Args) > 1 && os.Args[1] == "--daemon" {
+		syncDaemon()
+		return
+	}
 	dtStart := time.Now()
-	synced := syncAllProjects()
+	synced := syncAllProjects(nil)
 	dtEnd := time.Now()
 	if synced {
 		lib.Printf("Synced all projects in: %v\n", dtEnd.Sub(dtStart))
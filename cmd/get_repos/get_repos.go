@@ -1,18 +1,36 @@
 package main
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
+	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	lib "devstats"
 
+	"github.com/google/go-github/github"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	gitssh "gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -24,6 +42,193 @@ type dbCommits struct {
 	filesSkipPattern string
 }
 
+// dbConfig holds settings of projects using a given database
+// Projects can share database, its repos are processed when they match repos filter of any of them
+type dbConfig struct {
+	filesSkipPattern string
+	filters          []repoFilter
+	lfsFetch         bool
+	// GHA2DB_INCREMENTAL_REPOS: max event id when repos list was read and repos that have to be processed to save it as a watermark
+	eventID int64
+	repos   []string
+}
+
+// repoFilter holds project's repos_include and repos_exclude patterns
+type repoFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// matches returns true if repo matches any include pattern (or there are none) and no exclude pattern
+func (f repoFilter) matches(repo string) bool {
+	included := len(f.include) == 0
+	for _, re := range f.include {
+		if re.MatchString(repo) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+	for _, re := range f.exclude {
+		if re.MatchString(repo) {
+			return false
+		}
+	}
+	return true
+}
+
+// repoMatches returns true if repo matches repos filter of any project using the database
+func (c dbConfig) repoMatches(repo string) bool {
+	for _, filter := range c.filters {
+		if filter.matches(repo) {
+			return true
+		}
+	}
+	return len(c.filters) == 0
+}
+
+// gitAuth holds credentials and clone URLs used to clone/pull/fetch repos of a given org
+type gitAuth struct {
+	token  string
+	sshKey string
+	// Clone URL templates from projects.yaml: "org" or "org/repo" -> URL
+	urls map[string]string
+	// URL prefixes token can be sent to
+	hosts []string
+	// Repos that need git LFS objects fetched (from projects with `lfs_fetch`)
+	lfs map[string]bool
+}
+
+// newGitAuth creates credentials, token containing "/" is a file name to read token from
+func newGitAuth(token, sshKey string) gitAuth {
+	if strings.Contains(token, "/") {
+		bytes, err := ioutil.ReadFile(token)
+		lib.FatalOnError(err)
+		token = strings.TrimSpace(string(bytes))
+	}
+	return gitAuth{token: token, sshKey: sshKey, hosts: []string{"https://github.com/"}, lfs: make(map[string]bool)}
+}
+
+// withURLs returns credentials using given clone URL templates
+// When `trusted` (org has its own credentials in projects.yaml), token is also sent to templates' hosts
+// Global GHA2DB_GIT_TOKEN is only sent to GitHub
+func (a gitAuth) withURLs(urls map[string]string, trusted bool) gitAuth {
+	a.urls = urls
+	if !trusted {
+		return a
+	}
+	hosts := append([]string{}, a.hosts...)
+	for _, url := range urls {
+		if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+			continue
+		}
+		ary := strings.SplitN(url, "/", 4)
+		if len(ary) < 3 {
+			continue
+		}
+		host := ary[0] + "//" + ary[2] + "/"
+		found := false
+		for _, h := range hosts {
+			if h == host {
+				found = true
+				break
+			}
+		}
+		if !found {
+			hosts = append(hosts, host)
+		}
+	}
+	a.hosts = hosts
+	return a
+}
+
+// urlTemplate returns clone URL template defined for given repo or its org, "" if none
+func (a gitAuth) urlTemplate(orgRepo string) string {
+	if url, ok := a.urls[orgRepo]; ok {
+		return url
+	}
+	return a.urls[strings.Split(orgRepo, "/")[0]]
+}
+
+// onGitHub returns true if repo is cloned from GitHub (there is no URL template for it)
+func (a gitAuth) onGitHub(orgRepo string) bool {
+	return a.urlTemplate(orgRepo) == ""
+}
+
+// cloneURL returns URL from template when defined, then SSH clone URL when SSH key is set, https URL otherwise
+// Templates can use {{org}}, {{repo}} and {{org_repo}} placeholders
+func (a gitAuth) cloneURL(orgRepo string) string {
+	if url := a.urlTemplate(orgRepo); url != "" {
+		ary := strings.Split(orgRepo, "/")
+		url = strings.Replace(url, "{{org_repo}}", orgRepo, -1)
+		url = strings.Replace(url, "{{org}}", ary[0], -1)
+		return strings.Replace(url, "{{repo}}", ary[1], -1)
+	}
+	if a.sshKey != "" {
+		return "git@github.com:" + orgRepo + ".git"
+	}
+	return "https://github.com/" + orgRepo + ".git"
+}
+
+// tokenHost returns true if token can be sent to a given URL
+func (a gitAuth) tokenHost(url string) bool {
+	for _, host := range a.hosts {
+		if strings.HasPrefix(url, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// method returns go-git auth method to use with a given remote URL, nil means anonymous access
+func (a gitAuth) method(url string) (transport.AuthMethod, error) {
+	if strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") {
+		if a.token == "" || !a.tokenHost(url) {
+			return nil, nil
+		}
+		// GitHub accepts any non-empty user name with a token as password
+		return &githttp.BasicAuth{Username: "devstats", Password: a.token}, nil
+	}
+	if a.sshKey == "" {
+		return nil, nil
+	}
+	return gitssh.NewPublicKeysFromFile("git", a.sshKey, "")
+}
+
+// env returns environment needed to pass credentials to git binary
+func (a gitAuth) env() map[string]string {
+	// LFS objects are only fetched explicitly by `git_lfs.sh` for projects that want them
+	env := map[string]string{"GIT_TERMINAL_PROMPT": "0", "GIT_LFS_SKIP_SMUDGE": "1"}
+	if a.sshKey != "" {
+		env["GIT_SSH_COMMAND"] = "ssh -i '" + a.sshKey + "' -o IdentitiesOnly=yes"
+	}
+	if a.token != "" {
+		// Pass token via environment instead of command line or URL (which is saved in .git/config)
+		header := "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte("devstats:"+a.token))
+		env["GIT_CONFIG_COUNT"] = strconv.Itoa(len(a.hosts))
+		for i, host := range a.hosts {
+			env["GIT_CONFIG_KEY_"+strconv.Itoa(i)] = "http." + host + ".extraheader"
+			env["GIT_CONFIG_VALUE_"+strconv.Itoa(i)] = header
+		}
+	}
+	return env
+}
+
+// snapshotMarker - file created in repos unpacked from GitHub tarball, they have no git history
+const snapshotMarker = ".devstats_snapshot"
+
+// gitLimiter limits git clones/pulls started per minute by all threads, nil means no limit
+var gitLimiter *lib.RateLimiter
+
+// Shallow cloned repos that were already unshallowed (or tried to)
+var (
+	unshallowMtx     sync.Mutex
+	unshallowRepoMtx = make(map[string]*sync.Mutex)
+	unshallowed      = make(map[string]bool)
+)
+
 // dirExists checks if given path exist and if is a directory
 func dirExists(path string) (bool, error) {
 	if path[len(path)-1:] == "/" {
@@ -43,7 +248,9 @@ func dirExists(path string) (bool, error) {
 }
 
 // getRepos returns map { 'org' --> list of repos } for all devstats projects
-func getRepos(ctx *lib.Ctx) (map[string]string, map[string][]string) {
+// It also returns git credentials to use for every org and map { 'org' --> list of repos } of all repos in `gha_repos`
+// tables, including ones skipped by GHA2DB_INCREMENTAL_REPOS (clones that must be kept by GHA2DB_PRUNE_REPOS)
+func getRepos(ctx *lib.Ctx) (map[string]dbConfig, map[string][]string, map[string]gitAuth, map[string][]string) {
 	// Process all projects, or restrict from environment variable?
 	onlyProjects := make(map[string]bool)
 	selectedProjects := false
@@ -67,19 +274,61 @@ func getRepos(ctx *lib.Ctx) (map[string]string, map[string][]string) {
 
 	var projects lib.AllProjects
 	lib.FatalOnError(yaml.Unmarshal(data, &projects))
-	dbs := make(map[string]string)
+	dbs := make(map[string]dbConfig)
+	orgsAuth := make(map[string]gitAuth)
+	orgsURLs := make(map[string]map[string]string)
+	orgsCloneAuth := make(map[string]lib.CloneAuth)
+	orgsCloneAuthProject := make(map[string]string)
 	for name, proj := range projects.Projects {
 		if proj.Disabled || (selectedProjects && !onlyProjects[name]) {
 			continue
 		}
-		dbs[proj.PDB] = proj.FilesSkipPattern
+		// Commits files of a database are saved once, so projects sharing it must skip the same files
+		cfg, shared := dbs[proj.PDB]
+		if shared && cfg.filesSkipPattern != proj.FilesSkipPattern {
+			lib.FatalOnError(fmt.Errorf("projects using database %s have different files_skip_pattern", proj.PDB))
+		}
+		cfg.filesSkipPattern = proj.FilesSkipPattern
+		cfg.lfsFetch = cfg.lfsFetch || proj.LFSFetch
+		filter := repoFilter{}
+		for _, pattern := range proj.ReposInclude {
+			filter.include = append(filter.include, regexp.MustCompile(pattern))
+		}
+		for _, pattern := range proj.ReposExclude {
+			filter.exclude = append(filter.exclude, regexp.MustCompile(pattern))
+		}
+		cfg.filters = append(cfg.filters, filter)
+		dbs[proj.PDB] = cfg
+		// Projects are visited in random order, so org's credentials (and clone URLs) given by many projects must be the same
+		for org, auth := range proj.CloneAuth {
+			if other, ok := orgsCloneAuth[org]; ok && other != auth {
+				lib.FatalOnError(fmt.Errorf("projects %s and %s have different clone_auth for org %s", orgsCloneAuthProject[org], name, org))
+			}
+			orgsCloneAuth[org], orgsCloneAuthProject[org] = auth, name
+			orgsAuth[org] = newGitAuth(auth.Token, auth.SSHKey)
+		}
+		for key, url := range proj.CloneURLs {
+			org := strings.Split(key, "/")[0]
+			if _, ok := orgsURLs[org]; !ok {
+				orgsURLs[org] = make(map[string]string)
+			}
+			if other, ok := orgsURLs[org][key]; ok && other != url {
+				lib.FatalOnError(fmt.Errorf("projects have different clone_urls for %s: %s, %s", key, other, url))
+			}
+			orgsURLs[org][key] = url
+		}
+	}
+	trusted := make(map[string]bool)
+	for org := range orgsAuth {
+		trusted[org] = true
 	}
+	defaultAuth := newGitAuth(ctx.GitToken, ctx.GitSSHKey)
 
 	allRepos := make(map[string][]string)
-	for db := range dbs {
+	knownRepos := make(map[string][]string)
+	for db, cfg := range dbs {
 		// Connect to Postgres `db` database.
 		con := lib.PgConnDB(ctx, db)
-		defer func() { lib.FatalOnError(con.Close()) }()
 
 		// Get list of orgs in a given database
 		rows, err := con.Query("select distinct name from gha_repos where name like '%/%'")
@@ -91,9 +340,41 @@ func getRepos(ctx *lib.Ctx) (map[string]string, map[string][]string) {
 		)
 		for rows.Next() {
 			lib.FatalOnError(rows.Scan(&repo))
+			if !cfg.repoMatches(repo) {
+				continue
+			}
 			repos = append(repos, repo)
 		}
 		lib.FatalOnError(rows.Err())
+		for _, repo := range repos {
+			org := strings.Split(repo, "/")[0]
+			knownRepos[org] = append(knownRepos[org], repo)
+		}
+
+		// Only process orgs with new events since last run
+		if ctx.IncrementalRepos {
+			orgs, all, eventID := changedOrgs(con)
+			if !all && len(orgs) == 0 {
+				lib.Printf("%s: no new events since last run, skipping\n", db)
+				delete(dbs, db)
+				continue
+			}
+			if !all {
+				changed := []string{}
+				for _, repo := range repos {
+					if orgs[strings.Split(repo, "/")[0]] {
+						changed = append(changed, repo)
+					}
+				}
+				if ctx.Debug > 0 {
+					lib.Printf("%s: %d orgs with new events, processing %d/%d repos\n", db, len(orgs), len(changed), len(repos))
+				}
+				repos = changed
+			}
+			cfg.eventID = eventID
+			cfg.repos = repos
+			dbs[db] = cfg
+		}
 
 		// Create map of distinct "org" --> list of repos
 		for _, repo := range repos {
@@ -106,48 +387,868 @@ func getRepos(ctx *lib.Ctx) (map[string]string, map[string][]string) {
 			if !ok {
 				allRepos[org] = []string{}
 			}
+			_, ok = orgsAuth[org]
+			if !ok {
+				orgsAuth[org] = defaultAuth
+			}
+			if cfg.lfsFetch {
+				orgsAuth[org].lfs[repo] = true
+			}
 			ary = append(allRepos[org], repo)
 			allRepos[org] = ary
 		}
 	}
 
-	// return final map
-	return dbs, allRepos
+	// Clone URL templates of orgs that have any repos
+	for org, urls := range orgsURLs {
+		if auth, ok := orgsAuth[org]; ok {
+			orgsAuth[org] = auth.withURLs(urls, trusted[org])
+		}
+	}
+
+	// return final maps
+	return dbs, allRepos, orgsAuth, knownRepos
+}
+
+// cloneRepo clones given repo into rwd directory
+// Partial clones are not supported by go-git, we need to call git binary in such case
+func cloneRepo(gctx context.Context, ctx *lib.Ctx, orgRepo, rwd string, auth gitAuth) error {
+	url := auth.cloneURL(orgRepo)
+	if ctx.PartialClone {
+		// We cannot chdir because this is a multithreaded app
+		// And all threads share CWD (current working directory)
+		cmdAndArgs := []string{"git", "clone", "--filter=blob:none"}
+		if ctx.MirrorClone {
+			cmdAndArgs = append(cmdAndArgs, "--mirror")
+		}
+		if ctx.ShallowClone > 0 {
+			cmdAndArgs = append(cmdAndArgs, "--depth", strconv.Itoa(ctx.ShallowClone), "--no-single-branch")
+		}
+		cmdAndArgs = append(cmdAndArgs, url, rwd)
+		_, err := lib.ExecCommand(ctx, cmdAndArgs, auth.env())
+		return err
+	}
+	method, err := auth.method(url)
+	if err != nil {
+		return err
+	}
+	options := &git.CloneOptions{URL: url, Auth: method, Depth: ctx.ShallowClone}
+	if ctx.Debug > 1 {
+		options.Progress = os.Stdout
+	}
+	repo, err := git.PlainCloneContext(gctx, rwd, ctx.MirrorClone, options)
+	if err == nil && ctx.MirrorClone {
+		// Bare clone only has default branch, get all other branches & tags
+		err = fetchMirror(gctx, ctx, repo, method)
+	}
+	if err != nil {
+		// Do not leave partially cloned repo, next run would try to pull it
+		_ = os.RemoveAll(rwd)
+	}
+	return err
+}
+
+// fetchMirror updates all branches and tags of a bare repo (like `git remote update` on `git clone --mirror`)
+func fetchMirror(gctx context.Context, ctx *lib.Ctx, repo *git.Repository, method transport.AuthMethod) error {
+	options := &git.FetchOptions{
+		RemoteName: "origin",
+		Auth:       method,
+		RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"},
+		Force:      true,
+	}
+	if ctx.Debug > 1 {
+		options.Progress = os.Stdout
+	}
+	err := repo.FetchContext(gctx, options)
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+	return err
+}
+
+// updateRepo updates repo cloned into rwd directory
+// Bare repos are fetched, repos with working tree are `git reset --hard` and then `git pull`
+func updateRepo(gctx context.Context, ctx *lib.Ctx, rwd string, auth gitAuth) error {
+	repo, err := git.PlainOpen(rwd)
+	if err != nil {
+		return err
+	}
+	// Credentials type depends on how repo was cloned
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return err
+	}
+	method, err := auth.method(remote.Config().URLs[0])
+	if err != nil {
+		return err
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	if cfg.Core.IsBare {
+		return fetchMirror(gctx, ctx, repo, method)
+	}
+	tree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	err = tree.Reset(&git.ResetOptions{Mode: git.HardReset})
+	if err != nil {
+		return err
+	}
+	options := &git.PullOptions{RemoteName: "origin", Auth: method}
+	if ctx.Debug > 1 {
+		options.Progress = os.Stdout
+	}
+	err = tree.PullContext(gctx, options)
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+	return err
+}
+
+// permanentGitError returns true for errors that will not go away when retried
+func permanentGitError(err error) bool {
+	return err == transport.ErrRepositoryNotFound ||
+		err == transport.ErrAuthenticationRequired ||
+		err == transport.ErrAuthorizationFailed ||
+		err == transport.ErrEmptyRemoteRepository
+}
+
+// retryGit calls git operation `f` retrying it up to ctx.CloneRetries times on failure
+// Waits ctx.CloneBackoff seconds * 2^(try-1) plus random jitter between tries
+// It stops retrying when `gctx` is cancelled
+func retryGit(gctx context.Context, ctx *lib.Ctx, orgRepo, op string, f func() error) (err error) {
+	for try := 0; ; try++ {
+		// Every try counts against GHA2DB_CLONES_PER_MINUTE
+		err = gitLimiter.Wait(gctx)
+		if err != nil {
+			return
+		}
+		err = f()
+		if err == nil || try >= ctx.CloneRetries || permanentGitError(err) || gctx.Err() != nil {
+			return
+		}
+		backoff := time.Duration(ctx.CloneBackoff) * time.Second << uint(try)
+		backoff += time.Duration(rand.Int63n(int64(backoff) + 1))
+		if ctx.Debug > 0 {
+			lib.Printf("%s %s failed (try %d/%d), retrying after %v: %+v\n", op, orgRepo, try+1, ctx.CloneRetries+1, backoff, err)
+		}
+		select {
+		case <-time.After(backoff):
+		case <-gctx.Done():
+			return
+		}
+	}
+}
+
+// changedOrgs returns orgs that have events newer than the last `get_repos` watermark and current max event id
+// all = true means there is no watermark yet, all orgs have to be processed
+func changedOrgs(con *sql.DB) (orgs map[string]bool, all bool, eventID int64) {
+	lib.FatalOnError(con.QueryRow("select coalesce(max(id), 0) from gha_events").Scan(&eventID))
+	var lastID int64
+	err := con.QueryRow("select event_id from gha_watermarks where tool = 'get_repos'").Scan(&lastID)
+	if err == sql.ErrNoRows {
+		return nil, true, eventID
+	}
+	lib.FatalOnError(err)
+	orgs = make(map[string]bool)
+	if eventID <= lastID {
+		return
+	}
+	rows, err := con.Query("select distinct dup_repo_name from gha_events where id > $1", lastID)
+	lib.FatalOnError(err)
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	var repo string
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&repo))
+		orgs[strings.Split(repo, "/")[0]] = true
+	}
+	lib.FatalOnError(rows.Err())
+	return
+}
+
+// saveWatermarks saves max event id read at start as the last `get_repos` run watermark
+// Database's watermark is only moved when all its repos were processed or skipped for disk quota, so failed ones are retried on the next run
+func saveWatermarks(ctx *lib.Ctx, dbs map[string]dbConfig, done map[string]bool) {
+	saved := 0
+	for db, cfg := range dbs {
+		ok := true
+		for _, repo := range cfg.repos {
+			if !done[repo] {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			lib.Printf("%s: not all repos were processed, keeping previous watermark\n", db)
+			continue
+		}
+		con := lib.PgConnDB(ctx, db)
+		lib.ExecSQLWithErr(
+			con,
+			ctx,
+			"insert into gha_watermarks(tool, event_id, dt) values('get_repos', $1, $2) "+
+				"on conflict (tool) do update set event_id = excluded.event_id, dt = excluded.dt",
+			cfg.eventID,
+			time.Now(),
+		)
+		saved++
+	}
+	lib.Printf("Saved watermarks of %d/%d databases\n", saved, len(dbs))
+}
+
+// canonicalName returns current name of a GitHub repo, renamed or transferred repos redirect to their new location
+func canonicalName(orgRepo string) (string, error) {
+	client := &http.Client{
+		Timeout: time.Duration(30) * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Head("https://github.com/" + orgRepo)
+	if err != nil {
+		return orgRepo, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusMovedPermanently && resp.StatusCode != http.StatusFound {
+		return orgRepo, nil
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return orgRepo, err
+	}
+	newName := strings.TrimSuffix(strings.Trim(location.Path, "/"), ".git")
+	if len(strings.Split(newName, "/")) != 2 || location.Host != "github.com" {
+		return orgRepo, fmt.Errorf("%s: unexpected redirect to: %s", orgRepo, location.String())
+	}
+	return newName, nil
+}
+
+// saveRenames writes old name -> canonical name mapping into every database that contains the old name
+func saveRenames(ctx *lib.Ctx, dbs map[string]dbConfig, renames map[string]string) {
+	for db := range dbs {
+		con := lib.PgConnDB(ctx, db)
+		for name, newName := range renames {
+			lib.ExecSQLWithErr(
+				con,
+				ctx,
+				"insert into gha_repos_renames(name, new_name, dt) "+
+					"select $1, $2, now() where exists (select 1 from gha_repos where name = $1) "+
+					"on conflict (name) do update set new_name = excluded.new_name, dt = excluded.dt",
+				name,
+				newName,
+			)
+		}
+	}
+	lib.Printf("Found %d renamed/transferred repos\n", len(renames))
+}
+
+// repoData - data read from repo's HEAD after successful clone/pull
+// Fields are nil when not requested or when they cannot be read
+type repoData struct {
+	languages  map[string]int64
+	licenses   map[string]string
+	owners     map[string][]lib.OwnersEntry
+	lfsObjects int
+}
+
+// analyzeRepo - reads all requested data from repo's HEAD, errors are only reported
+func analyzeRepo(ctx *lib.Ctx, orgRepo, rwd string) (data repoData) {
+	var err error
+	data.lfsObjects, err = repoLFSObjects(rwd)
+	if err != nil && ctx.Debug > 0 {
+		lib.Printf("Cannot count %s LFS objects: %+v\n", orgRepo, err)
+	}
+	if ctx.DetectLanguages {
+		data.languages, err = repoLanguages(rwd)
+		if err != nil {
+			lib.Printf("Cannot detect %s languages: %+v\n", orgRepo, err)
+			fmt.Fprintf(os.Stderr, "Cannot detect %s languages: %+v\n", orgRepo, err)
+		}
+	}
+	if ctx.DetectLicenses {
+		data.licenses, err = repoLicenses(rwd)
+		if err != nil {
+			lib.Printf("Cannot detect %s licenses: %+v\n", orgRepo, err)
+			fmt.Fprintf(os.Stderr, "Cannot detect %s licenses: %+v\n", orgRepo, err)
+		}
+	}
+	if ctx.ProcessOwners {
+		data.owners, err = repoOwners(orgRepo, rwd)
+		if err != nil {
+			lib.Printf("Cannot process %s owners: %+v\n", orgRepo, err)
+			fmt.Fprintf(os.Stderr, "Cannot process %s owners: %+v\n", orgRepo, err)
+		}
+	}
+	return
+}
+
+// headTree - returns tree of repo's HEAD commit
+// It reads git objects directly, so it works for both normal and bare clones
+func headTree(rwd string) (*object.Tree, error) {
+	repo, err := git.PlainOpen(rwd)
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return commit.Tree()
+}
+
+// repoFile - single file at repo's HEAD (or in a tarball snapshot)
+type repoFile struct {
+	name     string
+	size     int64
+	contents func() (string, error)
 }
 
-// processRepo - processes single repo (clone or reset+pull) in a separate thread/goroutine
-func processRepo(ch chan string, ctx *lib.Ctx, orgRepo, rwd string) {
+// isSnapshot returns true if repo directory is a tarball snapshot (no git history)
+func isSnapshot(rwd string) bool {
+	_, err := os.Stat(filepath.Join(rwd, snapshotMarker))
+	return err == nil
+}
+
+// forEachFile - calls `f` for every file at repo's HEAD, or every file of a tarball snapshot
+func forEachFile(rwd string, f func(repoFile) error) error {
+	if isSnapshot(rwd) {
+		return filepath.Walk(rwd, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			name, err := filepath.Rel(rwd, path)
+			if err != nil {
+				return err
+			}
+			if name == snapshotMarker {
+				return nil
+			}
+			return f(repoFile{
+				name: filepath.ToSlash(name),
+				size: info.Size(),
+				contents: func() (string, error) {
+					data, err := ioutil.ReadFile(path)
+					return string(data), err
+				},
+			})
+		})
+	}
+	tree, err := headTree(rwd)
+	if err != nil {
+		return err
+	}
+	return tree.Files().ForEach(func(file *object.File) error {
+		return f(repoFile{name: file.Name, size: file.Size, contents: file.Contents})
+	})
+}
+
+// fileContents - returns contents of a given file at repo's HEAD (or in a tarball snapshot)
+// Missing file is not an error, `found` is false then
+func fileContents(rwd, name string) (text string, found bool, err error) {
+	if isSnapshot(rwd) {
+		data, err := ioutil.ReadFile(filepath.Join(rwd, filepath.FromSlash(name)))
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return string(data), err == nil, err
+	}
+	tree, err := headTree(rwd)
+	if err != nil {
+		return
+	}
+	file, err := tree.File(name)
+	if err == object.ErrFileNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return
+	}
+	text, err = file.Contents()
+	return text, err == nil, err
+}
+
+// repoLFSObjects - returns number of git LFS pointer files at repo's HEAD
+// Only repos that have LFS filter in root .gitattributes are scanned
+func repoLFSObjects(rwd string) (int, error) {
+	text, found, err := fileContents(rwd, ".gitattributes")
+	if err != nil || !found || !strings.Contains(text, "filter=lfs") {
+		return 0, err
+	}
+	n := 0
+	err = forEachFile(rwd, func(f repoFile) error {
+		// LFS pointers are small text files
+		if f.size > 1024 {
+			return nil
+		}
+		text, err := f.contents()
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(text, "version https://git-lfs.github.com/spec/") {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// repoLanguages - returns bytes per language of files at repo's HEAD
+func repoLanguages(rwd string) (map[string]int64, error) {
+	files := make(map[string]int64)
+	err := forEachFile(rwd, func(f repoFile) error {
+		files[f.name] = f.size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lib.Languages(files), nil
+}
+
+// saveDefaultBranches - saves current default branch of all processed repos
+// Only into databases that have given repo in `gha_repos`
+func saveDefaultBranches(ctx *lib.Ctx, dbs map[string]dbConfig, branches map[string]string, dt time.Time) {
+	for db := range dbs {
+		con := lib.PgConnDB(ctx, db)
+		for repo, branch := range branches {
+			lib.ExecSQLWithErr(
+				con,
+				ctx,
+				"insert into gha_repos_default_branches(repo_name, branch, dt) "+
+					"select $1, $2, $3 where exists (select 1 from gha_repos where name = $1) "+
+					"on conflict (repo_name) do update set branch = excluded.branch, dt = excluded.dt",
+				repo,
+				branch,
+				dt,
+			)
+		}
+	}
+	lib.Printf("Saved default branches of %d repos\n", len(branches))
+}
+
+// saveLanguages - saves bytes per language of all processed repos
+// Only into databases that have given repo in `gha_repos`
+func saveLanguages(ctx *lib.Ctx, dbs map[string]dbConfig, languages map[string]map[string]int64, dt time.Time) {
+	for db := range dbs {
+		con := lib.PgConnDB(ctx, db)
+		for repo, langs := range languages {
+			for lang, bytes := range langs {
+				lib.ExecSQLWithErr(
+					con,
+					ctx,
+					"insert into gha_repos_languages(repo_name, language, bytes, dt) "+
+						"select $1, $2, $3, $4 where exists (select 1 from gha_repos where name = $1) "+
+						"on conflict do nothing",
+					repo,
+					lang,
+					bytes,
+					dt,
+				)
+			}
+		}
+	}
+	lib.Printf("Saved languages of %d repos\n", len(languages))
+}
+
+// repoLicenses - returns SPDX license id of each license file at repo's HEAD: path -> license
+// Repo without license files gets "" -> "NONE" entry
+func repoLicenses(rwd string) (map[string]string, error) {
+	licenses := make(map[string]string)
+	err := forEachFile(rwd, func(f repoFile) error {
+		if !lib.IsLicenseFile(f.name) {
+			return nil
+		}
+		// License texts are small, anything bigger is not a license text
+		if f.size > 1<<20 {
+			licenses[f.name] = lib.NoAssertion
+			return nil
+		}
+		text, err := f.contents()
+		if err != nil {
+			return err
+		}
+		licenses[f.name] = lib.DetectLicense(text)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(licenses) == 0 {
+		licenses[""] = "NONE"
+	}
+	return licenses, nil
+}
+
+// saveLicenses - replaces license files data of all processed repos
+// Only in databases that have given repo in `gha_repos`
+func saveLicenses(ctx *lib.Ctx, dbs map[string]dbConfig, licenses map[string]map[string]string, dt time.Time) {
+	for db := range dbs {
+		con := lib.PgConnDB(ctx, db)
+		for repo, files := range licenses {
+			// Replace all repo's data in a transaction: all or none
+			tx, err := con.Begin()
+			lib.FatalOnError(err)
+			lib.ExecSQLTxWithErr(tx, ctx, "delete from gha_repos_licenses where repo_name = $1", repo)
+			for path, license := range files {
+				lib.ExecSQLTxWithErr(
+					tx,
+					ctx,
+					"insert into gha_repos_licenses(repo_name, path, license, dt) "+
+						"select $1, $2, $3, $4 where exists (select 1 from gha_repos where name = $1)",
+					repo,
+					path,
+					license,
+					dt,
+				)
+			}
+			lib.FatalOnError(tx.Commit())
+		}
+	}
+	lib.Printf("Saved licenses of %d repos\n", len(licenses))
+}
+
+// repoOwners - returns parsed CODEOWNERS and OWNERS files at repo's HEAD: path -> entries
+// OWNERS files that cannot be parsed are reported and skipped
+func repoOwners(orgRepo, rwd string) (map[string][]lib.OwnersEntry, error) {
+	owners := make(map[string][]lib.OwnersEntry)
+	err := forEachFile(rwd, func(f repoFile) error {
+		codeowners := lib.IsCodeownersFile(f.name)
+		if !codeowners && !lib.IsOwnersFile(f.name) {
+			return nil
+		}
+		text, err := f.contents()
+		if err != nil {
+			return err
+		}
+		if codeowners {
+			owners[f.name] = lib.ParseCodeowners(text)
+			return nil
+		}
+		entries, err := lib.ParseOwners(f.name, text)
+		if err != nil {
+			lib.Printf("Cannot parse %s %s: %+v\n", orgRepo, f.name, err)
+			fmt.Fprintf(os.Stderr, "Cannot parse %s %s: %+v\n", orgRepo, f.name, err)
+			return nil
+		}
+		owners[f.name] = entries
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return owners, nil
+}
+
+// saveOwners - replaces CODEOWNERS/OWNERS data of all processed repos
+// Only in databases that have given repo in `gha_repos`
+func saveOwners(ctx *lib.Ctx, dbs map[string]dbConfig, owners map[string]map[string][]lib.OwnersEntry, dt time.Time) {
+	for db := range dbs {
+		con := lib.PgConnDB(ctx, db)
+		for repo, files := range owners {
+			// Replace all repo's data in a transaction: all or none
+			tx, err := con.Begin()
+			lib.FatalOnError(err)
+			lib.ExecSQLTxWithErr(tx, ctx, "delete from gha_repos_owners where repo_name = $1", repo)
+			for path, entries := range files {
+				for _, entry := range entries {
+					lib.ExecSQLTxWithErr(
+						tx,
+						ctx,
+						"insert into gha_repos_owners(repo_name, path, pattern, owner, role, dt) "+
+							"select $1, $2, $3, $4, $5, $6 where exists (select 1 from gha_repos where name = $1) "+
+							"on conflict do nothing",
+						repo,
+						path,
+						entry.Pattern,
+						entry.Owner,
+						entry.Role,
+						dt,
+					)
+				}
+			}
+			lib.FatalOnError(tx.Commit())
+		}
+	}
+	lib.Printf("Saved owners of %d repos\n", len(owners))
+}
+
+// remoteDefaultBranch - returns remote's default branch (target of remote HEAD)
+func remoteDefaultBranch(repo *git.Repository, auth gitAuth) (string, error) {
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return "", err
+	}
+	method, err := auth.method(remote.Config().URLs[0])
+	if err != nil {
+		return "", err
+	}
+	refs, err := remote.List(&git.ListOptions{Auth: method})
+	if err != nil {
+		return "", err
+	}
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference {
+			return ref.Target().Short(), nil
+		}
+	}
+	return "", fmt.Errorf("remote HEAD not found")
+}
+
+// trackDefaultBranch - returns repo's default branch and points clone's HEAD to it when it was changed
+// (for example master -> main), so `git log` on HEAD in downstream tools follows the default branch
+// Returns "" when default branch cannot be determined, errors are only reported
+func trackDefaultBranch(orgRepo, rwd string, auth gitAuth) string {
+	repo, err := git.PlainOpen(rwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot open %s: %+v\n", orgRepo, err)
+		return ""
+	}
+	local := ""
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err == nil && head.Type() == plumbing.SymbolicReference {
+		local = head.Target().Short()
+	}
+	branch, err := remoteDefaultBranch(repo, auth)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot get %s default branch, using local %s: %+v\n", orgRepo, local, err)
+		return local
+	}
+	if branch == local {
+		return branch
+	}
+	lib.Printf("%s default branch changed %s -> %s\n", orgRepo, local, branch)
+	err = switchBranch(repo, branch, auth)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot switch %s to %s: %+v\n", orgRepo, branch, err)
+	}
+	return branch
+}
+
+// switchBranch - points HEAD to a given branch (bare repos) or checks it out (repos with working tree)
+func switchBranch(repo *git.Repository, branch string, auth gitAuth) error {
+	name := plumbing.NewBranchReferenceName(branch)
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	if cfg.Core.IsBare {
+		// Mirror clones fetch all branches
+		_, err = repo.Reference(name, false)
+		if err != nil {
+			return err
+		}
+		return repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, name))
+	}
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return err
+	}
+	method, err := auth.method(remote.Config().URLs[0])
+	if err != nil {
+		return err
+	}
+	remoteName := plumbing.NewRemoteReferenceName("origin", branch)
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		Auth:       method,
+		RefSpecs:   []config.RefSpec{config.RefSpec("+" + name.String() + ":" + remoteName.String())},
+		Force:      true,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+	ref, err := repo.Reference(remoteName, true)
+	if err != nil {
+		return err
+	}
+	tree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	options := &git.CheckoutOptions{Branch: name, Force: true}
+	_, err = repo.Reference(name, false)
+	if err == nil {
+		// Local branch already exists, move it to remote's state
+		err = repo.Storer.SetReference(plumbing.NewHashReference(name, ref.Hash()))
+		if err != nil {
+			return err
+		}
+	} else {
+		options.Hash = ref.Hash()
+		options.Create = true
+	}
+	err = tree.Checkout(options)
+	if err != nil {
+		return err
+	}
+	err = repo.CreateBranch(&config.Branch{Name: branch, Remote: "origin", Merge: name})
+	if err == git.ErrBranchExists {
+		return nil
+	}
+	return err
+}
+
+// checkClone - checks if clone is usable: HEAD resolves to a commit with a tree
+// Full connectivity check (`git fsck`) is only done when GHA2DB_FSCK is set, it is slow on big repos
+func checkClone(ctx *lib.Ctx, rwd string) error {
+	_, err := headTree(rwd)
+	if err != nil {
+		return err
+	}
+	if !ctx.Fsck {
+		return nil
+	}
 	// Local or cron mode?
 	cmdPrefix := ""
 	if ctx.Local {
 		cmdPrefix = lib.LocalGitScripts
 	}
+	_, err = lib.ExecCommand(ctx, []string{cmdPrefix + "git_fsck.sh", rwd}, nil)
+	return err
+}
+
+// downloadTarball - downloads GitHub tarball of repo's default branch and unpacks it into `rwd`
+// Such repo is only a snapshot (marked with snapshotMarker file), it has no git history
+func downloadTarball(gctx context.Context, orgRepo, rwd string, auth gitAuth) error {
+	req, err := http.NewRequest("GET", "https://api.github.com/repos/"+orgRepo+"/tarball", nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(gctx)
+	if auth.token != "" {
+		// Authorization header is not sent to codeload.github.com we are redirected to
+		req.Header.Set("Authorization", "token "+auth.token)
+	}
+	client := &http.Client{Timeout: time.Duration(30) * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: tarball download failed: %s", orgRepo, resp.Status)
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return err
+	}
+	// Unpack into a temporary directory, so partial download never looks like a complete snapshot
+	tmp := rwd + ".tarball"
+	lib.FatalOnError(os.RemoveAll(tmp))
+	err = untar(tar.NewReader(gz), tmp)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(tmp, snapshotMarker), []byte(time.Now().Format(time.RFC3339)+"\n"), 0644)
+	}
+	if err == nil {
+		err = os.RemoveAll(rwd)
+	}
+	if err == nil {
+		err = os.Rename(tmp, rwd)
+	}
+	if err != nil {
+		_ = os.RemoveAll(tmp)
+	}
+	return err
+}
+
+// untar - unpacks regular files and directories from GitHub tarball into `dir`
+// Top level "org-repo-sha/" directory is stripped, links and entries pointing outside of `dir` are skipped
+func untar(tr *tar.Reader, dir string) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ary := strings.SplitN(hdr.Name, "/", 2)
+		if len(ary) < 2 || ary[1] == "" {
+			continue
+		}
+		name := filepath.Clean(filepath.FromSlash(ary[1]))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0755)
+		case tar.TypeReg, tar.TypeRegA:
+			err = os.MkdirAll(filepath.Dir(path), 0755)
+			if err != nil {
+				return err
+			}
+			var f *os.File
+			f, err = os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(hdr.Mode)&0755|0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
 
+// processRepo - processes single repo (clone or reset+pull), it is run by the worker pool
+// Returns snapshot = true when git clone failed and GitHub tarball was unpacked instead (GHA2DB_TARBALL_FALLBACK)
+func processRepo(gctx context.Context, ctx *lib.Ctx, orgRepo, rwd string, auth gitAuth) (bool, error) {
 	// Clone or reset+pull repo
 	exists, err := dirExists(rwd)
 	lib.FatalOnError(err)
+	if exists && isSnapshot(rwd) {
+		// Previous clone failed and tarball was used, try to clone again
+		lib.FatalOnError(os.RemoveAll(rwd))
+		exists = false
+	}
+	if exists {
+		// Clone can be corrupted (for example previous run was killed while cloning)
+		// Such clone would fail forever, remove it and clone again
+		err := checkClone(ctx, rwd)
+		if err != nil {
+			lib.Printf("Corrupted clone %s, removing and cloning again: %+v\n", orgRepo, err)
+			fmt.Fprintf(os.Stderr, "Corrupted clone %s, removing and cloning again: %+v\n", orgRepo, err)
+			lib.FatalOnError(os.RemoveAll(rwd))
+			exists = false
+		}
+	}
 	if !exists {
 		// We need to clone repo
 		if ctx.Debug > 0 {
 			lib.Printf("Cloning %s\n", orgRepo)
 		}
 		dtStart := time.Now()
-		// Clone repo into given directory (from command line)
-		// We cannot chdir because this is a multithreaded app
-		// And all threads share CWD (current working directory)
-		_, err := lib.ExecCommand(
-			ctx,
-			[]string{"git", "clone", "https://github.com/" + orgRepo + ".git", rwd},
-			map[string]string{"GIT_TERMINAL_PROMPT": "0"},
-		)
+		err := retryGit(gctx, ctx, orgRepo, "git-clone", func() error { return cloneRepo(gctx, ctx, orgRepo, rwd, auth) })
 		dtEnd := time.Now()
 		if err != nil {
 			if ctx.Debug > 0 {
 				lib.Printf("Warning git-clone failed: %s (took %v): %+v\n", orgRepo, dtEnd.Sub(dtStart), err)
 			}
 			fmt.Fprintf(os.Stderr, "Warning git-clone failed: %s (took %v): %+v\n", orgRepo, dtEnd.Sub(dtStart), err)
-			ch <- ""
-			return
+			if ctx.TarballFallback && auth.onGitHub(orgRepo) && gctx.Err() == nil {
+				terr := downloadTarball(gctx, orgRepo, rwd, auth)
+				if terr == nil {
+					lib.Printf("Using tarball snapshot of %s\n", orgRepo)
+					return true, nil
+				}
+				fmt.Fprintf(os.Stderr, "Warning tarball download failed: %s: %+v\n", orgRepo, terr)
+			}
+			return false, fmt.Errorf("%s: git-clone failed: %v", orgRepo, err)
 		}
 		if ctx.Debug > 0 {
 			lib.Printf("Cloned %s: took %v\n", orgRepo, dtEnd.Sub(dtStart))
@@ -158,33 +1259,197 @@ func processRepo(ch chan string, ctx *lib.Ctx, orgRepo, rwd string) {
 			lib.Printf("Pulling %s\n", orgRepo)
 		}
 		dtStart := time.Now()
-		// Update repo using shell script that uses 'chdir'
-		// We cannot chdir because this is a multithreaded app
-		// And all threads share CWD (current working directory)
-		_, err := lib.ExecCommand(
-			ctx,
-			[]string{cmdPrefix + "git_reset_pull.sh", rwd},
-			map[string]string{"GIT_TERMINAL_PROMPT": "0"},
-		)
+		err := retryGit(gctx, ctx, orgRepo, "git-reset/git-pull", func() error { return updateRepo(gctx, ctx, rwd, auth) })
 		dtEnd := time.Now()
 		if err != nil {
 			if ctx.Debug > 0 {
-				lib.Printf("Warning git_reset_pull.sh failed: %s (took %v): %+v\n", orgRepo, dtEnd.Sub(dtStart), err)
+				lib.Printf("Warning git-reset/git-pull failed: %s (took %v): %+v\n", orgRepo, dtEnd.Sub(dtStart), err)
 			}
-			fmt.Fprintf(os.Stderr, "Warning git_reset_pull.sh failed: %s (took %v): %+v\n", orgRepo, dtEnd.Sub(dtStart), err)
-			ch <- ""
-			return
+			fmt.Fprintf(os.Stderr, "Warning git-reset/git-pull failed: %s (took %v): %+v\n", orgRepo, dtEnd.Sub(dtStart), err)
+			return false, fmt.Errorf("%s: git-reset/git-pull failed: %v", orgRepo, err)
+		}
+		if ctx.Debug > 0 {
+			lib.Printf("Pulled %s: took %v\n", orgRepo, dtEnd.Sub(dtStart))
+		}
+	}
+	if ctx.Submodules {
+		updateSubmodules(ctx, orgRepo, rwd, auth)
+	}
+	if auth.lfs[orgRepo] {
+		fetchLFS(ctx, orgRepo, rwd, auth)
+	}
+	return false, nil
+}
+
+// fetchLFS - fetches git LFS objects (all of them for bare clones, checks them out otherwise)
+// Failure is only reported, main repo was cloned/pulled successfully
+func fetchLFS(ctx *lib.Ctx, orgRepo, rwd string, auth gitAuth) {
+	// Local or cron mode?
+	cmdPrefix := ""
+	if ctx.Local {
+		cmdPrefix = lib.LocalGitScripts
+	}
+	dtStart := time.Now()
+	_, err := lib.ExecCommand(ctx, []string{cmdPrefix + "git_lfs.sh", rwd}, auth.env())
+	dtEnd := time.Now()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning git_lfs.sh failed: %s (took %v): %+v\n", orgRepo, dtEnd.Sub(dtStart), err)
+		return
+	}
+	if ctx.Debug > 0 {
+		lib.Printf("Fetched %s LFS objects: took %v\n", orgRepo, dtEnd.Sub(dtStart))
+	}
+}
+
+// updateSubmodules - recursively inits/updates repo's submodules
+// Bare clones have no working tree and are skipped by `git_submodules.sh` (no .gitmodules file)
+// Submodules failure is only reported, main repo was cloned/pulled successfully
+func updateSubmodules(ctx *lib.Ctx, orgRepo, rwd string, auth gitAuth) {
+	// Local or cron mode?
+	cmdPrefix := ""
+	if ctx.Local {
+		cmdPrefix = lib.LocalGitScripts
+	}
+	cmd := []string{cmdPrefix + "git_submodules.sh", rwd}
+	if ctx.ShallowClone > 0 {
+		cmd = append(cmd, strconv.Itoa(ctx.ShallowClone))
+	}
+	dtStart := time.Now()
+	_, err := lib.ExecCommand(ctx, cmd, auth.env())
+	dtEnd := time.Now()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning git_submodules.sh failed: %s (took %v): %+v\n", orgRepo, dtEnd.Sub(dtStart), err)
+		return
+	}
+	if ctx.Debug > 0 {
+		lib.Printf("Updated %s submodules: took %v\n", orgRepo, dtEnd.Sub(dtStart))
+	}
+}
+
+// dirSize returns size in bytes of all files under path
+func dirSize(path string) (size int64) {
+	_ = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return
+}
+
+// orgsDiskUsage returns disk usage in bytes of every org's directory under ctx.ReposDir
+func orgsDiskUsage(ctx *lib.Ctx) map[string]int64 {
+	usage := make(map[string]int64)
+	orgs, err := ioutil.ReadDir(ctx.ReposDir)
+	lib.FatalOnError(err)
+	for _, org := range orgs {
+		if org.IsDir() {
+			usage[org.Name()] = dirSize(ctx.ReposDir + org.Name())
+		}
+	}
+	return usage
+}
+
+// diskUsageReport outputs disk usage per org, biggest first
+func diskUsageReport(ctx *lib.Ctx) {
+	usage := orgsDiskUsage(ctx)
+	orgs := []string{}
+	total := int64(0)
+	for org, size := range usage {
+		orgs = append(orgs, org)
+		total += size
+	}
+	sort.Slice(orgs, func(i, j int) bool {
+		if usage[orgs[i]] == usage[orgs[j]] {
+			return orgs[i] < orgs[j]
 		}
+		return usage[orgs[i]] > usage[orgs[j]]
+	})
+	lib.Printf("Disk usage per org (MB):\n")
+	for _, org := range orgs {
+		lib.Printf("%-40s %12.2f\n", org, float64(usage[org])/1048576.0)
+	}
+	lib.Printf("%-40s %12.2f\n", "Total", float64(total)/1048576.0)
+}
+
+// fitsQuota checks if a new clone of orgRepo fits global and org disk quotas
+// Repo size is estimated using GitHub API, if it fits it is added to `usage` map
+func fitsQuota(ctx *lib.Ctx, ghCtx context.Context, client *github.Client, usage map[string]int64, total *int64, org, orgRepo string, onGitHub bool) bool {
+	size := int64(0)
+	var (
+		repo *github.Repository
+		err  error
+	)
+	if onGitHub {
+		repo, _, err = client.Repositories.Get(ghCtx, org, strings.Split(orgRepo, "/")[1])
+	} else {
+		err = fmt.Errorf("not cloned from GitHub")
+	}
+	if err != nil {
+		// We cannot estimate size, let clone decide if repo exists
 		if ctx.Debug > 0 {
-			lib.Printf("Pulled %s: took %v\n", orgRepo, dtEnd.Sub(dtStart))
+			lib.Printf("Cannot get %s size: %+v\n", orgRepo, err)
 		}
+	} else {
+		// GitHub API returns size in KB
+		size = int64(repo.GetSize()) * 1024
+	}
+	mb := int64(1048576)
+	if ctx.DiskQuota > 0 && *total+size > int64(ctx.DiskQuota)*mb {
+		lib.Printf("Skipping %s (%d MB): global disk quota %d MB would be exceeded\n", orgRepo, size/mb, ctx.DiskQuota)
+		return false
+	}
+	if ctx.OrgDiskQuota > 0 && usage[org]+size > int64(ctx.OrgDiskQuota)*mb {
+		lib.Printf("Skipping %s (%d MB): %s org disk quota %d MB would be exceeded\n", orgRepo, size/mb, org, ctx.OrgDiskQuota)
+		return false
+	}
+	usage[org] += size
+	*total += size
+	return true
+}
+
+// repoStatus - single repo processing result, saved in GHA2DB_EXTERNAL_INFO_FILE
+type repoStatus struct {
+	Repo          string `json:"repo" yaml:"repo"`
+	Path          string `json:"path" yaml:"path"`
+	Status        string `json:"status" yaml:"status"`
+	Error         string `json:"error,omitempty" yaml:"error,omitempty"`
+	NewName       string `json:"new_name,omitempty" yaml:"new_name,omitempty"`
+	DefaultBranch string `json:"default_branch,omitempty" yaml:"default_branch,omitempty"`
+	LFSObjects    int    `json:"lfs_objects,omitempty" yaml:"lfs_objects,omitempty"`
+}
+
+// externalInfo - structured version of GHA2DB_EXTERNAL_INFO output
+type externalInfo struct {
+	Repos    []string     `json:"repos" yaml:"repos"`
+	Orgs     []string     `json:"orgs" yaml:"orgs"`
+	Paths    []string     `json:"paths" yaml:"paths"`
+	Command  string       `json:"command" yaml:"command"`
+	Statuses []repoStatus `json:"statuses" yaml:"statuses"`
+}
+
+// writeExternalInfo - writes external info to GHA2DB_EXTERNAL_INFO_FILE, YAML for .yaml/.yml files, JSON otherwise
+func writeExternalInfo(ctx *lib.Ctx, info *externalInfo) {
+	var (
+		data []byte
+		err  error
+	)
+	ext := strings.ToLower(filepath.Ext(ctx.ExternalInfoFile))
+	if ext == ".yaml" || ext == ".yml" {
+		data, err = yaml.Marshal(info)
+	} else {
+		data, err = json.MarshalIndent(info, "", "  ")
 	}
-	ch <- orgRepo
+	lib.FatalOnError(err)
+	lib.FatalOnError(ioutil.WriteFile(ctx.ExternalInfoFile, data, 0644))
+	lib.Printf("External info written to %s\n", ctx.ExternalInfoFile)
 }
 
 // processRepos process map of org -> list of repos to clone or pull them as needed
 // it also displays cncf/gitdm needed info in debug mode (called manually)
-func processRepos(ctx *lib.Ctx, allRepos map[string][]string) {
+// Processing stops when `runCtx` is cancelled (or on the first error in GHA2DB_FAIL_FAST mode)
+// Returns repos that were cloned/pulled (or are tracked under their new name)
+func processRepos(runCtx context.Context, ctx *lib.Ctx, dbs map[string]dbConfig, allRepos map[string][]string, orgsAuth map[string]gitAuth) map[string]bool {
 	// Set non-fatal exec mode, we want to run sync for next project(s) if current fails
 	// Also set quite mode, many git-pulls or git-clones can fail and this is not needed to log it to DB
 	// User can set higher debug level and run manually to debug this
@@ -205,9 +1470,27 @@ func processRepos(ctx *lib.Ctx, allRepos map[string][]string) {
 		}
 	}
 
+	// All tracked repos, renamed repos whose new name is also tracked are not cloned twice
+	tracked := make(map[string]struct{})
+	for _, repos := range allRepos {
+		for _, orgRepo := range repos {
+			tracked[orgRepo] = struct{}{}
+		}
+	}
+	renames := make(map[string]string)
+	languages := make(map[string]map[string]int64)
+	licenses := make(map[string]map[string]string)
+	owners := make(map[string]map[string][]lib.OwnersEntry)
+	statuses := make(map[string]repoStatus)
+	branches := make(map[string]string)
+	lfsRepos, lfsObjects := 0, 0
+	snapshots := 0
+
 	// Process all orgs & repos
-	thrN := lib.GetThreadsNum(ctx)
-	chanPool := []chan string{}
+	gitLimiter = lib.NewRateLimiter(ctx.ClonesPerMinute, 1)
+	pool := lib.NewPool(runCtx, lib.GetThreadsNum(ctx), ctx.FailFast)
+	gitSem := lib.GitSemaphore(ctx)
+	var mtx sync.Mutex
 	allOkRepos := []string{}
 	// Count all data
 	checked := 0
@@ -217,7 +1500,24 @@ func processRepos(ctx *lib.Ctx, allRepos map[string][]string) {
 	for _, repos := range allRepos {
 		allN += len(repos)
 	}
+	// Current disk usage is needed to check quotas for new clones
+	var (
+		usage  map[string]int64
+		total  int64
+		ghCtx  context.Context
+		client *github.Client
+	)
+	quotas := ctx.DiskQuota > 0 || ctx.OrgDiskQuota > 0
+	if quotas {
+		usage = orgsDiskUsage(ctx)
+		for _, size := range usage {
+			total += size
+		}
+		ghCtx, client = lib.GHClient(ctx)
+	}
+	skipped := 0
 	// Iterate orgs
+orgs:
 	for org, repos := range allRepos {
 		// Go to current 'org' subdirectory
 		owd := wd + org
@@ -234,48 +1534,149 @@ func processRepos(ctx *lib.Ctx, allRepos map[string][]string) {
 		}
 		// Iterate org's repositories
 		for _, orgRepo := range repos {
-			ch := make(chan string)
-			chanPool = append(chanPool, ch)
 			// repository's working dir (if present we only need to do git reset --hard; git pull)
 			ary := strings.Split(orgRepo, "/")
 			repo := ary[1]
 			rwd := owd + "/" + repo
-			go processRepo(ch, ctx, orgRepo, rwd)
-			if len(chanPool) == thrN {
-				ch = chanPool[0]
-				res := <-ch
-				chanPool = chanPool[1:]
-				if res != "" {
-					allOkRepos = append(allOkRepos, res)
+			auth := orgsAuth[org]
+			if quotas {
+				exists, err := dirExists(rwd)
+				lib.FatalOnError(err)
+				if !exists && !fitsQuota(ctx, ghCtx, client, usage, &total, org, orgRepo, auth.onGitHub(orgRepo)) {
+					statuses[orgRepo] = repoStatus{Repo: orgRepo, Path: rwd, Status: "skipped"}
+					skipped++
+					allN--
+					continue
+				}
+			}
+			orgRepo := orgRepo
+			started := pool.Go(func(gctx context.Context) error {
+				// Random delay, so threads do not hit git server at the same time
+				if ctx.CloneJitter > 0 {
+					select {
+					case <-time.After(time.Duration(rand.Int63n(int64(ctx.CloneJitter)+1)) * time.Millisecond):
+					case <-gctx.Done():
+						return nil
+					}
+				}
+				if ctx.DetectRenames && auth.onGitHub(orgRepo) {
+					newName, err := canonicalName(orgRepo)
+					if err != nil && ctx.Debug > 0 {
+						lib.Printf("Cannot get %s canonical name: %+v\n", orgRepo, err)
+					}
+					if newName != orgRepo {
+						_, ok := tracked[newName]
+						mtx.Lock()
+						renames[orgRepo] = newName
+						if ok {
+							statuses[orgRepo] = repoStatus{Repo: orgRepo, Path: rwd, Status: "renamed", NewName: newName}
+							checked++
+						}
+						mtx.Unlock()
+						if ok {
+							if ctx.Debug > 0 {
+								lib.Printf("%s was renamed to %s, which is processed instead\n", orgRepo, newName)
+							}
+							return nil
+						}
+					}
+				}
+				// Up to GHA2DB_GIT_PARALLEL repos are processed at once by all `get_repos` on this host
+				release, err := gitSem.Acquire(gctx)
+				if err != nil {
+					if gctx.Err() != nil {
+						return nil
+					}
+					return err
+				}
+				defer release()
+				snapshot, err := processRepo(gctx, ctx, orgRepo, rwd, auth)
+				branch := ""
+				if err == nil && !snapshot && ctx.DefaultBranches {
+					branch = trackDefaultBranch(orgRepo, rwd, auth)
+				}
+				var data repoData
+				if err == nil {
+					data = analyzeRepo(ctx, orgRepo, rwd)
+				}
+				mtx.Lock()
+				if err == nil {
+					// Snapshots have no history, they cannot be used by cncf/gitdm
+					status := "snapshot"
+					if snapshot {
+						snapshots++
+					} else {
+						allOkRepos = append(allOkRepos, orgRepo)
+						status = "ok"
+					}
+					statuses[orgRepo] = repoStatus{Repo: orgRepo, Path: rwd, Status: status, DefaultBranch: branch, LFSObjects: data.lfsObjects}
+					if data.lfsObjects > 0 {
+						lfsRepos++
+						lfsObjects += data.lfsObjects
+					}
+				} else {
+					statuses[orgRepo] = repoStatus{Repo: orgRepo, Path: rwd, Status: "failed", Error: err.Error()}
+				}
+				if branch != "" {
+					branches[orgRepo] = branch
+				}
+				if data.languages != nil {
+					languages[orgRepo] = data.languages
+				}
+				if data.licenses != nil {
+					licenses[orgRepo] = data.licenses
+				}
+				if data.owners != nil {
+					owners[orgRepo] = data.owners
 				}
 				checked++
 				lib.ProgressInfo(checked, allN, dtStart, &lastTime, time.Duration(10)*time.Second, orgRepo)
+				mtx.Unlock()
+				return err
+			})
+			if !started {
+				break orgs
 			}
 		}
 	}
-	for _, ch := range chanPool {
-		res := <-ch
-		if res != "" {
-			allOkRepos = append(allOkRepos, res)
+	errs := pool.Wait()
+	if runCtx.Err() != nil {
+		lib.Printf("Repos processing cancelled after %d/%d repos\n", checked, allN)
+		return nil
+	}
+	if ctx.FailFast && len(errs) > 0 {
+		lib.FatalOnError(errs[0])
+	}
+	done := make(map[string]bool)
+	for orgRepo, status := range statuses {
+		// Repos skipped for disk quota are not cloned until quota allows, they don't hold back watermarks
+		if status.Status == "ok" || status.Status == "renamed" || status.Status == "skipped" {
+			done[orgRepo] = true
 		}
-		checked++
-		lib.ProgressInfo(checked, allN, dtStart, &lastTime, time.Duration(10)*time.Second, "final join...")
+	}
+	if ctx.DetectRenames {
+		saveRenames(ctx, dbs, renames)
+	}
+	if ctx.DefaultBranches {
+		saveDefaultBranches(ctx, dbs, branches, dtStart)
+	}
+	if ctx.DetectLanguages {
+		saveLanguages(ctx, dbs, languages, dtStart)
+	}
+	if ctx.DetectLicenses {
+		saveLicenses(ctx, dbs, licenses, dtStart)
+	}
+	if ctx.ProcessOwners {
+		saveOwners(ctx, dbs, owners, dtStart)
 	}
 
 	// Output all repos as ruby object & Final cncf/gitdm command to generate concatenated git.log
 	// Only output when GHA2DB_EXTERNAL_INFO env variable is set
 	// Only output to stdout - not standard logs via lib.Printf(...)
-	if ctx.ExternalInfo {
+	if ctx.ExternalInfo || ctx.ExternalInfoFile != "" {
 		// Sort list of repos
 		sort.Strings(allOkRepos)
 
-		// Create Ruby-like string with all repos array
-		allOkReposStr := "[\n"
-		for _, okRepo := range allOkRepos {
-			allOkReposStr += "  '" + okRepo + "',\n"
-		}
-		allOkReposStr += "]"
-
 		// Create list of orgs
 		orgs := []string{}
 		for org := range allRepos {
@@ -291,19 +1692,113 @@ func processRepos(ctx *lib.Ctx, allRepos map[string][]string) {
 			finalCmd += ctx.ReposDir + org + "/* "
 		}
 
-		// Output cncf/gitdm related data
-		fmt.Printf("AllRepos:\n%s\n", allOkReposStr)
-		fmt.Printf("Final command:\n%s\n", finalCmd)
+		if ctx.ExternalInfo {
+			// Create Ruby-like string with all repos array
+			allOkReposStr := "[\n"
+			for _, okRepo := range allOkRepos {
+				allOkReposStr += "  '" + okRepo + "',\n"
+			}
+			allOkReposStr += "]"
+
+			// Output cncf/gitdm related data
+			fmt.Printf("AllRepos:\n%s\n", allOkReposStr)
+			fmt.Printf("Final command:\n%s\n", finalCmd)
+		}
+
+		if ctx.ExternalInfoFile != "" {
+			info := externalInfo{Repos: allOkRepos, Orgs: orgs, Command: finalCmd, Statuses: []repoStatus{}}
+			for _, org := range orgs {
+				info.Paths = append(info.Paths, ctx.ReposDir+org)
+			}
+			for _, status := range statuses {
+				info.Statuses = append(info.Statuses, status)
+			}
+			sort.Slice(info.Statuses, func(i, j int) bool { return info.Statuses[i].Repo < info.Statuses[j].Repo })
+			writeExternalInfo(ctx, &info)
+		}
 	}
 	lib.Printf("Sucesfully processed %d/%d repos\n", len(allOkRepos), checked)
+	if skipped > 0 {
+		lib.Printf("Skipped %d new repos due to disk quota\n", skipped)
+	}
+	if snapshots > 0 {
+		lib.Printf("Used GitHub tarball snapshots of %d repos that failed to clone\n", snapshots)
+	}
+	if lfsRepos > 0 {
+		lib.Printf("Found %d git LFS objects in %d repos\n", lfsObjects, lfsRepos)
+	}
+	if ctx.DiskUsage {
+		diskUsageReport(ctx)
+	}
+	return done
 }
 
-// processCommitsDB creates/updates mapping between commits and list of files they refer to on databse 'db'
-// using 'query' to get liist of unprocessed commits
-func processCommitsDB(ch chan dbCommits, ctx *lib.Ctx, db, filesSkipPattern, query string) {
-	// Result struct to be passed by the channel
-	var commits dbCommits
+// pruneRepos removes (or only reports in dry-run mode) repos cloned under ctx.ReposDir
+// that are no longer present in any project's gha_repos table
+func pruneRepos(ctx *lib.Ctx, allRepos map[string][]string) {
+	// We would remove other projects repos when only some projects are selected
+	if ctx.ProjectsCommits != "" {
+		lib.Printf("Prune skipped: GHA2DB_PROJECTS_COMMITS is set, cannot determine all needed repos\n")
+		return
+	}
+	orgs, err := ioutil.ReadDir(ctx.ReposDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return
+		}
+		lib.FatalOnError(err)
+	}
+
+	// Collect orphaned org and repo directories
+	orphans := []string{}
+	for _, org := range orgs {
+		if !org.IsDir() {
+			continue
+		}
+		repos, ok := allRepos[org.Name()]
+		if !ok {
+			orphans = append(orphans, ctx.ReposDir+org.Name())
+			continue
+		}
+		needed := make(map[string]struct{})
+		for _, orgRepo := range repos {
+			needed[orgRepo] = struct{}{}
+		}
+		dirs, err := ioutil.ReadDir(ctx.ReposDir + org.Name())
+		lib.FatalOnError(err)
+		for _, dir := range dirs {
+			if !dir.IsDir() {
+				continue
+			}
+			_, ok := needed[org.Name()+"/"+dir.Name()]
+			if !ok {
+				orphans = append(orphans, ctx.ReposDir+org.Name()+"/"+dir.Name())
+			}
+		}
+	}
+	sort.Strings(orphans)
+
+	// Remove them or just report
+	for _, orphan := range orphans {
+		if ctx.PruneDryRun {
+			lib.Printf("Would prune: %s\n", orphan)
+			continue
+		}
+		if ctx.Debug > 0 {
+			lib.Printf("Pruning: %s\n", orphan)
+		}
+		lib.FatalOnError(os.RemoveAll(orphan))
+	}
+	if ctx.PruneDryRun {
+		lib.Printf("Found %d orphaned directories (dry run)\n", len(orphans))
+	} else {
+		lib.Printf("Pruned %d orphaned directories\n", len(orphans))
+	}
+}
 
+// processCommitsDB gets list of unprocessed commits on databse 'db'
+// using 'query' to get liist of unprocessed commits
+func processCommitsDB(ctx *lib.Ctx, db string, cfg dbConfig, query string) (commits dbCommits) {
 	// Get list of unprocessed commits for current DB
 	lib.Printf("Running on database: %s\n", db)
 	dtStart := time.Now()
@@ -319,6 +1814,9 @@ func processCommitsDB(ch chan dbCommits, ctx *lib.Ctx, db, filesSkipPattern, que
 	)
 	for rows.Next() {
 		lib.FatalOnError(rows.Scan(&sha, &repo))
+		if !cfg.repoMatches(repo) {
+			continue
+		}
 		commits.shas = append(commits.shas, sha)
 		commits.repos = append(commits.repos, repo)
 	}
@@ -326,12 +1824,61 @@ func processCommitsDB(ch chan dbCommits, ctx *lib.Ctx, db, filesSkipPattern, que
 	dtEnd := time.Now()
 	lib.Printf("Database '%s' processed took %v, new commits: %d\n", db, dtEnd.Sub(dtStart), len(commits.shas))
 	commits.con = con
-	commits.filesSkipPattern = filesSkipPattern
-	ch <- commits
+	commits.filesSkipPattern = cfg.filesSkipPattern
+	return
+}
+
+// unshallowRepo fetches full history of a given shallow cloned repo (only once per repo)
+// Returns true if the repo was unshallowed now, false if it was already done before or failed
+func unshallowRepo(ctx *lib.Ctx, repo string, auth gitAuth) bool {
+	// Local or cron mode?
+	cmdPrefix := ""
+	if ctx.Local {
+		cmdPrefix = lib.LocalGitScripts
+	}
+
+	// Many threads can process commits from the same repo
+	// Only one of them should unshallow it, others should wait
+	unshallowMtx.Lock()
+	mtx, ok := unshallowRepoMtx[repo]
+	if !ok {
+		mtx = &sync.Mutex{}
+		unshallowRepoMtx[repo] = mtx
+	}
+	unshallowMtx.Unlock()
+	mtx.Lock()
+	defer mtx.Unlock()
+	unshallowMtx.Lock()
+	done := unshallowed[repo]
+	unshallowed[repo] = true
+	unshallowMtx.Unlock()
+	if done {
+		return false
+	}
+
+	if ctx.Debug > 0 {
+		lib.Printf("Unshallowing %s\n", repo)
+	}
+	dtStart := time.Now()
+	_, err := lib.ExecCommand(
+		ctx,
+		[]string{cmdPrefix + "git_unshallow.sh", ctx.ReposDir + repo},
+		auth.env(),
+	)
+	dtEnd := time.Now()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning git_unshallow.sh failed: %s (took %v): %+v\n", repo, dtEnd.Sub(dtStart), err)
+		return false
+	}
+	if ctx.Debug > 0 {
+		lib.Printf("Unshallowed %s: took %v\n", repo, dtEnd.Sub(dtStart))
+	}
+	return true
 }
 
 // getCommitFiles get given commit's list of files and saves it in the database
-func getCommitFiles(ch chan int, ctx *lib.Ctx, con *sql.DB, filesSkipPattern *regexp.Regexp, repo, sha string) {
+// Returns -1 on error, 0 for commit without files, 1 for commit with files
+func getCommitFiles(ctx *lib.Ctx, con *sql.DB, filesSkipPattern *regexp.Regexp, repo, sha string, auth gitAuth) int {
 	// Local or cron mode?
 	cmdPrefix := ""
 	if ctx.Local {
@@ -349,8 +1896,16 @@ func getCommitFiles(ch chan int, ctx *lib.Ctx, con *sql.DB, filesSkipPattern *re
 	filesStr, err := lib.ExecCommand(
 		ctx,
 		[]string{cmdPrefix + "git_files.sh", rwd, sha},
-		map[string]string{"GIT_TERMINAL_PROMPT": "0"},
+		auth.env(),
 	)
+	// Commit can be missing from a shallow clone, fetch full history and try again
+	if err != nil && ctx.ShallowClone > 0 && unshallowRepo(ctx, repo, auth) {
+		filesStr, err = lib.ExecCommand(
+			ctx,
+			[]string{cmdPrefix + "git_files.sh", rwd, sha},
+			auth.env(),
+		)
+	}
 	dtEnd := time.Now()
 	if err != nil {
 		if ctx.Debug > 1 {
@@ -363,8 +1918,7 @@ func getCommitFiles(ch chan int, ctx *lib.Ctx, con *sql.DB, filesSkipPattern *re
 			lib.InsertIgnore("into gha_skip_commits(sha) "+lib.NValues(1)),
 			lib.AnyArray{sha}...,
 		)
-		ch <- -1
-		return
+		return -1
 	}
 	files := strings.Split(filesStr, "\n")
 	nFiles := 0
@@ -403,6 +1957,7 @@ func getCommitFiles(ch chan int, ctx *lib.Ctx, con *sql.DB, filesSkipPattern *re
 		// 0 - file created - no contenets
 		// -1 - file referenced in the commit SHA but not found in this commit (means deleted)
 		// -2 - file size returned as "-" from git ls-tree - means some special file, directory
+		// -3 - submodule (gitlink) - commit bumps submodule's SHA
 		fileSize, err := strconv.ParseInt(fileDataAry[1], 10, 64)
 		if err != nil {
 			fileSize = -2
@@ -416,6 +1971,25 @@ func getCommitFiles(ch chan int, ctx *lib.Ctx, con *sql.DB, filesSkipPattern *re
 		)
 		nFiles++
 	}
+	// Lines added/removed and number of files changed, all files are counted here
+	// (including those matching files skip pattern)
+	statsStr, err := lib.ExecCommand(
+		ctx,
+		[]string{cmdPrefix + "git_numstat.sh", rwd, sha},
+		auth.env(),
+	)
+	if err == nil {
+		added, removed, changed := parseNumstat(statsStr)
+		lib.ExecSQLTxWithErr(
+			tx,
+			ctx,
+			lib.InsertIgnore("into gha_commits_stats(sha, added, removed, files, dt) "+lib.NValues(5)),
+			lib.AnyArray{sha, added, removed, changed, commitDate}...,
+		)
+	} else if ctx.Debug > 1 {
+		lib.Printf("Warning git_numstat.sh failed: %s:%s: %+v\n", repo, sha, err)
+		fmt.Fprintf(os.Stderr, "Warning git_numstat.sh failed: %s:%s: %+v\n", repo, sha, err)
+	}
 	// Some commits have no files (for example only renames)
 	// Mark them as skipped not to process again
 	if nFiles == 0 {
@@ -427,31 +2001,275 @@ func getCommitFiles(ch chan int, ctx *lib.Ctx, con *sql.DB, filesSkipPattern *re
 		)
 		// Commit transaction
 		lib.FatalOnError(tx.Commit())
-		ch <- 0
-		return
+		return 0
 	}
 	// Commit transaction
 	lib.FatalOnError(tx.Commit())
 	if ctx.Debug > 1 {
 		lib.Printf("Got %s:%s commit: %d files: took %v\n", repo, sha, nFiles, dtEnd.Sub(dtStart))
 	}
-	ch <- 1
+	return 1
+}
+
+// parseNumstat - sums `git log --numstat` output: "added<TAB>removed<TAB>path" lines
+// Binary files are reported as "-<TAB>-<TAB>path", they are counted as changed files with no lines
+func parseNumstat(numstat string) (added, removed, files int64) {
+	for _, line := range strings.Split(numstat, "\n") {
+		ary := strings.SplitN(strings.TrimSpace(line), "\t", 3)
+		if len(ary) != 3 {
+			continue
+		}
+		files++
+		if n, err := strconv.ParseInt(ary[0], 10, 64); err == nil {
+			added += n
+		}
+		if n, err := strconv.ParseInt(ary[1], 10, 64); err == nil {
+			removed += n
+		}
+	}
+	return
 }
 
 // postprocessCommitsDB - calls given SQL on a given database
 // to postprocess just created commit SHAs-files connections
-func postprocessCommitsDB(ch chan int, ctx *lib.Ctx, con *sql.DB, query string) {
-	_, err := con.Query(query)
-	lib.FatalOnError(err)
-	// Close connection
-	lib.FatalOnError(con.Close())
-	ch <- 1
+func postprocessCommitsDB(ctx *lib.Ctx, con *sql.DB, query string) {
+	// Metrics of periods with events whose commits got files change, so their cached results are cleared
+	query = "with inserted as (" + strings.TrimRight(strings.TrimSpace(query), ";") +
+		" returning dup_created_at) select min(dup_created_at) from inserted"
+	var from *time.Time
+	lib.FatalOnError(con.QueryRow(query).Scan(&from))
+	if from == nil {
+		return
+	}
+	to := time.Now()
+	cleared, err := lib.ClearMetricCache(con, ctx, from, &to)
+	if err != nil {
+		lib.Printf("Cannot clear metric cache: %v\n", err)
+	} else if ctx.Debug > 0 {
+		lib.Printf("Cleared %d cached metric results since %s\n", cleared, lib.ToYMDHMSDate(*from))
+	}
+}
+
+// fileChurn - aggregated changes of a single file
+type fileChurn struct {
+	commits int64
+	added   int64
+	removed int64
+	author  string
+	email   string
+	dt      time.Time
+}
+
+// parseChurn - parses `git_churn.sh` output: HEAD SHA in the first line, then commits from the oldest
+// Each commit is a "♂♀sha♂♀author♂♀email♂♀unix time" line followed by `--numstat` lines
+func parseChurn(out string) (head string, files map[string]*fileChurn, err error) {
+	files = make(map[string]*fileChurn)
+	var (
+		author string
+		email  string
+		dt     time.Time
+	)
+	for i, line := range strings.Split(out, "\n") {
+		if i == 0 {
+			head = strings.TrimSpace(line)
+			continue
+		}
+		if strings.HasPrefix(line, "♂♀") {
+			ary := strings.Split(line, "♂♀")
+			if len(ary) != 5 {
+				return "", nil, fmt.Errorf("invalid commit line: '%s'", line)
+			}
+			unixTimeStamp, err := strconv.ParseInt(strings.TrimSpace(ary[4]), 10, 64)
+			if err != nil {
+				return "", nil, err
+			}
+			author, email, dt = ary[2], ary[3], time.Unix(unixTimeStamp, 0)
+			continue
+		}
+		ary := strings.SplitN(line, "\t", 3)
+		if len(ary) != 3 {
+			continue
+		}
+		churn, ok := files[ary[2]]
+		if !ok {
+			churn = &fileChurn{}
+			files[ary[2]] = churn
+		}
+		churn.commits++
+		// Binary files are reported with "-" lines
+		if n, err := strconv.ParseInt(ary[0], 10, 64); err == nil {
+			churn.added += n
+		}
+		if n, err := strconv.ParseInt(ary[1], 10, 64); err == nil {
+			churn.removed += n
+		}
+		// Commits are from the oldest, so the last one is the last author
+		churn.author, churn.email, churn.dt = author, email, dt
+	}
+	return
+}
+
+// churnRepo - updates `gha_files_churn` with commits done since the last run (`gha_files_churn_repos` holds last SHA)
+// When history was rewritten all repo's commits are processed again
+func churnRepo(ctx *lib.Ctx, con *sql.DB, filesSkipPattern *regexp.Regexp, repo string) error {
+	// Local or cron mode?
+	cmdPrefix := ""
+	if ctx.Local {
+		cmdPrefix = lib.LocalGitScripts
+	}
+	rwd := ctx.ReposDir + repo
+	lastSHA := ""
+	err := con.QueryRow("select sha from gha_files_churn_repos where repo_name = $1", repo).Scan(&lastSHA)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	dtStart := time.Now()
+	out, err := lib.ExecCommand(ctx, []string{cmdPrefix + "git_churn.sh", rwd, lastSHA}, nil)
+	full := lastSHA == ""
+	if err != nil && !full {
+		if ctx.Debug > 0 {
+			lib.Printf("%s: cannot get commits since %s, processing all commits\n", repo, lastSHA)
+		}
+		full = true
+		out, err = lib.ExecCommand(ctx, []string{cmdPrefix + "git_churn.sh", rwd}, nil)
+	}
+	if err != nil {
+		return err
+	}
+	head, files, err := parseChurn(out)
+	if err != nil {
+		return err
+	}
+	if head == lastSHA {
+		return nil
+	}
+
+	// Update all repo's data in transaction: all or none
+	tx, err := con.Begin()
+	if err != nil {
+		return err
+	}
+	if full {
+		lib.ExecSQLTxWithErr(tx, ctx, "delete from gha_files_churn where repo_name = $1", repo)
+	}
+	for path, churn := range files {
+		if filesSkipPattern != nil && filesSkipPattern.MatchString(path) {
+			continue
+		}
+		lib.ExecSQLTxWithErr(
+			tx,
+			ctx,
+			"insert into gha_files_churn(repo_name, path, commits, added, removed, last_author, last_email, last_dt) "+
+				lib.NValues(8)+" on conflict (repo_name, path) do update set "+
+				"commits = gha_files_churn.commits + excluded.commits, "+
+				"added = gha_files_churn.added + excluded.added, "+
+				"removed = gha_files_churn.removed + excluded.removed, "+
+				"last_author = excluded.last_author, last_email = excluded.last_email, last_dt = excluded.last_dt",
+			lib.AnyArray{repo, path, churn.commits, churn.added, churn.removed, churn.author, churn.email, churn.dt}...,
+		)
+	}
+	lib.ExecSQLTxWithErr(
+		tx,
+		ctx,
+		"insert into gha_files_churn_repos(repo_name, sha, dt) "+lib.NValues(3)+
+			" on conflict (repo_name) do update set sha = excluded.sha, dt = excluded.dt",
+		lib.AnyArray{repo, head, time.Now()}...,
+	)
+	err = tx.Commit()
+	if err == nil && ctx.Debug > 0 {
+		lib.Printf("%s: churn of %d files updated (full: %v), took %v\n", repo, len(files), full, time.Now().Sub(dtStart))
+	}
+	return err
+}
+
+// processChurn updates files churn of all repos (that are cloned) in all databases given in `dbs`
+func processChurn(runCtx context.Context, ctx *lib.Ctx, dbs map[string]dbConfig) {
+	// Set non-fatal exec mode, git log can fail for single repo
+	ctx.ExecFatal = false
+	ctx.ExecQuiet = true
+
+	pool := lib.NewPool(runCtx, lib.GetThreadsNum(ctx), ctx.FailFast)
+	gitSem := lib.GitSemaphore(ctx)
+	var (
+		mtx     sync.Mutex
+		checked int
+		failed  int
+	)
+	allN := 0
+	dtStart := time.Now()
+	lastTime := dtStart
+dbs:
+	for db, cfg := range dbs {
+		con := lib.PgConnDB(ctx, db)
+		var re *regexp.Regexp
+		if cfg.filesSkipPattern != "" {
+			re = regexp.MustCompile(cfg.filesSkipPattern)
+		}
+		rows, err := con.Query("select distinct name from gha_repos where name like '%/%'")
+		lib.FatalOnError(err)
+		repos := []string{}
+		var repo string
+		for rows.Next() {
+			lib.FatalOnError(rows.Scan(&repo))
+			if !cfg.repoMatches(repo) {
+				continue
+			}
+			exists, err := dirExists(ctx.ReposDir + repo)
+			lib.FatalOnError(err)
+			if exists && !isSnapshot(ctx.ReposDir+repo) {
+				repos = append(repos, repo)
+			}
+		}
+		lib.FatalOnError(rows.Err())
+		lib.FatalOnError(rows.Close())
+		mtx.Lock()
+		allN += len(repos)
+		mtx.Unlock()
+		for _, repo := range repos {
+			repo := repo
+			started := pool.Go(func(gctx context.Context) error {
+				release, err := gitSem.Acquire(gctx)
+				if err != nil {
+					if gctx.Err() != nil {
+						return nil
+					}
+					return err
+				}
+				defer release()
+				err = churnRepo(ctx, con, re, repo)
+				mtx.Lock()
+				if err != nil {
+					failed++
+					fmt.Fprintf(os.Stderr, "Warning %s churn failed: %+v\n", repo, err)
+				}
+				checked++
+				lib.ProgressInfo(checked, allN, dtStart, &lastTime, time.Duration(10)*time.Second, repo)
+				mtx.Unlock()
+				if err != nil {
+					return fmt.Errorf("%s: churn failed: %v", repo, err)
+				}
+				return nil
+			})
+			if !started {
+				break dbs
+			}
+		}
+	}
+	errs := pool.Wait()
+	if runCtx.Err() != nil {
+		lib.Printf("Files churn cancelled after %d/%d repos\n", checked, allN)
+		return
+	}
+	if ctx.FailFast && len(errs) > 0 {
+		lib.FatalOnError(errs[0])
+	}
+	lib.Printf("Files churn updated for %d/%d repos, took %v\n", checked-failed, allN, time.Now().Sub(dtStart))
 }
 
 // processCommits process all databases given in `dbs`
 // on each database it creates/updates mapping between commits and list of files they refer to
 // It is multithreaded processing up to NCPU databases at the same time
-func processCommits(ctx *lib.Ctx, dbs map[string]string) {
+func processCommits(runCtx context.Context, ctx *lib.Ctx, dbs map[string]dbConfig, orgsAuth map[string]gitAuth) {
 	// Read SQL to get commits to sync from 'util_sql/list_unprocessed_commits.sql' file.
 	// Local or cron mode?
 	dataPrefix := lib.DataDir
@@ -467,22 +2285,26 @@ func processCommits(ctx *lib.Ctx, dbs map[string]string) {
 	// Process all DBs in a separate threads to get all commits
 	dtStart := time.Now()
 	thrN := lib.GetThreadsNum(ctx)
-	chanPool := []chan dbCommits{}
+	var mtx sync.Mutex
+	pool := lib.NewPool(runCtx, thrN, ctx.FailFast)
 	allCommits := []dbCommits{}
-	for db, filesSkipPattern := range dbs {
-		ch := make(chan dbCommits)
-		chanPool = append(chanPool, ch)
-		go processCommitsDB(ch, ctx, db, filesSkipPattern, sqlQuery)
-		if len(chanPool) == thrN {
-			ch = chanPool[0]
-			commits := <-ch
+	for db, cfg := range dbs {
+		db, cfg := db, cfg
+		started := pool.Go(func(gctx context.Context) error {
+			commits := processCommitsDB(ctx, db, cfg, sqlQuery)
+			mtx.Lock()
 			allCommits = append(allCommits, commits)
-			chanPool = chanPool[1:]
+			mtx.Unlock()
+			return nil
+		})
+		if !started {
+			break
 		}
 	}
-	for _, ch := range chanPool {
-		commits := <-ch
-		allCommits = append(allCommits, commits)
+	pool.Wait()
+	if runCtx.Err() != nil {
+		lib.Printf("Commits processing cancelled\n")
+		return
 	}
 	dtEnd := time.Now()
 	lib.Printf("Got new commits list: took %v\n", dtEnd.Sub(dtStart))
@@ -498,7 +2320,8 @@ func processCommits(ctx *lib.Ctx, dbs map[string]string) {
 	// Create final 'commits - file list' associations
 	dtStart = time.Now()
 	lastTime := dtStart
-	chPool := []chan int{}
+	pool = lib.NewPool(runCtx, thrN, ctx.FailFast)
+	gitSem := lib.GitSemaphore(ctx)
 	statuses := make(map[int]int)
 	// statuses:
 	// -1: error
@@ -509,11 +2332,23 @@ func processCommits(ctx *lib.Ctx, dbs map[string]string) {
 	statuses[1] = 0
 	allN := 0
 	checked := 0
-	// Count all commits
+	// Count all commits, tarball snapshots have no history, their commits are processed after repo is cloned successfully
+	snapshots := make(map[string]bool)
 	for _, commits := range allCommits {
-		allN += len(commits.shas)
+		for _, repo := range commits.repos {
+			snapshot, ok := snapshots[repo]
+			if !ok {
+				snapshot = isSnapshot(ctx.ReposDir + repo)
+				snapshots[repo] = snapshot
+			}
+			if !snapshot {
+				allN++
+			}
+		}
 	}
 	// process all commits
+	defaultAuth := newGitAuth(ctx.GitToken, ctx.GitSSHKey)
+commits:
 	for _, commits := range allCommits {
 		con := commits.con
 		filesSkipPattern := commits.filesSkipPattern
@@ -523,22 +2358,45 @@ func processCommits(ctx *lib.Ctx, dbs map[string]string) {
 		}
 		for i, sha := range commits.shas {
 			repo := commits.repos[i]
-			ch := make(chan int)
-			chPool = append(chPool, ch)
-			go getCommitFiles(ch, ctx, con, re, repo, sha)
-			if len(chPool) == thrN {
-				ch = chPool[0]
-				statuses[<-ch]++
-				chPool = chPool[1:]
+			if snapshots[repo] {
+				continue
+			}
+			auth, ok := orgsAuth[strings.Split(repo, "/")[0]]
+			if !ok {
+				auth = defaultAuth
+			}
+			sha := sha
+			started := pool.Go(func(gctx context.Context) error {
+				release, err := gitSem.Acquire(gctx)
+				if err != nil {
+					if gctx.Err() != nil {
+						return nil
+					}
+					return err
+				}
+				defer release()
+				status := getCommitFiles(ctx, con, re, repo, sha, auth)
+				mtx.Lock()
+				statuses[status]++
 				checked++
 				lib.ProgressInfo(checked, allN, dtStart, &lastTime, time.Duration(10)*time.Second, repo)
+				mtx.Unlock()
+				if status < 0 {
+					return fmt.Errorf("%s: getting files for commit %s failed", repo, sha)
+				}
+				return nil
+			})
+			if !started {
+				break commits
 			}
 		}
 	}
-	for _, ch := range chPool {
-		statuses[<-ch]++
-		checked++
-		lib.ProgressInfo(checked, allN, dtStart, &lastTime, time.Duration(10)*time.Second, "final join...")
+	// Commits processed before cancellation are postprocessed, so they don't need to be processed again
+	errs := pool.Wait()
+	if runCtx.Err() != nil {
+		lib.Printf("Commits processing cancelled after %d/%d commits\n", checked, allN)
+	} else if ctx.FailFast && len(errs) > 0 {
+		lib.FatalOnError(errs[0])
 	}
 	dtEnd = time.Now()
 	all := statuses[-1] + statuses[0] + statuses[1]
@@ -566,21 +2424,16 @@ func processCommits(ctx *lib.Ctx, dbs map[string]string) {
 	)
 	lib.FatalOnError(err)
 	sqlQuery = string(bytes)
-	chPool = []chan int{}
+	// Always postprocess all databases that got commits, do not cancel it
+	pool = lib.NewPool(context.Background(), thrN, false)
 	for _, commits := range allCommits {
 		con := commits.con
-		ch := make(chan int)
-		chPool = append(chPool, ch)
-		go postprocessCommitsDB(ch, ctx, con, sqlQuery)
-		if len(chPool) == thrN {
-			ch = chPool[0]
-			<-ch
-			chPool = chPool[1:]
-		}
-	}
-	for _, ch := range chPool {
-		<-ch
+		pool.Go(func(gctx context.Context) error {
+			postprocessCommitsDB(ctx, con, sqlQuery)
+			return nil
+		})
 	}
+	pool.Wait()
 	dtEnd = time.Now()
 	lib.Printf("Postprocessed all new commits, took %v\n", dtEnd.Sub(dtStart))
 }
@@ -590,12 +2443,30 @@ func main() {
 	// Environment context parse
 	var ctx lib.Ctx
 	ctx.Init()
-	dbs, repos := getRepos(&ctx)
+	// Ctrl-C or SIGTERM stops processing
+	runCtx, cancel := lib.SignalContext()
+	defer cancel()
+	dbs, repos, orgsAuth, knownRepos := getRepos(&ctx)
+	if ctx.PruneRepos {
+		pruneRepos(&ctx, knownRepos)
+	}
+	var done map[string]bool
 	if ctx.ProcessRepos {
-		processRepos(&ctx, repos)
+		done = processRepos(runCtx, &ctx, dbs, repos, orgsAuth)
 	}
 	if ctx.ProcessCommits {
-		processCommits(&ctx, dbs)
+		processCommits(runCtx, &ctx, dbs, orgsAuth)
+	}
+	if ctx.FilesChurn {
+		processChurn(runCtx, &ctx, dbs)
+	}
+	// Watermarks can only be moved when repos were processed
+	if ctx.IncrementalRepos && ctx.ProcessRepos && runCtx.Err() == nil {
+		saveWatermarks(&ctx, dbs, done)
+	}
+	// Callers (gha2db_sync) must know that not everything was processed
+	if runCtx.Err() != nil {
+		lib.FatalOnError(lib.ErrInterrupted)
 	}
 	dtEnd := time.Now()
 	lib.Printf("All repos processed in: %v\n", dtEnd.Sub(dtStart))
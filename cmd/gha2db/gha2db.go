@@ -1,19 +1,27 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
-	"compress/gzip"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	lib "devstats"
+
+	yaml "gopkg.in/yaml.v2"
 )
 
 // Inserts single GHA Actor
@@ -31,15 +39,16 @@ func ghaActor(con *sql.Tx, ctx *lib.Ctx, actor *lib.Actor) {
 }
 
 // Inserts single GHA Repo
-func ghaRepo(db *sql.DB, ctx *lib.Ctx, repo *lib.Repo, orgID, orgLogin interface{}) {
+// New repositories get repo group from project's `repo_groups` rules (if any matches), existing ones are not changed
+func ghaRepo(db *sql.DB, ctx *lib.Ctx, repo *lib.Repo, orgID, orgLogin, group interface{}) {
 	// gha_repos
 	// {"id:Fixnum"=>48592, "name:String"=>48592, "url:String"=>48592}
 	// {"id"=>8, "name"=>111, "url"=>140}
 	lib.ExecSQLWithErr(
 		db,
 		ctx,
-		lib.InsertIgnore("into gha_repos(id, name, org_id, org_login) "+lib.NValues(4)),
-		lib.AnyArray{repo.ID, repo.Name, orgID, orgLogin}...,
+		lib.InsertIgnore("into gha_repos(id, name, org_id, org_login, repo_group) "+lib.NValues(5)),
+		lib.AnyArray{repo.ID, repo.Name, orgID, orgLogin, group}...,
 	)
 }
 
@@ -60,21 +69,19 @@ func ghaOrg(db *sql.DB, ctx *lib.Ctx, org *lib.Org) {
 }
 
 // Inserts single GHA Milestone
-func ghaMilestone(con *sql.Tx, ctx *lib.Ctx, eid string, milestone *lib.Milestone, ev *lib.Event) {
+func ghaMilestone(con *sql.Tx, ctx *lib.Ctx, bw *lib.BulkWriter, eid string, milestone *lib.Milestone, ev *lib.Event) {
 	// creator
 	if milestone.Creator != nil {
 		ghaActor(con, ctx, milestone.Creator)
 	}
 
 	// gha_milestones
-	lib.ExecSQLTxWithErr(
-		con,
-		ctx,
-		"insert into gha_milestones("+
-			"id, event_id, closed_at, closed_issues, created_at, creator_id, "+
+	bw.Insert(
+		"gha_milestones",
+		"id, event_id, closed_at, closed_issues, created_at, creator_id, "+
 			"description, due_on, number, open_issues, state, title, updated_at, "+
 			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-			"dupn_creator_login) "+lib.NValues(20),
+			"dupn_creator_login",
 		lib.AnyArray{
 			milestone.ID,
 			eid,
@@ -101,7 +108,7 @@ func ghaMilestone(con *sql.Tx, ctx *lib.Ctx, eid string, milestone *lib.Mileston
 }
 
 // Inserts single GHA Forkee (old format < 2015)
-func ghaForkeeOld(con *sql.Tx, ctx *lib.Ctx, eid string, forkee *lib.ForkeeOld, actor *lib.Actor, repo *lib.Repo, ev *lib.EventOld) {
+func ghaForkeeOld(con *sql.Tx, ctx *lib.Ctx, bw *lib.BulkWriter, eid string, forkee *lib.ForkeeOld, ev *lib.Event) {
 
 	// Lookup author by GitHub login
 	aid := lookupActorTx(con, ctx, forkee.Owner)
@@ -112,16 +119,14 @@ func ghaForkeeOld(con *sql.Tx, ctx *lib.Ctx, eid string, forkee *lib.ForkeeOld,
 
 	// gha_forkees
 	// Table details and analysis in `analysis/analysis.txt` and `analysis/forkee_*.json`
-	lib.ExecSQLTxWithErr(
-		con,
-		ctx,
-		"insert into gha_forkees("+
-			"id, event_id, name, full_name, owner_id, description, fork, "+
+	bw.Insert(
+		"gha_forkees",
+		"id, event_id, name, full_name, owner_id, description, fork, "+
 			"created_at, updated_at, pushed_at, homepage, size, language, organization, "+
 			"stargazers_count, has_issues, has_projects, has_downloads, "+
 			"has_wiki, has_pages, forks, default_branch, open_issues, watchers, public, "+
 			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-			"dup_owner_login) "+lib.NValues(32),
+			"dup_owner_login",
 		lib.AnyArray{
 			forkee.ID,
 			eid,
@@ -148,10 +153,10 @@ func ghaForkeeOld(con *sql.Tx, ctx *lib.Ctx, eid string, forkee *lib.ForkeeOld,
 			forkee.OpenIssues,
 			forkee.Watchers,
 			lib.NegatedBoolOrNil(forkee.Private),
-			actor.ID,
-			actor.Login,
-			repo.ID,
-			repo.Name,
+			ev.Actor.ID,
+			ev.Actor.Login,
+			ev.Repo.ID,
+			ev.Repo.Name,
 			ev.Type,
 			ev.CreatedAt,
 			owner.Login,
@@ -160,22 +165,20 @@ func ghaForkeeOld(con *sql.Tx, ctx *lib.Ctx, eid string, forkee *lib.ForkeeOld,
 }
 
 // Inserts single GHA Forkee
-func ghaForkee(con *sql.Tx, ctx *lib.Ctx, eid string, forkee *lib.Forkee, ev *lib.Event) {
+func ghaForkee(con *sql.Tx, ctx *lib.Ctx, bw *lib.BulkWriter, eid string, forkee *lib.Forkee, ev *lib.Event) {
 	// owner
 	ghaActor(con, ctx, &forkee.Owner)
 
 	// gha_forkees
 	// Table details and analysis in `analysis/analysis.txt` and `analysis/forkee_*.json`
-	lib.ExecSQLTxWithErr(
-		con,
-		ctx,
-		"insert into gha_forkees("+
-			"id, event_id, name, full_name, owner_id, description, fork, "+
+	bw.Insert(
+		"gha_forkees",
+		"id, event_id, name, full_name, owner_id, description, fork, "+
 			"created_at, updated_at, pushed_at, homepage, size, language, organization, "+
 			"stargazers_count, has_issues, has_projects, has_downloads, "+
 			"has_wiki, has_pages, forks, default_branch, open_issues, watchers, public, "+
 			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-			"dup_owner_login) "+lib.NValues(32),
+			"dup_owner_login",
 		lib.AnyArray{
 			forkee.ID,
 			eid,
@@ -214,7 +217,7 @@ func ghaForkee(con *sql.Tx, ctx *lib.Ctx, eid string, forkee *lib.Forkee, ev *li
 }
 
 // Inserts single GHA Branch
-func ghaBranch(con *sql.Tx, ctx *lib.Ctx, eid string, branch *lib.Branch, ev *lib.Event, skipIDs []int) {
+func ghaBranch(con *sql.Tx, ctx *lib.Ctx, bw *lib.BulkWriter, eid string, branch *lib.Branch, ev *lib.Event, skipIDs []int) {
 	// user
 	if branch.User != nil {
 		ghaActor(con, ctx, branch.User)
@@ -231,18 +234,15 @@ func ghaBranch(con *sql.Tx, ctx *lib.Ctx, eid string, branch *lib.Branch, ev *li
 			}
 		}
 		if insert {
-			ghaForkee(con, ctx, eid, branch.Repo, ev)
+			ghaForkee(con, ctx, bw, eid, branch.Repo, ev)
 		}
 	}
 
 	// gha_branches
-	lib.ExecSQLTxWithErr(
-		con,
-		ctx,
-		"insert into gha_branches("+
-			"sha, event_id, user_id, repo_id, label, ref, "+
-			"dup_type, dup_created_at, dupn_user_login, dupn_forkee_name"+
-			") "+lib.NValues(10),
+	bw.Insert(
+		"gha_branches",
+		"sha, event_id, user_id, repo_id, label, ref, "+
+			"dup_type, dup_created_at, dupn_user_login, dupn_forkee_name",
 		lib.AnyArray{
 			branch.SHA,
 			eid,
@@ -404,20 +404,17 @@ func eventExists(db *sql.DB, ctx *lib.Ctx, eventID string) bool {
 // "action:String"=>370, "sha:String"=>370, "html_url:String"=>370}
 // {"page_name"=>65, "title"=>65, "summary"=>0, "action"=>7, "sha"=>40, "html_url"=>130}
 // 370
-func ghaPages(con *sql.Tx, ctx *lib.Ctx, payloadPages *[]lib.Page, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
+func ghaPages(con *sql.Tx, ctx *lib.Ctx, bw *lib.BulkWriter, payloadPages *[]lib.Page, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
 	pages := []lib.Page{}
 	if payloadPages != nil {
 		pages = *payloadPages
 	}
 	for _, page := range pages {
 		sha := page.SHA
-		lib.ExecSQLTxWithErr(
-			con,
-			ctx,
-			lib.InsertIgnore(
-				"into gha_pages(sha, event_id, action, title, "+
-					"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at"+
-					") "+lib.NValues(10)),
+		bw.Insert(
+			"gha_pages",
+			"sha, event_id, action, title, "+
+				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at",
 			lib.AnyArray{
 				sha,
 				eventID,
@@ -436,7 +433,7 @@ func ghaPages(con *sql.Tx, ctx *lib.Ctx, payloadPages *[]lib.Page, eventID strin
 
 // gha_comments
 // Table details and analysis in `analysis/analysis.txt` and `analysis/comment_*.json`
-func ghaComment(con *sql.Tx, ctx *lib.Ctx, payloadComment *lib.Comment, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
+func ghaComment(con *sql.Tx, ctx *lib.Ctx, bw *lib.BulkWriter, payloadComment *lib.Comment, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
 	if payloadComment == nil {
 		return
 	}
@@ -447,17 +444,13 @@ func ghaComment(con *sql.Tx, ctx *lib.Ctx, payloadComment *lib.Comment, eventID
 
 	// comment
 	cid := comment.ID
-	lib.ExecSQLTxWithErr(
-		con,
-		ctx,
-		lib.InsertIgnore(
-			"into gha_comments("+
-				"id, event_id, body, created_at, updated_at, user_id, "+
-				"commit_id, original_commit_id, diff_hunk, position, "+
-				"original_position, path, pull_request_review_id, line, "+
-				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-				"dup_user_login) "+lib.NValues(21),
-		),
+	bw.Insert(
+		"gha_comments",
+		"id, event_id, body, created_at, updated_at, user_id, "+
+			"commit_id, original_commit_id, diff_hunk, position, "+
+			"original_position, path, pull_request_review_id, line, "+
+			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
+			"dup_user_login",
 		lib.AnyArray{
 			cid,
 			eventID,
@@ -486,7 +479,7 @@ func ghaComment(con *sql.Tx, ctx *lib.Ctx, payloadComment *lib.Comment, eventID
 
 // gha_releases
 // Table details and analysis in `analysis/analysis.txt` and `analysis/release_*.json`
-func ghaRelease(con *sql.Tx, ctx *lib.Ctx, payloadRelease *lib.Release, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
+func ghaRelease(con *sql.Tx, ctx *lib.Ctx, bw *lib.BulkWriter, payloadRelease *lib.Release, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
 	if payloadRelease == nil {
 		return
 	}
@@ -497,14 +490,12 @@ func ghaRelease(con *sql.Tx, ctx *lib.Ctx, payloadRelease *lib.Release, eventID
 
 	// release
 	rid := release.ID
-	lib.ExecSQLTxWithErr(
-		con,
-		ctx,
-		"insert into gha_releases("+
-			"id, event_id, tag_name, target_commitish, name, draft, "+
+	bw.Insert(
+		"gha_releases",
+		"id, event_id, tag_name, target_commitish, name, draft, "+
 			"author_id, prerelease, created_at, published_at, body, "+
 			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-			"dup_author_login) "+lib.NValues(18),
+			"dup_author_login",
 		lib.AnyArray{
 			rid,
 			eventID,
@@ -534,14 +525,12 @@ func ghaRelease(con *sql.Tx, ctx *lib.Ctx, payloadRelease *lib.Release, eventID
 
 		// asset
 		aid := asset.ID
-		lib.ExecSQLTxWithErr(
-			con,
-			ctx,
-			"insert into gha_assets("+
-				"id, event_id, name, label, uploader_id, content_type, "+
+		bw.Insert(
+			"gha_assets",
+			"id, event_id, name, label, uploader_id, content_type, "+
 				"state, size, download_count, created_at, updated_at, "+
 				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-				"dup_uploader_login) "+lib.NValues(18),
+				"dup_uploader_login",
 			lib.AnyArray{
 				aid,
 				eventID,
@@ -565,10 +554,9 @@ func ghaRelease(con *sql.Tx, ctx *lib.Ctx, payloadRelease *lib.Release, eventID
 		)
 
 		// release-asset connection
-		lib.ExecSQLTxWithErr(
-			con,
-			ctx,
-			"insert into gha_releases_assets(release_id, event_id, asset_id) "+lib.NValues(3),
+		bw.Insert(
+			"gha_releases_assets",
+			"release_id, event_id, asset_id",
 			lib.AnyArray{rid, eventID, aid}...,
 		)
 	}
@@ -576,7 +564,7 @@ func ghaRelease(con *sql.Tx, ctx *lib.Ctx, payloadRelease *lib.Release, eventID
 
 // gha_pull_requests
 // Table details and analysis in `analysis/analysis.txt` and `analysis/pull_request_*.json`
-func ghaPullRequest(con *sql.Tx, ctx *lib.Ctx, payloadPullRequest *lib.PullRequest, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time, forkeeIDsToSkip []int) {
+func ghaPullRequest(con *sql.Tx, ctx *lib.Ctx, bw *lib.BulkWriter, payloadPullRequest *lib.PullRequest, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time, origin string, forkeeIDsToSkip []int) {
 	if payloadPullRequest == nil {
 		return
 	}
@@ -595,14 +583,14 @@ func ghaPullRequest(con *sql.Tx, ctx *lib.Ctx, payloadPullRequest *lib.PullReque
 	ev := lib.Event{Actor: *actor, Repo: *repo, Type: eType, CreatedAt: eCreatedAt}
 
 	// base
-	ghaBranch(con, ctx, eventID, &pr.Base, &ev, forkeeIDsToSkip)
+	ghaBranch(con, ctx, bw, eventID, &pr.Base, &ev, forkeeIDsToSkip)
 
 	// head (if different, and skip its repo if defined and the same as base repo)
 	if baseSHA != headSHA {
 		if baseRepoID != nil {
 			forkeeIDsToSkip = append(forkeeIDsToSkip, baseRepoID.(int))
 		}
-		ghaBranch(con, ctx, eventID, &pr.Head, &ev, forkeeIDsToSkip)
+		ghaBranch(con, ctx, bw, eventID, &pr.Head, &ev, forkeeIDsToSkip)
 	}
 
 	// merged_by
@@ -617,21 +605,19 @@ func ghaPullRequest(con *sql.Tx, ctx *lib.Ctx, payloadPullRequest *lib.PullReque
 
 	// milestone
 	if pr.Milestone != nil {
-		ghaMilestone(con, ctx, eventID, pr.Milestone, &ev)
+		ghaMilestone(con, ctx, bw, eventID, pr.Milestone, &ev)
 	}
 
 	// pull_request
 	prid := pr.ID
-	lib.ExecSQLTxWithErr(
-		con,
-		ctx,
-		"insert into gha_pull_requests("+
-			"id, event_id, user_id, base_sha, head_sha, merged_by_id, assignee_id, milestone_id, "+
+	bw.Insert(
+		"gha_pull_requests",
+		"id, event_id, user_id, base_sha, head_sha, merged_by_id, assignee_id, milestone_id, "+
 			"number, state, locked, title, body, created_at, updated_at, closed_at, merged_at, "+
 			"merge_commit_sha, merged, mergeable, rebaseable, mergeable_state, comments, "+
 			"review_comments, maintainer_can_modify, commits, additions, deletions, changed_files, "+
 			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-			"dup_user_login, dupn_assignee_login, dupn_merged_by_login) "+lib.NValues(38),
+			"dup_user_login, dupn_assignee_login, dupn_merged_by_login, origin",
 		lib.AnyArray{
 			prid,
 			eventID,
@@ -671,6 +657,7 @@ func ghaPullRequest(con *sql.Tx, ctx *lib.Ctx, payloadPullRequest *lib.PullReque
 			pr.User.Login,
 			lib.ActorLoginOrNil(pr.Assignee),
 			lib.ActorLoginOrNil(pr.MergedBy),
+			origin,
 		}...,
 	)
 
@@ -698,10 +685,9 @@ func ghaPullRequest(con *sql.Tx, ctx *lib.Ctx, payloadPullRequest *lib.PullReque
 		ghaActor(con, ctx, &assignee)
 
 		// pull_request-assignee connection
-		lib.ExecSQLTxWithErr(
-			con,
-			ctx,
-			"insert into gha_pull_requests_assignees(pull_request_id, event_id, assignee_id) "+lib.NValues(3),
+		bw.Insert(
+			"gha_pull_requests_assignees",
+			"pull_request_id, event_id, assignee_id",
 			lib.AnyArray{prid, eventID, assignee.ID}...,
 		)
 	}
@@ -713,10 +699,9 @@ func ghaPullRequest(con *sql.Tx, ctx *lib.Ctx, payloadPullRequest *lib.PullReque
 			ghaActor(con, ctx, &reviewer)
 
 			// pull_request-requested_reviewer connection
-			lib.ExecSQLTxWithErr(
-				con,
-				ctx,
-				"insert into gha_pull_requests_requested_reviewers(pull_request_id, event_id, requested_reviewer_id) "+lib.NValues(3),
+			bw.Insert(
+				"gha_pull_requests_requested_reviewers",
+				"pull_request_id, event_id, requested_reviewer_id",
 				lib.AnyArray{prid, eventID, reviewer.ID}...,
 			)
 		}
@@ -724,7 +709,7 @@ func ghaPullRequest(con *sql.Tx, ctx *lib.Ctx, payloadPullRequest *lib.PullReque
 }
 
 // gha_teams
-func ghaTeam(con *sql.Tx, ctx *lib.Ctx, payloadTeam *lib.Team, payloadRepo *lib.Forkee, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
+func ghaTeam(con *sql.Tx, ctx *lib.Ctx, bw *lib.BulkWriter, payloadTeam *lib.Team, payloadRepo *lib.Forkee, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
 	if payloadTeam == nil {
 		return
 	}
@@ -732,13 +717,10 @@ func ghaTeam(con *sql.Tx, ctx *lib.Ctx, payloadTeam *lib.Team, payloadRepo *lib.
 
 	// team
 	tid := team.ID
-	lib.ExecSQLTxWithErr(
-		con,
-		ctx,
-		"insert into gha_teams("+
-			"id, event_id, name, slug, permission, "+
-			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at"+
-			") "+lib.NValues(11),
+	bw.Insert(
+		"gha_teams",
+		"id, event_id, name, slug, permission, "+
+			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at",
 		lib.AnyArray{
 			tid,
 			eventID,
@@ -756,292 +738,132 @@ func ghaTeam(con *sql.Tx, ctx *lib.Ctx, payloadTeam *lib.Team, payloadRepo *lib.
 
 	// team-repository connection
 	if payloadRepo != nil {
-		lib.ExecSQLTxWithErr(
-			con,
-			ctx,
-			"insert into gha_teams_repositories(team_id, event_id, repository_id) "+lib.NValues(3),
+		bw.Insert(
+			"gha_teams_repositories",
+			"team_id, event_id, repository_id",
 			lib.AnyArray{tid, eventID, payloadRepo.ID}...,
 		)
 	}
 }
 
-// Write GHA entire event (in old pre 2015 format) into Postgres DB
-func writeToDBOldFmt(db *sql.DB, ctx *lib.Ctx, eventID string, ev *lib.EventOld) int {
-	if eventExists(db, ctx, eventID) {
-		return 0
-	}
-
-	// Lookup author by GitHub login
-	aid := lookupActor(db, ctx, ev.Actor)
-	actor := lib.Actor{ID: aid, Login: ev.Actor}
-
-	// Repository
-	repository := ev.Repository
-
-	// Find Org ID from Repository.Organization
-	oid := findOrgIDOrNil(db, ctx, repository.Organization)
-
-	// Find Repo ID from Repository (this is a ForkeeOld before 2015).
-	rid, ok := findRepoFromNameAndOrg(db, ctx, repository.Name, oid)
-	if !ok {
-		rid = repository.ID
-	}
+// ghaPullRequestIssue - saves artificial issue (with negative ID) for pre 2015 pull request
+// gha_issues
+// Table details and analysis in `analysis/analysis.txt` and `analysis/issue_*.json`
+func ghaPullRequestIssue(bw *lib.BulkWriter, payloadPullRequest *lib.PullRequest, eventID string, ev *lib.Event) {
+	pr := *payloadPullRequest
 
-	// We defer transaction create until we're inserting data that can be shared between different events
-	lib.ExecSQLWithErr(
-		db,
-		ctx,
-		"insert into gha_events("+
-			"id, type, actor_id, repo_id, public, created_at, "+
-			"dup_actor_login, dup_repo_name, org_id, forkee_id) "+lib.NValues(10),
+	// issue
+	iid := -pr.ID
+	isPR := true
+	comments := 0
+	locked := false
+	if pr.Comments != nil {
+		comments = *pr.Comments
+	}
+	if pr.Locked != nil {
+		locked = *pr.Locked
+	}
+	bw.Insert(
+		"gha_issues",
+		"id, event_id, assignee_id, body, closed_at, comments, created_at, "+
+			"locked, milestone_id, number, state, title, updated_at, user_id, "+
+			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
+			"dup_user_login, dupn_assignee_login, is_pull_request",
 		lib.AnyArray{
+			iid,
 			eventID,
+			lib.ActorIDOrNil(pr.Assignee),
+			lib.TruncStringOrNil(pr.Body, 0xffff),
+			lib.TimeOrNil(pr.ClosedAt),
+			comments,
+			pr.CreatedAt,
+			locked,
+			lib.MilestoneIDOrNil(pr.Milestone),
+			pr.Number,
+			pr.State,
+			pr.Title,
+			pr.UpdatedAt,
+			pr.User.ID,
+			ev.Actor.ID,
+			ev.Actor.Login,
+			ev.Repo.ID,
+			ev.Repo.Name,
 			ev.Type,
-			aid,
-			rid,
-			ev.Public,
 			ev.CreatedAt,
-			ev.Actor,
-			ev.Repository.Name,
-			oid,
-			ev.Repository.ID,
+			pr.User.Login,
+			lib.ActorLoginOrNil(pr.Assignee),
+			isPR,
 		}...,
 	)
 
-	// Organization
-	if repository.Organization != nil {
-		if oid == nil {
-			h := lib.HashStrings([]string{*repository.Organization})
-			oid = &h
-		}
-		ghaOrg(db, ctx, &lib.Org{ID: *oid, Login: *repository.Organization})
-	}
-
-	// Add Repository
-	repo := lib.Repo{ID: rid, Name: repository.Name}
-	ghaRepo(db, ctx, &repo, oid, repository.Organization)
-
-	// Pre 2015 Payload
-	pl := ev.Payload
-	if pl == nil {
-		return 0
-	}
+	var assignees []lib.Actor
 
-	iid := lib.FirstIntOrNil([]*int{pl.Issue, pl.IssueID})
-	cid := lib.CommentIDOrNil(pl.Comment)
-	if cid == nil {
-		cid = lib.IntOrNil(pl.CommentID)
+	prAid := lib.ActorIDOrNil(pr.Assignee)
+	if pr.Assignee != nil {
+		assignees = append(assignees, *pr.Assignee)
 	}
 
-	lib.ExecSQLWithErr(
-		db,
-		ctx,
-		"insert into gha_payloads("+
-			"event_id, push_id, size, ref, head, befor, action, "+
-			"issue_id, pull_request_id, comment_id, ref_type, master_branch, commit, "+
-			"description, number, forkee_id, release_id, member_id, "+
-			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at"+
-			") "+lib.NValues(24),
-		lib.AnyArray{
-			eventID,
-			nil,
-			lib.IntOrNil(pl.Size),
-			lib.TruncStringOrNil(pl.Ref, 200),
-			lib.StringOrNil(pl.Head),
-			nil,
-			lib.StringOrNil(pl.Action),
-			iid,
-			lib.PullRequestIDOrNil(pl.PullRequest),
-			cid,
-			lib.StringOrNil(pl.RefType),
-			lib.TruncStringOrNil(pl.MasterBranch, 200),
-			lib.StringOrNil(pl.Commit),
-			lib.TruncStringOrNil(pl.Description, 0xffff),
-			lib.IntOrNil(pl.Number),
-			lib.ForkeeIDOrNil(pl.Repository),
-			lib.ReleaseIDOrNil(pl.Release),
-			lib.ActorIDOrNil(pl.Member),
-			actor.ID,
-			actor.Login,
-			repo.ID,
-			repo.Name,
-			ev.Type,
-			ev.CreatedAt,
-		}...,
-	)
-
-	// Start transaction for data possibly shared between events
-	con, err := db.Begin()
-	lib.FatalOnError(err)
-
-	// gha_actors
-	ghaActor(con, ctx, &actor)
-
-	// Payload's Forkee (it uses new structure, so I'm giving it precedence over
-	// Event's Forkee (which uses older structure)
-	if pl.Repository != nil {
-		// Reposotory is actually a Forkee (non old in this case!)
-		// Artificial event is only used to allow duplicating EventOld's data
-		// (passed as Event to avoid code duplication)
-		artificialEv := lib.Event{Actor: actor, Repo: repo, Type: ev.Type, CreatedAt: ev.CreatedAt}
-		ghaForkee(con, ctx, eventID, pl.Repository, &artificialEv)
-	}
-
-	// Add Forkee in old mode if we didn't added it from payload or if it is a different Forkee
-	if pl.Repository == nil || pl.Repository.ID != ev.Repository.ID {
-		ghaForkeeOld(con, ctx, eventID, &ev.Repository, &actor, &repo, ev)
-	}
-
-	// SHAs - commits
-	if pl.SHAs != nil {
-		commits := *pl.SHAs
-		for _, comm := range commits {
-			commit, ok := comm.([]interface{})
-			if !ok {
-				lib.FatalOnError(fmt.Errorf("comm is not []interface{}: %+v", comm))
-			}
-			sha, ok := commit[0].(string)
-			if !ok {
-				lib.FatalOnError(fmt.Errorf("commit[0] is not string: %+v", commit[0]))
+	if pr.Assignees != nil {
+		for _, assignee := range *pr.Assignees {
+			aid := assignee.ID
+			if aid == prAid {
+				continue
 			}
-			lib.ExecSQLTxWithErr(
-				con,
-				ctx,
-				"insert into gha_commits("+
-					"sha, event_id, author_name, message, is_distinct, "+
-					"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at"+
-					") "+lib.NValues(11),
-				lib.AnyArray{
-					sha,
-					eventID,
-					lib.TruncToBytes(commit[3].(string), 160),
-					lib.TruncToBytes(commit[2].(string), 0xffff),
-					commit[4].(bool),
-					actor.ID,
-					actor.Login,
-					repo.ID,
-					repo.Name,
-					ev.Type,
-					ev.CreatedAt,
-				}...,
-			)
+			assignees = append(assignees, assignee)
 		}
 	}
 
-	// Pages
-	ghaPages(con, ctx, pl.Pages, eventID, &actor, &repo, ev.Type, ev.CreatedAt)
-
-	// Member
-	if pl.Member != nil {
-		ghaActor(con, ctx, pl.Member)
+	for _, assignee := range assignees {
+		// pull_request-assignee connection
+		bw.Insert(
+			"gha_issues_assignees",
+			"issue_id, event_id, assignee_id",
+			lib.AnyArray{iid, eventID, assignee.ID}...,
+		)
 	}
+}
 
-	// Comment
-	ghaComment(con, ctx, pl.Comment, eventID, &actor, &repo, ev.Type, ev.CreatedAt)
-
-	// Release & assets
-	ghaRelease(con, ctx, pl.Release, eventID, &actor, &repo, ev.Type, ev.CreatedAt)
-
-	// Team & Repo connection
-	ghaTeam(con, ctx, pl.Team, pl.Repository, eventID, &actor, &repo, ev.Type, ev.CreatedAt)
-
-	// Pull Request
-	forkeeIDsToSkip := []int{ev.Repository.ID}
-	if pl.Repository != nil {
-		forkeeIDsToSkip = append(forkeeIDsToSkip, pl.Repository.ID)
+// Write entire GHA event into Postgres DB (new 2015+ format or normalized from pre 2015 format)
+func writeToDB(db *sql.DB, ctx *lib.Ctx, bw *lib.BulkWriter, ev *lib.Event, isBot bool, group interface{}) int {
+	eventID := ev.ID
+	// Events saved by previous runs are skipped, copies from adjacent hours are already skipped by parseHours
+	// Event saved concurrently by another run is not an error: failed COPY falls back to INSERT ... ON CONFLICT DO NOTHING
+	if eventExists(db, ctx, eventID) {
+		return 0
 	}
-	ghaPullRequest(con, ctx, pl.PullRequest, eventID, &actor, &repo, ev.Type, ev.CreatedAt, forkeeIDsToSkip)
-
-	// We need artificial issue
-	// gha_issues
-	// Table details and analysis in `analysis/analysis.txt` and `analysis/issue_*.json`
-	if pl.PullRequest != nil {
-		pr := *pl.PullRequest
-
-		// issue
-		iid = -pr.ID
-		isPR := true
-		comments := 0
-		locked := false
-		if pr.Comments != nil {
-			comments = *pr.Comments
-		}
-		if pr.Locked != nil {
-			locked = *pr.Locked
-		}
-		lib.ExecSQLTxWithErr(
-			con,
-			ctx,
-			"insert into gha_issues("+
-				"id, event_id, assignee_id, body, closed_at, comments, created_at, "+
-				"locked, milestone_id, number, state, title, updated_at, user_id, "+
-				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-				"dup_user_login, dupn_assignee_login, is_pull_request) "+lib.NValues(23),
-			lib.AnyArray{
-				iid,
-				eventID,
-				lib.ActorIDOrNil(pr.Assignee),
-				lib.TruncStringOrNil(pr.Body, 0xffff),
-				lib.TimeOrNil(pr.ClosedAt),
-				comments,
-				pr.CreatedAt,
-				locked,
-				lib.MilestoneIDOrNil(pr.Milestone),
-				pr.Number,
-				pr.State,
-				pr.Title,
-				pr.UpdatedAt,
-				pr.User.ID,
-				actor.ID,
-				actor.Login,
-				repo.ID,
-				repo.Name,
-				ev.Type,
-				ev.CreatedAt,
-				pr.User.Login,
-				lib.ActorLoginOrNil(pr.Assignee),
-				isPR,
-			}...,
-		)
-
-		var assignees []lib.Actor
 
-		prAid := lib.ActorIDOrNil(pr.Assignee)
-		if pr.Assignee != nil {
-			assignees = append(assignees, *pr.Assignee)
+	// Pre 2015 events have no actor, org and repo IDs, they are looked up by names (or hashed)
+	// Their repository is also saved as an old style forkee
+	old := ev.Old
+	var forkeeID interface{}
+	if old != nil {
+		ev.Actor.ID = lookupActor(db, ctx, ev.Actor.Login)
+		oid := findOrgIDOrNil(db, ctx, old.Repository.Organization)
+		if rid, ok := findRepoFromNameAndOrg(db, ctx, ev.Repo.Name, oid); ok {
+			ev.Repo.ID = rid
 		}
-
-		if pr.Assignees != nil {
-			for _, assignee := range *pr.Assignees {
-				aid := assignee.ID
-				if aid == prAid {
-					continue
-				}
-				assignees = append(assignees, assignee)
+		if ev.Org != nil {
+			if oid == nil {
+				h := lib.HashStrings([]string{ev.Org.Login})
+				oid = &h
 			}
+			ev.Org.ID = *oid
 		}
-
-		for _, assignee := range assignees {
-			// pull_request-assignee connection
-			lib.ExecSQLTxWithErr(
-				con,
-				ctx,
-				"insert into gha_issues_assignees(issue_id, event_id, assignee_id) "+lib.NValues(3),
-				lib.AnyArray{iid, eventID, assignee.ID}...,
-			)
-		}
+		forkeeID = old.Repository.ID
 	}
 
-	// Final commit
-	lib.FatalOnError(con.Commit())
-	return 1
-}
-
-// Write entire GHA event (in a new 2015+ format) into Postgres DB
-func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
-	eventID := ev.ID
-	if eventExists(db, ctx, eventID) {
-		return 0
+	// Gerrit changes of GitHub mirrors belong to already known orgs and repos (if any), their IDs are looked up by names
+	origin := lib.EventOrigin(ev)
+	if origin == lib.OriginGerrit {
+		var oid *int
+		if ev.Org != nil {
+			if oid = findOrgIDOrNil(db, ctx, &ev.Org.Login); oid != nil {
+				ev.Org.ID = *oid
+			}
+		}
+		if rid, ok := findRepoFromNameAndOrg(db, ctx, ev.Repo.Name, oid); ok {
+			ev.Repo.ID = rid
+		}
 	}
 
 	// We defer transaction create until we're inserting data that can be shared between different events
@@ -1053,12 +875,12 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 	// "created_at"=>20, "org"=>230}
 	// Fields dup_actor_login, dup_repo_name are copied from (gha_actors and gha_repos) to save
 	// joins on complex queries (MySQL has no hash joins and is very slow on big tables joins)
-	lib.ExecSQLWithErr(
-		db,
-		ctx,
-		"insert into gha_events("+
-			"id, type, actor_id, repo_id, public, created_at, "+
-			"dup_actor_login, dup_repo_name, org_id, forkee_id) "+lib.NValues(10),
+	// Field is_bot is set at ingestion time from bots.yaml and project's bots, so metrics can just use it
+	// Field origin tells where the event comes from (GitHub, GitLab, Gitea/Forgejo or Gerrit)
+	bw.Insert(
+		"gha_events",
+		"id, type, actor_id, repo_id, public, created_at, "+
+			"dup_actor_login, dup_repo_name, org_id, forkee_id, is_bot, origin",
 		lib.AnyArray{
 			eventID,
 			ev.Type,
@@ -1069,20 +891,27 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 			ev.Actor.Login,
 			ev.Repo.Name,
 			lib.OrgIDOrNil(ev.Org),
-			nil,
+			forkeeID,
+			isBot,
+			origin,
 		}...,
 	)
 
 	// Repository
 	repo := ev.Repo
 	org := ev.Org
-	ghaRepo(db, ctx, &repo, lib.OrgIDOrNil(org), lib.OrgLoginOrNil(org))
+	ghaRepo(db, ctx, &repo, lib.OrgIDOrNil(org), lib.OrgLoginOrNil(org), group)
 
 	// Organization
 	if org != nil {
 		ghaOrg(db, ctx, org)
 	}
 
+	// Some pre 2015 events have no payload
+	if old != nil && !old.HasPayload {
+		return 0
+	}
+
 	// gha_payloads
 	// {"push_id:Fixnum"=>24636, "size:Fixnum"=>24636, "distinct_size:Fixnum"=>24636,
 	// "ref:String"=>30522, "head:String"=>24636, "before:String"=>24636, "commits:Array"=>24636,
@@ -1098,16 +927,22 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 	// 48746
 	// using exec_stmt (without select), because payload are per event_id.
 	// Columns duplicated from gha_events starts with "dup_"
+	// Pre 2015 payloads can only have issue and comment IDs (not objects)
 	pl := ev.Payload
-	lib.ExecSQLWithErr(
-		db,
-		ctx,
-		"insert into gha_payloads("+
-			"event_id, push_id, size, ref, head, befor, action, "+
+	issueID := lib.IssueIDOrNil(pl.Issue)
+	if issueID == nil {
+		issueID = lib.IntOrNil(pl.IssueID)
+	}
+	commentID := lib.CommentIDOrNil(pl.Comment)
+	if commentID == nil {
+		commentID = lib.IntOrNil(pl.CommentID)
+	}
+	bw.Insert(
+		"gha_payloads",
+		"event_id, push_id, size, ref, head, befor, action, "+
 			"issue_id, pull_request_id, comment_id, ref_type, master_branch, commit, "+
 			"description, number, forkee_id, release_id, member_id, "+
-			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at"+
-			") "+lib.NValues(24),
+			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, overflow, raw",
 		lib.AnyArray{
 			eventID,
 			lib.IntOrNil(pl.PushID),
@@ -1116,12 +951,12 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 			lib.StringOrNil(pl.Head),
 			lib.StringOrNil(pl.Before),
 			lib.StringOrNil(pl.Action),
-			lib.IssueIDOrNil(pl.Issue),
+			issueID,
 			lib.PullRequestIDOrNil(pl.PullRequest),
-			lib.CommentIDOrNil(pl.Comment),
+			commentID,
 			lib.StringOrNil(pl.RefType),
 			lib.TruncStringOrNil(pl.MasterBranch, 200),
-			nil,
+			lib.StringOrNil(pl.Commit),
 			lib.TruncStringOrNil(pl.Description, 0xffff),
 			lib.IntOrNil(pl.Number),
 			lib.ForkeeIDOrNil(pl.Forkee),
@@ -1133,10 +968,13 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 			ev.Repo.Name,
 			ev.Type,
 			ev.CreatedAt,
+			jsonbOrNil(ev.Overflow),
+			jsonbOrNil(ev.Raw),
 		}...,
 	)
 
 	// Start transaction for data possibly shared between events
+	// Rows specific to this event are queued in `bw` and saved later, in batches
 	con, err := db.Begin()
 	lib.FatalOnError(err)
 
@@ -1156,13 +994,10 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 	}
 	for _, commit := range commits {
 		sha := commit.SHA
-		lib.ExecSQLTxWithErr(
-			con,
-			ctx,
-			"insert into gha_commits("+
-				"sha, event_id, author_name, message, is_distinct, "+
-				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at"+
-				") "+lib.NValues(11),
+		bw.Insert(
+			"gha_commits",
+			"sha, event_id, author_name, message, is_distinct, "+
+				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at",
 			lib.AnyArray{
 				sha,
 				eventID,
@@ -1180,7 +1015,7 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 	}
 
 	// Pages
-	ghaPages(con, ctx, pl.Pages, eventID, &ev.Actor, &ev.Repo, ev.Type, ev.CreatedAt)
+	ghaPages(con, ctx, bw, pl.Pages, eventID, &ev.Actor, &ev.Repo, ev.Type, ev.CreatedAt)
 
 	// Member
 	if pl.Member != nil {
@@ -1188,7 +1023,7 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 	}
 
 	// Comment
-	ghaComment(con, ctx, pl.Comment, eventID, &ev.Actor, &ev.Repo, ev.Type, ev.CreatedAt)
+	ghaComment(con, ctx, bw, pl.Comment, eventID, &ev.Actor, &ev.Repo, ev.Type, ev.CreatedAt)
 
 	// gha_issues
 	// Table details and analysis in `analysis/analysis.txt` and `analysis/issue_*.json`
@@ -1207,14 +1042,12 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 		if issue.PullRequest != nil {
 			isPR = true
 		}
-		lib.ExecSQLTxWithErr(
-			con,
-			ctx,
-			"insert into gha_issues("+
-				"id, event_id, assignee_id, body, closed_at, comments, created_at, "+
+		bw.Insert(
+			"gha_issues",
+			"id, event_id, assignee_id, body, closed_at, comments, created_at, "+
 				"locked, milestone_id, number, state, title, updated_at, user_id, "+
 				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-				"dup_user_login, dupn_assignee_login, is_pull_request) "+lib.NValues(23),
+				"dup_user_login, dupn_assignee_login, is_pull_request",
 			lib.AnyArray{
 				iid,
 				eventID,
@@ -1244,7 +1077,7 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 
 		// milestone
 		if issue.Milestone != nil {
-			ghaMilestone(con, ctx, eventID, issue.Milestone, ev)
+			ghaMilestone(con, ctx, bw, eventID, issue.Milestone, ev)
 		}
 
 		pAid := lib.ActorIDOrNil(issue.Assignee)
@@ -1258,10 +1091,9 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 			ghaActor(con, ctx, &assignee)
 
 			// issue-assignee connection
-			lib.ExecSQLTxWithErr(
-				con,
-				ctx,
-				"insert into gha_issues_assignees(issue_id, event_id, assignee_id) "+lib.NValues(3),
+			bw.Insert(
+				"gha_issues_assignees",
+				"issue_id, event_id, assignee_id",
 				lib.AnyArray{iid, eventID, aid}...,
 			)
 		}
@@ -1282,14 +1114,11 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 			)
 
 			// issue-label connection
-			lib.ExecSQLTxWithErr(
-				con,
-				ctx,
-				lib.InsertIgnore(
-					"into gha_issues_labels(issue_id, event_id, label_id, "+
-						"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-						"dup_issue_number, dup_label_name"+
-						") "+lib.NValues(11)),
+			bw.Insert(
+				"gha_issues_labels",
+				"issue_id, event_id, label_id, "+
+					"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
+					"dup_issue_number, dup_label_name",
 				lib.AnyArray{
 					iid,
 					eventID,
@@ -1309,20 +1138,48 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 
 	// gha_forkees
 	if pl.Forkee != nil {
-		ghaForkee(con, ctx, eventID, pl.Forkee, ev)
+		ghaForkee(con, ctx, bw, eventID, pl.Forkee, ev)
+	}
+
+	// Pre 2015 event's repository is saved as an old style forkee, unless payload already had it
+	forkeeIDsToSkip := []int{}
+	if old != nil {
+		if pl.Forkee == nil || pl.Forkee.ID != old.Repository.ID {
+			ghaForkeeOld(con, ctx, bw, eventID, &old.Repository, ev)
+		}
+		forkeeIDsToSkip = append(forkeeIDsToSkip, old.Repository.ID)
+		if pl.Forkee != nil {
+			forkeeIDsToSkip = append(forkeeIDsToSkip, pl.Forkee.ID)
+		}
 	}
 
 	// Release & assets
-	ghaRelease(con, ctx, pl.Release, eventID, &ev.Actor, &ev.Repo, ev.Type, ev.CreatedAt)
+	ghaRelease(con, ctx, bw, pl.Release, eventID, &ev.Actor, &ev.Repo, ev.Type, ev.CreatedAt)
+
+	// Team & Repo connection (pre 2015 only)
+	ghaTeam(con, ctx, bw, pl.Team, pl.Forkee, eventID, &ev.Actor, &ev.Repo, ev.Type, ev.CreatedAt)
 
 	// Pull Request
-	ghaPullRequest(con, ctx, pl.PullRequest, eventID, &ev.Actor, &ev.Repo, ev.Type, ev.CreatedAt, []int{})
+	ghaPullRequest(con, ctx, bw, pl.PullRequest, eventID, &ev.Actor, &ev.Repo, ev.Type, ev.CreatedAt, origin, forkeeIDsToSkip)
+
+	// Pre 2015 pull requests have no issue objects, artificial issues are created from them
+	if old != nil && pl.PullRequest != nil {
+		ghaPullRequestIssue(bw, pl.PullRequest, eventID, ev)
+	}
 
 	// Final commit
 	lib.FatalOnError(con.Commit())
 	return 1
 }
 
+// jsonbOrNil - JSON documents (unknown payload fields, raw event JSON) are saved as text, null when not set
+func jsonbOrNil(doc []byte) interface{} {
+	if doc == nil {
+		return nil
+	}
+	return string(doc)
+}
+
 // Before 2015 rpository name should be Organization/Name (if Organization present) or just Name
 func makeOldRepoName(repo *lib.ForkeeOld) string {
 	if repo.Organization == nil || *repo.Organization == "" {
@@ -1331,143 +1188,1273 @@ func makeOldRepoName(repo *lib.ForkeeOld) string {
 	return fmt.Sprintf("%s/%s", *repo.Organization, repo.Name)
 }
 
-// parseJSON - parse signle GHA JSON event
-func parseJSON(con *sql.DB, ctx *lib.Ctx, jsonStr []byte, dt time.Time, forg, frepo map[string]struct{}) (f int, e int) {
-	var (
-		h        lib.Event
-		hOld     lib.EventOld
-		err      error
-		fullName string
-		eid      string
+// startCheckpoint - marks hour as started (unfinished)
+// In resume mode unfinished hour is just processed again: event is saved with all its rows in one transaction,
+// so there are no incomplete events, and already saved ones are skipped
+func startCheckpoint(con *sql.DB, ctx *lib.Ctx, dt time.Time, key string) {
+	lib.ExecSQLWithErr(
+		con,
+		ctx,
+		"insert into gha_checkpoints(dt, orgs_repos, jsons, found, events, started, finished) "+
+			"values("+lib.NValue(1)+", "+lib.NValue(2)+", 0, 0, 0, "+lib.NValue(3)+", null) "+
+			"on conflict (dt, orgs_repos) do update set jsons = 0, found = 0, events = 0, "+
+			"started = excluded.started, finished = null, rows = null, download_ms = null, parse_ms = null, save_ms = null",
+		dt,
+		key,
+		time.Now(),
 	)
-	if ctx.OldFormat {
-		err = json.Unmarshal(jsonStr, &hOld)
-	} else {
-		err = json.Unmarshal(jsonStr, &h)
+}
+
+// finishCheckpoint - marks hour as completely ingested
+// Number of rows written and stage timings are saved too, so slow hours can be found later
+func finishCheckpoint(con *sql.DB, ctx *lib.Ctx, dt time.Time, key string, n, f, e, rows int, download, parse, save time.Duration) {
+	lib.ExecSQLWithErr(
+		con,
+		ctx,
+		"update gha_checkpoints set jsons = "+lib.NValue(1)+", found = "+lib.NValue(2)+", events = "+lib.NValue(3)+
+			", finished = "+lib.NValue(4)+", rows = "+lib.NValue(5)+", download_ms = "+lib.NValue(6)+
+			", parse_ms = "+lib.NValue(7)+", save_ms = "+lib.NValue(8)+
+			" where dt = "+lib.NValue(9)+" and orgs_repos = "+lib.NValue(10),
+		n,
+		f,
+		e,
+		time.Now(),
+		rows,
+		int64(download/time.Millisecond),
+		int64(parse/time.Millisecond),
+		int64(save/time.Millisecond),
+		dt,
+		key,
+	)
+}
+
+const (
+	// Read buffer size for decompressed JSON lines, single events can be bigger (line grows as needed)
+	jsonBufferSize = 1 << 20
+	// Heap usage is sampled every this many JSON lines (reading memory stats stops the world)
+	memSample = 1000
+	// Dry run summary lists at most this many most active repositories
+	dryRunRepos = 100
+)
+
+// ghaHour - single hour of GHA data passed between pipeline stages
+// Hour is parsed once and split into parts, one for every target that needs it (see split)
+// Parts count and save events of their targets separately
+type ghaHour struct {
+	dt   time.Time
+	fn   string
+	data []byte
+	// Targets that need this hour (whole hour) or target of this hour's part
+	targets []*target
+	t       *target
+	// Data is already decompressed JSON lines (BigQuery)
+	plain bool
+	// JSON lines stream, hour is decompressed while it is parsed
+	reader io.ReadCloser
+	// Peak heap usage seen while parsing this hour
+	peak uint64
+	// Dry run: matching events per event type
+	types map[string]int
+	// Rows written (estimated in dry run mode)
+	rows int
+	// Stage timings: hour taken by downloader, downloaded, parsing started and parsed
+	started    time.Time
+	downloaded time.Time
+	parsing    time.Time
+	parsed     time.Time
+	// Matching events not yet saved, hour is finished when all of them are saved
+	pending sync.WaitGroup
+	mtx     sync.Mutex
+	n       int
+	f       int
+	e       int
+	// Matching events skipped because they were already parsed in an adjacent hour
+	d int
+}
+
+// split - returns hour parts for all targets that need the hour
+func (h *ghaHour) split() []*ghaHour {
+	parts := make([]*ghaHour, len(h.targets))
+	for i, t := range h.targets {
+		parts[i] = &ghaHour{dt: h.dt, fn: h.fn, t: t, started: h.started, downloaded: h.downloaded, parsing: h.parsing}
 	}
-	if err != nil {
-		lib.Printf("%v: Cannot unmarshal:\n%s\n%v\n", dt, string(jsonStr), err)
-		fmt.Fprintf(os.Stderr, "%v: Cannot unmarshal:\n%s\n%v\n", dt, string(jsonStr), err)
-		pretty := lib.PrettyPrintJSON(jsonStr)
-		lib.Printf("%v: JSON Unmarshal failed for:\n'%v'\n", dt, string(pretty))
-		fmt.Fprintf(os.Stderr, "%v: JSON Unmarshal failed for:\n'%v'\n", dt, string(pretty))
+	return parts
+}
+
+// prefix - log prefix of hour part, only parts of multi project targets have it
+func (h *ghaHour) prefix() string {
+	if h.t == nil || h.t.name == "" {
+		return ""
 	}
+	return h.t.name + ": "
+}
+
+// target - database events are saved to, with its own filters: orgs/repos, event types, bots and repo groups
+// gha2db has a single target, `gha2db multi` has one for every project (see projectTargets)
+type target struct {
+	name   string
+	ctx    *lib.Ctx
+	con    *sql.DB
+	forg   map[string]struct{}
+	frepo  map[string]struct{}
+	ftype  map[string]struct{}
+	bots   *lib.BotDetector
+	groups *lib.RepoGroups
+	// Checkpoints key, hours finished with it are skipped in resume mode, hours before project's start date are skipped too
+	key      string
+	finished map[int64]bool
+	start    *time.Time
+}
+
+// newTarget - creates target saving events of orgs `forg` and repos `frepo` into `ctx` database
+// Event types are taken from `ctx`, bots and repo groups from `ctx.Project` (if set)
+func newTarget(ctx *lib.Ctx, name string, forg, frepo map[string]struct{}, key string) *target {
+	bots, err := lib.ProjectBotDetector(ctx)
+	lib.FatalOnError(err)
+	groups, err := lib.ProjectRepoGroups(ctx)
 	lib.FatalOnError(err)
-	if ctx.OldFormat {
-		fullName = makeOldRepoName(&hOld.Repository)
+	return &target{
+		name:   name,
+		ctx:    ctx,
+		forg:   forg,
+		frepo:  frepo,
+		ftype:  lib.StringsMapToSet(func(x string) string { return x }, ctx.EventTypes),
+		bots:   bots,
+		groups: groups,
+		key:    key,
+	}
+}
+
+// hit - returns true when target needs events of repository `name`
+func (t *target) hit(name string) bool {
+	return lib.RepoHit(t.ctx.Exact, name, t.forg, t.frepo)
+}
+
+// wants - returns true when target needs events of type `typ`
+func (t *target) wants(typ string) bool {
+	if len(t.ftype) == 0 {
+		return true
+	}
+	_, ok := t.ftype[typ]
+	return ok
+}
+
+// needs - returns true when target still needs hour `dt`
+func (t *target) needs(dt time.Time) bool {
+	return !t.finished[dt.Unix()] && (t.start == nil || !dt.Before(*t.start))
+}
+
+// ghaEvent - single parsed GHA event that matches target of its hour part
+type ghaEvent struct {
+	hour *ghaHour
+	eid  string
+	ev   lib.Event
+	bot  bool
+	// Repo group from project's `repo_groups` rules (nil when no rule matches)
+	group interface{}
+	// Payload keys unknown to lib.Payload (GHA2DB_PAYLOAD_OVERFLOW mode)
+	unknown []string
+}
+
+// decodeJSON - parse signle GHA JSON event, returns one event for every hour part whose target matches it (orgs/repos filter and event types)
+// Events done by bots (as seen by target's bots detector) are flagged, their repos get repo groups from target's rules
+// Returns error for events (matching any target, if it can be checked at all) that cannot be parsed
+// Hours before 2015 use old JSON format, such events are normalized into the new format structures
+func decodeJSON(ctx *lib.Ctx, jsonStr []byte, hour *ghaHour, parts []*ghaHour) ([]*ghaEvent, error) {
+	var (
+		ev       lib.Event
+		err      error
+		fullName string
+		unknown  []string
+	)
+	dt := hour.dt
+	old := lib.IsOldFormat(ctx, dt)
+	// Only event type is decoded first, payloads of events no target wants are never parsed
+	if !wantsAll(parts) {
+		var evType struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(jsonStr, &evType) == nil && !wanted(parts, evType.Type) {
+			return nil, nil
+		}
+	}
+	if old {
+		var evOld lib.EventOld
+		err = json.Unmarshal(jsonStr, &evOld)
+		if err == nil {
+			var normalized *lib.Event
+			normalized, err = lib.NormalizeEventOld(&evOld)
+			if err == nil {
+				ev = *normalized
+			}
+		}
 	} else {
-		fullName = h.Repo.Name
+		err = json.Unmarshal(jsonStr, &ev)
 	}
-	if lib.RepoHit(ctx.Exact, fullName, forg, frepo) {
-		if ctx.OldFormat {
-			eid = fmt.Sprintf("%v", lib.HashStrings([]string{hOld.Type, hOld.Actor, hOld.Repository.Name, lib.ToYMDHMSDate(hOld.CreatedAt)}))
-		} else {
-			eid = h.ID
+	if err != nil {
+		// Malformed events of other repositories are not interesting
+		if len(nameParts(old, jsonStr, parts)) == 0 {
+			return nil, nil
+		}
+		// Only the beginning of the line is logged, malformed lines can be huge
+		excerpt := lib.TruncToBytes(string(jsonStr), 0x400)
+		lib.Printf("%v: Cannot unmarshal:\n%s\n%v\n", dt, excerpt, err)
+		fmt.Fprintf(os.Stderr, "%v: Cannot unmarshal:\n%s\n%v\n", dt, excerpt, err)
+		return nil, err
+	}
+	if old {
+		fullName = makeOldRepoName(&ev.Old.Repository)
+	} else {
+		fullName = ev.Repo.Name
+	}
+	matching := []*ghaHour{}
+	for _, part := range parts {
+		if part.t.wants(ev.Type) && part.t.hit(fullName) {
+			matching = append(matching, part)
+		}
+	}
+	if len(matching) == 0 {
+		return nil, nil
+	}
+	// Payload fields GitHub added after lib.Payload was defined are kept as they are
+	if ctx.PayloadOverflow && !old {
+		unknown, ev.Overflow, err = lib.PayloadOverflow(jsonStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ctx.JSONOut {
+		// We want to Unmarshal/Marshall ALL JSON data, regardless of what is defined in lib.Event
+		pretty := lib.PrettyPrintJSON(jsonStr)
+		ofn := fmt.Sprintf("jsons/%v_%v.json", dt.Unix(), ev.ID)
+		lib.FatalOnError(ioutil.WriteFile(ofn, pretty, 0644))
+	}
+	events := []*ghaEvent{}
+	var raw []byte
+	for i, part := range matching {
+		t := part.t
+		e := &ghaEvent{hour: part, eid: ev.ID, ev: ev, unknown: unknown}
+		// Org IDs of old events are looked up in target's database, so every target needs own org
+		if i > 0 && ev.Org != nil {
+			org := *ev.Org
+			e.ev.Org = &org
+		}
+		e.bot = t.bots.IsBot(ev.Actor.Login)
+		e.group = t.groups.GroupOrNil(ev.Repo.Name)
+		// Full event JSON is only kept for events within target's retention window
+		if t.ctx.RawJSON && (t.ctx.RawJSONDays == 0 || ev.CreatedAt.After(rawJSONSince(t.ctx))) {
+			if raw == nil {
+				raw, err = lib.JSONB(jsonStr)
+				if err != nil {
+					return nil, err
+				}
+			}
+			e.ev.Raw = raw
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// wantsAll - returns true when any of hour parts' targets wants all event types
+func wantsAll(parts []*ghaHour) bool {
+	for _, part := range parts {
+		if len(part.t.ftype) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// wanted - returns true when any of hour parts' targets wants events of type `typ`
+func wanted(parts []*ghaHour, typ string) bool {
+	for _, part := range parts {
+		if part.t.wants(typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// nameParts - returns hour parts whose targets can need malformed event (all of them when its repository name cannot be decoded)
+func nameParts(old bool, jsonStr []byte, parts []*ghaHour) []*ghaHour {
+	name, ok := repoName(old, jsonStr)
+	if !ok {
+		return parts
+	}
+	hit := []*ghaHour{}
+	for _, part := range parts {
+		if part.t.hit(name) {
+			hit = append(hit, part)
+		}
+	}
+	return hit
+}
+
+// repoName - decodes only repository name from event JSON (in old pre 2015 or new format)
+func repoName(old bool, jsonStr []byte) (string, bool) {
+	var names struct {
+		Repo struct {
+			Name string `json:"name"`
+		} `json:"repo"`
+		Repository struct {
+			Name         string  `json:"name"`
+			Organization *string `json:"organization"`
+		} `json:"repository"`
+	}
+	if json.Unmarshal(jsonStr, &names) != nil {
+		return "", false
+	}
+	if old {
+		return makeOldRepoName(&lib.ForkeeOld{Name: names.Repository.Name, Organization: names.Repository.Organization}), true
+	}
+	return names.Repo.Name, true
+}
+
+// saveParseError - saves event that cannot be parsed to `gha_parse_errors` (dead letters)
+// Such events can be processed again (after fixing the parser) using `gha2db reprocess`
+func saveParseError(con *sql.DB, ctx *lib.Ctx, dt time.Time, line int, jsonStr []byte, perr error) {
+	// Postgres text cannot contain NUL bytes and invalid UTF-8 sequences
+	text := strings.Replace(string([]rune(string(jsonStr))), "\x00", "", -1)
+	lib.ExecSQLWithErr(
+		con,
+		ctx,
+		"insert into gha_parse_errors(dt, line, json, error, created_at) "+lib.NValues(5)+
+			" on conflict (dt, line) do update set json = excluded.json, error = excluded.error, created_at = excluded.created_at",
+		dt,
+		line,
+		text,
+		perr.Error(),
+		time.Now(),
+	)
+}
+
+// writeEvent - queues single parsed GHA event in `bw`, returns 1 if event was added, 0 if it already existed
+func writeEvent(con *sql.DB, ctx *lib.Ctx, bw *lib.BulkWriter, ev *ghaEvent) (e int) {
+	if ctx.DBOut {
+		e = writeToDB(con, ctx, bw, &ev.ev, ev.bot, ev.group)
+	}
+	if ctx.Debug >= 1 {
+		lib.Printf("Processed: '%v' event: %v\n", ev.hour.dt, ev.eid)
+	}
+	return
+}
+
+// sourceDay - single day of events read from a day source: hour -> JSON lines
+type sourceDay struct {
+	once  sync.Once
+	hours map[int][]byte
+	err   error
+}
+
+// daySource - source returning whole days of events as GHA JSON lines (BigQuery, GitLab API, Gitea/Forgejo API, Gerrit API)
+// Each day is read once and its hours are removed when taken
+// Hours are processed in parallel, so first hour of a day that is requested reads the day and others wait for it
+type daySource struct {
+	mtx  sync.Mutex
+	days map[string]*sourceDay
+	// Prefix of hour names used in logs and checkpoints, like "githubarchive.day."
+	prefix string
+	read   func(ctx *lib.Ctx, day time.Time) (map[int][]byte, error)
+}
+
+// newDaySource - creates day source using `read` function
+func newDaySource(prefix string, read func(ctx *lib.Ctx, day time.Time) (map[int][]byte, error)) *daySource {
+	return &daySource{days: make(map[string]*sourceDay), prefix: prefix, read: read}
+}
+
+// name - returns name of a given hour, like "githubarchive.day.20170801:10"
+func (s *daySource) name(dt time.Time) string {
+	return fmt.Sprintf("%s%s:%d", s.prefix, strings.Replace(lib.ToYMDDate(dt), "-", "", -1), dt.Hour())
+}
+
+// hour - returns JSON lines of a given hour, reading its day when it is not read yet
+func (s *daySource) hour(ctx *lib.Ctx, dt time.Time) ([]byte, error) {
+	key := lib.ToYMDDate(dt)
+	s.mtx.Lock()
+	day, ok := s.days[key]
+	if !ok {
+		day = &sourceDay{}
+		s.days[key] = day
+	}
+	s.mtx.Unlock()
+	day.once.Do(func() {
+		dtStart := time.Now()
+		day.hours, day.err = s.read(ctx, dt)
+		if day.err == nil {
+			lib.Printf("Read %s%s: took %v\n", s.prefix, key, time.Now().Sub(dtStart))
+		}
+	})
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if day.err != nil {
+		return nil, day.err
+	}
+	data := day.hours[dt.Hour()]
+	delete(day.hours, dt.Hour())
+	if len(day.hours) == 0 {
+		delete(s.days, key)
+	}
+	return data, nil
+}
+
+// bigQuerySource - reads days from public githubarchive BigQuery dataset using `bq` tool
+func bigQuerySource(forg, frepo map[string]struct{}) *daySource {
+	return newDaySource(
+		"githubarchive.day.",
+		func(ctx *lib.Ctx, day time.Time) (map[int][]byte, error) {
+			query, err := lib.BigQueryDayQuery(day, forg, frepo)
+			if err != nil {
+				return nil, err
+			}
+			// Output is needed and single failed query should not stop the whole import
+			bqCtx := *ctx
+			bqCtx.ExecOutput = true
+			bqCtx.ExecFatal = false
+			out, err := lib.ExecCommand(
+				&bqCtx,
+				[]string{"bq", "query", "--quiet", "--format=json", "--use_legacy_sql=false", "--max_rows=1000000000", query},
+				nil,
+			)
+			if err != nil {
+				return nil, err
+			}
+			return lib.BigQueryRowsToJSONs([]byte(out))
+		},
+	)
+}
+
+// gitLabSource - reads days of GitLab projects events using GitLab REST API
+// Projects matching orgs/repos filter are listed once, when the first day is read
+func gitLabSource(ctx *lib.Ctx, forg, frepo map[string]struct{}) *daySource {
+	client := lib.NewGitLabClient(ctx)
+	var (
+		once     sync.Once
+		projects []lib.GitLabProject
+		err      error
+	)
+	return newDaySource(
+		"gitlab.",
+		func(ctx *lib.Ctx, day time.Time) (map[int][]byte, error) {
+			once.Do(func() {
+				projects, err = client.Projects(ctx.Exact, forg, frepo)
+				if err == nil {
+					lib.Printf("GitLab projects: %d\n", len(projects))
+				}
+			})
+			if err != nil {
+				return nil, err
+			}
+			return client.DayJSONs(day, projects)
+		},
+	)
+}
+
+// giteaSource - reads days of Gitea/Forgejo repositories activities using instance's REST API
+// Repositories matching orgs/repos filter are listed once, when the first day is read
+func giteaSource(ctx *lib.Ctx, forg, frepo map[string]struct{}) *daySource {
+	client := lib.NewGiteaClient(ctx)
+	var (
+		once  sync.Once
+		repos []lib.GiteaRepository
+		err   error
+	)
+	return newDaySource(
+		"gitea.",
+		func(ctx *lib.Ctx, day time.Time) (map[int][]byte, error) {
+			once.Do(func() {
+				repos, err = client.Repos(ctx.Exact, forg, frepo)
+				if err == nil {
+					lib.Printf("Gitea repositories: %d\n", len(repos))
+				}
+			})
+			if err != nil {
+				return nil, err
+			}
+			return client.DayJSONs(day, repos)
+		},
+	)
+}
+
+// gerritSource - maps Gerrit changes onto GHA events of the days they happened
+// Changes matching orgs/repos filter updated since `from` are requested once, when the first day is read
+// Gerrit can only query changes by their last update, so each day would need all changes updated since then
+func gerritSource(ctx *lib.Ctx, forg, frepo map[string]struct{}, from time.Time) *daySource {
+	client := lib.NewGerritClient(ctx)
+	var (
+		once    sync.Once
+		changes []lib.GerritChange
+		err     error
+	)
+	return newDaySource(
+		"gerrit.",
+		func(ctx *lib.Ctx, day time.Time) (map[int][]byte, error) {
+			once.Do(func() {
+				changes, err = client.Changes(ctx.Exact, forg, frepo, lib.DayStart(from))
+				if err == nil {
+					lib.Printf("Gerrit changes: %d\n", len(changes))
+				}
+			})
+			if err != nil {
+				return nil, err
+			}
+			return lib.GerritDayJSONs(changes, day)
+		},
+	)
+}
+
+// localHour - reads GHA hour from GHA2DB_ARCHIVE_DIR, returns false if there is no such file
+func localHour(ctx *lib.Ctx, hour *ghaHour) bool {
+	if ctx.ArchiveDir == "" {
+		return false
+	}
+	for _, ext := range lib.ArchiveExtensions {
+		fn := ctx.ArchiveDir + lib.ToGHADate(hour.dt) + ext
+		data, err := ioutil.ReadFile(fn)
+		if os.IsNotExist(err) {
+			continue
 		}
-		if ctx.JSONOut {
-			// We want to Unmarshal/Marshall ALL JSON data, regardless of what is defined in lib.Event
-			pretty := lib.PrettyPrintJSON(jsonStr)
-			ofn := fmt.Sprintf("jsons/%v_%v.json", dt.Unix(), eid)
-			lib.FatalOnError(ioutil.WriteFile(ofn, pretty, 0644))
+		lib.FatalOnError(err)
+		hour.fn = fn
+		hour.data = data
+		return true
+	}
+	return false
+}
+
+// downloadHours - pipeline stage: downloads compressed GHA hours (or reads local or cached ones), network bound
+// GHA files are looked up in local directory, cache and bucket first, files downloaded from GHA2DB_ARCHIVE_URL (or its mirrors) can be mirrored into the bucket
+// downloadHour - downloads GHA file of hour `dt`, file that is broken (or doesn't match checksum published at GHA2DB_ARCHIVE_CHECKSUMS)
+// is downloaded again, up to GHA2DB_ARCHIVE_RETRIES times
+// Responses other than HTTP 200 (like 404 of the hour that is not published yet) are returned as they are
+// Returns validation error when file is still broken after all retries
+func downloadHour(ctx *lib.Ctx, mirrors *lib.ArchiveMirrors, dt time.Time) ([]byte, string, int, error) {
+	checksum := ""
+	if ctx.ArchiveChecksums != "" {
+		sumURL := strings.Replace(ctx.ArchiveChecksums, "{{date}}", lib.ToGHADate(dt), -1)
+		var err error
+		checksum, err = lib.ArchiveChecksum(sumURL)
+		if err != nil {
+			lib.Printf("%v: Cannot read checksum %s, only checking file integrity:\n%v\n", dt, sumURL, err)
 		}
+	}
+	var (
+		data   []byte
+		url    string
+		status int
+		err    error
+	)
+	for try := 1; try <= ctx.ArchiveRetries+1; try++ {
+		data, url, status, err = mirrors.Get(dt)
+		if err != nil {
+			lib.Printf("%v: Error downloading:\n%v\n", dt, err)
+			fmt.Fprintf(os.Stderr, "%v: Error downloading:\n%v\n", dt, err)
+		}
+		lib.FatalOnError(err)
+		if status != http.StatusOK {
+			return data, url, status, nil
+		}
+		err = lib.ValidateArchive(data, checksum)
+		if err == nil {
+			return data, url, status, nil
+		}
+		lib.Printf("%v: try %d/%d: %v\n", dt, try, ctx.ArchiveRetries+1, err)
+		fmt.Fprintf(os.Stderr, "%v: try %d/%d: %v\n", dt, try, ctx.ArchiveRetries+1, err)
+	}
+	return data, url, status, err
+}
+
+func downloadHours(ctx *lib.Ctx, cache *lib.ArchiveCache, bucket *lib.ArchiveBucket, mirrors *lib.ArchiveMirrors, days *daySource, in <-chan *ghaHour, out chan<- *ghaHour, broken *quarantine, prog *progress) {
+	for hour := range in {
+		lib.Printf("Working on %v\n", hour.dt)
+		hour.started = time.Now()
+
+		// Hour stays unfinished until all its events are saved
 		if ctx.DBOut {
-			if ctx.OldFormat {
-				e = writeToDBOldFmt(con, ctx, eid, &hOld)
-			} else {
-				e = writeToDB(con, ctx, &h)
+			for _, t := range hour.targets {
+				startCheckpoint(t.con, t.ctx, hour.dt, t.key)
+			}
+		}
+
+		// Day sources return JSON lines, hour without events is sent too, so it is finished
+		if days != nil {
+			var err error
+			hour.fn = days.name(hour.dt)
+			hour.data, err = days.hour(ctx, hour.dt)
+			hour.plain = true
+			if err != nil {
+				lib.Printf("%v: Error reading %s:\n%v\n", hour.dt, hour.fn, err)
+				fmt.Fprintf(os.Stderr, "%v: Error reading %s:\n%v\n", hour.dt, hour.fn, err)
+				prog.hourDone()
+				continue
+			}
+			lib.Printf("Opened %s\n", hour.fn)
+			hour.downloaded = time.Now()
+			out <- hour
+			continue
+		}
+
+		// Local files are used as they are, unless they are broken
+		name := hour.fn
+		if localHour(ctx, hour) {
+			err := lib.ValidateArchive(hour.data, "")
+			if err == nil {
+				lib.Printf("Opened %s\n", hour.fn)
+				hour.downloaded = time.Now()
+				out <- hour
+				continue
+			}
+			lib.Printf("%v: %s: %v, downloading it\n", hour.dt, hour.fn, err)
+			fmt.Fprintf(os.Stderr, "%v: %s: %v, downloading it\n", hour.dt, hour.fn, err)
+			hour.fn = name
+			hour.data = nil
+		}
+
+		// Cache is shared by all projects, they ingest the same hours
+		// Broken cached file is downloaded again (and replaced)
+		var ok bool
+		if hour.data, ok = cache.Get(hour.fn); ok {
+			err := lib.ValidateArchive(hour.data, "")
+			if err == nil {
+				lib.Printf("Opened %s from cache\n", hour.fn)
+				hour.downloaded = time.Now()
+				out <- hour
+				continue
 			}
+			lib.Printf("%v: cached %s: %v, downloading it\n", hour.dt, hour.fn, err)
+			fmt.Fprintf(os.Stderr, "%v: cached %s: %v, downloading it\n", hour.dt, hour.fn, err)
+			hour.data = nil
 		}
-		if ctx.Debug >= 1 {
-			lib.Printf("Processed: '%v' event: %v\n", dt, eid)
+
+		// Bucket can be shared by many clusters, files read from it are cached locally too
+		data, err := bucket.Get(hour.fn)
+		if err == nil {
+			err = lib.ValidateArchive(data, "")
+		}
+		if err == nil {
+			hour.data = data
+			err = cache.Put(hour.fn, hour.data)
+			if err != nil {
+				lib.Printf("%v: Cannot cache %s: %v\n", hour.dt, hour.fn, err)
+				fmt.Fprintf(os.Stderr, "%v: Cannot cache %s: %v\n", hour.dt, hour.fn, err)
+			}
+			lib.Printf("Opened %s from %s\n", hour.fn, bucket)
+			hour.downloaded = time.Now()
+			out <- hour
+			continue
 		}
-		f = 1
+		if err != lib.ErrNotFound {
+			lib.Printf("%v: Error reading %s from %s, downloading it:\n%v\n", hour.dt, hour.fn, bucket, err)
+			fmt.Fprintf(os.Stderr, "%v: Error reading %s from %s, downloading it:\n%v\n", hour.dt, hour.fn, bucket, err)
+		}
+
+		// Get compressed JSON array via HTTP, from GHA2DB_ARCHIVE_URL or its first working mirror
+		data, url, status, err := downloadHour(ctx, mirrors, hour.dt)
+		if err != nil {
+			// Hour is not parsed and stays unfinished, so the next run downloads it again
+			broken.add(ctx, hour.dt, url, data, err)
+			prog.hourDone()
+			continue
+		}
+		hour.data = data
+		// Only validated GHA files are cached (and mirrored), not error pages
+		if status == http.StatusOK {
+			err = cache.Put(hour.fn, hour.data)
+			if err != nil {
+				lib.Printf("%v: Cannot cache %s: %v\n", hour.dt, hour.fn, err)
+				fmt.Fprintf(os.Stderr, "%v: Cannot cache %s: %v\n", hour.dt, hour.fn, err)
+			}
+			if ctx.BucketMirror {
+				err = bucket.Put(hour.fn, hour.data)
+				if err != nil {
+					lib.Printf("%v: Cannot mirror %s into %s: %v\n", hour.dt, hour.fn, bucket, err)
+					fmt.Fprintf(os.Stderr, "%v: Cannot mirror %s into %s: %v\n", hour.dt, hour.fn, bucket, err)
+				}
+			}
+		}
+		lib.Printf("Opened %s\n", url)
+		hour.downloaded = time.Now()
+		out <- hour
+	}
+}
+
+// decompressHours - pipeline stage: opens decompressing streams for GHA hours
+// Format (gzip, zstd or plain JSON) is detected by content, not by file name
+// Data is decompressed later, while parser reads it, so decompressed hour is never kept in memory
+func decompressHours(in <-chan *ghaHour, out chan<- *ghaHour) {
+	for hour := range in {
+		if hour.plain {
+			hour.reader = ioutil.NopCloser(bytes.NewReader(hour.data))
+			hour.data = nil
+			out <- hour
+			continue
+		}
+		var err error
+		hour.reader, err = lib.ArchiveReader(hour.data)
+		hour.data = nil
+		if err != nil {
+			lib.Printf("%v: No data yet, decompress:\n%v\n", hour.dt, err)
+			fmt.Fprintf(os.Stderr, "%v: No data yet, decompress:\n%v\n", hour.dt, err)
+			continue
+		}
+		lib.Printf("Decompressing %s\n", hour.fn)
+		out <- hour
+	}
+}
+
+// heapInUse - current heap allocation in bytes
+func heapInUse() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// readJSONs - calls `f` for every JSON line read from `reader`, only one line is kept in memory
+// Heap usage is sampled every `memSample` lines, max value is returned as `peak`
+func readJSONs(reader io.Reader, f func([]byte)) (peak uint64, err error) {
+	buf := bufio.NewReaderSize(reader, jsonBufferSize)
+	for i := 1; ; i++ {
+		line, rerr := buf.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			f(line)
+		}
+		if i%memSample == 0 {
+			if heap := heapInUse(); heap > peak {
+				peak = heap
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			err = rerr
+			break
+		}
+	}
+	if heap := heapInUse(); heap > peak {
+		peak = heap
 	}
 	return
 }
 
-// getGHAJSON - This is a work for single go routine - 1 hour of GHA data
-// Usually such JSON conatin about 15000 - 60000 singe GHA events
-// Boolean channel `ch` is used to synchronize go routines
-func getGHAJSON(ch chan bool, ctx *lib.Ctx, dt time.Time, forg map[string]struct{}, frepo map[string]struct{}) {
-	lib.Printf("Working on %v\n", dt)
+// parseHours - pipeline stage: splits GHA hours into JSONs and parses them, CPU bound
+// Every hour is parsed once and matching events are sent to `out` for every target that needs them
+// Hour part is finished when all its events are saved
+// Events already parsed in another hour (remembered in `recent`) are skipped
+func parseHours(ctx *lib.Ctx, in <-chan *ghaHour, out chan<- *ghaEvent, finished *sync.WaitGroup, parseErrors *int64, recent *lib.RecentIDs, unknown *unknownFields, prog *progress, report *dryRunReport) {
+	for hour := range in {
+		// Process JSONs one by one, as they are decompressed
+		hour.parsing = time.Now()
+		parts := hour.split()
+		old := lib.IsOldFormat(ctx, hour.dt)
+		n, d, pe := 0, 0, 0
+		peak, err := readJSONs(hour.reader, func(json []byte) {
+			n++
+			events, perr := decodeJSON(ctx, json, hour, parts)
+			if perr != nil {
+				// Malformed event is saved as a dead letter, the rest of the hour is processed
+				pe++
+				if ctx.DBOut {
+					for _, part := range nameParts(old, json, parts) {
+						saveParseError(part.t.con, part.t.ctx, hour.dt, n, json, perr)
+					}
+				}
+				return
+			}
+			if len(events) == 0 {
+				return
+			}
+			// GHA files sometimes repeat events of the previous hour, only the first copy is saved
+			// Targets that skipped the previous hour have already finished it (or it is before their start date)
+			if recent.Seen(events[0].eid) {
+				d++
+				return
+			}
+			unknown.add(events[0])
+			for _, ev := range events {
+				ev.hour.mtx.Lock()
+				ev.hour.f++
+				ev.hour.mtx.Unlock()
+				ev.hour.pending.Add(1)
+				out <- ev
+			}
+		})
+		if cerr := hour.reader.Close(); err == nil {
+			err = cerr
+		}
+		hour.reader = nil
+		parsed := time.Now()
+		for _, part := range parts {
+			part.mtx.Lock()
+			part.n, part.d, part.peak, part.parsed = n, d, peak, parsed
+			part.mtx.Unlock()
+		}
+		atomic.AddInt64(parseErrors, int64(pe))
+		// Truncated hour is not finished, so it will be processed again
+		if err != nil {
+			lib.Printf("%v: No data yet, decompress:\n%v\n", hour.dt, err)
+			fmt.Fprintf(os.Stderr, "%v: No data yet, decompress:\n%v\n", hour.dt, err)
+		}
+		finished.Add(1)
+		go func(parts []*ghaHour, pe int, broken bool) {
+			defer finished.Done()
+			for _, part := range parts {
+				part.pending.Wait()
+				saved := time.Now()
+				part.mtx.Lock()
+				n, f, d, e, rows, peak := part.n, part.f, part.d, part.e, part.rows, part.peak
+				download, parse, save := part.downloaded.Sub(part.started), part.parsed.Sub(part.parsing), saved.Sub(part.parsed)
+				part.mtx.Unlock()
+				lib.Printf(
+					"Parsed: %s%s: %d JSONs, found %d matching, events %d, duplicates %d, parse errors %d, peak heap %d MB\n",
+					part.prefix(), part.fn, n, f, e, d, pe, peak>>20,
+				)
+				// Parsing includes decompression, saving is waiting for the last event of the hour to be saved
+				lib.Printf("Timing: %s%s: download %v, parse %v, save %v, rows %d\n", part.prefix(), part.fn, download, parse, save, rows)
+				if ctx.DBOut && !broken {
+					finishCheckpoint(part.t.con, part.t.ctx, part.dt, part.t.key, n, f, e, rows, download, parse, save)
+				}
+			}
+			prog.hourDone()
+			if report != nil {
+				report.addHour(parts, broken)
+			}
+		}(parts, pe, err != nil)
+	}
+}
+
+// insertEvents - pipeline stage: saves parsed events into their targets, database bound
+// Every worker batches rows of many events and saves them at once (see lib.BulkWriter), each target has own batch
+// Events are only marked as saved after their batch is flushed, batches are also flushed when there are no more events queued
+func insertEvents(ctx *lib.Ctx, in <-chan *ghaEvent, prog *progress) {
+	bws := make(map[*target]*lib.BulkWriter)
+	queued := []*ghaEvent{}
+	events, rows := 0, 0
+	flush := func() {
+		for _, bw := range bws {
+			bw.Flush()
+		}
+		for _, ev := range queued {
+			ev.hour.pending.Done()
+		}
+		queued = queued[:0]
+		prog.add(events, rows)
+		events, rows = 0, 0
+	}
+	for {
+		var (
+			ev *ghaEvent
+			ok bool
+		)
+		select {
+		case ev, ok = <-in:
+		default:
+			flush()
+			ev, ok = <-in
+		}
+		if !ok {
+			break
+		}
+		t := ev.hour.t
+		bw, ok := bws[t]
+		if !ok {
+			bw = lib.NewBulkWriter(t.con, t.ctx, ctx.BulkSize)
+			bws[t] = bw
+		}
+		queuedRows := bw.Rows()
+		e := writeEvent(t.con, t.ctx, bw, ev)
+		r := bw.Rows() - queuedRows
+		ev.hour.mtx.Lock()
+		ev.hour.e += e
+		ev.hour.rows += r
+		ev.hour.mtx.Unlock()
+		events += e
+		rows += r
+		queued = append(queued, ev)
+		if bw.Full() || len(queued) >= ctx.BulkSize {
+			flush()
+		}
+	}
+	flush()
+}
 
-	// Connect to Postgres DB
-	con := lib.PgConn(ctx)
-	defer func() { lib.FatalOnError(con.Close()) }()
+// estimateRows - returns approximate number of rows saving event would add
+// Rows of data shared between events (actors, repos, orgs, labels, milestones, branches) are not counted
+func estimateRows(ev *lib.Event) int {
+	// gha_events
+	rows := 1
+	if ev.Old != nil && !ev.Old.HasPayload {
+		return rows
+	}
+	// gha_payloads
+	rows++
+	pl := &ev.Payload
+	if pl.Commits != nil {
+		rows += len(*pl.Commits)
+	}
+	if pl.Pages != nil {
+		rows += len(*pl.Pages)
+	}
+	if pl.Comment != nil {
+		rows++
+	}
+	if pl.Issue != nil {
+		rows += 1 + len(pl.Issue.Labels) + len(pl.Issue.Assignees)
+	}
+	if pl.Forkee != nil {
+		rows++
+	}
+	if ev.Old != nil && (pl.Forkee == nil || pl.Forkee.ID != ev.Old.Repository.ID) {
+		rows++
+	}
+	if pl.Release != nil {
+		// Release, its assets and release-asset connections
+		rows += 1 + 2*len(pl.Release.Assets)
+	}
+	if pl.Team != nil {
+		rows++
+		if pl.Forkee != nil {
+			rows++
+		}
+	}
+	if pr := pl.PullRequest; pr != nil {
+		rows++
+		if pr.Assignees != nil {
+			rows += len(*pr.Assignees)
+		}
+		if pr.RequestedReviewers != nil {
+			rows += len(*pr.RequestedReviewers)
+		}
+		// Artificial issue for pre 2015 pull request
+		if ev.Old != nil {
+			rows++
+		}
+	}
+	return rows
+}
 
-	fn := fmt.Sprintf("http://data.githubarchive.org/%s.json.gz", lib.ToGHADate(dt))
+// dryRunReport - collects matching events statistics in dry run mode
+type dryRunReport struct {
+	mtx    sync.Mutex
+	hours  int
+	broken int
+	jsons  int
+	found  int
+	dups   int
+	rows   int
+	repos  map[string]int
+}
 
-	// Get gzipped JSON array via HTTP
-	response, err := http.Get(fn)
-	if err != nil {
-		lib.Printf("%v: Error http.Get:\n%v\n", dt, err)
-		fmt.Fprintf(os.Stderr, "%v: Error http.Get:\n%v\n", dt, err)
+// addEvent - counts single matching event (in its hour and in the whole report), returns estimated number of its rows
+func (r *dryRunReport) addEvent(ev *ghaEvent) int {
+	rows := estimateRows(&ev.ev)
+	hour := ev.hour
+	hour.mtx.Lock()
+	if hour.types == nil {
+		hour.types = make(map[string]int)
+	}
+	hour.types[ev.ev.Type]++
+	hour.rows += rows
+	hour.mtx.Unlock()
+	r.mtx.Lock()
+	r.repos[ev.ev.Repo.Name]++
+	r.mtx.Unlock()
+	return rows
+}
+
+// addHour - outputs statistics of finished hour's parts and adds them to the report
+// JSONs and duplicates are counted once per hour, matching events and rows of all parts are summed
+func (r *dryRunReport) addHour(parts []*ghaHour, broken bool) {
+	for i, part := range parts {
+		part.mtx.Lock()
+		n, f, d, rows := part.n, part.f, part.d, part.rows
+		types := []string{}
+		for typ, count := range part.types {
+			types = append(types, fmt.Sprintf("%s: %d", typ, count))
+		}
+		part.mtx.Unlock()
+		sort.Strings(types)
+		lib.Printf("Dry run: %s%v: %d JSONs, %d matching events (%s), %d duplicates, ~%d rows\n", part.prefix(), part.dt, n, f, strings.Join(types, ", "), d, rows)
+		r.mtx.Lock()
+		if i == 0 {
+			r.hours++
+			if broken {
+				r.broken++
+			}
+			r.jsons += n
+			r.dups += d
+		}
+		r.found += f
+		r.rows += rows
+		r.mtx.Unlock()
 	}
-	lib.FatalOnError(err)
-	defer func() { _ = response.Body.Close() }()
+}
 
-	// Decompress Gzipped response
-	reader, err := gzip.NewReader(response.Body)
-	//lib.FatalOnError(err)
-	if err != nil {
-		lib.Printf("%v: No data yet, gzip reader:\n%v\n", dt, err)
-		fmt.Fprintf(os.Stderr, "%v: No data yet, gzip reader:\n%v\n", dt, err)
-		if ch != nil {
-			ch <- true
+// summary - outputs totals and number of matching events per repository (most active first)
+func (r *dryRunReport) summary() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	lib.Printf(
+		"Dry run: %d hours parsed (%d incomplete), %d JSONs, %d matching events in %d repositories, %d duplicates skipped, ~%d rows would be written\n",
+		r.hours, r.broken, r.jsons, r.found, len(r.repos), r.dups, r.rows,
+	)
+	repos := []string{}
+	for repo := range r.repos {
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		if r.repos[repos[i]] == r.repos[repos[j]] {
+			return repos[i] < repos[j]
 		}
-		return
+		return r.repos[repos[i]] > r.repos[repos[j]]
+	})
+	for i, repo := range repos {
+		if i >= dryRunRepos {
+			lib.Printf("Dry run: ... and %d more repositories\n", len(repos)-dryRunRepos)
+			break
+		}
+		lib.Printf("Dry run: %s: %d events\n", repo, r.repos[repo])
 	}
-	lib.Printf("Opened %s\n", fn)
-	defer func() { _ = reader.Close() }()
+}
 
-	jsonsBytes, err := ioutil.ReadAll(reader)
-	//lib.FatalOnError(err)
-	if err != nil {
-		lib.Printf("%v: Error (no data yet, ioutil readall):\n%v\n", dt, err)
-		fmt.Fprintf(os.Stderr, "%v: Error (no data yet, ioutil readall):\n%v\n", dt, err)
-		if ch != nil {
-			ch <- true
+// countEvents - dry run pipeline stage used instead of insertEvents, events are counted and never saved
+func countEvents(in <-chan *ghaEvent, report *dryRunReport, prog *progress) {
+	for ev := range in {
+		prog.add(1, report.addEvent(ev))
+		ev.hour.pending.Done()
+	}
+}
+
+// progress - ingestion progress of all pipeline stages, reported periodically
+type progress struct {
+	hours   int64
+	events  int64
+	rows    int64
+	total   int
+	started time.Time
+}
+
+// hourDone - counts processed hour (finished or not)
+func (p *progress) hourDone() {
+	atomic.AddInt64(&p.hours, 1)
+}
+
+// add - counts saved events and rows (counted in dry run mode)
+func (p *progress) add(events, rows int) {
+	atomic.AddInt64(&p.events, int64(events))
+	atomic.AddInt64(&p.rows, int64(rows))
+}
+
+// report - outputs single progress line: hours done, events and rows (total and per second) and estimated time left
+func (p *progress) report() {
+	hours, events, rows := atomic.LoadInt64(&p.hours), atomic.LoadInt64(&p.events), atomic.LoadInt64(&p.rows)
+	elapsed := time.Now().Sub(p.started)
+	seconds := elapsed.Seconds()
+	if seconds <= 0 {
+		seconds = 1
+	}
+	pct := 100.0
+	if p.total > 0 {
+		pct = float64(hours) * 100.0 / float64(p.total)
+	}
+	eta := "unknown"
+	if hours > 0 {
+		eta = (time.Duration(int64(elapsed)/hours) * time.Duration(int64(p.total)-hours)).Round(time.Second).String()
+	}
+	lib.Printf(
+		"Progress: hours=%d/%d (%.1f%%) events=%d events/s=%.1f rows=%d rows/s=%.1f elapsed=%v eta=%s\n",
+		hours, p.total, pct, events, float64(events)/seconds, rows, float64(rows)/seconds, elapsed.Round(time.Second), eta,
+	)
+}
+
+// quarantine - GHA hours still broken after all download retries, they are not parsed and stay unfinished
+type quarantine struct {
+	mtx   sync.Mutex
+	hours []string
+}
+
+// add - records broken hour, its file is saved into GHA2DB_QUARANTINE_DIR (if set) for inspection
+func (q *quarantine) add(ctx *lib.Ctx, dt time.Time, url string, data []byte, err error) {
+	line := fmt.Sprintf("%s: %v", lib.ToGHADate(dt), err)
+	if ctx.QuarantineDir != "" && len(data) > 0 {
+		fn := ctx.QuarantineDir + path.Base(url)
+		werr := os.MkdirAll(ctx.QuarantineDir, 0755)
+		if werr == nil {
+			werr = ioutil.WriteFile(fn, data, 0644)
+		}
+		if werr != nil {
+			line += fmt.Sprintf(" (cannot save %s: %v)", fn, werr)
+		} else {
+			line += " (saved as " + fn + ")"
 		}
+	}
+	lib.Printf("%v: Quarantined %s\n", dt, line)
+	fmt.Fprintf(os.Stderr, "%v: Quarantined %s\n", dt, line)
+	q.mtx.Lock()
+	q.hours = append(q.hours, line)
+	q.mtx.Unlock()
+}
+
+// summary - outputs all quarantined hours, they are retried by the next run
+func (q *quarantine) summary() {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if len(q.hours) == 0 {
 		return
 	}
-	lib.Printf("Decompressed %s\n", fn)
+	sort.Strings(q.hours)
+	lib.Printf("Quarantined %d broken GHA hours (not parsed, the next run retries them):\n", len(q.hours))
+	fmt.Fprintf(os.Stderr, "Quarantined %d broken GHA hours (not parsed, the next run retries them):\n", len(q.hours))
+	for _, line := range q.hours {
+		lib.Printf("%s\n", line)
+		fmt.Fprintf(os.Stderr, "%s\n", line)
+	}
+}
 
-	// Split JSON array into separate JSONs
-	jsonsArray := bytes.Split(jsonsBytes, []byte("\n"))
-	lib.Printf("Splitted %s, %d JSONs\n", fn, len(jsonsArray))
+// unknownFields - payload keys unknown to lib.Payload seen in GHA2DB_PAYLOAD_OVERFLOW mode: "EventType.key" -> number of events
+// nil *unknownFields means unknown fields are not counted
+type unknownFields struct {
+	mtx    sync.Mutex
+	counts map[string]int
+}
 
-	// Process JSONs one by one
-	n, f, e := 0, 0, 0
-	for _, json := range jsonsArray {
-		if len(json) < 1 {
-			continue
+// add - counts unknown payload keys of a single event
+func (u *unknownFields) add(ev *ghaEvent) {
+	if u == nil || len(ev.unknown) == 0 {
+		return
+	}
+	u.mtx.Lock()
+	for _, key := range ev.unknown {
+		u.counts[ev.ev.Type+"."+key]++
+	}
+	u.mtx.Unlock()
+}
+
+// summary - outputs unknown payload fields seen, most frequent first
+func (u *unknownFields) summary() {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	if len(u.counts) == 0 {
+		lib.Printf("No unknown payload fields found\n")
+		return
+	}
+	fields := []string{}
+	for field := range u.counts {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if u.counts[fields[i]] == u.counts[fields[j]] {
+			return fields[i] < fields[j]
 		}
-		fi, ei := parseJSON(con, ctx, json, dt, forg, frepo)
-		n++
-		f += fi
-		e += ei
+		return u.counts[fields[i]] > u.counts[fields[j]]
+	})
+	lib.Printf("Unknown payload fields:\n")
+	for _, field := range fields {
+		lib.Printf("%s: %d events\n", field, u.counts[field])
 	}
-	lib.Printf(
-		"Parsed: %s: %d JSONs, found %d matching, events %d\n",
-		fn, n, f, e,
+}
+
+// runPipeline - processes hours from `hours` channel using separate download, decompress, parse and insert stages
+// Each stage has its own workers and stages are connected by bounded queues
+// So downloads, JSON parsing and Postgres writes overlap, while only few hours are kept in memory
+// In dry run mode (`report` is set) events are only counted instead of being saved and database is not used at all
+// Hours start at `from` (Gerrit source requests all changes updated since then), `total` hours are expected
+// Day sources (BigQuery, GitLab, Gitea/Forgejo and Gerrit) read events of the only target (`targets[0]`) orgs and repos
+// Progress is reported every GHA2DB_PROGRESS_INTERVAL seconds and when all hours are processed
+// Returns number of events that could not be parsed
+func runPipeline(ctx *lib.Ctx, thrN int, hours <-chan *ghaHour, total int, from time.Time, targets []*target, report *dryRunReport) int64 {
+	// Network and database stages use all threads, CPU bound stages use half of them
+	cpuN := thrN / 2
+	if cpuN < 1 {
+		cpuN = 1
+	}
+	downloaded := make(chan *ghaHour, 1)
+	decompressed := make(chan *ghaHour, 1)
+	events := make(chan *ghaEvent, 1000*thrN)
+
+	// Starts `n` workers, closes `out` when all of them are done
+	stage := func(n int, work func(), done func()) {
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				work()
+			}()
+		}
+		go func() {
+			wg.Wait()
+			done()
+		}()
+	}
+	var (
+		finished    sync.WaitGroup
+		inserted    sync.WaitGroup
+		parseErrors int64
 	)
-	if ch != nil {
-		ch <- true
+	inserted.Add(1)
+	cache := lib.NewArchiveCache(ctx.ArchiveCacheDir, ctx.ArchiveCacheSize)
+	bucket, err := lib.NewArchiveBucket(ctx)
+	lib.FatalOnError(err)
+	mirrors := lib.NewArchiveMirrors(ctx)
+	defer mirrors.Summary()
+	var days *daySource
+	forg, frepo := targets[0].forg, targets[0].frepo
+	if ctx.BigQuery {
+		days = bigQuerySource(forg, frepo)
+	} else if ctx.GitLab {
+		days = gitLabSource(ctx, forg, frepo)
+	} else if ctx.GiteaURL != "" {
+		days = giteaSource(ctx, forg, frepo)
+	} else if ctx.GerritURL != "" {
+		days = gerritSource(ctx, forg, frepo, from)
+	}
+	prog := &progress{total: total, started: time.Now()}
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Duration(ctx.ProgressInterval) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				prog.report()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	broken := &quarantine{}
+	defer broken.summary()
+	stage(thrN, func() { downloadHours(ctx, cache, bucket, mirrors, days, hours, downloaded, broken, prog) }, func() { close(downloaded) })
+	stage(cpuN, func() { decompressHours(downloaded, decompressed) }, func() { close(decompressed) })
+	recent := lib.NewRecentIDs(ctx.DedupCache)
+	var unknown *unknownFields
+	if ctx.PayloadOverflow {
+		unknown = &unknownFields{counts: make(map[string]int)}
+		defer unknown.summary()
+	}
+	stage(cpuN, func() {
+		parseHours(ctx, decompressed, events, &finished, &parseErrors, recent, unknown, prog, report)
+	}, func() { close(events) })
+	if report != nil {
+		stage(1, func() { countEvents(events, report, prog) }, func() { inserted.Done() })
+	} else {
+		stage(thrN, func() { insertEvents(ctx, events, prog) }, func() { inserted.Done() })
 	}
+	inserted.Wait()
+	finished.Wait()
+	close(stop)
+	prog.report()
+	return parseErrors
 }
 
-// gha2db - main work horse
-func gha2db(args []string) {
-	// Environment context parse
+// hourRange - parses hours range from `date_from hour_from date_to hour_to` args ("today" and "now" are supported)
+func hourRange(args []string) (dFrom, dTo time.Time) {
 	var (
-		ctx      lib.Ctx
 		err      error
 		hourFrom int
 		hourTo   int
-		dFrom    time.Time
-		dTo      time.Time
 	)
-	ctx.Init()
 
 	// Current date
 	now := time.Now()
@@ -1508,6 +2495,15 @@ func gha2db(args []string) {
 		)
 		lib.FatalOnError(err)
 	}
+	return
+}
+
+// gha2db - main work horse
+func gha2db(args []string) {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+	dFrom, dTo := hourRange(args)
 
 	// Strip function to be used by MapString
 	stripFunc := func(x string) string { return strings.TrimSpace(x) }
@@ -1529,6 +2525,31 @@ func gha2db(args []string) {
 		)
 	}
 
+	// GitLab events are converted to the new format, orgs are needed to find GitLab projects
+	if ctx.GitLab && (ctx.BigQuery || ctx.OldFormat || len(org) == 0) {
+		lib.FatalOnError(fmt.Errorf("GHA2DB_GITLAB needs GitLab groups or projects given as orgs, it cannot be used with GHA2DB_BIGQUERY or GHA2DB_OLDFMT"))
+	}
+
+	// The same applies to Gitea/Forgejo (organizations, users or repositories given as orgs)
+	if ctx.GiteaURL != "" && (ctx.BigQuery || ctx.GitLab || ctx.OldFormat || len(org) == 0) {
+		lib.FatalOnError(fmt.Errorf("GHA2DB_GITEA_URL needs Gitea organizations, users or repositories given as orgs, it cannot be used with GHA2DB_BIGQUERY, GHA2DB_GITLAB or GHA2DB_OLDFMT"))
+	}
+
+	// And to Gerrit (project prefixes or projects given as orgs), it is usually used next to GHA events of GitHub mirrors
+	if ctx.GerritURL != "" && (ctx.BigQuery || ctx.GitLab || ctx.GiteaURL != "" || ctx.OldFormat || len(org) == 0) {
+		lib.FatalOnError(fmt.Errorf("GHA2DB_GERRIT_URL needs Gerrit project prefixes or projects given as orgs, it cannot be used with GHA2DB_BIGQUERY, GHA2DB_GITLAB, GHA2DB_GITEA_URL or GHA2DB_OLDFMT"))
+	}
+
+	// BigQuery dataset only has events in the new format
+	if ctx.BigQuery && (ctx.OldFormat || dFrom.Before(lib.GHANewFormatStart)) {
+		lib.FatalOnError(
+			fmt.Errorf(
+				"GHA2DB_BIGQUERY day tables only have events since %v, cannot be used with old format",
+				lib.ToYMDDate(lib.GHANewFormatStart),
+			),
+		)
+	}
+
 	// Get number of CPUs available
 	thrN := lib.GetThreadsNum(&ctx)
 	lib.Printf(
@@ -1538,42 +2559,401 @@ func gha2db(args []string) {
 		strings.Join(lib.StringsSetKeys(repo), "+"),
 	)
 
-	dt := dFrom
-	if thrN > 1 {
-		chanPool := []chan bool{}
-		for dt.Before(dTo) || dt.Equal(dTo) {
-			ch := make(chan bool)
-			chanPool = append(chanPool, ch)
-			go getGHAJSON(ch, &ctx, dt, org, repo)
-			dt = dt.Add(time.Hour)
-			if len(chanPool) == thrN {
-				ch = chanPool[0]
-				<-ch
-				chanPool = chanPool[1:]
+	report := startDryRun(&ctx, dFrom, dTo)
+
+	// Gerrit changes of GitHub mirrors have own checkpoints
+	key := lib.CheckpointKey(org, repo)
+	if ctx.GerritURL != "" {
+		key = lib.GerritCheckpointKey(org, repo)
+	}
+	ingest(&ctx, thrN, dFrom, dTo, []*target{newTarget(&ctx, "", org, repo, key)}, report)
+}
+
+// gha2dbMulti - ingests hours into databases of many projects at once (`gha2db multi`)
+// Every hour is downloaded and parsed once and its events are saved into all projects that need them
+// Projects are read from projects.yaml, like gha2db_sync does (see projectTargets)
+func gha2dbMulti(args []string) {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+	dFrom, dTo := hourRange(args)
+
+	// Only GHA files contain events of all projects
+	if ctx.BigQuery || ctx.GitLab || ctx.GiteaURL != "" || ctx.GerritURL != "" {
+		lib.FatalOnError(fmt.Errorf("gha2db multi only reads GHA files, it cannot be used with GHA2DB_BIGQUERY, GHA2DB_GITLAB, GHA2DB_GITEA_URL or GHA2DB_GERRIT_URL"))
+	}
+	var names map[string]struct{}
+	if len(args) >= 5 {
+		names = lib.StringsMapToSet(
+			func(x string) string { return strings.TrimSpace(x) },
+			strings.Split(args[4], ","),
+		)
+	}
+
+	report := startDryRun(&ctx, dFrom, dTo)
+	targets := projectTargets(&ctx, names)
+	if len(targets) == 0 {
+		lib.FatalOnError(fmt.Errorf("no projects to ingest"))
+	}
+	projects := []string{}
+	for _, t := range targets {
+		projects = append(projects, t.name)
+	}
+
+	// Get number of CPUs available
+	thrN := lib.GetThreadsNum(&ctx)
+	lib.Printf(
+		"gha2db.go: Running multi project (%v CPUs): %v - %v %s\n",
+		thrN, dFrom, dTo, strings.Join(projects, "+"),
+	)
+	ingest(&ctx, thrN, dFrom, dTo, targets, report)
+}
+
+// projectTargets - returns targets of all enabled projects defined in projects.yaml (or projects from `names` if given)
+// Project's database, orgs, event types, start date and raw JSON settings are used like gha2db_sync uses them
+// Checkpoints key is the same as gha2db_sync and gha2db_backfill use, so all these tools see the same finished hours
+// Projects without database and projects whose events are not in GHA files (GitLab, Gitea/Forgejo) are skipped
+func projectTargets(ctx *lib.Ctx, names map[string]struct{}) []*target {
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+	data, err := ioutil.ReadFile(dataPrefix + ctx.ProjectsYaml)
+	lib.FatalOnError(err)
+	var projects lib.AllProjects
+	lib.FatalOnError(yaml.Unmarshal(data, &projects))
+	for name := range names {
+		if _, ok := projects.Projects[name]; !ok {
+			lib.FatalOnError(fmt.Errorf("project '%s' is not defined in '%s'", name, ctx.ProjectsYaml))
+		}
+	}
+	stripFunc := func(x string) string { return strings.TrimSpace(x) }
+	targets := []*target{}
+	all := []string{}
+	for name := range projects.Projects {
+		all = append(all, name)
+	}
+	sort.Strings(all)
+	for _, name := range all {
+		proj := projects.Projects[name]
+		if len(names) > 0 {
+			if _, ok := names[name]; !ok {
+				continue
 			}
+		} else if proj.Disabled {
+			continue
 		}
-		lib.Printf("Final threads join\n")
-		for _, ch := range chanPool {
-			<-ch
+		if proj.GitLab || proj.Gitea != nil {
+			lib.Printf("%s: skipped, its events are not in GHA files\n", name)
+			continue
 		}
-	} else {
-		lib.Printf("Using single threaded version\n")
-		for dt.Before(dTo) || dt.Equal(dTo) {
-			getGHAJSON(nil, &ctx, dt, org, repo)
-			dt = dt.Add(time.Hour)
+		if proj.PDB == "" {
+			lib.Printf("%s: skipped, it has no psql_db\n", name)
+			continue
+		}
+		pctx := *ctx
+		pctx.Project = name
+		pctx.PgDB = proj.PDB
+		if len(proj.EventTypes) > 0 {
+			pctx.EventTypes = proj.EventTypes
+		}
+		pctx.RawJSON = ctx.RawJSON || proj.RawJSON
+		if proj.RawJSONDays > 0 {
+			pctx.RawJSONDays = proj.RawJSONDays
+		}
+		org := lib.StringsMapToSet(stripFunc, strings.Split(proj.CommandLine, ","))
+		t := newTarget(&pctx, name, org, nil, lib.CheckpointKey(org, nil))
+		if proj.StartDate != nil {
+			start := lib.HourStart(*proj.StartDate)
+			t.start = &start
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// ingest - saves hours from `dFrom` to `dTo` into all targets
+// Hours are downloaded and parsed once, events are routed to targets that need them (in memory)
+// In resume mode hours already finished by a target are skipped for it, hours finished by all targets are not processed at all
+func ingest(ctx *lib.Ctx, thrN int, dFrom, dTo time.Time, targets []*target, report *dryRunReport) {
+	// Connect to Postgres DBs, connection pools are shared by all stages
+	if report == nil {
+		for _, t := range targets {
+			t.con = lib.PgConn(t.ctx)
+		}
+	}
+
+	// Monthly partitioned tables need partitions of ingested hours
+	if ctx.DBOut && report == nil {
+		for _, t := range targets {
+			lib.FatalOnError(lib.EnsurePartitions(t.con, t.ctx, dFrom, dTo))
+		}
+	}
+
+	// Hours already ingested with the same orgs/repos filter are skipped in resume mode
+	if ctx.Resume && ctx.DBOut {
+		for _, t := range targets {
+			t.finished = lib.FinishedHours(t.con, t.ctx, t.key)
+		}
+	}
+	needed := func(dt time.Time) []*target {
+		ts := []*target{}
+		for _, t := range targets {
+			if t.needs(dt) {
+				ts = append(ts, t)
+			}
+		}
+		return ts
+	}
+	skipped, total := 0, 0
+	for dt := dFrom; dt.Before(dTo) || dt.Equal(dTo); dt = dt.Add(time.Hour) {
+		if len(needed(dt)) == 0 {
+			skipped++
+			continue
+		}
+		total++
+	}
+
+	// Hours are processed by a pipeline, see runPipeline
+	// SIGINT/SIGTERM stops feeding new hours, hours in progress are saved and checkpointed
+	runCtx, cancel := lib.SignalContext()
+	defer cancel()
+	hours := make(chan *ghaHour)
+	go func() {
+		defer close(hours)
+		for dt := dFrom; dt.Before(dTo) || dt.Equal(dTo); dt = dt.Add(time.Hour) {
+			ts := needed(dt)
+			if len(ts) == 0 {
+				continue
+			}
+			select {
+			case hours <- &ghaHour{dt: dt, fn: strings.Replace(ctx.ArchiveURL, "{{date}}", lib.ToGHADate(dt), -1), targets: ts}:
+			case <-runCtx.Done():
+				lib.Printf("Not ingesting hours from %s\n", lib.ToYMDHDate(dt))
+				return
+			}
+		}
+	}()
+	parseErrors := runPipeline(ctx, thrN, hours, total, dFrom, targets, report)
+	if report != nil {
+		report.summary()
+	}
+	if skipped > 0 {
+		lib.Printf("Skipped %d already ingested hours\n", skipped)
+	}
+	if parseErrors > 0 {
+		lib.Printf("%d events could not be parsed\n", parseErrors)
+		if ctx.DBOut {
+			lib.Printf("They are saved in gha_parse_errors, use `gha2db reprocess` to process them again\n")
+		}
+	}
+	if runCtx.Err() != nil {
+		lib.FatalOnError(lib.ErrInterrupted)
+	}
+	for _, t := range targets {
+		if t.ctx.DBOut && t.ctx.RawJSONDays > 0 {
+			pruneRawJSON(t.ctx)
 		}
 	}
 	// Finished
 	lib.Printf("All done.\n")
 }
 
+// startDryRun - in dry run mode nothing is written, neither to the database nor to JSON files
+// Returns report matching events are counted in (nil when not in dry run mode)
+func startDryRun(ctx *lib.Ctx, dFrom, dTo time.Time) *dryRunReport {
+	if !ctx.DryRun {
+		return nil
+	}
+	ctx.DBOut = false
+	ctx.JSONOut = false
+	dryRunPlan(ctx, dFrom, dTo)
+	return &dryRunReport{repos: make(map[string]int)}
+}
+
+// dryRunPlan - outputs what would be ingested: hours, JSON formats, data source and event filters
+func dryRunPlan(ctx *lib.Ctx, dFrom, dTo time.Time) {
+	hours, oldHours := 0, 0
+	for dt := dFrom; dt.Before(dTo) || dt.Equal(dTo); dt = dt.Add(time.Hour) {
+		hours++
+		if lib.IsOldFormat(ctx, dt) {
+			oldHours++
+		}
+	}
+	source := ctx.ArchiveURL
+	if ctx.BigQuery {
+		source = "BigQuery githubarchive.day dataset"
+	} else if ctx.GitLab {
+		source = "GitLab API " + ctx.GitLabURL
+	} else if ctx.GiteaURL != "" {
+		source = "Gitea/Forgejo API " + ctx.GiteaURL
+	} else if ctx.GerritURL != "" {
+		source = "Gerrit API " + ctx.GerritURL
+	} else if ctx.ArchiveDir != "" && ctx.ArchiveBucket != "" {
+		source = ctx.ArchiveDir + " (missing hours from " + ctx.ArchiveBucket + " or " + ctx.ArchiveURL + ")"
+	} else if ctx.ArchiveDir != "" {
+		source = ctx.ArchiveDir + " (missing hours from " + ctx.ArchiveURL + ")"
+	} else if ctx.ArchiveBucket != "" {
+		source = ctx.ArchiveBucket + " (missing hours from " + ctx.ArchiveURL + ")"
+	}
+	types := "all"
+	if len(ctx.EventTypes) > 0 {
+		types = strings.Join(ctx.EventTypes, ",")
+	}
+	lib.Printf("Dry run: nothing will be written, only matching events are counted\n")
+	lib.Printf("Dry run: %d hours (%d in pre 2015 format), source: %s, event types: %s\n", hours, oldHours, source, types)
+}
+
+// rawJSONSince - events created before this time have no raw JSON (GHA2DB_RAW_JSON_DAYS)
+func rawJSONSince(ctx *lib.Ctx) time.Time {
+	return time.Now().AddDate(0, 0, -ctx.RawJSONDays)
+}
+
+// pruneRawJSON - clears raw JSON of events that are outside of retention window, so raw JSON doesn't grow forever
+// It is done even when raw JSON is no longer saved, so project can stop saving it and old data is still removed
+func pruneRawJSON(ctx *lib.Ctx) {
+	con := lib.PgConn(ctx)
+	res := lib.ExecSQLWithErr(
+		con,
+		ctx,
+		"update gha_payloads set raw = null where raw is not null and dup_created_at < "+lib.NValue(1),
+		rawJSONSince(ctx),
+	)
+	cleared, err := res.RowsAffected()
+	lib.FatalOnError(err)
+	if cleared > 0 {
+		lib.Printf("Cleared raw JSON of %d events older than %d days\n", cleared, ctx.RawJSONDays)
+	}
+}
+
+// deadLetter - single `gha_parse_errors` row
+type deadLetter struct {
+	dt   time.Time
+	line int
+	json string
+}
+
+// reprocess - parses events saved in `gha_parse_errors` again (after parser fixes)
+// Events that can be parsed now are saved (if they match orgs/repos/event types) and removed from dead letters
+// Others stay there with updated error
+func reprocess(args []string) {
+	var ctx lib.Ctx
+	ctx.Init()
+	if ctx.DryRun {
+		lib.FatalOnError(fmt.Errorf("GHA2DB_DRY_RUN cannot be used with reprocess, it removes processed dead letters"))
+	}
+
+	// Orgs and repos filters are the same as for normal processing
+	stripFunc := func(x string) string { return strings.TrimSpace(x) }
+	var org, repo map[string]struct{}
+	if len(args) >= 1 {
+		org = lib.StringsMapToSet(stripFunc, strings.Split(args[0], ","))
+	}
+	if len(args) >= 2 {
+		repo = lib.StringsMapToSet(stripFunc, strings.Split(args[1], ","))
+	}
+	t := newTarget(&ctx, "", org, repo, "")
+
+	con := lib.PgConn(&ctx)
+	t.con = con
+
+	// Dead letters are few, read all of them first
+	letters := []deadLetter{}
+	rows := lib.QuerySQLWithErr(con, &ctx, "select dt, line, json from gha_parse_errors order by dt, line")
+	for rows.Next() {
+		var letter deadLetter
+		lib.FatalOnError(rows.Scan(&letter.dt, &letter.line, &letter.json))
+		letters = append(letters, letter)
+	}
+	lib.FatalOnError(rows.Err())
+	lib.FatalOnError(rows.Close())
+	lib.Printf("Reprocessing %d dead letters\n", len(letters))
+
+	// Dead letters are only removed after their events are saved
+	bw := lib.NewBulkWriter(con, &ctx, ctx.BulkSize)
+	done := []deadLetter{}
+	flush := func() {
+		bw.Flush()
+		for _, letter := range done {
+			lib.ExecSQLWithErr(
+				con,
+				&ctx,
+				"delete from gha_parse_errors where dt = "+lib.NValue(1)+" and line = "+lib.NValue(2),
+				letter.dt,
+				letter.line,
+			)
+		}
+		done = done[:0]
+	}
+	e, failed := 0, 0
+	// Saved events are in past hours, cached results of their periods are cleared
+	var from, to *time.Time
+	for _, letter := range letters {
+		hour := &ghaHour{dt: letter.dt, fn: "gha_parse_errors", t: t}
+		events, perr := decodeJSON(&ctx, []byte(letter.json), hour, []*ghaHour{hour})
+		if perr != nil {
+			saveParseError(con, &ctx, letter.dt, letter.line, []byte(letter.json), perr)
+			failed++
+			continue
+		}
+		for _, ev := range events {
+			n := writeEvent(con, &ctx, bw, ev)
+			if n == 0 {
+				continue
+			}
+			e += n
+			dt := ev.ev.CreatedAt
+			if from == nil || dt.Before(*from) {
+				from = &dt
+			}
+			if to == nil || dt.After(*to) {
+				to = &dt
+			}
+		}
+		done = append(done, letter)
+		if bw.Full() || len(done) >= ctx.BulkSize {
+			flush()
+		}
+	}
+	flush()
+	lib.Printf("Reprocessed %d dead letters: %d events saved, %d still cannot be parsed\n", len(letters), e, failed)
+	if from != nil {
+		dtTo := to.Add(time.Second)
+		cleared, err := lib.ClearMetricCache(con, &ctx, from, &dtTo)
+		if err != nil {
+			lib.Printf("Cannot clear metric cache: %v\n", err)
+		} else {
+			lib.Printf("Cleared %d cached metric results of periods %s - %s\n", cleared, lib.ToYMDHMSDate(*from), lib.ToYMDHMSDate(dtTo))
+		}
+	}
+}
+
 func main() {
 	dtStart := time.Now()
+	// Dead letters mode
+	if len(os.Args) > 1 && os.Args[1] == "reprocess" {
+		reprocess(os.Args[2:])
+		lib.Printf("Time: %v\n", time.Now().Sub(dtStart))
+		return
+	}
+	// Multi project mode
+	if len(os.Args) > 1 && os.Args[1] == "multi" {
+		if len(os.Args) < 6 {
+			lib.Printf("Arguments required: multi date_from_YYYY-MM-DD hour_from_HH date_to_YYYY-MM-DD hour_to_HH ['project1,project2,...,projectN']\n")
+			os.Exit(1)
+		}
+		gha2dbMulti(os.Args[2:])
+		lib.Printf("Time: %v\n", time.Now().Sub(dtStart))
+		return
+	}
 	// Required args
 	if len(os.Args) < 5 {
 		lib.Printf(
 			"Arguments required: date_from_YYYY-MM-DD hour_from_HH date_to_YYYY-MM-DD hour_to_HH " +
-				"['org1,org2,...,orgN' ['repo1,repo2,...,repoN']]\n",
+				"['org1,org2,...,orgN' ['repo1,repo2,...,repoN']]\n" +
+				"Or: reprocess ['org1,org2,...,orgN' ['repo1,repo2,...,repoN']]\n" +
+				"Or: multi date_from_YYYY-MM-DD hour_from_HH date_to_YYYY-MM-DD hour_to_HH ['project1,project2,...,projectN']\n",
 		)
 		os.Exit(1)
 	}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	lib "devstats"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// parseHour - parses "YYYY-MM-DD" day and "HH" hour arguments
+func parseHour(day, hour string) time.Time {
+	h, err := strconv.Atoi(hour)
+	lib.FatalOnError(err)
+	dt, err := time.Parse(time.RFC3339, fmt.Sprintf("%sT%02d:00:00+00:00", day, h))
+	lib.FatalOnError(err)
+	return dt
+}
+
+// backfillSource - runs gha2db for hour ranges between `from` and `to` that have no finished checkpoint with a given key
+// Returns number of hours backfilled
+func backfillSource(ctx *lib.Ctx, project, source, orgs string, from, to time.Time, key, cmdPrefix string, env map[string]string) int {
+	con := lib.PgConn(ctx)
+	finished := lib.FinishedHours(con, ctx, key)
+	ranges := lib.MissingHours(from, to, finished)
+	missing := 0
+	for _, r := range ranges {
+		missing += r.Hours()
+	}
+	lib.Printf(
+		"%s: %s %v - %v: %d hours missing in %d ranges (database %s, orgs %s)\n",
+		project, source, from, to, missing, len(ranges), ctx.PgDB, orgs,
+	)
+	for i, r := range ranges {
+		lib.Printf("%s: backfilling %s range #%d/%d: %v - %v (%d hours)\n", project, source, i+1, len(ranges), r.From, r.To, r.Hours())
+		dtStart := time.Now()
+		_, err := lib.ExecCommand(
+			ctx,
+			[]string{
+				cmdPrefix + "gha2db",
+				lib.ToYMDDate(r.From),
+				strconv.Itoa(r.From.Hour()),
+				lib.ToYMDDate(r.To),
+				strconv.Itoa(r.To.Hour()),
+				orgs,
+			},
+			env,
+		)
+		lib.FatalOnError(err)
+		lib.Printf("%s: backfilled %s range #%d/%d, took: %v\n", project, source, i+1, len(ranges), time.Now().Sub(dtStart))
+	}
+	return missing
+}
+
+// backfill - ingests hours between `from` and `to` that are missing in project's database
+// Orgs/repos filter, database and event types are taken from project's `projects.yaml` entry
+// Hours are compared with `gha_checkpoints` using the same filter, so only missing hour ranges are passed to `gha2db`
+// Projects reviewed in Gerrit also get missing hours of their Gerrit changes
+func backfill(ctx *lib.Ctx, project string, from, to time.Time) {
+	// Local or cron mode?
+	cmdPrefix := ""
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		cmdPrefix = "./"
+		dataPrefix = "./"
+	}
+
+	// Read defined projects
+	data, err := ioutil.ReadFile(dataPrefix + ctx.ProjectsYaml)
+	lib.FatalOnError(err)
+	var projects lib.AllProjects
+	lib.FatalOnError(yaml.Unmarshal(data, &projects))
+	proj, ok := projects.Projects[project]
+	if !ok {
+		lib.FatalOnError(fmt.Errorf("project '%s' is not defined in '%s'", project, ctx.ProjectsYaml))
+	}
+	if proj.Disabled {
+		lib.FatalOnError(fmt.Errorf("project '%s' is disabled", project))
+	}
+
+	// Project's data starts at its start date
+	if proj.StartDate != nil && from.Before(*proj.StartDate) {
+		lib.Printf("%s: starting from project's start date %v instead of %v\n", project, *proj.StartDate, from)
+		from = lib.HourStart(*proj.StartDate)
+	}
+
+	// The same filter as gha2db_sync uses, so checkpoints of both tools are shared
+	orgs := strings.TrimSpace(proj.CommandLine)
+	org := lib.StringsMapToSet(func(x string) string { return strings.TrimSpace(x) }, strings.Split(orgs, ","))
+	ctx.Project = project
+	if proj.PDB != "" {
+		ctx.PgDB = proj.PDB
+	}
+
+	// Hours already finished are skipped by gha2db (resume mode), unfinished ones are processed again
+	env := map[string]string{
+		"GHA2DB_PROJECT": project,
+		"GHA2DB_RESUME":  "1",
+		"PG_DB":          ctx.PgDB,
+	}
+	if len(proj.EventTypes) > 0 {
+		env["GHA2DB_EVENT_TYPES"] = strings.Join(proj.EventTypes, ",")
+	}
+	if proj.GitLab {
+		env["GHA2DB_GITLAB"] = "1"
+	}
+	if proj.Gitea != nil {
+		env["GHA2DB_GITEA_URL"] = proj.Gitea.URL
+		env["GHA2DB_GITEA_TOKEN"] = proj.Gitea.Token
+	}
+	if proj.RawJSON {
+		env["GHA2DB_RAW_JSON"] = "1"
+	}
+	if proj.RawJSONDays > 0 {
+		env["GHA2DB_RAW_JSON_DAYS"] = strconv.Itoa(proj.RawJSONDays)
+	}
+	hours := backfillSource(ctx, project, "GHA", orgs, from, to, lib.CheckpointKey(org, nil), cmdPrefix, env)
+
+	// Gerrit changes of projects with GitHub mirrors have own checkpoints
+	if proj.Gerrit != nil {
+		gerritEnv := map[string]string{"GHA2DB_GERRIT_URL": proj.Gerrit.URL, "GHA2DB_GERRIT_TOKEN": proj.Gerrit.Token}
+		for k, v := range env {
+			gerritEnv[k] = v
+		}
+		hours += backfillSource(ctx, project, "Gerrit", orgs, from, to, lib.GerritCheckpointKey(org, nil), cmdPrefix, gerritEnv)
+	}
+
+	// Cached metric results of periods with backfilled hours are no longer valid
+	if hours > 0 {
+		con := lib.PgConn(ctx)
+		dtTo := to.Add(time.Hour)
+		cleared, err := lib.ClearMetricCache(con, ctx, &from, &dtTo)
+		if err != nil {
+			lib.Printf("%s: cannot clear metric cache: %v\n", project, err)
+		} else {
+			lib.Printf("%s: cleared %d cached metric results of backfilled periods\n", project, cleared)
+		}
+	}
+	lib.Printf("%s: backfill finished\n", project)
+}
+
+func main() {
+	dtStart := time.Now()
+	if len(os.Args) < 6 {
+		lib.Printf(
+			"%s: required arguments: project date_from_YYYY-MM-DD hour_from_HH date_to_YYYY-MM-DD hour_to_HH\n",
+			os.Args[0],
+		)
+		os.Exit(1)
+	}
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+	backfill(&ctx, os.Args[1], parseHour(os.Args[2], os.Args[3]), parseHour(os.Args[4], os.Args[5]))
+	dtEnd := time.Now()
+	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -36,27 +38,389 @@ type metricGap struct {
 	Skip      string   `yaml:"skip"`
 	Desc      bool     `yaml:"desc"`
 	Values    []string `yaml:"values"`
+	Tags      []string `yaml:"tags"`
 }
 
 // metrics contain list of metrics to evaluate
 type metrics struct {
 	Metrics []metric `yaml:"metrics"`
+	Timeout int      `yaml:"timeout"`
 }
 
 // metric contain each metric data
 type metric struct {
-	Name              string `yaml:"name"`
-	Periods           string `yaml:"periods"`
-	SeriesNameOrFunc  string `yaml:"series_name_or_func"`
-	MetricSQL         string `yaml:"sql"`
-	AddPeriodToName   bool   `yaml:"add_period_to_name"`
-	Histogram         bool   `yaml:"histogram"`
-	Aggregate         string `yaml:"aggregate"`
-	Skip              string `yaml:"skip"`
-	Desc              string `yaml:"desc"`
-	MultiValue        bool   `yaml:"multi_value"`
-	EscapeValueName   bool   `yaml:"escape_value_name"`
-	AnnotationsRanges bool   `yaml:"annotations_ranges"`
+	Name              string   `yaml:"name"`
+	Periods           string   `yaml:"periods"`
+	SeriesNameOrFunc  string   `yaml:"series_name_or_func"`
+	MetricSQL         string   `yaml:"sql"`
+	AddPeriodToName   bool     `yaml:"add_period_to_name"`
+	Histogram         bool     `yaml:"histogram"`
+	Histogram2D       bool     `yaml:"histogram_2d"`
+	Aggregate         string   `yaml:"aggregate"`
+	Skip              string   `yaml:"skip"`
+	Desc              string   `yaml:"desc"`
+	MultiValue        bool     `yaml:"multi_value"`
+	EscapeValueName   bool     `yaml:"escape_value_name"`
+	AnnotationsRanges bool     `yaml:"annotations_ranges"`
+	Tags              []string `yaml:"tags"`
+	Timeout           int      `yaml:"timeout"`
+	Derived           *derived `yaml:"derived"`
+	Percentiles       string   `yaml:"percentiles"`
+	Smoothing         string   `yaml:"smoothing"`
+	Recompute         int      `yaml:"recompute"`
+	Unit              string   `yaml:"unit"`
+	ValueType         string   `yaml:"value_type"`
+	AllProjects       string   `yaml:"all_projects"`
+	Anomalies         string   `yaml:"anomalies"`
+	AnomalyWindow     int      `yaml:"anomaly_window"`
+	DependsOn         []string `yaml:"depends_on"`
+	Validate          string   `yaml:"validate"`
+	ValidateBlock     bool     `yaml:"validate_block"`
+	Calculator        string   `yaml:"calculator"`
+	Weight            string   `yaml:"weight"`
+}
+
+// derived - metric computed as `a op b` per period (see lib.DerivedValue), instead of its own SQL
+// Operands are SQL file names (like `sql`) or existing series "series:name", period is added to series names like
+// `add_period_to_name` does, series must be computed before (by metrics listed earlier)
+type derived struct {
+	Op string `yaml:"op"`
+	A  string `yaml:"a"`
+	B  string `yaml:"b"`
+}
+
+// checkMetric returns error when derived, percentiles, smoothing, 2D histogram, release periods, recompute, unit, all projects,
+// anomalies, validation, calculator or weight metric settings are invalid
+func checkMetric(m *metric) error {
+	if m.Weight != "" {
+		if err := lib.CheckWeight(m.Weight); err != nil {
+			return err
+		}
+		if m.Calculator != "" {
+			return fmt.Errorf("calculator metric has no SQL to weight events in")
+		}
+	}
+	if m.Calculator != "" {
+		if _, err := lib.GetCalculator(m.Calculator); err != nil {
+			return err
+		}
+		if m.MetricSQL != "" || m.Derived != nil {
+			return fmt.Errorf("calculator metric has no sql or derived operands")
+		}
+		if m.Histogram || m.AnnotationsRanges || m.Percentiles != "" || m.AllProjects != "" {
+			return fmt.Errorf("calculator metric cannot be histogram, annotations ranges, percentiles or all projects")
+		}
+	}
+	if m.Validate != "" {
+		if _, err := lib.ParseValidationRules(m.Validate); err != nil {
+			return err
+		}
+		if m.Histogram || m.AnnotationsRanges {
+			return fmt.Errorf("histogram and annotations ranges metrics cannot be validated")
+		}
+	} else if m.ValidateBlock {
+		return fmt.Errorf("validate_block needs validation rules, like validate: non_negative")
+	}
+	if m.Anomalies != "" {
+		if _, err := lib.ParseAnomalyDetector(m.Anomalies, m.AnomalyWindow); err != nil {
+			return err
+		}
+		if m.Histogram || m.AnnotationsRanges {
+			return fmt.Errorf("histogram and annotations ranges metrics cannot be checked for anomalies")
+		}
+	} else if m.AnomalyWindow != 0 {
+		return fmt.Errorf("anomaly_window needs anomalies detector, like anomalies: zscore3")
+	}
+	if m.AllProjects != "" {
+		if err := lib.CheckMergeOp(m.AllProjects); err != nil {
+			return err
+		}
+		if m.Histogram || m.AnnotationsRanges || m.Derived != nil || m.Percentiles != "" {
+			return fmt.Errorf("all projects metric cannot be histogram, annotations ranges, derived or percentiles")
+		}
+	}
+	if m.Unit != "" || m.ValueType != "" {
+		if _, err := lib.ParseSeriesUnit(m.Unit, m.ValueType); err != nil {
+			return err
+		}
+	}
+	if m.Recompute < 0 {
+		return fmt.Errorf("recompute must be a number of periods, got %d", m.Recompute)
+	}
+	if m.Recompute > 0 && (m.Histogram || m.AnnotationsRanges) {
+		return fmt.Errorf("histogram and annotations ranges metrics are always computed whole, remove recompute")
+	}
+	if m.Histogram2D && (!m.Histogram || m.MultiValue) {
+		return fmt.Errorf("histogram_2d metric must be a histogram (histogram: true) and cannot be multi value")
+	}
+	for _, period := range strings.Split(m.Periods, ",") {
+		if period != lib.ReleasePeriod {
+			continue
+		}
+		if m.Histogram || m.AnnotationsRanges || (m.Aggregate != "" && m.Aggregate != "1") {
+			return fmt.Errorf("periods between releases (%s) cannot be aggregated or used by histograms", lib.ReleasePeriod)
+		}
+	}
+	if m.Smoothing != "" {
+		if _, err := lib.ParseSmoothing(m.Smoothing); err != nil {
+			return err
+		}
+		if m.Histogram || m.AnnotationsRanges {
+			return fmt.Errorf("histogram and annotations ranges metrics cannot be smoothed")
+		}
+	}
+	if m.Percentiles != "" {
+		if _, err := lib.ParsePercentiles(m.Percentiles); err != nil {
+			return err
+		}
+		if m.Histogram || m.MultiValue || m.AnnotationsRanges || m.Derived != nil {
+			return fmt.Errorf("percentiles metric cannot be histogram, multi value or derived")
+		}
+		if m.AddPeriodToName {
+			return fmt.Errorf("percentiles metric adds period to series names itself, remove add_period_to_name")
+		}
+	}
+	if m.Derived != nil {
+		if err := lib.CheckDerivedOp(m.Derived.Op); err != nil {
+			return err
+		}
+		if m.Derived.A == "" || m.Derived.B == "" {
+			return fmt.Errorf("derived metric operands 'a' and 'b' are required")
+		}
+		if m.Histogram || m.MultiValue || m.AnnotationsRanges {
+			return fmt.Errorf("derived metric cannot be histogram or multi value")
+		}
+	}
+	return nil
+}
+
+// metricsEnv returns environment passing project's time zone and Postgres replicas to tools computing metrics, nil when it has none
+func metricsEnv(ctx *lib.Ctx) map[string]string {
+	var env map[string]string
+	if ctx.TimeZone != "" {
+		env = map[string]string{"GHA2DB_TIMEZONE": ctx.TimeZone}
+	}
+	if len(ctx.PgReplicas) > 0 {
+		if env == nil {
+			env = make(map[string]string)
+		}
+		env["PG_REPLICAS"] = strings.Join(ctx.PgReplicas, ",")
+	}
+	return env
+}
+
+// metricParams returns db2influx params used for all metric's periods, `timeout` is metric's (or metrics.yaml default) timeout
+func metricParams(ctx *lib.Ctx, metric *metric, timeout int) []string {
+	extraParams := []string{}
+	if metric.Histogram {
+		extraParams = append(extraParams, "hist")
+	}
+	if metric.Histogram2D {
+		extraParams = append(extraParams, "hist2d")
+	}
+	if metric.MultiValue {
+		extraParams = append(extraParams, "multivalue")
+	}
+	if metric.EscapeValueName {
+		extraParams = append(extraParams, "escape_value_name")
+	}
+	if metric.Desc != "" {
+		extraParams = append(extraParams, "desc:"+metric.Desc)
+	}
+	if metric.Percentiles != "" {
+		extraParams = append(extraParams, "percentiles:"+strings.Replace(strings.Replace(metric.Percentiles, " ", "", -1), ",", ";", -1))
+	}
+	if metric.Smoothing != "" {
+		extraParams = append(extraParams, "smooth:"+strings.Replace(strings.Replace(metric.Smoothing, " ", "", -1), ",", ";", -1))
+	}
+	if metric.Unit != "" {
+		extraParams = append(extraParams, "unit:"+strings.TrimSpace(metric.Unit))
+	}
+	if metric.ValueType != "" {
+		extraParams = append(extraParams, "value_type:"+strings.TrimSpace(metric.ValueType))
+	}
+	if metric.AllProjects != "" {
+		extraParams = append(extraParams, "all_projects:"+metric.AllProjects)
+	}
+	if metric.Anomalies != "" {
+		extraParams = append(extraParams, "anomalies:"+strings.TrimSpace(metric.Anomalies))
+		if metric.AnomalyWindow != 0 {
+			extraParams = append(extraParams, "anomaly_window:"+strconv.Itoa(metric.AnomalyWindow))
+		}
+	}
+	if metric.Weight != "" {
+		extraParams = append(extraParams, "weight:"+metric.Weight)
+	}
+	if metric.Validate != "" {
+		extraParams = append(extraParams, "validate:"+strings.Replace(strings.Replace(metric.Validate, " ", "", -1), ",", ";", -1))
+		if metric.ValidateBlock {
+			extraParams = append(extraParams, "validate_block")
+		}
+	}
+	if metric.AnnotationsRanges {
+		extraParams = append(extraParams, "annotations_ranges")
+	}
+	if !ctx.ResetIDB && !ctx.ResetRanges {
+		extraParams = append(extraParams, "skip_past")
+	}
+	if timeout > 0 {
+		extraParams = append(extraParams, "timeout:"+strconv.Itoa(timeout))
+	}
+	return extraParams
+}
+
+// metricPeriod - period (like "d") and aggregate suffix (like "7", "" for 1) metric is computed for
+type metricPeriod struct {
+	period     string
+	aggrSuffix string
+}
+
+// metricPeriods returns periods metric is computed for (in all its aggregates), without `skipped` ones
+// Annotations ranges metrics are computed for quick ranges
+func metricPeriods(metric *metric, quickRanges []string) (result []metricPeriod, skipped []string) {
+	periods := strings.Split(metric.Periods, ",")
+	aggregate := metric.Aggregate
+	if aggregate == "" {
+		aggregate = "1"
+	}
+	if metric.AnnotationsRanges {
+		periods = quickRanges
+		aggregate = "1"
+	}
+	skipMap := make(map[string]struct{})
+	for _, skip := range strings.Split(metric.Skip, ",") {
+		skipMap[skip] = struct{}{}
+	}
+	for _, aggrStr := range strings.Split(aggregate, ",") {
+		_, err := strconv.Atoi(aggrStr)
+		lib.FatalOnError(err)
+		aggrSuffix := aggrStr
+		if aggrSuffix == "1" {
+			aggrSuffix = ""
+		}
+		for _, period := range periods {
+			if _, found := skipMap[period+aggrSuffix]; found {
+				skipped = append(skipped, period+aggrSuffix)
+				continue
+			}
+			result = append(result, metricPeriod{period: period, aggrSuffix: aggrSuffix})
+		}
+	}
+	return
+}
+
+// metricSeries returns db2influx series name (or function) of metric in given period
+func metricSeries(metric *metric, periodAggr string) string {
+	if metric.AddPeriodToName {
+		return metric.SeriesNameOrFunc + "_" + periodAggr
+	}
+	return metric.SeriesNameOrFunc
+}
+
+// rowFunctions - db2influx functions naming series by metric's rows, instead of a series name
+var rowFunctions = map[string]struct{}{
+	"single_row_multi_column": {},
+	"multi_row_single_column": {},
+	"multi_row_multi_column":  {},
+}
+
+// metricTargets returns series metric writes in all its periods
+// Series named by rows (see rowFunctions) are only known when they are computed, so the function, metric's rows
+// source (SQL, calculator or derived operands) and period stand for them: metrics using the same ones write the same series
+func metricTargets(metric *metric, quickRanges []string) (result []string) {
+	var (
+		percentiles []float64
+		smoothings  []lib.Smoothing
+	)
+	if metric.Percentiles != "" {
+		percentiles, _ = lib.ParsePercentiles(metric.Percentiles)
+	}
+	if metric.Smoothing != "" {
+		smoothings, _ = lib.ParseSmoothing(metric.Smoothing)
+	}
+	periods, _ := metricPeriods(metric, quickRanges)
+	for _, mp := range periods {
+		periodAggr := mp.period + mp.aggrSuffix
+		seriesNameOrFunc := metricSeries(metric, periodAggr)
+		names := []string{}
+		if _, ok := rowFunctions[seriesNameOrFunc]; ok {
+			source := metric.MetricSQL + ".sql"
+			if metric.Calculator != "" {
+				source = lib.CalculatorPrefix + metric.Calculator
+			} else if metric.Derived != nil {
+				source = fmt.Sprintf("%s %s %s", metric.Derived.A, metric.Derived.Op, metric.Derived.B)
+			}
+			names = append(names, fmt.Sprintf("%s(%s)_%s", seriesNameOrFunc, source, periodAggr))
+		} else if len(percentiles) > 0 {
+			for _, p := range percentiles {
+				names = append(names, seriesNameOrFunc+"_"+lib.PercentileSuffix(p)+"_"+periodAggr)
+			}
+		} else {
+			names = append(names, seriesNameOrFunc)
+			if metric.Histogram2D {
+				names = append(names, seriesNameOrFunc+"_columns")
+			}
+		}
+		for _, name := range names {
+			result = append(result, name)
+			for _, smoothing := range smoothings {
+				result = append(result, name+"_"+smoothing.Suffix())
+			}
+		}
+	}
+	return
+}
+
+// checkSeriesCollisions returns error listing metrics (entries of metrics.yaml) writing the same series, they would interleave data
+func checkSeriesCollisions(ctx *lib.Ctx, allMetrics []metric, quickRanges []string) error {
+	targets := [][]string{}
+	for i := range allMetrics {
+		targets = append(targets, metricTargets(&allMetrics[i], quickRanges))
+	}
+	collisions := lib.SeriesCollisions(targets)
+	if len(collisions) == 0 {
+		return nil
+	}
+	errs := []string{}
+	for _, collision := range collisions {
+		entries := []string{}
+		for _, i := range collision.Metrics {
+			entries = append(entries, fmt.Sprintf("#%d '%s'", i+1, allMetrics[i].Name))
+		}
+		series := collision.Series
+		more := ""
+		if len(series) > 3 {
+			more = fmt.Sprintf(" and %d more", len(series)-3)
+			series = series[:3]
+		}
+		errs = append(errs, fmt.Sprintf("metrics %s write: %s%s", strings.Join(entries, ", "), strings.Join(series, ", "), more))
+		lib.Printf("Series collision: metrics %s write the same series: %s\n", strings.Join(entries, ", "), strings.Join(collision.Series, ", "))
+	}
+	return fmt.Errorf("%s: metrics write the same series, use different series names or periods: %s", ctx.MetricsYaml, strings.Join(errs, "; "))
+}
+
+// db2influxArgs returns db2influx SQL argument (calculator or derived metric's first operand) and params in given period
+func db2influxArgs(metric *metric, metricsDir, periodAggr string, extraParams []string) (string, []string) {
+	if metric.Calculator != "" {
+		return lib.CalculatorPrefix + metric.Calculator, extraParams
+	}
+	if metric.Derived != nil {
+		params := append(
+			append([]string{}, extraParams...),
+			"derived:"+metric.Derived.Op,
+			"operand2:"+derivedOperand(metric.Derived.B, metricsDir, periodAggr),
+		)
+		return derivedOperand(metric.Derived.A, metricsDir, periodAggr), params
+	}
+	return fmt.Sprintf("%s/%s.sql", metricsDir, metric.MetricSQL), extraParams
+}
+
+// derivedOperand returns db2influx argument for derived metric's operand in given period
+func derivedOperand(arg, metricsDir, periodAggr string) string {
+	if strings.HasPrefix(arg, lib.DerivedSeriesPrefix) {
+		return arg + "_" + periodAggr
+	}
+	return fmt.Sprintf("%s/%s.sql", metricsDir, arg)
 }
 
 // Add _period to all array items
@@ -158,7 +522,8 @@ func createSeriesFromFormula(def string) (result []string) {
 
 // fills series gaps
 // Reads config from YAML (which series, for which periods)
-func fillGapsInSeries(ctx *lib.Ctx, from, to time.Time) {
+// Only gaps selected by GHA2DB_METRICS (`filter`) are filled, other series would be zeroed without being recomputed
+func fillGapsInSeries(runCtx context.Context, ctx *lib.Ctx, from, to time.Time, filter *lib.MetricFilter) {
 	lib.Printf("Fill gaps in series\n")
 	var gaps gaps
 
@@ -180,6 +545,9 @@ func fillGapsInSeries(ctx *lib.Ctx, from, to time.Time) {
 	// Iterate metrics and periods
 	bSize := 1000
 	for _, metric := range gaps.Metrics {
+		if !filter.Selected(metric.Name, "", metric.Tags) {
+			continue
+		}
 		extraParams := []string{}
 		if metric.Desc {
 			extraParams = append(extraParams, "desc")
@@ -238,7 +606,7 @@ func fillGapsInSeries(ctx *lib.Ctx, from, to time.Time) {
 					lib.Printf("Skipped filling gaps on period %s\n", periodAggr)
 					continue
 				}
-				if !ctx.ResetIDB && !lib.ComputePeriodAtThisDate(period, to) {
+				if !ctx.ResetIDB && !lib.ComputePeriodAtThisDate(period, to.In(ctx.Location())) {
 					lib.Printf("Skipping filling gaps for period \"%s\" for date %v\n", periodAggr, to)
 					continue
 				}
@@ -249,7 +617,8 @@ func fillGapsInSeries(ctx *lib.Ctx, from, to time.Time) {
 						bTo = nSeries
 					}
 					lib.Printf("Filling metric gaps %v, descriptions %v, period: %s, %d series (%d - %d)...\n", metric.Name, metric.Desc, periodAggr, nSeries, bFrom, bTo)
-					_, err := lib.ExecCommand(
+					_, err := lib.ExecCommandContext(
+						runCtx,
 						ctx,
 						[]string{
 							cmdPrefix + "z2influx",
@@ -259,7 +628,7 @@ func fillGapsInSeries(ctx *lib.Ctx, from, to time.Time) {
 							periodAggr,
 							strings.Join(extraParams, ","),
 						},
-						nil,
+						metricsEnv(ctx),
 					)
 					lib.FatalOnError(err)
 				}
@@ -268,7 +637,83 @@ func fillGapsInSeries(ctx *lib.Ctx, from, to time.Time) {
 	}
 }
 
-func sync(ctx *lib.Ctx, args []string) {
+// metricsRunKey - identifies kind of sync (metrics selection and reset flags), only the same kind of sync is resumed
+func metricsRunKey(ctx *lib.Ctx) string {
+	return lib.QueryHash(
+		strings.Join(ctx.OnlyMetrics, ","),
+		strconv.FormatBool(ctx.ResetIDB),
+		strconv.FormatBool(ctx.ResetRanges),
+		strconv.FormatBool(ctx.FullBackfill),
+	)
+}
+
+// metricsProgress returns metrics computed by unfinished sync of the same kind (`runKey`) and its window
+// Window is nil when there is nothing to resume, progress of a different kind of sync is discarded
+func metricsProgress(con *sql.DB, ctx *lib.Ctx, runKey string) (map[string]bool, *time.Time, *time.Time) {
+	rows := lib.QuerySQLWithErr(con, ctx, "select metric, run_key, window_from, window_to from gha_metrics_progress")
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	var (
+		metric, key          string
+		windowFrom, windowTo time.Time
+		from, to             *time.Time
+	)
+	done := make(map[string]bool)
+	other := false
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&metric, &key, &windowFrom, &windowTo))
+		if key != runKey {
+			other = true
+			continue
+		}
+		done[metric] = true
+		dtFrom, dtTo := windowFrom, windowTo
+		from, to = &dtFrom, &dtTo
+	}
+	lib.FatalOnError(rows.Err())
+	if other {
+		lib.Printf("Discarding progress of unfinished sync with different metrics selection or reset flags\n")
+		lib.ExecSQLWithErr(con, ctx, "delete from gha_metrics_progress where run_key <> "+lib.NValue(1), runKey)
+	}
+	return done, from, to
+}
+
+// setMetricDone records that `metric` was computed in sync window `from` - `to`
+func setMetricDone(con *sql.DB, ctx *lib.Ctx, runKey string, from, to time.Time, metric string) {
+	lib.ExecSQLWithErr(
+		con,
+		ctx,
+		"insert into gha_metrics_progress(metric, run_key, window_from, window_to, dt) "+lib.NValues(5)+
+			" on conflict (metric) do update set run_key = excluded.run_key, window_from = excluded.window_from, "+
+			"window_to = excluded.window_to, dt = excluded.dt",
+		metric,
+		runKey,
+		from,
+		to,
+		time.Now(),
+	)
+}
+
+// syncJob - Pushgateway job of sync metrics
+const syncJob = "devstats_sync"
+
+// pushFailureOnPanic must be deferred by sync, it pushes metrics of a failed sync (collected so far) and panics again
+func pushFailureOnPanic(ctx *lib.Ctx, prom *lib.PromMetrics) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	prom.Set("devstats_sync_success", "1 if the last sync succeeded, 0 if it failed.", nil, 0)
+	prom.Set("devstats_sync_last_failure_timestamp_seconds", "Time of the last failed sync.", nil, float64(time.Now().Unix()))
+	err := lib.PushMetrics(ctx, syncJob, prom)
+	if err != nil {
+		lib.Printf("Cannot push sync metrics: %v\n", err)
+	}
+	panic(r)
+}
+
+// SIGINT/SIGTERM cancels `runCtx`: running step gets SIGTERM and finishes its work in progress, next steps are not started
+// Sync fails then (releasing its lock and saving the error in sync status), the next sync continues from saved data
+func sync(runCtx context.Context, ctx *lib.Ctx, args []string) {
 	// Strip function to be used by MapString
 	stripFunc := func(x string) string { return strings.TrimSpace(x) }
 
@@ -295,16 +740,42 @@ func sync(ctx *lib.Ctx, args []string) {
 
 	// Connect to Postgres DB
 	con := lib.PgConn(ctx)
-	defer func() { lib.FatalOnError(con.Close()) }()
+
+	// Only one sync of the project database can run at a time, incremental state would be corrupted otherwise
+	lock, err := lib.AcquireLock(con, ctx, lib.SyncLock)
+	lib.FatalOnError(err)
+	defer func() { lib.FatalOnError(lock.Unlock()) }()
+
+	// Project's sync status (in `devstats` database), sync status errors are only logged
+	dtStart := time.Now()
+	if ctx.Project != "" {
+		err = lib.SyncStarted(ctx, dtStart)
+		if err != nil {
+			lib.Printf("Cannot save sync status: %v\n", err)
+		}
+		defer lib.SyncFailedOnPanic(ctx)
+	}
+	prom := lib.NewPromMetrics()
+	defer pushFailureOnPanic(ctx, prom)
+	events := int64(0)
+	timeouts := []string{}
 
 	// Connect to InfluxDB
 	ic := lib.IDBConn(ctx)
 	defer func() { lib.FatalOnError(ic.Close()) }()
 
 	// Get max event date from Postgres database
+	// Gerrit changes are read up to now, GHA events are behind them, so they are not used here
 	var maxDtPtr *time.Time
 	maxDtPg := ctx.DefaultStartDate
-	lib.FatalOnError(lib.QueryRowSQL(con, ctx, "select max(created_at) from gha_events").Scan(&maxDtPtr))
+	lib.FatalOnError(
+		lib.QueryRowSQL(
+			con,
+			ctx,
+			"select max(created_at) from gha_events where origin <> "+lib.NValue(1),
+			lib.OriginGerrit,
+		).Scan(&maxDtPtr),
+	)
 	if maxDtPtr != nil {
 		maxDtPg = *maxDtPtr
 	}
@@ -326,34 +797,100 @@ func sync(ctx *lib.Ctx, args []string) {
 	toDate := lib.ToYMDDate(to)
 	toHour := strconv.Itoa(to.Hour())
 
+	// Catch-up mode after downtime (more than GHA2DB_CATCHUP_HOURS behind): GHA hours are ingested in batches
+	// and metrics are computed once for the whole range, with all their periods (period ends passed during downtime)
+	behind := func(since time.Time) bool {
+		return ctx.CatchUpHours > 0 && to.Sub(since) > time.Duration(ctx.CatchUpHours)*time.Hour
+	}
+	catchUp := false
+
 	// Get new GHAs
 	if !ctx.SkipPDB {
 		// Clear old DB logs
 		lib.ClearDBLogs()
 
-		// gha2db
+		// gha2db, only project's event types are saved (if defined), GitLab and Gitea/Forgejo projects use their APIs
 		lib.Printf("GHA range: %s %s - %s %s\n", fromDate, fromHour, toDate, toHour)
-		_, err := lib.ExecCommand(
-			ctx,
-			[]string{
-				cmdPrefix + "gha2db",
-				fromDate,
-				fromHour,
-				toDate,
-				toHour,
-				strings.Join(org, ","),
-				strings.Join(repo, ","),
-			},
-			nil,
+		batches := []lib.CatchUpBatch{{From: from, To: to}}
+		if behind(maxDtPg) {
+			catchUp = true
+			batches = lib.CatchUpBatches(from, to)
+			lib.Printf("Catch-up mode: %v behind, ingesting %d batches\n", to.Sub(maxDtPg).Round(time.Hour), len(batches))
+		}
+		env := make(map[string]string)
+		if len(ctx.EventTypes) > 0 {
+			env["GHA2DB_EVENT_TYPES"] = strings.Join(ctx.EventTypes, ",")
+		}
+		if ctx.GitLab {
+			env["GHA2DB_GITLAB"] = "1"
+		}
+		if ctx.GiteaURL != "" {
+			env["GHA2DB_GITEA_URL"] = ctx.GiteaURL
+			env["GHA2DB_GITEA_TOKEN"] = ctx.GiteaToken
+		}
+		if ctx.RawJSON {
+			env["GHA2DB_RAW_JSON"] = "1"
+		}
+		if ctx.RawJSONDays > 0 {
+			env["GHA2DB_RAW_JSON_DAYS"] = strconv.Itoa(ctx.RawJSONDays)
+		}
+		for i, batch := range batches {
+			if catchUp {
+				lib.Printf("Catch-up batch %d/%d: %s - %s\n", i+1, len(batches), lib.ToYMDHDate(batch.From), lib.ToYMDHDate(batch.To))
+			}
+			_, err := lib.ExecCommandContext(
+				runCtx,
+				ctx,
+				[]string{
+					cmdPrefix + "gha2db",
+					lib.ToYMDDate(batch.From),
+					strconv.Itoa(batch.From.Hour()),
+					lib.ToYMDDate(batch.To),
+					strconv.Itoa(batch.To.Hour()),
+					strings.Join(org, ","),
+					strings.Join(repo, ","),
+				},
+				env,
+			)
+			lib.FatalOnError(err)
+		}
+
+		// Projects reviewed in Gerrit (with GitHub mirrors) also get their changes, for the same range
+		if ctx.GerritURL != "" {
+			lib.Printf("Gerrit range: %s %s - %s %s\n", fromDate, fromHour, toDate, toHour)
+			env["GHA2DB_GERRIT_URL"] = ctx.GerritURL
+			env["GHA2DB_GERRIT_TOKEN"] = ctx.GerritToken
+			_, err := lib.ExecCommandContext(
+				runCtx,
+				ctx,
+				[]string{
+					cmdPrefix + "gha2db",
+					fromDate,
+					fromHour,
+					toDate,
+					toHour,
+					strings.Join(org, ","),
+					strings.Join(repo, ","),
+				},
+				env,
+			)
+			lib.FatalOnError(err)
+		}
+
+		// Number of new events saved in sync status
+		lib.FatalOnError(
+			lib.QueryRowSQL(con, ctx, "select count(*) from gha_events where created_at > "+lib.NValue(1), maxDtPg).Scan(&events),
 		)
-		lib.FatalOnError(err)
+		prom.Set("devstats_sync_hours", "GHA hours ingested by the last sync.", nil, float64(lib.HourStart(to).Sub(lib.HourStart(from))/time.Hour+1))
+		prom.Set("devstats_sync_events", "New events written by the last sync.", nil, float64(events))
 
 		// Only run commits analysis for current DB here
 		// We have updated repos to the newest state as 1st step in "devstats" call
 		// We have also fetched all data from current GHA hour using "gha2db"
 		// Now let's update new commits files (from newest hour)
 		lib.Printf("Update git commits\n")
-		_, err = lib.ExecCommand(
+		_, err = lib.ExecCommandContext(
+			runCtx,
 			ctx,
 			[]string{
 				cmdPrefix + "get_repos",
@@ -368,7 +905,8 @@ func sync(ctx *lib.Ctx, args []string) {
 		// Eventual postprocess SQL's from 'structure' call
 		lib.Printf("Update structure\n")
 		// Recompute views and DB summaries
-		_, err = lib.ExecCommand(
+		_, err = lib.ExecCommandContext(
+			runCtx,
 			ctx,
 			[]string{
 				cmdPrefix + "structure",
@@ -396,21 +934,22 @@ func sync(ctx *lib.Ctx, args []string) {
 		lib.Printf("Influx range: %s - %s\n", lib.ToYMDHDate(from), lib.ToYMDHDate(to))
 
 		// InfluxDB tags (repo groups template variable currently)
-		if ctx.ResetIDB || time.Now().Hour() == 0 {
-			_, err := lib.ExecCommand(ctx, []string{cmdPrefix + "idb_tags"}, nil)
+		if ctx.ResetIDB || behind(from) || time.Now().Hour() == 0 {
+			_, err := lib.ExecCommandContext(runCtx, ctx, []string{cmdPrefix + "idb_tags"}, nil)
 			lib.FatalOnError(err)
 		} else {
 			lib.Printf("Skipping `idb_tags` recalculation, it is only computed once per day\n")
 		}
 
 		// Annotations
-		if ctx.Project != "" && (ctx.ResetIDB || time.Now().Hour() == 0) {
-			_, err := lib.ExecCommand(
+		if ctx.Project != "" && (ctx.ResetIDB || behind(from) || time.Now().Hour() == 0) {
+			_, err := lib.ExecCommandContext(
+				runCtx,
 				ctx,
 				[]string{
 					cmdPrefix + "annotations",
 				},
-				nil,
+				metricsEnv(ctx),
 			)
 			lib.FatalOnError(err)
 		} else {
@@ -421,9 +960,6 @@ func sync(ctx *lib.Ctx, args []string) {
 		quickRanges := lib.GetTagValues(ic, ctx, "quick_ranges_suffix")
 		lib.Printf("Quick ranges: %+v\n", quickRanges)
 
-		// Fill gaps in series
-		fillGapsInSeries(ctx, from, to)
-
 		// Read metrics configuration
 		data, err := ioutil.ReadFile(dataPrefix + ctx.MetricsYaml)
 		if err != nil {
@@ -433,84 +969,353 @@ func sync(ctx *lib.Ctx, args []string) {
 		var allMetrics metrics
 		lib.FatalOnError(yaml.Unmarshal(data, &allMetrics))
 
-		// Iterate all metrics
+		// Only compute metrics selected by GHA2DB_METRICS (names, SQL files or tags), all by default
+		filter := lib.NewMetricFilter(ctx.OnlyMetrics)
+		selected := []metric{}
 		for _, metric := range allMetrics.Metrics {
-			extraParams := []string{}
-			if metric.Histogram {
-				extraParams = append(extraParams, "hist")
-			}
-			if metric.MultiValue {
-				extraParams = append(extraParams, "multivalue")
-			}
-			if metric.EscapeValueName {
-				extraParams = append(extraParams, "escape_value_name")
+			if filter.Selected(metric.Name, metric.MetricSQL, metric.Tags) {
+				selected = append(selected, metric)
 			}
-			if metric.Desc != "" {
-				extraParams = append(extraParams, "desc:"+metric.Desc)
+		}
+		if unmatched := filter.Unmatched(); len(unmatched) > 0 {
+			lib.FatalOnError(fmt.Errorf("GHA2DB_METRICS: '%s' don't match any metric in %s", strings.Join(unmatched, "', '"), ctx.MetricsYaml))
+		}
+		if !filter.All() {
+			lib.Printf("Computing %d/%d metrics selected by GHA2DB_METRICS\n", len(selected), len(allMetrics.Metrics))
+		}
+		for _, metric := range selected {
+			if err := checkMetric(&metric); err != nil {
+				lib.FatalOnError(fmt.Errorf("metric '%s' in %s: %v", metric.Name, ctx.MetricsYaml, err))
 			}
-			periods := strings.Split(metric.Periods, ",")
-			aggregate := metric.Aggregate
-			if aggregate == "" {
-				aggregate = "1"
+		}
+		// All metrics are checked, also not selected ones write their series in other syncs
+		lib.FatalOnError(checkSeriesCollisions(ctx, allMetrics.Metrics, quickRanges))
+
+		// When the last sync failed (or was interrupted) after computing some metrics, it is resumed:
+		// its window is used, gaps (filled before all metrics) and metrics it computed are skipped
+		// Remaining metrics end where computed ones ended too, the next sync continues from there for all of them
+		runKey := metricsRunKey(ctx)
+		done, windowFrom, windowTo := metricsProgress(con, ctx, runKey)
+		if windowFrom != nil {
+			from, to = *windowFrom, *windowTo
+			lib.Printf("Resuming unfinished sync %s - %s, %d metrics already computed\n", lib.ToYMDHDate(from), lib.ToYMDHDate(to), len(done))
+		} else {
+			// Fill gaps in series
+			fillGapsInSeries(runCtx, ctx, from, to, filter)
+		}
+		// Metrics window decides, so a resumed catch-up sync still computes all periods
+		allPeriods := !ctx.ResetIDB && behind(from)
+		if allPeriods {
+			catchUp = true
+			lib.Printf("Catch-up mode: computing all periods since %s\n", lib.ToYMDHDate(from))
+		}
+
+		// Metric's queries are cancelled after its timeout (or metrics.yaml default timeout) in seconds
+		// Metrics that time out are recorded in sync status and skipped, other db2influx errors fail the sync
+		mctx := *ctx
+		mctx.ExecFatal = false
+
+		// Periods are due by project's time zone clock, recomputed periods start at its midnight
+		loc := ctx.Location()
+
+		// Metrics start after metrics they depend on (`depends_on`) finished, up to GHA2DB_METRICS_PARALLEL independent ones at once
+		deps := make(map[string][]string)
+		for _, metric := range allMetrics.Metrics {
+			deps[metric.Name] = metric.DependsOn
+		}
+		names := []string{}
+		indexes := make(map[string]int)
+		for i, metric := range selected {
+			names = append(names, metric.Name)
+			indexes[metric.Name] = i
+		}
+		plan, err := lib.NewMetricPlan(deps, names)
+		if err != nil {
+			lib.FatalOnError(fmt.Errorf("%s: %v", ctx.MetricsYaml, err))
+		}
+		for _, name := range names {
+			if metricDeps := plan.Deps(name); len(metricDeps) > 0 {
+				lib.Printf("Metric %v waits for: %s\n", name, strings.Join(metricDeps, ", "))
 			}
-			if metric.AnnotationsRanges {
-				extraParams = append(extraParams, "annotations_ranges")
-				periods = quickRanges
-				aggregate = "1"
+		}
+
+		// Each metric records its timed out periods in its own slot, metrics can run at once
+		metricTimeouts := make([][]string, len(selected))
+		plan.Run(ctx.MetricsParallel, func(name string) {
+			metric := selected[indexes[name]]
+			if done[metric.Name] {
+				lib.Printf("Skipping metric %v, computed by unfinished sync\n", metric.Name)
+				return
 			}
-			aggregateArr := strings.Split(aggregate, ",")
-			skips := strings.Split(metric.Skip, ",")
-			skipMap := make(map[string]struct{})
-			for _, skip := range skips {
-				skipMap[skip] = struct{}{}
+			timeout := metric.Timeout
+			if timeout == 0 {
+				timeout = allMetrics.Timeout
 			}
-			if !ctx.ResetIDB && !ctx.ResetRanges {
-				extraParams = append(extraParams, "skip_past")
+			extraParams := metricParams(ctx, &metric, timeout)
+			periods, skipped := metricPeriods(&metric, quickRanges)
+			for _, periodAggr := range skipped {
+				lib.Printf("Skipped period %s\n", periodAggr)
 			}
-			for _, aggrStr := range aggregateArr {
-				_, err := strconv.Atoi(aggrStr)
-				lib.FatalOnError(err)
-				aggrSuffix := aggrStr
-				if aggrSuffix == "1" {
-					aggrSuffix = ""
-				}
-				for _, period := range periods {
-					periodAggr := period + aggrSuffix
-					_, found := skipMap[periodAggr]
-					if found {
-						lib.Printf("Skipped period %s\n", periodAggr)
-						continue
+			for _, mp := range periods {
+				period, aggrSuffix, periodAggr := mp.period, mp.aggrSuffix, mp.period+mp.aggrSuffix
+				seriesNameOrFunc := metricSeries(&metric, periodAggr)
+				// Metrics with `recompute: N` compute their last N periods, series never computed before (or all with
+				// GHA2DB_FULL_BACKFILL) get the full history, that is also computed when their period isn't scheduled now
+				metricFrom, full := from, false
+				if metric.Recompute > 0 {
+					window, err := lib.GetMetricWindow(con, ctx, metric.Name, seriesNameOrFunc, periodAggr)
+					if err != nil {
+						lib.Printf("Cannot read metric window, computing from %s: %v\n", lib.ToYMDHDate(from), err)
+					} else {
+						metricFrom, full = lib.MetricWindowFrom(window, periodAggr, metric.Recompute, from, to.In(loc), ctx.DefaultStartDate, ctx.FullBackfill, ctx.ResetIDB)
 					}
-					if !ctx.ResetIDB && !lib.ComputePeriodAtThisDate(period, to) {
-						lib.Printf("Skipping recalculating period \"%s%s\" for date to %v\n", period, aggrSuffix, to)
-						continue
-					}
-					lib.Printf("Calculate metric %v, period %v, histogram: %v, desc: '%v', aggregate: '%v' ...\n", metric.Name, period, metric.Histogram, metric.Desc, aggrSuffix)
-					seriesNameOrFunc := metric.SeriesNameOrFunc
-					if metric.AddPeriodToName {
-						seriesNameOrFunc += "_" + periodAggr
+				}
+				if !full && !ctx.ResetIDB && !allPeriods && !lib.ComputePeriodAtThisDate(period, to.In(loc)) {
+					lib.Printf("Skipping recalculating period \"%s%s\" for date to %v\n", period, aggrSuffix, to)
+					continue
+				}
+				lib.Printf("Calculate metric %v, period %v, histogram: %v, desc: '%v', aggregate: '%v' ...\n", metric.Name, period, metric.Histogram, metric.Desc, aggrSuffix)
+				if metric.Recompute > 0 {
+					lib.Printf("Metric %v, period %v window: %s - %s, full history: %v\n", metric.Name, periodAggr, lib.ToYMDHDate(metricFrom), lib.ToYMDHDate(to), full)
+				}
+				sqlArg, params := db2influxArgs(&metric, metricsDir, periodAggr, extraParams)
+				dtMetric := time.Now()
+				_, err := lib.ExecCommandContext(
+					runCtx,
+					&mctx,
+					[]string{
+						cmdPrefix + "db2influx",
+						seriesNameOrFunc,
+						sqlArg,
+						lib.ToYMDHDate(metricFrom),
+						lib.ToYMDHDate(to),
+						periodAggr,
+						strings.Join(params, ","),
+					},
+					metricsEnv(ctx),
+				)
+				prom.Observe(
+					"devstats_metric_duration_seconds",
+					"Duration of metric's periods computed by the last sync.",
+					map[string]string{"metric": metric.Name},
+					time.Now().Sub(dtMetric).Seconds(),
+				)
+				if lib.IsExitCode(err, lib.MetricTimeoutExitCode) {
+					lib.Printf("Metric %v, period %v timed out after %ds, skipping it\n", metric.Name, periodAggr, timeout)
+					metricTimeouts[indexes[name]] = append(metricTimeouts[indexes[name]], fmt.Sprintf("%s %s (%ds)", metric.Name, periodAggr, timeout))
+					continue
+				}
+				lib.FatalOnError(err)
+				if metric.Recompute > 0 {
+					err = lib.SetMetricWindow(con, ctx, metric.Name, seriesNameOrFunc, periodAggr, metricFrom, to, full)
+					if err != nil {
+						lib.Printf("Cannot save metric window: %v\n", err)
 					}
-					_, err = lib.ExecCommand(
-						ctx,
-						[]string{
-							cmdPrefix + "db2influx",
-							seriesNameOrFunc,
-							fmt.Sprintf("%s/%s.sql", metricsDir, metric.MetricSQL),
-							lib.ToYMDHDate(from),
-							lib.ToYMDHDate(to),
-							periodAggr,
-							strings.Join(extraParams, ","),
-						},
-						nil,
-					)
-					lib.FatalOnError(err)
 				}
 			}
+			setMetricDone(con, ctx, runKey, from, to, metric.Name)
+		})
+		for _, metricTimeout := range metricTimeouts {
+			timeouts = append(timeouts, metricTimeout...)
+		}
+		lib.ExecSQLWithErr(con, ctx, "delete from gha_metrics_progress")
+
+		// Clear old metric runs
+		lib.ExecSQLWithErr(con, ctx, "delete from gha_metric_runs where dt < now() - '"+ctx.MetricRunsPeriod+"'::interval")
+
+		// Validation violations are kept as long as metric runs
+		_, err = lib.ExecSQL(con, ctx, "delete from gha_metric_violations where dt < now() - '"+ctx.MetricRunsPeriod+"'::interval")
+		if err != nil {
+			lib.Printf("Cannot clear metric violations: %v\n", err)
+		}
+
+		// Clear expired and old cached metric results (old ones are computed and cached again when needed)
+		_, err = lib.ExecSQL(
+			con,
+			ctx,
+			"delete from gha_metric_cache where expires_at < now() or dt < now() - '"+ctx.MetricCachePeriod+"'::interval",
+		)
+		if err != nil {
+			lib.Printf("Cannot clear metric cache: %v\n", err)
+		}
+
+		// Explained slow queries are kept as long as metric runs
+		_, err = lib.ExecSQL(con, ctx, "delete from gha_slow_queries where dt < now() - '"+ctx.MetricRunsPeriod+"'::interval")
+		if err != nil {
+			lib.Printf("Cannot clear slow queries: %v\n", err)
 		}
 	}
+	if ctx.Project != "" {
+		err = lib.SyncSucceeded(ctx, dtStart, events, timeouts)
+		if err != nil {
+			lib.Printf("Cannot save sync status: %v\n", err)
+		}
+	}
+	if len(timeouts) > 0 {
+		lib.Printf("Metrics timed out: %s\n", strings.Join(timeouts, ", "))
+	}
+	catchUpValue := 0.0
+	if catchUp {
+		catchUpValue = 1
+	}
+	prom.Set("devstats_sync_catch_up", "1 if the last sync ran in catch-up mode, 0 otherwise.", nil, catchUpValue)
+	prom.Set("devstats_sync_metric_timeouts", "Metric periods that timed out in the last sync.", nil, float64(len(timeouts)))
+	prom.Set("devstats_sync_duration_seconds", "Duration of the last successful sync.", nil, time.Now().Sub(dtStart).Seconds())
+	prom.Set("devstats_sync_success", "1 if the last sync succeeded, 0 if it failed.", nil, 1)
+	prom.Set("devstats_sync_last_success_timestamp_seconds", "Time of the last successful sync.", nil, float64(time.Now().Unix()))
+	err = lib.PushMetrics(ctx, syncJob, prom)
+	if err != nil {
+		lib.Printf("Cannot push sync metrics: %v\n", err)
+	}
 	lib.Printf("Sync success\n")
 }
 
+// backfillMetric recomputes the full history of project's metric (selected by name or SQL file) in all its periods
+// Periods are computed by up to GHA2DB_METRICS_PARALLEL db2influx commands at once, each of them computes its periods in parallel
+// With `staging` InfluxDB database series (and annotations) are written there, to be checked before they are backfilled for real
+func backfillMetric(runCtx context.Context, ctx *lib.Ctx, name, staging string) {
+	// Local or cron mode?
+	cmdPrefix := ""
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		cmdPrefix = "./"
+		dataPrefix = "./"
+	}
+	metricsDir := dataPrefix + "metrics/" + ctx.Project
+
+	// Read metrics configuration, name must select exactly one metric
+	data, err := ioutil.ReadFile(dataPrefix + ctx.MetricsYaml)
+	lib.FatalOnError(err)
+	var allMetrics metrics
+	lib.FatalOnError(yaml.Unmarshal(data, &allMetrics))
+	filter := lib.NewMetricFilter([]string{name})
+	selected := []metric{}
+	for _, metric := range allMetrics.Metrics {
+		if filter.Selected(metric.Name, metric.MetricSQL, metric.Tags) {
+			selected = append(selected, metric)
+		}
+	}
+	if len(selected) != 1 {
+		lib.FatalOnError(fmt.Errorf("'%s' selects %d metrics in %s, backfill needs exactly one", name, len(selected), ctx.MetricsYaml))
+	}
+	metric := selected[0]
+	if err := checkMetric(&metric); err != nil {
+		lib.FatalOnError(fmt.Errorf("metric '%s' in %s: %v", metric.Name, ctx.MetricsYaml, err))
+	}
+
+	// Staging database gets annotations too, so its quick ranges and dashboards work
+	env := metricsEnv(ctx)
+	ictx := *ctx
+	if staging != "" {
+		if env == nil {
+			env = make(map[string]string)
+		}
+		env["IDB_DB"] = staging
+		ictx.IDBDB = staging
+		ic := lib.IDBConn(ctx)
+		lib.QueryIDB(ic, ctx, "create database "+staging)
+		lib.FatalOnError(ic.Close())
+		_, err := lib.ExecCommandContext(runCtx, ctx, []string{cmdPrefix + "annotations"}, env)
+		lib.FatalOnError(err)
+	}
+	quickRanges := []string{}
+	if metric.AnnotationsRanges {
+		ic := lib.IDBConn(&ictx)
+		quickRanges = lib.GetTagValues(ic, &ictx, "quick_ranges_suffix")
+		lib.FatalOnError(ic.Close())
+	}
+
+	// Full history is computed, like in GHA2DB_RESETIDB mode
+	ctx.ResetIDB = true
+	timeout := metric.Timeout
+	if timeout == 0 {
+		timeout = allMetrics.Timeout
+	}
+	extraParams := metricParams(ctx, &metric, timeout)
+	from, to := ctx.DefaultStartDate, time.Now()
+	periods, skipped := metricPeriods(&metric, quickRanges)
+	for _, periodAggr := range skipped {
+		lib.Printf("Skipped period %s\n", periodAggr)
+	}
+	lib.Printf("Backfilling metric %v, %d periods: %s - %s, InfluxDB: %s\n", metric.Name, len(periods), lib.ToYMDHDate(from), lib.ToYMDHDate(to), ictx.IDBDB)
+
+	// Connect to Postgres DB, backfilled `recompute` metrics windows are saved there
+	con := lib.PgConn(ctx)
+
+	mctx := *ctx
+	mctx.ExecFatal = false
+	parallel := ctx.MetricsParallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	sem := make(chan struct{}, parallel)
+	type result struct {
+		periodAggr string
+		took       time.Duration
+		err        error
+	}
+	results := make(chan result)
+	for _, mp := range periods {
+		go func(periodAggr string) {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			seriesNameOrFunc := metricSeries(&metric, periodAggr)
+			sqlArg, params := db2influxArgs(&metric, metricsDir, periodAggr, extraParams)
+			dtPeriod := time.Now()
+			_, err := lib.ExecCommandContext(
+				runCtx,
+				&mctx,
+				[]string{
+					cmdPrefix + "db2influx",
+					seriesNameOrFunc,
+					sqlArg,
+					lib.ToYMDHDate(from),
+					lib.ToYMDHDate(to),
+					periodAggr,
+					strings.Join(params, ","),
+				},
+				env,
+			)
+			if err == nil && metric.Recompute > 0 && staging == "" {
+				err = lib.SetMetricWindow(con, ctx, metric.Name, seriesNameOrFunc, periodAggr, from, to, true)
+			}
+			results <- result{periodAggr: periodAggr, took: time.Now().Sub(dtPeriod), err: err}
+		}(mp.period + mp.aggrSuffix)
+	}
+
+	// Progress is displayed when every period finishes
+	dtStart := time.Now()
+	last := time.Time{}
+	errs := []string{}
+	for i := range periods {
+		res := <-results
+		msg := fmt.Sprintf("%s period %s took %v", metric.Name, res.periodAggr, res.took)
+		if res.err != nil {
+			msg = fmt.Sprintf("%s period %s failed: %v", metric.Name, res.periodAggr, res.err)
+			errs = append(errs, fmt.Sprintf("%s: %v", res.periodAggr, res.err))
+		}
+		lib.ProgressInfo(i+1, len(periods), dtStart, &last, 0, msg)
+	}
+	if len(errs) > 0 {
+		lib.FatalOnError(fmt.Errorf("backfilling metric '%s' failed in %d/%d periods: %s", metric.Name, len(errs), len(periods), strings.Join(errs, ", ")))
+	}
+	lib.Printf("Backfilled metric %v, %d periods, took %v\n", metric.Name, len(periods), time.Now().Sub(dtStart))
+}
+
+// Terminates sessions holding sync lock of the project database (when their sync is stuck)
+func forceUnlock(ctx *lib.Ctx) {
+	con := lib.PgConn(ctx)
+	holders, err := lib.ForceUnlock(con, ctx, lib.SyncLock)
+	lib.FatalOnError(err)
+	if len(holders) == 0 {
+		lib.Printf("Lock %s in %s is not held\n", lib.SyncLock, ctx.PgDB)
+		return
+	}
+	for _, holder := range holders {
+		lib.Printf("Terminated %s holding lock %s in %s\n", holder, lib.SyncLock, ctx.PgDB)
+	}
+}
+
 // Return per project args (if no args given) or get args from command line (if given)
 // When no args given and no project set (via GHA2DB_PROJECT) it panics
 func getSyncArgs(ctx *lib.Ctx, osArgs []string) []string {
@@ -545,6 +1350,30 @@ func getSyncArgs(ctx *lib.Ctx, osArgs []string) []string {
 		if proj.StartDate != nil {
 			ctx.DefaultStartDate = *proj.StartDate
 		}
+		if len(proj.EventTypes) > 0 {
+			ctx.EventTypes = proj.EventTypes
+		}
+		ctx.GitLab = ctx.GitLab || proj.GitLab
+		if proj.Gitea != nil {
+			ctx.GiteaURL = proj.Gitea.URL
+			ctx.GiteaToken = proj.Gitea.Token
+		}
+		if proj.Gerrit != nil {
+			ctx.GerritURL = proj.Gerrit.URL
+			ctx.GerritToken = proj.Gerrit.Token
+		}
+		ctx.RawJSON = ctx.RawJSON || proj.RawJSON
+		if proj.RawJSONDays > 0 {
+			ctx.RawJSONDays = proj.RawJSONDays
+		}
+		if proj.TimeZone != "" {
+			ctx.TimeZone = proj.TimeZone
+			// Unknown time zone fails now, not in tools computing periods
+			ctx.Location()
+		}
+		if len(proj.Replicas) > 0 {
+			ctx.PgReplicas = proj.Replicas
+		}
 		return []string{proj.CommandLine}
 	}
 	// No user commandline and project not found
@@ -563,7 +1392,29 @@ func main() {
 	// Environment context parse
 	var ctx lib.Ctx
 	ctx.Init()
-	sync(&ctx, getSyncArgs(&ctx, os.Args))
+	if len(os.Args) > 1 && os.Args[1] == "--force-unlock" {
+		forceUnlock(&ctx)
+	} else if len(os.Args) > 2 && os.Args[1] == "--backfill" {
+		// gha2db_sync --backfill 'metric name' [staging_influx_db], project's settings are read like without arguments
+		runCtx, cancel := lib.SignalContext()
+		defer cancel()
+		getSyncArgs(&ctx, os.Args[:1])
+		staging := ""
+		if len(os.Args) > 3 {
+			staging = os.Args[3]
+		}
+		backfillMetric(runCtx, &ctx, os.Args[2], staging)
+	} else {
+		defer lib.NotifyOnPanic(&ctx, "gha2db_sync", dtStart)
+		// Kubernetes evictions send SIGTERM
+		runCtx, cancel := lib.SignalContext()
+		defer cancel()
+		sync(runCtx, &ctx, getSyncArgs(&ctx, os.Args))
+		err := lib.Notify(&ctx, lib.NewNotification(&ctx, "gha2db_sync", dtStart, true, "Sync success", nil))
+		if err != nil {
+			lib.Printf("%v\n", err)
+		}
+	}
 	dtEnd := time.Now()
 	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
 }
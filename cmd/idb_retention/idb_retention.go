@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	lib "devstats"
+
+	client "github.com/influxdata/influxdb/client/v2"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// rollupSeries rolls up series points before `cutoff` into one point per policy's downsample period
+// Rolled up points (they keep their tags) are written first, then original points of their periods are deleted
+// Returns number of periods rolled up
+func rollupSeries(ic client.Client, ctx *lib.Ctx, series string, policy *lib.RetentionPolicy, cutoff time.Time) int {
+	_, _, start, next, _ := lib.GetIntervalFunctionsIn(policy.Downsample, false, ctx.Location())
+	res := lib.QueryIDB(ic, ctx, fmt.Sprintf("select * from \"%s\" where time < %d group by *", series, cutoff.UnixNano()))
+	if len(res) < 1 {
+		return 0
+	}
+
+	// Get BatchPoints
+	var pts lib.IDBBatchPointsN
+	bp := lib.IDBBatchPoints(ctx, &ic)
+	pts.NPoints = 0
+	pts.Points = &bp
+
+	periods := make(map[time.Time]struct{})
+	for _, row := range res[0].Series {
+		points := []lib.RollupPoint{}
+		for _, values := range row.Values {
+			point := lib.RollupPoint{Time: lib.TimeParseIDB(values[0].(string)), Fields: make(map[string]interface{})}
+			for i, column := range row.Columns[1:] {
+				switch value := values[i+1].(type) {
+				case nil:
+				case json.Number:
+					point.Fields[column], _ = value.Float64()
+				default:
+					point.Fields[column] = value
+				}
+			}
+			points = append(points, point)
+		}
+		for _, point := range lib.Rollup(points, start, policy.Op) {
+			lib.IDBAddPointN(ctx, &ic, &pts, lib.IDBNewPointWithErr(series, row.Tags, point.Fields, point.Time))
+			periods[point.Time] = struct{}{}
+		}
+	}
+	if ctx.SkipIDB {
+		lib.Printf("%s: skipping write of %d rolled up periods\n", series, len(periods))
+		return len(periods)
+	}
+	lib.FatalOnError(lib.IDBWritePointsN(ctx, &ic, &pts))
+
+	// Rolled up points are at periods' starts, other points of these periods are deleted
+	for dt := range periods {
+		lib.QueryIDB(ic, ctx, fmt.Sprintf("delete from \"%s\" where time > %d and time < %d", series, dt.UnixNano(), next(dt).UnixNano()))
+	}
+	return len(periods)
+}
+
+// applyRetention rolls up (or deletes) series points older than their retention policies keep them
+func applyRetention() {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+
+	// Read retention policies, projects without them keep all points
+	data, err := ioutil.ReadFile(dataPrefix + ctx.RetentionYaml)
+	if os.IsNotExist(err) {
+		lib.Printf("No retention policies in %s\n", ctx.RetentionYaml)
+		return
+	}
+	lib.FatalOnError(err)
+	var policies lib.RetentionPolicies
+	lib.FatalOnError(yaml.Unmarshal(data, &policies))
+	lib.FatalOnError(policies.Check())
+
+	// Connect to InfluxDB
+	ic := lib.IDBConn(&ctx)
+	defer func() { lib.FatalOnError(ic.Close()) }()
+
+	// All series names
+	names := []string{}
+	res := lib.QueryIDB(ic, &ctx, "show measurements")
+	if len(res) > 0 && len(res[0].Series) > 0 {
+		for _, row := range res[0].Series[0].Values {
+			names = append(names, row[0].(string))
+		}
+	}
+
+	now := time.Now()
+	nSeries, nPeriods := 0, 0
+	for _, name := range names {
+		policy := policies.Policy(name)
+		if policy == nil {
+			continue
+		}
+		nSeries++
+		cutoff := policy.Cutoff(now, ctx.Location())
+		if policy.Downsample == "" {
+			if !ctx.SkipIDB {
+				lib.QueryIDB(ic, &ctx, fmt.Sprintf("delete from \"%s\" where time < %d", name, cutoff.UnixNano()))
+			}
+			lib.Printf("%s: deleted points before %s\n", name, lib.ToYMDHMSDate(cutoff))
+			continue
+		}
+		n := rollupSeries(ic, &ctx, name, policy, cutoff)
+		nPeriods += n
+		lib.Printf("%s: rolled up %d %s periods before %s (%s)\n", name, n, policy.Downsample, lib.ToYMDHMSDate(cutoff), policy.Op)
+	}
+	lib.Printf("Applied retention policies to %d/%d series, rolled up %d periods\n", nSeries, len(names), nPeriods)
+}
+
+func main() {
+	dtStart := time.Now()
+	applyRetention()
+	dtEnd := time.Now()
+	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
+}
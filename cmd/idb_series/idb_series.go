@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	lib "devstats"
+
+	client "github.com/influxdata/influxdb/client/v2"
+)
+
+// seriesQuery executes InfluxDB query, returning error instead of exiting, so copied series can be dropped on failure
+func seriesQuery(ic client.Client, ctx *lib.Ctx, query string) ([]client.Result, error) {
+	response, err := lib.SafeQueryIDB(ic, ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if err = response.Error(); err != nil {
+		return nil, err
+	}
+	return response.Results, nil
+}
+
+// allSeries returns names of all series in InfluxDB database
+func allSeries(ic client.Client, ctx *lib.Ctx) (names []string) {
+	res := lib.QueryIDB(ic, ctx, "show measurements")
+	if len(res) < 1 || len(res[0].Series) < 1 {
+		return
+	}
+	for _, row := range res[0].Series[0].Values {
+		names = append(names, row[0].(string))
+	}
+	return
+}
+
+// matchingSeries returns names of series matching `pattern` regexp
+func matchingSeries(ic client.Client, ctx *lib.Ctx, pattern string) (names []string) {
+	re, err := regexp.Compile(pattern)
+	lib.FatalOnError(err)
+	for _, name := range allSeries(ic, ctx) {
+		if re.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+// seriesInfo returns number of series points (with all their tags) and times of the first and the last one
+func seriesInfo(ic client.Client, ctx *lib.Ctx, name string) (points int64, first, last time.Time, err error) {
+	res, err := seriesQuery(ic, ctx, fmt.Sprintf("select count(*) from \"%s\" group by *", name))
+	if err != nil || len(res) < 1 {
+		return
+	}
+	// Count is per field, point has at least one of them
+	for _, row := range res[0].Series {
+		for _, values := range row.Values {
+			max := int64(0)
+			for _, value := range values[1:] {
+				if number, ok := value.(json.Number); ok {
+					if n, _ := number.Int64(); n > max {
+						max = n
+					}
+				}
+			}
+			points += max
+		}
+	}
+	for _, order := range []string{"asc", "desc"} {
+		res, err = seriesQuery(ic, ctx, fmt.Sprintf("select * from \"%s\" order by time %s limit 1", name, order))
+		if err != nil {
+			return
+		}
+		if len(res) < 1 || len(res[0].Series) < 1 || len(res[0].Series[0].Values) < 1 {
+			continue
+		}
+		dt := lib.TimeParseIDB(res[0].Series[0].Values[0][0].(string))
+		if order == "asc" {
+			first = dt
+		} else {
+			last = dt
+		}
+	}
+	return
+}
+
+// copyPoints copies all points of series `from` to series `to` (with their tags), returns number of points copied
+func copyPoints(ic client.Client, ctx *lib.Ctx, from, to string) (int64, error) {
+	res, err := seriesQuery(ic, ctx, fmt.Sprintf("select * from \"%s\" group by *", from))
+	if err != nil || len(res) < 1 {
+		return 0, err
+	}
+
+	// Get BatchPoints
+	var pts lib.IDBBatchPointsN
+	bp := lib.IDBBatchPoints(ctx, &ic)
+	pts.NPoints = 0
+	pts.Points = &bp
+
+	n := int64(0)
+	for _, row := range res[0].Series {
+		for _, values := range row.Values {
+			dt := lib.TimeParseIDB(values[0].(string))
+			fields := make(map[string]interface{})
+			for i, column := range row.Columns[1:] {
+				switch value := values[i+1].(type) {
+				case nil:
+				case json.Number:
+					fields[column], err = value.Float64()
+					if err != nil {
+						return n, err
+					}
+				default:
+					fields[column] = value
+				}
+			}
+			lib.IDBAddPointN(ctx, &ic, &pts, lib.IDBNewPointWithErr(to, row.Tags, fields, dt))
+			n++
+		}
+	}
+	return n, lib.IDBWritePointsN(ctx, &ic, &pts)
+}
+
+// listSeries displays series matching `pattern` with their number of points and time range
+func listSeries(ic client.Client, ctx *lib.Ctx, pattern string) {
+	names := matchingSeries(ic, ctx, pattern)
+	total := int64(0)
+	for _, name := range names {
+		points, first, last, err := seriesInfo(ic, ctx, name)
+		lib.FatalOnError(err)
+		total += points
+		fmt.Printf("%s\t%d\t%s\t%s\n", name, points, lib.ToYMDHMSDate(first), lib.ToYMDHMSDate(last))
+	}
+	lib.Printf("%d series, %d points\n", len(names), total)
+}
+
+// dropSeries drops series matching `pattern`
+func dropSeries(ic client.Client, ctx *lib.Ctx, pattern string) {
+	names := matchingSeries(ic, ctx, pattern)
+	for _, name := range names {
+		if ctx.SkipIDB {
+			lib.Printf("Would drop %s\n", name)
+			continue
+		}
+		lib.QueryIDB(ic, ctx, fmt.Sprintf("drop measurement \"%s\"", name))
+		lib.Printf("Dropped %s\n", name)
+	}
+	lib.Printf("%d series dropped\n", len(names))
+}
+
+// copySeries copies series matching `pattern` to new names (`replacement` can use pattern's groups, like "new_$1")
+// When all of them are copied and have all their points, sources are dropped in `rename` mode
+// When any series fails, all copied series are dropped and sources are kept, so nothing changes
+func copySeries(ic client.Client, ctx *lib.Ctx, pattern, replacement string, rename bool) {
+	renames, err := lib.SeriesRenames(allSeries(ic, ctx), pattern, replacement)
+	lib.FatalOnError(err)
+	if ctx.SkipIDB {
+		for _, r := range renames {
+			lib.Printf("Would copy %s -> %s\n", r.From, r.To)
+		}
+		lib.Printf("%d series would be copied, rename: %v\n", len(renames), rename)
+		return
+	}
+	copied := []string{}
+	rollback := func(err error) {
+		for _, name := range copied {
+			lib.QueryIDB(ic, ctx, fmt.Sprintf("drop measurement \"%s\"", name))
+		}
+		lib.Printf("Dropped %d copied series, nothing was changed\n", len(copied))
+		lib.FatalOnError(err)
+	}
+	for _, r := range renames {
+		copied = append(copied, r.To)
+		n, err := copyPoints(ic, ctx, r.From, r.To)
+		if err != nil {
+			rollback(fmt.Errorf("copying %s -> %s: %v", r.From, r.To, err))
+		}
+		points, _, _, err := seriesInfo(ic, ctx, r.To)
+		if err != nil {
+			rollback(fmt.Errorf("checking %s: %v", r.To, err))
+		}
+		if points != n {
+			rollback(fmt.Errorf("%s has %d points, %d were copied from %s", r.To, points, n, r.From))
+		}
+		lib.Printf("Copied %s -> %s: %d points\n", r.From, r.To, n)
+	}
+	if rename {
+		for _, r := range renames {
+			lib.QueryIDB(ic, ctx, fmt.Sprintf("drop measurement \"%s\"", r.From))
+		}
+	}
+	lib.Printf("%d series copied, rename: %v\n", len(renames), rename)
+}
+
+func main() {
+	dtStart := time.Now()
+	if len(os.Args) < 3 {
+		lib.Printf("Required args: list|drop 'series_regexp' or copy|rename 'series_regexp' 'replacement'\n")
+		os.Exit(1)
+	}
+
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Connect to InfluxDB
+	ic := lib.IDBConn(&ctx)
+	defer func() { lib.FatalOnError(ic.Close()) }()
+
+	cmd, pattern := strings.ToLower(os.Args[1]), os.Args[2]
+	switch cmd {
+	case "list":
+		listSeries(ic, &ctx, pattern)
+	case "drop":
+		dropSeries(ic, &ctx, pattern)
+	case "copy", "rename":
+		if len(os.Args) < 4 {
+			lib.Printf("Required args: %s 'series_regexp' 'replacement'\n", cmd)
+			os.Exit(1)
+		}
+		copySeries(ic, &ctx, pattern, os.Args[3], cmd == "rename")
+	default:
+		lib.Printf("Unknown command '%s', use: list, drop, copy or rename\n", cmd)
+		os.Exit(1)
+	}
+	dtEnd := time.Now()
+	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
+}
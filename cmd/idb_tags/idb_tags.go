@@ -32,7 +32,6 @@ func idbTags() {
 
 	// Connect to Postgres DB
 	con := lib.PgConn(&ctx)
-	defer func() { lib.FatalOnError(con.Close()) }()
 
 	// Connect to InfluxDB
 	ic := lib.IDBConn(&ctx)
@@ -115,7 +115,6 @@ func importAffs(jsonFN string) {
 
 	// Connect to Postgres DB
 	con := lib.PgConn(&ctx)
-	defer func() { lib.FatalOnError(con.Close()) }()
 
 	// Parse github_users.json
 	var users gitHubUsers
@@ -343,6 +342,14 @@ func importAffs(jsonFN string) {
 		"Processed %d affiliations, added %d actors, cache hit: %d, miss: %d\n",
 		len(affList), added, cached, nonCached,
 	)
+
+	// Company metrics of all periods can change with affiliations
+	cleared, err := lib.ClearMetricCache(con, &ctx, nil, nil)
+	if err != nil {
+		lib.Printf("Cannot clear metric cache: %v\n", err)
+	} else {
+		lib.Printf("Cleared %d cached metric results\n", cleared)
+	}
 }
 
 func main() {
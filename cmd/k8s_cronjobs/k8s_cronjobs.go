@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	lib "devstats"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Kubernetes objects, only fields used by generated manifests
+type cronJob struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   objectMeta  `yaml:"metadata"`
+	Spec       cronJobSpec `yaml:"spec"`
+}
+
+type objectMeta struct {
+	Name      string            `yaml:"name,omitempty"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+type cronJobSpec struct {
+	Schedule                   string          `yaml:"schedule"`
+	TimeZone                   string          `yaml:"timeZone"`
+	ConcurrencyPolicy          string          `yaml:"concurrencyPolicy"`
+	SuccessfulJobsHistoryLimit int             `yaml:"successfulJobsHistoryLimit"`
+	FailedJobsHistoryLimit     int             `yaml:"failedJobsHistoryLimit"`
+	JobTemplate                jobTemplateSpec `yaml:"jobTemplate"`
+}
+
+type jobTemplateSpec struct {
+	Spec jobSpec `yaml:"spec"`
+}
+
+type jobSpec struct {
+	BackoffLimit int             `yaml:"backoffLimit"`
+	Template     podTemplateSpec `yaml:"template"`
+}
+
+type podTemplateSpec struct {
+	Metadata objectMeta `yaml:"metadata"`
+	Spec     podSpec    `yaml:"spec"`
+}
+
+type podSpec struct {
+	RestartPolicy string      `yaml:"restartPolicy"`
+	Containers    []container `yaml:"containers"`
+}
+
+type container struct {
+	Name      string                `yaml:"name"`
+	Image     string                `yaml:"image"`
+	Command   []string              `yaml:"command"`
+	Env       []envVar              `yaml:"env"`
+	EnvFrom   []envFromSource       `yaml:"envFrom"`
+	Resources *resourceRequirements `yaml:"resources,omitempty"`
+}
+
+type envVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+type envFromSource struct {
+	ConfigMapRef *optionalRef `yaml:"configMapRef,omitempty"`
+	SecretRef    *optionalRef `yaml:"secretRef,omitempty"`
+}
+
+type optionalRef struct {
+	Name     string `yaml:"name"`
+	Optional bool   `yaml:"optional"`
+}
+
+type resourceRequirements struct {
+	Requests map[string]string `yaml:"requests,omitempty"`
+	Limits   map[string]string `yaml:"limits,omitempty"`
+}
+
+// nameRe - characters not allowed in Kubernetes object names
+var nameRe = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// k8sName returns project name allowed in Kubernetes names and labels
+func k8sName(project string) string {
+	return strings.Trim(nameRe.ReplaceAllString(strings.ToLower(project), "-"), "-")
+}
+
+// jobName returns CronJob name for project, CronJob names can have up to 52 characters
+func jobName(project string) string {
+	name := "devstats-sync-" + k8sName(project)
+	if len(name) > 52 {
+		name = strings.TrimRight(name[:52], "-")
+	}
+	return name
+}
+
+// resources returns container resources from project's `k8s` settings, nil when none are set
+func resources(k8s *lib.K8sJob) *resourceRequirements {
+	if k8s == nil {
+		return nil
+	}
+	res := &resourceRequirements{Requests: make(map[string]string), Limits: make(map[string]string)}
+	set := func(m map[string]string, key, value string) {
+		if value != "" {
+			m[key] = value
+		}
+	}
+	set(res.Requests, "cpu", k8s.CPU)
+	set(res.Requests, "memory", k8s.Memory)
+	set(res.Limits, "cpu", k8s.CPULimit)
+	set(res.Limits, "memory", k8s.MemoryLimit)
+	if len(res.Requests) == 0 && len(res.Limits) == 0 {
+		return nil
+	}
+	return res
+}
+
+// projectCronJob returns CronJob running `gha2db_sync` of a single project
+// Connection settings and credentials are taken from "devstats" ConfigMap and Secret, so manifests don't contain them
+func projectCronJob(name string, proj *lib.Project, image, namespace string) (*cronJob, error) {
+	schedule, err := lib.ParseSyncSchedule(proj.SyncSchedule)
+	if err != nil {
+		return nil, err
+	}
+	// Projects start at different minutes, so they don't all hit databases at once
+	spec, err := schedule.CronSpec(8 + 7*proj.Order)
+	if err != nil {
+		return nil, err
+	}
+	env := []envVar{
+		{Name: "GHA2DB_PROJECT", Value: name},
+		{Name: "PG_DB", Value: proj.PDB},
+		{Name: "IDB_DB", Value: proj.IDB},
+	}
+	if proj.K8s != nil {
+		keys := []string{}
+		for key := range proj.K8s.Env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			env = append(env, envVar{Name: key, Value: proj.K8s.Env[key]})
+		}
+	}
+	labels := map[string]string{"app": "devstats", "project": lib.TruncToBytes(k8sName(name), 63)}
+	return &cronJob{
+		APIVersion: "batch/v1",
+		Kind:       "CronJob",
+		Metadata:   objectMeta{Name: jobName(name), Namespace: namespace, Labels: labels},
+		Spec: cronJobSpec{
+			Schedule: spec,
+			// `sync_schedule` is in UTC
+			TimeZone: "Etc/UTC",
+			// Next sync of a project doesn't start while the previous one runs (`gha2db_sync` lock would make it fail anyway)
+			ConcurrencyPolicy:          "Forbid",
+			SuccessfulJobsHistoryLimit: 1,
+			FailedJobsHistoryLimit:     3,
+			JobTemplate: jobTemplateSpec{
+				Spec: jobSpec{
+					// Failed sync is retried by the next scheduled run, which resumes it
+					BackoffLimit: 0,
+					Template: podTemplateSpec{
+						Metadata: objectMeta{Labels: labels},
+						Spec: podSpec{
+							RestartPolicy: "Never",
+							Containers: []container{
+								{
+									Name:    "sync",
+									Image:   image,
+									Command: []string{"gha2db_sync"},
+									Env:     env,
+									EnvFrom: []envFromSource{
+										{ConfigMapRef: &optionalRef{Name: "devstats", Optional: true}},
+										{SecretRef: &optionalRef{Name: "devstats", Optional: true}},
+									},
+									Resources: resources(proj.K8s),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// Outputs Kubernetes CronJob manifests syncing every enabled project from "projects.yaml" (in their "order")
+func k8sCronJobs(image, namespace string) {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+
+	// Read defined projects
+	data, err := ioutil.ReadFile(dataPrefix + ctx.ProjectsYaml)
+	lib.FatalOnError(err)
+	var projects lib.AllProjects
+	lib.FatalOnError(yaml.Unmarshal(data, &projects))
+	names := []string{}
+	for name, proj := range projects.Projects {
+		if !proj.Disabled {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return projects.Projects[names[i]].Order < projects.Projects[names[j]].Order })
+
+	docs := []string{}
+	for _, name := range names {
+		proj := projects.Projects[name]
+		// Every project runs on its own, `devstats` waits for `depends_on` but CronJobs don't
+		if len(proj.DependsOn) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %s depends on %s, schedule it after them\n", name, strings.Join(proj.DependsOn, ", "))
+		}
+		job, err := projectCronJob(name, &proj, image, namespace)
+		if err != nil {
+			lib.FatalOnError(fmt.Errorf("project '%s': %v", name, err))
+		}
+		doc, err := yaml.Marshal(job)
+		lib.FatalOnError(err)
+		docs = append(docs, fmt.Sprintf("# %s\n%s", name, doc))
+	}
+	fmt.Printf("%s", strings.Join(docs, "---\n"))
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Required image: k8s_cronjobs image [namespace]\n")
+		fmt.Printf("Outputs Kubernetes CronJob manifests syncing projects from projects.yaml, like: k8s_cronjobs devstats:latest devstats | kubectl apply -f -\n")
+		os.Exit(1)
+	}
+	namespace := ""
+	if len(os.Args) > 2 {
+		namespace = os.Args[2]
+	}
+	k8sCronJobs(os.Args[1], namespace)
+}
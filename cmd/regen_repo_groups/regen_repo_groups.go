@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	lib "devstats"
+)
+
+// repoGroup - single gha_repos row with its current and new repo group
+type repoGroup struct {
+	id       int64
+	name     string
+	current  *string
+	expected string
+}
+
+// Applies project's `repo_groups` rules from projects.yaml to all known repositories (in one transaction)
+// Repositories not matching any rule keep their current repo group (so manual SQL scripts can still set them)
+func regenRepoGroups() {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Needs GHA2DB_PROJECT variable set
+	if ctx.Project == "" {
+		lib.FatalOnError(
+			fmt.Errorf("you have to set project via GHA2DB_PROJECT environment variable"),
+		)
+	}
+	groups, err := lib.ProjectRepoGroups(&ctx)
+	lib.FatalOnError(err)
+	if groups == nil {
+		lib.Printf("Project %s has no repo_groups rules\n", ctx.Project)
+		return
+	}
+
+	// Connect to Postgres DB
+	con := lib.PgConn(&ctx)
+
+	tx, err := con.Begin()
+	lib.FatalOnError(err)
+	changed := []repoGroup{}
+	all, matched := 0, 0
+	rows := lib.QuerySQLTxWithErr(tx, &ctx, "select id, name, repo_group from gha_repos")
+	for rows.Next() {
+		var repo repoGroup
+		lib.FatalOnError(rows.Scan(&repo.id, &repo.name, &repo.current))
+		all++
+		group, ok := groups.Group(repo.name)
+		if !ok {
+			continue
+		}
+		matched++
+		if repo.current == nil || *repo.current != group {
+			repo.expected = group
+			changed = append(changed, repo)
+		}
+	}
+	lib.FatalOnError(rows.Err())
+	lib.FatalOnError(rows.Close())
+	lib.Printf("Repos: %d, matching rules: %d, to update: %d\n", all, matched, len(changed))
+	for _, repo := range changed {
+		current := "(none)"
+		if repo.current != nil {
+			current = *repo.current
+		}
+		if ctx.Debug > 0 || ctx.DryRun {
+			lib.Printf("%s: %s -> %s\n", repo.name, current, repo.expected)
+		}
+		if ctx.DryRun {
+			continue
+		}
+		lib.ExecSQLTxWithErr(
+			tx,
+			&ctx,
+			"update gha_repos set repo_group = "+lib.NValue(1)+" where id = "+lib.NValue(2)+" and name = "+lib.NValue(3),
+			repo.expected,
+			repo.id,
+			repo.name,
+		)
+	}
+	if ctx.DryRun {
+		lib.FatalOnError(tx.Rollback())
+		return
+	}
+	lib.FatalOnError(tx.Commit())
+	lib.Printf("Updated %d repos\n", len(changed))
+
+	// Repo group metrics of all periods can change with repo groups
+	if len(changed) > 0 {
+		cleared, err := lib.ClearMetricCache(con, &ctx, nil, nil)
+		if err != nil {
+			lib.Printf("Cannot clear metric cache: %v\n", err)
+		} else {
+			lib.Printf("Cleared %d cached metric results\n", cleared)
+		}
+	}
+}
+
+func main() {
+	dtStart := time.Now()
+	regenRepoGroups()
+	dtEnd := time.Now()
+	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -11,7 +15,123 @@ import (
 	lib "devstats"
 )
 
-func runq(sqlFile string, params []string) {
+// numericTypes - Postgres column types written as numbers in JSON output
+var numericTypes = map[string]bool{"INT2": true, "INT4": true, "INT8": true, "NUMERIC": true, "FLOAT4": true, "FLOAT8": true, "OID": true}
+
+// writeCSV writes results as CSV with header row, NULL is an empty value
+func writeCSV(w io.Writer, names []string, records [][]*string) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(names)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		row := make([]string, len(record))
+		for i, value := range record {
+			if value != nil {
+				row[i] = *value
+			}
+		}
+		err = cw.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeJSON writes results as JSON array of objects (keys are in columns order), NULL is null
+// Values of numeric columns are numbers, others are strings
+func writeJSON(w io.Writer, names []string, numeric []bool, records [][]*string) error {
+	keys := make([]string, len(names))
+	for i, name := range names {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		keys[i] = string(key)
+	}
+	lines := []string{}
+	for _, record := range records {
+		pairs := []string{}
+		for i, value := range record {
+			var data []byte
+			if value != nil && numeric[i] && json.Valid([]byte(*value)) {
+				data = []byte(*value)
+			} else {
+				var err error
+				data, err = json.Marshal(value)
+				if err != nil {
+					return err
+				}
+			}
+			pairs = append(pairs, keys[i]+": "+string(data))
+		}
+		lines = append(lines, "  {"+strings.Join(pairs, ", ")+"}")
+	}
+	if len(lines) == 0 {
+		_, err := fmt.Fprintf(w, "[]\n")
+		return err
+	}
+	_, err := fmt.Fprintf(w, "[\n%s\n]\n", strings.Join(lines, ",\n"))
+	return err
+}
+
+// markdownCell escapes value for a Markdown table cell
+func markdownCell(value string) string {
+	return strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>", "\r", "<br>").Replace(value)
+}
+
+// writeMarkdown writes results as Markdown (GitHub flavored) table, NULL is an empty cell
+func writeMarkdown(w io.Writer, names []string, records [][]*string) error {
+	cells := make([]string, len(names))
+	for i, name := range names {
+		cells[i] = markdownCell(name)
+	}
+	output := "| " + strings.Join(cells, " | ") + " |\n|" + strings.Repeat(" --- |", len(names)) + "\n"
+	for _, record := range records {
+		for i, value := range record {
+			cells[i] = ""
+			if value != nil {
+				cells[i] = markdownCell(*value)
+			}
+		}
+		output += "| " + strings.Join(cells, " | ") + " |\n"
+	}
+	_, err := io.WriteString(w, output)
+	return err
+}
+
+// writeResults writes results in `format` (csv, json or md) to `outFile`, stdout when it is empty
+func writeResults(format, outFile string, names []string, numeric []bool, records [][]*string) {
+	w := os.Stdout
+	if outFile != "" {
+		f, err := os.Create(outFile)
+		lib.FatalOnError(err)
+		defer func() { lib.FatalOnError(f.Close()) }()
+		w = f
+	}
+	var err error
+	switch format {
+	case "csv":
+		err = writeCSV(w, names, records)
+	case "json":
+		err = writeJSON(w, names, numeric, records)
+	case "md":
+		err = writeMarkdown(w, names, records)
+	default:
+		err = fmt.Errorf("unknown output format '%s', use csv, json or md", format)
+	}
+	lib.FatalOnError(err)
+	if outFile != "" {
+		lib.Printf("Rows: %v written to %s\n", len(records), outFile)
+	}
+}
+
+// runq runs `sqlFile` with given parameters (and parameters from `paramsFile` YAML or JSON, unless empty),
+// prints results as a table or writes them in `format` (csv, json or md) to `outFile`
+func runq(sqlFile, paramsFile string, params []string, format, outFile string) {
 	// Environment context parse
 	var ctx lib.Ctx
 	ctx.Init()
@@ -22,37 +142,68 @@ func runq(sqlFile string, params []string) {
 		os.Exit(1)
 	}
 
-	// SQL arguments parse
+	// Parameter values from file, overridden by command line
+	// Command line '{{name}}' sets parameter `name`, other strings are replaced as they are
+	values := make(map[string]string)
+	var err error
+	if paramsFile != "" {
+		values, err = lib.ReadSQLParamsFile(paramsFile)
+		lib.FatalOnError(err)
+	}
 	replaces := make(map[string]string)
 	paramName := ""
 	for index, param := range params {
 		if index%2 == 0 {
-			replaces[param] = ""
 			paramName = param
+			continue
+		}
+		if name, ok := lib.SQLParamName(paramName); ok {
+			values[name] = param
 		} else {
 			replaces[paramName] = param
-			paramName = ""
 		}
 	}
+	// Special replace 'qr' 'period,from,to' is used for {{period.alias.name}} replacements
+	qrValue, qr := replaces["qr"]
+	if !qr {
+		qrValue, qr = values["qr"]
+	}
+	delete(replaces, "qr")
+	delete(values, "qr")
+	qrPeriod := ""
+	qrFrom := ""
+	qrTo := ""
+	if qr {
+		qrAry := strings.Split(qrValue, ",")
+		if len(qrAry) != 3 {
+			lib.Printf("Special replace 'qr' needs 'period,from,to', got: '%s'\n", qrValue)
+			os.Exit(1)
+		}
+		qrPeriod, qrFrom, qrTo = qrAry[0], qrAry[1], qrAry[2]
+	}
 
 	// Read and eventually transform SQL file.
 	bytes, err := ioutil.ReadFile(sqlFile)
 	lib.FatalOnError(err)
 	sqlQuery := string(bytes)
-	qrPeriod := ""
-	qrFrom := ""
-	qrTo := ""
-	qr := false
 	for from, to := range replaces {
-		// Special replace 'qr' 'period,from,to' is used for {{period.alias.name}} replacements
-		if from == "qr" {
-			qrAry := strings.Split(to, ",")
-			qr = true
-			qrPeriod, qrFrom, qrTo = qrAry[0], qrAry[1], qrAry[2]
-			continue
-		}
 		sqlQuery = strings.Replace(sqlQuery, from, to, -1)
 	}
+	// Expand macros (util_sql/macros.yaml and project's macros.yaml), after replaces so they can replace macros
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+	macros, err := lib.ReadMacros(dataPrefix, sqlFile)
+	lib.FatalOnError(err)
+	sqlQuery, err = lib.ExpandMacros(sqlQuery, macros)
+	lib.FatalOnError(err)
+	// Parameters declared in SQL file header ("-- @param name[:type] [= default]") and {{name[:type]}} placeholders
+	// are set after macros expansion, because macros can use them
+	declared, err := lib.ParseSQLParams(sqlQuery)
+	lib.FatalOnError(err)
+	sqlQuery, err = lib.ApplySQLParams(sqlQuery, declared, values)
+	lib.FatalOnError(err)
 	if qr {
 		sqlQuery = lib.PrepareQuickRangeQuery(sqlQuery, qrPeriod, qrFrom, qrTo)
 	}
@@ -60,17 +211,25 @@ func runq(sqlFile string, params []string) {
 		sqlQuery = strings.Replace(sqlQuery, "select\n", "explain select\n", -1)
 	}
 
-	// Connect to Postgres DB
+	// Connect to Postgres DB, query that only reads runs on an up to date replica when there is one (PG_REPLICAS)
 	c := lib.PgConn(&ctx)
-	defer func() { lib.FatalOnError(c.Close()) }()
+	rc := lib.PgQueryConn(&ctx, sqlQuery)
 
 	// Execute SQL
-	rows := lib.QuerySQLWithErr(c, &ctx, sqlQuery)
+	dtStart := time.Now()
+	rows := lib.QuerySQLWithErr(rc, &ctx, sqlQuery)
 	defer func() { lib.FatalOnError(rows.Close()) }()
 
 	// Now unknown rows, with unknown types
 	columns, err := rows.Columns()
 	lib.FatalOnError(err)
+	names := append([]string{}, columns...)
+	types, err := rows.ColumnTypes()
+	lib.FatalOnError(err)
+	numeric := make([]bool, len(types))
+	for i, typ := range types {
+		numeric[i] = numericTypes[typ.DatabaseTypeName()]
+	}
 	// Make columns unique
 	for i := range columns {
 		columns[i] += strconv.Itoa(i)
@@ -82,24 +241,43 @@ func runq(sqlFile string, params []string) {
 		vals[i] = new([]byte)
 	}
 
-	// Get results into `results` array of maps
-	var results []map[string]string
+	// Get results into `results` array of maps (and `records`, NULL values are nil there)
+	var (
+		results []map[string]string
+		records [][]*string
+	)
 	rowCount := 0
 	for rows.Next() {
 		rowMap := make(map[string]string)
+		record := make([]*string, len(vals))
 		lib.FatalOnError(rows.Scan(vals...))
 		for index, val := range vals {
 			value := ""
 			if val != nil {
 				value = string(*val.(*[]byte))
+				if *val.(*[]byte) != nil {
+					record[index] = &value
+				}
 			}
 			rowMap[columns[index]] = value
 		}
 		results = append(results, rowMap)
+		records = append(records, record)
 		rowCount++
 	}
 	lib.FatalOnError(rows.Err())
 
+	// Slow query is explained, unless it already is an EXPLAIN (GHA2DB_EXPLAIN)
+	if !ctx.Explain {
+		lib.ExplainIfSlow(c, &ctx, "runq", filepath.Base(sqlFile), sqlQuery, time.Now().Sub(dtStart))
+	}
+
+	// Other formats than a table are written as they are, so they can be piped
+	if format != "" {
+		writeResults(format, outFile, names, numeric, records)
+		return
+	}
+
 	if len(results) < 1 {
 		lib.Printf("Metric returned no data\n")
 		return
@@ -183,12 +361,39 @@ func runq(sqlFile string, params []string) {
 
 func main() {
 	dtStart := time.Now()
-	if len(os.Args) < 2 {
+	// Output format and file options come before SQL file name
+	args := os.Args[1:]
+	format, outFile, paramsFile := "", "", ""
+	for len(args) > 1 && (args[0] == "-o" || args[0] == "-f" || args[0] == "-p") {
+		switch args[0] {
+		case "-o":
+			format = args[1]
+		case "-f":
+			outFile = args[1]
+		case "-p":
+			paramsFile = args[1]
+		}
+		args = args[2:]
+	}
+	if len(args) < 1 {
 		lib.Printf("Required SQL file name [param1 value1 [param2 value2 ...]]\n")
 		lib.Printf("Special replace 'qr' 'period,from,to' is used for {{period.alias.name}} replacements\n")
+		lib.Printf("Output as CSV, JSON or Markdown table (to stdout or file): runq [-o csv|json|md] [-f file] some.sql ...\n")
+		lib.Printf("Parameters from YAML or JSON file (command line values take precedence): runq -p params.yaml some.sql ...\n")
+		os.Exit(1)
+	}
+	if format != "" && format != "csv" && format != "json" && format != "md" {
+		lib.Printf("Unknown output format '%s', use csv, json or md\n", format)
+		os.Exit(1)
+	}
+	if outFile != "" && format == "" {
+		lib.Printf("Output file needs output format: -o csv|json|md\n")
 		os.Exit(1)
 	}
-	runq(os.Args[1], os.Args[2:])
-	dtEnd := time.Now()
-	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
+	runq(args[0], paramsFile, args[1:], format, outFile)
+	// Results written to stdout are not followed by logs
+	if format == "" || outFile != "" {
+		dtEnd := time.Now()
+		lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
+	}
 }
@@ -2,11 +2,14 @@ package main
 
 import (
 	"crypto"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/subtle"
 	"crypto/x509"
 	lib "devstats"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
@@ -15,7 +18,12 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	yaml "gopkg.in/yaml.v2"
 )
 
 // Payload signature verification based on:
@@ -277,12 +285,294 @@ func webhookHandler(w http.ResponseWriter, r *http.Request) {
 	respondWithSuccess(w, "ok")
 }
 
+// Sync run statuses
+const (
+	runQueued    = "queued"
+	runRunning   = "running"
+	runSucceeded = "succeeded"
+	runFailed    = "failed"
+)
+
+// maxQueuedRuns - syncs waiting to run, more requests are rejected
+const maxQueuedRuns = 100
+
+// maxFinishedRuns - finished runs kept for status polling, older ones are forgotten
+const maxFinishedRuns = 100
+
+// syncRun - on-demand sync of a single project requested via sync API
+type syncRun struct {
+	ID       string     `json:"id"`
+	Project  string     `json:"project"`
+	Metrics  string     `json:"metrics,omitempty"`
+	ResetIDB bool       `json:"reset_idb,omitempty"`
+	Status   string     `json:"status"`
+	Queued   time.Time  `json:"queued"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// syncRuns - on-demand syncs, they run one at a time in the order they were requested
+// Runs are only kept in memory, webhook restart forgets them (and drops queued ones)
+type syncRuns struct {
+	ctx      *lib.Ctx
+	mtx      sync.Mutex
+	runs     map[string]*syncRun
+	finished []string
+	queue    chan *syncRun
+}
+
+// newSyncRuns creates runs queue and starts the goroutine running them
+func newSyncRuns(ctx *lib.Ctx) *syncRuns {
+	s := &syncRuns{ctx: ctx, runs: make(map[string]*syncRun), queue: make(chan *syncRun, maxQueuedRuns)}
+	go s.worker()
+	return s
+}
+
+// newRunID returns random run ID
+func newRunID() (string, error) {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// respondWithJSON writes `v` as JSON response with HTTP status `code`
+func respondWithJSON(w http.ResponseWriter, code int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		code = http.StatusInternalServerError
+		data, _ = json.Marshal(map[string]string{"message": err.Error()})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
+
+// respondWithMessage writes JSON response with a single message and HTTP status `code`
+func respondWithMessage(w http.ResponseWriter, code int, m string) {
+	respondWithJSON(w, code, map[string]string{"message": m})
+}
+
+// authorized checks sync API token sent as "Authorization: Bearer <token>"
+func (s *syncRuns) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(auth[7:]), []byte(s.ctx.SyncAPIToken)) == 1
+}
+
+// readProject returns project `name` from "projects.yaml", it is read on every request so projects added since start are known
+func (s *syncRuns) readProject(name string) (*lib.Project, error) {
+	dataPrefix := lib.DataDir
+	if s.ctx.Local {
+		dataPrefix = "./"
+	}
+	data, err := ioutil.ReadFile(dataPrefix + s.ctx.ProjectsYaml)
+	if err != nil {
+		return nil, err
+	}
+	var projects lib.AllProjects
+	err = yaml.Unmarshal(data, &projects)
+	if err != nil {
+		return nil, err
+	}
+	proj, ok := projects.Projects[name]
+	if !ok {
+		return nil, nil
+	}
+	return &proj, nil
+}
+
+// status returns copy of run `id`, nil when it is not known
+func (s *syncRuns) status(id string) *syncRun {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return nil
+	}
+	cp := *run
+	return &cp
+}
+
+// enqueue queues sync of `project`, the same sync already waiting in the queue is returned instead of queuing another one
+// Returned bool is true when a new run was queued
+func (s *syncRuns) enqueue(project, metrics string, resetIDB bool) (*syncRun, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, run := range s.runs {
+		if run.Status == runQueued && run.Project == project && run.Metrics == metrics && run.ResetIDB == resetIDB {
+			cp := *run
+			return &cp, false, nil
+		}
+	}
+	id, err := newRunID()
+	if err != nil {
+		return nil, false, err
+	}
+	run := &syncRun{ID: id, Project: project, Metrics: metrics, ResetIDB: resetIDB, Status: runQueued, Queued: time.Now()}
+	select {
+	case s.queue <- run:
+	default:
+		return nil, false, fmt.Errorf("too many queued syncs (%d)", maxQueuedRuns)
+	}
+	s.runs[id] = run
+	cp := *run
+	return &cp, true, nil
+}
+
+// finish saves result of run and forgets the oldest finished runs
+func (s *syncRuns) finish(run *syncRun, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	now := time.Now()
+	run.Finished = &now
+	if err != nil {
+		run.Status = runFailed
+		run.Error = err.Error()
+	} else {
+		run.Status = runSucceeded
+	}
+	s.finished = append(s.finished, run.ID)
+	if len(s.finished) > maxFinishedRuns {
+		delete(s.runs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+// worker runs queued syncs one at a time
+func (s *syncRuns) worker() {
+	for run := range s.queue {
+		s.mtx.Lock()
+		now := time.Now()
+		run.Status = runRunning
+		run.Started = &now
+		s.mtx.Unlock()
+		lib.Printf("Sync API: running %s sync of %s\n", run.ID, run.Project)
+		err := s.runSync(run)
+		if err != nil {
+			lib.Printf("Sync API: %s sync of %s failed (took %v): %v\n", run.ID, run.Project, time.Now().Sub(now), err)
+		} else {
+			lib.Printf("Sync API: %s sync of %s finished, took %v\n", run.ID, run.Project, time.Now().Sub(now))
+		}
+		s.finish(run, err)
+	}
+}
+
+// runSync runs `gha2db_sync` of run's project
+func (s *syncRuns) runSync(run *syncRun) error {
+	// Project could be removed or disabled since the run was queued
+	proj, err := s.readProject(run.Project)
+	if err != nil {
+		return err
+	}
+	if proj == nil || proj.Disabled {
+		return fmt.Errorf("project '%s' is not defined or disabled", run.Project)
+	}
+	cmdPrefix := ""
+	if s.ctx.Local {
+		cmdPrefix = "./"
+	}
+	env := map[string]string{
+		"GHA2DB_PROJECT": run.Project,
+		"PG_DB":          proj.PDB,
+		"IDB_DB":         proj.IDB,
+	}
+	if run.Metrics != "" {
+		env["GHA2DB_METRICS"] = run.Metrics
+	}
+	if run.ResetIDB {
+		env["GHA2DB_RESETIDB"] = "1"
+	}
+	// Scheduled sync of the project can be running, wait for it instead of failing at once
+	if s.ctx.LockTimeout == 0 {
+		env["GHA2DB_LOCK_TIMEOUT"] = "3600"
+	}
+	ctx := *s.ctx
+	ctx.ExecFatal = false
+	_, err = lib.ExecCommand(&ctx, []string{cmdPrefix + "gha2db_sync"}, env)
+	return err
+}
+
+// syncHandler handles POST /sync/{project}, queues project's sync and returns its run
+// Optional `metrics` (see GHA2DB_METRICS) and `reset` (GHA2DB_RESETIDB) parameters recompute metrics after fixing them
+func (s *syncRuns) syncHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		respondWithMessage(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if r.Method != http.MethodPost {
+		respondWithMessage(w, http.StatusMethodNotAllowed, "use POST /sync/{project}")
+		return
+	}
+	project := strings.Trim(strings.TrimPrefix(r.URL.Path, "/sync/"), "/")
+	if project == "" || strings.Contains(project, "/") {
+		respondWithMessage(w, http.StatusNotFound, "use POST /sync/{project}")
+		return
+	}
+	proj, err := s.readProject(project)
+	if err != nil {
+		lib.Printf("Sync API: cannot read projects: %v\n", err)
+		respondWithMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if proj == nil {
+		respondWithMessage(w, http.StatusNotFound, fmt.Sprintf("project '%s' is not defined", project))
+		return
+	}
+	if proj.Disabled {
+		respondWithMessage(w, http.StatusConflict, fmt.Sprintf("project '%s' is disabled", project))
+		return
+	}
+	resetIDB := false
+	if reset := r.FormValue("reset"); reset != "" {
+		resetIDB, err = strconv.ParseBool(reset)
+		if err != nil {
+			respondWithMessage(w, http.StatusBadRequest, fmt.Sprintf("invalid reset '%s'", reset))
+			return
+		}
+	}
+	run, queued, err := s.enqueue(project, r.FormValue("metrics"), resetIDB)
+	if err != nil {
+		respondWithMessage(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if queued {
+		lib.Printf("Sync API: queued %s sync of %s (from %s)\n", run.ID, project, r.RemoteAddr)
+	}
+	respondWithJSON(w, http.StatusAccepted, run)
+}
+
+// runsHandler handles GET /runs/{id}, returns status of run
+func (s *syncRuns) runsHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		respondWithMessage(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if r.Method != http.MethodGet {
+		respondWithMessage(w, http.StatusMethodNotAllowed, "use GET /runs/{id}")
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/runs/"), "/")
+	run := s.status(id)
+	if run == nil {
+		respondWithMessage(w, http.StatusNotFound, fmt.Sprintf("run '%s' is not known", id))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, run)
+}
+
 func main() {
 	// Environment context parse
 	var ctx lib.Ctx
 	ctx.Init()
-	if ctx.ProjectRoot == "" {
+	if ctx.ProjectRoot == "" && ctx.SyncAPIToken == "" {
 		lib.Printf("You need to define reposiory path via GHA2DB_PROJECT_ROOT=/path/to/repo %s\n", os.Args[0])
+		lib.Printf("Or enable sync API via GHA2DB_SYNC_API_TOKEN=token %s\n", os.Args[0])
 		return
 	}
 
@@ -290,6 +580,14 @@ func main() {
 	// WebHookHost defaults to "127.0.0.1"
 	// WebHookPort defaults to ":1982"
 	// WebHookRoot defaults to "/"
-	http.HandleFunc(ctx.WebHookRoot, webhookHandler)
+	if ctx.ProjectRoot != "" {
+		http.HandleFunc(ctx.WebHookRoot, webhookHandler)
+	}
+	// On-demand syncs API
+	if ctx.SyncAPIToken != "" {
+		runs := newSyncRuns(&ctx)
+		http.HandleFunc("/sync/", runs.syncHandler)
+		http.HandleFunc("/runs/", runs.runsHandler)
+	}
 	_ = http.ListenAndServe(ctx.WebHookHost+ctx.WebHookPort, nil)
 }
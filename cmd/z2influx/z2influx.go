@@ -70,8 +70,8 @@ func z2influx(series, from, to, intervalAbbr string, desc bool, values []string)
 	dFrom := lib.TimeParseAny(from)
 	dTo := lib.TimeParseAny(to)
 
-	// Process interval
-	interval, _, intervalStart, nextIntervalStart, _ := lib.GetIntervalFunctions(intervalAbbr, false)
+	// Process interval, in the same time zone as db2influx
+	interval, _, intervalStart, nextIntervalStart, _ := lib.GetIntervalFunctionsIn(intervalAbbr, false, ctx.Location())
 
 	// Round dates to the given interval
 	dFrom = intervalStart(dFrom)
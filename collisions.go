@@ -0,0 +1,55 @@
+package devstats
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SeriesCollision - series written by more than one metric, `Metrics` are indexes of these metrics (in metrics.yaml order)
+type SeriesCollision struct {
+	Metrics []int
+	Series  []string
+}
+
+// SeriesCollisions returns series written by more than one metric, `targets[i]` are series written by i-th metric
+// Series of one metric can repeat (like histograms computed for many periods), they only collide with other metrics
+// Series written by the same metrics are grouped into one collision, collisions are sorted by metrics
+func SeriesCollisions(targets [][]string) (result []SeriesCollision) {
+	writers := make(map[string][]int)
+	for i, series := range targets {
+		for _, name := range series {
+			metrics := writers[name]
+			if len(metrics) > 0 && metrics[len(metrics)-1] == i {
+				continue
+			}
+			writers[name] = append(metrics, i)
+		}
+	}
+	groups := make(map[string]*SeriesCollision)
+	for name, metrics := range writers {
+		if len(metrics) < 2 {
+			continue
+		}
+		key := fmt.Sprint(metrics)
+		group, ok := groups[key]
+		if !ok {
+			group = &SeriesCollision{Metrics: metrics}
+			groups[key] = group
+		}
+		group.Series = append(group.Series, name)
+	}
+	for _, group := range groups {
+		sort.Strings(group.Series)
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].Metrics, result[j].Metrics
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return
+}
@@ -0,0 +1,45 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestSeriesCollisions(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		targets  [][]string
+		expected []lib.SeriesCollision
+	}{
+		{targets: nil, expected: nil},
+		{targets: [][]string{{"a_d", "a_w"}, {"b_d", "b_w"}}, expected: nil},
+		{
+			// Histogram computed for many periods writes the same series
+			targets:  [][]string{{"hist", "hist", "hist"}, {"b_d"}},
+			expected: nil,
+		},
+		{
+			targets: [][]string{{"a_d", "a_w"}, {"b_d"}, {"a_w", "a_d", "a_m"}},
+			expected: []lib.SeriesCollision{
+				{Metrics: []int{0, 2}, Series: []string{"a_d", "a_w"}},
+			},
+		},
+		{
+			targets: [][]string{{"x", "y"}, {"y", "z"}, {"x", "y", "z"}},
+			expected: []lib.SeriesCollision{
+				{Metrics: []int{0, 1, 2}, Series: []string{"y"}},
+				{Metrics: []int{0, 2}, Series: []string{"x"}},
+				{Metrics: []int{1, 2}, Series: []string{"z"}},
+			},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.SeriesCollisions(test.targets)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %+v, got %+v", index+1, test.expected, got)
+		}
+	}
+}
@@ -38,3 +38,6 @@ const EngineIsClosedError string = "engine is closed"
 
 // LocalGitScripts - common constant string
 const LocalGitScripts string = "./git/"
+
+// MetricTimeoutExitCode - exit code of `db2influx` when metric's query exceeded its timeout
+const MetricTimeoutExitCode int = 3
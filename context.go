@@ -22,9 +22,14 @@ type Ctx struct {
 	PgUser            string    // from PG_USER, default "gha_admin"
 	PgPass            string    // from PG_PASS, default "password"
 	PgSSL             string    // from PG_SSL, default "disable"
+	PgMaxConns        int       // from PG_MAX_CONNS, maximum number of open connections of process-wide pool of every database, default 0 - no limit
+	PgStmtTimeout     int       // from PG_STATEMENT_TIMEOUT, Postgres statement_timeout of all connections in seconds, default 0 - no timeout
+	PgReplicas        []string  // from PG_REPLICAS, comma separated list of read replicas ("host" or "host:port") used by db2influx and runq metric queries, `gha2db_sync` sets it from project's `replicas`, default "" - primary only
+	PgReplicaWait     int       // from PG_REPLICA_WAIT, seconds to wait for a replica to replay all primary's changes before its reads fall back to primary, default 30
 	Index             bool      // from GHA2DB_INDEX Create DB index? default false
 	Table             bool      // from GHA2DB_SKIPTABLE Create table structure? default true
 	Tools             bool      // from GHA2DB_SKIPTOOLS Create DB tools (like views, summary tables, materialized views etc)? default true
+	Partition         bool      // from GHA2DB_PARTITION, structure creates gha_events, gha_issues_events_labels and gha_texts partitioned by month (or migrates them), default false
 	Mgetc             string    // from GHA2DB_MGETC Character returned by mgetc (if non empty), default ""
 	IDBHost           string    // from IDB_HOST, default "http://localhost"
 	IDBPort           string    // form IDB_PORT, default 8086
@@ -32,25 +37,77 @@ type Ctx struct {
 	IDBUser           string    // from IDB_USER, default "gha_admin"
 	IDBPass           string    // from IDB_PASS, default "password"
 	IDBMaxBatchPoints int       // from IDB_MAXBATCHPONTS, all Influx related tools, default 10240 (10k)
+	IDBMaxMemPoints   int       // from IDB_MAXMEMPOINTS, all Influx related tools, full batches are written when they hold that many points (db2influx doesn't cache bigger query results), default 102400 (100k), 0 - no limit
 	QOut              bool      // from GHA2DB_QOUT output all SQL queries?, default false
 	CtxOut            bool      // from GHA2DB_CTXOUT output all context data (this struct), default false
 	LogTime           bool      // from GHA2DB_SKIPTIME, output time with all lib.Printf(...) calls, default true, use GHA2DB_SKIPTIME to disable
 	DefaultStartDate  time.Time // from GHA2DB_STARTDT, default `2014-06-01 00:00 UTC`, expects format "YYYY-MM-DD HH:MI:SS", can be set in `projects.yaml` via `start_date:`, value from projects.yaml (if set) has the highest priority.
+	TimeZone          string    // from GHA2DB_TIMEZONE (like "Europe/Warsaw"), day, week, month, ... periods of metrics and annotations start at midnight of this time zone, `gha2db_sync` sets it from project's `time_zone`, default "" - UTC
 	LastSeries        string    // from GHA2DB_LASTSERIES, use this InfluxDB series to determine last timestamp date, default "events_h"
 	SkipIDB           bool      // from GHA2DB_SKIPIDB gha2db_sync tool, skip Influx DB processing? for db2influx it skips final series write, default false
 	SkipPDB           bool      // from GHA2DB_SKIPPDB gha2db_sync tool, skip Postgres DB processing? default false
+	CatchUpHours      int       // from GHA2DB_CATCHUP_HOURS gha2db_sync tool, catch-up mode when project is more hours behind, default 24, 0 disables it
 	ResetIDB          bool      // from GHA2DB_RESETIDB sync tool, regenerate all InfluxDB points? default false
 	ResetRanges       bool      // from GHA2DB_RESETRANGES sync tool, regenerate all past quick ranges? default false
+	ForceCompute      bool      // from GHA2DB_FORCE_COMPUTE db2influx tool, recompute histograms even when their query hash (SQL, parameters, last event date) didn't change, default false
+	FullBackfill      bool      // from GHA2DB_FULL_BACKFILL sync tool, compute full history of metrics that only recompute their last periods (`recompute` in metrics.yaml), default false
+	ExplainSlow       int       // from GHA2DB_EXPLAIN_SLOW db2influx and runq tools, seconds (or duration like "90s", "2m") after which EXPLAIN (ANALYZE, BUFFERS) of query is saved in gha_slow_queries, default 0 - disabled
+	SkipMetricCache   bool      // from GHA2DB_SKIP_METRIC_CACHE db2influx tool, don't use nor save cached metric query results (gha_metric_cache), default false
+	MetricCacheTTL    int       // from GHA2DB_METRIC_CACHE_TTL db2influx tool, seconds cached result of the still open period is used, default 600, 0 - only closed periods are cached
+	LockTimeout       int       // from GHA2DB_LOCK_TIMEOUT sync tool, seconds to wait for other sync of the same project database to finish, default 0 - fail at once
+	OnlyMetrics       []string  // from GHA2DB_METRICS sync tool, comma separated list of metrics to compute (metric names, SQL file names or "tag:name"), other metrics (and their gaps) are skipped, default "" - all
+	SyncByOrder       bool      // from GHA2DB_SYNC_BY_ORDER devstats tool, sync projects in their "order" instead of the most stale (oldest last event) first, default false
+	SyncParallel      int       // from GHA2DB_SYNC_PARALLEL devstats tool, maximum number of projects synced at once (limited by number of CPUs), default 1 - one by one
+	MetricsParallel   int       // from GHA2DB_METRICS_PARALLEL sync tool, maximum number of metrics computed at once (metrics that don't depend on each other), default 1 - one by one
+	DBParallel        int       // from GHA2DB_DB_PARALLEL db2influx tool, maximum number of metric queries run at once against one database by all processes on this host, default 0 - no limit
+	DaemonInterval    int       // from GHA2DB_DAEMON_INTERVAL devstats tool, minutes between syncs of all projects in `devstats --daemon` mode, default 20
+	GitParallel       int       // from GHA2DB_GIT_PARALLEL get_repos tool, maximum number of repos cloned, pulled or analyzed at once by all processes on this host, default 0 - no limit
 	Explain           bool      // from GHA2DB_EXPLAIN runq tool, prefix query with "explain " - it will display query plan instead of executing real query, default false
 	OldFormat         bool      // from GHA2DB_OLDFMT gha2db tool, if set then use pre 2015 GHA JSONs format
 	Exact             bool      // From GHA2DB_EXACT gha2db tool, if set then orgs list provided from commandline is used as a list of exact repository full names, like "a/b,c/d,e", if not only full names "a/b,x/y" can be treated like this, names without "/" are either orgs or repos.
+	Resume            bool      // From GHA2DB_RESUME gha2db tool, skip hours already ingested (finished in `gha_checkpoints` table) and process unfinished ones again, default false
+	ArchiveDir        string    // From GHA2DB_ARCHIVE_DIR gha2db tool, directory with local GHA files (YYYY-MM-DD-H.json.gz, .json.zst or .json) used instead of downloading them, default "" - always download
+	ArchiveURL        string    // From GHA2DB_ARCHIVE_URL gha2db tool, GHA files URL template, {{date}} is replaced with YYYY-MM-DD-H, can point to mirror with zstd files, default "http://data.githubarchive.org/{{date}}.json.gz"
+	ArchiveMirrors    []string  // From GHA2DB_ARCHIVE_MIRRORS gha2db tool, comma separated list of GHA files URL templates (or base URLs) tried in order when GHA2DB_ARCHIVE_URL fails, default "" - no mirrors
+	MirrorFailures    int       // From GHA2DB_MIRROR_FAILURES gha2db tool, number of failures in a row after which archive URL or mirror is tried last for the rest of the run, default 3
+	ArchiveChecksums  string    // From GHA2DB_ARCHIVE_CHECKSUMS gha2db tool, URL template of published GHA files checksums (MD5 or SHA256, "sha256sum" format), {{date}} is replaced with YYYY-MM-DD-H, default "" - archives are only checked for completeness
+	ArchiveRetries    int       // From GHA2DB_ARCHIVE_RETRIES gha2db tool, number of downloads retried when GHA file is broken (truncated or checksum mismatch), default 2
+	QuarantineDir     string    // From GHA2DB_QUARANTINE_DIR gha2db tool, directory where GHA files still broken after all retries are saved for inspection, default "" - not saved
+	ArchiveCacheDir   string    // From GHA2DB_ARCHIVE_CACHE_DIR gha2db tool, directory where downloaded GHA files are cached (can be shared by many projects), default "" - no cache
+	ArchiveCacheSize  int       // From GHA2DB_ARCHIVE_CACHE_SIZE gha2db tool, maximum GHA files cache size in MB, least recently used files are removed, default 0 - no limit
+	ArchiveBucket     string    // From GHA2DB_ARCHIVE_BUCKET gha2db tool, S3 or GCS bucket with GHA files ("s3://bucket/prefix" or "gs://bucket/prefix") read before downloading them, default "" - no bucket
+	BucketMirror      bool      // From GHA2DB_ARCHIVE_BUCKET_MIRROR gha2db tool, save GHA files downloaded from GHA2DB_ARCHIVE_URL in the bucket too, default false
+	BucketEndpoint    string    // From GHA2DB_ARCHIVE_BUCKET_ENDPOINT gha2db tool, S3 compatible API URL (like MinIO), default "https://s3.{{region}}.amazonaws.com" for S3 and "https://storage.googleapis.com" for GCS
+	BucketRegion      string    // From GHA2DB_ARCHIVE_BUCKET_REGION gha2db tool, bucket region used to sign requests, default AWS_REGION, "" - "us-east-1" for S3 and "auto" for GCS
+	BucketKeyID       string    // From GHA2DB_ARCHIVE_BUCKET_KEY_ID gha2db tool, access key ID (GCS HMAC key), default AWS_ACCESS_KEY_ID, "" - anonymous access to public bucket
+	BucketSecret      string    // From GHA2DB_ARCHIVE_BUCKET_SECRET gha2db tool, secret access key (GCS HMAC secret), default AWS_SECRET_ACCESS_KEY
+	BigQuery          bool      // From GHA2DB_BIGQUERY gha2db tool, read events from public githubarchive BigQuery dataset (one `bq` query per day) instead of downloading GHA files, default false
+	EventTypes        []string  // From GHA2DB_EVENT_TYPES gha2db tool, comma separated list of event types to save (like "IssuesEvent,PullRequestEvent,PushEvent"), other events are skipped before saving, `gha2db_sync` sets it from project's `event_types`, default "" - all
+	BotsYaml          string    // From GHA2DB_BOTS_YAML gha2db tool, global bots list (logins and login regexp patterns) used to set `gha_events`.`is_bot`, projects can add their own bots in projects.yaml, default "bots.yaml"
+	BulkSize          int       // From GHA2DB_BULK_SIZE gha2db tool, number of rows per table saved at once using COPY (falls back to multi-row INSERT), default 1000
+	DryRun            bool      // From GHA2DB_DRY_RUN gha2db tool, download and parse hours, report matching events per hour and estimated rows, but write nothing to the database (dedup_events tool only reports duplicates), default false
+	PayloadOverflow   bool      // From GHA2DB_PAYLOAD_OVERFLOW gha2db tool, save payload fields unknown to devstats (added by GitHub later) in `gha_payloads`.`overflow` JSONB column and report unknown fields seen, default false
+	RawJSON           bool      // From GHA2DB_RAW_JSON gha2db tool, save full event JSON in `gha_payloads`.`raw` JSONB column, `gha2db_sync` sets it from project's `raw_json`, default false
+	RawJSONDays       int       // From GHA2DB_RAW_JSON_DAYS gha2db tool, keep raw event JSON only for events from last N days (older ones are cleared after every run), `gha2db_sync` sets it from project's `raw_json_days`, default 0 - keep forever
+	DedupCache        int       // From GHA2DB_DEDUP_CACHE gha2db tool, number of recently parsed event IDs remembered to skip events duplicated in adjacent GHA hours, default 500000
+	ProgressInterval  int       // From GHA2DB_PROGRESS_INTERVAL gha2db tool, seconds between progress lines (hours done, events and rows per second, ETA), default 60
+	GitLab            bool      // From GHA2DB_GITLAB gha2db tool, read events of GitLab projects (orgs are GitLab groups or full project paths) from GitLab REST API instead of GHA files, default false
+	GitLabURL         string    // From GHA2DB_GITLAB_URL gha2db tool, GitLab REST API URL, default "https://gitlab.com/api/v4"
+	GitLabToken       string    // From GHA2DB_GITLAB_TOKEN gha2db tool, GitLab API token (if it contains "/" it is a file to read token from), default "" - public access
+	GiteaURL          string    // From GHA2DB_GITEA_URL gha2db tool, read events from REST API of Gitea/Forgejo instance with this base URL (orgs are organizations, users or full repository names) instead of GHA files, default "" - not used
+	GiteaToken        string    // From GHA2DB_GITEA_TOKEN gha2db tool, Gitea/Forgejo API token (if it contains "/" it is a file to read token from), default "" - public access
+	GerritURL         string    // From GHA2DB_GERRIT_URL gha2db tool, read changes from REST API of Gerrit with this URL (orgs are project prefixes or full project names) instead of GHA files, default "" - not used
+	GerritToken       string    // From GHA2DB_GERRIT_TOKEN gha2db tool, Gerrit "username:HTTP password" (if it contains "/" it is a file to read it from), default "" - anonymous access
 	LogToDB           bool      // From GHA2DB_SKIPLOG all tools, if set, DB logging into Postgres table `gha_logs` in `devstats` database will be disabled
 	Local             bool      // From GHA2DB_LOCAL gha2db_sync tool, if set, gha2_db will call other tools prefixed with "./" to use local compile ones. Otherwise it will call binaries without prefix (so it will use thos ein /usr/bin/).
 	MetricsYaml       string    // From GHA2DB_METRICS_YAML gha2db_sync tool, set other metrics.yaml file, default is "metrics/{{project}}metrics.yaml"
 	GapsYaml          string    // From GHA2DB_GAPS_YAML gha2db_sync tool, set other gaps.yaml file, default is "metrics/{{project}}/gaps.yaml"
 	TagsYaml          string    // From GHA2DB_TAGS_YAML idb_tags tool, set other idb_tags.yaml file, default is "metrics/{{project}}/idb_tags.yaml"
+	RetentionYaml     string    // From GHA2DB_RETENTION_YAML idb_retention tool, set other retention.yaml file, default is "metrics/{{project}}/retention.yaml"
 	GitHubOAuth       string    // From GHA2DB_GITHUB_OAUTH annotations tool, if not set reads from /etc/github/oauth file, set to "-" to force public access.
 	ClearDBPeriod     string    // From GHA2DB_MAXLOGAGE gha2db_sync tool, maximum age of devstats.gha_logs entries, default "1 week"
+	MetricRunsPeriod  string    // From GHA2DB_MAXMETRICRUNAGE gha2db_sync tool, maximum age of gha_metric_runs entries, default "1 year"
+	MetricCachePeriod string    // From GHA2DB_MAXMETRICCACHEAGE gha2db_sync tool, maximum age of gha_metric_cache entries, default "3 months"
 	Trials            []int     // From GHA2DB_TRIALS, all Postgres related tools, retry periods for "too many connections open" error
 	WebHookRoot       string    // From GHA2DB_WHROOT, webhook tool, default "/hook", must match .travis.yml notifications webhooks
 	WebHookPort       string    // From GHA2DB_WHPORT, webhook tool, default ":1982", note that webhook listens using http:1982, but we use apache on https:2982 (to enable https protocol and proxy requests to http:1982)
@@ -60,7 +117,8 @@ type Ctx struct {
 	DeployStatuses    []string  // From GHA2DB_DEPLOY_STATUSES, webhook tool, default "Passed,Fixed", - comma separated list
 	DeployResults     []int     // From GHA2DB_DEPLOY_RESULTS, webhook tool, default "0", - comma separated list
 	DeployTypes       []string  // From GHA2DB_DEPLOY_TYPES, webhook tool, default "push", - comma separated list
-	ProjectRoot       string    // From GHA2DB_PROJECT_ROOT, webhook tool, no default, must be specified to run webhook tool
+	ProjectRoot       string    // From GHA2DB_PROJECT_ROOT, webhook tool, no default, must be specified to run webhook tool (unless only sync API is used)
+	SyncAPIToken      string    // From GHA2DB_SYNC_API_TOKEN, webhook tool, default "" - sync API disabled, callers of POST /sync/{project} send it as "Authorization: Bearer <token>"
 	ExecFatal         bool      // default true, set this manually to false to avoid lib.ExecCommand calling os.Exit() on failure and return error instead
 	ExecQuiet         bool      // default false, set this manually to true to have quite exec failures (for example `get_repos` git-clones or git-pulls on errors).
 	ExecOutput        bool      // default false, set to true to capture commands STDOUT
@@ -70,8 +128,43 @@ type Ctx struct {
 	ProcessRepos      bool      // From GHA2DB_PROCESS_REPOS ./get_repos tool, enable processing (cloning/pulling) all devstats repos, default false
 	ProcessCommits    bool      // From GHA2DB_PROCESS_COMMITS ./get_repos tool, enable update/create mapping table: commit - list of file that commit refers to, default false
 	ExternalInfo      bool      // From GHA2DB_EXTERNAL_INFO ./get_repos tool, enable outputing data needed by external tools (cncf/gitdm), default false
+	ExternalInfoFile  string    // From GHA2DB_EXTERNAL_INFO_FILE ./get_repos tool, write external info with per repo status to this file, JSON or YAML (for .yaml/.yml files), default "" - don't write
 	ProjectsCommits   string    // From GHA2DB_PROJECTS_COMMITS ./get_repos tool, set list of projects for commits analysis instead of analysing all, default "" - means all
 	ProjectsYaml      string    // From GHA2DB_PROJECTS_YAML, many tool - set main projects file, default "projects.yaml"
+	ShallowClone      int       // From GHA2DB_SHALLOW_CLONE ./get_repos tool, clone repos using `git clone --depth N`, repos are unshallowed when commits processing needs older history, default 0 - means full clone
+	PartialClone      bool      // From GHA2DB_PARTIAL_CLONE ./get_repos tool, clone repos using `git clone --filter=blob:none`, file contents are fetched on demand, default false
+	MirrorClone       bool      // From GHA2DB_MIRROR_CLONE ./get_repos tool, create bare (mirror) clones without working tree, existing clones are kept as they are, default false
+	GitToken          string    // From GHA2DB_GIT_TOKEN ./get_repos tool, GitHub token used to clone/pull repos via https (if it contains "/" it is a file to read token from), default "" - anonymous access
+	GitSSHKey         string    // From GHA2DB_GIT_SSH_KEY ./get_repos tool, path to SSH private key, if set repos are cloned via "git@github.com:org/repo.git", default "" - use https
+	CloneRetries      int       // From GHA2DB_CLONE_RETRIES ./get_repos tool, number of retries for failed clones/pulls, default 2
+	CloneBackoff      int       // From GHA2DB_CLONE_BACKOFF ./get_repos tool, initial delay in seconds between clone/pull retries, doubled on every retry with random jitter added, default 5
+	ClonesPerMinute   int       // From GHA2DB_CLONES_PER_MINUTE ./get_repos tool, maximum number of git clones/pulls started per minute (shared by all threads), default 0 - no limit
+	CloneJitter       int       // From GHA2DB_CLONE_JITTER ./get_repos tool, maximum random delay in milliseconds before processing each repo, default 0
+	Fsck              bool      // From GHA2DB_FSCK ./get_repos tool, run `git fsck` on existing clones before pull (corrupted clones are always re-cloned), default false - only check HEAD commit
+	PruneRepos        bool      // From GHA2DB_PRUNE_REPOS ./get_repos tool, remove clones of repos no longer present in any project, default false
+	PruneDryRun       bool      // From GHA2DB_PRUNE_DRY_RUN ./get_repos tool, only report repos that would be pruned, default false
+	DiskQuota         int       // From GHA2DB_DISK_QUOTA ./get_repos tool, maximum disk space in MB used by all repos, new repos that would exceed it are not cloned, default 0 - no limit
+	OrgDiskQuota      int       // From GHA2DB_ORG_DISK_QUOTA ./get_repos tool, maximum disk space in MB used by a single org's repos, default 0 - no limit
+	DiskUsage         bool      // From GHA2DB_DISK_USAGE ./get_repos tool, output disk usage per org after processing repos, default false
+	DetectRenames     bool      // From GHA2DB_DETECT_RENAMES ./get_repos tool, detect renamed/transferred repos (by following GitHub redirects) and save them in `gha_repos_renames` table, default false
+	DefaultBranches   bool      // From GHA2DB_DEFAULT_BRANCHES ./get_repos tool, track repos default branches (clones follow them) and save them in `gha_repos_default_branches` table, default false
+	Submodules        bool      // From GHA2DB_SUBMODULES ./get_repos tool, recursively init/update git submodules after clone/pull, default false
+	DetectLanguages   bool      // From GHA2DB_DETECT_LANGUAGES ./get_repos tool, compute bytes per language (by file names and extensions) of each repo and save them in `gha_repos_languages` table, default false
+	DetectLicenses    bool      // From GHA2DB_DETECT_LICENSES ./get_repos tool, classify LICENSE/COPYING files of each repo (SPDX ids) and save them in `gha_repos_licenses` table, default false
+	ProcessOwners     bool      // From GHA2DB_PROCESS_OWNERS ./get_repos tool, parse CODEOWNERS and Kubernetes OWNERS files of each repo and save them in `gha_repos_owners` table, default false
+	FilesChurn        bool      // From GHA2DB_FILES_CHURN ./get_repos tool, aggregate per file churn (commits, lines added/removed, last author) from `git log` into `gha_files_churn` table incrementally, default false
+	TarballFallback   bool      // From GHA2DB_TARBALL_FALLBACK ./get_repos tool, when git clone fails download GitHub tarball of the default branch instead (snapshot only, no history), default false
+	IncrementalRepos  bool      // From GHA2DB_INCREMENTAL_REPOS ./get_repos tool, skip databases (and orgs) without new events since last successful run (`gha_watermarks` table), default false
+	FailFast          bool      // From GHA2DB_FAIL_FAST ./get_repos tool, stop processing repos/commits on the first failure, default false - process all and report failures
+	NotifySlack       string    // From GHA2DB_NOTIFY_SLACK gha2db_sync and devstats tools, Slack incoming webhook URL run failures are posted to, default "" - not used
+	NotifyWebhook     string    // From GHA2DB_NOTIFY_WEBHOOK gha2db_sync and devstats tools, HTTP endpoint run failures are posted to (as JSON), default "" - not used
+	NotifyEmails      []string  // From GHA2DB_NOTIFY_EMAIL gha2db_sync and devstats tools, comma separated list of e-mails run failures are sent to, default "" - not used
+	Pushgateway       string    // From GHA2DB_PUSHGATEWAY gha2db_sync tool, Prometheus Pushgateway URL to push sync metrics to, default "" - don't push
+	NotifySuccess     bool      // From GHA2DB_NOTIFY_SUCCESS gha2db_sync and devstats tools, notify about successful runs too, default false - only failures
+	SMTPServer        string    // From GHA2DB_SMTP_SERVER, "host:port" of SMTP server used to send notification e-mails, default "localhost:25"
+	SMTPUser          string    // From GHA2DB_SMTP_USER, SMTP user (PLAIN authentication), default "" - no authentication
+	SMTPPassword      string    // From GHA2DB_SMTP_PASSWORD, SMTP password
+	SMTPFrom          string    // From GHA2DB_SMTP_FROM, sender of notification e-mails, default "devstats@localhost"
 }
 
 // Init - get context from environment variables
@@ -143,6 +236,39 @@ func (ctx *Ctx) Init() {
 	if ctx.PgSSL == "" {
 		ctx.PgSSL = "disable"
 	}
+	ctx.PgMaxConns = 0
+	if os.Getenv("PG_MAX_CONNS") != "" {
+		maxConns, err := strconv.Atoi(os.Getenv("PG_MAX_CONNS"))
+		FatalOnError(err)
+		if maxConns > 0 {
+			ctx.PgMaxConns = maxConns
+		}
+	}
+	ctx.PgStmtTimeout = 0
+	if os.Getenv("PG_STATEMENT_TIMEOUT") != "" {
+		stmtTimeout, err := strconv.Atoi(os.Getenv("PG_STATEMENT_TIMEOUT"))
+		FatalOnError(err)
+		if stmtTimeout > 0 {
+			ctx.PgStmtTimeout = stmtTimeout
+		}
+	}
+	replicas := os.Getenv("PG_REPLICAS")
+	if replicas != "" {
+		for _, replica := range strings.Split(replicas, ",") {
+			replica = strings.TrimSpace(replica)
+			if replica != "" {
+				ctx.PgReplicas = append(ctx.PgReplicas, replica)
+			}
+		}
+	}
+	ctx.PgReplicaWait = 30
+	if os.Getenv("PG_REPLICA_WAIT") != "" {
+		replicaWait, err := strconv.Atoi(os.Getenv("PG_REPLICA_WAIT"))
+		FatalOnError(err)
+		if replicaWait >= 0 {
+			ctx.PgReplicaWait = replicaWait
+		}
+	}
 
 	// Influx DB
 	ctx.IDBHost = os.Getenv("IDB_HOST")
@@ -180,10 +306,21 @@ func (ctx *Ctx) Init() {
 		}
 	}
 
+	// IDBMaxMemPoints
+	ctx.IDBMaxMemPoints = 102400
+	if os.Getenv("IDB_MAXMEMPOINTS") != "" {
+		maxMemPoints, err := strconv.Atoi(os.Getenv("IDB_MAXMEMPOINTS"))
+		FatalOnError(err)
+		if maxMemPoints >= 0 {
+			ctx.IDBMaxMemPoints = maxMemPoints
+		}
+	}
+
 	// Environment controlling index creation, table & tools
 	ctx.Index = os.Getenv("GHA2DB_INDEX") != ""
 	ctx.Table = os.Getenv("GHA2DB_SKIPTABLE") == ""
 	ctx.Tools = os.Getenv("GHA2DB_SKIPTOOLS") == ""
+	ctx.Partition = os.Getenv("GHA2DB_PARTITION") != ""
 	ctx.Mgetc = os.Getenv("GHA2DB_MGETC")
 	if len(ctx.Mgetc) > 1 {
 		ctx.Mgetc = ctx.Mgetc[:1]
@@ -199,6 +336,13 @@ func (ctx *Ctx) Init() {
 		ctx.DefaultStartDate = time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)
 	}
 
+	// Periods' time zone
+	ctx.TimeZone = os.Getenv("GHA2DB_TIMEZONE")
+	if ctx.TimeZone != "" {
+		_, err := time.LoadLocation(ctx.TimeZone)
+		FatalOnError(err)
+	}
+
 	// Last InfluxDB series
 	ctx.LastSeries = os.Getenv("GHA2DB_LASTSERIES")
 	if ctx.LastSeries == "" {
@@ -209,10 +353,108 @@ func (ctx *Ctx) Init() {
 	ctx.SkipIDB = os.Getenv("GHA2DB_SKIPIDB") != ""
 	ctx.ResetIDB = os.Getenv("GHA2DB_RESETIDB") != ""
 	ctx.ResetRanges = os.Getenv("GHA2DB_RESETRANGES") != ""
+	ctx.ForceCompute = os.Getenv("GHA2DB_FORCE_COMPUTE") != ""
+	ctx.FullBackfill = os.Getenv("GHA2DB_FULL_BACKFILL") != ""
+	ctx.SkipMetricCache = os.Getenv("GHA2DB_SKIP_METRIC_CACHE") != ""
+	if explainSlow := os.Getenv("GHA2DB_EXPLAIN_SLOW"); explainSlow != "" {
+		seconds, err := strconv.Atoi(explainSlow)
+		if err != nil {
+			var d time.Duration
+			d, err = time.ParseDuration(explainSlow)
+			seconds = int(d / time.Second)
+		}
+		FatalOnError(err)
+		ctx.ExplainSlow = seconds
+	}
+	ctx.MetricCacheTTL = 600
+	if os.Getenv("GHA2DB_METRIC_CACHE_TTL") != "" {
+		metricCacheTTL, err := strconv.Atoi(os.Getenv("GHA2DB_METRIC_CACHE_TTL"))
+		FatalOnError(err)
+		if metricCacheTTL >= 0 {
+			ctx.MetricCacheTTL = metricCacheTTL
+		}
+	}
+	onlyMetrics := os.Getenv("GHA2DB_METRICS")
+	if onlyMetrics != "" {
+		for _, metric := range strings.Split(onlyMetrics, ",") {
+			metric = strings.TrimSpace(metric)
+			if metric != "" {
+				ctx.OnlyMetrics = append(ctx.OnlyMetrics, metric)
+			}
+		}
+	}
 
 	// Postgres DB variables
 	ctx.SkipPDB = os.Getenv("GHA2DB_SKIPPDB") != ""
 
+	// Catch-up mode after downtime
+	ctx.CatchUpHours = 24
+	if os.Getenv("GHA2DB_CATCHUP_HOURS") != "" {
+		catchUpHours, err := strconv.Atoi(os.Getenv("GHA2DB_CATCHUP_HOURS"))
+		FatalOnError(err)
+		if catchUpHours >= 0 {
+			ctx.CatchUpHours = catchUpHours
+		}
+	}
+
+	// Parallel projects sync
+	ctx.SyncByOrder = os.Getenv("GHA2DB_SYNC_BY_ORDER") != ""
+	ctx.SyncParallel = 1
+	if os.Getenv("GHA2DB_SYNC_PARALLEL") != "" {
+		syncParallel, err := strconv.Atoi(os.Getenv("GHA2DB_SYNC_PARALLEL"))
+		FatalOnError(err)
+		if syncParallel > 0 {
+			ctx.SyncParallel = syncParallel
+		}
+	}
+
+	// Parallel metrics computation
+	ctx.MetricsParallel = 1
+	if os.Getenv("GHA2DB_METRICS_PARALLEL") != "" {
+		metricsParallel, err := strconv.Atoi(os.Getenv("GHA2DB_METRICS_PARALLEL"))
+		FatalOnError(err)
+		if metricsParallel > 0 {
+			ctx.MetricsParallel = metricsParallel
+		}
+	}
+
+	// Daemon mode sync interval
+	ctx.DaemonInterval = 20
+	if os.Getenv("GHA2DB_DAEMON_INTERVAL") != "" {
+		daemonInterval, err := strconv.Atoi(os.Getenv("GHA2DB_DAEMON_INTERVAL"))
+		FatalOnError(err)
+		if daemonInterval > 0 {
+			ctx.DaemonInterval = daemonInterval
+		}
+	}
+
+	// Parallel database queries and git operations (shared by all processes)
+	ctx.DBParallel = 0
+	if os.Getenv("GHA2DB_DB_PARALLEL") != "" {
+		dbParallel, err := strconv.Atoi(os.Getenv("GHA2DB_DB_PARALLEL"))
+		FatalOnError(err)
+		if dbParallel > 0 {
+			ctx.DBParallel = dbParallel
+		}
+	}
+	ctx.GitParallel = 0
+	if os.Getenv("GHA2DB_GIT_PARALLEL") != "" {
+		gitParallel, err := strconv.Atoi(os.Getenv("GHA2DB_GIT_PARALLEL"))
+		FatalOnError(err)
+		if gitParallel > 0 {
+			ctx.GitParallel = gitParallel
+		}
+	}
+
+	// Sync lock
+	if os.Getenv("GHA2DB_LOCK_TIMEOUT") != "" {
+		lockTimeout, err := strconv.Atoi(os.Getenv("GHA2DB_LOCK_TIMEOUT"))
+		FatalOnError(err)
+		if lockTimeout > 0 {
+			ctx.LockTimeout = lockTimeout
+		}
+	}
+
 	// Explain
 	ctx.Explain = os.Getenv("GHA2DB_EXPLAIN") != ""
 
@@ -222,6 +464,164 @@ func (ctx *Ctx) Init() {
 	// Exact repository full names to match
 	ctx.Exact = os.Getenv("GHA2DB_EXACT") != ""
 
+	// Resume interrupted GHA hours ingestion
+	ctx.Resume = os.Getenv("GHA2DB_RESUME") != ""
+
+	// Local GHA files directory
+	ctx.ArchiveDir = os.Getenv("GHA2DB_ARCHIVE_DIR")
+	if ctx.ArchiveDir != "" && ctx.ArchiveDir[len(ctx.ArchiveDir)-1:] != "/" {
+		ctx.ArchiveDir += "/"
+	}
+
+	// GHA files URL
+	ctx.ArchiveURL = os.Getenv("GHA2DB_ARCHIVE_URL")
+	if ctx.ArchiveURL == "" {
+		ctx.ArchiveURL = "http://data.githubarchive.org/{{date}}.json.gz"
+	}
+
+	// GHA files mirrors
+	mirrors := os.Getenv("GHA2DB_ARCHIVE_MIRRORS")
+	if mirrors != "" {
+		for _, mirror := range strings.Split(mirrors, ",") {
+			mirror = strings.TrimSpace(mirror)
+			if mirror != "" {
+				ctx.ArchiveMirrors = append(ctx.ArchiveMirrors, mirror)
+			}
+		}
+	}
+	ctx.MirrorFailures = 3
+	if os.Getenv("GHA2DB_MIRROR_FAILURES") != "" {
+		mirrorFailures, err := strconv.Atoi(os.Getenv("GHA2DB_MIRROR_FAILURES"))
+		FatalOnError(err)
+		if mirrorFailures > 0 {
+			ctx.MirrorFailures = mirrorFailures
+		}
+	}
+
+	// GHA files validation
+	ctx.ArchiveChecksums = os.Getenv("GHA2DB_ARCHIVE_CHECKSUMS")
+	ctx.ArchiveRetries = 2
+	if os.Getenv("GHA2DB_ARCHIVE_RETRIES") != "" {
+		archiveRetries, err := strconv.Atoi(os.Getenv("GHA2DB_ARCHIVE_RETRIES"))
+		FatalOnError(err)
+		if archiveRetries >= 0 {
+			ctx.ArchiveRetries = archiveRetries
+		}
+	}
+	ctx.QuarantineDir = os.Getenv("GHA2DB_QUARANTINE_DIR")
+	if ctx.QuarantineDir != "" && ctx.QuarantineDir[len(ctx.QuarantineDir)-1:] != "/" {
+		ctx.QuarantineDir += "/"
+	}
+
+	// GHA files cache
+	ctx.ArchiveCacheDir = os.Getenv("GHA2DB_ARCHIVE_CACHE_DIR")
+	if os.Getenv("GHA2DB_ARCHIVE_CACHE_SIZE") == "" {
+		ctx.ArchiveCacheSize = 0
+	} else {
+		size, err := strconv.Atoi(os.Getenv("GHA2DB_ARCHIVE_CACHE_SIZE"))
+		FatalOnError(err)
+		if size > 0 {
+			ctx.ArchiveCacheSize = size
+		}
+	}
+
+	// GHA files S3/GCS bucket, standard AWS environment variables are used when bucket specific ones are not set
+	ctx.ArchiveBucket = os.Getenv("GHA2DB_ARCHIVE_BUCKET")
+	ctx.BucketMirror = os.Getenv("GHA2DB_ARCHIVE_BUCKET_MIRROR") != ""
+	ctx.BucketEndpoint = os.Getenv("GHA2DB_ARCHIVE_BUCKET_ENDPOINT")
+	ctx.BucketRegion = os.Getenv("GHA2DB_ARCHIVE_BUCKET_REGION")
+	if ctx.BucketRegion == "" {
+		ctx.BucketRegion = os.Getenv("AWS_REGION")
+	}
+	ctx.BucketKeyID = os.Getenv("GHA2DB_ARCHIVE_BUCKET_KEY_ID")
+	ctx.BucketSecret = os.Getenv("GHA2DB_ARCHIVE_BUCKET_SECRET")
+	if ctx.BucketKeyID == "" {
+		ctx.BucketKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		ctx.BucketSecret = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+
+	// BigQuery githubarchive dataset
+	ctx.BigQuery = os.Getenv("GHA2DB_BIGQUERY") != ""
+
+	// Event types to save
+	eventTypes := os.Getenv("GHA2DB_EVENT_TYPES")
+	if eventTypes != "" {
+		for _, eventType := range strings.Split(eventTypes, ",") {
+			eventType = strings.TrimSpace(eventType)
+			if eventType != "" {
+				ctx.EventTypes = append(ctx.EventTypes, eventType)
+			}
+		}
+	}
+
+	// Global bots list
+	ctx.BotsYaml = os.Getenv("GHA2DB_BOTS_YAML")
+	if ctx.BotsYaml == "" {
+		ctx.BotsYaml = "bots.yaml"
+	}
+
+	// Bulk insert batch size
+	ctx.BulkSize = 1000
+	if os.Getenv("GHA2DB_BULK_SIZE") != "" {
+		bulkSize, err := strconv.Atoi(os.Getenv("GHA2DB_BULK_SIZE"))
+		FatalOnError(err)
+		if bulkSize > 0 {
+			ctx.BulkSize = bulkSize
+		}
+	}
+
+	// Dry run mode
+	ctx.DryRun = os.Getenv("GHA2DB_DRY_RUN") != ""
+
+	// Unknown payload fields
+	ctx.PayloadOverflow = os.Getenv("GHA2DB_PAYLOAD_OVERFLOW") != ""
+
+	// Raw event JSON retention
+	ctx.RawJSON = os.Getenv("GHA2DB_RAW_JSON") != ""
+	if os.Getenv("GHA2DB_RAW_JSON_DAYS") != "" {
+		rawJSONDays, err := strconv.Atoi(os.Getenv("GHA2DB_RAW_JSON_DAYS"))
+		FatalOnError(err)
+		if rawJSONDays > 0 {
+			ctx.RawJSONDays = rawJSONDays
+		}
+	}
+
+	// Recent event IDs cache size
+	ctx.DedupCache = 500000
+	if os.Getenv("GHA2DB_DEDUP_CACHE") != "" {
+		dedupCache, err := strconv.Atoi(os.Getenv("GHA2DB_DEDUP_CACHE"))
+		FatalOnError(err)
+		if dedupCache > 0 {
+			ctx.DedupCache = dedupCache
+		}
+	}
+
+	// Progress reporting interval
+	ctx.ProgressInterval = 60
+	if os.Getenv("GHA2DB_PROGRESS_INTERVAL") != "" {
+		progressInterval, err := strconv.Atoi(os.Getenv("GHA2DB_PROGRESS_INTERVAL"))
+		FatalOnError(err)
+		if progressInterval > 0 {
+			ctx.ProgressInterval = progressInterval
+		}
+	}
+
+	// GitLab source
+	ctx.GitLab = os.Getenv("GHA2DB_GITLAB") != ""
+	ctx.GitLabURL = os.Getenv("GHA2DB_GITLAB_URL")
+	if ctx.GitLabURL == "" {
+		ctx.GitLabURL = "https://gitlab.com/api/v4"
+	}
+	ctx.GitLabToken = os.Getenv("GHA2DB_GITLAB_TOKEN")
+
+	// Gitea/Forgejo source
+	ctx.GiteaURL = os.Getenv("GHA2DB_GITEA_URL")
+	ctx.GiteaToken = os.Getenv("GHA2DB_GITEA_TOKEN")
+
+	// Gerrit source
+	ctx.GerritURL = os.Getenv("GHA2DB_GERRIT_URL")
+	ctx.GerritToken = os.Getenv("GHA2DB_GERRIT_TOKEN")
+
 	// Log to Postgres DB, table `devstats`.`gha_logs`
 	ctx.LogToDB = os.Getenv("GHA2DB_SKIPLOG") == ""
 
@@ -239,6 +639,7 @@ func (ctx *Ctx) Init() {
 	ctx.MetricsYaml = os.Getenv("GHA2DB_METRICS_YAML")
 	ctx.GapsYaml = os.Getenv("GHA2DB_GAPS_YAML")
 	ctx.TagsYaml = os.Getenv("GHA2DB_TAGS_YAML")
+	ctx.RetentionYaml = os.Getenv("GHA2DB_RETENTION_YAML")
 	if ctx.MetricsYaml == "" {
 		ctx.MetricsYaml = "metrics/" + proj + "metrics.yaml"
 	}
@@ -248,6 +649,9 @@ func (ctx *Ctx) Init() {
 	if ctx.TagsYaml == "" {
 		ctx.TagsYaml = "metrics/" + proj + "idb_tags.yaml"
 	}
+	if ctx.RetentionYaml == "" {
+		ctx.RetentionYaml = "metrics/" + proj + "retention.yaml"
+	}
 
 	// GitHub OAuth
 	ctx.GitHubOAuth = os.Getenv("GHA2DB_GITHUB_OAUTH")
@@ -261,6 +665,18 @@ func (ctx *Ctx) Init() {
 		ctx.ClearDBPeriod = "1 week"
 	}
 
+	// Max metric runs age
+	ctx.MetricRunsPeriod = os.Getenv("GHA2DB_MAXMETRICRUNAGE")
+	if ctx.MetricRunsPeriod == "" {
+		ctx.MetricRunsPeriod = "1 year"
+	}
+
+	// Max metric cache age
+	ctx.MetricCachePeriod = os.Getenv("GHA2DB_MAXMETRICCACHEAGE")
+	if ctx.MetricCachePeriod == "" {
+		ctx.MetricCachePeriod = "3 months"
+	}
+
 	// Trials
 	trials := os.Getenv("GHA2DB_TRIALS")
 	if trials == "" {
@@ -305,6 +721,7 @@ func (ctx *Ctx) Init() {
 		}
 	}
 	ctx.ProjectRoot = os.Getenv("GHA2DB_PROJECT_ROOT")
+	ctx.SyncAPIToken = os.Getenv("GHA2DB_SYNC_API_TOKEN")
 
 	// WebHook Host, Port, Root
 	ctx.WebHookHost = os.Getenv("GHA2DB_WHHOST")
@@ -349,14 +766,161 @@ func (ctx *Ctx) Init() {
 	ctx.ProcessRepos = os.Getenv("GHA2DB_PROCESS_REPOS") != ""
 	ctx.ProcessCommits = os.Getenv("GHA2DB_PROCESS_COMMITS") != ""
 	ctx.ExternalInfo = os.Getenv("GHA2DB_EXTERNAL_INFO") != ""
+	ctx.ExternalInfoFile = os.Getenv("GHA2DB_EXTERNAL_INFO_FILE")
 	ctx.ProjectsCommits = os.Getenv("GHA2DB_PROJECTS_COMMITS")
 
+	// `get_repos`: shallow and partial clones
+	if os.Getenv("GHA2DB_SHALLOW_CLONE") == "" {
+		ctx.ShallowClone = 0
+	} else {
+		depth, err := strconv.Atoi(os.Getenv("GHA2DB_SHALLOW_CLONE"))
+		FatalOnError(err)
+		if depth > 0 {
+			ctx.ShallowClone = depth
+		}
+	}
+	ctx.PartialClone = os.Getenv("GHA2DB_PARTIAL_CLONE") != ""
+	ctx.MirrorClone = os.Getenv("GHA2DB_MIRROR_CLONE") != ""
+
+	// `get_repos`: clone/pull retries
+	if os.Getenv("GHA2DB_CLONE_RETRIES") == "" {
+		ctx.CloneRetries = 2
+	} else {
+		retries, err := strconv.Atoi(os.Getenv("GHA2DB_CLONE_RETRIES"))
+		FatalOnError(err)
+		if retries >= 0 {
+			ctx.CloneRetries = retries
+		}
+	}
+	if os.Getenv("GHA2DB_CLONE_BACKOFF") == "" {
+		ctx.CloneBackoff = 5
+	} else {
+		backoff, err := strconv.Atoi(os.Getenv("GHA2DB_CLONE_BACKOFF"))
+		FatalOnError(err)
+		if backoff >= 0 {
+			ctx.CloneBackoff = backoff
+		}
+	}
+
+	// `get_repos`: clones rate limit
+	if os.Getenv("GHA2DB_CLONES_PER_MINUTE") != "" {
+		perMinute, err := strconv.Atoi(os.Getenv("GHA2DB_CLONES_PER_MINUTE"))
+		FatalOnError(err)
+		if perMinute > 0 {
+			ctx.ClonesPerMinute = perMinute
+		}
+	}
+	if os.Getenv("GHA2DB_CLONE_JITTER") != "" {
+		jitter, err := strconv.Atoi(os.Getenv("GHA2DB_CLONE_JITTER"))
+		FatalOnError(err)
+		if jitter > 0 {
+			ctx.CloneJitter = jitter
+		}
+	}
+
+	// `get_repos`: git credentials
+	ctx.GitToken = os.Getenv("GHA2DB_GIT_TOKEN")
+	ctx.GitSSHKey = os.Getenv("GHA2DB_GIT_SSH_KEY")
+
+	// `get_repos`: full clones check
+	ctx.Fsck = os.Getenv("GHA2DB_FSCK") != ""
+
+	// `get_repos`: prune orphaned clones
+	ctx.PruneRepos = os.Getenv("GHA2DB_PRUNE_REPOS") != ""
+	ctx.PruneDryRun = os.Getenv("GHA2DB_PRUNE_DRY_RUN") != ""
+
+	// `get_repos`: disk quotas and usage report
+	if os.Getenv("GHA2DB_DISK_QUOTA") == "" {
+		ctx.DiskQuota = 0
+	} else {
+		quota, err := strconv.Atoi(os.Getenv("GHA2DB_DISK_QUOTA"))
+		FatalOnError(err)
+		if quota > 0 {
+			ctx.DiskQuota = quota
+		}
+	}
+	if os.Getenv("GHA2DB_ORG_DISK_QUOTA") == "" {
+		ctx.OrgDiskQuota = 0
+	} else {
+		quota, err := strconv.Atoi(os.Getenv("GHA2DB_ORG_DISK_QUOTA"))
+		FatalOnError(err)
+		if quota > 0 {
+			ctx.OrgDiskQuota = quota
+		}
+	}
+	ctx.DiskUsage = os.Getenv("GHA2DB_DISK_USAGE") != ""
+
+	// `get_repos`: detect renamed repos
+	ctx.DetectRenames = os.Getenv("GHA2DB_DETECT_RENAMES") != ""
+
+	// `get_repos`: track default branches
+	ctx.DefaultBranches = os.Getenv("GHA2DB_DEFAULT_BRANCHES") != ""
+
+	// `get_repos`: update submodules
+	ctx.Submodules = os.Getenv("GHA2DB_SUBMODULES") != ""
+
+	// `get_repos`: detect repos languages
+	ctx.DetectLanguages = os.Getenv("GHA2DB_DETECT_LANGUAGES") != ""
+
+	// `get_repos`: detect repos licenses
+	ctx.DetectLicenses = os.Getenv("GHA2DB_DETECT_LICENSES") != ""
+
+	// `get_repos`: process CODEOWNERS/OWNERS files
+	ctx.ProcessOwners = os.Getenv("GHA2DB_PROCESS_OWNERS") != ""
+
+	// `get_repos`: files churn
+	ctx.FilesChurn = os.Getenv("GHA2DB_FILES_CHURN") != ""
+
+	// `get_repos`: tarball fallback
+	ctx.TarballFallback = os.Getenv("GHA2DB_TARBALL_FALLBACK") != ""
+
+	// `get_repos`: incremental mode
+	ctx.IncrementalRepos = os.Getenv("GHA2DB_INCREMENTAL_REPOS") != ""
+
+	// `get_repos`: stop on first failure
+	ctx.FailFast = os.Getenv("GHA2DB_FAIL_FAST") != ""
+
+	// Notifications
+	ctx.NotifySlack = os.Getenv("GHA2DB_NOTIFY_SLACK")
+	ctx.NotifyWebhook = os.Getenv("GHA2DB_NOTIFY_WEBHOOK")
+	emails := os.Getenv("GHA2DB_NOTIFY_EMAIL")
+	if emails != "" {
+		for _, email := range strings.Split(emails, ",") {
+			email = strings.TrimSpace(email)
+			if email != "" {
+				ctx.NotifyEmails = append(ctx.NotifyEmails, email)
+			}
+		}
+	}
+	ctx.Pushgateway = os.Getenv("GHA2DB_PUSHGATEWAY")
+	ctx.NotifySuccess = os.Getenv("GHA2DB_NOTIFY_SUCCESS") != ""
+	ctx.SMTPServer = os.Getenv("GHA2DB_SMTP_SERVER")
+	if ctx.SMTPServer == "" {
+		ctx.SMTPServer = "localhost:25"
+	}
+	ctx.SMTPUser = os.Getenv("GHA2DB_SMTP_USER")
+	ctx.SMTPPassword = os.Getenv("GHA2DB_SMTP_PASSWORD")
+	ctx.SMTPFrom = os.Getenv("GHA2DB_SMTP_FROM")
+	if ctx.SMTPFrom == "" {
+		ctx.SMTPFrom = "devstats@localhost"
+	}
+
 	// Context out if requested
 	if ctx.CtxOut {
 		ctx.Print()
 	}
 }
 
+// Location returns time zone of periods' boundaries (TimeZone), UTC when not set
+func (ctx *Ctx) Location() *time.Location {
+	if ctx.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(ctx.TimeZone)
+	FatalOnError(err)
+	return loc
+}
+
 // Print context contents
 func (ctx *Ctx) Print() {
 	fmt.Printf("Environment Context Dump\n%+v\n", ctx)
@@ -25,9 +25,14 @@ func copyContext(in *lib.Ctx) *lib.Ctx {
 		PgUser:            in.PgUser,
 		PgPass:            in.PgPass,
 		PgSSL:             in.PgSSL,
+		PgMaxConns:        in.PgMaxConns,
+		PgStmtTimeout:     in.PgStmtTimeout,
+		PgReplicas:        in.PgReplicas,
+		PgReplicaWait:     in.PgReplicaWait,
 		Index:             in.Index,
 		Table:             in.Table,
 		Tools:             in.Tools,
+		Partition:         in.Partition,
 		Mgetc:             in.Mgetc,
 		IDBHost:           in.IDBHost,
 		IDBPort:           in.IDBPort,
@@ -35,24 +40,76 @@ func copyContext(in *lib.Ctx) *lib.Ctx {
 		IDBUser:           in.IDBUser,
 		IDBPass:           in.IDBPass,
 		IDBMaxBatchPoints: in.IDBMaxBatchPoints,
+		IDBMaxMemPoints:   in.IDBMaxMemPoints,
 		QOut:              in.QOut,
 		CtxOut:            in.CtxOut,
 		DefaultStartDate:  in.DefaultStartDate,
+		TimeZone:          in.TimeZone,
 		LastSeries:        in.LastSeries,
 		SkipIDB:           in.SkipIDB,
 		SkipPDB:           in.SkipPDB,
+		CatchUpHours:      in.CatchUpHours,
 		ResetIDB:          in.ResetIDB,
 		ResetRanges:       in.ResetRanges,
+		ForceCompute:      in.ForceCompute,
+		FullBackfill:      in.FullBackfill,
+		ExplainSlow:       in.ExplainSlow,
+		SkipMetricCache:   in.SkipMetricCache,
+		MetricCacheTTL:    in.MetricCacheTTL,
+		LockTimeout:       in.LockTimeout,
+		OnlyMetrics:       in.OnlyMetrics,
+		SyncByOrder:       in.SyncByOrder,
+		SyncParallel:      in.SyncParallel,
+		MetricsParallel:   in.MetricsParallel,
+		DBParallel:        in.DBParallel,
+		GitParallel:       in.GitParallel,
+		DaemonInterval:    in.DaemonInterval,
 		Explain:           in.Explain,
 		OldFormat:         in.OldFormat,
 		Exact:             in.Exact,
+		Resume:            in.Resume,
+		ArchiveDir:        in.ArchiveDir,
+		ArchiveURL:        in.ArchiveURL,
+		ArchiveMirrors:    in.ArchiveMirrors,
+		MirrorFailures:    in.MirrorFailures,
+		ArchiveChecksums:  in.ArchiveChecksums,
+		ArchiveRetries:    in.ArchiveRetries,
+		QuarantineDir:     in.QuarantineDir,
+		ArchiveCacheDir:   in.ArchiveCacheDir,
+		ArchiveCacheSize:  in.ArchiveCacheSize,
+		ArchiveBucket:     in.ArchiveBucket,
+		BucketMirror:      in.BucketMirror,
+		BucketEndpoint:    in.BucketEndpoint,
+		BucketRegion:      in.BucketRegion,
+		BucketKeyID:       in.BucketKeyID,
+		BucketSecret:      in.BucketSecret,
+		BigQuery:          in.BigQuery,
+		EventTypes:        in.EventTypes,
+		BotsYaml:          in.BotsYaml,
+		BulkSize:          in.BulkSize,
+		DryRun:            in.DryRun,
+		PayloadOverflow:   in.PayloadOverflow,
+		RawJSON:           in.RawJSON,
+		RawJSONDays:       in.RawJSONDays,
+		DedupCache:        in.DedupCache,
+		ProgressInterval:  in.ProgressInterval,
+		GitLab:            in.GitLab,
+		GitLabURL:         in.GitLabURL,
+		GitLabToken:       in.GitLabToken,
+		GiteaURL:          in.GiteaURL,
+		GiteaToken:        in.GiteaToken,
+		GerritURL:         in.GerritURL,
+		GerritToken:       in.GerritToken,
 		LogToDB:           in.LogToDB,
 		Local:             in.Local,
 		MetricsYaml:       in.MetricsYaml,
 		GapsYaml:          in.GapsYaml,
 		TagsYaml:          in.TagsYaml,
+		RetentionYaml:     in.RetentionYaml,
 		GitHubOAuth:       in.GitHubOAuth,
 		ClearDBPeriod:     in.ClearDBPeriod,
+		MetricRunsPeriod:  in.MetricRunsPeriod,
+		MetricCachePeriod: in.MetricCachePeriod,
 		Trials:            in.Trials,
 		LogTime:           in.LogTime,
 		WebHookRoot:       in.WebHookRoot,
@@ -64,6 +121,7 @@ func copyContext(in *lib.Ctx) *lib.Ctx {
 		DeployResults:     in.DeployResults,
 		DeployTypes:       in.DeployTypes,
 		ProjectRoot:       in.ProjectRoot,
+		SyncAPIToken:      in.SyncAPIToken,
 		Project:           in.Project,
 		TestsYaml:         in.TestsYaml,
 		ReposDir:          in.ReposDir,
@@ -73,8 +131,43 @@ func copyContext(in *lib.Ctx) *lib.Ctx {
 		ProcessRepos:      in.ProcessRepos,
 		ProcessCommits:    in.ProcessCommits,
 		ExternalInfo:      in.ExternalInfo,
+		ExternalInfoFile:  in.ExternalInfoFile,
 		ProjectsCommits:   in.ProjectsCommits,
 		ProjectsYaml:      in.ProjectsYaml,
+		ShallowClone:      in.ShallowClone,
+		PartialClone:      in.PartialClone,
+		MirrorClone:       in.MirrorClone,
+		GitToken:          in.GitToken,
+		GitSSHKey:         in.GitSSHKey,
+		CloneRetries:      in.CloneRetries,
+		CloneBackoff:      in.CloneBackoff,
+		ClonesPerMinute:   in.ClonesPerMinute,
+		CloneJitter:       in.CloneJitter,
+		Fsck:              in.Fsck,
+		PruneRepos:        in.PruneRepos,
+		PruneDryRun:       in.PruneDryRun,
+		DiskQuota:         in.DiskQuota,
+		OrgDiskQuota:      in.OrgDiskQuota,
+		DiskUsage:         in.DiskUsage,
+		FailFast:          in.FailFast,
+		NotifySlack:       in.NotifySlack,
+		NotifyWebhook:     in.NotifyWebhook,
+		NotifyEmails:      in.NotifyEmails,
+		Pushgateway:       in.Pushgateway,
+		NotifySuccess:     in.NotifySuccess,
+		SMTPServer:        in.SMTPServer,
+		SMTPUser:          in.SMTPUser,
+		SMTPPassword:      in.SMTPPassword,
+		SMTPFrom:          in.SMTPFrom,
+		DetectRenames:     in.DetectRenames,
+		DefaultBranches:   in.DefaultBranches,
+		Submodules:        in.Submodules,
+		DetectLanguages:   in.DetectLanguages,
+		DetectLicenses:    in.DetectLicenses,
+		ProcessOwners:     in.ProcessOwners,
+		FilesChurn:        in.FilesChurn,
+		TarballFallback:   in.TarballFallback,
+		IncrementalRepos:  in.IncrementalRepos,
 	}
 	return &out
 }
@@ -172,9 +265,14 @@ func TestInit(t *testing.T) {
 		PgUser:            "gha_admin",
 		PgPass:            "password",
 		PgSSL:             "disable",
+		PgMaxConns:        0,
+		PgStmtTimeout:     0,
+		PgReplicas:        nil,
+		PgReplicaWait:     30,
 		Index:             false,
 		Table:             true,
 		Tools:             true,
+		Partition:         false,
 		Mgetc:             "",
 		IDBHost:           "http://localhost",
 		IDBPort:           "8086",
@@ -182,24 +280,74 @@ func TestInit(t *testing.T) {
 		IDBUser:           "gha_admin",
 		IDBPass:           "password",
 		IDBMaxBatchPoints: 10240,
+		IDBMaxMemPoints:   102400,
 		QOut:              false,
 		CtxOut:            false,
 		DefaultStartDate:  time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC),
+		TimeZone:          "",
 		LastSeries:        "events_h",
 		SkipIDB:           false,
 		SkipPDB:           false,
+		CatchUpHours:      24,
 		ResetIDB:          false,
 		ResetRanges:       false,
+		ForceCompute:      false,
+		FullBackfill:      false,
+		ExplainSlow:       0,
+		SkipMetricCache:   false,
+		MetricCacheTTL:    600,
+		LockTimeout:       0,
+		OnlyMetrics:       nil,
+		SyncByOrder:       false,
+		SyncParallel:      1,
+		MetricsParallel:   1,
+		DaemonInterval:    20,
 		Explain:           false,
 		OldFormat:         false,
 		Exact:             false,
+		Resume:            false,
+		ArchiveDir:        "",
+		ArchiveURL:        "http://data.githubarchive.org/{{date}}.json.gz",
+		ArchiveMirrors:    nil,
+		MirrorFailures:    3,
+		ArchiveChecksums:  "",
+		ArchiveRetries:    2,
+		QuarantineDir:     "",
+		ArchiveCacheDir:   "",
+		ArchiveCacheSize:  0,
+		ArchiveBucket:     "",
+		BucketMirror:      false,
+		BucketEndpoint:    "",
+		BucketRegion:      "",
+		BucketKeyID:       "",
+		BucketSecret:      "",
+		BigQuery:          false,
+		EventTypes:        nil,
+		BotsYaml:          "bots.yaml",
+		BulkSize:          1000,
+		DryRun:            false,
+		PayloadOverflow:   false,
+		RawJSON:           false,
+		RawJSONDays:       0,
+		DedupCache:        500000,
+		ProgressInterval:  60,
+		GitLab:            false,
+		GitLabURL:         "https://gitlab.com/api/v4",
+		GitLabToken:       "",
+		GiteaURL:          "",
+		GiteaToken:        "",
+		GerritURL:         "",
+		GerritToken:       "",
 		LogToDB:           true,
 		Local:             false,
 		MetricsYaml:       "metrics/metrics.yaml",
 		GapsYaml:          "metrics/gaps.yaml",
 		TagsYaml:          "metrics/idb_tags.yaml",
+		RetentionYaml:     "metrics/retention.yaml",
 		GitHubOAuth:       "/etc/github/oauth",
 		ClearDBPeriod:     "1 week",
+		MetricRunsPeriod:  "1 year",
+		MetricCachePeriod: "3 months",
 		Trials:            []int{10, 30, 60, 120, 300, 600},
 		LogTime:           true,
 		WebHookRoot:       "/hook",
@@ -211,6 +359,7 @@ func TestInit(t *testing.T) {
 		DeployResults:     []int{0},
 		DeployTypes:       []string{"push"},
 		ProjectRoot:       "",
+		SyncAPIToken:      "",
 		Project:           "",
 		TestsYaml:         "tests.yaml",
 		ReposDir:          os.Getenv("HOME") + "/devstats_repos/",
@@ -220,8 +369,43 @@ func TestInit(t *testing.T) {
 		ProcessRepos:      false,
 		ProcessCommits:    false,
 		ExternalInfo:      false,
+		ExternalInfoFile:  "",
 		ProjectsCommits:   "",
 		ProjectsYaml:      "projects.yaml",
+		ShallowClone:      0,
+		PartialClone:      false,
+		MirrorClone:       false,
+		GitToken:          "",
+		GitSSHKey:         "",
+		CloneRetries:      2,
+		CloneBackoff:      5,
+		ClonesPerMinute:   0,
+		CloneJitter:       0,
+		Fsck:              false,
+		PruneRepos:        false,
+		PruneDryRun:       false,
+		DiskQuota:         0,
+		OrgDiskQuota:      0,
+		DiskUsage:         false,
+		FailFast:          false,
+		NotifySlack:       "",
+		NotifyWebhook:     "",
+		NotifyEmails:      nil,
+		Pushgateway:       "",
+		NotifySuccess:     false,
+		SMTPServer:        "localhost:25",
+		SMTPUser:          "",
+		SMTPPassword:      "",
+		SMTPFrom:          "devstats@localhost",
+		DetectRenames:     false,
+		DefaultBranches:   false,
+		Submodules:        false,
+		DetectLanguages:   false,
+		DetectLicenses:    false,
+		ProcessOwners:     false,
+		FilesChurn:        false,
+		TarballFallback:   false,
+		IncrementalRepos:  false,
 	}
 
 	// Test cases
@@ -303,6 +487,36 @@ func TestInit(t *testing.T) {
 				},
 			),
 		},
+		{
+			"Setting Postgres pool",
+			map[string]string{
+				"PG_MAX_CONNS":         "20",
+				"PG_STATEMENT_TIMEOUT": "600",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"PgMaxConns":    20,
+					"PgStmtTimeout": 600,
+				},
+			),
+		},
+		{
+			"Setting Postgres replicas",
+			map[string]string{
+				"PG_REPLICAS":     "replica1, replica2:5433,",
+				"PG_REPLICA_WAIT": "0",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"PgReplicas":    []string{"replica1", "replica2:5433"},
+					"PgReplicaWait": 0,
+				},
+			),
+		},
 		{
 			"Setting index, table, tools",
 			map[string]string{
@@ -372,6 +586,15 @@ func TestInit(t *testing.T) {
 				map[string]interface{}{"IDBMaxBatchPoints": 1000000},
 			),
 		},
+		{
+			"Setting IDBMaxMemPoints",
+			map[string]string{"IDB_MAXMEMPOINTS": "0"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"IDBMaxMemPoints": 0},
+			),
+		},
 		{
 			"Setting query out & context out",
 			map[string]string{"GHA2DB_QOUT": "1", "GHA2DB_CTXOUT": "1"},
@@ -398,6 +621,114 @@ func TestInit(t *testing.T) {
 				},
 			),
 		},
+		{
+			"Setting force compute",
+			map[string]string{"GHA2DB_FORCE_COMPUTE": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"ForceCompute": true},
+			),
+		},
+		{
+			"Setting full backfill",
+			map[string]string{"GHA2DB_FULL_BACKFILL": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"FullBackfill": true},
+			),
+		},
+		{
+			"Setting metrics subset",
+			map[string]string{"GHA2DB_METRICS": "all_prs_merged, SIG mentions,,tag:slow"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"OnlyMetrics": []string{"all_prs_merged", "SIG mentions", "tag:slow"}},
+			),
+		},
+		{
+			"Setting parallel projects sync",
+			map[string]string{"GHA2DB_SYNC_PARALLEL": "4"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"SyncParallel": 4},
+			),
+		},
+		{
+			"Setting parallel metrics",
+			map[string]string{"GHA2DB_METRICS_PARALLEL": "4"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"MetricsParallel": 4},
+			),
+		},
+		{
+			"Setting parallel database queries and git operations",
+			map[string]string{"GHA2DB_DB_PARALLEL": "3", "GHA2DB_GIT_PARALLEL": "8"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"DBParallel": 3, "GitParallel": 8},
+			),
+		},
+		{
+			"Enabling sync API",
+			map[string]string{"GHA2DB_SYNC_API_TOKEN": "secret"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"SyncAPIToken": "secret"},
+			),
+		},
+		{
+			"Setting catch-up hours",
+			map[string]string{"GHA2DB_CATCHUP_HOURS": "72"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"CatchUpHours": 72},
+			),
+		},
+		{
+			"Disabling catch-up mode",
+			map[string]string{"GHA2DB_CATCHUP_HOURS": "0"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"CatchUpHours": 0},
+			),
+		},
+		{
+			"Setting daemon sync interval",
+			map[string]string{"GHA2DB_DAEMON_INTERVAL": "30"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"DaemonInterval": 30},
+			),
+		},
+		{
+			"Setting projects sync by order",
+			map[string]string{"GHA2DB_SYNC_BY_ORDER": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"SyncByOrder": true},
+			),
+		},
+		{
+			"Setting sync lock timeout",
+			map[string]string{"GHA2DB_LOCK_TIMEOUT": "600"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"LockTimeout": 600},
+			),
+		},
 		{
 			"Setting skip PDB",
 			map[string]string{"GHA2DB_SKIPPDB": "1"},
@@ -447,6 +778,24 @@ func TestInit(t *testing.T) {
 				},
 			),
 		},
+		{
+			"Setting monthly partitions",
+			map[string]string{"GHA2DB_PARTITION": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"Partition": true},
+			),
+		},
+		{
+			"Setting periods time zone",
+			map[string]string{"GHA2DB_TIMEZONE": "Europe/Warsaw"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"TimeZone": "Europe/Warsaw"},
+			),
+		},
 		{
 			"Setting Old pre 2015 GHA JSONs format",
 			map[string]string{"GHA2DB_OLDFMT": "1"},
@@ -465,6 +814,267 @@ func TestInit(t *testing.T) {
 				map[string]interface{}{"Exact": true},
 			),
 		},
+		{
+			"Setting resume mode",
+			map[string]string{"GHA2DB_RESUME": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"Resume": true},
+			),
+		},
+		{
+			"Setting local GHA files directory",
+			map[string]string{"GHA2DB_ARCHIVE_DIR": "/data/gha"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"ArchiveDir": "/data/gha/"},
+			),
+		},
+		{
+			"Setting GHA files URL",
+			map[string]string{"GHA2DB_ARCHIVE_URL": "https://mirror.example.com/{{date}}.json.zst"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"ArchiveURL": "https://mirror.example.com/{{date}}.json.zst"},
+			),
+		},
+		{
+			"Setting GHA files mirrors",
+			map[string]string{
+				"GHA2DB_ARCHIVE_MIRRORS": "https://mirror1.example.com/{{date}}.json.gz, ,https://mirror2.example.com/gha/",
+				"GHA2DB_MIRROR_FAILURES": "5",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"ArchiveMirrors": []string{"https://mirror1.example.com/{{date}}.json.gz", "https://mirror2.example.com/gha/"},
+					"MirrorFailures": 5,
+				},
+			),
+		},
+		{
+			"Setting GHA files validation",
+			map[string]string{
+				"GHA2DB_ARCHIVE_CHECKSUMS": "https://mirror.example.com/{{date}}.json.gz.sha256",
+				"GHA2DB_ARCHIVE_RETRIES":   "0",
+				"GHA2DB_QUARANTINE_DIR":    "/data/quarantine",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"ArchiveChecksums": "https://mirror.example.com/{{date}}.json.gz.sha256",
+					"ArchiveRetries":   0,
+					"QuarantineDir":    "/data/quarantine/",
+				},
+			),
+		},
+		{
+			"Setting GHA files cache",
+			map[string]string{
+				"GHA2DB_ARCHIVE_CACHE_DIR":  "/var/cache/gha",
+				"GHA2DB_ARCHIVE_CACHE_SIZE": "20480",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"ArchiveCacheDir":  "/var/cache/gha",
+					"ArchiveCacheSize": 20480,
+				},
+			),
+		},
+		{
+			"Setting negative GHA files cache size",
+			map[string]string{"GHA2DB_ARCHIVE_CACHE_SIZE": "-1"},
+			copyContext(&defaultContext),
+		},
+		{
+			"Setting GHA files bucket",
+			map[string]string{
+				"GHA2DB_ARCHIVE_BUCKET":          "gs://gha/hours",
+				"GHA2DB_ARCHIVE_BUCKET_MIRROR":   "1",
+				"GHA2DB_ARCHIVE_BUCKET_ENDPOINT": "http://minio:9000",
+				"GHA2DB_ARCHIVE_BUCKET_REGION":   "eu-west-1",
+				"GHA2DB_ARCHIVE_BUCKET_KEY_ID":   "key",
+				"GHA2DB_ARCHIVE_BUCKET_SECRET":   "secret",
+				"AWS_ACCESS_KEY_ID":              "aws-key",
+				"AWS_SECRET_ACCESS_KEY":          "aws-secret",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"ArchiveBucket":  "gs://gha/hours",
+					"BucketMirror":   true,
+					"BucketEndpoint": "http://minio:9000",
+					"BucketRegion":   "eu-west-1",
+					"BucketKeyID":    "key",
+					"BucketSecret":   "secret",
+				},
+			),
+		},
+		{
+			"Setting GHA files bucket with AWS credentials",
+			map[string]string{
+				"GHA2DB_ARCHIVE_BUCKET": "s3://gha",
+				"AWS_REGION":            "eu-west-1",
+				"AWS_ACCESS_KEY_ID":     "aws-key",
+				"AWS_SECRET_ACCESS_KEY": "aws-secret",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"ArchiveBucket": "s3://gha",
+					"BucketRegion":  "eu-west-1",
+					"BucketKeyID":   "aws-key",
+					"BucketSecret":  "aws-secret",
+				},
+			),
+		},
+		{
+			"Setting BigQuery source",
+			map[string]string{"GHA2DB_BIGQUERY": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"BigQuery": true},
+			),
+		},
+		{
+			"Setting event types",
+			map[string]string{"GHA2DB_EVENT_TYPES": "IssuesEvent, PullRequestEvent,,PushEvent"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"EventTypes": []string{"IssuesEvent", "PullRequestEvent", "PushEvent"}},
+			),
+		},
+		{
+			"Setting bots.yaml",
+			map[string]string{"GHA2DB_BOTS_YAML": "my_bots.yaml"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"BotsYaml": "my_bots.yaml"},
+			),
+		},
+		{
+			"Setting bulk size",
+			map[string]string{"GHA2DB_BULK_SIZE": "5000"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"BulkSize": 5000},
+			),
+		},
+		{
+			"Setting invalid bulk size",
+			map[string]string{"GHA2DB_BULK_SIZE": "0"},
+			copyContext(&defaultContext),
+		},
+		{
+			"Setting dry run mode",
+			map[string]string{"GHA2DB_DRY_RUN": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"DryRun": true},
+			),
+		},
+		{
+			"Setting payload overflow mode",
+			map[string]string{"GHA2DB_PAYLOAD_OVERFLOW": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"PayloadOverflow": true},
+			),
+		},
+		{
+			"Setting raw event JSON retention",
+			map[string]string{"GHA2DB_RAW_JSON": "1", "GHA2DB_RAW_JSON_DAYS": "90"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"RawJSON": true, "RawJSONDays": 90},
+			),
+		},
+		{
+			"Setting invalid raw event JSON retention",
+			map[string]string{"GHA2DB_RAW_JSON_DAYS": "-1"},
+			copyContext(&defaultContext),
+		},
+		{
+			"Setting recent event IDs cache size",
+			map[string]string{"GHA2DB_DEDUP_CACHE": "1000"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"DedupCache": 1000},
+			),
+		},
+		{
+			"Setting progress interval",
+			map[string]string{"GHA2DB_PROGRESS_INTERVAL": "10"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"ProgressInterval": 10},
+			),
+		},
+		{
+			"Setting GitLab source",
+			map[string]string{
+				"GHA2DB_GITLAB":       "1",
+				"GHA2DB_GITLAB_URL":   "https://gitlab.example.com/api/v4",
+				"GHA2DB_GITLAB_TOKEN": "/etc/gitlab/token",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"GitLab":      true,
+					"GitLabURL":   "https://gitlab.example.com/api/v4",
+					"GitLabToken": "/etc/gitlab/token",
+				},
+			),
+		},
+		{
+			"Setting Gitea source",
+			map[string]string{
+				"GHA2DB_GITEA_URL":   "https://codeberg.org",
+				"GHA2DB_GITEA_TOKEN": "secret",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"GiteaURL":   "https://codeberg.org",
+					"GiteaToken": "secret",
+				},
+			),
+		},
+		{
+			"Setting Gerrit source",
+			map[string]string{
+				"GHA2DB_GERRIT_URL":   "https://review.opendev.org",
+				"GHA2DB_GERRIT_TOKEN": "/etc/gerrit/auth",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"GerritURL":   "https://review.opendev.org",
+					"GerritToken": "/etc/gerrit/auth",
+				},
+			),
+		},
 		{
 			"Setting skip DB log mode mode",
 			map[string]string{"GHA2DB_SKIPLOG": "1"},
@@ -486,17 +1096,19 @@ func TestInit(t *testing.T) {
 		{
 			"Setting non standard YAML files",
 			map[string]string{
-				"GHA2DB_METRICS_YAML": "met.YAML",
-				"GHA2DB_GAPS_YAML":    "/gapz.yml",
-				"GHA2DB_TAGS_YAML":    "/t/g/s.yml",
+				"GHA2DB_METRICS_YAML":   "met.YAML",
+				"GHA2DB_GAPS_YAML":      "/gapz.yml",
+				"GHA2DB_TAGS_YAML":      "/t/g/s.yml",
+				"GHA2DB_RETENTION_YAML": "/r.yml",
 			},
 			dynamicSetFields(
 				t,
 				copyContext(&defaultContext),
 				map[string]interface{}{
-					"MetricsYaml": "met.YAML",
-					"GapsYaml":    "/gapz.yml",
-					"TagsYaml":    "/t/g/s.yml",
+					"MetricsYaml":   "met.YAML",
+					"GapsYaml":      "/gapz.yml",
+					"TagsYaml":      "/t/g/s.yml",
+					"RetentionYaml": "/r.yml",
 				},
 			),
 		},
@@ -607,10 +1219,11 @@ func TestInit(t *testing.T) {
 				t,
 				copyContext(&defaultContext),
 				map[string]interface{}{
-					"Project":     "prometheus",
-					"MetricsYaml": "metrics/prometheus/metrics.yaml",
-					"GapsYaml":    "metrics/prometheus/gaps.yaml",
-					"TagsYaml":    "metrics/prometheus/idb_tags.yaml",
+					"Project":       "prometheus",
+					"MetricsYaml":   "metrics/prometheus/metrics.yaml",
+					"GapsYaml":      "metrics/prometheus/gaps.yaml",
+					"TagsYaml":      "metrics/prometheus/idb_tags.yaml",
+					"RetentionYaml": "metrics/prometheus/retention.yaml",
 				},
 			),
 		},
@@ -624,10 +1237,11 @@ func TestInit(t *testing.T) {
 				t,
 				copyContext(&defaultContext),
 				map[string]interface{}{
-					"Project":     "prometheus",
-					"MetricsYaml": "metrics/prometheus/metrics.yaml",
-					"GapsYaml":    "/gapz.yml",
-					"TagsYaml":    "metrics/prometheus/idb_tags.yaml",
+					"Project":       "prometheus",
+					"MetricsYaml":   "metrics/prometheus/metrics.yaml",
+					"GapsYaml":      "/gapz.yml",
+					"TagsYaml":      "metrics/prometheus/idb_tags.yaml",
+					"RetentionYaml": "metrics/prometheus/retention.yaml",
 				},
 			),
 		},
@@ -711,6 +1325,19 @@ func TestInit(t *testing.T) {
 				},
 			),
 		},
+		{
+			"Set get_repos external info file",
+			map[string]string{
+				"GHA2DB_EXTERNAL_INFO_FILE": "/tmp/repos.json",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"ExternalInfoFile": "/tmp/repos.json",
+				},
+			),
+		},
 		{
 			"Setting projects commits",
 			map[string]string{
@@ -724,6 +1351,349 @@ func TestInit(t *testing.T) {
 				},
 			),
 		},
+		{
+			"Setting shallow & partial clone",
+			map[string]string{
+				"GHA2DB_SHALLOW_CLONE": "50",
+				"GHA2DB_PARTIAL_CLONE": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"ShallowClone": 50,
+					"PartialClone": true,
+				},
+			),
+		},
+		{
+			"Setting negative shallow clone depth",
+			map[string]string{
+				"GHA2DB_SHALLOW_CLONE": "-1",
+			},
+			&defaultContext,
+		},
+		{
+			"Setting mirror clone",
+			map[string]string{
+				"GHA2DB_MIRROR_CLONE": "y",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"MirrorClone": true,
+				},
+			),
+		},
+		{
+			"Setting git credentials",
+			map[string]string{
+				"GHA2DB_GIT_TOKEN":   "/etc/github/oauth",
+				"GHA2DB_GIT_SSH_KEY": "/root/.ssh/id_rsa",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"GitToken":  "/etc/github/oauth",
+					"GitSSHKey": "/root/.ssh/id_rsa",
+				},
+			),
+		},
+		{
+			"Setting clone retries & backoff",
+			map[string]string{
+				"GHA2DB_CLONE_RETRIES": "0",
+				"GHA2DB_CLONE_BACKOFF": "30",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"CloneRetries": 0,
+					"CloneBackoff": 30,
+				},
+			),
+		},
+		{
+			"Setting clones rate limit & jitter",
+			map[string]string{
+				"GHA2DB_CLONES_PER_MINUTE": "120",
+				"GHA2DB_CLONE_JITTER":      "500",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"ClonesPerMinute": 120,
+					"CloneJitter":     500,
+				},
+			),
+		},
+		{
+			"Setting fsck",
+			map[string]string{
+				"GHA2DB_FSCK": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"Fsck": true,
+				},
+			),
+		},
+		{
+			"Setting negative clones rate limit & jitter",
+			map[string]string{
+				"GHA2DB_CLONES_PER_MINUTE": "-1",
+				"GHA2DB_CLONE_JITTER":      "-1",
+			},
+			copyContext(&defaultContext),
+		},
+		{
+			"Setting prune repos in dry-run mode",
+			map[string]string{
+				"GHA2DB_PRUNE_REPOS":   "1",
+				"GHA2DB_PRUNE_DRY_RUN": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"PruneRepos":  true,
+					"PruneDryRun": true,
+				},
+			),
+		},
+		{
+			"Setting disk quotas & usage report",
+			map[string]string{
+				"GHA2DB_DISK_QUOTA":     "102400",
+				"GHA2DB_ORG_DISK_QUOTA": "10240",
+				"GHA2DB_DISK_USAGE":     "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"DiskQuota":    102400,
+					"OrgDiskQuota": 10240,
+					"DiskUsage":    true,
+				},
+			),
+		},
+		{
+			"Setting detect renames",
+			map[string]string{
+				"GHA2DB_DETECT_RENAMES": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"DetectRenames": true,
+				},
+			),
+		},
+		{
+			"Setting default branches",
+			map[string]string{
+				"GHA2DB_DEFAULT_BRANCHES": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"DefaultBranches": true,
+				},
+			),
+		},
+		{
+			"Setting submodules",
+			map[string]string{
+				"GHA2DB_SUBMODULES": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"Submodules": true,
+				},
+			),
+		},
+		{
+			"Setting detect languages",
+			map[string]string{
+				"GHA2DB_DETECT_LANGUAGES": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"DetectLanguages": true,
+				},
+			),
+		},
+		{
+			"Setting detect licenses",
+			map[string]string{
+				"GHA2DB_DETECT_LICENSES": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"DetectLicenses": true,
+				},
+			),
+		},
+		{
+			"Setting process owners",
+			map[string]string{
+				"GHA2DB_PROCESS_OWNERS": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"ProcessOwners": true,
+				},
+			),
+		},
+		{
+			"Setting files churn",
+			map[string]string{
+				"GHA2DB_FILES_CHURN": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"FilesChurn": true,
+				},
+			),
+		},
+		{
+			"Setting tarball fallback",
+			map[string]string{
+				"GHA2DB_TARBALL_FALLBACK": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"TarballFallback": true,
+				},
+			),
+		},
+		{
+			"Setting incremental repos",
+			map[string]string{
+				"GHA2DB_INCREMENTAL_REPOS": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"IncrementalRepos": true,
+				},
+			),
+		},
+		{
+			"Setting fail fast mode",
+			map[string]string{
+				"GHA2DB_FAIL_FAST": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"FailFast": true,
+				},
+			),
+		},
+		{
+			"Setting notifications",
+			map[string]string{
+				"GHA2DB_NOTIFY_SLACK":   "https://hooks.slack.com/services/T0/B0/X",
+				"GHA2DB_NOTIFY_WEBHOOK": "https://alerts.example.com/devstats",
+				"GHA2DB_NOTIFY_EMAIL":   "ops@example.com, ,dev@example.com",
+				"GHA2DB_NOTIFY_SUCCESS": "1",
+				"GHA2DB_SMTP_SERVER":    "smtp.example.com:587",
+				"GHA2DB_SMTP_USER":      "devstats",
+				"GHA2DB_SMTP_PASSWORD":  "secret",
+				"GHA2DB_SMTP_FROM":      "devstats@example.com",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"NotifySlack":   "https://hooks.slack.com/services/T0/B0/X",
+					"NotifyWebhook": "https://alerts.example.com/devstats",
+					"NotifyEmails":  []string{"ops@example.com", "dev@example.com"},
+					"NotifySuccess": true,
+					"SMTPServer":    "smtp.example.com:587",
+					"SMTPUser":      "devstats",
+					"SMTPPassword":  "secret",
+					"SMTPFrom":      "devstats@example.com",
+				},
+			),
+		},
+		{
+			"Setting Pushgateway",
+			map[string]string{"GHA2DB_PUSHGATEWAY": "http://pushgateway:9091"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"Pushgateway": "http://pushgateway:9091"},
+			),
+		},
+		{
+			"Setting metric runs max age",
+			map[string]string{"GHA2DB_MAXMETRICRUNAGE": "3 months"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"MetricRunsPeriod": "3 months"},
+			),
+		},
+		{
+			"Setting explain slow queries duration",
+			map[string]string{"GHA2DB_EXPLAIN_SLOW": "1m30s"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"ExplainSlow": 90},
+			),
+		},
+		{
+			"Setting explain slow queries seconds",
+			map[string]string{"GHA2DB_EXPLAIN_SLOW": "60"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"ExplainSlow": 60},
+			),
+		},
+		{
+			"Setting metric cache",
+			map[string]string{
+				"GHA2DB_SKIP_METRIC_CACHE": "1",
+				"GHA2DB_METRIC_CACHE_TTL":  "0",
+				"GHA2DB_MAXMETRICCACHEAGE": "1 month",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"SkipMetricCache":   true,
+					"MetricCacheTTL":    0,
+					"MetricCachePeriod": "1 month",
+				},
+			),
+		},
 	}
 
 	// Context Init() is verbose when called with CtxDebug
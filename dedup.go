@@ -0,0 +1,103 @@
+package devstats
+
+import (
+	"strings"
+	"sync"
+)
+
+// RecentIDs - bounded set of recently seen event IDs
+// GHA files sometimes contain the same event in adjacent hours
+// When the set is full, the oldest ID is forgotten
+type RecentIDs struct {
+	mtx  sync.Mutex
+	ids  map[string]struct{}
+	ring []string
+	next int
+}
+
+// NewRecentIDs - creates set remembering up to size IDs
+func NewRecentIDs(size int) *RecentIDs {
+	if size < 1 {
+		size = 1
+	}
+	return &RecentIDs{
+		ids:  make(map[string]struct{}, size),
+		ring: make([]string, 0, size),
+	}
+}
+
+// Seen - returns true if ID was already seen, otherwise remembers it and returns false
+// Safe to call from many goroutines
+func (r *RecentIDs) Seen(id string) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if _, ok := r.ids[id]; ok {
+		return true
+	}
+	if len(r.ring) < cap(r.ring) {
+		r.ring = append(r.ring, id)
+	} else {
+		delete(r.ids, r.ring[r.next])
+		r.ring[r.next] = id
+		r.next = (r.next + 1) % len(r.ring)
+	}
+	r.ids[id] = struct{}{}
+	return false
+}
+
+// Len - number of remembered IDs
+func (r *RecentIDs) Len() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return len(r.ids)
+}
+
+// EventTables - tables with event specific rows (keyed by `event_id`)
+var EventTables = []string{
+	"gha_assets",
+	"gha_branches",
+	"gha_comments",
+	"gha_commits",
+	"gha_events_commits_files",
+	"gha_forkees",
+	"gha_issues",
+	"gha_issues_assignees",
+	"gha_issues_events_labels",
+	"gha_issues_labels",
+	"gha_milestones",
+	"gha_pages",
+	"gha_payloads",
+	"gha_pull_requests",
+	"gha_pull_requests_assignees",
+	"gha_pull_requests_requested_reviewers",
+	"gha_releases",
+	"gha_releases_assets",
+	"gha_teams",
+	"gha_teams_repositories",
+	"gha_texts",
+}
+
+// DupEventsQuery returns query selecting IDs of events that are copies of an event with lower ID
+// Copy has the same event row and the same rows in all event specific tables, only event ID differs (and raw JSON,
+// it contains the ID), so distinct events sharing type, actor, repo and time (like two wiki edits) are not copies
+// Only events sharing type, actor, repo and time with another event are compared
+func DupEventsQuery() string {
+	rows := []string{}
+	for _, table := range EventTables {
+		rows = append(rows, "select '"+table+":' || (to_jsonb(t) - 'event_id' - 'raw')::text as r from "+table+" t where t.event_id = c.id")
+	}
+	return "with candidates as (" +
+		"select id from (" +
+		"select e.id, count(*) over (partition by e.origin, e.type, e.actor_id, e.repo_id, e.created_at) as n " +
+		"from gha_events e" +
+		") sub where n > 1" +
+		"), signatures as (" +
+		"select c.id, md5((to_jsonb(e) - 'id')::text) as ev, s.sig " +
+		"from candidates c join gha_events e on e.id = c.id, lateral (" +
+		"select md5(coalesce(string_agg(r, '|' order by r), '')) as sig from (" +
+		strings.Join(rows, " union all ") +
+		") x) s" +
+		") select id from (" +
+		"select id, row_number() over (partition by ev, sig order by id) as n from signatures" +
+		") sub where n > 1"
+}
@@ -0,0 +1,39 @@
+package devstats
+
+import (
+	"testing"
+
+	lib "devstats"
+)
+
+func TestRecentIDs(t *testing.T) {
+	recent := lib.NewRecentIDs(3)
+
+	// Test cases
+	var testCases = []struct {
+		id       string
+		expected bool
+		length   int
+	}{
+		{id: "1", expected: false, length: 1},
+		{id: "2", expected: false, length: 2},
+		{id: "1", expected: true, length: 2},
+		{id: "3", expected: false, length: 3},
+		{id: "4", expected: false, length: 3},
+		{id: "1", expected: false, length: 3},
+		{id: "2", expected: false, length: 3},
+		{id: "4", expected: true, length: 3},
+		{id: "3", expected: false, length: 3},
+		{id: "1", expected: true, length: 3},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := recent.Seen(test.id)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+		if l := recent.Len(); l != test.length {
+			t.Errorf("test number %d, expected length %d, got %d", index+1, test.length, l)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package devstats
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Derived metric operations, derived metric combines values of its two operands (SQL results or existing series) per period
+const (
+	DerivedRatio      = "ratio"
+	DerivedDifference = "difference"
+	DerivedSum        = "sum"
+)
+
+// DerivedSeriesPrefix - derived metric's operand with this prefix is an existing InfluxDB series, otherwise it is SQL
+const DerivedSeriesPrefix = "series:"
+
+// CheckDerivedOp returns error when `op` is not a known derived metric operation
+func CheckDerivedOp(op string) error {
+	switch op {
+	case DerivedRatio, DerivedDifference, DerivedSum:
+		return nil
+	}
+	return fmt.Errorf("unknown derived metric operation '%s', known: %s", op, strings.Join([]string{DerivedRatio, DerivedDifference, DerivedSum}, ", "))
+}
+
+// DerivedValue returns `a op b`, ratio with `b` = 0 is 0 (there is nothing to compare to in this period)
+func DerivedValue(op string, a, b float64) (float64, error) {
+	switch op {
+	case DerivedRatio:
+		if b == 0 {
+			return 0, nil
+		}
+		return a / b, nil
+	case DerivedDifference:
+		return a - b, nil
+	case DerivedSum:
+		return a + b, nil
+	}
+	return 0, CheckDerivedOp(op)
+}
+
+// CombineDerived combines operands values by row name ("" for queries returning a single value)
+// Row missing in one of the operands counts as 0 there
+func CombineDerived(op string, a, b map[string]float64) (map[string]float64, error) {
+	result := make(map[string]float64)
+	for _, values := range []map[string]float64{a, b} {
+		for name := range values {
+			if _, ok := result[name]; ok {
+				continue
+			}
+			value, err := DerivedValue(op, a[name], b[name])
+			if err != nil {
+				return nil, err
+			}
+			result[name] = value
+		}
+	}
+	return result, nil
+}
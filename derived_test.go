@@ -0,0 +1,94 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestDerivedValue(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		op       string
+		a        float64
+		b        float64
+		expected float64
+		err      bool
+	}{
+		{op: lib.DerivedRatio, a: 3, b: 4, expected: 0.75},
+		{op: lib.DerivedRatio, a: 3, b: 0, expected: 0},
+		{op: lib.DerivedRatio, a: 0, b: 0, expected: 0},
+		{op: lib.DerivedDifference, a: 3, b: 4, expected: -1},
+		{op: lib.DerivedSum, a: 3, b: 4, expected: 7},
+		{op: "product", a: 3, b: 4, err: true},
+		{op: "", a: 3, b: 4, err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.DerivedValue(test.op, test.a, test.b)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error for '%s', got %v", index+1, test.op, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestCombineDerived(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		op       string
+		a        map[string]float64
+		b        map[string]float64
+		expected map[string]float64
+	}{
+		{
+			op:       lib.DerivedRatio,
+			a:        map[string]float64{"": 10},
+			b:        map[string]float64{"": 40},
+			expected: map[string]float64{"": 0.25},
+		},
+		{
+			op:       lib.DerivedDifference,
+			a:        map[string]float64{"prs,Kubernetes": 5, "prs,Apps": 2},
+			b:        map[string]float64{"prs,Kubernetes": 3, "prs,Docs": 1},
+			expected: map[string]float64{"prs,Kubernetes": 2, "prs,Apps": 2, "prs,Docs": -1},
+		},
+		{
+			op:       lib.DerivedRatio,
+			a:        map[string]float64{},
+			b:        map[string]float64{"prs,Apps": 4},
+			expected: map[string]float64{"prs,Apps": 0},
+		},
+		{
+			op:       lib.DerivedSum,
+			a:        map[string]float64{},
+			b:        map[string]float64{},
+			expected: map[string]float64{},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.CombineDerived(test.op, test.a, test.b)
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+	_, err := lib.CombineDerived("product", map[string]float64{"": 1}, nil)
+	if err == nil {
+		t.Errorf("expected error for unknown operation")
+	}
+}
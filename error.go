@@ -3,11 +3,37 @@ package devstats
 import (
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/lib/pq"
 )
 
+// fatalErr - the first error passed to FatalOnError, reported by tools when they panic
+var (
+	fatalErr    error
+	fatalErrMtx sync.Mutex
+)
+
+// setFatalError - remembers the first fatal error
+func setFatalError(err error) {
+	fatalErrMtx.Lock()
+	if fatalErr == nil {
+		fatalErr = err
+	}
+	fatalErrMtx.Unlock()
+}
+
+// fatalErrorOr returns error passed to FatalOnError, or recovered panic value `r` when there was none
+func fatalErrorOr(r interface{}) string {
+	fatalErrMtx.Lock()
+	defer fatalErrMtx.Unlock()
+	if fatalErr != nil {
+		return fatalErr.Error()
+	}
+	return fmt.Sprintf("%v", r)
+}
+
 // FatalOnError displays error message (if error present) and exits program
 func FatalOnError(err error) string {
 	if err != nil {
@@ -21,6 +47,7 @@ func FatalOnError(err error) string {
 				return Retry
 			}
 		}
+		setFatalError(err)
 		panic("stacktrace")
 	}
 	return "ok"
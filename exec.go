@@ -2,11 +2,13 @@ package devstats
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -18,11 +20,54 @@ func logCommand(ctx *Ctx, cmdAndArgs []string, env map[string]string) {
 	}
 }
 
+// terminateOnCancel - sends SIGTERM to started command when `runCtx` is cancelled, so it can stop cleanly
+// Returned function must be called when command finished
+func terminateOnCancel(runCtx context.Context, cmd *exec.Cmd) func() {
+	if runCtx.Done() == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-runCtx.Done():
+			_ = cmd.Process.Signal(syscall.SIGTERM)
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// IsExitCode - is `err` returned by ExecCommand an exit of command with given exit code?
+func IsExitCode(err error, code int) bool {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.ExitStatus() == code
+}
+
 // ExecCommand - execute command given by array of strings with eventual environment map
 func ExecCommand(ctx *Ctx, cmdAndArgs []string, env map[string]string) (string, error) {
+	return ExecCommandContext(context.Background(), ctx, cmdAndArgs, env)
+}
+
+// ExecCommandContext - execute command like ExecCommand, command is not started when `runCtx` is already cancelled
+// and gets SIGTERM when it is cancelled while running (see SignalContext)
+// Command runs in its own process group then, so Ctrl-C from terminal doesn't reach it besides SIGTERM
+func ExecCommandContext(runCtx context.Context, ctx *Ctx, cmdAndArgs []string, env map[string]string) (string, error) {
 	// Execution time
 	dtStart := time.Now()
 
+	// Do not start anything when run was cancelled
+	if runCtx.Err() != nil {
+		err := fmt.Errorf("%s: %v", cmdAndArgs[0], ErrInterrupted)
+		if ctx.ExecFatal {
+			FatalOnError(err)
+		}
+		return "", err
+	}
+
 	// STDOUT pipe size
 	pipeSize := 0x100
 
@@ -45,6 +90,10 @@ func ExecCommand(ctx *Ctx, cmdAndArgs []string, env map[string]string) (string,
 		Printf("%s\n", strings.Join(args, " "))
 	}
 	cmd := exec.Command(command, arguments...)
+	if runCtx.Done() != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+	stopTerminate := func() {}
 
 	// Environment setup (if any)
 	if len(env) > 0 {
@@ -94,6 +143,7 @@ func ExecCommand(ctx *Ctx, cmdAndArgs []string, env map[string]string) (string,
 				return "", e
 			}
 		}
+		stopTerminate = terminateOnCancel(runCtx, cmd)
 		buffer := make([]byte, pipeSize, pipeSize)
 		nBytes, e := stdOutPipe.Read(buffer)
 		for e == nil && nBytes > 0 {
@@ -118,9 +168,16 @@ func ExecCommand(ctx *Ctx, cmdAndArgs []string, env map[string]string) (string,
 				return "", e
 			}
 		}
+		stopTerminate = terminateOnCancel(runCtx, cmd)
 	}
 	// Wait for command to finish
 	err := cmd.Wait()
+	stopTerminate()
+
+	// Command stopped because run was cancelled
+	if err != nil && runCtx.Err() != nil {
+		err = fmt.Errorf("%s: %v: %v", command, ErrInterrupted, err)
+	}
 
 	// If error - then output STDOUT, STDERR and error info
 	if err != nil {
@@ -0,0 +1,149 @@
+package devstats
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FunctionsDir - directory with SQL function packs installed by `structure` tool, project's own packs are in its subdirectory (like functions/kubernetes/)
+const FunctionsDir = "functions/"
+
+// functionVersionRe - version declared in pack's header: "-- version: 2"
+var functionVersionRe = regexp.MustCompile(`(?m)^--\s*version:\s*([0-9]+)\s*$`)
+
+// FunctionPack - SQL file with Postgres functions (using "create or replace function"), its version and hash of its SQL
+type FunctionPack struct {
+	Name    string
+	Version int
+	Hash    string
+	SQL     string
+}
+
+// ParseFunctionPack returns pack `name` with `sql`, it must declare its version in a "-- version: N" comment line
+func ParseFunctionPack(name, sql string) (FunctionPack, error) {
+	match := functionVersionRe.FindStringSubmatch(sql)
+	if match == nil {
+		return FunctionPack{}, fmt.Errorf("function pack '%s' has no version, add '-- version: 1' line", name)
+	}
+	version, err := strconv.Atoi(match[1])
+	if err != nil {
+		return FunctionPack{}, err
+	}
+	return FunctionPack{Name: name, Version: version, Hash: QueryHash(sql), SQL: sql}, nil
+}
+
+// readFunctionPacks adds packs from `dir`/*.sql to `packs` (replacing those with the same names), missing directory is not an error
+func readFunctionPacks(packs map[string]FunctionPack, dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".sql" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(file.Name(), ".sql")
+		pack, err := ParseFunctionPack(name, string(data))
+		if err != nil {
+			return err
+		}
+		packs[name] = pack
+	}
+	return nil
+}
+
+// ReadFunctionPacks returns packs from functions/*.sql and project's functions/project/*.sql (they replace shared packs with
+// the same names), ordered by name
+func ReadFunctionPacks(dataPrefix, project string) ([]FunctionPack, error) {
+	packs := make(map[string]FunctionPack)
+	err := readFunctionPacks(packs, dataPrefix+FunctionsDir)
+	if err != nil {
+		return nil, err
+	}
+	if project != "" {
+		err = readFunctionPacks(packs, dataPrefix+FunctionsDir+project)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result := []FunctionPack{}
+	for _, pack := range packs {
+		result = append(result, pack)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// InstallFunctionPacks installs packs that are not installed yet or whose version or SQL differs from installed ones
+// (saved in `gha_functions`), returns number of installed packs
+func InstallFunctionPacks(con *sql.DB, ctx *Ctx, packs []FunctionPack) (int, error) {
+	rows, err := QuerySQL(con, ctx, "select name, version, hash from gha_functions")
+	if err != nil {
+		return 0, err
+	}
+	installed := make(map[string]FunctionPack)
+	for rows.Next() {
+		var pack FunctionPack
+		err = rows.Scan(&pack.Name, &pack.Version, &pack.Hash)
+		if err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		installed[pack.Name] = pack
+	}
+	err = rows.Err()
+	if err != nil {
+		_ = rows.Close()
+		return 0, err
+	}
+	err = rows.Close()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, pack := range packs {
+		prev, ok := installed[pack.Name]
+		if ok && prev.Version == pack.Version && prev.Hash == pack.Hash {
+			continue
+		}
+		_, err = ExecSQL(con, ctx, pack.SQL)
+		if err != nil {
+			return n, fmt.Errorf("function pack '%s': %v", pack.Name, err)
+		}
+		_, err = ExecSQL(
+			con,
+			ctx,
+			"insert into gha_functions(name, version, hash, dt) "+NValues(4)+
+				" on conflict (name) do update set version = excluded.version, hash = excluded.hash, dt = excluded.dt",
+			pack.Name,
+			pack.Version,
+			pack.Hash,
+			time.Now(),
+		)
+		if err != nil {
+			return n, err
+		}
+		if ok {
+			Printf("Refreshed function pack %s: version %d -> %d\n", pack.Name, prev.Version, pack.Version)
+		} else {
+			Printf("Installed function pack %s: version %d\n", pack.Name, pack.Version)
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,13 @@
+-- Actors deduplication: actors sharing an email (gha_actors_emails, see util_sql/dup_actors.sql) are the same person
+-- devstats_actor_id(actor_id) returns the lowest id of them, count distinct devstats_actor_id(e.actor_id) to count people
+-- devstats_actor_key(login) returns login normalized for comparisons
+-- version: 1
+create or replace function devstats_actor_id(bigint) returns bigint as $$
+  select coalesce(min(e2.actor_id), $1)
+  from gha_actors_emails e1, gha_actors_emails e2
+  where e1.actor_id = $1 and e2.email = e1.email
+$$ language sql stable;
+
+create or replace function devstats_actor_key(text) returns text as $$
+  select lower(trim($1))
+$$ language sql immutable;
@@ -0,0 +1,6 @@
+-- Bot detection, use like: where not devstats_is_bot(a.login)
+-- Patterns are the same as in util_sql/exclude_bots.sql ({{exclude_bots}} macro), keep them in sync
+-- version: 1
+create or replace function devstats_is_bot(text) returns boolean as $$
+  select lower($1) like any(array['googlebot', 'coveralls', 'rktbot', 'k8s-%', '%-bot', '%-robot', 'bot-%', 'robot-%', '%[bot]%', '%-jenkins', '%-ci%bot', '%-testing', 'codecov-%'])
+$$ language sql immutable;
@@ -0,0 +1,24 @@
+-- Interval bucketing for histograms of durations (like time to merge or to first review)
+-- devstats_hours_bucket(hours) returns bucket name, devstats_hours_bucket_ord(hours) its order (for "order by")
+-- version: 1
+create or replace function devstats_hours_bucket(double precision) returns text as $$
+  select case
+    when $1 < 1 then '< 1 hour'
+    when $1 < 6 then '1 - 6 hours'
+    when $1 < 24 then '6 - 24 hours'
+    when $1 < 168 then '1 - 7 days'
+    when $1 < 720 then '7 - 30 days'
+    else '> 30 days'
+  end
+$$ language sql immutable;
+
+create or replace function devstats_hours_bucket_ord(double precision) returns int as $$
+  select case
+    when $1 < 1 then 1
+    when $1 < 6 then 2
+    when $1 < 24 then 3
+    when $1 < 168 then 4
+    when $1 < 720 then 5
+    else 6
+  end
+$$ language sql immutable;
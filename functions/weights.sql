@@ -0,0 +1,35 @@
+-- Event weights by size, so contribution metrics can reflect effort instead of counting events (metrics.yaml `weight`)
+-- devstats_event_weight(event_id, type, mode), mode: 'count' - 1, 'lines' - lines added and removed, 'log_lines' - 1 + ln(1 + lines),
+-- 'files' - files changed. Pushes use stats of their commits (gha_commits_stats), pull requests their additions, deletions
+-- and changed files. Every event weighs at least 1, other events and events without stats weigh 1
+-- version: 1
+create or replace function devstats_event_lines(bigint, text) returns bigint as $$
+  select case $2
+    when 'PushEvent' then (
+      select sum(s.added + s.removed) from gha_commits c, gha_commits_stats s where c.event_id = $1 and s.sha = c.sha
+    )
+    when 'PullRequestEvent' then (
+      select max(coalesce(pr.additions, 0) + coalesce(pr.deletions, 0)) from gha_pull_requests pr where pr.event_id = $1
+    )
+  end
+$$ language sql stable;
+
+create or replace function devstats_event_files(bigint, text) returns bigint as $$
+  select case $2
+    when 'PushEvent' then (
+      select sum(s.files) from gha_commits c, gha_commits_stats s where c.event_id = $1 and s.sha = c.sha
+    )
+    when 'PullRequestEvent' then (
+      select max(pr.changed_files) from gha_pull_requests pr where pr.event_id = $1
+    )
+  end
+$$ language sql stable;
+
+create or replace function devstats_event_weight(bigint, text, text) returns double precision as $$
+  select case $3
+    when 'lines' then greatest(coalesce(devstats_event_lines($1, $2), 1), 1)::double precision
+    when 'log_lines' then 1 + ln(1 + coalesce(devstats_event_lines($1, $2), 0))
+    when 'files' then greatest(coalesce(devstats_event_files($1, $2), 1), 1)::double precision
+    else 1
+  end
+$$ language sql stable;
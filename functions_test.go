@@ -0,0 +1,111 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestParseFunctionPack(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		sql     string
+		version int
+		err     bool
+	}{
+		{sql: "-- Bots\n-- version: 3\ncreate or replace function f() returns int as $$ select 1 $$ language sql;", version: 3},
+		{sql: "--version:12\nselect 1", version: 12},
+		{sql: "create or replace function f() returns int as $$ select 1 $$ language sql;", err: true},
+		{sql: "-- version: x\nselect 1", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.ParseFunctionPack("pack", test.sql)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got %+v", index+1, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if got.Name != "pack" || got.Version != test.version || got.SQL != test.sql || got.Hash != lib.QueryHash(test.sql) {
+			t.Errorf("test number %d, expected version %d, got %+v", index+1, test.version, got)
+		}
+	}
+}
+
+func TestReadFunctionPacks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "functions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	files := map[string]string{
+		"functions/bots.sql":            "-- version: 1\nselect 'bots'",
+		"functions/intervals.sql":       "-- version: 2\nselect 'intervals'",
+		"functions/README.md":           "not a pack",
+		"functions/kubernetes/bots.sql": "-- version: 5\nselect 'k8s bots'",
+		"functions/kubernetes/k8s.sql":  "-- version: 1\nselect 'k8s'",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prefix := dir + "/"
+
+	// Test cases
+	var testCases = []struct {
+		project  string
+		expected []string
+	}{
+		{project: "", expected: []string{"bots:1", "intervals:2"}},
+		{project: "kubernetes", expected: []string{"bots:5", "intervals:2", "k8s:1"}},
+		{project: "prometheus", expected: []string{"bots:1", "intervals:2"}},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		packs, err := lib.ReadFunctionPacks(prefix, test.project)
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		got := []string{}
+		for _, pack := range packs {
+			got = append(got, pack.Name+":"+strconv.Itoa(pack.Version))
+		}
+		if len(got) != len(test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.expected[i] {
+				t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+				break
+			}
+		}
+	}
+
+	// Missing directory has no packs, pack without version is an error
+	packs, err := lib.ReadFunctionPacks(prefix+"missing/", "kubernetes")
+	if err != nil || len(packs) != 0 {
+		t.Errorf("expected no packs, got %+v, %v", packs, err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "functions/bad.sql"), []byte("select 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lib.ReadFunctionPacks(prefix, ""); err == nil {
+		t.Errorf("expected error for pack without version")
+	}
+}
@@ -0,0 +1,424 @@
+package devstats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gerritPageSize - number of changes requested per page
+const gerritPageSize = 500
+
+// gerritTimeFormat - Gerrit REST API timestamps are UTC without time zone
+const gerritTimeFormat = "2006-01-02 15:04:05.999999999"
+
+// gerritMagic - prefix of every Gerrit REST API JSON response (protection against XSSI)
+var gerritMagic = []byte(")]}'")
+
+// GerritTime - Gerrit REST API timestamp
+type GerritTime struct {
+	time.Time
+}
+
+// UnmarshalJSON parses Gerrit timestamp like "2017-08-01 10:00:00.000000000"
+func (t *GerritTime) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	if s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+	t.Time, err = time.Parse(gerritTimeFormat, s)
+	return err
+}
+
+// GerritAccount - Gerrit REST API account
+type GerritAccount struct {
+	AccountID int    `json:"_account_id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Username  string `json:"username"`
+}
+
+// GerritCommit - Gerrit REST API commit info of a patch set
+type GerritCommit struct {
+	Parents []struct {
+		Commit string `json:"commit"`
+	} `json:"parents"`
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+}
+
+// GerritRevision - Gerrit REST API revision (patch set) info
+type GerritRevision struct {
+	Number   int           `json:"_number"`
+	Created  GerritTime    `json:"created"`
+	Uploader GerritAccount `json:"uploader"`
+	Ref      string        `json:"ref"`
+	Commit   *GerritCommit `json:"commit"`
+	// Revision SHA is the key of change's revisions map
+	SHA string `json:"-"`
+}
+
+// GerritMessage - Gerrit REST API change message (patch set uploads, votes, review comments, status changes)
+type GerritMessage struct {
+	ID             string         `json:"id"`
+	Author         *GerritAccount `json:"author"`
+	Date           GerritTime     `json:"date"`
+	Message        string         `json:"message"`
+	RevisionNumber int            `json:"_revision_number"`
+	Tag            string         `json:"tag"`
+}
+
+// GerritChange - Gerrit REST API change info (requested with all revisions, commits and messages)
+type GerritChange struct {
+	ID          string                     `json:"id"`
+	Project     string                     `json:"project"`
+	Branch      string                     `json:"branch"`
+	ChangeID    string                     `json:"change_id"`
+	Subject     string                     `json:"subject"`
+	Status      string                     `json:"status"`
+	Created     GerritTime                 `json:"created"`
+	Updated     GerritTime                 `json:"updated"`
+	Submitted   *GerritTime                `json:"submitted"`
+	Submitter   *GerritAccount             `json:"submitter"`
+	Number      int                        `json:"_number"`
+	Owner       GerritAccount              `json:"owner"`
+	Insertions  int                        `json:"insertions"`
+	Deletions   int                        `json:"deletions"`
+	Revisions   map[string]*GerritRevision `json:"revisions"`
+	Messages    []GerritMessage            `json:"messages"`
+	Reviewers   map[string][]GerritAccount `json:"reviewers"`
+	MoreChanges bool                       `json:"_more_changes"`
+}
+
+// GerritMessageKind returns what change message records: "patch_set", "merged", "abandoned", "restored" or "review"
+// Messages are recognized by tags, older Gerrit versions have no tags, so message texts are checked too
+func GerritMessageKind(m *GerritMessage) string {
+	switch m.Tag {
+	case "autogenerated:gerrit:newPatchSet", "autogenerated:gerrit:newWipPatchSet":
+		return "patch_set"
+	case "autogenerated:gerrit:merged":
+		return "merged"
+	case "autogenerated:gerrit:abandon":
+		return "abandoned"
+	case "autogenerated:gerrit:restore":
+		return "restored"
+	}
+	if m.Tag != "" {
+		return "review"
+	}
+	switch {
+	case strings.HasPrefix(m.Message, "Uploaded patch set "):
+		return "patch_set"
+	case strings.HasPrefix(m.Message, "Change has been successfully merged"):
+		return "merged"
+	case strings.HasPrefix(m.Message, "Abandoned"):
+		return "abandoned"
+	case strings.HasPrefix(m.Message, "Restored"):
+		return "restored"
+	}
+	return "review"
+}
+
+// gerritActor - Gerrit account as GHA actor, accounts are identified by usernames (emails or names when there is no username)
+// Gerrit account IDs would collide with GitHub IDs, so hashed logins are used as IDs (like pre 2015 actors)
+func gerritActor(a *GerritAccount) Actor {
+	login := a.Username
+	if login == "" {
+		login = a.Email
+	}
+	if login == "" {
+		login = a.Name
+	}
+	if login == "" {
+		login = strconv.Itoa(a.AccountID)
+	}
+	return Actor{ID: HashStrings([]string{login}), Login: login, Name: a.Name}
+}
+
+// gerritRevisions - change's revisions ordered by patch set number
+func gerritRevisions(c *GerritChange) []*GerritRevision {
+	revisions := []*GerritRevision{}
+	for sha, rev := range c.Revisions {
+		rev.SHA = sha
+		revisions = append(revisions, rev)
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Number < revisions[j].Number })
+	return revisions
+}
+
+// gerritEvent - single change event, before it is mapped onto GHA event
+type gerritEvent struct {
+	key      string
+	kind     string
+	dt       time.Time
+	actor    *GerritAccount
+	revision *GerritRevision
+	message  *GerritMessage
+}
+
+// GerritChangeEvents maps Gerrit change onto GHA events ordered by time
+// Change creation is "opened" PullRequestEvent, next patch sets are "synchronize", merge and abandon are "closed" and restore is "reopened"
+// Review messages (votes, comments, CI results) are PullRequestReviewCommentEvents
+// Pull request state in every event is the one from the event time
+// IDs are hashes (negative), so they never collide with GitHub IDs of a mirror repository
+func GerritChangeEvents(c *GerritChange) []*Event {
+	revisions := gerritRevisions(c)
+	if len(revisions) == 0 {
+		return nil
+	}
+	byNumber := make(map[int]*GerritRevision)
+	for _, rev := range revisions {
+		byNumber[rev.Number] = rev
+	}
+	events := []gerritEvent{}
+	for i, rev := range revisions {
+		kind := "synchronize"
+		actor := &rev.Uploader
+		if i == 0 {
+			kind = "opened"
+			actor = &c.Owner
+		}
+		events = append(events, gerritEvent{key: rev.SHA, kind: kind, dt: rev.Created.Time, actor: actor, revision: rev})
+	}
+	for i := range c.Messages {
+		m := &c.Messages[i]
+		kind := GerritMessageKind(m)
+		if kind == "patch_set" || m.Author == nil {
+			continue
+		}
+		rev := byNumber[m.RevisionNumber]
+		if rev == nil {
+			rev = revisions[len(revisions)-1]
+		}
+		events = append(events, gerritEvent{key: m.ID, kind: kind, dt: m.Date.Time, actor: m.Author, revision: rev, message: m})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].dt.Before(events[j].dt) })
+
+	repo := Repo{ID: HashStrings([]string{c.Project}), Name: c.Project}
+	var org *Org
+	if i := strings.Index(c.Project, "/"); i > 0 {
+		login := c.Project[:i]
+		org = &Org{ID: HashStrings([]string{login}), Login: login}
+	}
+	prID := HashStrings([]string{OriginGerrit, c.ID})
+	owner := gerritActor(&c.Owner)
+	reviewers := []Actor{}
+	for i := range c.Reviewers["REVIEWER"] {
+		reviewer := gerritActor(&c.Reviewers["REVIEWER"][i])
+		if reviewer.Login != owner.Login {
+			reviewers = append(reviewers, reviewer)
+		}
+	}
+	commits := 1
+	additions, deletions := c.Insertions, c.Deletions
+
+	ghaEvents := []*Event{}
+	state := "open"
+	var closedAt, mergedAt *time.Time
+	var mergedBy *Actor
+	comments := 0
+	head := revisions[0]
+	for i := range events {
+		e := &events[i]
+		actor := gerritActor(e.actor)
+		evType := "PullRequestEvent"
+		action := e.kind
+		switch e.kind {
+		case "synchronize":
+			head = e.revision
+		case "merged":
+			dt := e.dt
+			state, closedAt, mergedAt, mergedBy = "closed", &dt, &dt, &actor
+			action = "closed"
+		case "abandoned":
+			dt := e.dt
+			state, closedAt = "closed", &dt
+			action = "closed"
+		case "restored":
+			state, closedAt = "open", nil
+			action = "reopened"
+		case "review":
+			evType = "PullRequestReviewCommentEvent"
+			action = "created"
+			comments++
+		}
+		title, body := c.Subject, c.Subject
+		if head.Commit != nil {
+			title, body = head.Commit.Subject, head.Commit.Message
+		}
+		base := Branch{Label: c.Branch, Ref: c.Branch}
+		if head.Commit != nil && len(head.Commit.Parents) > 0 {
+			base.SHA = head.Commit.Parents[0].Commit
+		}
+		merged := mergedAt != nil
+		nComments := comments
+		pr := &PullRequest{
+			ID:                 prID,
+			Base:               base,
+			Head:               Branch{SHA: head.SHA, Label: c.ChangeID, Ref: head.Ref},
+			User:               owner,
+			Number:             c.Number,
+			State:              state,
+			Title:              title,
+			Body:               &body,
+			CreatedAt:          c.Created.Time,
+			UpdatedAt:          e.dt,
+			ClosedAt:           closedAt,
+			MergedAt:           mergedAt,
+			RequestedReviewers: &reviewers,
+			Merged:             &merged,
+			MergedBy:           mergedBy,
+			Comments:           &nComments,
+			Commits:            &commits,
+			Additions:          &additions,
+			Deletions:          &deletions,
+		}
+		if merged {
+			sha := head.SHA
+			pr.MergeCommitSHA = &sha
+		}
+		ev := &Event{
+			ID:        strconv.Itoa(HashStrings([]string{OriginGerrit, c.ID, e.kind, e.key})),
+			Type:      evType,
+			Public:    true,
+			CreatedAt: e.dt,
+			Actor:     actor,
+			Repo:      repo,
+			Org:       org,
+			Origin:    OriginGerrit,
+		}
+		number := c.Number
+		ev.Payload.Action = &action
+		ev.Payload.Number = &number
+		ev.Payload.PullRequest = pr
+		if e.message != nil && evType == "PullRequestReviewCommentEvent" {
+			sha := e.revision.SHA
+			ev.Payload.Comment = &Comment{
+				ID:        HashStrings([]string{OriginGerrit, c.ID, e.message.ID}),
+				Body:      e.message.Message,
+				CreatedAt: e.dt,
+				UpdatedAt: e.dt,
+				User:      actor,
+				CommitID:  &sha,
+			}
+		}
+		ghaEvents = append(ghaEvents, ev)
+	}
+	return ghaEvents
+}
+
+// GerritDayJSONs returns events of given changes created at a given day (UTC) as GHA JSON lines, grouped by hour of day
+func GerritDayJSONs(changes []GerritChange, day time.Time) (map[int][]byte, error) {
+	day = DayStart(day)
+	ghaEvents := []*Event{}
+	for i := range changes {
+		for _, ev := range GerritChangeEvents(&changes[i]) {
+			if DayStart(ev.CreatedAt).Equal(day) {
+				ghaEvents = append(ghaEvents, ev)
+			}
+		}
+	}
+	return hourJSONs(ghaEvents)
+}
+
+// GerritClient - minimal Gerrit REST API client
+type GerritClient struct {
+	url      string
+	user     string
+	password string
+	client   *http.Client
+}
+
+// NewGerritClient creates client using ctx.GerritURL and ctx.GerritToken
+// ctx.GerritToken is "username:HTTP password" (or a file name to read it from), empty means anonymous access
+func NewGerritClient(ctx *Ctx) *GerritClient {
+	c := &GerritClient{
+		url:    strings.TrimSuffix(ctx.GerritURL, "/"),
+		client: &http.Client{Timeout: 5 * time.Minute},
+	}
+	if token := readToken(ctx.GerritToken); token != "" {
+		ary := strings.SplitN(token, ":", 2)
+		if len(ary) != 2 {
+			FatalOnError(fmt.Errorf("Gerrit token must be in 'username:password' format"))
+		}
+		c.user, c.password = ary[0], ary[1]
+	}
+	return c
+}
+
+// get - requests Gerrit REST API path, authenticated requests use "/a/" prefix
+func (c *GerritClient) get(path string, params url.Values, out interface{}) error {
+	target := c.url
+	headers := make(map[string]string)
+	if c.user != "" {
+		target += "/a"
+		req := http.Request{Header: http.Header{}}
+		req.SetBasicAuth(c.user, c.password)
+		headers["Authorization"] = req.Header.Get("Authorization")
+	}
+	target += path
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+	_, data, err := RESTGet(c.client, target, headers)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes.TrimPrefix(data, gerritMagic), out)
+}
+
+// GerritQuery returns Gerrit query selecting changes of orgs (project prefixes) or projects updated since a given time
+func GerritQuery(forg map[string]struct{}, since time.Time) string {
+	projects := []string{}
+	for _, name := range StringsSetKeys(forg) {
+		if strings.Contains(name, "/") {
+			projects = append(projects, "project:"+name)
+		} else {
+			projects = append(projects, "projects:"+name+"/")
+		}
+	}
+	return fmt.Sprintf("(%s) after:\"%s\"", strings.Join(projects, " OR "), since.UTC().Format("2006-01-02 15:04:05"))
+}
+
+// Changes returns changes of projects matching orgs/repos filter, updated since a given time
+// Orgs are Gerrit project prefixes (like "openstack") or full project names, filter has the same meaning as in RepoHit
+func (c *GerritClient) Changes(exact bool, forg, frepo map[string]struct{}, since time.Time) ([]GerritChange, error) {
+	if len(forg) == 0 {
+		return nil, fmt.Errorf("Gerrit source needs Gerrit project prefixes or projects given as orgs")
+	}
+	params := url.Values{
+		"q": {GerritQuery(forg, since)},
+		"o": {"ALL_REVISIONS", "ALL_COMMITS", "MESSAGES", "DETAILED_ACCOUNTS", "DETAILED_LABELS"},
+		"n": {strconv.Itoa(gerritPageSize)},
+	}
+	changes := []GerritChange{}
+	for start := 0; ; {
+		params.Set("S", strconv.Itoa(start))
+		var page []GerritChange
+		err := c.get("/changes/", params, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range page {
+			if RepoHit(exact, change.Project, forg, frepo) {
+				changes = append(changes, change)
+			}
+		}
+		start += len(page)
+		if len(page) == 0 || !page[len(page)-1].MoreChanges {
+			break
+		}
+	}
+	return changes, nil
+}
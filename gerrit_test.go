@@ -0,0 +1,139 @@
+package devstats
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestGerritMessageKind(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		tag      string
+		message  string
+		expected string
+	}{
+		{tag: "autogenerated:gerrit:newPatchSet", message: "Uploaded patch set 2.", expected: "patch_set"},
+		{tag: "autogenerated:gerrit:merged", message: "Change has been successfully merged by Jane", expected: "merged"},
+		{tag: "autogenerated:gerrit:abandon", message: "Abandoned", expected: "abandoned"},
+		{tag: "autogenerated:gerrit:restore", message: "Restored", expected: "restored"},
+		{tag: "autogenerated:zuul:check", message: "Build succeeded (check pipeline).", expected: "review"},
+		{message: "Uploaded patch set 3: Patch Set 2 was rebased.", expected: "patch_set"},
+		{message: "Change has been successfully cherry-picked as abc", expected: "review"},
+		{message: "Abandoned\n\nNot needed anymore", expected: "abandoned"},
+		{message: "Patch Set 2: Code-Review+2\n\n(1 comment)", expected: "review"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.GerritMessageKind(&lib.GerritMessage{Tag: test.tag, Message: test.message})
+		if got != test.expected {
+			t.Errorf("test number %d, expected %s, got %s", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestGerritQuery(t *testing.T) {
+	got := lib.GerritQuery(
+		map[string]struct{}{"openstack": {}, "zuul/zuul": {}},
+		time.Date(2017, 8, 1, 10, 0, 0, 0, time.UTC),
+	)
+	expected := `(projects:openstack/ OR project:zuul/zuul) after:"2017-08-01 10:00:00"`
+	if got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestGerritChangeEvents(t *testing.T) {
+	var change lib.GerritChange
+	err := json.Unmarshal(
+		[]byte(`{
+			"id": "openstack%2Fnova~master~I123", "project": "openstack/nova", "branch": "master", "change_id": "I123",
+			"subject": "Fix race", "status": "MERGED", "_number": 4711, "insertions": 10, "deletions": 2,
+			"created": "2017-08-01 10:00:00.000000000", "updated": "2017-08-02 12:00:00.000000000",
+			"owner": {"_account_id": 1, "name": "Jane Doe", "username": "jane"},
+			"reviewers": {"REVIEWER": [{"_account_id": 1, "username": "jane"}, {"_account_id": 2, "username": "joe"}]},
+			"revisions": {
+				"bbb": {"_number": 2, "created": "2017-08-02 09:00:00.000000000", "uploader": {"_account_id": 1, "username": "jane"},
+					"ref": "refs/changes/11/4711/2", "commit": {"parents": [{"commit": "base2"}], "subject": "Fix race", "message": "Fix race\n\nDetails"}},
+				"aaa": {"_number": 1, "created": "2017-08-01 10:00:00.000000000", "uploader": {"_account_id": 1, "username": "jane"},
+					"ref": "refs/changes/11/4711/1", "commit": {"parents": [{"commit": "base1"}], "subject": "Fix race", "message": "Fix race"}}
+			},
+			"messages": [
+				{"id": "m1", "author": {"_account_id": 1, "username": "jane"}, "date": "2017-08-01 10:00:00.000000000",
+					"message": "Uploaded patch set 1.", "_revision_number": 1, "tag": "autogenerated:gerrit:newPatchSet"},
+				{"id": "m2", "author": {"_account_id": 2, "username": "joe"}, "date": "2017-08-01 15:00:00.000000000",
+					"message": "Patch Set 1: Code-Review-1\n\nPlease add a test", "_revision_number": 1},
+				{"id": "m3", "author": {"_account_id": 1, "username": "jane"}, "date": "2017-08-02 09:00:00.000000000",
+					"message": "Uploaded patch set 2.", "_revision_number": 2, "tag": "autogenerated:gerrit:newPatchSet"},
+				{"id": "m4", "author": {"_account_id": 3, "username": "core"}, "date": "2017-08-02 12:00:00.000000000",
+					"message": "Change has been successfully merged by Core", "_revision_number": 2, "tag": "autogenerated:gerrit:merged"}
+			]
+		}`),
+		&change,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := lib.GerritChangeEvents(&change)
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %d", len(events))
+	}
+
+	// Test cases
+	var testCases = []struct {
+		evType  string
+		action  string
+		actor   string
+		state   string
+		head    string
+		merged  bool
+		comment bool
+	}{
+		{evType: "PullRequestEvent", action: "opened", actor: "jane", state: "open", head: "aaa"},
+		{evType: "PullRequestReviewCommentEvent", action: "created", actor: "joe", state: "open", head: "aaa", comment: true},
+		{evType: "PullRequestEvent", action: "synchronize", actor: "jane", state: "open", head: "bbb"},
+		{evType: "PullRequestEvent", action: "closed", actor: "core", state: "closed", head: "bbb", merged: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		ev := events[index]
+		pr := ev.Payload.PullRequest
+		if ev.Type != test.evType || *ev.Payload.Action != test.action || ev.Actor.Login != test.actor {
+			t.Errorf("test number %d, expected %s/%s by %s, got %s/%s by %s", index+1, test.evType, test.action, test.actor, ev.Type, *ev.Payload.Action, ev.Actor.Login)
+		}
+		if pr.State != test.state || pr.Head.SHA != test.head || *pr.Merged != test.merged || (pr.MergedAt != nil) != test.merged {
+			t.Errorf("test number %d, unexpected pull request state: %+v", index+1, pr)
+		}
+		if (ev.Payload.Comment != nil) != test.comment {
+			t.Errorf("test number %d, unexpected comment: %+v", index+1, ev.Payload.Comment)
+		}
+		if ev.Origin != lib.OriginGerrit || ev.Repo.Name != "openstack/nova" || ev.Org.Login != "openstack" || pr.Number != 4711 {
+			t.Errorf("test number %d, unexpected event: %+v", index+1, ev)
+		}
+	}
+
+	// IDs are negative (no collisions with GitHub mirror IDs) and stable
+	again := lib.GerritChangeEvents(&change)
+	for index, ev := range events {
+		if ev.ID[0] != '-' || ev.ID != again[index].ID || ev.Payload.PullRequest.ID >= 0 || ev.Actor.ID >= 0 {
+			t.Errorf("test number %d, unexpected IDs: %s, %d, %d", index+1, ev.ID, ev.Payload.PullRequest.ID, ev.Actor.ID)
+		}
+	}
+	if pr := events[2].Payload.PullRequest; pr.Base.SHA != "base2" || *pr.Body != "Fix race\n\nDetails" || len(*pr.RequestedReviewers) != 1 {
+		t.Errorf("unexpected second patch set pull request: %+v", pr)
+	}
+	if comment := events[1].Payload.Comment; *comment.CommitID != "aaa" || comment.User.Login != "joe" {
+		t.Errorf("unexpected review comment: %+v", comment)
+	}
+
+	// Events are grouped by day and hour
+	jsons, err := lib.GerritDayJSONs([]lib.GerritChange{change}, time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jsons) != 2 || len(jsons[10]) == 0 || len(jsons[15]) == 0 {
+		t.Errorf("expected events at 10 and 15, got %d hours", len(jsons))
+	}
+}
@@ -12,16 +12,55 @@ type AllProjects struct {
 
 // Project contain mapping from project name to its command line used to sync it
 type Project struct {
-	CommandLine      string     `yaml:"command_line"`
-	StartDate        *time.Time `yaml:"start_date"`
-	PDB              string     `yaml:"psql_db"`
-	IDB              string     `yaml:"influx_db"`
-	Disabled         bool       `yaml:"disabled"`
-	MainRepo         string     `yaml:"main_repo"`
-	AnnotationRegexp string     `yaml:"annotation_regexp"`
-	Order            int        `yaml:"order"`
-	JoinDate         *time.Time `yaml:"join_date"`
-	FilesSkipPattern string     `yaml:"files_skip_pattern"`
+	CommandLine      string               `yaml:"command_line"`
+	StartDate        *time.Time           `yaml:"start_date"`
+	PDB              string               `yaml:"psql_db"`
+	IDB              string               `yaml:"influx_db"`
+	Disabled         bool                 `yaml:"disabled"`
+	MainRepo         string               `yaml:"main_repo"`
+	AnnotationRegexp string               `yaml:"annotation_regexp"`
+	Order            int                  `yaml:"order"`
+	JoinDate         *time.Time           `yaml:"join_date"`
+	FilesSkipPattern string               `yaml:"files_skip_pattern"`
+	CloneAuth        map[string]CloneAuth `yaml:"clone_auth"`
+	CloneURLs        map[string]string    `yaml:"clone_urls"`
+	LFSFetch         bool                 `yaml:"lfs_fetch"`
+	EventTypes       []string             `yaml:"event_types"`
+	ReposInclude     []string             `yaml:"repos_include"`
+	ReposExclude     []string             `yaml:"repos_exclude"`
+	Bots             *BotsConfig          `yaml:"bots"`
+	GitLab           bool                 `yaml:"gitlab"`
+	Gitea            *APISource           `yaml:"gitea"`
+	Gerrit           *APISource           `yaml:"gerrit"`
+	RawJSON          bool                 `yaml:"raw_json"`
+	RawJSONDays      int                  `yaml:"raw_json_days"`
+	RepoGroups       []RepoGroupRule      `yaml:"repo_groups"`
+	SyncSchedule     string               `yaml:"sync_schedule"`
+	TimeZone         string               `yaml:"time_zone"`
+	Replicas         []string             `yaml:"replicas"`
+	DependsOn        []string             `yaml:"depends_on"`
+	K8s              *K8sJob              `yaml:"k8s"`
+}
+
+// K8sJob - project's Kubernetes CronJob settings used by `k8s_cronjobs`, resources are Kubernetes quantities (like "500m" or "2Gi")
+type K8sJob struct {
+	CPU         string            `yaml:"cpu"`
+	Memory      string            `yaml:"memory"`
+	CPULimit    string            `yaml:"cpu_limit"`
+	MemoryLimit string            `yaml:"memory_limit"`
+	Env         map[string]string `yaml:"env"`
+}
+
+// APISource - Gitea/Forgejo instance or Gerrit server project's events are read from (token can be a file name to read it from)
+type APISource struct {
+	URL   string `yaml:"url"`
+	Token string `yaml:"token"`
+}
+
+// CloneAuth contains per org git credentials used by `get_repos` (overrides GHA2DB_GIT_TOKEN, GHA2DB_GIT_SSH_KEY)
+type CloneAuth struct {
+	Token  string `yaml:"token"`
+	SSHKey string `yaml:"ssh_key"`
 }
 
 // AnyArray - holds array of interface{} - just a shortcut
@@ -31,6 +70,14 @@ type AnyArray []interface{}
 // pointer to this struct is used to test if such field was present in JSON or not
 type Dummy struct{}
 
+// Event origins saved in `gha_events` and `gha_pull_requests` `origin` columns
+const (
+	OriginGitHub = "github"
+	OriginGitLab = "gitlab"
+	OriginGitea  = "gitea"
+	OriginGerrit = "gerrit"
+)
+
 // Event - full GHA (GitHub Archive) event structure
 type Event struct {
 	ID        string    `json:"id"`
@@ -41,6 +88,14 @@ type Event struct {
 	Repo      Repo      `json:"repo"`
 	Org       *Org      `json:"org"`
 	Payload   Payload   `json:"payload"`
+	// Only set for events mapped from other sources (GitHub Archive events have no origin)
+	Origin string `json:"origin,omitempty"`
+	// Only set for events normalized from pre 2015 format
+	Old *EventOldInfo `json:"-"`
+	// Only set in GHA2DB_PAYLOAD_OVERFLOW mode: payload fields without Payload structure fields (JSON object, see PayloadOverflow)
+	Overflow []byte `json:"-"`
+	// Only set in GHA2DB_RAW_JSON mode: full event JSON as read from GHA file
+	Raw []byte `json:"-"`
 }
 
 // EventOld - full GHA (GitHub Archive) event structure, before 2015
@@ -74,6 +129,11 @@ type Payload struct {
 	Commits      *[]Commit    `json:"commits"`
 	Pages        *[]Page      `json:"pages"`
 	PullRequest  *PullRequest `json:"pull_request"`
+	// Fields below are only set for events normalized from pre 2015 format
+	IssueID   *int    `json:"-"`
+	CommentID *int    `json:"-"`
+	Commit    *string `json:"-"`
+	Team      *Team   `json:"-"`
 }
 
 // PayloadOld - GHA Payload structure (from before 2015)
@@ -335,6 +395,14 @@ type Team struct {
 	Permission string `json:"permission"`
 }
 
+// EventOrigin returns event's origin, events without origin come from GitHub
+func EventOrigin(ev *Event) string {
+	if ev.Origin == "" {
+		return OriginGitHub
+	}
+	return ev.Origin
+}
+
 // RepoHit - are we interested in this org/repo ?
 func RepoHit(exact bool, fullName string, forg, frepo map[string]struct{}) bool {
 	// Return false if no repo name
@@ -0,0 +1,17 @@
+#!/bin/sh
+if [ -z "$1" ]
+then
+  echo "Arguments required: path, optional: sha (only commits after it), none given"
+  exit 1
+fi
+
+cd "$1" || exit 2
+git rev-parse HEAD || exit 3
+range="HEAD"
+if [ ! -z "$2" ]
+then
+  # History was rewritten (or sha is gone), caller should process all commits
+  git merge-base --is-ancestor "$2" HEAD 2>/dev/null || exit 4
+  range="$2..HEAD"
+fi
+git log --reverse --no-merges --no-renames --numstat --format='♂♀%H♂♀%an♂♀%ae♂♀%ct' "$range" || exit 5
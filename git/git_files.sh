@@ -17,7 +17,8 @@ git show -s --format=%ct "$2" || exit 4
 files=`git diff-tree --no-commit-id --name-only -M7 -r "$2"` || exit 5
 for file in $files
 do
-    file_and_size=`git ls-tree -r -l "$2" "$file" | awk '{print $5 "♂♀" $4}'`
+    # Submodule bumps (gitlinks, type 'commit') are reported with size -3
+    file_and_size=`git ls-tree -r -l "$2" "$file" | awk '{ if ($2 == "commit") print $5 "♂♀-3"; else print $5 "♂♀" $4 }'`
     if [ -z "$file_and_size" ]
     then
       echo "$file♂♀-1"
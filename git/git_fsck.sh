@@ -0,0 +1,9 @@
+#!/bin/sh
+if [ -z "$1" ]
+then
+  echo "Argument required: path to call git-fsck"
+  exit 1
+fi
+
+cd "$1" || exit 2
+git fsck --connectivity-only --no-dangling --no-progress || exit 3
@@ -0,0 +1,15 @@
+#!/bin/sh
+if [ -z "$1" ]
+then
+  echo "Argument required: path to fetch git LFS objects"
+  exit 1
+fi
+
+cd "$1" || exit 2
+git lfs version > /dev/null 2>&1 || { echo "git-lfs is not installed"; exit 3; }
+if [ "`git rev-parse --is-bare-repository`" = "true" ]
+then
+  git lfs fetch --all || exit 4
+else
+  git lfs pull || exit 4
+fi
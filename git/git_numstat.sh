@@ -0,0 +1,14 @@
+#!/bin/sh
+if [ -z "$1" ]
+then
+  echo "Arguments required: path sha, none given"
+  exit 1
+fi
+if [ -z "$2" ]
+then
+  echo "Arguments required: path sha, only path given"
+  exit 2
+fi
+
+cd "$1" || exit 3
+git log -1 --numstat --format= -M7 "$2" || exit 4
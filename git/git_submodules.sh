@@ -0,0 +1,19 @@
+#!/bin/sh
+if [ -z "$1" ]
+then
+  echo "Argument required: path to call git-submodule update, optional: depth"
+  exit 1
+fi
+
+cd "$1" || exit 2
+if [ ! -f ".gitmodules" ]
+then
+  exit 0
+fi
+git submodule sync --recursive || exit 3
+if [ -z "$2" ]
+then
+  git submodule update --init --recursive --force || exit 4
+else
+  git submodule update --init --recursive --force --depth "$2" || exit 4
+fi
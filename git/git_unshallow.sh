@@ -0,0 +1,12 @@
+#!/bin/sh
+if [ -z "$1" ]
+then
+  echo "Argument required: path to call git-fetch --unshallow"
+  exit 1
+fi
+
+cd "$1" || exit 2
+if [ -f "`git rev-parse --git-dir`/shallow" ]
+then
+  git fetch --unshallow || exit 3
+fi
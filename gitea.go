@@ -0,0 +1,491 @@
+package devstats
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// giteaPageSize - number of objects requested per page (default maximum of Gitea and Forgejo instances)
+const giteaPageSize = 50
+
+// GiteaUser - Gitea/Forgejo API user (or organization)
+type GiteaUser struct {
+	ID       int    `json:"id"`
+	Login    string `json:"login"`
+	FullName string `json:"full_name"`
+}
+
+// GiteaRepository - Gitea/Forgejo API repository
+type GiteaRepository struct {
+	ID            int       `json:"id"`
+	Owner         GiteaUser `json:"owner"`
+	Name          string    `json:"name"`
+	FullName      string    `json:"full_name"`
+	Description   string    `json:"description"`
+	Private       bool      `json:"private"`
+	DefaultBranch string    `json:"default_branch"`
+	// Set when repository owner is an organization
+	Org bool `json:"-"`
+}
+
+// GiteaActivity - Gitea/Forgejo API activity feed entry
+// Content depends on the operation: "index|title" for issues and pull requests, "index|body" for comments, JSON for pushes
+type GiteaActivity struct {
+	ID        int              `json:"id"`
+	OpType    string           `json:"op_type"`
+	ActUser   *GiteaUser       `json:"act_user"`
+	RepoID    int              `json:"repo_id"`
+	Repo      *GiteaRepository `json:"repo"`
+	CommentID int              `json:"comment_id"`
+	Comment   *Comment         `json:"comment"`
+	RefName   string           `json:"ref_name"`
+	IsPrivate bool             `json:"is_private"`
+	Content   string           `json:"content"`
+	Created   time.Time        `json:"created"`
+}
+
+// GiteaPushCommit - commit in Gitea/Forgejo push activity content
+type GiteaPushCommit struct {
+	Sha1        string `json:"Sha1"`
+	Message     string `json:"Message"`
+	AuthorEmail string `json:"AuthorEmail"`
+	AuthorName  string `json:"AuthorName"`
+}
+
+// GiteaPushCommits - Gitea/Forgejo push activity content
+type GiteaPushCommits struct {
+	Commits    []GiteaPushCommit `json:"Commits"`
+	CompareURL string            `json:"CompareURL"`
+	Len        int               `json:"Len"`
+}
+
+// GiteaDetails - objects activity refers to, requested separately
+type GiteaDetails struct {
+	Issue       *Issue
+	PullRequest *PullRequest
+}
+
+// GiteaEventType returns GHA event type and payload action for Gitea/Forgejo activity, empty type means activity is not supported
+// Detail is what the activity refers to: "issue", "pull" or ""
+func GiteaEventType(act *GiteaActivity) (evType, action, detail string) {
+	switch act.OpType {
+	case "create_issue":
+		return "IssuesEvent", "opened", "issue"
+	case "close_issue":
+		return "IssuesEvent", "closed", "issue"
+	case "reopen_issue":
+		return "IssuesEvent", "reopened", "issue"
+	case "create_pull_request":
+		return "PullRequestEvent", "opened", "pull"
+	case "close_pull_request":
+		return "PullRequestEvent", "closed", "pull"
+	case "reopen_pull_request":
+		return "PullRequestEvent", "reopened", "pull"
+	case "merge_pull_request", "auto_merge_pull_request":
+		return "PullRequestEvent", "closed", "pull"
+	case "comment_issue", "comment_pull":
+		if act.Comment == nil {
+			return "", "", ""
+		}
+		return "IssueCommentEvent", "created", "issue"
+	case "commit_repo":
+		return "PushEvent", "", ""
+	case "push_tag":
+		return "CreateEvent", "", ""
+	case "delete_tag", "delete_branch":
+		return "DeleteEvent", "", ""
+	case "star_repo":
+		return "WatchEvent", "started", ""
+	}
+	return "", "", ""
+}
+
+// giteaMerged - is activity a pull request merge
+func giteaMerged(act *GiteaActivity) bool {
+	return act.OpType == "merge_pull_request" || act.OpType == "auto_merge_pull_request"
+}
+
+// GiteaIndex returns issue or pull request number activity refers to, 0 if there is none
+func GiteaIndex(act *GiteaActivity) int {
+	index, err := strconv.Atoi(strings.SplitN(act.Content, "|", 2)[0])
+	if err != nil {
+		return 0
+	}
+	return index
+}
+
+// giteaRef - short branch or tag name, older instances save it without "refs/heads/" or "refs/tags/" prefix
+func giteaRef(ref string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(ref, "refs/heads/"), "refs/tags/")
+}
+
+// giteaLabels - Gitea/Forgejo label colors can have "#" prefix
+func giteaLabels(labels []Label) []Label {
+	for i := range labels {
+		labels[i].Color = strings.TrimPrefix(labels[i].Color, "#")
+	}
+	return labels
+}
+
+// GiteaActivityToGHA maps Gitea/Forgejo activity onto GHA event, returns nil for not supported activities or missing details
+// Gitea/Forgejo IDs are used as they are, so such projects must use their own database (IDs would collide with GitHub IDs)
+func GiteaActivityToGHA(act *GiteaActivity, details *GiteaDetails) (*Event, error) {
+	evType, action, detail := GiteaEventType(act)
+	if evType == "" || act.Repo == nil || act.ActUser == nil {
+		return nil, nil
+	}
+	if (detail == "issue" && details.Issue == nil) || (detail == "pull" && details.PullRequest == nil) {
+		return nil, nil
+	}
+	repo := act.Repo
+	e := &Event{
+		ID:        strconv.Itoa(act.ID),
+		Type:      evType,
+		Public:    !act.IsPrivate,
+		CreatedAt: act.Created.UTC(),
+		Actor:     Actor{ID: act.ActUser.ID, Login: act.ActUser.Login, Name: act.ActUser.FullName},
+		Repo:      Repo{ID: repo.ID, Name: repo.FullName},
+		Origin:    OriginGitea,
+	}
+	if repo.Org {
+		e.Org = &Org{ID: repo.Owner.ID, Login: repo.Owner.Login}
+	}
+	pl := &e.Payload
+	if action != "" {
+		pl.Action = &action
+	}
+	switch evType {
+	case "PushEvent":
+		var push GiteaPushCommits
+		if act.Content != "" {
+			err := json.Unmarshal([]byte(act.Content), &push)
+			if err != nil {
+				return nil, fmt.Errorf("%s: activity %d: cannot parse pushed commits: %v", repo.FullName, act.ID, err)
+			}
+		}
+		ref := "refs/heads/" + giteaRef(act.RefName)
+		size := push.Len
+		if size < len(push.Commits) {
+			size = len(push.Commits)
+		}
+		pl.Ref = &ref
+		pl.Size = &size
+		// Gitea/Forgejo list pushed commits starting from the newest one, GHA from the oldest one
+		commits := []Commit{}
+		for i := len(push.Commits) - 1; i >= 0; i-- {
+			c := push.Commits[i]
+			commits = append(commits, Commit{SHA: c.Sha1, Author: Author{Name: c.AuthorName, Email: c.AuthorEmail}, Message: c.Message, Distinct: true})
+		}
+		if len(push.Commits) > 0 {
+			head := push.Commits[0].Sha1
+			pl.Head = &head
+		}
+		pl.Commits = &commits
+	case "CreateEvent", "DeleteEvent":
+		ref, refType := giteaRef(act.RefName), "branch"
+		if strings.HasPrefix(act.RefName, "refs/tags/") || act.OpType == "push_tag" || act.OpType == "delete_tag" {
+			refType = "tag"
+		}
+		pl.Ref = &ref
+		pl.RefType = &refType
+		if evType == "CreateEvent" {
+			masterBranch := repo.DefaultBranch
+			description := repo.Description
+			pl.MasterBranch = &masterBranch
+			pl.Description = &description
+		}
+	case "IssuesEvent":
+		pl.Issue = details.Issue
+		eventState(action, pl.Issue, nil)
+	case "PullRequestEvent":
+		pl.Number = &details.PullRequest.Number
+		pl.PullRequest = details.PullRequest
+		if !giteaMerged(act) {
+			eventState(action, nil, pl.PullRequest)
+		}
+	case "IssueCommentEvent":
+		pl.Issue = details.Issue
+		pl.Comment = act.Comment
+	}
+	return e, nil
+}
+
+// GiteaClient - minimal Gitea/Forgejo REST API (v1) client
+type GiteaClient struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewGiteaClient creates client using ctx.GiteaURL (instance base URL) and ctx.GiteaToken
+// ctx.GiteaToken can be a token or a file name to read token from, empty means public access
+func NewGiteaClient(ctx *Ctx) *GiteaClient {
+	return &GiteaClient{
+		url:    strings.TrimSuffix(ctx.GiteaURL, "/") + "/api/v1",
+		token:  readToken(ctx.GiteaToken),
+		client: &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// get - requests a single object or page
+func (c *GiteaClient) get(path string, params url.Values, out interface{}) error {
+	target := c.url + path
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+	headers := make(map[string]string)
+	if c.token != "" {
+		headers["Authorization"] = "token " + c.token
+	}
+	_, data, err := RESTGet(c.client, target, headers)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// getAll - requests all pages, `page` is called with every page's JSON array and returns its length
+// Gitea/Forgejo have no next page header in all versions, so the last page is the one that is not full
+func (c *GiteaClient) getAll(path string, params url.Values, page func(data json.RawMessage) (int, error)) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("limit", strconv.Itoa(giteaPageSize))
+	for p := 1; ; p++ {
+		params.Set("page", strconv.Itoa(p))
+		var data json.RawMessage
+		err := c.get(path, params, &data)
+		if err != nil {
+			return err
+		}
+		n, err := page(data)
+		if err != nil {
+			return err
+		}
+		if n < giteaPageSize {
+			return nil
+		}
+	}
+}
+
+// repositories - requests all pages of repositories
+func (c *GiteaClient) repositories(path string) ([]GiteaRepository, error) {
+	repos := []GiteaRepository{}
+	err := c.getAll(
+		path,
+		nil,
+		func(data json.RawMessage) (int, error) {
+			var page []GiteaRepository
+			err := json.Unmarshal(data, &page)
+			repos = append(repos, page...)
+			return len(page), err
+		},
+	)
+	return repos, err
+}
+
+// OwnerRepos returns all repositories of an organization or a user
+func (c *GiteaClient) OwnerRepos(owner string) ([]GiteaRepository, error) {
+	repos, err := c.repositories("/orgs/" + url.PathEscape(owner) + "/repos")
+	if err == nil {
+		for i := range repos {
+			repos[i].Org = true
+		}
+		return repos, nil
+	}
+	if err != ErrNotFound {
+		return nil, err
+	}
+	return c.repositories("/users/" + url.PathEscape(owner) + "/repos")
+}
+
+// Repo returns repository with a given full name
+func (c *GiteaClient) Repo(fullName string) (*GiteaRepository, error) {
+	var repo GiteaRepository
+	err := c.get("/repos/"+fullName, nil, &repo)
+	if err != nil {
+		return nil, err
+	}
+	var org GiteaUser
+	err = c.get("/orgs/"+url.PathEscape(repo.Owner.Login), nil, &org)
+	if err != nil && err != ErrNotFound {
+		return nil, err
+	}
+	repo.Org = err == nil
+	return &repo, nil
+}
+
+// Repos returns repositories matching orgs/repos filter
+// Orgs are Gitea/Forgejo organizations (or users) or full repository names, filter has the same meaning as in RepoHit
+func (c *GiteaClient) Repos(exact bool, forg, frepo map[string]struct{}) ([]GiteaRepository, error) {
+	if len(forg) == 0 {
+		return nil, fmt.Errorf("Gitea source needs Gitea organizations or repositories given as orgs")
+	}
+	seen := make(map[int]struct{})
+	repos := []GiteaRepository{}
+	add := func(repo GiteaRepository) {
+		if _, ok := seen[repo.ID]; ok {
+			return
+		}
+		if !RepoHit(exact, repo.FullName, forg, frepo) {
+			return
+		}
+		seen[repo.ID] = struct{}{}
+		repos = append(repos, repo)
+	}
+	for _, name := range StringsSetKeys(forg) {
+		if strings.Contains(name, "/") {
+			repo, err := c.Repo(name)
+			if err != nil {
+				return nil, err
+			}
+			add(*repo)
+			continue
+		}
+		owned, err := c.OwnerRepos(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range owned {
+			add(repo)
+		}
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].FullName < repos[j].FullName })
+	return repos, nil
+}
+
+// RepoActivities returns repository activities created at a given day (UTC)
+// Feed's date is a day in instance's time zone, so adjacent days are read too
+// Activities are saved for every user watching the repository, such duplicates are removed (the lowest ID is kept)
+func (c *GiteaClient) RepoActivities(repo *GiteaRepository, day time.Time) ([]GiteaActivity, error) {
+	day = DayStart(day)
+	unique := make(map[string]GiteaActivity)
+	for d := -1; d <= 1; d++ {
+		err := c.getAll(
+			"/repos/"+repo.FullName+"/activities/feeds",
+			url.Values{"date": {ToYMDDate(day.AddDate(0, 0, d))}},
+			func(data json.RawMessage) (int, error) {
+				var page []GiteaActivity
+				err := json.Unmarshal(data, &page)
+				for _, act := range page {
+					if !DayStart(act.Created.UTC()).Equal(day) {
+						continue
+					}
+					act.Repo = repo
+					key := fmt.Sprintf("%s:%d:%d:%s:%s:%d", act.OpType, act.RepoID, act.CommentID, act.RefName, act.Content, act.Created.Unix())
+					if act.ActUser != nil {
+						key += ":" + act.ActUser.Login
+					}
+					if prev, ok := unique[key]; !ok || act.ID < prev.ID {
+						unique[key] = act
+					}
+				}
+				return len(page), err
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	activities := []GiteaActivity{}
+	for _, act := range unique {
+		activities = append(activities, act)
+	}
+	sort.Slice(activities, func(i, j int) bool { return activities[i].ID < activities[j].ID })
+	return activities, nil
+}
+
+// Issue returns repository's issue (or pull request's issue) with a given number
+func (c *GiteaClient) Issue(repo string, index int) (*Issue, error) {
+	var issue Issue
+	err := c.get(fmt.Sprintf("/repos/%s/issues/%d", repo, index), nil, &issue)
+	if err != nil {
+		return nil, err
+	}
+	issue.Labels = giteaLabels(issue.Labels)
+	return &issue, nil
+}
+
+// PullRequest returns repository's pull request with a given number
+func (c *GiteaClient) PullRequest(repo string, index int) (*PullRequest, error) {
+	var pr PullRequest
+	err := c.get(fmt.Sprintf("/repos/%s/pulls/%d", repo, index), nil, &pr)
+	if err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// details - requests objects activity refers to, they are cached (many activities refer to the same issue)
+// Cached objects are copied, because payload states are changed to the ones from the activity time
+func (c *GiteaClient) details(act *GiteaActivity, detail string, cache map[string]interface{}) (*GiteaDetails, error) {
+	details := &GiteaDetails{}
+	index := GiteaIndex(act)
+	if detail == "" || index == 0 {
+		return details, nil
+	}
+	key := fmt.Sprintf("%s:%d:%d", detail, act.Repo.ID, index)
+	var err error
+	switch detail {
+	case "issue":
+		issue, ok := cache[key].(*Issue)
+		if !ok {
+			issue, err = c.Issue(act.Repo.FullName, index)
+			cache[key] = issue
+		}
+		if issue != nil {
+			copied := *issue
+			details.Issue = &copied
+		}
+	case "pull":
+		pr, ok := cache[key].(*PullRequest)
+		if !ok {
+			pr, err = c.PullRequest(act.Repo.FullName, index)
+			cache[key] = pr
+		}
+		if pr != nil {
+			copied := *pr
+			details.PullRequest = &copied
+		}
+	}
+	// Deleted issues and pull requests still have their activities, such activities are skipped
+	if err == ErrNotFound {
+		Printf("Gitea repository %s: %s %d not found, activity %d skipped\n", act.Repo.FullName, detail, index, act.ID)
+		return &GiteaDetails{}, nil
+	}
+	return details, err
+}
+
+// DayJSONs returns activities of given repositories created at a given day as GHA JSON lines, grouped by hour of day
+func (c *GiteaClient) DayJSONs(day time.Time, repos []GiteaRepository) (map[int][]byte, error) {
+	ghaEvents := []*Event{}
+	cache := make(map[string]interface{})
+	for i := range repos {
+		activities, err := c.RepoActivities(&repos[i], day)
+		if err != nil {
+			return nil, err
+		}
+		for j := range activities {
+			act := &activities[j]
+			_, _, detail := GiteaEventType(act)
+			details, err := c.details(act, detail, cache)
+			if err != nil {
+				return nil, err
+			}
+			e, err := GiteaActivityToGHA(act, details)
+			if err != nil {
+				return nil, err
+			}
+			if e != nil {
+				ghaEvents = append(ghaEvents, e)
+			}
+		}
+	}
+	return hourJSONs(ghaEvents)
+}
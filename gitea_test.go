@@ -0,0 +1,190 @@
+package devstats
+
+import (
+	"encoding/json"
+	"testing"
+
+	lib "devstats"
+)
+
+// giteaActivity - parses Gitea/Forgejo API activity JSON
+func giteaActivity(t *testing.T, data string) *lib.GiteaActivity {
+	var act lib.GiteaActivity
+	err := json.Unmarshal([]byte(data), &act)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &act
+}
+
+func TestGiteaEventType(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		activity string
+		evType   string
+		action   string
+		detail   string
+		index    int
+	}{
+		{
+			activity: `{"op_type": "create_issue", "content": "12|Crash on start"}`,
+			evType:   "IssuesEvent", action: "opened", detail: "issue", index: 12,
+		},
+		{
+			activity: `{"op_type": "reopen_issue", "content": "12|Crash on start"}`,
+			evType:   "IssuesEvent", action: "reopened", detail: "issue", index: 12,
+		},
+		{
+			activity: `{"op_type": "merge_pull_request", "content": "7|Add feature"}`,
+			evType:   "PullRequestEvent", action: "closed", detail: "pull", index: 7,
+		},
+		{
+			activity: `{"op_type": "comment_pull", "content": "7|LGTM", "comment": {"id": 900, "body": "LGTM"}}`,
+			evType:   "IssueCommentEvent", action: "created", detail: "issue", index: 7,
+		},
+		{
+			activity: `{"op_type": "comment_issue", "content": "12|Deleted"}`,
+			index:    12,
+		},
+		{
+			activity: `{"op_type": "commit_repo", "ref_name": "refs/heads/main", "content": "{}"}`,
+			evType:   "PushEvent",
+		},
+		{
+			activity: `{"op_type": "push_tag", "ref_name": "refs/tags/v1.0"}`,
+			evType:   "CreateEvent",
+		},
+		{
+			activity: `{"op_type": "delete_branch", "ref_name": "feature"}`,
+			evType:   "DeleteEvent",
+		},
+		{
+			activity: `{"op_type": "star_repo"}`,
+			evType:   "WatchEvent", action: "started",
+		},
+		{
+			activity: `{"op_type": "mirror_sync_push"}`,
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		act := giteaActivity(t, test.activity)
+		evType, action, detail := lib.GiteaEventType(act)
+		if evType != test.evType || action != test.action || detail != test.detail {
+			t.Errorf(
+				"test number %d, expected (%s, %s, %s), got (%s, %s, %s)",
+				index+1, test.evType, test.action, test.detail, evType, action, detail,
+			)
+		}
+		if got := lib.GiteaIndex(act); got != test.index {
+			t.Errorf("test number %d, expected index %d, got %d", index+1, test.index, got)
+		}
+	}
+}
+
+func TestGiteaActivityToGHA(t *testing.T) {
+	repo := &lib.GiteaRepository{
+		ID:            42,
+		Owner:         lib.GiteaUser{ID: 5, Login: "forgejo"},
+		Name:          "forgejo",
+		FullName:      "forgejo/forgejo",
+		DefaultBranch: "forgejo",
+		Org:           true,
+	}
+	var pr lib.PullRequest
+	err := json.Unmarshal(
+		[]byte(`{
+			"id": 3000, "number": 7, "title": "Add feature", "body": "Body", "state": "closed",
+			"user": {"id": 1, "login": "author"}, "merged": true, "merged_at": "2023-05-02T09:00:00+02:00",
+			"merged_by": {"id": 2, "login": "maintainer"}, "closed_at": "2023-05-02T09:00:00+02:00",
+			"created_at": "2023-05-01T10:00:00+02:00", "updated_at": "2023-05-02T09:00:00+02:00",
+			"base": {"label": "forgejo", "ref": "forgejo", "sha": "base", "repo_id": 42},
+			"head": {"label": "feature", "ref": "feature", "sha": "head", "repo": null}
+		}`),
+		&pr,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pull request opened: state from activity time, not the current one
+	act := giteaActivity(t, `{"id": 100, "op_type": "create_pull_request", "act_user": {"id": 1, "login": "author"}, "repo_id": 42,
+		"content": "7|Add feature", "created": "2023-05-01T10:00:00+02:00"}`)
+	copied := pr
+	ev, err := lib.GiteaActivityToGHA(act, &lib.GiteaDetails{PullRequest: &copied})
+	if err != nil || ev != nil {
+		t.Fatalf("expected no event for activity without repository, got %+v (error: %v)", ev, err)
+	}
+	act.Repo = repo
+	ev, err = lib.GiteaActivityToGHA(act, &lib.GiteaDetails{PullRequest: &copied})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev == nil || ev.ID != "100" || ev.Type != "PullRequestEvent" || ev.Repo.Name != "forgejo/forgejo" || ev.Org == nil || ev.Org.Login != "forgejo" {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	if ev.CreatedAt.Hour() != 8 || ev.CreatedAt.Location().String() != "UTC" {
+		t.Errorf("expected event time in UTC, got %v", ev.CreatedAt)
+	}
+	if p := ev.Payload.PullRequest; p.State != "open" || *p.Merged || p.MergedAt != nil || p.ClosedAt != nil || *ev.Payload.Number != 7 {
+		t.Errorf("unexpected opened pull request: %+v", p)
+	}
+
+	// Pull request merged keeps its current state
+	act = giteaActivity(t, `{"id": 101, "op_type": "merge_pull_request", "act_user": {"id": 2, "login": "maintainer"}, "repo_id": 42,
+		"content": "7|Add feature", "created": "2023-05-02T09:00:00+02:00"}`)
+	act.Repo = repo
+	copied = pr
+	ev, _ = lib.GiteaActivityToGHA(act, &lib.GiteaDetails{PullRequest: &copied})
+	if p := ev.Payload.PullRequest; *ev.Payload.Action != "closed" || !*p.Merged || p.MergedAt == nil || p.MergedBy.Login != "maintainer" {
+		t.Errorf("unexpected merged pull request: %+v", p)
+	}
+
+	// Missing details (deleted issue) skip the activity
+	act = giteaActivity(t, `{"id": 102, "op_type": "close_issue", "act_user": {"id": 1, "login": "author"}, "content": "5|Gone"}`)
+	act.Repo = repo
+	ev, _ = lib.GiteaActivityToGHA(act, &lib.GiteaDetails{})
+	if ev != nil {
+		t.Errorf("expected no event for missing issue, got %+v", ev)
+	}
+
+	// Push with commits (newest first in Gitea/Forgejo), event must be readable as GHA JSON
+	act = giteaActivity(t, `{"id": 103, "op_type": "commit_repo", "act_user": {"id": 1, "login": "author"}, "ref_name": "feature",
+		"created": "2023-05-01T12:00:00Z",
+		"content": "{\"Commits\":[{\"Sha1\":\"bbb\",\"Message\":\"Fix\",\"AuthorEmail\":\"a@b.c\",\"AuthorName\":\"Author\"},{\"Sha1\":\"aaa\",\"Message\":\"Add\",\"AuthorEmail\":\"a@b.c\",\"AuthorName\":\"Author\"}],\"Len\":2}"}`)
+	act.Repo = repo
+	ev, err = lib.GiteaActivityToGHA(act, &lib.GiteaDetails{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gha lib.Event
+	err = json.Unmarshal(data, &gha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gha.Type != "PushEvent" || *gha.Payload.Ref != "refs/heads/feature" || *gha.Payload.Head != "bbb" || *gha.Payload.Size != 2 {
+		t.Errorf("unexpected push event: %+v", gha)
+	}
+	if commits := *gha.Payload.Commits; len(commits) != 2 || commits[0].SHA != "aaa" || commits[1].Author.Email != "a@b.c" {
+		t.Errorf("unexpected commits: %+v", commits)
+	}
+
+	// Invalid push content is an error
+	act.Content = "{"
+	_, err = lib.GiteaActivityToGHA(act, &lib.GiteaDetails{})
+	if err == nil {
+		t.Errorf("expected error for invalid push content")
+	}
+
+	// Tag pushed with full ref name
+	act = giteaActivity(t, `{"id": 104, "op_type": "push_tag", "act_user": {"id": 1, "login": "author"}, "ref_name": "refs/tags/v1.0"}`)
+	act.Repo = repo
+	ev, _ = lib.GiteaActivityToGHA(act, &lib.GiteaDetails{})
+	if *ev.Payload.Ref != "v1.0" || *ev.Payload.RefType != "tag" || *ev.Payload.MasterBranch != "forgejo" {
+		t.Errorf("unexpected create event payload: %+v", ev.Payload)
+	}
+}
@@ -0,0 +1,35 @@
+package devstats
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+)
+
+// GHClient - get GitHub API client (using ctx.GitHubOAuth)
+// ctx.GitHubOAuth can be a token or a file name to read token from, "-" means public access
+func GHClient(ctx *Ctx) (ghCtx context.Context, client *github.Client) {
+	// Get GitHub OAuth from env or from file
+	oAuth := ctx.GitHubOAuth
+	if strings.Contains(ctx.GitHubOAuth, "/") {
+		bytes, err := ioutil.ReadFile(ctx.GitHubOAuth)
+		FatalOnError(err)
+		oAuth = strings.TrimSpace(string(bytes))
+	}
+
+	// GitHub authentication or use public access
+	ghCtx = context.Background()
+	if oAuth == "-" {
+		client = github.NewClient(nil)
+	} else {
+		ts := oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: oAuth},
+		)
+		tc := oauth2.NewClient(ghCtx, ts)
+		client = github.NewClient(tc)
+	}
+	return
+}
@@ -0,0 +1,693 @@
+package devstats
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitLabUser - GitLab API user
+type GitLabUser struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+// GitLabNamespace - GitLab API project namespace (group or user)
+type GitLabNamespace struct {
+	ID       int    `json:"id"`
+	FullPath string `json:"full_path"`
+	Kind     string `json:"kind"`
+}
+
+// GitLabProject - GitLab API project
+type GitLabProject struct {
+	ID                int             `json:"id"`
+	Name              string          `json:"name"`
+	PathWithNamespace string          `json:"path_with_namespace"`
+	Namespace         GitLabNamespace `json:"namespace"`
+	Description       *string         `json:"description"`
+	DefaultBranch     string          `json:"default_branch"`
+	Visibility        string          `json:"visibility"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
+
+// GitLabLabel - GitLab API label (issues and merge requests are requested with label details)
+type GitLabLabel struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// GitLabMilestone - GitLab API milestone
+type GitLabMilestone struct {
+	ID          int        `json:"id"`
+	IID         int        `json:"iid"`
+	Title       string     `json:"title"`
+	Description *string    `json:"description"`
+	State       string     `json:"state"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DueDate     *string    `json:"due_date"`
+	ClosedAt    *time.Time `json:"closed_at"`
+}
+
+// GitLabIssue - GitLab API issue
+type GitLabIssue struct {
+	ID               int              `json:"id"`
+	IID              int              `json:"iid"`
+	Title            string           `json:"title"`
+	Description      *string          `json:"description"`
+	State            string           `json:"state"`
+	CreatedAt        time.Time        `json:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+	ClosedAt         *time.Time       `json:"closed_at"`
+	Labels           []GitLabLabel    `json:"labels"`
+	Milestone        *GitLabMilestone `json:"milestone"`
+	Author           GitLabUser       `json:"author"`
+	Assignee         *GitLabUser      `json:"assignee"`
+	Assignees        []GitLabUser     `json:"assignees"`
+	UserNotesCount   int              `json:"user_notes_count"`
+	DiscussionLocked *bool            `json:"discussion_locked"`
+}
+
+// GitLabDiffRefs - GitLab API merge request base and head commits
+type GitLabDiffRefs struct {
+	BaseSHA string `json:"base_sha"`
+	HeadSHA string `json:"head_sha"`
+}
+
+// GitLabMergeRequest - GitLab API merge request, it has all issue fields and more
+type GitLabMergeRequest struct {
+	GitLabIssue
+	MergedAt       *time.Time      `json:"merged_at"`
+	MergedBy       *GitLabUser     `json:"merged_by"`
+	MergeCommitSHA *string         `json:"merge_commit_sha"`
+	SHA            string          `json:"sha"`
+	SourceBranch   string          `json:"source_branch"`
+	TargetBranch   string          `json:"target_branch"`
+	Reviewers      []GitLabUser    `json:"reviewers"`
+	DiffRefs       *GitLabDiffRefs `json:"diff_refs"`
+}
+
+// GitLabNotePosition - GitLab API diff note position
+type GitLabNotePosition struct {
+	HeadSHA string  `json:"head_sha"`
+	NewPath *string `json:"new_path"`
+	NewLine *int    `json:"new_line"`
+}
+
+// GitLabNote - GitLab API note (comment)
+type GitLabNote struct {
+	ID           int                 `json:"id"`
+	Type         *string             `json:"type"`
+	Body         string              `json:"body"`
+	Author       GitLabUser          `json:"author"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+	System       bool                `json:"system"`
+	NoteableType string              `json:"noteable_type"`
+	NoteableIID  *int                `json:"noteable_iid"`
+	Position     *GitLabNotePosition `json:"position"`
+}
+
+// GitLabPushData - GitLab API push event details
+type GitLabPushData struct {
+	CommitCount int     `json:"commit_count"`
+	Action      string  `json:"action"`
+	RefType     string  `json:"ref_type"`
+	CommitFrom  *string `json:"commit_from"`
+	CommitTo    *string `json:"commit_to"`
+	Ref         string  `json:"ref"`
+}
+
+// GitLabCommit - GitLab API commit
+type GitLabCommit struct {
+	ID          string `json:"id"`
+	Message     string `json:"message"`
+	AuthorName  string `json:"author_name"`
+	AuthorEmail string `json:"author_email"`
+}
+
+// GitLabEvent - GitLab API project event
+type GitLabEvent struct {
+	ID         int             `json:"id"`
+	ProjectID  int             `json:"project_id"`
+	ActionName string          `json:"action_name"`
+	TargetIID  *int            `json:"target_iid"`
+	TargetType *string         `json:"target_type"`
+	Author     GitLabUser      `json:"author"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Note       *GitLabNote     `json:"note"`
+	PushData   *GitLabPushData `json:"push_data"`
+}
+
+// GitLabDetails - objects GitLab event refers to, they are not included in events API and must be requested separately
+type GitLabDetails struct {
+	Issue        *GitLabIssue
+	MergeRequest *GitLabMergeRequest
+	Commits      []GitLabCommit
+}
+
+// GitLabEventType returns GHA event type and payload action for GitLab event, empty type means event is not supported
+// Detail is what the event refers to: "issue", "merge_request", "commits" or ""
+func GitLabEventType(ev *GitLabEvent) (evType, action, detail string) {
+	if ev.PushData != nil {
+		switch {
+		case ev.PushData.Action == "removed":
+			return "DeleteEvent", "", ""
+		case ev.PushData.Action == "created" && ev.PushData.RefType == "tag":
+			return "CreateEvent", "", ""
+		case ev.PushData.RefType == "branch" && ev.PushData.CommitTo != nil:
+			return "PushEvent", "", "commits"
+		}
+		return "", "", ""
+	}
+	if ev.TargetType == nil {
+		return "", "", ""
+	}
+	switch *ev.TargetType {
+	case "Issue":
+		switch ev.ActionName {
+		case "opened", "closed", "reopened":
+			return "IssuesEvent", ev.ActionName, "issue"
+		}
+	case "MergeRequest":
+		switch ev.ActionName {
+		case "opened", "closed", "reopened":
+			return "PullRequestEvent", ev.ActionName, "merge_request"
+		case "accepted", "merged":
+			return "PullRequestEvent", "closed", "merge_request"
+		}
+	case "Note", "DiffNote", "DiscussionNote":
+		if ev.Note == nil || ev.Note.System || ev.Note.NoteableIID == nil {
+			return "", "", ""
+		}
+		switch ev.Note.NoteableType {
+		case "Issue":
+			return "IssueCommentEvent", "created", "issue"
+		case "MergeRequest":
+			if *ev.TargetType == "DiffNote" {
+				return "PullRequestReviewCommentEvent", "created", "merge_request"
+			}
+			return "IssueCommentEvent", "created", "merge_request"
+		}
+	}
+	return "", "", ""
+}
+
+// actor - GitLab user as GHA actor
+func (u *GitLabUser) actor() Actor {
+	return Actor{ID: u.ID, Login: u.Username, Name: u.Name}
+}
+
+// gitLabActorOrNil - GitLab user as GHA actor, nil when there is no user
+func gitLabActorOrNil(u *GitLabUser) *Actor {
+	if u == nil {
+		return nil
+	}
+	actor := u.actor()
+	return &actor
+}
+
+// gitLabActors - GitLab users as GHA actors
+func gitLabActors(users []GitLabUser) []Actor {
+	actors := []Actor{}
+	for i := range users {
+		actors = append(actors, users[i].actor())
+	}
+	return actors
+}
+
+// gitLabState - GitLab issue/merge request state as GHA state
+func gitLabState(state string) string {
+	if state == "opened" || state == "locked" {
+		return "open"
+	}
+	return "closed"
+}
+
+// gitLabLabels - GitLab labels as GHA labels, GitLab colors have "#" prefix
+func gitLabLabels(labels []GitLabLabel) []Label {
+	ghaLabels := []Label{}
+	for _, label := range labels {
+		id := label.ID
+		ghaLabels = append(ghaLabels, Label{ID: &id, Name: label.Name, Color: strings.TrimPrefix(label.Color, "#")})
+	}
+	return ghaLabels
+}
+
+// gitLabMilestone - GitLab milestone as GHA milestone, only date of due date is known
+func gitLabMilestone(m *GitLabMilestone) *Milestone {
+	if m == nil {
+		return nil
+	}
+	milestone := &Milestone{
+		ID:          m.ID,
+		Name:        m.Title,
+		Number:      m.IID,
+		Title:       m.Title,
+		Description: m.Description,
+		State:       m.State,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+		ClosedAt:    m.ClosedAt,
+	}
+	if milestone.State == "active" {
+		milestone.State = "open"
+	}
+	if m.DueDate != nil {
+		if due, err := time.Parse("2006-01-02", *m.DueDate); err == nil {
+			milestone.DueOn = &due
+		}
+	}
+	return milestone
+}
+
+// GitLabIssueToGHA maps GitLab issue onto GHA issue
+// GitLab issue and merge request numbers (iids) are separate sequences, so they can be the same in one project
+func GitLabIssueToGHA(i *GitLabIssue) *Issue {
+	issue := &Issue{
+		ID:        i.ID,
+		Number:    i.IID,
+		Comments:  i.UserNotesCount,
+		Title:     i.Title,
+		State:     gitLabState(i.State),
+		Body:      i.Description,
+		User:      i.Author.actor(),
+		Assignee:  gitLabActorOrNil(i.Assignee),
+		Labels:    gitLabLabels(i.Labels),
+		Assignees: gitLabActors(i.Assignees),
+		Milestone: gitLabMilestone(i.Milestone),
+		CreatedAt: i.CreatedAt,
+		UpdatedAt: i.UpdatedAt,
+		ClosedAt:  i.ClosedAt,
+	}
+	if i.DiscussionLocked != nil {
+		issue.Locked = *i.DiscussionLocked
+	}
+	return issue
+}
+
+// GitLabMergeRequestIssue returns artificial GHA issue of a merge request (GitHub has issue for every pull request)
+// It uses negative merge request ID, like artificial issues of pre 2015 pull requests
+func GitLabMergeRequestIssue(mr *GitLabMergeRequest) *Issue {
+	issue := GitLabIssueToGHA(&mr.GitLabIssue)
+	issue.ID = -mr.ID
+	issue.PullRequest = &Dummy{}
+	return issue
+}
+
+// GitLabMergeRequestToGHA maps GitLab merge request onto GHA pull request
+func GitLabMergeRequestToGHA(mr *GitLabMergeRequest) *PullRequest {
+	assignees := gitLabActors(mr.Assignees)
+	reviewers := gitLabActors(mr.Reviewers)
+	comments := mr.UserNotesCount
+	merged := mr.State == "merged"
+	locked := mr.State == "locked"
+	if mr.DiscussionLocked != nil && *mr.DiscussionLocked {
+		locked = true
+	}
+	pr := &PullRequest{
+		ID:                 mr.ID,
+		Base:               Branch{Label: mr.TargetBranch, Ref: mr.TargetBranch},
+		Head:               Branch{SHA: mr.SHA, Label: mr.SourceBranch, Ref: mr.SourceBranch},
+		User:               mr.Author.actor(),
+		Number:             mr.IID,
+		State:              gitLabState(mr.State),
+		Locked:             &locked,
+		Title:              mr.Title,
+		Body:               mr.Description,
+		CreatedAt:          mr.CreatedAt,
+		UpdatedAt:          mr.UpdatedAt,
+		ClosedAt:           mr.ClosedAt,
+		MergedAt:           mr.MergedAt,
+		MergeCommitSHA:     mr.MergeCommitSHA,
+		Assignee:           gitLabActorOrNil(mr.Assignee),
+		Assignees:          &assignees,
+		RequestedReviewers: &reviewers,
+		Milestone:          gitLabMilestone(mr.Milestone),
+		Merged:             &merged,
+		MergedBy:           gitLabActorOrNil(mr.MergedBy),
+		Comments:           &comments,
+	}
+	if mr.DiffRefs != nil {
+		pr.Base.SHA = mr.DiffRefs.BaseSHA
+		pr.Head.SHA = mr.DiffRefs.HeadSHA
+	}
+	// Merged merge requests are closed in GitHub terms
+	if merged && pr.ClosedAt == nil {
+		pr.ClosedAt = mr.MergedAt
+	}
+	return pr
+}
+
+// GitLabNoteToGHA maps GitLab note onto GHA comment, diff notes have file path, line and commit
+func GitLabNoteToGHA(n *GitLabNote) *Comment {
+	comment := &Comment{
+		ID:        n.ID,
+		Body:      n.Body,
+		CreatedAt: n.CreatedAt,
+		UpdatedAt: n.UpdatedAt,
+		User:      n.Author.actor(),
+	}
+	if n.Position != nil {
+		sha := n.Position.HeadSHA
+		comment.CommitID = &sha
+		comment.Path = n.Position.NewPath
+		comment.Line = n.Position.NewLine
+	}
+	return comment
+}
+
+// GitLabEventToGHA maps GitLab event onto GHA event, returns nil for not supported events or missing details
+// GitLab IDs are used as they are, so GitLab projects must use their own database (IDs would collide with GitHub IDs)
+func GitLabEventToGHA(ev *GitLabEvent, project *GitLabProject, details *GitLabDetails) *Event {
+	evType, action, detail := GitLabEventType(ev)
+	if evType == "" {
+		return nil
+	}
+	if (detail == "issue" && details.Issue == nil) || (detail == "merge_request" && details.MergeRequest == nil) {
+		return nil
+	}
+	e := &Event{
+		ID:        strconv.Itoa(ev.ID),
+		Type:      evType,
+		Public:    project.Visibility == "" || project.Visibility == "public",
+		CreatedAt: ev.CreatedAt,
+		Actor:     ev.Author.actor(),
+		Repo:      Repo{ID: project.ID, Name: project.PathWithNamespace},
+		Origin:    OriginGitLab,
+	}
+	if project.Namespace.Kind == "group" {
+		e.Org = &Org{ID: project.Namespace.ID, Login: project.Namespace.FullPath}
+	}
+	pl := &e.Payload
+	if action != "" {
+		pl.Action = &action
+	}
+	switch evType {
+	case "PushEvent":
+		push := ev.PushData
+		ref := "refs/heads/" + push.Ref
+		size := push.CommitCount
+		pl.Ref = &ref
+		pl.Head = push.CommitTo
+		pl.Before = push.CommitFrom
+		pl.Size = &size
+		commits := []Commit{}
+		for _, c := range details.Commits {
+			commits = append(commits, Commit{SHA: c.ID, Author: Author{Name: c.AuthorName, Email: c.AuthorEmail}, Message: c.Message, Distinct: true})
+		}
+		pl.Commits = &commits
+	case "CreateEvent", "DeleteEvent":
+		ref, refType := ev.PushData.Ref, ev.PushData.RefType
+		pl.Ref = &ref
+		pl.RefType = &refType
+		if evType == "CreateEvent" {
+			masterBranch := project.DefaultBranch
+			pl.MasterBranch = &masterBranch
+			pl.Description = project.Description
+		}
+	case "IssuesEvent":
+		pl.Issue = GitLabIssueToGHA(details.Issue)
+		eventState(action, pl.Issue, nil)
+	case "PullRequestEvent":
+		pl.Number = &details.MergeRequest.IID
+		pl.PullRequest = GitLabMergeRequestToGHA(details.MergeRequest)
+		eventState(ev.ActionName, nil, pl.PullRequest)
+	case "IssueCommentEvent":
+		if details.MergeRequest != nil {
+			pl.Issue = GitLabMergeRequestIssue(details.MergeRequest)
+		} else {
+			pl.Issue = GitLabIssueToGHA(details.Issue)
+		}
+		pl.Comment = GitLabNoteToGHA(ev.Note)
+	case "PullRequestReviewCommentEvent":
+		pl.PullRequest = GitLabMergeRequestToGHA(details.MergeRequest)
+		pl.Comment = GitLabNoteToGHA(ev.Note)
+	}
+	return e
+}
+
+// GitLabClient - minimal GitLab REST API (v4) client
+type GitLabClient struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewGitLabClient creates client using ctx.GitLabURL and ctx.GitLabToken
+// ctx.GitLabToken can be a token or a file name to read token from, empty means public access
+func NewGitLabClient(ctx *Ctx) *GitLabClient {
+	return &GitLabClient{
+		url:    strings.TrimSuffix(ctx.GitLabURL, "/"),
+		token:  readToken(ctx.GitLabToken),
+		client: &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// get - requests single page, returns next page number ("" on the last page)
+func (c *GitLabClient) get(path string, params url.Values, out interface{}) (string, error) {
+	target := c.url + path
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+	headers := make(map[string]string)
+	if c.token != "" {
+		headers["PRIVATE-TOKEN"] = c.token
+	}
+	header, data, err := RESTGet(c.client, target, headers)
+	if err != nil {
+		return "", err
+	}
+	return header.Get("X-Next-Page"), json.Unmarshal(data, out)
+}
+
+// getAll - requests all pages, `page` is called with every page's JSON array
+func (c *GitLabClient) getAll(path string, params url.Values, page func(data json.RawMessage) error) error {
+	params.Set("per_page", "100")
+	params.Set("page", "1")
+	for {
+		var data json.RawMessage
+		next, err := c.get(path, params, &data)
+		if err != nil {
+			return err
+		}
+		err = page(data)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		params.Set("page", next)
+	}
+}
+
+// gitLabID - project or group ID for API paths, it can also be its URL encoded full path
+func gitLabID(path string) string {
+	return url.PathEscape(path)
+}
+
+// Project returns project with a given full path
+func (c *GitLabClient) Project(path string) (*GitLabProject, error) {
+	var project GitLabProject
+	_, err := c.get("/projects/"+gitLabID(path), nil, &project)
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// GroupProjects returns all projects of a group, including subgroups
+func (c *GitLabClient) GroupProjects(group string) ([]GitLabProject, error) {
+	projects := []GitLabProject{}
+	err := c.getAll(
+		"/groups/"+gitLabID(group)+"/projects",
+		url.Values{"include_subgroups": {"true"}},
+		func(data json.RawMessage) error {
+			var page []GitLabProject
+			err := json.Unmarshal(data, &page)
+			projects = append(projects, page...)
+			return err
+		},
+	)
+	return projects, err
+}
+
+// Projects returns projects matching orgs/repos filter
+// Orgs are GitLab groups or full project paths, filter has the same meaning as in RepoHit
+func (c *GitLabClient) Projects(exact bool, forg, frepo map[string]struct{}) ([]GitLabProject, error) {
+	if len(forg) == 0 {
+		return nil, fmt.Errorf("GitLab source needs GitLab groups or projects given as orgs")
+	}
+	seen := make(map[int]struct{})
+	projects := []GitLabProject{}
+	add := func(project GitLabProject) {
+		if _, ok := seen[project.ID]; ok {
+			return
+		}
+		if !RepoHit(exact, project.PathWithNamespace, forg, frepo) {
+			return
+		}
+		seen[project.ID] = struct{}{}
+		projects = append(projects, project)
+	}
+	for _, name := range StringsSetKeys(forg) {
+		if strings.Contains(name, "/") {
+			project, err := c.Project(name)
+			if err != nil {
+				return nil, err
+			}
+			add(*project)
+			continue
+		}
+		group, err := c.GroupProjects(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range group {
+			add(project)
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].PathWithNamespace < projects[j].PathWithNamespace })
+	return projects, nil
+}
+
+// ProjectEvents returns all project events created at a given day (UTC)
+func (c *GitLabClient) ProjectEvents(projectID int, day time.Time) ([]GitLabEvent, error) {
+	// Both dates are exclusive
+	day = DayStart(day)
+	events := []GitLabEvent{}
+	err := c.getAll(
+		fmt.Sprintf("/projects/%d/events", projectID),
+		url.Values{
+			"after":  {ToYMDDate(day.AddDate(0, 0, -1))},
+			"before": {ToYMDDate(day.AddDate(0, 0, 1))},
+			"sort":   {"asc"},
+		},
+		func(data json.RawMessage) error {
+			var page []GitLabEvent
+			err := json.Unmarshal(data, &page)
+			events = append(events, page...)
+			return err
+		},
+	)
+	return events, err
+}
+
+// Issue returns project's issue with a given iid
+func (c *GitLabClient) Issue(projectID, iid int) (*GitLabIssue, error) {
+	var issue GitLabIssue
+	_, err := c.get(fmt.Sprintf("/projects/%d/issues/%d", projectID, iid), url.Values{"with_labels_details": {"true"}}, &issue)
+	if err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// MergeRequest returns project's merge request with a given iid
+func (c *GitLabClient) MergeRequest(projectID, iid int) (*GitLabMergeRequest, error) {
+	var mr GitLabMergeRequest
+	_, err := c.get(fmt.Sprintf("/projects/%d/merge_requests/%d", projectID, iid), url.Values{"with_labels_details": {"true"}}, &mr)
+	if err != nil {
+		return nil, err
+	}
+	return &mr, nil
+}
+
+// PushCommits returns commits pushed, new branch push (no previous commit) only returns its head commit
+func (c *GitLabClient) PushCommits(projectID int, push *GitLabPushData) ([]GitLabCommit, error) {
+	if push.CommitTo == nil {
+		return nil, nil
+	}
+	if push.CommitFrom == nil || strings.Trim(*push.CommitFrom, "0") == "" {
+		var commit GitLabCommit
+		_, err := c.get(fmt.Sprintf("/projects/%d/repository/commits/%s", projectID, *push.CommitTo), nil, &commit)
+		if err != nil {
+			return nil, err
+		}
+		return []GitLabCommit{commit}, nil
+	}
+	var compare struct {
+		Commits []GitLabCommit `json:"commits"`
+	}
+	_, err := c.get(
+		fmt.Sprintf("/projects/%d/repository/compare", projectID),
+		url.Values{"from": {*push.CommitFrom}, "to": {*push.CommitTo}},
+		&compare,
+	)
+	return compare.Commits, err
+}
+
+// details - requests objects event refers to, they are cached (many events refer to the same issue)
+func (c *GitLabClient) details(ev *GitLabEvent, detail string, cache map[string]interface{}) (*GitLabDetails, error) {
+	details := &GitLabDetails{}
+	iid := 0
+	if ev.Note != nil && ev.Note.NoteableIID != nil {
+		iid = *ev.Note.NoteableIID
+	} else if ev.TargetIID != nil {
+		iid = *ev.TargetIID
+	}
+	key := fmt.Sprintf("%s:%d:%d", detail, ev.ProjectID, iid)
+	var err error
+	switch detail {
+	case "issue":
+		if cached, ok := cache[key]; ok {
+			details.Issue = cached.(*GitLabIssue)
+			break
+		}
+		details.Issue, err = c.Issue(ev.ProjectID, iid)
+		cache[key] = details.Issue
+	case "merge_request":
+		if cached, ok := cache[key]; ok {
+			details.MergeRequest = cached.(*GitLabMergeRequest)
+			break
+		}
+		details.MergeRequest, err = c.MergeRequest(ev.ProjectID, iid)
+		cache[key] = details.MergeRequest
+	case "commits":
+		details.Commits, err = c.PushCommits(ev.ProjectID, ev.PushData)
+	}
+	// Deleted issues and merge requests still have their events, such events are skipped
+	if err == ErrNotFound {
+		Printf("GitLab project %d: %s %d not found, event %d skipped\n", ev.ProjectID, detail, iid, ev.ID)
+		return &GitLabDetails{}, nil
+	}
+	return details, err
+}
+
+// DayJSONs returns events of given projects created at a given day as GHA JSON lines, grouped by hour of day
+func (c *GitLabClient) DayJSONs(day time.Time, projects []GitLabProject) (map[int][]byte, error) {
+	ghaEvents := []*Event{}
+	cache := make(map[string]interface{})
+	for i := range projects {
+		project := &projects[i]
+		events, err := c.ProjectEvents(project.ID, day)
+		if err != nil {
+			return nil, err
+		}
+		for j := range events {
+			ev := &events[j]
+			// Day is given by dates in GitLab API, events are checked in UTC
+			if !DayStart(ev.CreatedAt.UTC()).Equal(DayStart(day)) {
+				continue
+			}
+			_, _, detail := GitLabEventType(ev)
+			details, err := c.details(ev, detail, cache)
+			if err != nil {
+				return nil, err
+			}
+			if e := GitLabEventToGHA(ev, project, details); e != nil {
+				ghaEvents = append(ghaEvents, e)
+			}
+		}
+	}
+	return hourJSONs(ghaEvents)
+}
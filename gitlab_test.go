@@ -0,0 +1,195 @@
+package devstats
+
+import (
+	"encoding/json"
+	"testing"
+
+	lib "devstats"
+)
+
+// gitLabEvent - parses GitLab API event JSON
+func gitLabEvent(t *testing.T, data string) *lib.GitLabEvent {
+	var ev lib.GitLabEvent
+	err := json.Unmarshal([]byte(data), &ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ev
+}
+
+func TestGitLabEventType(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		event  string
+		evType string
+		action string
+		detail string
+	}{
+		{
+			event:  `{"action_name": "opened", "target_type": "Issue", "target_iid": 1}`,
+			evType: "IssuesEvent", action: "opened", detail: "issue",
+		},
+		{
+			event:  `{"action_name": "closed", "target_type": "MergeRequest", "target_iid": 2}`,
+			evType: "PullRequestEvent", action: "closed", detail: "merge_request",
+		},
+		{
+			event:  `{"action_name": "accepted", "target_type": "MergeRequest", "target_iid": 2}`,
+			evType: "PullRequestEvent", action: "closed", detail: "merge_request",
+		},
+		{
+			event:  `{"action_name": "commented on", "target_type": "Note", "note": {"noteable_type": "Issue", "noteable_iid": 1}}`,
+			evType: "IssueCommentEvent", action: "created", detail: "issue",
+		},
+		{
+			event:  `{"action_name": "commented on", "target_type": "DiffNote", "note": {"noteable_type": "MergeRequest", "noteable_iid": 2}}`,
+			evType: "PullRequestReviewCommentEvent", action: "created", detail: "merge_request",
+		},
+		{
+			event:  `{"action_name": "commented on", "target_type": "DiscussionNote", "note": {"noteable_type": "MergeRequest", "noteable_iid": 2}}`,
+			evType: "IssueCommentEvent", action: "created", detail: "merge_request",
+		},
+		{
+			event: `{"action_name": "commented on", "target_type": "Note", "note": {"noteable_type": "Issue", "noteable_iid": 1, "system": true}}`,
+		},
+		{
+			event: `{"action_name": "commented on", "target_type": "Note", "note": {"noteable_type": "Commit"}}`,
+		},
+		{
+			event:  `{"action_name": "pushed to", "push_data": {"action": "pushed", "ref_type": "branch", "ref": "master", "commit_to": "abc"}}`,
+			evType: "PushEvent", detail: "commits",
+		},
+		{
+			event:  `{"action_name": "pushed new", "push_data": {"action": "created", "ref_type": "tag", "ref": "v1.0", "commit_to": "abc"}}`,
+			evType: "CreateEvent",
+		},
+		{
+			event:  `{"action_name": "deleted", "push_data": {"action": "removed", "ref_type": "branch", "ref": "feature"}}`,
+			evType: "DeleteEvent",
+		},
+		{
+			event: `{"action_name": "joined"}`,
+		},
+		{
+			event: `{"action_name": "updated", "target_type": "Milestone", "target_iid": 3}`,
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		evType, action, detail := lib.GitLabEventType(gitLabEvent(t, test.event))
+		if evType != test.evType || action != test.action || detail != test.detail {
+			t.Errorf(
+				"test number %d, expected (%s, %s, %s), got (%s, %s, %s)",
+				index+1, test.evType, test.action, test.detail, evType, action, detail,
+			)
+		}
+	}
+}
+
+func TestGitLabEventToGHA(t *testing.T) {
+	project := &lib.GitLabProject{
+		ID:                278964,
+		PathWithNamespace: "gitlab-org/gitaly",
+		Namespace:         lib.GitLabNamespace{ID: 9970, FullPath: "gitlab-org", Kind: "group"},
+		DefaultBranch:     "master",
+		Visibility:        "public",
+	}
+	var mr lib.GitLabMergeRequest
+	err := json.Unmarshal(
+		[]byte(`{
+			"id": 5000, "iid": 12, "title": "Add feature", "description": "Body", "state": "merged",
+			"created_at": "2017-08-01T10:00:00Z", "updated_at": "2017-08-02T10:00:00Z",
+			"merged_at": "2017-08-02T09:00:00Z", "closed_at": null,
+			"author": {"id": 1, "username": "author"}, "merged_by": {"id": 2, "username": "maintainer"},
+			"labels": [{"id": 7, "name": "bug", "color": "#d9534f"}],
+			"assignees": [{"id": 3, "username": "assignee"}], "reviewers": [{"id": 4, "username": "reviewer"}],
+			"source_branch": "feature", "target_branch": "master", "sha": "head",
+			"diff_refs": {"base_sha": "base", "head_sha": "head"}, "user_notes_count": 3
+		}`),
+		&mr,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	details := &lib.GitLabDetails{MergeRequest: &mr}
+
+	// Merge request opened: state from event time, not the current one
+	ev := lib.GitLabEventToGHA(
+		gitLabEvent(t, `{"id": 100, "project_id": 278964, "action_name": "opened", "target_type": "MergeRequest", "target_iid": 12,
+			"author": {"id": 1, "username": "author"}, "created_at": "2017-08-01T10:00:00Z"}`),
+		project,
+		details,
+	)
+	if ev == nil || ev.ID != "100" || ev.Type != "PullRequestEvent" || ev.Repo.Name != "gitlab-org/gitaly" || ev.Org == nil || ev.Org.Login != "gitlab-org" {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	pr := ev.Payload.PullRequest
+	if pr.State != "open" || *pr.Merged || pr.MergedAt != nil || pr.Base.SHA != "base" || pr.Head.SHA != "head" || pr.Number != 12 {
+		t.Errorf("unexpected opened pull request: %+v", pr)
+	}
+
+	// Merge request merged
+	ev = lib.GitLabEventToGHA(
+		gitLabEvent(t, `{"id": 101, "project_id": 278964, "action_name": "accepted", "target_type": "MergeRequest", "target_iid": 12,
+			"author": {"id": 2, "username": "maintainer"}, "created_at": "2017-08-02T09:00:00Z"}`),
+		project,
+		details,
+	)
+	pr = ev.Payload.PullRequest
+	if *ev.Payload.Action != "closed" || pr.State != "closed" || !*pr.Merged || pr.MergedAt == nil || pr.ClosedAt == nil || pr.MergedBy.Login != "maintainer" {
+		t.Errorf("unexpected merged pull request: %+v", pr)
+	}
+	if len(*pr.RequestedReviewers) != 1 || len(*pr.Assignees) != 1 {
+		t.Errorf("unexpected reviewers/assignees: %+v, %+v", pr.RequestedReviewers, pr.Assignees)
+	}
+
+	// Comment on merge request is saved with artificial issue (negative ID)
+	ev = lib.GitLabEventToGHA(
+		gitLabEvent(t, `{"id": 102, "project_id": 278964, "action_name": "commented on", "target_type": "Note",
+			"author": {"id": 4, "username": "reviewer"}, "created_at": "2017-08-01T11:00:00Z",
+			"note": {"id": 900, "body": "LGTM", "author": {"id": 4, "username": "reviewer"}, "noteable_type": "MergeRequest", "noteable_iid": 12,
+			"created_at": "2017-08-01T11:00:00Z", "updated_at": "2017-08-01T11:00:00Z"}}`),
+		project,
+		details,
+	)
+	issue := ev.Payload.Issue
+	if ev.Type != "IssueCommentEvent" || issue.ID != -5000 || issue.PullRequest == nil || ev.Payload.Comment.ID != 900 {
+		t.Errorf("unexpected comment event: %+v, issue: %+v", ev, issue)
+	}
+	if len(issue.Labels) != 1 || issue.Labels[0].Color != "d9534f" || *issue.Labels[0].ID != 7 {
+		t.Errorf("unexpected labels: %+v", issue.Labels)
+	}
+
+	// Missing details (deleted issue) skip the event
+	ev = lib.GitLabEventToGHA(
+		gitLabEvent(t, `{"id": 103, "action_name": "closed", "target_type": "Issue", "target_iid": 5}`),
+		project,
+		&lib.GitLabDetails{},
+	)
+	if ev != nil {
+		t.Errorf("expected no event for missing issue, got %+v", ev)
+	}
+
+	// Push with commits, event must be readable as GHA JSON
+	ev = lib.GitLabEventToGHA(
+		gitLabEvent(t, `{"id": 104, "action_name": "pushed to", "author": {"id": 1, "username": "author"}, "created_at": "2017-08-01T12:00:00Z",
+			"push_data": {"commit_count": 1, "action": "pushed", "ref_type": "branch", "ref": "master", "commit_from": "aaa", "commit_to": "bbb"}}`),
+		project,
+		&lib.GitLabDetails{Commits: []lib.GitLabCommit{{ID: "bbb", Message: "Fix", AuthorName: "Author", AuthorEmail: "a@b.c"}}},
+	)
+	data, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gha lib.Event
+	err = json.Unmarshal(data, &gha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gha.Type != "PushEvent" || *gha.Payload.Ref != "refs/heads/master" || *gha.Payload.Head != "bbb" || len(*gha.Payload.Commits) != 1 {
+		t.Errorf("unexpected push event: %+v", gha)
+	}
+	if commit := (*gha.Payload.Commits)[0]; commit.SHA != "bbb" || commit.Author.Email != "a@b.c" {
+		t.Errorf("unexpected commit: %+v", commit)
+	}
+}
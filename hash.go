@@ -1,6 +1,8 @@
 package devstats
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"hash/fnv"
 )
 
@@ -22,3 +24,14 @@ func HashStrings(strs []string) int {
 	}
 	return res
 }
+
+// QueryHash - returns hex SHA1 of query parts (SQL, parameters, input data markers)
+// Used to detect that query would return the same result as the last time
+func QueryHash(parts ...string) string {
+	h := sha1.New()
+	for _, part := range parts {
+		_, _ = h.Write([]byte(part))
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
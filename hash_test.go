@@ -0,0 +1,32 @@
+package devstats
+
+import (
+	"testing"
+
+	lib "devstats"
+)
+
+func TestQueryHash(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		a, b  []string
+		equal bool
+	}{
+		{a: []string{"select 1", "2017-08-01"}, b: []string{"select 1", "2017-08-01"}, equal: true},
+		{a: []string{"select 1", "2017-08-01"}, b: []string{"select 1", "2017-08-02"}, equal: false},
+		{a: []string{"select 1", "2017-08-01"}, b: []string{"select 2", "2017-08-01"}, equal: false},
+		{a: []string{"ab", "c"}, b: []string{"a", "bc"}, equal: false},
+		{a: []string{}, b: []string{""}, equal: false},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		ha := lib.QueryHash(test.a...)
+		hb := lib.QueryHash(test.b...)
+		if len(ha) != 40 {
+			t.Errorf("test number %d, expected 40 hex digits, got '%s'", index+1, ha)
+		}
+		if (ha == hb) != test.equal {
+			t.Errorf("test number %d, hashes of %v and %v: '%s', '%s', expected equal: %v", index+1, test.a, test.b, ha, hb, test.equal)
+		}
+	}
+}
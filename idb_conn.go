@@ -8,10 +8,12 @@ import (
 )
 
 // IDBBatchPointsN - keeps InfluxDB batch points and number of points in the current batch
+// Full batches are kept until IDBWritePointsN, or written as soon as they hold IDB_MAXMEMPOINTS points
 type IDBBatchPointsN struct {
 	Points      *client.BatchPoints
 	fullBatches []*client.BatchPoints
 	NPoints     int
+	err         error
 }
 
 // IDBAddPointNWithDB - adds point to the batch, eventually auto flushing
@@ -27,6 +29,9 @@ func IDBAddPointNWithDB(ctx *Ctx, con *client.Client, points *IDBBatchPointsN, p
 		points.fullBatches = append(points.fullBatches, points.Points)
 		bp := IDBBatchPointsWithDB(ctx, con, db)
 		points.Points = &bp
+		if ctx.IDBMaxMemPoints > 0 && len(points.fullBatches)*ctx.IDBMaxBatchPoints >= ctx.IDBMaxMemPoints {
+			idbFlushFullBatches(ctx, con, points)
+		}
 	}
 }
 
@@ -35,8 +40,20 @@ func IDBAddPointN(ctx *Ctx, con *client.Client, points *IDBBatchPointsN, pt *cli
 	IDBAddPointNWithDB(ctx, con, points, pt, ctx.IDBDB)
 }
 
-// IDBWritePointsN - writes batch points
-func IDBWritePointsN(ctx *Ctx, con *client.Client, points *IDBBatchPointsN) (err error) {
+// idbFlushFullBatches writes full batches when memory limit is reached, so big results are streamed to InfluxDB
+// Nothing is written when GHA2DB_SKIPIDB is set, the first error is returned by IDBWritePointsN
+func idbFlushFullBatches(ctx *Ctx, con *client.Client, points *IDBBatchPointsN) {
+	if !ctx.SkipIDB && points.err == nil {
+		if ctx.Debug > 0 {
+			Printf("Writing %d cached batches (memory limit reached)\n", len(points.fullBatches))
+		}
+		points.err = idbWriteFullBatches(ctx, con, points)
+	}
+	points.fullBatches = nil
+}
+
+// idbWriteFullBatches writes all full batches, retrying on timeouts
+func idbWriteFullBatches(ctx *Ctx, con *client.Client, points *IDBBatchPointsN) (err error) {
 	for idx, bp := range points.fullBatches {
 		if ctx.Debug > 0 {
 			Printf("Batch #%d: writing %d points\n", idx+1, ctx.IDBMaxBatchPoints)
@@ -58,6 +75,18 @@ func IDBWritePointsN(ctx *Ctx, con *client.Client, points *IDBBatchPointsN) (err
 			return err
 		}
 	}
+	return nil
+}
+
+// IDBWritePointsN - writes batch points
+func IDBWritePointsN(ctx *Ctx, con *client.Client, points *IDBBatchPointsN) (err error) {
+	if points.err != nil {
+		return points.err
+	}
+	err = idbWriteFullBatches(ctx, con, points)
+	if err != nil {
+		return err
+	}
 	if ctx.Debug > 1 || (ctx.Debug == 1 && len(points.fullBatches) > 0) {
 		Printf("Writing %d points\n", points.NPoints)
 	}
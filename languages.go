@@ -0,0 +1,167 @@
+package devstats
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// languageExtensions - maps file extension (lower case) to language name
+// Names follow github/linguist, only languages that can appear in tracked projects are listed
+var languageExtensions = map[string]string{
+	".go":         "Go",
+	".c":          "C",
+	".cc":         "C++",
+	".cpp":        "C++",
+	".cxx":        "C++",
+	".hh":         "C++",
+	".hpp":        "C++",
+	".hxx":        "C++",
+	".m":          "Objective-C",
+	".mm":         "Objective-C++",
+	".rs":         "Rust",
+	".java":       "Java",
+	".kt":         "Kotlin",
+	".scala":      "Scala",
+	".groovy":     "Groovy",
+	".gradle":     "Gradle",
+	".cs":         "C#",
+	".fs":         "F#",
+	".swift":      "Swift",
+	".py":         "Python",
+	".rb":         "Ruby",
+	".rake":       "Ruby",
+	".php":        "PHP",
+	".pl":         "Perl",
+	".pm":         "Perl",
+	".lua":        "Lua",
+	".js":         "JavaScript",
+	".jsx":        "JavaScript",
+	".mjs":        "JavaScript",
+	".ts":         "TypeScript",
+	".tsx":        "TypeScript",
+	".vue":        "Vue",
+	".html":       "HTML",
+	".htm":        "HTML",
+	".css":        "CSS",
+	".scss":       "SCSS",
+	".less":       "Less",
+	".sh":         "Shell",
+	".bash":       "Shell",
+	".zsh":        "Shell",
+	".ps1":        "PowerShell",
+	".bat":        "Batchfile",
+	".sql":        "SQL",
+	".proto":      "Protocol Buffer",
+	".thrift":     "Thrift",
+	".erl":        "Erlang",
+	".ex":         "Elixir",
+	".exs":        "Elixir",
+	".hs":         "Haskell",
+	".ml":         "OCaml",
+	".clj":        "Clojure",
+	".r":          "R",
+	".jl":         "Julia",
+	".dart":       "Dart",
+	".tf":         "HCL",
+	".hcl":        "HCL",
+	".jsonnet":    "Jsonnet",
+	".libsonnet":  "Jsonnet",
+	".mk":         "Makefile",
+	".cmake":      "CMake",
+	".dockerfile": "Dockerfile",
+	".s":          "Assembly",
+	".asm":        "Assembly",
+	".tpl":        "Smarty",
+	".md":         "Markdown",
+	".rst":        "reStructuredText",
+	".yaml":       "YAML",
+	".yml":        "YAML",
+	".json":       "JSON",
+	".toml":       "TOML",
+	".xml":        "XML",
+}
+
+// languageFilenames - maps well known file names (without extension) to language name
+var languageFilenames = map[string]string{
+	"makefile":       "Makefile",
+	"gnumakefile":    "Makefile",
+	"dockerfile":     "Dockerfile",
+	"cmakelists.txt": "CMake",
+	"rakefile":       "Ruby",
+	"gemfile":        "Ruby",
+	"vagrantfile":    "Ruby",
+	"jenkinsfile":    "Groovy",
+}
+
+// languageExactFilenames - maps file names that are only meaningful in exact case to language name
+// Bazel only reads `BUILD` and `WORKSPACE`, lowercase `build` is often a build script or directory-like file of other tools
+var languageExactFilenames = map[string]string{
+	"BUILD":           "Starlark",
+	"BUILD.bazel":     "Starlark",
+	"WORKSPACE":       "Starlark",
+	"WORKSPACE.bazel": "Starlark",
+}
+
+// vendoredRe - paths not counted: vendored dependencies, generated and minified files
+// This is a simplified version of github/linguist vendor.yml & generated.rb
+var vendoredRe = regexp.MustCompile(
+	`(^|/)(vendor|Godeps|node_modules|bower_components|third_party|_output|dist)/|` +
+		`\.min\.(js|css)$|\.pb\.go$|\.pb\.gw\.go$|_pb2\.py$|(^|/)zz_generated[^/]*\.go$|\.generated\.go$|` +
+		`(^|/)(go\.sum|package-lock\.json|yarn\.lock|Gopkg\.lock|Cargo\.lock|Gemfile\.lock)$`,
+)
+
+// FileLanguage returns language of a given file path, detected by file name or extension
+// Returns "" for vendored/generated files and files with unknown type
+func FileLanguage(filePath string) string {
+	if vendoredRe.MatchString(filePath) {
+		return ""
+	}
+	if lang, ok := languageExactFilenames[path.Base(filePath)]; ok {
+		return lang
+	}
+	base := strings.ToLower(path.Base(filePath))
+	if lang, ok := languageFilenames[base]; ok {
+		return lang
+	}
+	ext := path.Ext(base)
+	if strings.HasPrefix(base, "dockerfile.") {
+		return "Dockerfile"
+	}
+	return languageExtensions[ext]
+}
+
+// Languages returns bytes per language for a given files list: path -> size
+// ".h" headers are ambiguous, they are assigned to C++, Objective-C or C
+// depending on which of them has more bytes in other files of the same repo
+func Languages(files map[string]int64) map[string]int64 {
+	langs := make(map[string]int64)
+	headers := int64(0)
+	for filePath, size := range files {
+		if size <= 0 {
+			continue
+		}
+		if strings.ToLower(path.Ext(filePath)) == ".h" {
+			if !vendoredRe.MatchString(filePath) {
+				headers += size
+			}
+			continue
+		}
+		lang := FileLanguage(filePath)
+		if lang == "" {
+			continue
+		}
+		langs[lang] += size
+	}
+	if headers > 0 {
+		lang := "C"
+		if langs["C++"] > langs[lang] {
+			lang = "C++"
+		}
+		if langs["Objective-C"] > langs[lang] {
+			lang = "Objective-C"
+		}
+		langs[lang] += headers
+	}
+	return langs
+}
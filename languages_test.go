@@ -0,0 +1,84 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestFileLanguage(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		path     string
+		expected string
+	}{
+		{path: "main.go", expected: "Go"},
+		{path: "cmd/get_repos/get_repos.go", expected: "Go"},
+		{path: "README.md", expected: "Markdown"},
+		{path: "Makefile", expected: "Makefile"},
+		{path: "build/Dockerfile", expected: "Dockerfile"},
+		{path: "images/Dockerfile.test", expected: "Dockerfile"},
+		{path: "src/LIB.CPP", expected: "C++"},
+		{path: "LICENSE", expected: ""},
+		{path: "image.png", expected: ""},
+		{path: "vendor/github.com/lib/pq/conn.go", expected: ""},
+		{path: "pkg/vendor/x.go", expected: ""},
+		{path: "web/node_modules/a/index.js", expected: ""},
+		{path: "web/app.min.js", expected: ""},
+		{path: "api/types.pb.go", expected: ""},
+		{path: "pkg/apis/zz_generated.deepcopy.go", expected: ""},
+		{path: "go.sum", expected: ""},
+		{path: "vendors.go", expected: "Go"},
+		{path: "pkg/BUILD", expected: "Starlark"},
+		{path: "pkg/BUILD.bazel", expected: "Starlark"},
+		{path: "WORKSPACE", expected: "Starlark"},
+		{path: "scripts/build", expected: ""},
+		{path: "Build", expected: ""},
+		{path: "workspace", expected: ""},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.FileLanguage(test.path)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v, test case: %+v", index+1, test.expected, got, test)
+		}
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		files    map[string]int64
+		expected map[string]int64
+	}{
+		{files: map[string]int64{}, expected: map[string]int64{}},
+		{
+			files:    map[string]int64{"a.go": 10, "b.go": 20, "c.py": 5, "d.png": 100, "e.go": -1},
+			expected: map[string]int64{"Go": 30, "Python": 5},
+		},
+		{
+			files:    map[string]int64{"a.c": 10, "a.h": 3},
+			expected: map[string]int64{"C": 13},
+		},
+		{
+			files:    map[string]int64{"a.c": 10, "b.cc": 20, "a.h": 3, "vendor/x.h": 100},
+			expected: map[string]int64{"C": 10, "C++": 23},
+		},
+		{
+			files:    map[string]int64{"a.m": 30, "b.cc": 20, "a.h": 3},
+			expected: map[string]int64{"Objective-C": 33, "C++": 20},
+		},
+		{
+			files:    map[string]int64{"a.h": 3},
+			expected: map[string]int64{"C": 3},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.Languages(test.files)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v, test case: %+v", index+1, test.expected, got, test)
+		}
+	}
+}
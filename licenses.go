@@ -0,0 +1,92 @@
+package devstats
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// NoAssertion - SPDX identifier used when license file is present but its license cannot be classified
+const NoAssertion = "NOASSERTION"
+
+// licenseFileRe - LICENSE, LICENSE.md, LICENSE-APACHE, COPYING, COPYING.LESSER, UNLICENSE etc.
+var licenseFileRe = regexp.MustCompile(`^(licen[cs]e|copying|unlicense)([.\-_][^/]*)?$`)
+
+// spaceRe - used to normalize license texts, they are often reformatted/rewrapped
+var spaceRe = regexp.MustCompile(`\s+`)
+
+// licenseHeadSize - title of a license text (possibly after a short copyright preamble) is searched in its normalized beginning only
+const licenseHeadSize = 0x400
+
+// spdxLicenses - SPDX identifiers, title that must appear in the normalized license text head and phrases that must all appear anywhere in it
+// Titles are required for licenses whose texts mention other licenses: full GPL-3.0 text mentions AGPL and LGPL,
+// MPL-2.0 and EPL-2.0 texts mention GPL versions as "Secondary Licenses", so their phrases alone are ambiguous
+// Order matters: more specific licenses must be checked first
+var spdxLicenses = []struct {
+	id      string
+	title   *regexp.Regexp
+	phrases []string
+}{
+	{id: "AGPL-3.0", title: regexp.MustCompile(`gnu affero general public license\W+version 3\b`)},
+	{id: "LGPL-3.0", title: regexp.MustCompile(`gnu lesser general public license\W+version 3\b`)},
+	{id: "LGPL-2.1", title: regexp.MustCompile(`gnu lesser general public license\W+version 2\.1\b`)},
+	{id: "GPL-3.0", title: regexp.MustCompile(`gnu general public license\W+version 3\b`)},
+	{id: "GPL-2.0", title: regexp.MustCompile(`gnu general public license\W+version 2\b`)},
+	{id: "MPL-2.0", title: regexp.MustCompile(`mozilla public license\W+version 2\.0\b`)},
+	{id: "EPL-2.0", title: regexp.MustCompile(`eclipse public license\W+v(ersion)? ?2\.0\b`)},
+	{id: "EPL-1.0", title: regexp.MustCompile(`eclipse public license\W+v(ersion)? ?1\.0\b`)},
+	{id: "Apache-2.0", phrases: []string{"apache license", "version 2.0"}},
+	{id: "CC0-1.0", phrases: []string{"cc0 1.0 universal"}},
+	{id: "CC-BY-4.0", phrases: []string{"creative commons attribution 4.0 international"}},
+	{id: "Unlicense", phrases: []string{"this is free and unencumbered software released into the public domain"}},
+	{id: "ISC", phrases: []string{"permission to use, copy, modify, and/or distribute this software for any purpose with or without fee is hereby granted"}},
+	{
+		id: "MIT",
+		phrases: []string{
+			"permission is hereby granted, free of charge",
+			"the above copyright notice and this permission notice shall be included",
+		},
+	},
+	{
+		id: "BSD-3-Clause",
+		phrases: []string{
+			"redistribution and use in source and binary forms",
+			"neither the name of",
+		},
+	},
+	{id: "BSD-2-Clause", phrases: []string{"redistribution and use in source and binary forms"}},
+}
+
+// IsLicenseFile returns true if given path is a (non vendored) license file
+func IsLicenseFile(filePath string) bool {
+	if vendoredRe.MatchString(filePath) {
+		return false
+	}
+	return licenseFileRe.MatchString(strings.ToLower(path.Base(filePath)))
+}
+
+// DetectLicense returns SPDX identifier of a given license text
+// It returns NoAssertion when text doesn't match any known license
+func DetectLicense(text string) string {
+	text = strings.ToLower(spaceRe.ReplaceAllString(text, " "))
+	head := text
+	if len(head) > licenseHeadSize {
+		head = head[:licenseHeadSize]
+	}
+	for _, license := range spdxLicenses {
+		if license.title != nil && !license.title.MatchString(head) {
+			continue
+		}
+		matches := true
+		for _, phrase := range license.phrases {
+			if !strings.Contains(text, phrase) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return license.id
+		}
+	}
+	return NoAssertion
+}
@@ -0,0 +1,132 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestIsLicenseFile(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		path     string
+		expected bool
+	}{
+		{path: "LICENSE", expected: true},
+		{path: "LICENSE.md", expected: true},
+		{path: "License.txt", expected: true},
+		{path: "LICENCE", expected: true},
+		{path: "LICENSE-APACHE", expected: true},
+		{path: "COPYING", expected: true},
+		{path: "COPYING.LESSER", expected: true},
+		{path: "UNLICENSE", expected: true},
+		{path: "docs/LICENSE", expected: true},
+		{path: "vendor/github.com/lib/pq/LICENSE.md", expected: false},
+		{path: "third_party/LICENSE", expected: false},
+		{path: "licenses.go", expected: false},
+		{path: "LICENSES", expected: false},
+		{path: "README.md", expected: false},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.IsLicenseFile(test.path)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v, test case: %+v", index+1, test.expected, got, test)
+		}
+	}
+}
+
+func TestDetectLicense(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		text     string
+		expected string
+	}{
+		{
+			text:     "\n                                 Apache License\n                           Version 2.0, January 2004\n",
+			expected: "Apache-2.0",
+		},
+		{
+			text: "MIT License\n\nCopyright (c) 2017 CNCF\n\nPermission is hereby granted, free of charge, to any person obtaining a copy\n" +
+				"...\nThe above copyright notice and this permission notice shall be\nincluded in all copies or substantial portions of the Software.\n",
+			expected: "MIT",
+		},
+		{
+			text: "Redistribution and use in source and binary forms, with or without\nmodification, are permitted provided that ...\n" +
+				"   * Neither the name of Google Inc. nor the names of its\ncontributors may be used ...",
+			expected: "BSD-3-Clause",
+		},
+		{
+			text:     "Redistribution and use in source and binary forms, with or without modification, are permitted",
+			expected: "BSD-2-Clause",
+		},
+		{
+			text:     "GNU GENERAL PUBLIC LICENSE\n   Version 2, June 1991",
+			expected: "GPL-2.0",
+		},
+		{
+			text:     "GNU GENERAL PUBLIC LICENSE\n   Version 3, 29 June 2007",
+			expected: "GPL-3.0",
+		},
+		{
+			text:     "GNU LESSER GENERAL PUBLIC LICENSE\n   Version 3, 29 June 2007\n ... GNU General Public License ...",
+			expected: "LGPL-3.0",
+		},
+		{
+			text:     "GNU AFFERO GENERAL PUBLIC LICENSE\n   Version 3, 19 November 2007",
+			expected: "AGPL-3.0",
+		},
+		{
+			text:     "Mozilla Public License Version 2.0\n==================================",
+			expected: "MPL-2.0",
+		},
+		{
+			text:     "This is free and unencumbered software released into the public domain.",
+			expected: "Unlicense",
+		},
+		{
+			text:     "All rights reserved.",
+			expected: lib.NoAssertion,
+		},
+		{
+			text:     "",
+			expected: lib.NoAssertion,
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.DetectLicense(test.text)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v, test case: %+v", index+1, test.expected, got, test)
+		}
+	}
+}
+
+func TestDetectLicenseFullTexts(t *testing.T) {
+	// Full license texts mention other licenses (GPL-3.0 mentions AGPL and LGPL, MPL-2.0 lists GPLs as secondary licenses)
+	var testCases = []struct {
+		file     string
+		expected string
+	}{
+		{file: "testdata/licenses/GPL-3.0.txt", expected: "GPL-3.0"},
+		{file: "testdata/licenses/AGPL-3.0.txt", expected: "AGPL-3.0"},
+		{file: "testdata/licenses/MPL-2.0.txt", expected: "MPL-2.0"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		text, err := ioutil.ReadFile(test.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := lib.DetectLicense(string(text))
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v, test case: %+v", index+1, test.expected, got, test)
+		}
+		// Copyright preamble before license text is common
+		got = lib.DetectLicense("Copyright 2018 The Authors.\n\n" + string(text))
+		if got != test.expected {
+			t.Errorf("test number %d with preamble, expected %v, got %v, test case: %+v", index+1, test.expected, got, test)
+		}
+	}
+}
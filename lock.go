@@ -0,0 +1,183 @@
+package devstats
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strings"
+	"time"
+)
+
+// SyncLock - name of advisory lock held by `gha2db_sync` for the whole sync
+// Advisory locks are per database, so syncs of different projects don't wait for each other
+const SyncLock = "gha2db_sync"
+
+// AdvisoryLockKey returns Postgres advisory lock key of lock `name`
+func AdvisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("devstats:" + name))
+	return int64(h.Sum64())
+}
+
+// AdvisoryLock - Postgres session level advisory lock, held on its own connection taken from the pool
+// Postgres releases it when that connection is closed, so lock of a crashed process is never left behind
+type AdvisoryLock struct {
+	name string
+	key  int64
+	conn *sql.Conn
+}
+
+// LockHolder - session holding an advisory lock
+type LockHolder struct {
+	PID         int
+	Application string
+	Client      string
+	Since       time.Time
+}
+
+func (h LockHolder) String() string {
+	return fmt.Sprintf("pid %d (%s) from %s since %s", h.PID, h.Application, h.Client, ToYMDHMSDate(h.Since))
+}
+
+// AcquireLock takes advisory lock `name` in `ctx.PgDB` database
+// Waits up to GHA2DB_LOCK_TIMEOUT seconds when lock is held by other session, then returns error describing its holders
+func AcquireLock(con *sql.DB, ctx *Ctx, name string) (*AdvisoryLock, error) {
+	bg := context.Background()
+	conn, err := con.Conn(bg)
+	if err != nil {
+		return nil, err
+	}
+	// Lock holder is identified by its application name in `pg_stat_activity`
+	host, _ := os.Hostname()
+	_, err = conn.ExecContext(
+		bg,
+		"select set_config('application_name', "+NValue(1)+", false)",
+		fmt.Sprintf("devstats %s %s:%d", name, host, os.Getpid()),
+	)
+	if err != nil {
+		_ = releaseConn(conn)
+		return nil, err
+	}
+	key := AdvisoryLockKey(name)
+	deadline := time.Now().Add(time.Duration(ctx.LockTimeout) * time.Second)
+	waiting := false
+	for {
+		var locked bool
+		err = conn.QueryRowContext(bg, "select pg_try_advisory_lock("+NValue(1)+")", key).Scan(&locked)
+		if err != nil {
+			_ = releaseConn(conn)
+			return nil, err
+		}
+		if locked {
+			if ctx.Debug > 0 {
+				Printf("Acquired lock %s in %s\n", name, ctx.PgDB)
+			}
+			return &AdvisoryLock{name: name, key: key, conn: conn}, nil
+		}
+		if !time.Now().Before(deadline) {
+			_ = releaseConn(conn)
+			holders, err := LockHolders(con, ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			desc := []string{}
+			for _, holder := range holders {
+				desc = append(desc, holder.String())
+			}
+			return nil, fmt.Errorf(
+				"lock %s in %s is held by: %s, run with --force-unlock to release it when its holder is stuck",
+				name, ctx.PgDB, strings.Join(desc, ", "),
+			)
+		}
+		if !waiting {
+			Printf("Lock %s in %s is held by other session, waiting up to %ds\n", name, ctx.PgDB, ctx.LockTimeout)
+			waiting = true
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// Unlock releases the lock and returns its connection to the pool
+func (l *AdvisoryLock) Unlock() error {
+	var unlocked bool
+	err := l.conn.QueryRowContext(context.Background(), "select pg_advisory_unlock("+NValue(1)+")", l.key).Scan(&unlocked)
+	if err != nil {
+		// Session may still hold the lock, it must not be reused
+		discardConn(l.conn)
+		return err
+	}
+	cerr := releaseConn(l.conn)
+	if !unlocked {
+		return fmt.Errorf("lock %s was not held", l.name)
+	}
+	return cerr
+}
+
+// releaseConn resets lock's application name and returns connection to the pool
+// Otherwise all later queries on that pooled session would show up as lock holder in `pg_stat_activity`
+func releaseConn(conn *sql.Conn) error {
+	_, err := conn.ExecContext(context.Background(), "reset application_name")
+	if err != nil {
+		discardConn(conn)
+		return err
+	}
+	return conn.Close()
+}
+
+// discardConn closes connection's session instead of returning it to the pool
+func discardConn(conn *sql.Conn) {
+	_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
+
+// LockHolders returns sessions holding advisory lock `name` in `ctx.PgDB` database
+func LockHolders(con *sql.DB, ctx *Ctx, name string) ([]LockHolder, error) {
+	// Bigint advisory lock key is split into classid (high 32 bits) and objid (low 32 bits)
+	key := uint64(AdvisoryLockKey(name))
+	rows, err := con.Query(
+		"select a.pid, coalesce(a.application_name, ''), coalesce(host(a.client_addr), 'local'), a.backend_start "+
+			"from pg_locks l join pg_stat_activity a on a.pid = l.pid "+
+			"where l.locktype = 'advisory' and l.granted and l.objsubid = 1 "+
+			"and l.database = (select oid from pg_database where datname = current_database()) "+
+			"and l.classid::bigint = "+NValue(1)+" and l.objid::bigint = "+NValue(2),
+		int64(key>>32),
+		int64(key&0xffffffff),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	holders := []LockHolder{}
+	for rows.Next() {
+		var holder LockHolder
+		err = rows.Scan(&holder.PID, &holder.Application, &holder.Client, &holder.Since)
+		if err != nil {
+			return nil, err
+		}
+		holders = append(holders, holder)
+	}
+	return holders, rows.Err()
+}
+
+// ForceUnlock terminates sessions holding advisory lock `name` in `ctx.PgDB` database, Postgres releases the lock then
+// Returns holders that were terminated
+func ForceUnlock(con *sql.DB, ctx *Ctx, name string) ([]LockHolder, error) {
+	holders, err := LockHolders(con, ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, holder := range holders {
+		var terminated bool
+		err = con.QueryRow("select pg_terminate_backend("+NValue(1)+")", holder.PID).Scan(&terminated)
+		if err != nil {
+			return nil, err
+		}
+		if !terminated {
+			return nil, fmt.Errorf("cannot terminate %s", holder)
+		}
+	}
+	return holders, nil
+}
@@ -0,0 +1,20 @@
+package devstats
+
+import (
+	"testing"
+
+	lib "devstats"
+)
+
+func TestAdvisoryLockKey(t *testing.T) {
+	// Key must be stable, other devstats tools (and older versions) use the same lock
+	key := lib.AdvisoryLockKey(lib.SyncLock)
+	if key != lib.AdvisoryLockKey("gha2db_sync") {
+		t.Errorf("expected the same key for the same lock name, got %d", key)
+	}
+	for _, name := range []string{"", "gha2db", "gha2db_sync ", "GHA2DB_SYNC"} {
+		if lib.AdvisoryLockKey(name) == key {
+			t.Errorf("expected different key for lock '%s', got %d", name, key)
+		}
+	}
+}
@@ -101,7 +101,6 @@ func ClearDBLogs() {
 
 	// Connect to DB
 	c := PgConn(&ctx)
-	defer func() { _ = c.Close() }()
 
 	// Clear logs older that defined period
 	fmt.Printf("Clearing old DB logs.\n")
@@ -0,0 +1,123 @@
+package devstats
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// maxMacroDepth - macros can use other macros, deeper nesting is most likely a cycle
+const maxMacroDepth = 10
+
+// macroRe - macro reference: {{name}} or {{name(arg1, arg2)}}
+// Other placeholders ({{from}}, {{period:alias.column}}, ...) are left as they are when no macro has their name
+var macroRe = regexp.MustCompile(`{{([a-z_][a-z0-9_]*)(?:\(([^(){}]*)\))?}}`)
+
+// macroArgRe - argument placeholder in macro's SQL: $1, $2, ...
+var macroArgRe = regexp.MustCompile(`\$([0-9]+)`)
+
+// Macros - named SQL fragments shared by metrics' SQL files, by name
+type Macros map[string]string
+
+// macrosFile - macros.yaml contents
+type macrosFile struct {
+	Macros Macros `yaml:"macros"`
+}
+
+// readMacrosFile adds macros from `path` to `macros` (replacing those with the same names), missing file is not an error
+func readMacrosFile(macros Macros, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var file macrosFile
+	err = yaml.Unmarshal(data, &file)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	for name, sql := range file.Macros {
+		if !macroRe.MatchString("{{" + name + "}}") {
+			return fmt.Errorf("%s: invalid macro name '%s'", path, name)
+		}
+		macros[name] = strings.TrimSpace(sql)
+	}
+	return nil
+}
+
+// ReadMacros returns macros available to `sqlFile`: "exclude_bots" (util_sql/exclude_bots.sql), those defined in
+// util_sql/macros.yaml and project's overrides in macros.yaml next to `sqlFile` (like metrics/kubernetes/macros.yaml)
+func ReadMacros(dataPrefix, sqlFile string) (Macros, error) {
+	macros := Macros{}
+	data, err := ioutil.ReadFile(dataPrefix + "util_sql/exclude_bots.sql")
+	if err != nil {
+		return nil, err
+	}
+	macros["exclude_bots"] = string(data)
+	err = readMacrosFile(macros, dataPrefix+"util_sql/macros.yaml")
+	if err != nil {
+		return nil, err
+	}
+	err = readMacrosFile(macros, filepath.Join(filepath.Dir(sqlFile), "macros.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	return macros, nil
+}
+
+// expandMacro returns macro's SQL with $1, $2, ... replaced by `args`
+func expandMacro(name, sql string, args []string) (string, error) {
+	var err error
+	sql = macroArgRe.ReplaceAllStringFunc(sql, func(arg string) string {
+		n, _ := strconv.Atoi(arg[1:])
+		if n < 1 || n > len(args) {
+			if err == nil {
+				err = fmt.Errorf("macro '%s' uses %s, but it has %d argument(s)", name, arg, len(args))
+			}
+			return arg
+		}
+		return args[n-1]
+	})
+	return sql, err
+}
+
+// ExpandMacros replaces {{name}} and {{name(arg1, arg2)}} in `sql` with SQL of macro `name`, arguments replace its $1, $2, ...
+// Macros can use other macros, placeholders that are not macros are kept (they are replaced later, like {{from}} and {{to}})
+func ExpandMacros(sql string, macros Macros) (string, error) {
+	for depth := 0; depth < maxMacroDepth; depth++ {
+		var err error
+		expanded := false
+		sql = macroRe.ReplaceAllStringFunc(sql, func(ref string) string {
+			match := macroRe.FindStringSubmatch(ref)
+			body, ok := macros[match[1]]
+			if !ok || err != nil {
+				return ref
+			}
+			args := []string{}
+			if strings.TrimSpace(match[2]) != "" {
+				for _, arg := range strings.Split(match[2], ",") {
+					args = append(args, strings.TrimSpace(arg))
+				}
+			}
+			var res string
+			res, err = expandMacro(match[1], body, args)
+			expanded = true
+			return res
+		})
+		if err != nil {
+			return "", err
+		}
+		if !expanded {
+			return sql, nil
+		}
+	}
+	return "", fmt.Errorf("macros nested deeper than %d levels (macro uses itself?)", maxMacroDepth)
+}
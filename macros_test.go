@@ -0,0 +1,108 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestExpandMacros(t *testing.T) {
+	macros := lib.Macros{
+		"exclude_bots": "not like '%-bot'",
+		"period_range": "$1 >= '{{from}}' and $1 < '{{to}}'",
+		"between":      "$1 between $2 and $3",
+		"recent":       "{{period_range($1)}} and {{bots($1)}}",
+		"bots":         "$2 is null",
+		"loop":         "{{loop}}",
+	}
+
+	// Test cases
+	var testCases = []struct {
+		sql      string
+		expected string
+		err      bool
+	}{
+		{sql: "select 1", expected: "select 1"},
+		{sql: "login {{exclude_bots}}", expected: "login not like '%-bot'"},
+		{sql: "{{period_range(e.created_at)}}", expected: "e.created_at >= '{{from}}' and e.created_at < '{{to}}'"},
+		{sql: "{{ between( x , 1, 2 ) }}", expected: "{{ between( x , 1, 2 ) }}"},
+		{sql: "{{between( x , 1, 2 )}}", expected: "x between 1 and 2"},
+		{sql: "{{period:e.created_at}} and {{n}}", expected: "{{period:e.created_at}} and {{n}}"},
+		{sql: "{{unknown(a)}}", expected: "{{unknown(a)}}"},
+		{sql: "{{recent(e.dt, x)}}", expected: "", err: true},
+		{sql: "{{between(x, 1)}}", expected: "", err: true},
+		{sql: "{{loop}}", expected: "", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.ExpandMacros(test.sql, macros)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error for '%s', got '%s'", index+1, test.sql, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("test number %d, expected '%s', got '%s'", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestReadMacros(t *testing.T) {
+	dir, err := ioutil.TempDir("", "macros")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	write := func(path, data string) {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	write("util_sql/exclude_bots.sql", "not like '%-bot'\n")
+	write("util_sql/macros.yaml", "macros:\n  a: shared a\n  b: shared b\n")
+	write("metrics/proj/macros.yaml", "macros:\n  b: proj b\n  exclude_bots: not like 'bot-%'\n")
+	write("metrics/bad/macros.yaml", "macros:\n  Bad-Name: x\n")
+	prefix := dir + "/"
+
+	// Project overrides shared macros and exclude_bots
+	macros, err := lib.ReadMacros(prefix, prefix+"metrics/proj/metric.sql")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := lib.Macros{"exclude_bots": "not like 'bot-%'", "a": "shared a", "b": "proj b"}
+	if len(macros) != len(expected) {
+		t.Errorf("expected %+v, got %+v", expected, macros)
+	}
+	for name, sql := range expected {
+		if macros[name] != sql {
+			t.Errorf("macro '%s': expected '%s', got '%s'", name, sql, macros[name])
+		}
+	}
+
+	// Projects without macros.yaml use shared ones
+	macros, err = lib.ReadMacros(prefix, prefix+"metrics/other/metric.sql")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if macros["exclude_bots"] != "not like '%-bot'\n" || macros["b"] != "shared b" {
+		t.Errorf("unexpected macros: %+v", macros)
+	}
+
+	// Invalid macro name
+	_, err = lib.ReadMacros(prefix, prefix+"metrics/bad/metric.sql")
+	if err == nil {
+		t.Errorf("expected error for invalid macro name")
+	}
+}
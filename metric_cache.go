@@ -0,0 +1,95 @@
+package devstats
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// MetricCacheResult - metric query result saved in `gha_metric_cache`: number of columns and rows (NULL values are nil)
+type MetricCacheResult struct {
+	Columns int         `json:"columns"`
+	Rows    [][]*string `json:"rows"`
+}
+
+// MetricCacheExpires returns when cached result of a period ending at `to` expires
+// Period ending before the last event is closed (new events are always later), its result doesn't expire (nil)
+// Tools changing its other inputs (affiliations, repo groups, commits files) clear it, see ClearMetricCache
+// Result of the still open period expires after `ttl`, it is not cached at all (false) when `ttl` is 0
+func MetricCacheExpires(to, lastEvent, now time.Time, ttl time.Duration) (*time.Time, bool) {
+	if !to.After(lastEvent) {
+		return nil, true
+	}
+	if ttl <= 0 {
+		return nil, false
+	}
+	expires := now.Add(ttl)
+	return &expires, true
+}
+
+// GetMetricCache returns cached result of query `key` (QueryHash of the final SQL) in period, nil when there is none or it expired
+func GetMetricCache(con *sql.DB, ctx *Ctx, key, period string, from, to time.Time) (*MetricCacheResult, error) {
+	var data string
+	err := QueryRowSQL(
+		con,
+		ctx,
+		"select result from gha_metric_cache where hash = "+NValue(1)+" and period = "+NValue(2)+
+			" and dt_from = "+NValue(3)+" and dt_to = "+NValue(4)+" and (expires_at is null or expires_at > now())",
+		key,
+		period,
+		from,
+		to,
+	).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var res MetricCacheResult
+	err = json.Unmarshal([]byte(data), &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// SetMetricCache saves result of query `key` in period, `expires` is nil for closed periods
+func SetMetricCache(con *sql.DB, ctx *Ctx, key, period string, from, to time.Time, res *MetricCacheResult, expires *time.Time) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	_, err = ExecSQL(
+		con,
+		ctx,
+		"insert into gha_metric_cache(hash, period, dt_from, dt_to, result, dt, expires_at) "+NValues(7)+
+			" on conflict (hash, period, dt_from, dt_to) do update set result = excluded.result, dt = excluded.dt, expires_at = excluded.expires_at",
+		key,
+		period,
+		from,
+		to,
+		string(data),
+		time.Now(),
+		expires,
+	)
+	return err
+}
+
+// ClearMetricCache deletes cached results of periods overlapping `from` - `to`, all of them when `from` is nil
+// Tools changing past data (backfill, dead letters reprocessing, affiliations import, repo groups, commits files) call it, so metrics are computed again
+func ClearMetricCache(con *sql.DB, ctx *Ctx, from, to *time.Time) (int64, error) {
+	var (
+		res sql.Result
+		err error
+	)
+	if from == nil {
+		res, err = ExecSQL(con, ctx, "delete from gha_metric_cache")
+	} else {
+		res, err = ExecSQL(con, ctx, "delete from gha_metric_cache where dt_to > "+NValue(1)+" and dt_from < "+NValue(2), *from, *to)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
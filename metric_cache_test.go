@@ -0,0 +1,40 @@
+package devstats
+
+import (
+	"testing"
+	"time"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestMetricCacheExpires(t *testing.T) {
+	ft := testlib.YMDHMS
+	now := ft(2018, 3, 10, 12, 30, 0)
+	lastEvent := ft(2018, 3, 10, 11, 59, 58)
+	expires := ft(2018, 3, 10, 12, 40, 0)
+
+	// Test cases
+	var testCases = []struct {
+		to      time.Time
+		ttl     time.Duration
+		cached  bool
+		expires *time.Time
+	}{
+		{to: ft(2018, 3, 10, 0, 0, 0), ttl: 10 * time.Minute, cached: true},
+		{to: ft(2018, 3, 10, 0, 0, 0), ttl: 0, cached: true},
+		{to: lastEvent, ttl: 0, cached: true},
+		{to: ft(2018, 3, 11, 0, 0, 0), ttl: 10 * time.Minute, cached: true, expires: &expires},
+		{to: ft(2018, 3, 11, 0, 0, 0), ttl: 0, cached: false},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, cached := lib.MetricCacheExpires(test.to, lastEvent, now, test.ttl)
+		if cached != test.cached {
+			t.Errorf("test number %d, expected cached %v, got %v", index+1, test.cached, cached)
+		}
+		if (got == nil) != (test.expires == nil) || (got != nil && !got.Equal(*test.expires)) {
+			t.Errorf("test number %d, expected expiry %v, got %v", index+1, test.expires, got)
+		}
+	}
+}
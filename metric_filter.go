@@ -0,0 +1,64 @@
+package devstats
+
+import (
+	"strings"
+)
+
+// MetricFilter - metrics selected by GHA2DB_METRICS: metric names, SQL file names (without .sql) or tags ("tag:name")
+// Empty filter selects all metrics
+type MetricFilter struct {
+	selectors []string
+	matched   map[string]bool
+}
+
+// NewMetricFilter creates filter from GHA2DB_METRICS selectors, comparison is case insensitive
+func NewMetricFilter(selectors []string) *MetricFilter {
+	f := &MetricFilter{matched: make(map[string]bool)}
+	for _, selector := range selectors {
+		f.selectors = append(f.selectors, strings.ToLower(selector))
+	}
+	return f
+}
+
+// All - does filter select all metrics?
+func (f *MetricFilter) All() bool {
+	return len(f.selectors) == 0
+}
+
+// Selected - is metric with given name, SQL file name and tags selected?
+// Gaps have no SQL, empty `sql` is not compared
+func (f *MetricFilter) Selected(name, sql string, tags []string) bool {
+	if f.All() {
+		return true
+	}
+	selected := false
+	for _, selector := range f.selectors {
+		match := false
+		if strings.HasPrefix(selector, "tag:") {
+			for _, tag := range tags {
+				if strings.ToLower(tag) == selector[4:] {
+					match = true
+					break
+				}
+			}
+		} else {
+			match = strings.ToLower(name) == selector || (sql != "" && strings.ToLower(sql) == selector)
+		}
+		if match {
+			f.matched[selector] = true
+			selected = true
+		}
+	}
+	return selected
+}
+
+// Unmatched returns selectors that didn't select any metric yet (probably misspelled)
+func (f *MetricFilter) Unmatched() []string {
+	unmatched := []string{}
+	for _, selector := range f.selectors {
+		if !f.matched[selector] {
+			unmatched = append(unmatched, selector)
+		}
+	}
+	return unmatched
+}
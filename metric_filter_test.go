@@ -0,0 +1,53 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestMetricFilter(t *testing.T) {
+	type metric struct {
+		name string
+		sql  string
+		tags []string
+	}
+	metrics := []metric{
+		{name: "All PRs merged", sql: "all_prs_merged"},
+		{name: "Stargazers, watchers, forks", sql: "watchers", tags: []string{"repos"}},
+		{name: "Time opened to merged (number of hours)", sql: "opened_to_merged", tags: []string{"slow", "prs"}},
+		{name: "SIG mentions", sql: "", tags: []string{"sigs"}},
+	}
+
+	// Test cases
+	var testCases = []struct {
+		selectors []string
+		expected  []string
+		unmatched []string
+	}{
+		{selectors: nil, expected: []string{"all_prs_merged", "watchers", "opened_to_merged", ""}, unmatched: []string{}},
+		{selectors: []string{"all_prs_merged"}, expected: []string{"all_prs_merged"}, unmatched: []string{}},
+		{selectors: []string{"ALL PRS MERGED", "watchers"}, expected: []string{"all_prs_merged", "watchers"}, unmatched: []string{}},
+		{selectors: []string{"tag:slow", "tag:Sigs"}, expected: []string{"opened_to_merged", ""}, unmatched: []string{}},
+		{selectors: []string{"tag:prs", "opened_to_merged"}, expected: []string{"opened_to_merged"}, unmatched: []string{}},
+		{selectors: []string{"watchers", "all_pr_merged", "tag:none"}, expected: []string{"watchers"}, unmatched: []string{"all_pr_merged", "tag:none"}},
+		{selectors: []string{""}, expected: []string{}, unmatched: []string{""}},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		filter := lib.NewMetricFilter(test.selectors)
+		got := []string{}
+		for _, m := range metrics {
+			if filter.Selected(m.name, m.sql, m.tags) {
+				got = append(got, m.sql)
+			}
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+		if unmatched := filter.Unmatched(); !reflect.DeepEqual(unmatched, test.unmatched) {
+			t.Errorf("test number %d, expected unmatched %v, got %v", index+1, test.unmatched, unmatched)
+		}
+	}
+}
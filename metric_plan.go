@@ -0,0 +1,148 @@
+package devstats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MetricPlan - metrics computed by one sync with their dependencies (metrics.yaml `depends_on`)
+// Metric starts after all its dependencies computed in the same sync finished
+type MetricPlan struct {
+	names []string
+	deps  map[string][]string
+}
+
+// NewMetricPlan creates plan of computing metrics `names` (in metrics.yaml order), `deps` are dependencies of all defined metrics
+// Dependencies not computed by this sync (not selected by GHA2DB_METRICS) are not waited for
+// Returns error for unknown dependencies and dependency cycles
+func NewMetricPlan(deps map[string][]string, names []string) (*MetricPlan, error) {
+	p := &MetricPlan{names: names, deps: make(map[string][]string)}
+	planned := make(map[string]bool)
+	for _, name := range names {
+		planned[name] = true
+	}
+	for _, name := range names {
+		added := make(map[string]bool)
+		for _, dep := range deps[name] {
+			if _, ok := deps[dep]; !ok {
+				return nil, fmt.Errorf("metric '%s' depends on unknown metric '%s'", name, dep)
+			}
+			if dep == name {
+				return nil, fmt.Errorf("metric '%s' depends on itself", name)
+			}
+			if planned[dep] && !added[dep] {
+				added[dep] = true
+				p.deps[name] = append(p.deps[name], dep)
+			}
+		}
+	}
+	// Check cycles
+	state := make(map[string]int)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("metrics dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		for _, dep := range p.deps[name] {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Deps returns metrics that `name` waits for, sorted
+func (p *MetricPlan) Deps(name string) []string {
+	deps := append([]string{}, p.deps[name]...)
+	sort.Strings(deps)
+	return deps
+}
+
+// Order returns metrics in the order they start when computed one by one: metrics.yaml order, but after their dependencies
+func (p *MetricPlan) Order() (order []string) {
+	finished := make(map[string]bool)
+	for len(order) < len(p.names) {
+		for _, name := range p.names {
+			if !finished[name] && p.ready(name, finished) {
+				finished[name] = true
+				order = append(order, name)
+				break
+			}
+		}
+	}
+	return
+}
+
+// ready - have all dependencies of `name` finished?
+func (p *MetricPlan) ready(name string, finished map[string]bool) bool {
+	for _, dep := range p.deps[name] {
+		if !finished[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// Run computes all metrics calling `compute` for each of them, up to `parallel` metrics at once
+// Metrics that are ready (all dependencies finished) start in metrics.yaml order, independent branches run in parallel
+// When `compute` panics (like FatalOnError), no other metric starts and the panic is raised again in the calling
+// goroutine after running metrics finish, so caller's deferred failure handlers see it
+func (p *MetricPlan) Run(parallel int, compute func(name string)) {
+	if parallel < 1 {
+		parallel = 1
+	}
+	type result struct {
+		name  string
+		fatal interface{}
+	}
+	finished := make(map[string]bool)
+	started := make(map[string]bool)
+	done := make(chan result)
+	running := 0
+	var fatal interface{}
+	for len(finished) < len(p.names) {
+		for _, name := range p.names {
+			if running >= parallel || fatal != nil {
+				break
+			}
+			if started[name] || !p.ready(name, finished) {
+				continue
+			}
+			started[name] = true
+			running++
+			go func(name string) {
+				res := result{name: name}
+				defer func() {
+					res.fatal = recover()
+					done <- res
+				}()
+				compute(name)
+			}(name)
+		}
+		if running == 0 {
+			break
+		}
+		res := <-done
+		finished[res.name] = true
+		running--
+		if res.fatal != nil && fatal == nil {
+			fatal = res.fatal
+		}
+	}
+	if fatal != nil {
+		panic(fatal)
+	}
+}
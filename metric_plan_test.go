@@ -0,0 +1,154 @@
+package devstats
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestNewMetricPlan(t *testing.T) {
+	deps := map[string][]string{
+		"tags":       nil,
+		"temp_prs":   nil,
+		"prs_merged": {"temp_prs"},
+		"prs_ratio":  {"prs_merged", "temp_prs", "prs_merged"},
+		"top":        {"tags"},
+	}
+	all := []string{"prs_ratio", "tags", "temp_prs", "prs_merged", "top"}
+
+	// Test cases
+	var testCases = []struct {
+		names    []string
+		metric   string
+		expected []string
+		order    []string
+	}{
+		{names: all, metric: "prs_ratio", expected: []string{"prs_merged", "temp_prs"}, order: []string{"tags", "temp_prs", "prs_merged", "prs_ratio", "top"}},
+		{names: all, metric: "tags", expected: []string{}, order: []string{"tags", "temp_prs", "prs_merged", "prs_ratio", "top"}},
+		// Dependencies not selected are not waited for
+		{names: []string{"prs_ratio", "top"}, metric: "prs_ratio", expected: []string{}, order: []string{"prs_ratio", "top"}},
+		{names: []string{"top", "tags"}, metric: "top", expected: []string{"tags"}, order: []string{"tags", "top"}},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		plan, err := lib.NewMetricPlan(deps, test.names)
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		got := plan.Deps(test.metric)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+		order := plan.Order()
+		if !reflect.DeepEqual(order, test.order) {
+			t.Errorf("test number %d, expected order %v, got %v", index+1, test.order, order)
+		}
+	}
+}
+
+func TestNewMetricPlanErrors(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		deps     map[string][]string
+		expected string
+	}{
+		{deps: map[string][]string{"a": {"x"}}, expected: "depends on unknown metric 'x'"},
+		{deps: map[string][]string{"a": {"a"}}, expected: "depends on itself"},
+		{deps: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}}, expected: "dependency cycle"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		_, err := lib.NewMetricPlan(test.deps, []string{"a", "b", "c"}[:len(test.deps)])
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("test number %d, expected error containing '%s', got %v", index+1, test.expected, err)
+		}
+	}
+}
+
+func TestMetricPlanRun(t *testing.T) {
+	deps := map[string][]string{
+		"a":  nil,
+		"b":  nil,
+		"c":  nil,
+		"ab": {"a", "b"},
+		"d":  {"ab"},
+	}
+	names := []string{"d", "ab", "a", "b", "c"}
+	plan, err := lib.NewMetricPlan(deps, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Track metrics running at once and finished ones
+	var (
+		mtx      sync.Mutex
+		running  = make(map[string]bool)
+		finished = make(map[string]bool)
+		maxRun   int
+		errs     []string
+	)
+	plan.Run(3, func(name string) {
+		mtx.Lock()
+		for _, dep := range deps[name] {
+			if !finished[dep] {
+				errs = append(errs, name+" started before "+dep)
+			}
+		}
+		running[name] = true
+		if len(running) > maxRun {
+			maxRun = len(running)
+		}
+		mtx.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mtx.Lock()
+		delete(running, name)
+		finished[name] = true
+		mtx.Unlock()
+	})
+	if len(errs) > 0 {
+		t.Errorf("dependencies not finished: %v", errs)
+	}
+	if maxRun != 3 {
+		t.Errorf("expected 3 metrics running at once, got %d", maxRun)
+	}
+	if len(finished) != len(names) {
+		t.Errorf("expected all metrics computed, got %v", finished)
+	}
+}
+
+func TestMetricPlanRunPanic(t *testing.T) {
+	deps := map[string][]string{"a": nil, "b": nil, "c": {"a"}}
+	plan, err := lib.NewMetricPlan(deps, []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Panic of a metric is raised in the calling goroutine, after other running metrics finish, dependent metrics don't start
+	var (
+		mtx      sync.Mutex
+		finished = make(map[string]bool)
+	)
+	defer func() {
+		if r := recover(); r != "metric a failed" {
+			t.Errorf("expected panic 'metric a failed', got %v", r)
+		}
+		if !finished["b"] || finished["c"] {
+			t.Errorf("expected only b finished, got %v", finished)
+		}
+	}()
+	plan.Run(2, func(name string) {
+		if name == "a" {
+			panic("metric a failed")
+		}
+		time.Sleep(20 * time.Millisecond)
+		mtx.Lock()
+		finished[name] = true
+		mtx.Unlock()
+	})
+	t.Errorf("expected Run to panic")
+}
@@ -0,0 +1,16 @@
+select
+  concat('company_contributions,', affs.company_name),
+  round(sum({{event_weight(ev.id, ev.type)}}) / {{n}}, 2) as contributions
+from
+  gha_events ev
+  {{affiliations_join(affs, ev.actor_id, ev.created_at)}}
+where
+  {{period_range(ev.created_at)}}
+  and ev.type in ('PushEvent', 'PullRequestEvent')
+  and (ev.dup_actor_login {{exclude_bots}})
+group by
+  affs.company_name
+order by
+  contributions desc,
+  affs.company_name asc
+;
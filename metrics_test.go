@@ -300,7 +300,6 @@ func executeMetricTestCase(testMetric *metricTestCase, tests *metricTests, ctx *
 
 	// Connect to Postgres DB
 	c := lib.PgConn(ctx)
-	defer func() { lib.FatalOnError(c.Close()) }()
 
 	// Create DB structure
 	lib.Structure(ctx)
@@ -0,0 +1,422 @@
+package devstats
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Migration - numbered change of project database schema, `Up` applies it and `Down` reverts it
+// Statements of one migration run in one transaction. Databases created by `structure` before it was added can
+// already have the change (they were upgraded by re-running `structure`), so `Up` must work on them too
+type Migration struct {
+	Version int
+	Name    string
+	Up      []string
+	Down    []string
+}
+
+// Migrations - all schema migrations, in order of their versions (1, 2, ...), new ones are only appended
+// `structure` creates the latest schema, so it must create everything added here too
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "metric runs",
+		Up: []string{
+			"create table if not exists gha_metric_runs(sql varchar(200) not null, series varchar(200) not null, " +
+				"period varchar(20) not null, hist boolean not null, rows bigint not null, took_ms bigint not null, " +
+				"dt timestamp not null, primary key(sql, series, period, dt))",
+			"create index if not exists metric_runs_dt_idx on gha_metric_runs(dt)",
+		},
+		Down: []string{"drop table if exists gha_metric_runs"},
+	},
+	{
+		Version: 2,
+		Name:    "metric cache",
+		Up: []string{
+			"create table if not exists gha_metric_cache(hash varchar(40) not null, period varchar(20) not null, " +
+				"dt_from timestamp not null, dt_to timestamp not null, result text not null, dt timestamp not null, " +
+				"expires_at timestamp, primary key(hash, period, dt_from, dt_to))",
+			"create index if not exists metric_cache_dt_to_idx on gha_metric_cache(dt_to)",
+			"create index if not exists metric_cache_dt_idx on gha_metric_cache(dt)",
+		},
+		Down: []string{"drop table if exists gha_metric_cache"},
+	},
+	{
+		Version: 3,
+		Name:    "slow queries",
+		Up: []string{
+			"create table if not exists gha_slow_queries(tool varchar(40) not null, name varchar(200) not null, " +
+				"hash varchar(40) not null, query text not null, took_ms bigint not null, explain text not null, " +
+				"dt timestamp not null, primary key(tool, name, dt))",
+			"create index if not exists slow_queries_dt_idx on gha_slow_queries(dt)",
+		},
+		Down: []string{"drop table if exists gha_slow_queries"},
+	},
+	{
+		Version: 4,
+		Name:    "metric violations",
+		Up: []string{
+			"create table if not exists gha_metric_violations(sql varchar(200) not null, series varchar(200) not null, " +
+				"period varchar(20) not null, point_dt timestamp not null, field varchar(200) not null, rule varchar(40) not null, " +
+				"value double precision not null, prev double precision, blocked boolean not null, dt timestamp not null, " +
+				"primary key(sql, series, period, point_dt, field, rule, dt))",
+			"create index if not exists metric_violations_dt_idx on gha_metric_violations(dt)",
+		},
+		Down: []string{"drop table if exists gha_metric_violations"},
+	},
+	{
+		Version: 5,
+		Name:    "metric windows and progress",
+		Up: []string{
+			"create table if not exists gha_metric_windows(metric varchar(200) not null, series varchar(200) not null, " +
+				"period varchar(20) not null, dt_from timestamp not null, dt_to timestamp not null, " +
+				"backfilled_at timestamp not null, dt timestamp not null, primary key(metric, series, period))",
+			"create table if not exists gha_metrics_progress(metric varchar(200) not null, run_key varchar(40) not null, " +
+				"window_from timestamp not null, dt timestamp not null, primary key(metric))",
+		},
+		Down: []string{"drop table if exists gha_metrics_progress", "drop table if exists gha_metric_windows"},
+	},
+	{
+		// Already ingested events are not flagged, util_sql/events_is_bot.sql flags them by the default bots list
+		Version: 6,
+		Name:    "events bot flag",
+		Up: []string{
+			"alter table gha_events add column if not exists is_bot boolean not null default false",
+			"create index if not exists events_is_bot_idx on gha_events(is_bot)",
+		},
+		Down: []string{"drop index if exists events_is_bot_idx", "alter table gha_events drop column if exists is_bot"},
+	},
+	{
+		Version: 7,
+		Name:    "events and pull requests origin",
+		Up: []string{
+			"alter table gha_events add column if not exists origin varchar(16) not null default 'github'",
+			"alter table gha_pull_requests add column if not exists origin varchar(16) not null default 'github'",
+			"create index if not exists events_origin_idx on gha_events(origin)",
+			"create index if not exists pull_requests_origin_idx on gha_pull_requests(origin)",
+		},
+		Down: []string{
+			"drop index if exists pull_requests_origin_idx",
+			"drop index if exists events_origin_idx",
+			"alter table gha_pull_requests drop column if exists origin",
+			"alter table gha_events drop column if exists origin",
+		},
+	},
+	{
+		Version: 8,
+		Name:    "payloads overflow and raw JSON",
+		Up: []string{
+			"alter table gha_payloads add column if not exists overflow jsonb",
+			"alter table gha_payloads add column if not exists raw jsonb",
+		},
+		Down: []string{"alter table gha_payloads drop column if exists raw", "alter table gha_payloads drop column if exists overflow"},
+	},
+	{
+		// Tables created before hours had timings (scripts/git_files/tables_checkpoints.sh) get their columns
+		Version: 9,
+		Name:    "checkpoints",
+		Up: []string{
+			"create table if not exists gha_checkpoints(dt timestamp not null, orgs_repos text not null, jsons int not null, " +
+				"found int not null, events int not null, started timestamp not null, finished timestamp, rows int, " +
+				"download_ms int, parse_ms int, save_ms int, primary key(dt, orgs_repos))",
+			"alter table gha_checkpoints add column if not exists rows int",
+			"alter table gha_checkpoints add column if not exists download_ms int",
+			"alter table gha_checkpoints add column if not exists parse_ms int",
+			"alter table gha_checkpoints add column if not exists save_ms int",
+		},
+		Down: []string{"drop table if exists gha_checkpoints"},
+	},
+	{
+		Version: 10,
+		Name:    "parse errors",
+		Up: []string{
+			"create table if not exists gha_parse_errors(dt timestamp not null, line int not null, json text not null, " +
+				"error text not null, created_at timestamp not null, primary key(dt, line))",
+		},
+		Down: []string{"drop table if exists gha_parse_errors"},
+	},
+	{
+		Version: 11,
+		Name:    "commits stats",
+		Up: []string{
+			"create table if not exists gha_commits_stats(sha varchar(40) not null, added bigint not null, removed bigint not null, " +
+				"files int not null, dt timestamp not null, primary key(sha))",
+			"create index if not exists commits_stats_dt_idx on gha_commits_stats(dt)",
+		},
+		Down: []string{"drop table if exists gha_commits_stats"},
+	},
+	{
+		Version: 12,
+		Name:    "files churn",
+		Up: []string{
+			"create table if not exists gha_files_churn(repo_name varchar(160) not null, path text not null, commits int not null, " +
+				"added bigint not null, removed bigint not null, last_author varchar(160) not null, last_email varchar(160) not null, " +
+				"last_dt timestamp not null, primary key(repo_name, path))",
+			"create table if not exists gha_files_churn_repos(repo_name varchar(160) not null, sha varchar(40) not null, " +
+				"dt timestamp not null, primary key(repo_name))",
+			"create index if not exists files_churn_path_idx on gha_files_churn(path)",
+			"create index if not exists files_churn_commits_idx on gha_files_churn(commits)",
+			"create index if not exists files_churn_last_author_idx on gha_files_churn(last_author)",
+			"create index if not exists files_churn_last_dt_idx on gha_files_churn(last_dt)",
+		},
+		Down: []string{"drop table if exists gha_files_churn_repos", "drop table if exists gha_files_churn"},
+	},
+	{
+		Version: 13,
+		Name:    "watermarks",
+		Up: []string{
+			"create table if not exists gha_watermarks(tool varchar(40) not null, event_id bigint not null, " +
+				"dt timestamp not null, primary key(tool))",
+		},
+		Down: []string{"drop table if exists gha_watermarks"},
+	},
+	{
+		Version: 14,
+		Name:    "repos renames, default branches, languages, licenses and owners",
+		Up: []string{
+			"create table if not exists gha_repos_renames(name varchar(160) not null, new_name varchar(160) not null, " +
+				"dt timestamp not null, primary key(name))",
+			"create index if not exists repos_renames_new_name_idx on gha_repos_renames(new_name)",
+			"create index if not exists repos_renames_dt_idx on gha_repos_renames(dt)",
+			"create table if not exists gha_repos_default_branches(repo_name varchar(160) not null, branch varchar(200) not null, " +
+				"dt timestamp not null, primary key(repo_name))",
+			"create index if not exists repos_default_branches_branch_idx on gha_repos_default_branches(branch)",
+			"create table if not exists gha_repos_languages(repo_name varchar(160) not null, language varchar(80) not null, " +
+				"bytes bigint not null, dt timestamp not null, primary key(repo_name, language, dt))",
+			"create index if not exists repos_languages_repo_name_idx on gha_repos_languages(repo_name)",
+			"create index if not exists repos_languages_language_idx on gha_repos_languages(language)",
+			"create index if not exists repos_languages_dt_idx on gha_repos_languages(dt)",
+			"create table if not exists gha_repos_licenses(repo_name varchar(160) not null, path text not null, " +
+				"license varchar(40) not null, dt timestamp not null, primary key(repo_name, path))",
+			"create index if not exists repos_licenses_license_idx on gha_repos_licenses(license)",
+			"create index if not exists repos_licenses_dt_idx on gha_repos_licenses(dt)",
+			"create table if not exists gha_repos_owners(repo_name varchar(160) not null, path text not null, pattern text not null, " +
+				"owner varchar(160) not null, role varchar(20) not null, dt timestamp not null, " +
+				"primary key(repo_name, path, pattern, owner, role))",
+			"create index if not exists repos_owners_owner_idx on gha_repos_owners(owner)",
+			"create index if not exists repos_owners_role_idx on gha_repos_owners(role)",
+			"create index if not exists repos_owners_dt_idx on gha_repos_owners(dt)",
+		},
+		Down: []string{
+			"drop table if exists gha_repos_owners",
+			"drop table if exists gha_repos_licenses",
+			"drop table if exists gha_repos_languages",
+			"drop table if exists gha_repos_default_branches",
+			"drop table if exists gha_repos_renames",
+		},
+	},
+	{
+		Version: 15,
+		Name:    "function packs",
+		Up: []string{
+			"create table if not exists gha_functions(name varchar(200) not null, version int not null, " +
+				"hash varchar(40) not null, dt timestamp not null, primary key(name))",
+		},
+		Down: []string{"drop table if exists gha_functions"},
+	},
+	{
+		// Only `devstats` database uses them, but `structure` creates them in every database
+		Version: 16,
+		Name:    "sync status and projects control",
+		Up: []string{
+			"create table if not exists gha_sync_status(project varchar(32) not null, host varchar(255) not null, " +
+				"last_start timestamp not null, last_success timestamp, last_error_dt timestamp, last_error text, " +
+				"took_ms bigint, events bigint, timeouts text, primary key(project))",
+			"alter table gha_sync_status add column if not exists timeouts text",
+			"create table if not exists gha_projects_control(project varchar(32) not null, state varchar(10) not null, " +
+				"reason text not null, dt timestamp not null, primary key(project))",
+		},
+		Down: []string{"drop table if exists gha_projects_control", "drop table if exists gha_sync_status"},
+	},
+	{
+		// Progress saved without window end cannot be resumed safely, it is discarded and the next sync computes all metrics
+		Version: 17,
+		Name:    "metrics progress window end",
+		Up: []string{
+			"alter table gha_metrics_progress add column if not exists window_to timestamp",
+			"delete from gha_metrics_progress where window_to is null",
+			"alter table gha_metrics_progress alter column window_to set not null",
+		},
+		Down: []string{"alter table gha_metrics_progress drop column if exists window_to"},
+	},
+}
+
+// LatestSchemaVersion returns version of the last migration, schema created by `structure` has it
+func LatestSchemaVersion() int {
+	return Migrations[len(Migrations)-1].Version
+}
+
+// MigrationSteps returns migrations to run from `current` to `target` version: ones to apply (`up`) or to revert
+// (in reverse order), checks `migrations` are numbered 1, 2, ... and `target` is between 0 and the latest version
+func MigrationSteps(migrations []Migration, current, target int) (steps []Migration, up bool, err error) {
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, false, fmt.Errorf("migration '%s' has version %d, expected %d", migration.Name, migration.Version, i+1)
+		}
+	}
+	if target < 0 || target > len(migrations) {
+		return nil, false, fmt.Errorf("version %d doesn't exist, versions are 0 - %d", target, len(migrations))
+	}
+	if current < 0 || current > len(migrations) {
+		return nil, false, fmt.Errorf("database has version %d, unknown to this binary (latest is %d)", current, len(migrations))
+	}
+	if target >= current {
+		return migrations[current:target], true, nil
+	}
+	for i := current - 1; i >= target; i-- {
+		steps = append(steps, migrations[i])
+	}
+	return steps, false, nil
+}
+
+// schemaVersion returns schema version of database `con` (read in transaction `tx` when it is set), 0 when it has none
+// `gha_schema_version` holds every migration applied or reverted, database has the version of the last one
+func schemaVersion(con *sql.DB, tx *sql.Tx, ctx *Ctx) (int, error) {
+	var (
+		version   int
+		direction string
+	)
+	query := "select version, direction from gha_schema_version order by id desc limit 1"
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query).Scan(&version, &direction)
+	} else {
+		err = QueryRowSQL(con, ctx, query).Scan(&version, &direction)
+	}
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if direction == "down" {
+		version--
+	}
+	return version, nil
+}
+
+// createSchemaVersionTable creates `gha_schema_version` table unless it exists (databases created before migrations)
+func createSchemaVersionTable(con *sql.DB, ctx *Ctx) error {
+	_, err := ExecSQL(
+		con,
+		ctx,
+		"create table if not exists gha_schema_version(id serial, version int not null, name varchar(200) not null, "+
+			"direction varchar(4) not null, host varchar(255) not null, took_ms bigint not null, dt timestamp not null, primary key(id))",
+	)
+	return err
+}
+
+// SchemaVersion returns schema version of `ctx.PgDB` database, 0 when it was never migrated
+func SchemaVersion(con *sql.DB, ctx *Ctx) (int, error) {
+	var exists bool
+	if err := QueryRowSQL(con, ctx, "select to_regclass('gha_schema_version') is not null").Scan(&exists); err != nil || !exists {
+		return 0, err
+	}
+	return schemaVersion(con, nil, ctx)
+}
+
+// StampSchemaVersion records that database has the latest schema (used by `structure` after it creates all tables)
+func StampSchemaVersion(con *sql.DB, ctx *Ctx) error {
+	if err := createSchemaVersionTable(con, ctx); err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	_, err := ExecSQL(
+		con,
+		ctx,
+		"insert into gha_schema_version(version, name, direction, host, took_ms, dt) "+NValues(6),
+		LatestSchemaVersion(),
+		"structure",
+		"up",
+		host,
+		0,
+		time.Now(),
+	)
+	return err
+}
+
+// migrationStep applies or reverts one migration in a transaction, together with its `gha_schema_version` entry
+// Database must still have version `from`, so migrations run by other process at the same time are not repeated
+func migrationStep(con *sql.DB, ctx *Ctx, migration Migration, up bool, from int) error {
+	dtStart := time.Now()
+	tx, err := con.Begin()
+	if err != nil {
+		return err
+	}
+	rollback := func(err error) error {
+		_ = tx.Rollback()
+		return fmt.Errorf("migration %d (%s): %v", migration.Version, migration.Name, err)
+	}
+	// Transactions changing version wait for each other here
+	if _, err = ExecSQLTx(tx, ctx, "lock table gha_schema_version in exclusive mode"); err != nil {
+		return rollback(err)
+	}
+	version, err := schemaVersion(con, tx, ctx)
+	if err != nil {
+		return rollback(err)
+	}
+	if version != from {
+		return rollback(fmt.Errorf("database has version %d, expected %d", version, from))
+	}
+	direction, statements := "up", migration.Up
+	if !up {
+		direction, statements = "down", migration.Down
+	}
+	for _, statement := range statements {
+		if _, err = ExecSQLTx(tx, ctx, statement); err != nil {
+			return rollback(err)
+		}
+	}
+	host, _ := os.Hostname()
+	_, err = ExecSQLTx(
+		tx,
+		ctx,
+		"insert into gha_schema_version(version, name, direction, host, took_ms, dt) "+NValues(6),
+		migration.Version,
+		migration.Name,
+		direction,
+		host,
+		int64(time.Now().Sub(dtStart)/time.Millisecond),
+		time.Now(),
+	)
+	if err != nil {
+		return rollback(err)
+	}
+	return tx.Commit()
+}
+
+// Migrate migrates `ctx.PgDB` database to schema `target` version, returns versions it had before and has now
+// Every migration is committed separately, so failed one leaves database at the version before it
+// GHA2DB_SKIPPDB only outputs migrations that would be run
+func Migrate(con *sql.DB, ctx *Ctx, target int) (int, int, error) {
+	if err := createSchemaVersionTable(con, ctx); err != nil {
+		return 0, 0, err
+	}
+	current, err := schemaVersion(con, nil, ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	steps, up, err := MigrationSteps(Migrations, current, target)
+	if err != nil {
+		return current, current, err
+	}
+	version := current
+	for _, migration := range steps {
+		if ctx.SkipPDB {
+			Printf("%s: would run migration %d (%s), up: %v\n", ctx.PgDB, migration.Version, migration.Name, up)
+			continue
+		}
+		if err = migrationStep(con, ctx, migration, up, version); err != nil {
+			return current, version, err
+		}
+		if up {
+			version = migration.Version
+		} else {
+			version = migration.Version - 1
+		}
+		Printf("%s: migration %d (%s) done, up: %v, version %d\n", ctx.PgDB, migration.Version, migration.Name, up, version)
+	}
+	return current, version, nil
+}
@@ -0,0 +1,59 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestMigrationSteps(t *testing.T) {
+	m1 := lib.Migration{Version: 1, Name: "one"}
+	m2 := lib.Migration{Version: 2, Name: "two"}
+	m3 := lib.Migration{Version: 3, Name: "three"}
+	migrations := []lib.Migration{m1, m2, m3}
+
+	// Test cases
+	var testCases = []struct {
+		migrations []lib.Migration
+		current    int
+		target     int
+		steps      []lib.Migration
+		up         bool
+		err        bool
+	}{
+		{migrations: migrations, current: 0, target: 3, steps: []lib.Migration{m1, m2, m3}, up: true},
+		{migrations: migrations, current: 1, target: 2, steps: []lib.Migration{m2}, up: true},
+		{migrations: migrations, current: 3, target: 3, steps: []lib.Migration{}, up: true},
+		{migrations: migrations, current: 3, target: 1, steps: []lib.Migration{m3, m2}},
+		{migrations: migrations, current: 2, target: 0, steps: []lib.Migration{m2, m1}},
+		// Unknown versions
+		{migrations: migrations, current: 0, target: 4, err: true},
+		{migrations: migrations, current: 4, target: 3, err: true},
+		{migrations: migrations, current: 1, target: -1, err: true},
+		// Wrong numbering
+		{migrations: []lib.Migration{m1, m3}, current: 0, target: 2, err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		steps, up, err := lib.MigrationSteps(test.migrations, test.current, test.target)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got %+v", index+1, steps)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if !reflect.DeepEqual(steps, test.steps) || up != test.up {
+			t.Errorf("test number %d, expected %+v (up: %v), got %+v (up: %v)", index+1, test.steps, test.up, steps, up)
+		}
+	}
+
+	// Migrations of this binary must be numbered right, so every database can be migrated to the latest version
+	if _, _, err := lib.MigrationSteps(lib.Migrations, 0, lib.LatestSchemaVersion()); err != nil {
+		t.Errorf("migrations are not valid: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package devstats
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArchiveMirrors - GHA2DB_ARCHIVE_URL and its mirrors (GHA2DB_ARCHIVE_MIRRORS), GHA files are downloaded from the first one that works
+// Health of every URL is tracked for the whole run: URL that failed GHA2DB_MIRROR_FAILURES times in a row is marked down
+// and tried after all healthy ones, until it works again
+type ArchiveMirrors struct {
+	mtx         sync.Mutex
+	client      *http.Client
+	urls        []string
+	stats       []mirrorStats
+	maxFailures int
+}
+
+// mirrorStats - health of a single archive URL
+type mirrorStats struct {
+	downloads int
+	failures  int
+	inRow     int
+	down      bool
+}
+
+// NewArchiveMirrors creates mirrors list from GHA2DB_ARCHIVE_URL and GHA2DB_ARCHIVE_MIRRORS
+// Mirror is a GHA files URL template ({{date}} is replaced with YYYY-MM-DD-H) or a base URL (GHA file name is appended)
+func NewArchiveMirrors(ctx *Ctx) *ArchiveMirrors {
+	urls := []string{ctx.ArchiveURL}
+	for _, mirror := range ctx.ArchiveMirrors {
+		if !strings.Contains(mirror, "{{date}}") {
+			mirror = strings.TrimRight(mirror, "/") + "/" + path.Base(ctx.ArchiveURL)
+		}
+		urls = append(urls, mirror)
+	}
+	return &ArchiveMirrors{
+		client:      &http.Client{},
+		urls:        urls,
+		stats:       make([]mirrorStats, len(urls)),
+		maxFailures: ctx.MirrorFailures,
+	}
+}
+
+// order - returns indices of URLs to try: healthy ones first, then the ones marked down (both in configured order)
+func (m *ArchiveMirrors) order() []int {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	healthy, down := []int{}, []int{}
+	for i := range m.urls {
+		if m.stats[i].down {
+			down = append(down, i)
+		} else {
+			healthy = append(healthy, i)
+		}
+	}
+	return append(healthy, down...)
+}
+
+// result - records download result of URL `i`, marks URL down or up again
+func (m *ArchiveMirrors) result(i int, err error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	st := &m.stats[i]
+	if err == nil {
+		st.downloads++
+		st.inRow = 0
+		if st.down {
+			st.down = false
+			Printf("Archive URL %s works again\n", m.urls[i])
+		}
+		return
+	}
+	st.failures++
+	st.inRow++
+	if !st.down && st.inRow >= m.maxFailures {
+		st.down = true
+		Printf("Archive URL %s failed %d times in a row, it is tried last now\n", m.urls[i], st.inRow)
+	}
+}
+
+// Get downloads GHA file of hour `dt`, trying URLs in order until one of them works
+// Network errors and server errors (5xx) fail over to the next URL
+// Other responses (like 404 of the hour that is not published yet) are returned as they are, with their HTTP status
+// Returns URL data was downloaded from, error is only returned when all URLs failed
+func (m *ArchiveMirrors) Get(dt time.Time) ([]byte, string, int, error) {
+	var errs []string
+	for _, i := range m.order() {
+		url := strings.Replace(m.urls[i], "{{date}}", ToGHADate(dt), -1)
+		data, status, err := m.download(url)
+		m.result(i, err)
+		if err == nil {
+			return data, url, status, nil
+		}
+		Printf("%v: Error downloading %s: %v\n", dt, url, err)
+		errs = append(errs, url+": "+err.Error())
+	}
+	return nil, "", 0, fmt.Errorf("%v: all archive URLs failed: %s", dt, strings.Join(errs, ", "))
+}
+
+// download - returns response body and status, server errors are returned as errors
+func (m *ArchiveMirrors) download(url string) ([]byte, int, error) {
+	response, err := m.client.Get(url)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err := ioutil.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, 0, err
+	}
+	if response.StatusCode >= 500 {
+		return nil, 0, fmt.Errorf("HTTP %d", response.StatusCode)
+	}
+	return data, response.StatusCode, nil
+}
+
+// Summary outputs downloads and failures of every archive URL (only when mirrors are used)
+func (m *ArchiveMirrors) Summary() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if len(m.urls) < 2 {
+		return
+	}
+	for i, url := range m.urls {
+		st := m.stats[i]
+		state := "up"
+		if st.down {
+			state = "down"
+		}
+		Printf("Archive URL %s: %d downloads, %d failures, %s\n", url, st.downloads, st.failures, state)
+	}
+}
@@ -0,0 +1,109 @@
+package devstats
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestArchiveMirrors(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		requests []string
+		broken   = true
+	)
+	handler := func(name string, status func() int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mtx.Lock()
+			requests = append(requests, name+r.URL.Path)
+			mtx.Unlock()
+			w.WriteHeader(status())
+			_, _ = w.Write([]byte(name))
+		}
+	}
+	primary := httptest.NewServer(handler("primary", func() int {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if broken {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	}))
+	defer primary.Close()
+	mirror := httptest.NewServer(handler("mirror", func() int { return http.StatusOK }))
+	defer mirror.Close()
+	missing := httptest.NewServer(handler("missing", func() int { return http.StatusNotFound }))
+	defer missing.Close()
+
+	dt := time.Date(2017, 8, 1, 3, 0, 0, 0, time.UTC)
+	get := func(m *lib.ArchiveMirrors) (string, int) {
+		mtx.Lock()
+		requests = nil
+		mtx.Unlock()
+		data, _, status, err := m.Get(dt)
+		if err != nil {
+			return "error", 0
+		}
+		return string(data), status
+	}
+	tried := func() string {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return strings.Join(requests, ",")
+	}
+
+	// Mirror is a base URL, GHA file name is appended
+	ctx := lib.Ctx{
+		ArchiveURL:     primary.URL + "/{{date}}.json.gz",
+		ArchiveMirrors: []string{mirror.URL + "/gha/"},
+		MirrorFailures: 2,
+	}
+	m := lib.NewArchiveMirrors(&ctx)
+	if data, status := get(m); data != "mirror" || status != http.StatusOK {
+		t.Errorf("expected data from mirror, got %s %d", data, status)
+	}
+	if got := tried(); got != "primary/2017-08-01-3.json.gz,mirror/gha/2017-08-01-3.json.gz" {
+		t.Errorf("expected primary tried first, got %s", got)
+	}
+
+	// Second failure in a row marks primary down, it is tried last then
+	get(m)
+	get(m)
+	if got := tried(); got != "mirror/gha/2017-08-01-3.json.gz" {
+		t.Errorf("expected only mirror tried, got %s", got)
+	}
+
+	// Primary works again when all others fail
+	mtx.Lock()
+	broken = false
+	mtx.Unlock()
+	ctx.ArchiveMirrors = []string{missing.URL + "/{{date}}.json.zst"}
+	m = lib.NewArchiveMirrors(&ctx)
+	if data, status := get(m); data != "primary" || status != http.StatusOK {
+		t.Errorf("expected data from primary, got %s %d", data, status)
+	}
+
+	// Not found is not a failure, it is returned as it is
+	ctx.ArchiveURL = missing.URL + "/{{date}}.json.gz"
+	ctx.ArchiveMirrors = []string{mirror.URL}
+	m = lib.NewArchiveMirrors(&ctx)
+	if data, status := get(m); data != "missing" || status != http.StatusNotFound {
+		t.Errorf("expected not found from primary, got %s %d", data, status)
+	}
+
+	// All URLs fail
+	mtx.Lock()
+	broken = true
+	mtx.Unlock()
+	ctx.ArchiveURL = primary.URL + "/{{date}}.json.gz"
+	ctx.ArchiveMirrors = nil
+	m = lib.NewArchiveMirrors(&ctx)
+	if data, _ := get(m); data != "error" {
+		t.Errorf("expected error, got %s", data)
+	}
+}
@@ -0,0 +1,162 @@
+package devstats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Notification - result of a tool run, sent to Slack (GHA2DB_NOTIFY_SLACK), e-mail (GHA2DB_NOTIFY_EMAIL)
+// and HTTP endpoint (GHA2DB_NOTIFY_WEBHOOK, as JSON)
+type Notification struct {
+	Tool    string    `json:"tool"`
+	Project string    `json:"project,omitempty"`
+	Host    string    `json:"host"`
+	Success bool      `json:"success"`
+	Summary string    `json:"summary"`
+	Errors  []string  `json:"errors,omitempty"`
+	Started time.Time `json:"started"`
+	Took    string    `json:"took"`
+}
+
+// notifyEnv - environment variables of notification channels
+var notifyEnv = []string{"GHA2DB_NOTIFY_SLACK", "GHA2DB_NOTIFY_WEBHOOK", "GHA2DB_NOTIFY_EMAIL"}
+
+// NewNotification creates notification of `tool` run started at `dtStart`
+// Errors can contain commands output, they are truncated
+func NewNotification(ctx *Ctx, tool string, dtStart time.Time, success bool, summary string, errs []string) *Notification {
+	host, _ := os.Hostname()
+	for i := range errs {
+		errs[i] = TruncToBytes(errs[i], 0x400)
+	}
+	return &Notification{
+		Tool:    tool,
+		Project: ctx.Project,
+		Host:    host,
+		Success: success,
+		Summary: summary,
+		Errors:  errs,
+		Started: dtStart,
+		Took:    time.Now().Sub(dtStart).String(),
+	}
+}
+
+// NotifyEnvOff turns notifications off in environment `env` of a child tool, its caller notifies about its result
+func NotifyEnvOff(env map[string]string) map[string]string {
+	for _, key := range notifyEnv {
+		env[key] = ""
+	}
+	return env
+}
+
+// Text returns notification as a human readable text, the first line is a title
+func (n *Notification) Text() string {
+	result := "succeeded"
+	if !n.Success {
+		result = "failed"
+	}
+	name := n.Tool
+	if n.Project != "" {
+		name += " " + n.Project
+	}
+	text := fmt.Sprintf("devstats: %s %s on %s (started %s, took %s)\n", name, result, n.Host, ToYMDHMSDate(n.Started), n.Took)
+	if n.Summary != "" {
+		text += n.Summary + "\n"
+	}
+	for _, e := range n.Errors {
+		text += "- " + e + "\n"
+	}
+	return text
+}
+
+// Notify sends notification to all configured channels
+// Successful runs are only reported when GHA2DB_NOTIFY_SUCCESS is set
+// All channels are tried, returns errors of channels that failed
+func Notify(ctx *Ctx, n *Notification) error {
+	if n.Success && !ctx.NotifySuccess {
+		return nil
+	}
+	var errs []string
+	if ctx.NotifySlack != "" {
+		data, err := json.Marshal(map[string]string{"text": n.Text()})
+		if err == nil {
+			err = notifyPost(ctx.NotifySlack, data)
+		}
+		if err != nil {
+			errs = append(errs, "slack: "+err.Error())
+		}
+	}
+	if ctx.NotifyWebhook != "" {
+		data, err := json.Marshal(n)
+		if err == nil {
+			err = notifyPost(ctx.NotifyWebhook, data)
+		}
+		if err != nil {
+			errs = append(errs, "webhook: "+err.Error())
+		}
+	}
+	if len(ctx.NotifyEmails) > 0 {
+		err := notifyEmail(ctx, n)
+		if err != nil {
+			errs = append(errs, "email: "+err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("notification failed: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// notifyPost - posts JSON `data` to `url`
+func notifyPost(url string, data []byte) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	response, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	body, _ := ioutil.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("%s: HTTP %d: %s", url, response.StatusCode, TruncToBytes(string(body), 0x100))
+	}
+	return nil
+}
+
+// notifyEmail - sends notification via GHA2DB_SMTP_SERVER, with PLAIN authentication when GHA2DB_SMTP_USER is set
+func notifyEmail(ctx *Ctx, n *Notification) error {
+	text := n.Text()
+	i := strings.Index(text, "\n")
+	msg := "From: " + ctx.SMTPFrom + "\r\n" +
+		"To: " + strings.Join(ctx.NotifyEmails, ", ") + "\r\n" +
+		"Subject: " + text[:i] + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + strings.Replace(text, "\n", "\r\n", -1)
+	var auth smtp.Auth
+	if ctx.SMTPUser != "" {
+		host := ctx.SMTPServer
+		if j := strings.LastIndex(host, ":"); j >= 0 {
+			host = host[:j]
+		}
+		auth = smtp.PlainAuth("", ctx.SMTPUser, ctx.SMTPPassword, host)
+	}
+	return smtp.SendMail(ctx.SMTPServer, auth, ctx.SMTPFrom, ctx.NotifyEmails, []byte(msg))
+}
+
+// NotifyOnPanic must be deferred by tool's main, it reports a failed run (error passed to FatalOnError) and panics again
+func NotifyOnPanic(ctx *Ctx, tool string, dtStart time.Time) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	nerr := Notify(ctx, NewNotification(ctx, tool, dtStart, false, "Run failed", []string{fatalErrorOr(r)}))
+	if nerr != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", nerr)
+	}
+	panic(r)
+}
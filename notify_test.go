@@ -0,0 +1,101 @@
+package devstats
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestNotify(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		received = make(map[string][]byte)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mtx.Lock()
+		received[r.URL.Path] = body
+		mtx.Unlock()
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	got := func(path string) []byte {
+		mtx.Lock()
+		defer mtx.Unlock()
+		data := received[path]
+		delete(received, path)
+		return data
+	}
+
+	ctx := lib.Ctx{Project: "kubernetes", NotifySlack: server.URL + "/slack", NotifyWebhook: server.URL + "/hook"}
+	dtStart := time.Now().Add(-time.Minute)
+
+	// Successful runs are not reported by default
+	n := lib.NewNotification(&ctx, "gha2db_sync", dtStart, true, "Sync success", nil)
+	if err := lib.Notify(&ctx, n); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if data := got("/slack"); data != nil {
+		t.Errorf("expected no notification of success, got %s", data)
+	}
+
+	// Failure is posted to Slack as text and to webhook as JSON
+	n = lib.NewNotification(&ctx, "gha2db_sync", dtStart, false, "Run failed", []string{"pq: connection refused"})
+	if err := lib.Notify(&ctx, n); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	var slack map[string]string
+	if err := json.Unmarshal(got("/slack"), &slack); err != nil {
+		t.Errorf("expected Slack JSON: %v", err)
+	}
+	if !strings.HasPrefix(slack["text"], "devstats: gha2db_sync kubernetes failed on ") ||
+		!strings.Contains(slack["text"], "\nRun failed\n- pq: connection refused\n") {
+		t.Errorf("unexpected Slack text: %s", slack["text"])
+	}
+	var hook lib.Notification
+	if err := json.Unmarshal(got("/hook"), &hook); err != nil {
+		t.Errorf("expected webhook JSON: %v", err)
+	}
+	if hook.Tool != "gha2db_sync" || hook.Project != "kubernetes" || hook.Success || len(hook.Errors) != 1 {
+		t.Errorf("unexpected webhook notification: %+v", hook)
+	}
+
+	// Success is reported when requested, failing channel doesn't stop others
+	ctx.NotifySuccess = true
+	ctx.NotifySlack = server.URL + "/broken"
+	n = lib.NewNotification(&ctx, "devstats", dtStart, true, "Synced 3 projects, 0 failed", nil)
+	err := lib.Notify(&ctx, n)
+	if err == nil || !strings.Contains(err.Error(), "slack: ") || strings.Contains(err.Error(), "webhook: ") {
+		t.Errorf("expected Slack error only, got %v", err)
+	}
+	if data := got("/hook"); data == nil {
+		t.Errorf("expected webhook notification of success")
+	}
+}
+
+func TestNotifyEnvOff(t *testing.T) {
+	env := lib.NotifyEnvOff(map[string]string{"GHA2DB_PROJECT": "kubernetes"})
+	expected := map[string]string{
+		"GHA2DB_PROJECT":        "kubernetes",
+		"GHA2DB_NOTIFY_SLACK":   "",
+		"GHA2DB_NOTIFY_WEBHOOK": "",
+		"GHA2DB_NOTIFY_EMAIL":   "",
+	}
+	if len(env) != len(expected) {
+		t.Errorf("expected %v, got %v", expected, env)
+	}
+	for key, value := range expected {
+		if got, ok := env[key]; !ok || got != value {
+			t.Errorf("expected %s=%s, got %v", key, value, env)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package devstats
+
+import (
+	"fmt"
+	"time"
+)
+
+// GHANewFormatStart - GitHub Archive JSONs use new format starting from this date, older hours use pre 2015 format
+var GHANewFormatStart = time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// IsOldFormat returns true if GHA hour `dt` uses pre 2015 JSONs format (or it is forced by GHA2DB_OLDFMT)
+// GitLab, Gitea/Forgejo and Gerrit events are always converted to the new format
+func IsOldFormat(ctx *Ctx, dt time.Time) bool {
+	return ctx.OldFormat || (!ctx.GitLab && ctx.GiteaURL == "" && ctx.GerritURL == "" && dt.Before(GHANewFormatStart))
+}
+
+// EventOldInfo - pre 2015 event data that has no place in the new format structures
+// Pre 2015 events have no actor, org and repo IDs, they must be looked up by names when saving
+type EventOldInfo struct {
+	Repository ForkeeOld
+	HasPayload bool
+}
+
+// NormalizeEventOld maps pre 2015 event onto the new format Event structure
+// Event ID is a hash of type, actor, repo and creation date (old events have no IDs)
+// Commits (payload "shas" tuples) are converted to Commit structures
+func NormalizeEventOld(e *EventOld) (*Event, error) {
+	ev := &Event{
+		ID:        fmt.Sprintf("%v", HashStrings([]string{e.Type, e.Actor, e.Repository.Name, ToYMDHMSDate(e.CreatedAt)})),
+		Type:      e.Type,
+		Public:    e.Public,
+		CreatedAt: e.CreatedAt,
+		Actor:     Actor{Login: e.Actor},
+		Repo:      Repo{ID: e.Repository.ID, Name: e.Repository.Name},
+		Old:       &EventOldInfo{Repository: e.Repository},
+	}
+	if e.Repository.Organization != nil {
+		ev.Org = &Org{Login: *e.Repository.Organization}
+	}
+	pl := e.Payload
+	if pl == nil {
+		return ev, nil
+	}
+	ev.Old.HasPayload = true
+	ev.Payload = Payload{
+		Size:         pl.Size,
+		Ref:          pl.Ref,
+		Head:         pl.Head,
+		Action:       pl.Action,
+		RefType:      pl.RefType,
+		MasterBranch: pl.MasterBranch,
+		Description:  pl.Description,
+		Number:       pl.Number,
+		Forkee:       pl.Repository,
+		Release:      pl.Release,
+		Member:       pl.Member,
+		Comment:      pl.Comment,
+		Pages:        pl.Pages,
+		PullRequest:  pl.PullRequest,
+		IssueID:      pl.Issue,
+		CommentID:    pl.CommentID,
+		Commit:       pl.Commit,
+		Team:         pl.Team,
+	}
+	if ev.Payload.IssueID == nil {
+		ev.Payload.IssueID = pl.IssueID
+	}
+	if pl.SHAs != nil {
+		commits := []Commit{}
+		for _, sha := range *pl.SHAs {
+			commit, err := commitFromSHA(sha)
+			if err != nil {
+				return nil, err
+			}
+			commits = append(commits, commit)
+		}
+		ev.Payload.Commits = &commits
+	}
+	return ev, nil
+}
+
+// commitFromSHA - converts pre 2015 ["sha", "author email", "message", "author name", distinct] tuple
+func commitFromSHA(sha interface{}) (commit Commit, err error) {
+	tuple, ok := sha.([]interface{})
+	if !ok || len(tuple) < 5 {
+		err = fmt.Errorf("sha is not a 5 elements array: %+v", sha)
+		return
+	}
+	strs := []string{}
+	for i := 0; i < 4; i++ {
+		str, ok := tuple[i].(string)
+		if !ok {
+			err = fmt.Errorf("sha[%d] is not string: %+v", i, tuple[i])
+			return
+		}
+		strs = append(strs, str)
+	}
+	distinct, ok := tuple[4].(bool)
+	if !ok {
+		err = fmt.Errorf("sha[4] is not bool: %+v", tuple[4])
+		return
+	}
+	commit = Commit{
+		SHA:      strs[0],
+		Author:   Author{Email: strs[1], Name: strs[3]},
+		Message:  strs[2],
+		Distinct: distinct,
+	}
+	return
+}
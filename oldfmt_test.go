@@ -0,0 +1,129 @@
+package devstats
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestIsOldFormat(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		forced   bool
+		gitLab   bool
+		gitea    string
+		gerrit   string
+		dt       time.Time
+		expected bool
+	}{
+		{forced: false, dt: time.Date(2014, 12, 31, 23, 0, 0, 0, time.UTC), expected: true},
+		{forced: false, dt: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC), expected: false},
+		{forced: false, dt: time.Date(2017, 8, 1, 12, 0, 0, 0, time.UTC), expected: false},
+		{forced: true, dt: time.Date(2017, 8, 1, 12, 0, 0, 0, time.UTC), expected: true},
+		{forced: false, dt: time.Date(2012, 3, 1, 0, 0, 0, 0, time.UTC), expected: true},
+		{forced: false, gitLab: true, dt: time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC), expected: false},
+		{forced: false, gitea: "https://codeberg.org", dt: time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC), expected: false},
+		{forced: false, gerrit: "https://review.opendev.org", dt: time.Date(2013, 3, 1, 0, 0, 0, 0, time.UTC), expected: false},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		ctx := lib.Ctx{OldFormat: test.forced, GitLab: test.gitLab, GiteaURL: test.gitea, GerritURL: test.gerrit}
+		got := lib.IsOldFormat(&ctx, test.dt)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v, dt: %v", index+1, test.expected, got, test.dt)
+		}
+	}
+}
+
+func TestNormalizeEventOld(t *testing.T) {
+	jsonStr := `{
+		"type": "PushEvent",
+		"public": true,
+		"created_at": "2014-06-01T10:00:00Z",
+		"actor": "lukaszgryglicki",
+		"repository": {"id": 123, "name": "kubernetes", "organization": "GoogleCloudPlatform", "owner": "GoogleCloudPlatform"},
+		"payload": {
+			"size": 2,
+			"ref": "refs/heads/master",
+			"head": "abc",
+			"issue_id": 7,
+			"shas": [
+				["abc", "a@b.c", "Fix build", "Author A", true],
+				["def", "d@e.f", "Add test", "Author D", false]
+			]
+		}
+	}`
+	var evOld lib.EventOld
+	err := json.Unmarshal([]byte(jsonStr), &evOld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev, err := lib.NormalizeEventOld(&evOld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Actor.Login != "lukaszgryglicki" || ev.Repo.ID != 123 || ev.Repo.Name != "kubernetes" {
+		t.Errorf("unexpected actor/repo: %+v, %+v", ev.Actor, ev.Repo)
+	}
+	if ev.Org == nil || ev.Org.Login != "GoogleCloudPlatform" {
+		t.Errorf("expected org GoogleCloudPlatform, got %+v", ev.Org)
+	}
+	if ev.Old == nil || !ev.Old.HasPayload || ev.Old.Repository.ID != 123 {
+		t.Errorf("unexpected old event info: %+v", ev.Old)
+	}
+	if ev.ID == "" {
+		t.Errorf("expected event ID to be generated")
+	}
+	again, err := lib.NormalizeEventOld(&evOld)
+	if err != nil || again.ID != ev.ID {
+		t.Errorf("expected the same event ID for the same event, got %v and %v (error: %v)", ev.ID, again.ID, err)
+	}
+	pl := ev.Payload
+	if pl.Size == nil || *pl.Size != 2 || pl.IssueID == nil || *pl.IssueID != 7 {
+		t.Errorf("unexpected payload: %+v", pl)
+	}
+	if pl.Commits == nil || len(*pl.Commits) != 2 {
+		t.Fatalf("expected 2 commits, got %+v", pl.Commits)
+	}
+	commit := (*pl.Commits)[1]
+	if commit.SHA != "def" || commit.Author.Email != "d@e.f" || commit.Author.Name != "Author D" ||
+		commit.Message != "Add test" || commit.Distinct {
+		t.Errorf("unexpected commit: %+v", commit)
+	}
+}
+
+func TestNormalizeEventOldNoPayload(t *testing.T) {
+	evOld := lib.EventOld{Type: "WatchEvent", Actor: "a", Repository: lib.ForkeeOld{ID: 1, Name: "r"}}
+	ev, err := lib.NormalizeEventOld(&evOld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Old.HasPayload || ev.Org != nil {
+		t.Errorf("expected no payload and no org, got %+v, %+v", ev.Old, ev.Org)
+	}
+}
+
+func TestNormalizeEventOldInvalidSHAs(t *testing.T) {
+	// Test cases
+	var testCases = []string{
+		`["abc", "a@b.c", "msg", "name"]`,
+		`"abc"`,
+		`["abc", 1, "msg", "name", true]`,
+		`["abc", "a@b.c", "msg", "name", "true"]`,
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		var sha interface{}
+		err := json.Unmarshal([]byte(test), &sha)
+		if err != nil {
+			t.Fatal(err)
+		}
+		evOld := lib.EventOld{Payload: &lib.PayloadOld{SHAs: &[]interface{}{sha}}}
+		_, err = lib.NormalizeEventOld(&evOld)
+		if err == nil {
+			t.Errorf("test number %d, expected error for sha: %s", index+1, test)
+		}
+	}
+}
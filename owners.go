@@ -0,0 +1,95 @@
+package devstats
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// OwnersEntry - single path pattern -> owner mapping from CODEOWNERS or OWNERS file
+// Role is "owner" for CODEOWNERS and "approver" or "reviewer" for Kubernetes OWNERS files
+type OwnersEntry struct {
+	Pattern string
+	Owner   string
+	Role    string
+}
+
+// ownersFile - Kubernetes OWNERS file, filters map regexp of file names to approvers/reviewers
+type ownersFile struct {
+	Approvers []string `yaml:"approvers"`
+	Reviewers []string `yaml:"reviewers"`
+	Filters   map[string]struct {
+		Approvers []string `yaml:"approvers"`
+		Reviewers []string `yaml:"reviewers"`
+	} `yaml:"filters"`
+}
+
+// IsCodeownersFile returns true for CODEOWNERS locations supported by GitHub
+func IsCodeownersFile(filePath string) bool {
+	return filePath == "CODEOWNERS" || filePath == ".github/CODEOWNERS" || filePath == "docs/CODEOWNERS"
+}
+
+// IsOwnersFile returns true for (non vendored) Kubernetes OWNERS files
+func IsOwnersFile(filePath string) bool {
+	return path.Base(filePath) == "OWNERS" && !vendoredRe.MatchString(filePath)
+}
+
+// normalizeOwner - strips "@" from GitHub logins and teams, so they can be joined with actors
+func normalizeOwner(owner string) string {
+	return strings.TrimPrefix(strings.TrimSpace(owner), "@")
+}
+
+// ParseCodeowners parses CODEOWNERS file: "pattern owner1 owner2 ..." lines
+func ParseCodeowners(text string) (entries []OwnersEntry) {
+	for _, line := range strings.Split(text, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		for _, owner := range fields[1:] {
+			entries = append(entries, OwnersEntry{Pattern: fields[0], Owner: normalizeOwner(owner), Role: "owner"})
+		}
+	}
+	return
+}
+
+// ParseOwners parses Kubernetes OWNERS file located at `filePath`
+// Top level approvers/reviewers own the whole directory: "dir/**"
+// Filters are stored as "dir/<file name regexp>"
+func ParseOwners(filePath, text string) ([]OwnersEntry, error) {
+	var owners ownersFile
+	err := yaml.Unmarshal([]byte(text), &owners)
+	if err != nil {
+		return nil, err
+	}
+	dir := path.Dir(filePath)
+	prefix := ""
+	if dir != "." {
+		prefix = dir + "/"
+	}
+	entries := []OwnersEntry{}
+	add := func(pattern string, approvers, reviewers []string) {
+		for _, owner := range approvers {
+			entries = append(entries, OwnersEntry{Pattern: pattern, Owner: normalizeOwner(owner), Role: "approver"})
+		}
+		for _, owner := range reviewers {
+			entries = append(entries, OwnersEntry{Pattern: pattern, Owner: normalizeOwner(owner), Role: "reviewer"})
+		}
+	}
+	add(prefix+"**", owners.Approvers, owners.Reviewers)
+	filters := []string{}
+	for filter := range owners.Filters {
+		filters = append(filters, filter)
+	}
+	sort.Strings(filters)
+	for _, filter := range filters {
+		data := owners.Filters[filter]
+		add(prefix+filter, data.Approvers, data.Reviewers)
+	}
+	return entries, nil
+}
@@ -0,0 +1,108 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestIsOwnersFiles(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		path       string
+		codeowners bool
+		owners     bool
+	}{
+		{path: "CODEOWNERS", codeowners: true, owners: false},
+		{path: ".github/CODEOWNERS", codeowners: true, owners: false},
+		{path: "docs/CODEOWNERS", codeowners: true, owners: false},
+		{path: "pkg/CODEOWNERS", codeowners: false, owners: false},
+		{path: "OWNERS", codeowners: false, owners: true},
+		{path: "pkg/kubelet/OWNERS", codeowners: false, owners: true},
+		{path: "vendor/k8s.io/api/OWNERS", codeowners: false, owners: false},
+		{path: "OWNERS_ALIASES", codeowners: false, owners: false},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		codeowners := lib.IsCodeownersFile(test.path)
+		owners := lib.IsOwnersFile(test.path)
+		if codeowners != test.codeowners || owners != test.owners {
+			t.Errorf("test number %d, expected %v/%v, got %v/%v, test case: %+v", index+1, test.codeowners, test.owners, codeowners, owners, test)
+		}
+	}
+}
+
+func TestParseCodeowners(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		text     string
+		expected []lib.OwnersEntry
+	}{
+		{text: "", expected: nil},
+		{text: "# comment only\n\n", expected: nil},
+		{text: "*.go\n", expected: nil},
+		{
+			text: "# Default owners\n* @lukaszgryglicki @cncf/devstats\n/docs/ docs@cncf.io # docs team\n",
+			expected: []lib.OwnersEntry{
+				{Pattern: "*", Owner: "lukaszgryglicki", Role: "owner"},
+				{Pattern: "*", Owner: "cncf/devstats", Role: "owner"},
+				{Pattern: "/docs/", Owner: "docs@cncf.io", Role: "owner"},
+			},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.ParseCodeowners(test.text)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %+v, got %+v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestParseOwners(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		path     string
+		text     string
+		expected []lib.OwnersEntry
+		err      bool
+	}{
+		{path: "OWNERS", text: "", expected: []lib.OwnersEntry{}},
+		{
+			path: "OWNERS",
+			text: "approvers:\n- a1\nreviewers:\n- r1\n- r2\n",
+			expected: []lib.OwnersEntry{
+				{Pattern: "**", Owner: "a1", Role: "approver"},
+				{Pattern: "**", Owner: "r1", Role: "reviewer"},
+				{Pattern: "**", Owner: "r2", Role: "reviewer"},
+			},
+		},
+		{
+			path: "pkg/kubelet/OWNERS",
+			text: "options:\n  no_parent_owners: true\nfilters:\n  \".*\":\n    approvers:\n    - a1\n  \"\\\\.go$\":\n    reviewers:\n    - r1\n",
+			expected: []lib.OwnersEntry{
+				{Pattern: "pkg/kubelet/.*", Owner: "a1", Role: "approver"},
+				{Pattern: "pkg/kubelet/\\.go$", Owner: "r1", Role: "reviewer"},
+			},
+		},
+		{path: "OWNERS", text: "approvers: [", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.ParseOwners(test.path, test.text)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got %+v", index+1, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %+v, got %+v", index+1, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package devstats
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PartitionedTables - tables partitioned by month of their `created_at` with GHA2DB_PARTITION, by their primary key columns
+// Primary key of a partitioned table must contain `created_at` too, so it is added to them
+var PartitionedTables = map[string]string{
+	"gha_events":               "id",
+	"gha_issues_events_labels": "issue_id, event_id, label_id",
+	"gha_texts":                "",
+}
+
+// PrimaryKey returns primary key definition of `table` (see PartitionedTables), "" when it has none
+func PrimaryKey(ctx *Ctx, table string) string {
+	key := PartitionedTables[table]
+	if key == "" {
+		return ""
+	}
+	if ctx.Partition {
+		key += ", created_at"
+	}
+	return ", primary key(" + key + ")"
+}
+
+// PartitionBy returns partitioning clause of `table` definition, "" when tables are not partitioned
+func PartitionBy(ctx *Ctx) string {
+	if !ctx.Partition {
+		return ""
+	}
+	return " partition by range (created_at)"
+}
+
+// PartitionMonths returns starts of months from `from`'s month to `to`'s month (both included)
+func PartitionMonths(from, to time.Time) (months []time.Time) {
+	for dt := MonthStart(from); !dt.After(to); dt = NextMonthStart(dt) {
+		months = append(months, dt)
+	}
+	return
+}
+
+// PartitionName returns name of `table`'s partition of month starting at `month`, like "gha_events_201801"
+func PartitionName(table string, month time.Time) string {
+	return fmt.Sprintf("%s_%04d%02d", table, month.Year(), int(month.Month()))
+}
+
+// IsPartitioned returns whether `table` is a partitioned table
+func IsPartitioned(con *sql.DB, ctx *Ctx, table string) (bool, error) {
+	var kind string
+	err := QueryRowSQL(con, ctx, "select relkind from pg_class where relname = "+NValue(1), table).Scan(&kind)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return kind == "p", err
+}
+
+// createPartitions creates missing monthly partitions of `table` (named `parent` while it is migrated) from `from` to `to`
+func createPartitions(exec func(string) error, table, parent string, from, to time.Time) error {
+	for _, month := range PartitionMonths(from, to) {
+		err := exec(
+			fmt.Sprintf(
+				"create table if not exists %s partition of %s for values from ('%s') to ('%s')",
+				PartitionName(table, month),
+				parent,
+				ToYMDHMSDate(month),
+				ToYMDHMSDate(NextMonthStart(month)),
+			),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsurePartitions creates missing monthly partitions of all partitioned tables (see PartitionedTables) from `from` to `to`
+// Tables that are not partitioned are skipped, so it can be called before every ingestion
+// Events can be created a bit before hour they are saved in (and other sources save older events), so previous month is added
+func EnsurePartitions(con *sql.DB, ctx *Ctx, from, to time.Time) error {
+	exec := func(query string) error {
+		_, err := ExecSQL(con, ctx, query)
+		return err
+	}
+	for table := range PartitionedTables {
+		partitioned, err := IsPartitioned(con, ctx, table)
+		if err != nil {
+			return err
+		}
+		if !partitioned {
+			continue
+		}
+		if err = createPartitions(exec, table, table, PrevMonthStart(MonthStart(from)), to); err != nil {
+			return fmt.Errorf("%s: %v", table, err)
+		}
+	}
+	return nil
+}
+
+// MigrateToPartitions moves rows of not partitioned `table` (see PartitionedTables) into a new partitioned table in one transaction
+// Table gets partitions of all months it has rows in (up to the next month) and indexes of the old table
+// Returns number of rows moved, -1 when table is already partitioned
+func MigrateToPartitions(con *sql.DB, ctx *Ctx, table string) (int64, error) {
+	partitioned, err := IsPartitioned(con, ctx, table)
+	if err != nil || partitioned {
+		return -1, err
+	}
+	var indexes []string
+	rows, err := QuerySQL(
+		con,
+		ctx,
+		"select indexdef from pg_indexes where tablename = "+NValue(1)+" and indexname <> "+NValue(2),
+		table,
+		table+"_pkey",
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = rows.Close() }()
+	index := ""
+	for rows.Next() {
+		if err = rows.Scan(&index); err != nil {
+			return 0, err
+		}
+		indexes = append(indexes, index)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	from, to := time.Now(), time.Now()
+	var minDt, maxDt *time.Time
+	err = QueryRowSQL(con, ctx, "select min(created_at), max(created_at) from "+table).Scan(&minDt, &maxDt)
+	if err != nil {
+		return 0, err
+	}
+	if minDt != nil {
+		from = *minDt
+	}
+	if maxDt != nil && maxDt.After(to) {
+		to = *maxDt
+	}
+
+	tx, err := con.Begin()
+	if err != nil {
+		return 0, err
+	}
+	exec := func(query string) error {
+		_, err := ExecSQLTx(tx, ctx, query)
+		return err
+	}
+	rollback := func(err error) (int64, error) {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("%s: %v", table, err)
+	}
+	// New table is filled under a temporary name, it gets table's name when the old one is dropped
+	tmp := table + "_partitioned"
+	pctx := *ctx
+	pctx.Partition = true
+	if err = exec("create table " + tmp + "(like " + table + " including defaults" + PrimaryKey(&pctx, table) + ")" + PartitionBy(&pctx)); err != nil {
+		return rollback(err)
+	}
+	if err = createPartitions(exec, table, tmp, from, NextMonthStart(to)); err != nil {
+		return rollback(err)
+	}
+	res, err := ExecSQLTx(tx, ctx, "insert into "+tmp+" select * from "+table)
+	if err != nil {
+		return rollback(err)
+	}
+	moved, _ := res.RowsAffected()
+	queries := []string{"drop table " + table, "alter table " + tmp + " rename to " + table}
+	if PartitionedTables[table] != "" {
+		queries = append(queries, "alter table "+table+" rename constraint "+tmp+"_pkey to "+table+"_pkey")
+	}
+	// Index definitions are of the old table, indexes of partitioned table are created on all its partitions
+	queries = append(queries, indexes...)
+	for _, query := range queries {
+		if err = exec(query); err != nil {
+			return rollback(err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %v", table, err)
+	}
+	return moved, nil
+}
@@ -0,0 +1,60 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestPartitionMonths(t *testing.T) {
+	ft := testlib.YMDHMS
+
+	// Test cases
+	var testCases = []struct {
+		from     time.Time
+		to       time.Time
+		expected []time.Time
+	}{
+		{from: ft(2018, 1, 15, 10), to: ft(2018, 1, 20), expected: []time.Time{ft(2018, 1)}},
+		{from: ft(2017, 11, 30, 23), to: ft(2018, 2), expected: []time.Time{ft(2017, 11), ft(2017, 12), ft(2018, 1), ft(2018, 2)}},
+		{from: ft(2018, 3), to: ft(2018, 2), expected: nil},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.PartitionMonths(test.from, test.to)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestPartitionDefinitions(t *testing.T) {
+	ft := testlib.YMDHMS
+	if got := lib.PartitionName("gha_events", ft(2018, 3)); got != "gha_events_201803" {
+		t.Errorf("expected partition gha_events_201803, got %s", got)
+	}
+
+	// Test cases
+	var testCases = []struct {
+		partition bool
+		table     string
+		key       string
+		by        string
+	}{
+		{table: "gha_events", key: ", primary key(id)"},
+		{partition: true, table: "gha_events", key: ", primary key(id, created_at)", by: " partition by range (created_at)"},
+		{partition: true, table: "gha_issues_events_labels", key: ", primary key(issue_id, event_id, label_id, created_at)", by: " partition by range (created_at)"},
+		{partition: true, table: "gha_texts", key: "", by: " partition by range (created_at)"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		ctx := lib.Ctx{Partition: test.partition}
+		key, by := lib.PrimaryKey(&ctx, test.table), lib.PartitionBy(&ctx)
+		if key != test.key || by != test.by {
+			t.Errorf("test number %d, expected '%s', '%s', got '%s', '%s'", index+1, test.key, test.by, key, by)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package devstats
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// payloadKeys - GHA payload keys that have their Payload structure fields
+var payloadKeys = jsonKeys(reflect.TypeOf(Payload{}))
+
+// jsonKeys - returns JSON keys of structure fields, fields not decoded from JSON ("-" tag) are skipped
+func jsonKeys(t reflect.Type) map[string]struct{} {
+	keys := make(map[string]struct{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := strings.Split(field.Tag.Get("json"), ",")[0]
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = field.Name
+		}
+		keys[key] = struct{}{}
+	}
+	return keys
+}
+
+// PayloadOverflow returns payload keys of GHA event JSON that Payload structure doesn't have (sorted)
+// and JSON object with their values, so fields GitHub adds to payloads are kept until they are handled
+// Returns no keys and nil object when all payload keys are known
+func PayloadOverflow(eventJSON []byte) ([]string, []byte, error) {
+	var ev struct {
+		Payload map[string]json.RawMessage `json:"payload"`
+	}
+	err := json.Unmarshal(eventJSON, &ev)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := []string{}
+	overflow := make(map[string]json.RawMessage)
+	for key, value := range ev.Payload {
+		if _, ok := payloadKeys[key]; ok {
+			continue
+		}
+		keys = append(keys, key)
+		overflow[key] = value
+	}
+	if len(keys) == 0 {
+		return nil, nil, nil
+	}
+	sort.Strings(keys)
+	data, err := json.Marshal(overflow)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err = JSONB(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keys, data, nil
+}
+
+// JSONB returns JSON document that Postgres jsonb column accepts
+// Postgres jsonb cannot contain NUL characters and invalid UTF-8, such documents are encoded again without them
+// Other documents are returned as they are (without surrounding white space)
+func JSONB(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	if utf8.Valid(data) && !bytes.Contains(data, []byte(`\u0000`)) {
+		return data, nil
+	}
+	// Numbers are kept as they are (no float64 rounding of big IDs)
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(stripNUL(value))
+}
+
+// stripNUL - removes NUL characters from all strings of decoded JSON value
+func stripNUL(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.Replace(v, "\x00", "", -1)
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stripNUL(item)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = stripNUL(item)
+		}
+	}
+	return value
+}
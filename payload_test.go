@@ -0,0 +1,78 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestPayloadOverflow(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		json     string
+		keys     []string
+		overflow string
+		err      bool
+	}{
+		{
+			json: `{"type": "PushEvent", "payload": {"push_id": 1, "size": 1, "distinct_size": 1, "ref": "refs/heads/master", "commits": []}}`,
+			keys: []string{"distinct_size"}, overflow: `{"distinct_size":1}`,
+		},
+		{
+			json: `{"type": "IssuesEvent", "payload": {"action": "opened", "issue": {"id": 1}}}`,
+		},
+		{
+			json: `{"type": "WatchEvent"}`,
+		},
+		{
+			json: `{"type": "PullRequestReviewEvent", "payload": {"action": "created", "review": {"id": 2, "state": "approved"}, "pull_request": {"id": 3}, "changes": null}}`,
+			keys: []string{"changes", "review"}, overflow: `{"changes":null,"review":{"id":2,"state":"approved"}}`,
+		},
+		{
+			json: `{"type": "IssuesEvent", "payload": {"label": {"name": "bug\u0000", "tags": ["a\u0000b"]}}}`,
+			keys: []string{"label"}, overflow: `{"label":{"name":"bug","tags":["ab"]}}`,
+		},
+		{
+			json: `{"type": "IssuesEvent", "payload": [1]}`,
+			err:  true,
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		keys, overflow, err := lib.PayloadOverflow([]byte(test.json))
+		if (err != nil) != test.err {
+			t.Errorf("test number %d, expected error %v, got %v", index+1, test.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(keys, test.keys) || string(overflow) != test.overflow {
+			t.Errorf("test number %d, expected %v %s, got %v %s", index+1, test.keys, test.overflow, keys, string(overflow))
+		}
+	}
+}
+
+func TestJSONB(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		json     string
+		expected string
+		err      bool
+	}{
+		{json: " {\"id\": 123456789012345678, \"a\": \"b\"}\n", expected: `{"id": 123456789012345678, "a": "b"}`},
+		{json: `{"id": 123456789012345678, "a": "b\u0000c"}`, expected: `{"a":"bc","id":123456789012345678}`},
+		{json: "{\"a\": \"b\xffc\"}", expected: `{"a":"b` + "�" + `c"}`},
+		{json: `{"a": "b\\u0000c"}`, expected: `{"a":"b\\u0000c"}`},
+		{json: "{\"a\": \xff", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.JSONB([]byte(test.json))
+		if (err != nil) != test.err {
+			t.Errorf("test number %d, expected error %v, got %v", index+1, test.err, err)
+			continue
+		}
+		if string(got) != test.expected {
+			t.Errorf("test number %d, expected %s, got %s", index+1, test.expected, string(got))
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package devstats
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParsePercentiles parses comma separated percentiles (like "50,90,99"), each must be between 0 and 100
+func ParsePercentiles(s string) ([]float64, error) {
+	percentiles := []float64{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		p, err := strconv.ParseFloat(item, 64)
+		if err != nil || p < 0 || p > 100 {
+			return nil, fmt.Errorf("invalid percentile '%s', it must be a number between 0 and 100", item)
+		}
+		percentiles = append(percentiles, p)
+	}
+	if len(percentiles) == 0 {
+		return nil, fmt.Errorf("no percentiles in '%s'", s)
+	}
+	return percentiles, nil
+}
+
+// Percentile returns `p` percentile of sorted values, interpolated between the closest values like Postgres percentile_cont
+// Returns 0 when there are no values
+func Percentile(sorted []float64, p float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(n-1)
+	lower := int(math.Floor(rank))
+	if lower >= n-1 {
+		return sorted[n-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// PercentileSuffix returns series name part for percentile `p`, like "p50" or "p99_9"
+func PercentileSuffix(p float64) string {
+	return "p" + strings.Replace(strconv.FormatFloat(p, 'f', -1, 64), ".", "_", -1)
+}
@@ -0,0 +1,86 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestParsePercentiles(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		s        string
+		expected []float64
+		err      bool
+	}{
+		{s: "50,90,99", expected: []float64{50, 90, 99}},
+		{s: " 50, 99.9 ,", expected: []float64{50, 99.9}},
+		{s: "0,100", expected: []float64{0, 100}},
+		{s: "", err: true},
+		{s: "50,101", err: true},
+		{s: "-1", err: true},
+		{s: "p90", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.ParsePercentiles(test.s)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error for '%s', got %v", index+1, test.s, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		sorted   []float64
+		p        float64
+		expected float64
+	}{
+		{sorted: []float64{}, p: 50, expected: 0},
+		{sorted: []float64{7}, p: 90, expected: 7},
+		{sorted: []float64{1, 2, 3, 4}, p: 50, expected: 2.5},
+		{sorted: []float64{1, 2, 3, 4, 5}, p: 50, expected: 3},
+		{sorted: []float64{1, 2, 3, 4, 5}, p: 0, expected: 1},
+		{sorted: []float64{1, 2, 3, 4, 5}, p: 100, expected: 5},
+		{sorted: []float64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100}, p: 90, expected: 90},
+		{sorted: []float64{0, 10}, p: 99, expected: 9.9},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.Percentile(test.sorted, test.p)
+		if got < test.expected-1e-9 || got > test.expected+1e-9 {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestPercentileSuffix(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		p        float64
+		expected string
+	}{
+		{p: 50, expected: "p50"},
+		{p: 99.9, expected: "p99_9"},
+		{p: 0, expected: "p0"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.PercentileSuffix(test.p)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %s, got %s", index+1, test.expected, got)
+		}
+	}
+}
@@ -1,18 +1,40 @@
 package devstats
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/lib/pq" // As suggested by lib/pq driver
 )
 
-// PgConn Connects to Postgres database
-func PgConn(ctx *Ctx) *sql.DB {
-	connectionString := "client_encoding=UTF8 sslmode='" + ctx.PgSSL + "' host='" + ctx.PgHost + "' port=" + ctx.PgPort + " dbname='" + ctx.PgDB + "' user='" + ctx.PgUser + "' password='" + ctx.PgPass + "'"
+// pgPools - process-wide Postgres connection pools by connection string, shared by all goroutines of the process
+var (
+	pgPools    = make(map[string]*sql.DB)
+	pgPoolsMtx sync.Mutex
+)
+
+// pgIdleTimeout - pool closes connections idle for that long, so connections dropped by server or proxies are not reused
+const pgIdleTimeout = 5 * time.Minute
+
+// pgPool returns process-wide connection pool of database `dbName`, it is created on the first use
+// Pool has up to PG_MAX_CONNS open connections (callers wait for a free one), connections get PG_STATEMENT_TIMEOUT
+// Broken connections are discarded by the pool, pools must not be closed (they are used until the process ends)
+func pgPool(ctx *Ctx, dbName string) *sql.DB {
+	connectionString := "client_encoding=UTF8 sslmode='" + ctx.PgSSL + "' host='" + ctx.PgHost + "' port=" + ctx.PgPort + " dbname='" + dbName + "' user='" + ctx.PgUser + "' password='" + ctx.PgPass + "'"
+	if ctx.PgStmtTimeout > 0 {
+		connectionString += " statement_timeout=" + strconv.Itoa(ctx.PgStmtTimeout*1000)
+	}
+	pgPoolsMtx.Lock()
+	defer pgPoolsMtx.Unlock()
+	if con, ok := pgPools[connectionString]; ok {
+		return con
+	}
 	if ctx.QOut {
 		// Use fmt.Printf (not lib.Printf that logs to DB) here
 		// Avoid trying to log something to DB while connecting
@@ -21,22 +43,38 @@ func PgConn(ctx *Ctx) *sql.DB {
 
 	con, err := sql.Open("postgres", connectionString)
 	FatalOnError(err)
+	idle := ctx.PgMaxConns
+	if idle == 0 {
+		idle = runtime.NumCPU()
+	}
+	con.SetMaxOpenConns(ctx.PgMaxConns)
+	con.SetMaxIdleConns(idle)
+	con.SetConnMaxIdleTime(pgIdleTimeout)
+	pgPools[connectionString] = con
 	return con
 }
 
-// PgConnDB Connects to Postgres database (with specific DB name)
-// uses database 'dbname' instead of 'PgDB'
-func PgConnDB(ctx *Ctx, dbName string) *sql.DB {
-	connectionString := "client_encoding=UTF8 sslmode='" + ctx.PgSSL + "' host='" + ctx.PgHost + "' port=" + ctx.PgPort + " dbname='" + dbName + "' user='" + ctx.PgUser + "' password='" + ctx.PgPass + "'"
-	if ctx.QOut {
-		// Use fmt.Printf (not lib.Printf that logs to DB) here
-		// Avoid trying to log something to DB while connecting
-		fmt.Printf("ConnectString: %s\n", connectionString)
+// closePgPools closes all process-wide connection pools of database `dbName`, they are created again when used
+func closePgPools(dbName string) {
+	pgPoolsMtx.Lock()
+	defer pgPoolsMtx.Unlock()
+	for connectionString, con := range pgPools {
+		if strings.Contains(connectionString, " dbname='"+dbName+"' ") {
+			_ = con.Close()
+			delete(pgPools, connectionString)
+		}
 	}
+}
 
-	con, err := sql.Open("postgres", connectionString)
-	FatalOnError(err)
-	return con
+// PgConn returns process-wide connection pool of Postgres database, don't close it
+func PgConn(ctx *Ctx) *sql.DB {
+	return pgPool(ctx, ctx.PgDB)
+}
+
+// PgConnDB returns process-wide connection pool of Postgres database (with specific DB name), don't close it
+// uses database 'dbname' instead of 'PgDB'
+func PgConnDB(ctx *Ctx, dbName string) *sql.DB {
+	return pgPool(ctx, dbName)
 }
 
 // CreateTable is used to replace DB specific parts of Create Table SQL statement
@@ -74,6 +112,14 @@ func QuerySQL(con *sql.DB, ctx *Ctx, query string, args ...interface{}) (*sql.Ro
 	return con.Query(query, args...)
 }
 
+// QuerySQLContext executes given SQL on Postgres DB like QuerySQL, query is cancelled when `qctx` is done
+func QuerySQLContext(qctx context.Context, con *sql.DB, ctx *Ctx, query string, args ...interface{}) (*sql.Rows, error) {
+	if ctx.QOut {
+		queryOut(query, args...)
+	}
+	return con.QueryContext(qctx, query, args...)
+}
+
 // QuerySQLWithErr wrapper to QuerySQL that exists on error
 func QuerySQLWithErr(con *sql.DB, ctx *Ctx, query string, args ...interface{}) *sql.Rows {
 	// Try to handle "too many connections" error
@@ -317,8 +363,8 @@ func TruncStringOrNil(strPtr *string, maxLen int) interface{} {
 }
 
 // DatabaseExists - checks if database stored in context exists
-// If closeConn is true - then it closes connection after checking if database exists
-// If closeConn is false, then it returns open connection to default database "postgres"
+// If closeConn is true - then it returns nil connection
+// If closeConn is false, then it returns connection pool of default database "postgres"
 func DatabaseExists(ctx *Ctx, closeConn bool) (exists bool, c *sql.DB) {
 	// We cannot connect to database stored in context, because it is possible it's not there
 	db := ctx.PgDB
@@ -327,10 +373,7 @@ func DatabaseExists(ctx *Ctx, closeConn bool) (exists bool, c *sql.DB) {
 	// Connect to Postgres DB using its default database "postgres"
 	c = PgConn(ctx)
 	if closeConn {
-		defer func() {
-			FatalOnError(c.Close())
-			c = nil
-		}()
+		defer func() { c = nil }()
 	}
 
 	// Try to get database name from `pg_database` - it will return row if database exists
@@ -352,10 +395,10 @@ func DatabaseExists(ctx *Ctx, closeConn bool) (exists bool, c *sql.DB) {
 func DropDatabaseIfExists(ctx *Ctx) bool {
 	// Check if database exists
 	exists, c := DatabaseExists(ctx, false)
-	defer func() { FatalOnError(c.Close()) }()
 
-	// Drop database if exists
+	// Drop database if exists, pools of this process cannot keep connections to it
 	if exists {
+		closePgPools(ctx.PgDB)
 		ExecSQLWithErr(c, ctx, "drop database "+ctx.PgDB)
 	}
 
@@ -368,7 +411,6 @@ func DropDatabaseIfExists(ctx *Ctx) bool {
 func CreateDatabaseIfNeeded(ctx *Ctx) bool {
 	// Check if database exists
 	exists, c := DatabaseExists(ctx, false)
-	defer func() { FatalOnError(c.Close()) }()
 
 	// Create database if not exists
 	if !exists {
@@ -2,6 +2,8 @@ package devstats
 
 import (
 	"database/sql"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -237,7 +239,6 @@ func TestPostgres(t *testing.T) {
 
 	// Connect to Postgres DB
 	c := lib.PgConn(&ctx)
-	defer func() { lib.FatalOnError(c.Close()) }()
 
 	// Create example table
 	lib.ExecSQLWithErr(
@@ -344,6 +345,101 @@ func TestPostgres(t *testing.T) {
 	}
 }
 
+func TestMigrationsMatchStructure(t *testing.T) {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Do not allow to run tests in "gha" database
+	if ctx.PgDB != "dbtest" {
+		t.Errorf("tests can only be run on \"dbtest\" database")
+		return
+	}
+	ctx.Table = true
+	ctx.Index = true
+	ctx.Tools = false
+	ctx.Partition = false
+
+	// Drop database if exists
+	lib.DropDatabaseIfExists(&ctx)
+
+	// Create database if needed
+	createdDatabase := lib.CreateDatabaseIfNeeded(&ctx)
+	if !createdDatabase {
+		t.Errorf("failed to create database \"%s\"", ctx.PgDB)
+		return
+	}
+
+	// Drop database after tests
+	defer func() {
+		// Drop database after tests
+		lib.DropDatabaseIfExists(&ctx)
+	}()
+
+	// Connect to Postgres DB
+	c := lib.PgConn(&ctx)
+
+	// Schema created by structure has the latest version
+	lib.Structure(&ctx)
+	expected := schemaObjects(c, &ctx)
+	version, err := lib.SchemaVersion(c, &ctx)
+	lib.FatalOnError(err)
+	if version != lib.LatestSchemaVersion() {
+		t.Errorf("expected structure to create version %d, got %d", lib.LatestSchemaVersion(), version)
+	}
+
+	// Reverting all migrations and applying them again must give the same schema
+	_, version, err = lib.Migrate(c, &ctx, 0)
+	lib.FatalOnError(err)
+	if version != 0 {
+		t.Errorf("expected version 0 after reverting all migrations, got %d", version)
+	}
+	_, version, err = lib.Migrate(c, &ctx, lib.LatestSchemaVersion())
+	lib.FatalOnError(err)
+	if version != lib.LatestSchemaVersion() {
+		t.Errorf("expected version %d after applying all migrations, got %d", lib.LatestSchemaVersion(), version)
+	}
+	got := schemaObjects(c, &ctx)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected migrated schema:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	// Migrations work on database that already has their changes (created by structure before migrations existed)
+	lib.Structure(&ctx)
+	lib.ExecSQLWithErr(c, &ctx, "delete from gha_schema_version")
+	_, version, err = lib.Migrate(c, &ctx, lib.LatestSchemaVersion())
+	lib.FatalOnError(err)
+	if version != lib.LatestSchemaVersion() {
+		t.Errorf("expected version %d after migrating database created by structure, got %d", lib.LatestSchemaVersion(), version)
+	}
+}
+
+// schemaObjects - gets all columns (with types, nullability and defaults) and indexes of tables, sorted
+// Migrations history is skipped, it differs by how schema was created
+func schemaObjects(c *sql.DB, ctx *lib.Ctx) []string {
+	rows := lib.QuerySQLWithErr(
+		c,
+		ctx,
+		"select table_name || '.' || column_name || ' ' || data_type || ' ' || coalesce(character_maximum_length, 0) || "+
+			"' ' || is_nullable || ' ' || coalesce(column_default, '') from information_schema.columns "+
+			"where table_schema = 'public' and table_name <> 'gha_schema_version' "+
+			"union select indexdef from pg_indexes where schemaname = 'public' and tablename <> 'gha_schema_version' "+
+			"order by 1",
+	)
+	defer func() { lib.FatalOnError(rows.Close()) }()
+
+	var (
+		object  string
+		objects []string
+	)
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&object))
+		objects = append(objects, object)
+	}
+	lib.FatalOnError(rows.Err())
+	return objects
+}
+
 // getInts - gets all ints from database, sorted
 func getInts(c *sql.DB, ctx *lib.Ctx) []int {
 	// Get inserted values
@@ -361,3 +457,139 @@ func getInts(c *sql.DB, ctx *lib.Ctx) []int {
 	lib.FatalOnError(rows.Err())
 	return arr
 }
+
+func TestAdvisoryLockReleasesConn(t *testing.T) {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Do not allow to run tests in "gha" database
+	if ctx.PgDB != "dbtest" {
+		t.Errorf("tests can only be run on \"dbtest\" database")
+		return
+	}
+
+	// Drop database if exists
+	lib.DropDatabaseIfExists(&ctx)
+
+	// Create database if needed
+	createdDatabase := lib.CreateDatabaseIfNeeded(&ctx)
+	if !createdDatabase {
+		t.Errorf("failed to create database \"%s\"", ctx.PgDB)
+		return
+	}
+
+	// Drop database after tests
+	defer func() {
+		// Drop database after tests
+		lib.DropDatabaseIfExists(&ctx)
+	}()
+
+	// Connect to Postgres DB
+	c := lib.PgConn(&ctx)
+	lock, err := lib.AcquireLock(c, &ctx, "test_lock")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	holders, err := lib.LockHolders(c, &ctx, "test_lock")
+	if err != nil || len(holders) != 1 || !strings.HasPrefix(holders[0].Application, "devstats test_lock ") {
+		t.Errorf("expected single lock holder, got %+v, error %v", holders, err)
+	}
+	err = lock.Unlock()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Lock's session is back in the pool, it must not keep lock's application name
+	var labeled int
+	err = c.QueryRow(
+		"select count(*) from pg_stat_activity where datname = current_database() and application_name like 'devstats test_lock %'",
+	).Scan(&labeled)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if labeled != 0 {
+		t.Errorf("expected no sessions with lock's application name after unlock, got %d", labeled)
+	}
+}
+
+func TestDupEventsQuery(t *testing.T) {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Do not allow to run tests in "gha" database
+	if ctx.PgDB != "dbtest" {
+		t.Errorf("tests can only be run on \"dbtest\" database")
+		return
+	}
+	ctx.Table = true
+	ctx.Index = false
+	ctx.Tools = false
+	ctx.Partition = false
+
+	// Drop database if exists
+	lib.DropDatabaseIfExists(&ctx)
+
+	// Create database if needed
+	createdDatabase := lib.CreateDatabaseIfNeeded(&ctx)
+	if !createdDatabase {
+		t.Errorf("failed to create database \"%s\"", ctx.PgDB)
+		return
+	}
+
+	// Drop database after tests
+	defer func() {
+		// Drop database after tests
+		lib.DropDatabaseIfExists(&ctx)
+	}()
+
+	// Connect to Postgres DB
+	c := lib.PgConn(&ctx)
+	lib.Structure(&ctx)
+
+	// Wiki edits of the same actor, repo and second, they only differ by their pages
+	// Event 3 is a copy of event 1 saved with a different ID
+	dt := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	pages := map[int]string{1: "Home", 2: "Install", 3: "Home"}
+	for id := 1; id <= 3; id++ {
+		lib.ExecSQLWithErr(
+			c,
+			&ctx,
+			"insert into gha_events(id, type, actor_id, repo_id, public, created_at, dup_actor_login, dup_repo_name) "+lib.NValues(8),
+			id, "GollumEvent", 10, 20, true, dt, "actor", "org/repo",
+		)
+		lib.ExecSQLWithErr(
+			c,
+			&ctx,
+			"insert into gha_payloads(event_id, dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at) "+
+				lib.NValues(7),
+			id, 10, "actor", 20, "org/repo", "GollumEvent", dt,
+		)
+		lib.ExecSQLWithErr(
+			c,
+			&ctx,
+			"insert into gha_pages(sha, event_id, action, title, dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, "+
+				"dup_type, dup_created_at) "+lib.NValues(10),
+			"sha-"+pages[id], id, "edited", pages[id], 10, "actor", 20, "org/repo", "GollumEvent", dt,
+		)
+	}
+
+	rows := lib.QuerySQLWithErr(c, &ctx, lib.DupEventsQuery()+" order by id")
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	var (
+		id   int
+		dups []int
+	)
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&id))
+		dups = append(dups, id)
+	}
+	lib.FatalOnError(rows.Err())
+	if !reflect.DeepEqual(dups, []int{3}) {
+		t.Errorf("expected only event 3 to be a duplicate, got %v", dups)
+	}
+}
@@ -0,0 +1,109 @@
+package devstats
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Pool - bounded worker pool, runs up to N tasks at the same time
+// Tasks are started as soon as any running task finishes (not the oldest one)
+// In fail fast mode the first error cancels pool's context and no new tasks are started
+// Otherwise all tasks are run and all errors are collected
+type Pool struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	sem      chan struct{}
+	wg       sync.WaitGroup
+	mtx      sync.Mutex
+	errs     []error
+	failFast bool
+}
+
+// NewPool creates pool running up to n tasks at the same time
+// Pool is cancelled when `parent` context is cancelled
+func NewPool(parent context.Context, n int, failFast bool) *Pool {
+	if n < 1 {
+		n = 1
+	}
+	ctx, cancel := context.WithCancel(parent)
+	return &Pool{
+		ctx:      ctx,
+		cancel:   cancel,
+		sem:      make(chan struct{}, n),
+		failFast: failFast,
+	}
+}
+
+// Context returns pool's context, long running tasks should check it to stop early
+func (p *Pool) Context() context.Context {
+	return p.ctx
+}
+
+// Go runs task in a new goroutine, it blocks while N tasks are already running
+// It returns false (and doesn't run the task) when the pool is already cancelled
+func (p *Pool) Go(task func(context.Context) error) bool {
+	select {
+	case p.sem <- struct{}{}:
+	case <-p.ctx.Done():
+		return false
+	}
+	// Pool can be cancelled while we were waiting for a free slot
+	if p.ctx.Err() != nil {
+		<-p.sem
+		return false
+	}
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		err := task(p.ctx)
+		if err != nil {
+			p.mtx.Lock()
+			p.errs = append(p.errs, err)
+			p.mtx.Unlock()
+			if p.failFast {
+				p.cancel()
+			}
+		}
+	}()
+	return true
+}
+
+// Wait waits for all started tasks and returns their errors
+// When pool was cancelled by its parent context, context error is the last one returned
+func (p *Pool) Wait() []error {
+	p.wg.Wait()
+	err := p.ctx.Err()
+	p.cancel()
+	if err != nil && !(p.failFast && len(p.errs) > 0) {
+		p.errs = append(p.errs, err)
+	}
+	return p.errs
+}
+
+// ErrInterrupted - tool was stopped by SIGINT or SIGTERM, work in progress was finished (or rolled back)
+var ErrInterrupted = errors.New("interrupted by signal")
+
+// SignalContext returns context that is cancelled on SIGINT (Ctrl-C) or SIGTERM
+// Second signal is not caught, so it terminates the program as usual
+func SignalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-sigs:
+			Printf("Got signal %v, cancelling\n", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sigs)
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,97 @@
+package devstats
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestPool(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		threads     int
+		tasks       int
+		failAt      int
+		failFast    bool
+		expectedRun int
+		expectedErr int
+	}{
+		{threads: 1, tasks: 10, failAt: -1, failFast: false, expectedRun: 10, expectedErr: 0},
+		{threads: 4, tasks: 10, failAt: -1, failFast: true, expectedRun: 10, expectedErr: 0},
+		{threads: 0, tasks: 3, failAt: -1, failFast: false, expectedRun: 3, expectedErr: 0},
+		{threads: 1, tasks: 10, failAt: 2, failFast: false, expectedRun: 10, expectedErr: 1},
+		{threads: 1, tasks: 10, failAt: 2, failFast: true, expectedRun: 3, expectedErr: 1},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		var (
+			mtx     sync.Mutex
+			run     int
+			running int
+			maxRun  int
+		)
+		pool := lib.NewPool(context.Background(), test.threads, test.failFast)
+		for i := 0; i < test.tasks; i++ {
+			task := i
+			pool.Go(func(ctx context.Context) error {
+				mtx.Lock()
+				run++
+				running++
+				if running > maxRun {
+					maxRun = running
+				}
+				mtx.Unlock()
+				time.Sleep(time.Millisecond)
+				mtx.Lock()
+				running--
+				mtx.Unlock()
+				if task == test.failAt {
+					return fmt.Errorf("task %d failed", task)
+				}
+				return nil
+			})
+		}
+		errs := pool.Wait()
+		if run != test.expectedRun {
+			t.Errorf("test number %d, expected %d tasks run, got %d", index+1, test.expectedRun, run)
+		}
+		if len(errs) != test.expectedErr {
+			t.Errorf("test number %d, expected %d errors, got %v", index+1, test.expectedErr, errs)
+		}
+		threads := test.threads
+		if threads < 1 {
+			threads = 1
+		}
+		if maxRun > threads {
+			t.Errorf("test number %d, expected at most %d tasks running at once, got %d", index+1, threads, maxRun)
+		}
+	}
+}
+
+func TestPoolCancel(t *testing.T) {
+	// Cancelling parent context should stop starting new tasks
+	parent, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := lib.NewPool(parent, 1, false)
+	run := 0
+	for i := 0; i < 5; i++ {
+		if i == 2 {
+			cancel()
+		}
+		pool.Go(func(ctx context.Context) error {
+			run++
+			return nil
+		})
+	}
+	errs := pool.Wait()
+	if run != 2 {
+		t.Errorf("expected 2 tasks run, got %d", run)
+	}
+	if len(errs) != 1 || errs[0] != context.Canceled {
+		t.Errorf("expected context cancelled error, got %v", errs)
+	}
+}
@@ -0,0 +1,73 @@
+package devstats
+
+import (
+	"fmt"
+	"time"
+)
+
+// Project control states, saved in `gha_projects_control` table by `devstats pause|force <project>` (`devstats resume` deletes it)
+const (
+	ProjectPaused = "paused" // Project is not synced until it is resumed, even if it is enabled in "projects.yaml"
+	ProjectForced = "forced" // Project is synced on the next `devstats` run even if its `sync_schedule` isn't due, then it is cleared
+)
+
+// ProjectControl - `gha_projects_control` row, it overrides "projects.yaml" without editing and redeploying it
+// It is kept in `devstats` database (like sync status), `devstats` consults it at start of every run
+type ProjectControl struct {
+	Project string    `json:"project"`
+	State   string    `json:"state"`
+	Reason  string    `json:"reason"`
+	Dt      time.Time `json:"dt"`
+}
+
+// ProjectControls returns control of every project that has one, by project name
+func ProjectControls(ctx *Ctx) (map[string]ProjectControl, error) {
+	con, sctx := syncStatusConn(ctx)
+	rows, err := QuerySQL(con, sctx, "select project, state, reason, dt from gha_projects_control")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	controls := make(map[string]ProjectControl)
+	for rows.Next() {
+		var c ProjectControl
+		err = rows.Scan(&c.Project, &c.State, &c.Reason, &c.Dt)
+		if err != nil {
+			return nil, err
+		}
+		controls[c.Project] = c
+	}
+	return controls, rows.Err()
+}
+
+// SetProjectControl sets `project` state to ProjectPaused or ProjectForced, replacing its previous state
+func SetProjectControl(ctx *Ctx, project, state, reason string) error {
+	if state != ProjectPaused && state != ProjectForced {
+		return fmt.Errorf("unknown project control state: '%s'", state)
+	}
+	con, sctx := syncStatusConn(ctx)
+	_, err := ExecSQL(
+		con,
+		sctx,
+		"insert into gha_projects_control(project, state, reason, dt) "+NValues(4)+
+			" on conflict (project) do update set state = excluded.state, reason = excluded.reason, dt = excluded.dt",
+		project,
+		state,
+		reason,
+		time.Now(),
+	)
+	return err
+}
+
+// ClearProjectControl deletes `project` control when it is in `state`, or whatever its state is when `state` is empty
+// Sync clears ProjectForced only, so a project paused while it was syncing stays paused
+func ClearProjectControl(ctx *Ctx, project, state string) error {
+	con, sctx := syncStatusConn(ctx)
+	var err error
+	if state == "" {
+		_, err = ExecSQL(con, sctx, "delete from gha_projects_control where project = "+NValue(1), project)
+	} else {
+		_, err = ExecSQL(con, sctx, "delete from gha_projects_control where project = "+NValue(1)+" and state = "+NValue(2), project, state)
+	}
+	return err
+}
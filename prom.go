@@ -0,0 +1,180 @@
+package devstats
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PromDurationBuckets - histogram buckets (in seconds) used for durations
+var PromDurationBuckets = []float64{1, 5, 15, 60, 300, 900, 3600}
+
+// PromMetrics - Prometheus metrics of a tool run, pushed to Pushgateway (GHA2DB_PUSHGATEWAY) in text exposition format
+// Tools run from cron are too short lived to be scraped, so they push metrics when they finish
+type PromMetrics struct {
+	mtx     sync.Mutex
+	metrics map[string]*promMetric
+}
+
+// promMetric - metric's samples by labels
+type promMetric struct {
+	help    string
+	typ     string
+	samples map[string]float64
+	hists   map[string]*promHist
+}
+
+// promHist - histogram samples
+type promHist struct {
+	counts []int64
+	sum    float64
+	count  int64
+}
+
+// NewPromMetrics creates empty metrics
+func NewPromMetrics() *PromMetrics {
+	return &PromMetrics{metrics: make(map[string]*promMetric)}
+}
+
+// promLabels returns labels in text format (sorted by name), like {metric="x",period="d"}
+func promLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := []string{}
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := []string{}
+	for _, name := range names {
+		pairs = append(pairs, name+"="+strconv.Quote(labels[name]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// metric returns metric `name` of type `typ`, creates it when needed
+func (m *PromMetrics) metric(name, help, typ string) *promMetric {
+	metric, ok := m.metrics[name]
+	if !ok {
+		metric = &promMetric{help: help, typ: typ, samples: make(map[string]float64), hists: make(map[string]*promHist)}
+		m.metrics[name] = metric
+	}
+	return metric
+}
+
+// Add adds `value` to counter `name` with given labels
+func (m *PromMetrics) Add(name, help string, labels map[string]string, value float64) {
+	m.mtx.Lock()
+	m.metric(name, help, "counter").samples[promLabels(labels)] += value
+	m.mtx.Unlock()
+}
+
+// Set sets gauge `name` with given labels to `value`
+func (m *PromMetrics) Set(name, help string, labels map[string]string, value float64) {
+	m.mtx.Lock()
+	m.metric(name, help, "gauge").samples[promLabels(labels)] = value
+	m.mtx.Unlock()
+}
+
+// Observe adds `value` to histogram `name` with given labels, buckets are PromDurationBuckets
+func (m *PromMetrics) Observe(name, help string, labels map[string]string, value float64) {
+	m.mtx.Lock()
+	metric := m.metric(name, help, "histogram")
+	key := promLabels(labels)
+	hist, ok := metric.hists[key]
+	if !ok {
+		hist = &promHist{counts: make([]int64, len(PromDurationBuckets))}
+		metric.hists[key] = hist
+	}
+	for i, le := range PromDurationBuckets {
+		if value <= le {
+			hist.counts[i]++
+		}
+	}
+	hist.sum += value
+	hist.count++
+	m.mtx.Unlock()
+}
+
+// withLe returns labels `key` with "le" label added
+func withLe(key, le string) string {
+	if key == "" {
+		return "{le=\"" + le + "\"}"
+	}
+	return key[:len(key)-1] + ",le=\"" + le + "\"}"
+}
+
+// promFloat formats sample value
+func promFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// Text returns all metrics in Prometheus text exposition format, sorted by metric name and labels
+func (m *PromMetrics) Text() string {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	names := []string{}
+	for name := range m.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		metric := m.metrics[name]
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s %s\n", name, metric.help, name, metric.typ)
+		keys := []string{}
+		for key := range metric.samples {
+			keys = append(keys, key)
+		}
+		for key := range metric.hists {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			hist, ok := metric.hists[key]
+			if !ok {
+				fmt.Fprintf(&buf, "%s%s %s\n", name, key, promFloat(metric.samples[key]))
+				continue
+			}
+			for i, le := range PromDurationBuckets {
+				fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, withLe(key, promFloat(le)), hist.counts[i])
+			}
+			fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, withLe(key, "+Inf"), hist.count)
+			fmt.Fprintf(&buf, "%s_sum%s %s\n", name, key, promFloat(hist.sum))
+			fmt.Fprintf(&buf, "%s_count%s %d\n", name, key, hist.count)
+		}
+	}
+	return buf.String()
+}
+
+// PushMetrics pushes metrics to Pushgateway (when GHA2DB_PUSHGATEWAY is set) as `job`, grouped by project
+// Metrics with the same names pushed before are replaced, others (like the last success time) are kept
+func PushMetrics(ctx *Ctx, job string, m *PromMetrics) error {
+	if ctx.Pushgateway == "" {
+		return nil
+	}
+	project := ctx.Project
+	if project == "" {
+		project = "none"
+	}
+	pushURL := strings.TrimRight(ctx.Pushgateway, "/") + "/metrics/job/" + url.PathEscape(job) + "/project/" + url.PathEscape(project)
+	client := &http.Client{Timeout: 30 * time.Second}
+	response, err := client.Post(pushURL, "text/plain; version=0.0.4", strings.NewReader(m.Text()))
+	if err != nil {
+		return err
+	}
+	body, _ := ioutil.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("%s: HTTP %d: %s", pushURL, response.StatusCode, TruncToBytes(string(body), 0x100))
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestPromMetricsText(t *testing.T) {
+	m := lib.NewPromMetrics()
+	m.Set("devstats_sync_events", "New events.", nil, 120)
+	m.Add("devstats_sync_hours", "GHA hours ingested.", nil, 2)
+	m.Add("devstats_sync_hours", "GHA hours ingested.", nil, 1)
+	m.Observe("devstats_metric_duration_seconds", "Duration.", map[string]string{"metric": "PRs \"merged\""}, 3)
+	m.Observe("devstats_metric_duration_seconds", "Duration.", map[string]string{"metric": "PRs \"merged\""}, 100.5)
+	m.Observe("devstats_metric_duration_seconds", "Duration.", map[string]string{"metric": "Reviewers"}, 7200)
+	expected := `# HELP devstats_metric_duration_seconds Duration.
+# TYPE devstats_metric_duration_seconds histogram
+devstats_metric_duration_seconds_bucket{metric="PRs \"merged\"",le="1"} 0
+devstats_metric_duration_seconds_bucket{metric="PRs \"merged\"",le="5"} 1
+devstats_metric_duration_seconds_bucket{metric="PRs \"merged\"",le="15"} 1
+devstats_metric_duration_seconds_bucket{metric="PRs \"merged\"",le="60"} 1
+devstats_metric_duration_seconds_bucket{metric="PRs \"merged\"",le="300"} 2
+devstats_metric_duration_seconds_bucket{metric="PRs \"merged\"",le="900"} 2
+devstats_metric_duration_seconds_bucket{metric="PRs \"merged\"",le="3600"} 2
+devstats_metric_duration_seconds_bucket{metric="PRs \"merged\"",le="+Inf"} 2
+devstats_metric_duration_seconds_sum{metric="PRs \"merged\""} 103.5
+devstats_metric_duration_seconds_count{metric="PRs \"merged\""} 2
+devstats_metric_duration_seconds_bucket{metric="Reviewers",le="1"} 0
+devstats_metric_duration_seconds_bucket{metric="Reviewers",le="5"} 0
+devstats_metric_duration_seconds_bucket{metric="Reviewers",le="15"} 0
+devstats_metric_duration_seconds_bucket{metric="Reviewers",le="60"} 0
+devstats_metric_duration_seconds_bucket{metric="Reviewers",le="300"} 0
+devstats_metric_duration_seconds_bucket{metric="Reviewers",le="900"} 0
+devstats_metric_duration_seconds_bucket{metric="Reviewers",le="3600"} 0
+devstats_metric_duration_seconds_bucket{metric="Reviewers",le="+Inf"} 1
+devstats_metric_duration_seconds_sum{metric="Reviewers"} 7200
+devstats_metric_duration_seconds_count{metric="Reviewers"} 1
+# HELP devstats_sync_events New events.
+# TYPE devstats_sync_events gauge
+devstats_sync_events 120
+# HELP devstats_sync_hours GHA hours ingested.
+# TYPE devstats_sync_hours counter
+devstats_sync_hours 3
+`
+	if got := m.Text(); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestPushMetrics(t *testing.T) {
+	var (
+		path, method, body string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		path, method, body = r.URL.Path, r.Method, string(data)
+	}))
+	defer server.Close()
+
+	m := lib.NewPromMetrics()
+	m.Set("devstats_sync_success", "Last sync succeeded.", nil, 1)
+
+	// Nothing is pushed without Pushgateway
+	ctx := lib.Ctx{Project: "kubernetes"}
+	if err := lib.PushMetrics(&ctx, "devstats_sync", m); err != nil || path != "" {
+		t.Errorf("expected nothing pushed, got error %v, path '%s'", err, path)
+	}
+
+	ctx.Pushgateway = server.URL + "/"
+	if err := lib.PushMetrics(&ctx, "devstats_sync", m); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if path != "/metrics/job/devstats_sync/project/kubernetes" || method != "POST" || body != m.Text() {
+		t.Errorf("unexpected push: %s %s:\n%s", method, path, body)
+	}
+
+	// Pushgateway errors are returned
+	ctx.Pushgateway = server.URL + "/broken"
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	if err := lib.PushMetrics(&ctx, "devstats_sync", m); err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -0,0 +1,65 @@
+package devstats
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter - token bucket rate limiter, safe to use from many goroutines
+// Bucket holds up to `burst` tokens and is refilled with `perMinute` tokens per minute
+// nil *RateLimiter means no limit
+type RateLimiter struct {
+	mtx    sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates limiter allowing `perMinute` operations per minute with bursts up to `burst`
+// Returns nil (no limit) when perMinute < 1
+func NewRateLimiter(perMinute, burst int) *RateLimiter {
+	if perMinute < 1 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   float64(perMinute) / 60.0,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until operation is allowed or `ctx` is cancelled (then it returns ctx error)
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	if r == nil {
+		return ctx.Err()
+	}
+	for {
+		r.mtx.Lock()
+		now := time.Now()
+		r.tokens += now.Sub(r.last).Seconds() * r.rate
+		if r.tokens > r.burst {
+			r.tokens = r.burst
+		}
+		r.last = now
+		if r.tokens >= 1.0 {
+			r.tokens--
+			r.mtx.Unlock()
+			return nil
+		}
+		wait := time.Duration((1.0 - r.tokens) / r.rate * float64(time.Second))
+		r.mtx.Unlock()
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package devstats
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestRateLimiter(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		perMinute int
+		burst     int
+		calls     int
+		minTime   time.Duration
+		maxTime   time.Duration
+	}{
+		{perMinute: 0, burst: 0, calls: 100, minTime: 0, maxTime: 50 * time.Millisecond},
+		{perMinute: 6000, burst: 5, calls: 5, minTime: 0, maxTime: 5 * time.Millisecond},
+		{perMinute: 6000, burst: 1, calls: 6, minTime: 45 * time.Millisecond, maxTime: 500 * time.Millisecond},
+		{perMinute: 6000, burst: 3, calls: 8, minTime: 45 * time.Millisecond, maxTime: 500 * time.Millisecond},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		limiter := lib.NewRateLimiter(test.perMinute, test.burst)
+		var wg sync.WaitGroup
+		dtStart := time.Now()
+		for i := 0; i < test.calls; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := limiter.Wait(context.Background()); err != nil {
+					t.Errorf("test number %d, unexpected error: %v", index+1, err)
+				}
+			}()
+		}
+		wg.Wait()
+		took := time.Now().Sub(dtStart)
+		if took < test.minTime || took > test.maxTime {
+			t.Errorf("test number %d, expected time between %v and %v, got %v", index+1, test.minTime, test.maxTime, took)
+		}
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	// Waiting for a token should stop when context is cancelled
+	limiter := lib.NewRateLimiter(1, 1)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Errorf("expected first call to pass, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	dtStart := time.Now()
+	err := limiter.Wait(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded error, got %v", err)
+	}
+	if time.Now().Sub(dtStart) > time.Second {
+		t.Errorf("expected cancelled wait to return early")
+	}
+}
@@ -0,0 +1,87 @@
+package devstats
+
+import (
+	"database/sql"
+	"time"
+)
+
+// MetricWindow - the last window computed by sync for metric's series in a period, saved in `gha_metric_windows`
+// BackfilledAt is when the full history of the series was computed
+type MetricWindow struct {
+	From         time.Time
+	To           time.Time
+	BackfilledAt time.Time
+}
+
+// RecomputeFrom returns start of the last `n` periods (`periodAggr` like "w" or "d7", the current period is the last one) at `to`
+// Points of aggregated periods are computed every base period, so "d7" gives `n` days. Unknown periods (like releases) give `to`
+// Periods start in `to`'s time zone (like project's TimeZone), result is in UTC
+func RecomputeFrom(periodAggr string, n int, to time.Time) time.Time {
+	_, _, intervalStart, nextIntervalStart, prevIntervalStart := GetIntervalFunctionsIn(periodAggr, true, to.Location())
+	if intervalStart == nil || n < 1 {
+		return to.UTC()
+	}
+	return AddNIntervals(intervalStart(to), 1-n, nextIntervalStart, prevIntervalStart)
+}
+
+// MetricWindowFrom returns where sync computes metric's series that recomputes the last `n` periods and whether it is a full backfill
+// Full history (from `start`) is computed when series has no saved `window` or when `fullBackfill` is requested
+// Otherwise the last `n` periods are computed (also in `reset` mode), earlier when sync window `from` or the saved window's end is earlier
+func MetricWindowFrom(window *MetricWindow, periodAggr string, n int, from, to, start time.Time, fullBackfill, reset bool) (time.Time, bool) {
+	if window == nil || fullBackfill {
+		return start, true
+	}
+	dt := RecomputeFrom(periodAggr, n, to)
+	if !reset && from.Before(dt) {
+		dt = from
+	}
+	if window.To.Before(dt) {
+		dt = window.To
+	}
+	return dt, false
+}
+
+// GetMetricWindow returns the last window computed for metric's series in period, nil when there is none
+func GetMetricWindow(con *sql.DB, ctx *Ctx, metric, series, period string) (*MetricWindow, error) {
+	var window MetricWindow
+	err := QueryRowSQL(
+		con,
+		ctx,
+		"select dt_from, dt_to, backfilled_at from gha_metric_windows where metric = "+NValue(1)+
+			" and series = "+NValue(2)+" and period = "+NValue(3),
+		metric,
+		series,
+		period,
+	).Scan(&window.From, &window.To, &window.BackfilledAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+// SetMetricWindow saves window computed for metric's series in period, `full` - window is the full history (backfill)
+func SetMetricWindow(con *sql.DB, ctx *Ctx, metric, series, period string, from, to time.Time, full bool) error {
+	now := time.Now()
+	var backfilledAt *time.Time
+	if full {
+		backfilledAt = &now
+	}
+	_, err := ExecSQL(
+		con,
+		ctx,
+		"insert into gha_metric_windows(metric, series, period, dt_from, dt_to, backfilled_at, dt) "+NValues(7)+
+			" on conflict (metric, series, period) do update set dt_from = excluded.dt_from, dt_to = excluded.dt_to, "+
+			"backfilled_at = coalesce(excluded.backfilled_at, gha_metric_windows.backfilled_at), dt = excluded.dt",
+		metric,
+		series,
+		period,
+		from,
+		to,
+		backfilledAt,
+		now,
+	)
+	return err
+}
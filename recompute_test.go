@@ -0,0 +1,92 @@
+package devstats
+
+import (
+	"testing"
+	"time"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestRecomputeFrom(t *testing.T) {
+	ft := testlib.YMDHMS
+	// Wednesday
+	to := ft(2018, 3, 14, 10, 30)
+
+	// Test cases
+	var testCases = []struct {
+		period   string
+		n        int
+		expected time.Time
+	}{
+		{period: "h", n: 1, expected: ft(2018, 3, 14, 10)},
+		{period: "h", n: 3, expected: ft(2018, 3, 14, 8)},
+		{period: "d", n: 2, expected: ft(2018, 3, 13)},
+		{period: "d7", n: 2, expected: ft(2018, 3, 13)},
+		{period: "w", n: 2, expected: ft(2018, 3, 5)},
+		{period: "m", n: 3, expected: ft(2018, 1, 1)},
+		{period: "q", n: 2, expected: ft(2017, 10, 1)},
+		{period: "y", n: 1, expected: ft(2018)},
+		{period: "r", n: 2, expected: to},
+		{period: "d", n: 0, expected: to},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.RecomputeFrom(test.period, test.n, to)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+
+	// Periods start in `to`'s time zone, 2018-03-14 10:30 UTC is 2018-03-14 19:30 in Tokyo (UTC+9)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := lib.RecomputeFrom("d", 2, to.In(tokyo))
+	if expected := ft(2018, 3, 12, 15); got != expected {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	got = lib.RecomputeFrom("r", 2, to.In(tokyo))
+	if got != to {
+		t.Errorf("expected %v, got %v", to, got)
+	}
+}
+
+func TestMetricWindowFrom(t *testing.T) {
+	ft := testlib.YMDHMS
+	start := ft(2014)
+	to := ft(2018, 3, 14, 10)
+	window := &lib.MetricWindow{From: ft(2018, 3, 13), To: ft(2018, 3, 14, 9), BackfilledAt: ft(2018, 1, 1)}
+	stale := &lib.MetricWindow{From: ft(2018, 2, 1), To: ft(2018, 2, 2), BackfilledAt: ft(2018, 1, 1)}
+
+	// Test cases
+	var testCases = []struct {
+		window       *lib.MetricWindow
+		from         time.Time
+		fullBackfill bool
+		reset        bool
+		expected     time.Time
+		full         bool
+	}{
+		// Never computed series gets full history
+		{window: nil, from: ft(2018, 3, 14, 9), expected: start, full: true},
+		{window: window, from: ft(2018, 3, 14, 9), fullBackfill: true, expected: start, full: true},
+		// The last 3 days
+		{window: window, from: ft(2018, 3, 14, 9), expected: ft(2018, 3, 12)},
+		// Reset only recomputes the last periods
+		{window: window, from: start, reset: true, expected: ft(2018, 3, 12)},
+		// Catch-up sync window is kept
+		{window: window, from: ft(2018, 3, 1), expected: ft(2018, 3, 1)},
+		// Series not computed since its window's end
+		{window: stale, from: ft(2018, 3, 14, 9), expected: ft(2018, 2, 2)},
+		{window: stale, from: start, reset: true, expected: ft(2018, 2, 2)},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, full := lib.MetricWindowFrom(test.window, "d", 3, test.from, to, start, test.fullBackfill, test.reset)
+		if got != test.expected || full != test.full {
+			t.Errorf("test number %d, expected (%v, %v), got (%v, %v)", index+1, test.expected, test.full, got, full)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package devstats
+
+import (
+	"sort"
+	"time"
+
+	client "github.com/influxdata/influxdb/client/v2"
+)
+
+// ReleasePeriod - metrics period between releases (annotations): point at every release, its value is computed
+// from this release to the next one (or to now for the last release). Series names get "_r" suffix like other periods
+const ReleasePeriod = "r"
+
+// JoinDateTitle - title of project's join date annotation, it is not a release
+const JoinDateTitle = "CNCF join Date"
+
+// ReleaseRecomputeAge - closed release ranges are recomputed while they are this recent
+// Annotations are refreshed once a day and release tags can be dated before they are pushed
+const ReleaseRecomputeAge = 7 * 24 * time.Hour
+
+// ReleaseRange - range between two releases, `To` is now for the last release
+type ReleaseRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// ReleaseRanges returns ranges between release `dates` that need computing: ending after `from` or closed recently
+// Dates after `now` are skipped
+func ReleaseRanges(dates []time.Time, from, now time.Time) (ranges []ReleaseRange) {
+	sorted := []time.Time{}
+	for _, dt := range dates {
+		if dt.Before(now) {
+			sorted = append(sorted, dt)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+	recent := now.Add(-ReleaseRecomputeAge)
+	for i, dt := range sorted {
+		to := now
+		if i < len(sorted)-1 {
+			to = sorted[i+1]
+		}
+		if !to.After(dt) {
+			continue
+		}
+		if to.After(from) || to.After(recent) {
+			ranges = append(ranges, ReleaseRange{From: dt, To: to})
+		}
+	}
+	return
+}
+
+// GetReleaseDates returns release dates from InfluxDB "annotations" series (written by `annotations` tool)
+func GetReleaseDates(ic client.Client, ctx *Ctx) (dates []time.Time) {
+	res := QueryIDB(ic, ctx, "select title from annotations")
+	if len(res) < 1 || len(res[0].Series) < 1 {
+		return
+	}
+	for _, row := range res[0].Series[0].Values {
+		if title, _ := row[1].(string); title == JoinDateTitle {
+			continue
+		}
+		dates = append(dates, TimeParseIDB(row[0].(string)))
+	}
+	return
+}
@@ -0,0 +1,69 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestReleaseRanges(t *testing.T) {
+	ft := testlib.YMDHMS
+	now := ft(2018, 3, 1, 12)
+	dates := []time.Time{ft(2018, 2, 27), ft(2017, 12, 1), ft(2018, 1, 10), ft(2018, 4, 1)}
+
+	// Test cases
+	var testCases = []struct {
+		dates    []time.Time
+		from     time.Time
+		expected []lib.ReleaseRange
+	}{
+		{dates: nil, from: ft(2017), expected: nil},
+		{
+			dates: dates,
+			from:  ft(2017),
+			expected: []lib.ReleaseRange{
+				{From: ft(2017, 12, 1), To: ft(2018, 1, 10)},
+				{From: ft(2018, 1, 10), To: ft(2018, 2, 27)},
+				{From: ft(2018, 2, 27), To: now},
+			},
+		},
+		{
+			dates: dates,
+			from:  ft(2018, 1, 15),
+			expected: []lib.ReleaseRange{
+				{From: ft(2018, 1, 10), To: ft(2018, 2, 27)},
+				{From: ft(2018, 2, 27), To: now},
+			},
+		},
+		// Release closed within a week is recomputed even when it ended before `from`
+		{
+			dates: dates,
+			from:  ft(2018, 3, 1, 10),
+			expected: []lib.ReleaseRange{
+				{From: ft(2018, 1, 10), To: ft(2018, 2, 27)},
+				{From: ft(2018, 2, 27), To: now},
+			},
+		},
+		{
+			dates:    []time.Time{ft(2018, 1, 10), ft(2018, 1, 20)},
+			from:     ft(2018, 3, 1, 10),
+			expected: []lib.ReleaseRange{{From: ft(2018, 1, 20), To: now}},
+		},
+		// The same date twice gives no empty range
+		{
+			dates:    []time.Time{ft(2018, 1, 10), ft(2018, 1, 10)},
+			from:     ft(2018, 3, 1, 10),
+			expected: []lib.ReleaseRange{{From: ft(2018, 1, 10), To: now}},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.ReleaseRanges(test.dates, test.from, now)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %+v, got %+v", index+1, test.expected, got)
+		}
+	}
+}
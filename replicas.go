@@ -0,0 +1,131 @@
+package devstats
+
+import (
+	"database/sql"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pgReadPools - connection pools used by read-only queries of each database, replica's or primary's, chosen once per process
+var (
+	pgReadPools    = make(map[string]*sql.DB)
+	pgReadPoolsMtx sync.Mutex
+)
+
+// writeKeywords - SQL keywords of statements that cannot run on a hot standby replica (read-only transaction)
+// "into" is in `insert into` and `select ... into table`, both write
+var writeKeywords = map[string]struct{}{
+	"create": {}, "insert": {}, "update": {}, "delete": {}, "merge": {}, "drop": {}, "alter": {}, "truncate": {}, "into": {},
+	"copy": {}, "grant": {}, "revoke": {}, "lock": {}, "vacuum": {}, "reindex": {}, "cluster": {}, "refresh": {}, "comment": {},
+	"call": {}, "do": {}, "nextval": {}, "setval": {},
+}
+
+// sqlNoise - comments and string literals, words in them are not keywords
+var sqlNoise = regexp.MustCompile(`(?s)--[^\n]*|/\*.*?\*/|'(?:[^']|'')*'`)
+
+// sqlWord - SQL identifier or keyword
+var sqlWord = regexp.MustCompile(`[a-z_][a-z0-9_$]*`)
+
+// ReadOnlyQuery returns whether `query` only reads data, so it can run on a replica
+// Queries with any DDL or DML (like metrics that `create temp table` first) must run on primary
+func ReadOnlyQuery(query string) bool {
+	for _, word := range sqlWord.FindAllString(sqlNoise.ReplaceAllString(strings.ToLower(query), " "), -1) {
+		if _, ok := writeKeywords[word]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// ReplicaHostPort returns host and port of `replica` given as "host" or "host:port" (IPv6 as "[host]:port"), default port is `port`
+func ReplicaHostPort(replica, port string) (string, string) {
+	replica = strings.TrimSpace(replica)
+	host, replicaPort, err := net.SplitHostPort(replica)
+	if err != nil {
+		return replica, port
+	}
+	return host, replicaPort
+}
+
+// replicaReplayed returns whether replica `con` replayed primary's WAL up to `lsn`, false when it is not a replica
+func replicaReplayed(con *sql.DB, ctx *Ctx, lsn string) (replayed bool, err error) {
+	err = QueryRowSQL(
+		con,
+		ctx,
+		"select coalesce(pg_last_wal_replay_lsn() >= "+NValue(1)+"::pg_lsn, false)",
+		lsn,
+	).Scan(&replayed)
+	return
+}
+
+// readPool returns pool of the first replica (PG_REPLICAS) that replayed all changes committed on primary so far
+// Replicas are checked every second for up to PG_REPLICA_WAIT seconds, then primary's pool is returned
+func readPool(ctx *Ctx) *sql.DB {
+	primary := PgConn(ctx)
+	var lsn string
+	if err := QueryRowSQL(primary, ctx, "select pg_current_wal_lsn()").Scan(&lsn); err != nil {
+		Printf("Cannot get primary's WAL position, reading from primary: %v\n", err)
+		return primary
+	}
+	failed := make(map[string]struct{})
+	deadline := time.Now().Add(time.Duration(ctx.PgReplicaWait) * time.Second)
+	for {
+		for _, replica := range ctx.PgReplicas {
+			if _, ok := failed[replica]; ok {
+				continue
+			}
+			rctx := *ctx
+			rctx.PgHost, rctx.PgPort = ReplicaHostPort(replica, ctx.PgPort)
+			con := PgConn(&rctx)
+			replayed, err := replicaReplayed(con, ctx, lsn)
+			if err != nil {
+				Printf("Replica %s is not used: %v\n", replica, err)
+				failed[replica] = struct{}{}
+				continue
+			}
+			if replayed {
+				if ctx.Debug > 0 {
+					Printf("Reading %s from replica %s\n", ctx.PgDB, replica)
+				}
+				return con
+			}
+		}
+		if len(failed) == len(ctx.PgReplicas) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	Printf("No replica of %s replayed primary's WAL position %s, reading from primary\n", ctx.PgDB, lsn)
+	return primary
+}
+
+// PgQueryConn returns process-wide connection pool to run `query` on, don't close it
+// Read-only queries use PgReadConn (a replica when there is one up to date), other ones use primary
+func PgQueryConn(ctx *Ctx, query string) *sql.DB {
+	if len(ctx.PgReplicas) == 0 || !ReadOnlyQuery(query) {
+		return PgConn(ctx)
+	}
+	return PgReadConn(ctx)
+}
+
+// PgReadConn returns process-wide connection pool for read-only queries of Postgres database, don't close it
+// It is a replica from PG_REPLICAS that has all changes committed on primary before the first call, so reads
+// see all data written before (like just ingested events), primary when there are no replicas or none catches up
+// The choice is kept until the process ends, queries writing anything must use PgConn
+func PgReadConn(ctx *Ctx) *sql.DB {
+	if len(ctx.PgReplicas) == 0 {
+		return PgConn(ctx)
+	}
+	// Other threads wait for the choice, so replicas are checked only once
+	pgReadPoolsMtx.Lock()
+	defer pgReadPoolsMtx.Unlock()
+	if con, ok := pgReadPools[ctx.PgDB]; ok {
+		return con
+	}
+	con := readPool(ctx)
+	pgReadPools[ctx.PgDB] = con
+	return con
+}
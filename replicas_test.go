@@ -0,0 +1,55 @@
+package devstats
+
+import (
+	"testing"
+
+	lib "devstats"
+)
+
+func TestReplicaHostPort(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		replica string
+		host    string
+		port    string
+	}{
+		{replica: "replica1", host: "replica1", port: "5432"},
+		{replica: " replica2:5433", host: "replica2", port: "5433"},
+		{replica: "10.0.0.7:6432", host: "10.0.0.7", port: "6432"},
+		{replica: "[fd00::7]:5433", host: "fd00::7", port: "5433"},
+		{replica: "fd00::7", host: "fd00::7", port: "5432"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		host, port := lib.ReplicaHostPort(test.replica, "5432")
+		if host != test.host || port != test.port {
+			t.Errorf("test number %d, expected %s:%s, got %s:%s", index+1, test.host, test.port, host, port)
+		}
+	}
+}
+
+func TestReadOnlyQuery(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		query    string
+		expected bool
+	}{
+		{query: "select count(*) from gha_events where created_at >= '{{from}}'", expected: true},
+		{query: "explain select\n  1", expected: true},
+		{query: "with prs as (select id from gha_pull_requests) select count(*) from prs", expected: true},
+		{query: "-- create temp table in a comment\nselect 'insert into x' as s /* drop table */", expected: true},
+		{query: "select 1 from gha_events where type = 'DeleteEvent'", expected: true},
+		{query: "create temp table prs as select id from gha_pull_requests;\nselect count(*) from prs;", expected: false},
+		{query: "CREATE TEMPORARY TABLE t(i int); select * from t", expected: false},
+		{query: "select id into tmp_prs from gha_pull_requests", expected: false},
+		{query: "with d as (delete from gha_logs returning id) select count(*) from d", expected: false},
+		{query: "update gha_metric_cache set dt = now()", expected: false},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.ReadOnlyQuery(test.query)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v for %s", index+1, test.expected, got, test.query)
+		}
+	}
+}
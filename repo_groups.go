@@ -0,0 +1,89 @@
+package devstats
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// RepoGroupRule - projects.yaml `repo_groups` entry: repositories with full name matching `pattern` belong to `group`
+type RepoGroupRule struct {
+	Group   string `yaml:"group"`
+	Pattern string `yaml:"pattern"`
+}
+
+// RepoGroups - assigns repo groups to repositories using project's rules
+// Patterns are case insensitive and the first matching rule wins, nil *RepoGroups assigns no groups
+type RepoGroups struct {
+	groups   []string
+	patterns []*regexp.Regexp
+}
+
+// NewRepoGroups compiles given rules (in order), returns nil when there are no rules
+func NewRepoGroups(rules []RepoGroupRule) (*RepoGroups, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	g := &RepoGroups{}
+	for _, rule := range rules {
+		group := strings.TrimSpace(rule.Group)
+		if group == "" || strings.TrimSpace(rule.Pattern) == "" {
+			return nil, fmt.Errorf("repo group rule needs both group and pattern: %+v", rule)
+		}
+		re, err := regexp.Compile("(?i)" + rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid repo group '%s' pattern '%s': %v", group, rule.Pattern, err)
+		}
+		g.groups = append(g.groups, group)
+		g.patterns = append(g.patterns, re)
+	}
+	return g, nil
+}
+
+// Group returns repo group of repository `name` (like "kubernetes/kubernetes")
+// Returns false when no rule matches it
+func (g *RepoGroups) Group(name string) (string, bool) {
+	if g == nil {
+		return "", false
+	}
+	for i, re := range g.patterns {
+		if re.MatchString(name) {
+			return g.groups[i], true
+		}
+	}
+	return "", false
+}
+
+// GroupOrNil returns repo group of repository `name` or nil (SQL null) when no rule matches it
+func (g *RepoGroups) GroupOrNil(name string) interface{} {
+	if group, ok := g.Group(name); ok {
+		return group
+	}
+	return nil
+}
+
+// ProjectRepoGroups creates repo groups from `ctx.Project` rules in `ctx.ProjectsYaml`
+// Returns nil when project is not set or has no rules
+func ProjectRepoGroups(ctx *Ctx) (*RepoGroups, error) {
+	if ctx.Project == "" {
+		return nil, nil
+	}
+	// Local or cron mode?
+	dataPrefix := DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+	data, err := ioutil.ReadFile(dataPrefix + ctx.ProjectsYaml)
+	if err != nil {
+		return nil, err
+	}
+	var projects AllProjects
+	err = yaml.Unmarshal(data, &projects)
+	if err != nil {
+		return nil, err
+	}
+	return NewRepoGroups(projects.Projects[ctx.Project].RepoGroups)
+}
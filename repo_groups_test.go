@@ -0,0 +1,110 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestRepoGroups(t *testing.T) {
+	rules := []lib.RepoGroupRule{
+		{Group: "Kubernetes", Pattern: "^kubernetes/kubernetes$"},
+		{Group: "API machinery", Pattern: "^kubernetes/(api|apimachinery|apiserver)$"},
+		{Group: "Clients", Pattern: "^kubernetes-client/"},
+		{Group: "Other", Pattern: "^kubernetes/"},
+	}
+
+	// Test cases
+	var testCases = []struct {
+		rules    []lib.RepoGroupRule
+		name     string
+		expected interface{}
+	}{
+		{rules: nil, name: "kubernetes/kubernetes", expected: nil},
+		{rules: rules, name: "kubernetes/kubernetes", expected: "Kubernetes"},
+		{rules: rules, name: "Kubernetes/Kubernetes", expected: "Kubernetes"},
+		{rules: rules, name: "kubernetes/apiserver", expected: "API machinery"},
+		{rules: rules, name: "kubernetes-client/python", expected: "Clients"},
+		{rules: rules, name: "kubernetes/kubectl", expected: "Other"},
+		{rules: rules, name: "kubernetes-incubator/kompose", expected: nil},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		groups, err := lib.NewRepoGroups(test.rules)
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		got := groups.GroupOrNil(test.name)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v, repo: %s", index+1, test.expected, got, test.name)
+		}
+	}
+}
+
+func TestRepoGroupsInvalidRules(t *testing.T) {
+	for index, rule := range []lib.RepoGroupRule{
+		{Group: "Kubernetes", Pattern: "[kubernetes"},
+		{Group: "", Pattern: "^kubernetes/"},
+		{Group: "Kubernetes", Pattern: " "},
+	} {
+		_, err := lib.NewRepoGroups([]lib.RepoGroupRule{rule})
+		if err == nil {
+			t.Errorf("test number %d, expected error for rule %+v", index+1, rule)
+		}
+	}
+}
+
+func TestProjectRepoGroups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devstats_repo_groups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	// Files are read relative to current directory in local mode
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(pwd) }()
+	err = ioutil.WriteFile(
+		"projects.yaml",
+		[]byte(
+			"projects:\n  kubernetes:\n    repo_groups:\n      - group: Kubernetes\n        pattern: '^kubernetes/kubernetes$'\n"+
+				"  prometheus:\n    psql_db: prometheus\n",
+		),
+		0644,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Test cases
+	var testCases = []struct {
+		project  string
+		expected interface{}
+	}{
+		{project: "", expected: nil},
+		{project: "kubernetes", expected: "Kubernetes"},
+		{project: "prometheus", expected: nil},
+		{project: "missing", expected: nil},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		ctx := lib.Ctx{Local: true, ProjectsYaml: "projects.yaml", Project: test.project}
+		groups, err := lib.ProjectRepoGroups(&ctx)
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		got := groups.GroupOrNil("kubernetes/kubernetes")
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package devstats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// restRetries - number of retries of REST API requests that hit the rate limit (HTTP 429) or a server error
+const restRetries = 5
+
+// ErrNotFound - requested REST API object does not exist (anymore)
+var ErrNotFound = errors.New("object not found")
+
+// RESTGet requests `target` URL with given headers, returns response headers and body
+// Requests hitting the rate limit or failing with a server error are retried (respecting Retry-After)
+// Returns ErrNotFound on HTTP 404 and error on other non 200 responses
+func RESTGet(client *http.Client, target string, headers map[string]string) (http.Header, []byte, error) {
+	wait := time.Second
+	for try := 0; ; try++ {
+		req, err := http.NewRequest("GET", target, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		data, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		if (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500) && try < restRetries {
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+				wait = time.Duration(seconds) * time.Second
+			}
+			Printf("%s: HTTP %d, retrying in %v\n", target, resp.StatusCode, wait)
+			time.Sleep(wait)
+			wait *= 2
+			continue
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return resp.Header, nil, ErrNotFound
+		}
+		if resp.StatusCode != http.StatusOK {
+			return resp.Header, nil, fmt.Errorf("%s: HTTP %d: %s", target, resp.StatusCode, string(data))
+		}
+		return resp.Header, data, nil
+	}
+}
+
+// readToken - API token can be given directly or as a file name to read it from (when it contains "/")
+func readToken(token string) string {
+	if !strings.Contains(token, "/") {
+		return token
+	}
+	data, err := ioutil.ReadFile(token)
+	FatalOnError(err)
+	return strings.TrimSpace(string(data))
+}
+
+// eventState - forge APIs return current issue and pull request states, payload should have a state from event time
+// Action is "opened", "reopened" or "closed" (not merged), other actions keep the current state
+func eventState(action string, issue *Issue, pr *PullRequest) {
+	open := action == "opened" || action == "reopened"
+	if issue != nil && open {
+		issue.State = "open"
+		issue.ClosedAt = nil
+	}
+	if pr != nil && open {
+		merged := false
+		pr.State = "open"
+		pr.ClosedAt = nil
+		pr.MergedAt = nil
+		pr.Merged = &merged
+		pr.MergedBy = nil
+	}
+	if pr != nil && action == "closed" {
+		merged := false
+		pr.State = "closed"
+		pr.MergedAt = nil
+		pr.Merged = &merged
+		pr.MergedBy = nil
+	}
+}
+
+// hourJSONs - GHA events as JSON lines grouped by hour of day (UTC)
+func hourJSONs(events []*Event) (map[int][]byte, error) {
+	hours := make(map[int]*bytes.Buffer)
+	for _, e := range events {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		hour := e.CreatedAt.UTC().Hour()
+		if _, ok := hours[hour]; !ok {
+			hours[hour] = &bytes.Buffer{}
+		}
+		hours[hour].Write(line)
+		hours[hour].WriteByte('\n')
+	}
+	jsons := make(map[int][]byte)
+	for hour, buffer := range hours {
+		jsons[hour] = buffer.Bytes()
+	}
+	return jsons, nil
+}
@@ -0,0 +1,154 @@
+package devstats
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Operations combining numeric fields of points rolled up into one point (retention.yaml `op`)
+const (
+	RollupAvg  = "avg"
+	RollupSum  = "sum"
+	RollupMin  = "min"
+	RollupMax  = "max"
+	RollupLast = "last"
+)
+
+// RetentionPolicy - series matching `Series` regexp keep their points for `Keep` periods (like "d90" - 90 days or "y2")
+// Older points are rolled up into one point per `Downsample` period (like "d") by `Op` (avg by default), or deleted without it
+type RetentionPolicy struct {
+	Series     string `yaml:"series"`
+	Keep       string `yaml:"keep"`
+	Downsample string `yaml:"downsample"`
+	Op         string `yaml:"op"`
+	re         *regexp.Regexp
+}
+
+// RetentionPolicies - policies from retention.yaml, series get the first policy matching their name
+type RetentionPolicies struct {
+	Policies []RetentionPolicy `yaml:"policies"`
+}
+
+var (
+	keepRe       = regexp.MustCompile(`^[hdwmqy][0-9]*$`)
+	downsampleRe = regexp.MustCompile(`^[hdwmqy]$`)
+)
+
+// Check validates all policies, it must be called before they are used
+func (r *RetentionPolicies) Check() error {
+	for i := range r.Policies {
+		p := &r.Policies[i]
+		re, err := regexp.Compile(p.Series)
+		if err != nil {
+			return fmt.Errorf("policy #%d: invalid series regexp '%s': %v", i+1, p.Series, err)
+		}
+		p.re = re
+		if !keepRe.MatchString(p.Keep) {
+			return fmt.Errorf("policy #%d: invalid keep '%s', use period and number of periods, like d90 or y2", i+1, p.Keep)
+		}
+		if p.Downsample != "" && !downsampleRe.MatchString(p.Downsample) {
+			return fmt.Errorf("policy #%d: invalid downsample '%s', use period: h, d, w, m, q or y", i+1, p.Downsample)
+		}
+		if p.Downsample == "" && p.Op != "" {
+			return fmt.Errorf("policy #%d: op '%s' needs downsample period", i+1, p.Op)
+		}
+		switch p.Op {
+		case "":
+			p.Op = RollupAvg
+		case RollupAvg, RollupSum, RollupMin, RollupMax, RollupLast:
+		default:
+			return fmt.Errorf("policy #%d: invalid op '%s', use: avg, sum, min, max or last", i+1, p.Op)
+		}
+	}
+	return nil
+}
+
+// Policy returns policy of series, nil when no policy matches it
+func (r *RetentionPolicies) Policy(series string) *RetentionPolicy {
+	for i := range r.Policies {
+		if r.Policies[i].re.MatchString(series) {
+			return &r.Policies[i]
+		}
+	}
+	return nil
+}
+
+// Cutoff returns time before which points are rolled up (or deleted) at `now`, periods start in time zone `loc`
+// With downsampling it is rounded down to downsample period start, so only whole periods are rolled up
+func (p *RetentionPolicy) Cutoff(now time.Time, loc *time.Location) time.Time {
+	_, _, start, next, prev := GetIntervalFunctionsIn(p.Keep[0:1], false, loc)
+	n := 1
+	if len(p.Keep) > 1 {
+		n, _ = strconv.Atoi(p.Keep[1:])
+	}
+	cutoff := AddNIntervals(start(now), -n, next, prev)
+	if p.Downsample != "" {
+		_, _, start, _, _ = GetIntervalFunctionsIn(p.Downsample, false, loc)
+		cutoff = start(cutoff)
+	}
+	return cutoff
+}
+
+// RollupPoint - series point (its fields) read for rolling up, numeric fields are float64
+type RollupPoint struct {
+	Time   time.Time
+	Fields map[string]interface{}
+}
+
+// Rollup returns one point per period (by `intervalStart`, like DayStart) at period's start, `points` must be sorted by time
+// Numeric fields are combined by `op`, other fields (like descriptions) get the last point's value
+// Periods that only have a point at their start are already rolled up, they are not returned
+func Rollup(points []RollupPoint, intervalStart func(time.Time) time.Time, op string) (result []RollupPoint) {
+	for i := 0; i < len(points); {
+		start := intervalStart(points[i].Time)
+		j := i + 1
+		for j < len(points) && intervalStart(points[j].Time).Equal(start) {
+			j++
+		}
+		group := points[i:j]
+		i = j
+		if len(group) == 1 && group[0].Time.Equal(start) {
+			continue
+		}
+		fields := make(map[string]interface{})
+		counts := make(map[string]int)
+		for _, point := range group {
+			for name, value := range point.Fields {
+				v, ok := value.(float64)
+				if !ok {
+					fields[name] = value
+					continue
+				}
+				prev, _ := fields[name].(float64)
+				counts[name]++
+				if counts[name] == 1 {
+					fields[name] = v
+					continue
+				}
+				switch op {
+				case RollupSum, RollupAvg:
+					fields[name] = prev + v
+				case RollupMin:
+					if v < prev {
+						fields[name] = v
+					}
+				case RollupMax:
+					if v > prev {
+						fields[name] = v
+					}
+				case RollupLast:
+					fields[name] = v
+				}
+			}
+		}
+		if op == RollupAvg {
+			for name, count := range counts {
+				fields[name] = fields[name].(float64) / float64(count)
+			}
+		}
+		result = append(result, RollupPoint{Time: start, Fields: fields})
+	}
+	return
+}
@@ -0,0 +1,142 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestRetentionPolicies(t *testing.T) {
+	ft := testlib.YMDHMS
+
+	policies := lib.RetentionPolicies{
+		Policies: []lib.RetentionPolicy{
+			{Series: "^events_h$", Keep: "w2"},
+			{Series: "_h$", Keep: "d90", Downsample: "d", Op: "sum"},
+			{Series: "_d$", Keep: "y", Downsample: "w"},
+		},
+	}
+	if err := policies.Check(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policies.Policies[2].Op != lib.RollupAvg {
+		t.Errorf("expected default op %s, got %s", lib.RollupAvg, policies.Policies[2].Op)
+	}
+
+	// Wednesday
+	now := ft(2018, 6, 13, 10, 30)
+
+	// Test cases
+	var testCases = []struct {
+		series   string
+		policy   int
+		expected time.Time
+	}{
+		{series: "events_h", policy: 1, expected: ft(2018, 5, 28)},
+		{series: "prs_opened_h", policy: 2, expected: ft(2018, 3, 15)},
+		{series: "prs_opened_d", policy: 3, expected: ft(2016, 12, 26)},
+		{series: "prs_opened_w", policy: 0},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		policy := policies.Policy(test.series)
+		if test.policy == 0 {
+			if policy != nil {
+				t.Errorf("test number %d, expected no policy, got %+v", index+1, policy)
+			}
+			continue
+		}
+		if policy != &policies.Policies[test.policy-1] {
+			t.Errorf("test number %d, expected policy #%d, got %+v", index+1, test.policy, policy)
+			continue
+		}
+		got := policy.Cutoff(now, time.UTC)
+		if got != test.expected {
+			t.Errorf("test number %d, expected cutoff %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestRetentionPoliciesCheck(t *testing.T) {
+	// Test cases
+	var testCases = []lib.RetentionPolicy{
+		{Series: "(", Keep: "d90"},
+		{Series: "_h$", Keep: "90d"},
+		{Series: "_h$", Keep: ""},
+		{Series: "_h$", Keep: "d90", Downsample: "d7"},
+		{Series: "_h$", Keep: "d90", Downsample: "d", Op: "median"},
+		{Series: "_h$", Keep: "d90", Op: "sum"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		policies := lib.RetentionPolicies{Policies: []lib.RetentionPolicy{test}}
+		if err := policies.Check(); err == nil {
+			t.Errorf("test number %d, expected error for %+v", index+1, test)
+		}
+	}
+}
+
+func TestRollup(t *testing.T) {
+	ft := testlib.YMDHMS
+	points := []lib.RollupPoint{
+		{Time: ft(2018, 1, 1, 0), Fields: map[string]interface{}{"value": 1.0, "descr": "a"}},
+		{Time: ft(2018, 1, 1, 5), Fields: map[string]interface{}{"value": 3.0, "descr": "b"}},
+		{Time: ft(2018, 1, 1, 7), Fields: map[string]interface{}{"value": 2.0, "other": 10.0}},
+		// Already rolled up day
+		{Time: ft(2018, 1, 2), Fields: map[string]interface{}{"value": 7.0}},
+		// Day without a point at its start
+		{Time: ft(2018, 1, 3, 12), Fields: map[string]interface{}{"value": 4.0}},
+	}
+
+	// Test cases
+	var testCases = []struct {
+		op       string
+		expected []lib.RollupPoint
+	}{
+		{
+			op: lib.RollupAvg,
+			expected: []lib.RollupPoint{
+				{Time: ft(2018, 1, 1), Fields: map[string]interface{}{"value": 2.0, "descr": "b", "other": 10.0}},
+				{Time: ft(2018, 1, 3), Fields: map[string]interface{}{"value": 4.0}},
+			},
+		},
+		{
+			op: lib.RollupSum,
+			expected: []lib.RollupPoint{
+				{Time: ft(2018, 1, 1), Fields: map[string]interface{}{"value": 6.0, "descr": "b", "other": 10.0}},
+				{Time: ft(2018, 1, 3), Fields: map[string]interface{}{"value": 4.0}},
+			},
+		},
+		{
+			op: lib.RollupMin,
+			expected: []lib.RollupPoint{
+				{Time: ft(2018, 1, 1), Fields: map[string]interface{}{"value": 1.0, "descr": "b", "other": 10.0}},
+				{Time: ft(2018, 1, 3), Fields: map[string]interface{}{"value": 4.0}},
+			},
+		},
+		{
+			op: lib.RollupMax,
+			expected: []lib.RollupPoint{
+				{Time: ft(2018, 1, 1), Fields: map[string]interface{}{"value": 3.0, "descr": "b", "other": 10.0}},
+				{Time: ft(2018, 1, 3), Fields: map[string]interface{}{"value": 4.0}},
+			},
+		},
+		{
+			op: lib.RollupLast,
+			expected: []lib.RollupPoint{
+				{Time: ft(2018, 1, 1), Fields: map[string]interface{}{"value": 2.0, "descr": "b", "other": 10.0}},
+				{Time: ft(2018, 1, 3), Fields: map[string]interface{}{"value": 4.0}},
+			},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.Rollup(points, lib.DayStart, test.op)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %+v, got %+v", index+1, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package devstats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scheduleSlack - `devstats` runs start a few minutes apart (cron start, previous projects' syncs), so interval is shortened by this
+const scheduleSlack = 5 * time.Minute
+
+// SyncSchedule - projects.yaml `sync_schedule`: how often `devstats` syncs the project
+// It is either an interval (like "3h") or a cron expression "minute hour day-of-month month day-of-week" in UTC (like "10 */6 * * *")
+// nil *SyncSchedule means project is synced on every `devstats` run
+type SyncSchedule struct {
+	spec     string
+	interval time.Duration
+	// minute, hour, day of month, month, day of week, nil means any value
+	fields [5]map[int]bool
+}
+
+// cronFields - allowed ranges of cron expression fields
+var cronFields = [5]struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseSyncSchedule parses project's `sync_schedule`, returns nil when it is empty
+func ParseSyncSchedule(spec string) (*SyncSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	s := &SyncSchedule{spec: spec}
+	parts := strings.Fields(spec)
+	if len(parts) == 1 {
+		interval, err := time.ParseDuration(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sync schedule '%s', expected interval (like \"3h\") or cron expression: %v", spec, err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("invalid sync schedule '%s', interval must be positive", spec)
+		}
+		s.interval = interval
+		return s, nil
+	}
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("invalid sync schedule '%s', cron expression needs 5 fields: minute hour day-of-month month day-of-week", spec)
+	}
+	for i, part := range parts {
+		values, err := parseCronField(part, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sync schedule '%s', %s: %v", spec, cronFields[i].name, err)
+		}
+		s.fields[i] = values
+	}
+	// Sunday is both 0 and 7
+	if dow := s.fields[4]; dow != nil && dow[7] {
+		dow[0] = true
+	}
+	return s, nil
+}
+
+// parseCronField parses single cron field: "*", "5", "1-5", "*/15", "0-30/10" or comma separated list of them
+// Returns nil for "*" (any value)
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	if field == "*" {
+		return nil, nil
+	}
+	values := make(map[int]bool)
+	for _, item := range strings.Split(field, ",") {
+		rng, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			rng = item[:i]
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in '%s'", item)
+			}
+		}
+		from, to := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			from, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value in '%s'", item)
+			}
+			to = from
+			if len(bounds) > 1 {
+				to, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid value in '%s'", item)
+				}
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("'%s' is out of range %d-%d", item, min, max)
+		}
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// matches - does cron expression match minute `dt` (UTC)?
+// When both day of month and day of week are set, either of them can match (like in cron)
+func (s *SyncSchedule) matches(dt time.Time) bool {
+	dt = dt.UTC()
+	in := func(i, v int) bool { return s.fields[i] == nil || s.fields[i][v] }
+	if !in(0, dt.Minute()) || !in(1, dt.Hour()) || !in(3, int(dt.Month())) {
+		return false
+	}
+	if s.fields[2] != nil && s.fields[4] != nil {
+		return in(2, dt.Day()) || in(4, int(dt.Weekday()))
+	}
+	return in(2, dt.Day()) && in(4, int(dt.Weekday()))
+}
+
+// Due - should project last synced at `last` be synced at `now`?
+// Interval schedule is due when interval passed since `last`, cron schedule when any of its times is after `last`
+// Project never synced (zero `last`) is always due
+func (s *SyncSchedule) Due(last, now time.Time) bool {
+	if s == nil || last.IsZero() {
+		return true
+	}
+	if s.interval > 0 {
+		return now.Sub(last) >= s.interval-scheduleSlack
+	}
+	// Expressions matching rarely (or never, like "0 0 30 2 *") are due at least once a year
+	if now.Sub(last) > 366*24*time.Hour {
+		return true
+	}
+	for dt := last.Truncate(time.Minute).Add(time.Minute); !dt.After(now); dt = dt.Add(time.Minute) {
+		if s.matches(dt) {
+			return true
+		}
+	}
+	return false
+}
+
+// CronSpec returns cron expression running the schedule's syncs, like Kubernetes CronJob schedule (in UTC)
+// Interval schedule starts at `minute` past the hour (so projects can start at different minutes), cron schedule is returned as it is
+// Intervals must divide an hour or a day, nil schedule (synced on every `devstats` run) is hourly
+func (s *SyncSchedule) CronSpec(minute int) (string, error) {
+	minute %= 60
+	if s == nil {
+		return fmt.Sprintf("%d * * * *", minute), nil
+	}
+	if s.interval == 0 {
+		return s.spec, nil
+	}
+	switch {
+	case s.interval%time.Hour == 0 && s.interval <= 24*time.Hour && (24*time.Hour)%s.interval == 0:
+		hours := int(s.interval / time.Hour)
+		switch hours {
+		case 1:
+			return fmt.Sprintf("%d * * * *", minute), nil
+		case 24:
+			return fmt.Sprintf("%d 0 * * *", minute), nil
+		}
+		return fmt.Sprintf("%d */%d * * *", minute, hours), nil
+	case s.interval%time.Minute == 0 && s.interval < time.Hour && time.Hour%s.interval == 0:
+		minutes := int(s.interval / time.Minute)
+		return fmt.Sprintf("%d-59/%d * * * *", minute%minutes, minutes), nil
+	}
+	return "", fmt.Errorf("sync schedule '%s' cannot be run by cron, interval must divide an hour or a day, use cron expression instead", s.spec)
+}
+
+func (s *SyncSchedule) String() string {
+	if s == nil {
+		return "every run"
+	}
+	return s.spec
+}
@@ -0,0 +1,136 @@
+package devstats
+
+import (
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestParseSyncSchedule(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		spec  string
+		isNil bool
+		ok    bool
+	}{
+		{spec: "", isNil: true, ok: true},
+		{spec: "  ", isNil: true, ok: true},
+		{spec: "3h", ok: true},
+		{spec: "90m", ok: true},
+		{spec: "10 */6 * * *", ok: true},
+		{spec: "0,30 0-12/3 1 1-6 0,7", ok: true},
+		{spec: "-1h"},
+		{spec: "0s"},
+		{spec: "daily"},
+		{spec: "10 */6 * *"},
+		{spec: "60 * * * *"},
+		{spec: "* 24 * * *"},
+		{spec: "* * 0 * *"},
+		{spec: "* * * 13 *"},
+		{spec: "* * * * 8"},
+		{spec: "*/0 * * * *"},
+		{spec: "5-1 * * * *"},
+		{spec: "a * * * *"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.ParseSyncSchedule(test.spec)
+		if (err == nil) != test.ok {
+			t.Errorf("test number %d, expected ok %v, got error %v, spec: '%s'", index+1, test.ok, err, test.spec)
+			continue
+		}
+		if test.ok && (got == nil) != test.isNil {
+			t.Errorf("test number %d, expected nil %v, got %v, spec: '%s'", index+1, test.isNil, got, test.spec)
+		}
+	}
+}
+
+func TestSyncScheduleDue(t *testing.T) {
+	// 2017-08-02 is Wednesday
+	dt := func(day, hour, minute int) time.Time {
+		return time.Date(2017, 8, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	// Test cases
+	var testCases = []struct {
+		spec     string
+		last     time.Time
+		now      time.Time
+		expected bool
+	}{
+		{spec: "", last: dt(2, 1, 10), now: dt(2, 2, 10), expected: true},
+		{spec: "6h", last: time.Time{}, now: dt(2, 2, 10), expected: true},
+		{spec: "6h", last: dt(2, 1, 10), now: dt(2, 6, 10), expected: false},
+		{spec: "6h", last: dt(2, 1, 10), now: dt(2, 7, 10), expected: true},
+		{spec: "6h", last: dt(2, 1, 10), now: dt(2, 7, 7), expected: true},
+		{spec: "6h", last: dt(2, 1, 10), now: dt(2, 7, 0), expected: false},
+		{spec: "0 */6 * * *", last: dt(2, 1, 10), now: dt(2, 5, 10), expected: false},
+		{spec: "0 */6 * * *", last: dt(2, 1, 10), now: dt(2, 6, 10), expected: true},
+		{spec: "0 */6 * * *", last: dt(2, 6, 0), now: dt(2, 6, 10), expected: false},
+		{spec: "0 */6 * * *", last: dt(2, 5, 59), now: dt(2, 6, 0), expected: true},
+		{spec: "10 2 * * 0", last: dt(2, 2, 10), now: dt(5, 23, 10), expected: false},
+		{spec: "10 2 * * 0", last: dt(2, 2, 10), now: dt(6, 2, 10), expected: true},
+		{spec: "10 2 * * 7", last: dt(2, 2, 10), now: dt(6, 2, 10), expected: true},
+		{spec: "10 2 15 * 0", last: dt(2, 2, 10), now: dt(5, 23, 10), expected: false},
+		{spec: "10 2 15 * 0", last: dt(7, 2, 10), now: dt(12, 23, 10), expected: false},
+		{spec: "10 2 15 * 6", last: dt(7, 2, 10), now: dt(12, 23, 10), expected: true},
+		{spec: "10 2 15 * 0", last: dt(14, 2, 10), now: dt(15, 2, 10), expected: true},
+		{spec: "0 0 30 2 *", last: dt(2, 2, 10), now: dt(2, 2, 10).AddDate(1, 0, 2), expected: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		schedule, err := lib.ParseSyncSchedule(test.spec)
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		got := schedule.Due(test.last, test.now)
+		if got != test.expected {
+			t.Errorf(
+				"test number %d, expected %v, got %v, schedule: '%s', last: %v, now: %v",
+				index+1, test.expected, got, test.spec, test.last, test.now,
+			)
+		}
+	}
+}
+
+func TestSyncScheduleCronSpec(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		spec     string
+		minute   int
+		expected string
+		ok       bool
+	}{
+		{spec: "", minute: 8, expected: "8 * * * *", ok: true},
+		{spec: "", minute: 67, expected: "7 * * * *", ok: true},
+		{spec: "1h", minute: 5, expected: "5 * * * *", ok: true},
+		{spec: "6h", minute: 14, expected: "14 */6 * * *", ok: true},
+		{spec: "24h", minute: 30, expected: "30 0 * * *", ok: true},
+		{spec: "15m", minute: 20, expected: "5-59/15 * * * *", ok: true},
+		{spec: "30m", minute: 0, expected: "0-59/30 * * * *", ok: true},
+		{spec: "10 */6 * * *", minute: 14, expected: "10 */6 * * *", ok: true},
+		{spec: "5h", minute: 0},
+		{spec: "48h", minute: 0},
+		{spec: "90m", minute: 0},
+		{spec: "7m", minute: 0},
+		{spec: "30s", minute: 0},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		schedule, err := lib.ParseSyncSchedule(test.spec)
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		got, err := schedule.CronSpec(test.minute)
+		if (err == nil) != test.ok {
+			t.Errorf("test number %d, expected ok %v, got error %v, spec: '%s'", index+1, test.ok, err, test.spec)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("test number %d, expected '%s', got '%s', spec: '%s'", index+1, test.expected, got, test.spec)
+		}
+	}
+}
@@ -0,0 +1,2 @@
+#!/bin/sh
+sudo -u postgres psql devstats < util_sql/sync_status_timeouts.sql
@@ -0,0 +1,13 @@
+#!/bin/sh
+sudo -u postgres psql gha < util_sql/tables_parse_errors.sql
+sudo -u postgres psql prometheus < util_sql/tables_parse_errors.sql
+sudo -u postgres psql opentracing < util_sql/tables_parse_errors.sql
+sudo -u postgres psql fluentd < util_sql/tables_parse_errors.sql
+sudo -u postgres psql linkerd < util_sql/tables_parse_errors.sql
+sudo -u postgres psql grpc < util_sql/tables_parse_errors.sql
+sudo -u postgres psql coredns < util_sql/tables_parse_errors.sql
+sudo -u postgres psql containerd < util_sql/tables_parse_errors.sql
+sudo -u postgres psql rkt < util_sql/tables_parse_errors.sql
+sudo -u postgres psql cni < util_sql/tables_parse_errors.sql
+sudo -u postgres psql envoy < util_sql/tables_parse_errors.sql
+sudo -u postgres psql cncf < util_sql/tables_parse_errors.sql
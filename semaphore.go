@@ -0,0 +1,90 @@
+package devstats
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"syscall"
+	"time"
+)
+
+// semaphorePoll - how often Acquire checks for a free slot
+const semaphorePoll = 200 * time.Millisecond
+
+// semaphoreNameRe - characters not allowed in slot file names
+var semaphoreNameRe = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// Semaphore - counting semaphore shared by all processes on this host
+// GetThreadsNum limits threads of a single tool, but parallel syncs run many tools at once (like `db2influx` of every project)
+// Every slot is a lock file held with flock, kernel releases slots of a process that crashed
+type Semaphore struct {
+	name  string
+	slots []string
+}
+
+// NewSemaphore creates semaphore `name` with `n` slots, semaphores with the same name share slots in all processes
+// n <= 0 means no limit, Acquire never waits then
+func NewSemaphore(name string, n int) *Semaphore {
+	s := &Semaphore{name: name}
+	base := filepath.Join(os.TempDir(), "devstats_sem_"+semaphoreNameRe.ReplaceAllString(name, "_"))
+	for i := 0; i < n; i++ {
+		s.slots = append(s.slots, fmt.Sprintf("%s_%d.lock", base, i))
+	}
+	return s
+}
+
+// DBSemaphore returns semaphore limiting queries run at once against `ctx.PgDB` database (GHA2DB_DB_PARALLEL)
+func DBSemaphore(ctx *Ctx) *Semaphore {
+	return NewSemaphore("db_"+ctx.PgHost+"_"+ctx.PgPort+"_"+ctx.PgDB, ctx.DBParallel)
+}
+
+// GitSemaphore returns semaphore limiting git operations run at once (GHA2DB_GIT_PARALLEL)
+func GitSemaphore(ctx *Ctx) *Semaphore {
+	return NewSemaphore("git", ctx.GitParallel)
+}
+
+// tryLock tries to take slot `path`, returns nil (and no error) when it is held by someone else
+func tryLock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == nil {
+		return f, nil
+	}
+	_ = f.Close()
+	if err == syscall.EWOULDBLOCK {
+		return nil, nil
+	}
+	return nil, err
+}
+
+// Acquire waits for a free slot, returns function releasing it
+// Returns `runCtx` error when it is cancelled (or its deadline passes) while waiting
+func (s *Semaphore) Acquire(runCtx context.Context) (func(), error) {
+	if len(s.slots) == 0 {
+		return func() {}, nil
+	}
+	// Processes start from different slots, so they don't all try the first one
+	start := os.Getpid() % len(s.slots)
+	for {
+		for i := range s.slots {
+			f, err := tryLock(s.slots[(start+i)%len(s.slots)])
+			if err != nil {
+				return nil, err
+			}
+			if f != nil {
+				// Closing the file releases the lock
+				return func() { _ = f.Close() }, nil
+			}
+		}
+		select {
+		case <-time.After(semaphorePoll):
+		case <-runCtx.Done():
+			return nil, fmt.Errorf("waiting for %s semaphore: %v", s.name, runCtx.Err())
+		}
+	}
+}
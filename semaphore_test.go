@@ -0,0 +1,78 @@
+package devstats
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestSemaphore(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		slots   int
+		workers int
+		maxRun  int
+	}{
+		{slots: 1, workers: 4, maxRun: 1},
+		{slots: 2, workers: 6, maxRun: 2},
+		{slots: 0, workers: 3, maxRun: 3},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		name := fmt.Sprintf("test_%d_%d", os.Getpid(), index)
+		var (
+			mtx     sync.Mutex
+			wg      sync.WaitGroup
+			running int
+			maxRun  int
+		)
+		for i := 0; i < test.workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Every worker uses its own semaphore, like separate processes do
+				release, err := lib.NewSemaphore(name, test.slots).Acquire(context.Background())
+				if err != nil {
+					t.Errorf("test number %d, unexpected error: %v", index+1, err)
+					return
+				}
+				mtx.Lock()
+				running++
+				if running > maxRun {
+					maxRun = running
+				}
+				mtx.Unlock()
+				time.Sleep(50 * time.Millisecond)
+				mtx.Lock()
+				running--
+				mtx.Unlock()
+				release()
+			}()
+		}
+		wg.Wait()
+		if maxRun != test.maxRun {
+			t.Errorf("test number %d, expected %d workers running at once, got %d", index+1, test.maxRun, maxRun)
+		}
+	}
+}
+
+func TestSemaphoreCancel(t *testing.T) {
+	// Waiting for a slot should stop when context is cancelled
+	name := fmt.Sprintf("test_cancel_%d", os.Getpid())
+	release, err := lib.NewSemaphore(name, 1).Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	_, err = lib.NewSemaphore(name, 1).Acquire(ctx)
+	if err == nil {
+		t.Errorf("expected error when all slots are held and context is cancelled")
+	}
+}
@@ -0,0 +1,48 @@
+package devstats
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// SeriesRename - series `From` copied (or renamed) to `To`
+type SeriesRename struct {
+	From string
+	To   string
+}
+
+// SeriesRenames returns new names of `series` matching `pattern` regexp, `replacement` can use its groups (like "new_$1")
+// Returns error when new names collide with each other or with existing series (`series` are all existing series),
+// so copying or renaming them never writes into a series that has points
+func SeriesRenames(series []string, pattern, replacement string) ([]SeriesRename, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]struct{})
+	for _, name := range series {
+		existing[name] = struct{}{}
+	}
+	sources := make(map[string]string)
+	result := []SeriesRename{}
+	for _, name := range series {
+		if !re.MatchString(name) {
+			continue
+		}
+		to := re.ReplaceAllString(name, replacement)
+		if to == "" || to == name {
+			return nil, fmt.Errorf("series '%s' would be renamed to '%s'", name, to)
+		}
+		if _, ok := existing[to]; ok {
+			return nil, fmt.Errorf("series '%s' would be renamed to existing series '%s'", name, to)
+		}
+		if from, ok := sources[to]; ok {
+			return nil, fmt.Errorf("series '%s' and '%s' would both be renamed to '%s'", from, name, to)
+		}
+		sources[to] = name
+		result = append(result, SeriesRename{From: name, To: to})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].From < result[j].From })
+	return result, nil
+}
@@ -0,0 +1,57 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestSeriesRenames(t *testing.T) {
+	series := []string{"prs_opened_w", "prs_opened_d", "prs_merged_d", "reviews_d", "old_d"}
+
+	// Test cases
+	var testCases = []struct {
+		pattern     string
+		replacement string
+		expected    []lib.SeriesRename
+		err         bool
+	}{
+		{
+			pattern:     "^prs_opened_(.*)$",
+			replacement: "prs_created_$1",
+			expected: []lib.SeriesRename{
+				{From: "prs_opened_d", To: "prs_created_d"},
+				{From: "prs_opened_w", To: "prs_created_w"},
+			},
+		},
+		{pattern: "^reviews_d$", replacement: "pr_reviews_d", expected: []lib.SeriesRename{{From: "reviews_d", To: "pr_reviews_d"}}},
+		{pattern: "^nothing", replacement: "x", expected: []lib.SeriesRename{}},
+		// Invalid regexp
+		{pattern: "(", replacement: "x", err: true},
+		// Existing series
+		{pattern: "^reviews_d$", replacement: "old_d", err: true},
+		// Two series to one
+		{pattern: "^prs_(opened|merged)_d$", replacement: "prs_d", err: true},
+		// Not renamed
+		{pattern: "^reviews_(.*)$", replacement: "reviews_$1", err: true},
+		{pattern: "^reviews_d$", replacement: "", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.SeriesRenames(series, test.pattern, test.replacement)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got %+v", index+1, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %+v, got %+v", index+1, test.expected, got)
+		}
+	}
+}
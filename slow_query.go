@@ -0,0 +1,67 @@
+package devstats
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// ExplainIfSlow saves EXPLAIN (ANALYZE, BUFFERS) of `query` in `gha_slow_queries` when it took longer than GHA2DB_EXPLAIN_SLOW
+// `name` is query's source (like metric's SQL file), EXPLAIN ANALYZE executes the query again, so every tool and name
+// is explained at most once a day. Failures are only logged, they don't fail the query
+func ExplainIfSlow(con *sql.DB, ctx *Ctx, tool, name, query string, took time.Duration) {
+	if ctx.ExplainSlow <= 0 || took < time.Duration(ctx.ExplainSlow)*time.Second {
+		return
+	}
+	var n int
+	err := QueryRowSQL(
+		con,
+		ctx,
+		"select count(*) from gha_slow_queries where tool = "+NValue(1)+" and name = "+NValue(2)+" and dt > now() - '1 day'::interval",
+		tool,
+		name,
+	).Scan(&n)
+	if err != nil {
+		Printf("Cannot check slow queries: %v\n", err)
+		return
+	}
+	if n > 0 {
+		return
+	}
+	Printf("%s: %s took %v, explaining it\n", tool, name, took)
+	rows, err := QuerySQL(con, ctx, "explain (analyze, buffers) "+query)
+	if err != nil {
+		Printf("Cannot explain slow query %s: %v\n", name, err)
+		return
+	}
+	defer func() { _ = rows.Close() }()
+	plan := []string{}
+	for rows.Next() {
+		var line string
+		err = rows.Scan(&line)
+		if err != nil {
+			Printf("Cannot explain slow query %s: %v\n", name, err)
+			return
+		}
+		plan = append(plan, line)
+	}
+	if err = rows.Err(); err != nil {
+		Printf("Cannot explain slow query %s: %v\n", name, err)
+		return
+	}
+	_, err = ExecSQL(
+		con,
+		ctx,
+		"insert into gha_slow_queries(tool, name, hash, query, took_ms, explain, dt) "+NValues(7),
+		tool,
+		TruncToBytes(name, 200),
+		QueryHash(query),
+		query,
+		int64(took/time.Millisecond),
+		strings.Join(plan, "\n"),
+		time.Now(),
+	)
+	if err != nil {
+		Printf("Cannot save slow query %s: %v\n", name, err)
+	}
+}
@@ -0,0 +1,83 @@
+package devstats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Smoothing - smoothed companion of a series: moving average of the last `Points` values ("ma7")
+// or exponential moving average with smoothing factor `Alpha` ("ema0.3"), exactly one of them is set
+type Smoothing struct {
+	Points int
+	Alpha  float64
+}
+
+// ParseSmoothing parses comma separated smoothings, like "ma7,ema0.3"
+// Moving average needs at least 2 points, EMA's alpha must be in (0, 1]
+func ParseSmoothing(s string) ([]Smoothing, error) {
+	smoothings := []Smoothing{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		switch {
+		case item == "":
+			continue
+		case strings.HasPrefix(item, "ema"):
+			alpha, err := strconv.ParseFloat(item[3:], 64)
+			if err != nil || alpha <= 0 || alpha > 1 {
+				return nil, fmt.Errorf("invalid smoothing '%s', EMA alpha must be a number in (0, 1], like ema0.3", item)
+			}
+			smoothings = append(smoothings, Smoothing{Alpha: alpha})
+		case strings.HasPrefix(item, "ma"):
+			points, err := strconv.Atoi(item[2:])
+			if err != nil || points < 2 {
+				return nil, fmt.Errorf("invalid smoothing '%s', moving average needs at least 2 points, like ma7", item)
+			}
+			smoothings = append(smoothings, Smoothing{Points: points})
+		default:
+			return nil, fmt.Errorf("invalid smoothing '%s', use maN (moving average of N points) or emaA (EMA with alpha A)", item)
+		}
+	}
+	if len(smoothings) == 0 {
+		return nil, fmt.Errorf("no smoothings in '%s'", s)
+	}
+	return smoothings, nil
+}
+
+// Suffix returns smoothed series name suffix, like "ma7" or "ema0_3"
+func (s Smoothing) Suffix() string {
+	if s.Points > 0 {
+		return "ma" + strconv.Itoa(s.Points)
+	}
+	return "ema" + strings.Replace(strconv.FormatFloat(s.Alpha, 'f', -1, 64), ".", "_", -1)
+}
+
+// Smooth returns smoothed `values` (ordered by time), `prev` is the smoothed value before the first one (EMA only, nil - none)
+// Moving average of the first values is computed from fewer points, EMA starts from the first value when there is no `prev`
+func (s Smoothing) Smooth(values []float64, prev *float64) []float64 {
+	smoothed := make([]float64, len(values))
+	if s.Points > 0 {
+		sum := 0.0
+		for i, value := range values {
+			sum += value
+			n := i + 1
+			if n > s.Points {
+				sum -= values[i-s.Points]
+				n = s.Points
+			}
+			smoothed[i] = sum / float64(n)
+		}
+		return smoothed
+	}
+	for i, value := range values {
+		switch {
+		case i > 0:
+			smoothed[i] = s.Alpha*value + (1-s.Alpha)*smoothed[i-1]
+		case prev != nil:
+			smoothed[i] = s.Alpha*value + (1-s.Alpha)*(*prev)
+		default:
+			smoothed[i] = value
+		}
+	}
+	return smoothed
+}
@@ -0,0 +1,85 @@
+package devstats
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestParseSmoothing(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		s        string
+		expected []lib.Smoothing
+		suffixes []string
+		err      bool
+	}{
+		{s: "ma7", expected: []lib.Smoothing{{Points: 7}}, suffixes: []string{"ma7"}},
+		{s: " MA7, ema0.3 ", expected: []lib.Smoothing{{Points: 7}, {Alpha: 0.3}}, suffixes: []string{"ma7", "ema0_3"}},
+		{s: "ema1,,ma28", expected: []lib.Smoothing{{Alpha: 1}, {Points: 28}}, suffixes: []string{"ema1", "ma28"}},
+		{s: "", err: true},
+		{s: "ma1", err: true},
+		{s: "ma", err: true},
+		{s: "ema0", err: true},
+		{s: "ema1.5", err: true},
+		{s: "median5", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.ParseSmoothing(test.s)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error for '%s', got %+v", index+1, test.s, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %+v, got %+v", index+1, test.expected, got)
+			continue
+		}
+		for i, s := range got {
+			if s.Suffix() != test.suffixes[i] {
+				t.Errorf("test number %d, expected suffix '%s', got '%s'", index+1, test.suffixes[i], s.Suffix())
+			}
+		}
+	}
+}
+
+func TestSmooth(t *testing.T) {
+	prev := 10.0
+
+	// Test cases
+	var testCases = []struct {
+		smoothing lib.Smoothing
+		values    []float64
+		prev      *float64
+		expected  []float64
+	}{
+		{smoothing: lib.Smoothing{Points: 3}, values: []float64{}, expected: []float64{}},
+		{smoothing: lib.Smoothing{Points: 3}, values: []float64{3, 6, 9, 0, 3}, expected: []float64{3, 4.5, 6, 5, 4}},
+		{smoothing: lib.Smoothing{Points: 2}, values: []float64{1, 2, 3}, prev: &prev, expected: []float64{1, 1.5, 2.5}},
+		{smoothing: lib.Smoothing{Alpha: 0.5}, values: []float64{4, 8, 0}, expected: []float64{4, 6, 3}},
+		{smoothing: lib.Smoothing{Alpha: 0.5}, values: []float64{4, 8}, prev: &prev, expected: []float64{7, 7.5}},
+		{smoothing: lib.Smoothing{Alpha: 1}, values: []float64{4, 8}, prev: &prev, expected: []float64{4, 8}},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := test.smoothing.Smooth(test.values, test.prev)
+		if len(got) != len(test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+			continue
+		}
+		for i := range got {
+			if math.Abs(got[i]-test.expected[i]) > 1e-9 {
+				t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+				break
+			}
+		}
+	}
+}
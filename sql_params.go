@@ -0,0 +1,194 @@
+package devstats
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// SQL parameter types, value is checked and formatted by SQLParamValue
+const (
+	SQLParamDate   = "date"   // any date TimeParseAny accepts, written as YYYY-MM-DD HH:MM:SS (SQL quotes it: '{{from:date}}')
+	SQLParamInt    = "int"    // integer
+	SQLParamFloat  = "float"  // number
+	SQLParamIdent  = "ident"  // identifier (like alias.column), parts are double quoted when needed
+	SQLParamString = "string" // text used inside SQL quotes, single quotes are doubled
+)
+
+// sqlParamTypes - known parameter types
+var sqlParamTypes = map[string]struct{}{
+	SQLParamDate:   {},
+	SQLParamInt:    {},
+	SQLParamFloat:  {},
+	SQLParamIdent:  {},
+	SQLParamString: {},
+}
+
+// sqlParamRe - placeholder {{name}} or typed placeholder {{name:type}}
+// {{period:alias.column}} (quick ranges) is not a typed placeholder, because "alias.column" is not a type
+var sqlParamRe = regexp.MustCompile(`{{([a-zA-Z_][a-zA-Z0-9_]*)(?::(date|int|float|ident|string))?}}`)
+
+// sqlParamDeclRe - parameter declared in SQL file's header comment: "-- @param name[:type] [= default]"
+var sqlParamDeclRe = regexp.MustCompile(`^--\s*@param\s+([a-zA-Z_][a-zA-Z0-9_]*)(?::([a-z]+))?\s*(?:=\s*(.*?))?\s*$`)
+
+// simpleIdentRe - identifier that doesn't need quoting
+var simpleIdentRe = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// SQLParam - parameter declared in SQL file's header, parameters without type are replaced as they are
+type SQLParam struct {
+	Type    string
+	Default *string
+}
+
+// ParseSQLParams returns parameters declared in header (leading "--" comment lines) of `sql`
+// Default value can be quoted with single or double quotes, to keep leading or trailing spaces
+func ParseSQLParams(sql string) (map[string]SQLParam, error) {
+	params := make(map[string]SQLParam)
+	for _, line := range strings.Split(sql, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		if !strings.Contains(line, "@param") {
+			continue
+		}
+		match := sqlParamDeclRe.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("invalid parameter declaration '%s', use: -- @param name[:type] [= default]", line)
+		}
+		param := SQLParam{Type: match[2]}
+		if _, ok := sqlParamTypes[param.Type]; param.Type != "" && !ok {
+			return nil, fmt.Errorf("unknown parameter type '%s' in '%s', use: date, int, float, ident or string", param.Type, line)
+		}
+		if strings.Contains(line, "=") {
+			def := match[3]
+			if len(def) >= 2 && (def[0] == '\'' || def[0] == '"') && def[len(def)-1] == def[0] {
+				def = def[1 : len(def)-1]
+			}
+			param.Default = &def
+		}
+		params[match[1]] = param
+	}
+	return params, nil
+}
+
+// quoteIdent returns identifier part double quoted when it is not a simple lowercase identifier
+func quoteIdent(ident string) string {
+	if simpleIdentRe.MatchString(ident) {
+		return ident
+	}
+	return `"` + strings.Replace(ident, `"`, `""`, -1) + `"`
+}
+
+// SQLParamValue checks `value` of parameter with type `typ` and returns it formatted for SQL ("" type - value as it is)
+func SQLParamValue(typ, value string) (string, error) {
+	switch typ {
+	case "":
+		return value, nil
+	case SQLParamDate:
+		dt, err := TimeParseAnyWithErr(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("invalid date '%s'", value)
+		}
+		return ToYMDHMSDate(dt), nil
+	case SQLParamInt:
+		i, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid integer '%s'", value)
+		}
+		return strconv.FormatInt(i, 10), nil
+	case SQLParamFloat:
+		value = strings.TrimSpace(value)
+		if _, err := strconv.ParseFloat(value, 64); err != nil || strings.ContainsAny(value, "nNiI") {
+			return "", fmt.Errorf("invalid number '%s'", value)
+		}
+		return value, nil
+	case SQLParamIdent:
+		parts := strings.Split(strings.TrimSpace(value), ".")
+		for i, part := range parts {
+			if part == "" {
+				return "", fmt.Errorf("invalid identifier '%s'", value)
+			}
+			parts[i] = quoteIdent(part)
+		}
+		return strings.Join(parts, "."), nil
+	case SQLParamString:
+		return strings.Replace(value, "'", "''", -1), nil
+	}
+	return "", fmt.Errorf("unknown parameter type '%s', use: date, int, float, ident or string", typ)
+}
+
+// ApplySQLParams replaces {{name}} and {{name:type}} placeholders of `declared` and given parameters with their `values`
+// (or declared defaults), typed values are checked and formatted. Type in placeholder takes precedence over declared one
+// Returns error listing missing values of declared (or typed) parameters, other placeholders are kept
+func ApplySQLParams(sql string, declared map[string]SQLParam, values map[string]string) (string, error) {
+	var errs []string
+	missing := make(map[string]struct{})
+	sql = sqlParamRe.ReplaceAllStringFunc(sql, func(placeholder string) string {
+		match := sqlParamRe.FindStringSubmatch(placeholder)
+		name, typ := match[1], match[2]
+		param, isDeclared := declared[name]
+		if typ == "" {
+			typ = param.Type
+		}
+		value, ok := values[name]
+		if !ok && param.Default != nil {
+			value, ok = *param.Default, true
+		}
+		if !ok {
+			if isDeclared || typ != "" {
+				missing[name] = struct{}{}
+			}
+			return placeholder
+		}
+		res, err := SQLParamValue(typ, value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			return placeholder
+		}
+		return res
+	})
+	if len(missing) > 0 {
+		names := []string{}
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		errs = append(errs, "missing values of parameters: "+strings.Join(names, ", "))
+	}
+	if len(errs) > 0 {
+		return "", fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return sql, nil
+}
+
+// SQLParamName returns parameter name when `key` is a placeholder like "{{name}}"
+func SQLParamName(key string) (string, bool) {
+	match := sqlParamRe.FindStringSubmatch(key)
+	if match == nil || match[0] != key || match[2] != "" {
+		return "", false
+	}
+	return match[1], true
+}
+
+// ReadSQLParamsFile reads parameter values from YAML (or JSON) file with `name: value` pairs
+func ReadSQLParamsFile(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	err = yaml.Unmarshal(data, &values)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return values, nil
+}
@@ -0,0 +1,203 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestParseSQLParams(t *testing.T) {
+	def := func(s string) *string { return &s }
+
+	// Test cases
+	var testCases = []struct {
+		sql      string
+		expected map[string]lib.SQLParam
+		err      bool
+	}{
+		{sql: "select 1", expected: map[string]lib.SQLParam{}},
+		{
+			sql: "-- Metric\n-- @param from:date = 2014-01-01\n--@param lim:int=10\n\n-- @param rg\n-- @param s:string = ' a '\nselect 1\n-- @param after:int\n",
+			expected: map[string]lib.SQLParam{
+				"from": {Type: "date", Default: def("2014-01-01")},
+				"lim":  {Type: "int", Default: def("10")},
+				"rg":   {},
+				"s":    {Type: "string", Default: def(" a ")},
+			},
+		},
+		{sql: "-- @param e =\nselect 1", expected: map[string]lib.SQLParam{"e": {Default: def("")}}},
+		{sql: "-- @param x:bool = 1\nselect 1", err: true},
+		{sql: "-- @param 1x\nselect 1", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.ParseSQLParams(test.sql)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got %+v", index+1, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %+v, got %+v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestSQLParamValue(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		typ      string
+		value    string
+		expected string
+		err      bool
+	}{
+		{typ: "", value: " any 'text' ", expected: " any 'text' "},
+		{typ: "date", value: "2018-02", expected: "2018-02-01 00:00:00"},
+		{typ: "date", value: "2018-02-03 04:05:06", expected: "2018-02-03 04:05:06"},
+		{typ: "date", value: "yesterday", err: true},
+		{typ: "int", value: " 42 ", expected: "42"},
+		{typ: "int", value: "1; drop table x", err: true},
+		{typ: "float", value: "-1.5e3", expected: "-1.5e3"},
+		{typ: "float", value: "NaN", err: true},
+		{typ: "ident", value: "ev.created_at", expected: "ev.created_at"},
+		{typ: "ident", value: `Repo Group.a"b`, expected: `"Repo Group"."a""b"`},
+		{typ: "ident", value: "a..b", err: true},
+		{typ: "string", value: "O'Reilly", expected: "O''Reilly"},
+		{typ: "bool", value: "1", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.SQLParamValue(test.typ, test.value)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got '%s'", index+1, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("test number %d, expected '%s', got '%s'", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestApplySQLParams(t *testing.T) {
+	def := func(s string) *string { return &s }
+	declared := map[string]lib.SQLParam{
+		"from": {Type: "date", Default: def("2014")},
+		"lim":  {Type: "int"},
+	}
+
+	// Test cases
+	var testCases = []struct {
+		sql      string
+		values   map[string]string
+		expected string
+		err      bool
+	}{
+		{
+			sql:      "'{{from}}' {{lim}} {{n}} {{x}} {{period:e.created_at}} {{exclude_bots}}",
+			values:   map[string]string{"lim": "5", "n": "1.0"},
+			expected: "'2014-01-01 00:00:00' 5 1.0 {{x}} {{period:e.created_at}} {{exclude_bots}}",
+		},
+		{
+			sql:      "'{{from}}' '{{to:date}}' {{lim}} {{col:ident}}",
+			values:   map[string]string{"from": "2018-01-02", "to": "2018-02", "lim": "7", "col": "Name"},
+			expected: "'2018-01-02 00:00:00' '2018-02-01 00:00:00' 7 \"Name\"",
+		},
+		{sql: "{{lim}} {{to:date}}", values: map[string]string{}, err: true},
+		{sql: "{{lim}}", values: map[string]string{"lim": "x"}, err: true},
+		{sql: "{{n:float}}", values: map[string]string{"n": "1,5"}, err: true},
+		{sql: "select 1", values: map[string]string{}, expected: "select 1"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.ApplySQLParams(test.sql, declared, test.values)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got '%s'", index+1, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("test number %d, expected '%s', got '%s'", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestSQLParamName(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		key      string
+		expected string
+		ok       bool
+	}{
+		{key: "{{from}}", expected: "from", ok: true},
+		{key: "{{from:date}}", ok: false},
+		{key: "qr", ok: false},
+		{key: " {{from}}", ok: false},
+		{key: "{{period:a.b}}", ok: false},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, ok := lib.SQLParamName(test.key)
+		if got != test.expected || ok != test.ok {
+			t.Errorf("test number %d, expected (%s, %v), got (%s, %v)", index+1, test.expected, test.ok, got, ok)
+		}
+	}
+}
+
+func TestReadSQLParamsFile(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		data     string
+		expected map[string]string
+		err      bool
+	}{
+		{data: "from: 2018-01-01\nlim: 10\nn: 1.5\nqr: '1 week,,'\n", expected: map[string]string{"from": "2018-01-01", "lim": "10", "n": "1.5", "qr": "1 week,,"}},
+		{data: `{"from": "2018-01-01", "lim": 10}`, expected: map[string]string{"from": "2018-01-01", "lim": "10"}},
+		{data: "- a\n- b\n", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		f, err := ioutil.TempFile("", "params")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = f.WriteString(test.data)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := lib.ReadSQLParamsFile(f.Name())
+		os.Remove(f.Name())
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got %+v", index+1, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %+v, got %+v", index+1, test.expected, got)
+		}
+	}
+}
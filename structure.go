@@ -2,6 +2,7 @@ package devstats
 
 import (
 	"io/ioutil"
+	"sort"
 	"time"
 )
 
@@ -9,7 +10,6 @@ import (
 func Structure(ctx *Ctx) {
 	// Connect to Postgres DB
 	c := PgConn(ctx)
-	defer func() { FatalOnError(c.Close()) }()
 
 	// gha_events
 	// {"id:String"=>48592, "type:String"=>48592, "actor:Hash"=>48592, "repo:Hash"=>48592,
@@ -18,6 +18,8 @@ func Structure(ctx *Ctx) {
 	// "created_at"=>20, "org"=>230}
 	// const
 	// dup columns: dup_actor_login, dup_repo_name
+	// is_bot: set by gha2db using bots.yaml and project's bots
+	// origin: github, gitlab, gitea or gerrit (Gerrit changes can be saved next to GitHub mirror events)
 	if ctx.Table {
 		ExecSQLWithErr(c, ctx, "drop table if exists gha_events")
 		ExecSQLWithErr(
@@ -25,7 +27,7 @@ func Structure(ctx *Ctx) {
 			ctx,
 			CreateTable(
 				"gha_events("+
-					"id bigint not null primary key, "+
+					"id bigint not null, "+
 					"type varchar(40) not null, "+
 					"actor_id bigint not null, "+
 					"repo_id bigint not null, "+
@@ -34,8 +36,11 @@ func Structure(ctx *Ctx) {
 					"org_id bigint, "+
 					"forkee_id bigint, "+
 					"dup_actor_login varchar(120) not null, "+
-					"dup_repo_name varchar(160) not null"+
-					")",
+					"dup_repo_name varchar(160) not null, "+
+					"is_bot boolean not null default false, "+
+					"origin varchar(16) not null default 'github'"+
+					PrimaryKey(ctx, "gha_events")+
+					")"+PartitionBy(ctx),
 			),
 		)
 	}
@@ -48,6 +53,8 @@ func Structure(ctx *Ctx) {
 		ExecSQLWithErr(c, ctx, "create index events_created_at_idx on gha_events(created_at)")
 		ExecSQLWithErr(c, ctx, "create index events_dup_actor_login_idx on gha_events(dup_actor_login)")
 		ExecSQLWithErr(c, ctx, "create index events_dup_repo_name_idx on gha_events(dup_repo_name)")
+		ExecSQLWithErr(c, ctx, "create index events_is_bot_idx on gha_events(is_bot)")
+		ExecSQLWithErr(c, ctx, "create index events_origin_idx on gha_events(origin)")
 	}
 
 	// gha_actors
@@ -199,6 +206,8 @@ func Structure(ctx *Ctx) {
 	// "number"=>5, "forkee"=>6880, "pages"=>855, "release"=>31206, "member"=>1040}
 	// 48746
 	// const
+	// Field overflow keeps payload fields that have no columns here (only saved in GHA2DB_PAYLOAD_OVERFLOW mode)
+	// Field raw keeps full event JSON (only saved in GHA2DB_RAW_JSON mode), big values are compressed by Postgres (TOAST)
 	if ctx.Table {
 		ExecSQLWithErr(c, ctx, "drop table if exists gha_payloads")
 		ExecSQLWithErr(
@@ -229,7 +238,9 @@ func Structure(ctx *Ctx) {
 					"dup_repo_id bigint not null, "+
 					"dup_repo_name varchar(160) not null, "+
 					"dup_type varchar(40) not null, "+
-					"dup_created_at {{ts}} not null"+
+					"dup_created_at {{ts}} not null, "+
+					"overflow jsonb, "+
+					"raw jsonb"+
 					")",
 			),
 		)
@@ -744,6 +755,7 @@ func Structure(ctx *Ctx) {
 	// Keys: actor: user_id, branch: base_sha, head_sha
 	// Nullable keys: actor: merged_by_id, assignee_id, milestone: milestone_id
 	// Arrays: actors: assignees, requested_reviewers
+	// origin: github, gitlab, gitea or gerrit (Gerrit changes are saved as pull requests)
 	// variable
 	if ctx.Table {
 		ExecSQLWithErr(c, ctx, "drop table if exists gha_pull_requests")
@@ -790,6 +802,7 @@ func Structure(ctx *Ctx) {
 					"dup_user_login varchar(120) not null, "+
 					"dupn_assignee_login varchar(120), "+
 					"dupn_merged_by_login varchar(120), "+
+					"origin varchar(16) not null default 'github', "+
 					"primary key(id, event_id)"+
 					")",
 			),
@@ -844,6 +857,7 @@ func Structure(ctx *Ctx) {
 		ExecSQLWithErr(c, ctx, "create index pull_requests_dup_user_login_idx on gha_pull_requests(dup_user_login)")
 		ExecSQLWithErr(c, ctx, "create index pull_requests_dupn_assignee_login_idx on gha_pull_requests(dupn_assignee_login)")
 		ExecSQLWithErr(c, ctx, "create index pull_requests_dupn_merged_by_login_idx on gha_pull_requests(dupn_merged_by_login)")
+		ExecSQLWithErr(c, ctx, "create index pull_requests_origin_idx on gha_pull_requests(origin)")
 	}
 
 	// gha_branches
@@ -1026,6 +1040,431 @@ func Structure(ctx *Ctx) {
 		ExecSQLWithErr(c, ctx, "create index skip_commits_sha_idx on gha_skip_commits(sha)")
 	}
 
+	// Per commit stats (from `git log --numstat`), used by `get_repos` tool
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_commits_stats")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_commits_stats("+
+					"sha varchar(40) not null, "+
+					"added bigint not null, "+
+					"removed bigint not null, "+
+					"files int not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(sha)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index commits_stats_dt_idx on gha_commits_stats(dt)")
+	}
+
+	// Per file churn aggregated from `git log` and last processed SHA of each repo, used by `get_repos` tool
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_files_churn")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_files_churn("+
+					"repo_name varchar(160) not null, "+
+					"path text not null, "+
+					"commits int not null, "+
+					"added bigint not null, "+
+					"removed bigint not null, "+
+					"last_author varchar(160) not null, "+
+					"last_email varchar(160) not null, "+
+					"last_dt {{ts}} not null, "+
+					"primary key(repo_name, path)"+
+					")",
+			),
+		)
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_files_churn_repos")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_files_churn_repos("+
+					"repo_name varchar(160) not null, "+
+					"sha varchar(40) not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(repo_name)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index files_churn_path_idx on gha_files_churn(path)")
+		ExecSQLWithErr(c, ctx, "create index files_churn_commits_idx on gha_files_churn(commits)")
+		ExecSQLWithErr(c, ctx, "create index files_churn_last_author_idx on gha_files_churn(last_author)")
+		ExecSQLWithErr(c, ctx, "create index files_churn_last_dt_idx on gha_files_churn(last_dt)")
+	}
+
+	// Last processed event id of tools that skip databases without new events, `get_repos` uses tool = 'get_repos'
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_watermarks")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_watermarks("+
+					"tool varchar(40) not null, "+
+					"event_id bigint not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(tool)"+
+					")",
+			),
+		)
+	}
+
+	// Every `db2influx` run: metric's SQL, series, period, number of rows returned, time it took and when it finished
+	// Used to find metrics that are getting slower, `gha2db_sync` deletes entries older than GHA2DB_MAXMETRICRUNAGE
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_metric_runs")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_metric_runs("+
+					"sql varchar(200) not null, "+
+					"series varchar(200) not null, "+
+					"period varchar(20) not null, "+
+					"hist boolean not null, "+
+					"rows bigint not null, "+
+					"took_ms bigint not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(sql, series, period, dt)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index metric_runs_dt_idx on gha_metric_runs(dt)")
+	}
+
+	// Results of metric queries by query hash (SQL with all parameters), period and its range, `db2influx` uses them instead of running the query
+	// Closed periods never expire, result of the still open period expires after GHA2DB_METRIC_CACHE_TTL seconds
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_metric_cache")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_metric_cache("+
+					"hash varchar(40) not null, "+
+					"period varchar(20) not null, "+
+					"dt_from {{ts}} not null, "+
+					"dt_to {{ts}} not null, "+
+					"result text not null, "+
+					"dt {{ts}} not null, "+
+					"expires_at {{ts}}, "+
+					"primary key(hash, period, dt_from, dt_to)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index metric_cache_dt_to_idx on gha_metric_cache(dt_to)")
+		ExecSQLWithErr(c, ctx, "create index metric_cache_dt_idx on gha_metric_cache(dt)")
+	}
+
+	// EXPLAIN (ANALYZE, BUFFERS) output of queries that took longer than GHA2DB_EXPLAIN_SLOW (`db2influx` metrics, `runq`)
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_slow_queries")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_slow_queries("+
+					"tool varchar(40) not null, "+
+					"name varchar(200) not null, "+
+					"hash varchar(40) not null, "+
+					"query text not null, "+
+					"took_ms bigint not null, "+
+					"explain text not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(tool, name, dt)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index slow_queries_dt_idx on gha_slow_queries(dt)")
+	}
+
+	// Values of metrics violating their validation rules (metrics.yaml `validate`) found by `db2influx` tool, `blocked` values were not written
+	// Field is the InfluxDB field checked ("value" or multi value series' field), `prev` is the previous value it was compared with
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_metric_violations")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_metric_violations("+
+					"sql varchar(200) not null, "+
+					"series varchar(200) not null, "+
+					"period varchar(20) not null, "+
+					"point_dt {{ts}} not null, "+
+					"field varchar(200) not null, "+
+					"rule varchar(40) not null, "+
+					"value double precision not null, "+
+					"prev double precision, "+
+					"blocked boolean not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(sql, series, period, point_dt, field, rule, dt)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index metric_violations_dt_idx on gha_metric_violations(dt)")
+	}
+
+	// The last window computed by `gha2db_sync` tool for every series (and period) of metrics that recompute their last N periods
+	// Series without a row (new metrics) get the full history, `backfilled_at` is when it was computed
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_metric_windows")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_metric_windows("+
+					"metric varchar(200) not null, "+
+					"series varchar(200) not null, "+
+					"period varchar(20) not null, "+
+					"dt_from {{ts}} not null, "+
+					"dt_to {{ts}} not null, "+
+					"backfilled_at {{ts}} not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(metric, series, period)"+
+					")",
+			),
+		)
+	}
+
+	// Metrics computed by `gha2db_sync` tool in a sync that didn't finish yet, the next sync resumes with remaining metrics
+	// All rows have the same sync window and run key (metrics selection and reset flags), they are deleted when sync finishes
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_metrics_progress")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_metrics_progress("+
+					"metric varchar(200) not null, "+
+					"run_key varchar(40) not null, "+
+					"window_from {{ts}} not null, "+
+					"window_to {{ts}} not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(metric)"+
+					")",
+			),
+		)
+	}
+
+	// Sync status of every project saved by `gha2db_sync` tool: last start, last success (with its duration, number of new events
+	// and metrics that timed out) and last error
+	// It is only used in `devstats` database (like logs)
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_sync_status")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_sync_status("+
+					"project varchar(32) not null, "+
+					"host varchar(255) not null, "+
+					"last_start {{ts}} not null, "+
+					"last_success {{ts}}, "+
+					"last_error_dt {{ts}}, "+
+					"last_error text, "+
+					"took_ms bigint, "+
+					"events bigint, "+
+					"timeouts text, "+
+					"primary key(project)"+
+					")",
+			),
+		)
+	}
+
+	// Projects paused (not synced) or forced (synced on the next run regardless of their schedule) by `devstats pause|force`
+	// It is only used in `devstats` database (like sync status)
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_projects_control")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_projects_control("+
+					"project varchar(32) not null, "+
+					"state varchar(10) not null, "+
+					"reason text not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(project)"+
+					")",
+			),
+		)
+	}
+
+	// GHA hours ingested by `gha2db` tool for a given orgs/repos filter, finished is null until whole hour is saved
+	// Finished hour also has number of rows written and time spent downloading, parsing and saving it (in milliseconds)
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_checkpoints")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_checkpoints("+
+					"dt {{ts}} not null, "+
+					"orgs_repos text not null, "+
+					"jsons int not null, "+
+					"found int not null, "+
+					"events int not null, "+
+					"started {{ts}} not null, "+
+					"finished {{ts}}, "+
+					"rows int, "+
+					"download_ms int, "+
+					"parse_ms int, "+
+					"save_ms int, "+
+					"primary key(dt, orgs_repos)"+
+					")",
+			),
+		)
+	}
+
+	// GHA events that `gha2db` cannot parse (dead letters), hour and line number in GHA file identify them
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_parse_errors")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_parse_errors("+
+					"dt {{ts}} not null, "+
+					"line int not null, "+
+					"json text not null, "+
+					"error text not null, "+
+					"created_at {{ts}} not null, "+
+					"primary key(dt, line)"+
+					")",
+			),
+		)
+	}
+
+	// Renamed/transferred repos: old name -> canonical name, used by `get_repos` tool
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_repos_renames")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_repos_renames("+
+					"name varchar(160) not null, "+
+					"new_name varchar(160) not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(name)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index repos_renames_new_name_idx on gha_repos_renames(new_name)")
+		ExecSQLWithErr(c, ctx, "create index repos_renames_dt_idx on gha_repos_renames(dt)")
+	}
+
+	// Current default branch of each repo, used by `get_repos` tool
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_repos_default_branches")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_repos_default_branches("+
+					"repo_name varchar(160) not null, "+
+					"branch varchar(200) not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(repo_name)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index repos_default_branches_branch_idx on gha_repos_default_branches(branch)")
+	}
+
+	// Bytes per language of each repo over time, used by `get_repos` tool
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_repos_languages")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_repos_languages("+
+					"repo_name varchar(160) not null, "+
+					"language varchar(80) not null, "+
+					"bytes bigint not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(repo_name, language, dt)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index repos_languages_repo_name_idx on gha_repos_languages(repo_name)")
+		ExecSQLWithErr(c, ctx, "create index repos_languages_language_idx on gha_repos_languages(language)")
+		ExecSQLWithErr(c, ctx, "create index repos_languages_dt_idx on gha_repos_languages(dt)")
+	}
+
+	// Licenses found in each repo, used by `get_repos` tool
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_repos_licenses")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_repos_licenses("+
+					"repo_name varchar(160) not null, "+
+					"path text not null, "+
+					"license varchar(40) not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(repo_name, path)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index repos_licenses_license_idx on gha_repos_licenses(license)")
+		ExecSQLWithErr(c, ctx, "create index repos_licenses_dt_idx on gha_repos_licenses(dt)")
+	}
+
+	// CODEOWNERS/OWNERS path patterns -> owners of each repo, used by `get_repos` tool
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_repos_owners")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_repos_owners("+
+					"repo_name varchar(160) not null, "+
+					"path text not null, "+
+					"pattern text not null, "+
+					"owner varchar(160) not null, "+
+					"role varchar(20) not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(repo_name, path, pattern, owner, role)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index repos_owners_owner_idx on gha_repos_owners(owner)")
+		ExecSQLWithErr(c, ctx, "create index repos_owners_role_idx on gha_repos_owners(role)")
+		ExecSQLWithErr(c, ctx, "create index repos_owners_dt_idx on gha_repos_owners(dt)")
+	}
+
 	// Scripts to run on a given database
 	if ctx.Table {
 		ExecSQLWithErr(c, ctx, "drop table if exists gha_postprocess_scripts")
@@ -1042,6 +1481,24 @@ func Structure(ctx *Ctx) {
 		)
 	}
 
+	// SQL function packs (functions/*.sql, functions/project/*.sql) installed by `structure` tool: version and hash of installed SQL
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_functions")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_functions("+
+					"name varchar(200) not null, "+
+					"version int not null, "+
+					"hash varchar(40) not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(name)"+
+					")",
+			),
+		)
+	}
+
 	// This table is a kind of `materialized view` of all texts
 	if ctx.Table {
 		ExecSQLWithErr(c, ctx, "drop table if exists gha_texts")
@@ -1058,7 +1515,8 @@ func Structure(ctx *Ctx) {
 					"repo_id bigint not null, "+
 					"repo_name varchar(160) not null, "+
 					"type varchar(40) not null"+
-					")",
+					PrimaryKey(ctx, "gha_texts")+
+					")"+PartitionBy(ctx),
 			),
 		)
 	}
@@ -1090,9 +1548,9 @@ func Structure(ctx *Ctx) {
 					"repo_id bigint not null, "+
 					"repo_name varchar(160) not null, "+
 					"type varchar(40) not null, "+
-					"issue_number int not null, "+
-					"primary key(issue_id, event_id, label_id)"+
-					")",
+					"issue_number int not null"+
+					PrimaryKey(ctx, "gha_issues_events_labels")+
+					")"+PartitionBy(ctx),
 			),
 		)
 	}
@@ -1158,6 +1616,36 @@ func Structure(ctx *Ctx) {
 	}
 	// Foreign keys are not needed - they slow down processing a lot
 
+	// Schema migrations applied to (or reverted from) this database by `devstats migrate`, tables created above have the latest version
+	// Databases created before it get the table on their first `devstats migrate` (that applies all migrations)
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_schema_version")
+		FatalOnError(StampSchemaVersion(c, ctx))
+	}
+
+	// Tables partitioned by month get partitions up to the next month, ingestion creates new ones when needed
+	// Existing tables are migrated to partitioned ones, with all their rows
+	if ctx.Partition {
+		if !ctx.Table {
+			tables := []string{}
+			for table := range PartitionedTables {
+				tables = append(tables, table)
+			}
+			sort.Strings(tables)
+			for _, table := range tables {
+				dtStart := time.Now()
+				moved, err := MigrateToPartitions(c, ctx, table)
+				FatalOnError(err)
+				if moved < 0 {
+					Printf("%s: already partitioned\n", table)
+					continue
+				}
+				Printf("%s: migrated %d rows to monthly partitions, took %v\n", table, moved, time.Now().Sub(dtStart))
+			}
+		}
+		FatalOnError(EnsurePartitions(c, ctx, ctx.DefaultStartDate, NextMonthStart(time.Now())))
+	}
+
 	// Tools (like views and functions needed for generating metrics)
 	if ctx.Tools {
 		// Local or cron mode?
@@ -1165,6 +1653,14 @@ func Structure(ctx *Ctx) {
 		if ctx.Local {
 			dataPrefix = "./"
 		}
+		// Install or refresh SQL function packs, scripts and metrics can use their functions
+		packs, err := ReadFunctionPacks(dataPrefix, ctx.Project)
+		FatalOnError(err)
+		n, err := InstallFunctionPacks(c, ctx, packs)
+		FatalOnError(err)
+		if ctx.Debug > 0 {
+			Printf("Function packs: %d, installed or refreshed: %d\n", len(packs), n)
+		}
 		// Get list of script files
 		rows, err := c.Query("select path from gha_postprocess_scripts order by ord")
 		defer func() { FatalOnError(rows.Close()) }()
@@ -121,6 +121,27 @@ CREATE TABLE gha_branches (
 
 ALTER TABLE gha_branches OWNER TO gha_admin;
 
+--
+-- Name: gha_checkpoints; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_checkpoints (
+    dt timestamp without time zone NOT NULL,
+    orgs_repos text NOT NULL,
+    jsons integer NOT NULL,
+    found integer NOT NULL,
+    events integer NOT NULL,
+    started timestamp without time zone NOT NULL,
+    finished timestamp without time zone,
+    rows integer,
+    download_ms integer,
+    parse_ms integer,
+    save_ms integer
+);
+
+
+ALTER TABLE gha_checkpoints OWNER TO gha_admin;
+
 --
 -- Name: gha_comments; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -187,6 +208,21 @@ CREATE TABLE gha_commits_files (
 
 ALTER TABLE gha_commits_files OWNER TO gha_admin;
 
+--
+-- Name: gha_commits_stats; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_commits_stats (
+    sha character varying(40) NOT NULL,
+    added bigint NOT NULL,
+    removed bigint NOT NULL,
+    files integer NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_commits_stats OWNER TO gha_admin;
+
 --
 -- Name: gha_companies; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -212,7 +248,9 @@ CREATE TABLE gha_events (
     org_id bigint,
     forkee_id bigint,
     dup_actor_login character varying(120) NOT NULL,
-    dup_repo_name character varying(160) NOT NULL
+    dup_repo_name character varying(160) NOT NULL,
+    is_bot boolean DEFAULT false NOT NULL,
+    origin character varying(16) DEFAULT 'github'::character varying NOT NULL
 );
 
 
@@ -238,6 +276,37 @@ CREATE TABLE gha_events_commits_files (
 
 ALTER TABLE gha_events_commits_files OWNER TO gha_admin;
 
+--
+-- Name: gha_files_churn; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_files_churn (
+    repo_name character varying(160) NOT NULL,
+    path text NOT NULL,
+    commits integer NOT NULL,
+    added bigint NOT NULL,
+    removed bigint NOT NULL,
+    last_author character varying(160) NOT NULL,
+    last_email character varying(160) NOT NULL,
+    last_dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_files_churn OWNER TO gha_admin;
+
+--
+-- Name: gha_files_churn_repos; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_files_churn_repos (
+    repo_name character varying(160) NOT NULL,
+    sha character varying(40) NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_files_churn_repos OWNER TO gha_admin;
+
 --
 -- Name: gha_forkees; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -280,6 +349,20 @@ CREATE TABLE gha_forkees (
 
 ALTER TABLE gha_forkees OWNER TO gha_admin;
 
+--
+-- Name: gha_functions; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_functions (
+    name character varying(200) NOT NULL,
+    version integer NOT NULL,
+    hash character varying(40) NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_functions OWNER TO gha_admin;
+
 --
 -- Name: gha_issues; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -435,6 +518,92 @@ ALTER TABLE gha_logs_id_seq OWNER TO gha_admin;
 ALTER SEQUENCE gha_logs_id_seq OWNED BY gha_logs.id;
 
 
+--
+-- Name: gha_metric_cache; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_metric_cache (
+    hash character varying(40) NOT NULL,
+    period character varying(20) NOT NULL,
+    dt_from timestamp without time zone NOT NULL,
+    dt_to timestamp without time zone NOT NULL,
+    result text NOT NULL,
+    dt timestamp without time zone NOT NULL,
+    expires_at timestamp without time zone
+);
+
+
+ALTER TABLE gha_metric_cache OWNER TO gha_admin;
+
+--
+-- Name: gha_metric_runs; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_metric_runs (
+    sql character varying(200) NOT NULL,
+    series character varying(200) NOT NULL,
+    period character varying(20) NOT NULL,
+    hist boolean NOT NULL,
+    rows bigint NOT NULL,
+    took_ms bigint NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_metric_runs OWNER TO gha_admin;
+
+--
+-- Name: gha_metric_violations; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_metric_violations (
+    sql character varying(200) NOT NULL,
+    series character varying(200) NOT NULL,
+    period character varying(20) NOT NULL,
+    point_dt timestamp without time zone NOT NULL,
+    field character varying(200) NOT NULL,
+    rule character varying(40) NOT NULL,
+    value double precision NOT NULL,
+    prev double precision,
+    blocked boolean NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_metric_violations OWNER TO gha_admin;
+
+--
+-- Name: gha_metric_windows; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_metric_windows (
+    metric character varying(200) NOT NULL,
+    series character varying(200) NOT NULL,
+    period character varying(20) NOT NULL,
+    dt_from timestamp without time zone NOT NULL,
+    dt_to timestamp without time zone NOT NULL,
+    backfilled_at timestamp without time zone NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_metric_windows OWNER TO gha_admin;
+
+--
+-- Name: gha_metrics_progress; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_metrics_progress (
+    metric character varying(200) NOT NULL,
+    run_key character varying(40) NOT NULL,
+    window_from timestamp without time zone NOT NULL,
+    window_to timestamp without time zone NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_metrics_progress OWNER TO gha_admin;
+
 --
 -- Name: gha_milestones; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -497,6 +666,21 @@ CREATE TABLE gha_pages (
 
 ALTER TABLE gha_pages OWNER TO gha_admin;
 
+--
+-- Name: gha_parse_errors; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_parse_errors (
+    dt timestamp without time zone NOT NULL,
+    line integer NOT NULL,
+    json text NOT NULL,
+    error text NOT NULL,
+    created_at timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_parse_errors OWNER TO gha_admin;
+
 --
 -- Name: gha_payloads; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -525,7 +709,9 @@ CREATE TABLE gha_payloads (
     dup_repo_id bigint NOT NULL,
     dup_repo_name character varying(160) NOT NULL,
     dup_type character varying(40) NOT NULL,
-    dup_created_at timestamp without time zone NOT NULL
+    dup_created_at timestamp without time zone NOT NULL,
+    overflow jsonb,
+    raw jsonb
 );
 
 
@@ -543,6 +729,20 @@ CREATE TABLE gha_postprocess_scripts (
 
 ALTER TABLE gha_postprocess_scripts OWNER TO gha_admin;
 
+--
+-- Name: gha_projects_control; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_projects_control (
+    project character varying(32) NOT NULL,
+    state character varying(10) NOT NULL,
+    reason text NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_projects_control OWNER TO gha_admin;
+
 --
 -- Name: gha_pull_requests; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -585,7 +785,8 @@ CREATE TABLE gha_pull_requests (
     dup_created_at timestamp without time zone NOT NULL,
     dup_user_login character varying(120) NOT NULL,
     dupn_assignee_login character varying(120),
-    dupn_merged_by_login character varying(120)
+    dupn_merged_by_login character varying(120),
+    origin character varying(16) DEFAULT 'github'::character varying NOT NULL
 );
 
 
@@ -674,6 +875,76 @@ CREATE TABLE gha_repos (
 
 ALTER TABLE gha_repos OWNER TO gha_admin;
 
+--
+-- Name: gha_repos_default_branches; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_repos_default_branches (
+    repo_name character varying(160) NOT NULL,
+    branch character varying(200) NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_repos_default_branches OWNER TO gha_admin;
+
+--
+-- Name: gha_repos_languages; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_repos_languages (
+    repo_name character varying(160) NOT NULL,
+    language character varying(80) NOT NULL,
+    bytes bigint NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_repos_languages OWNER TO gha_admin;
+
+--
+-- Name: gha_repos_licenses; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_repos_licenses (
+    repo_name character varying(160) NOT NULL,
+    path text NOT NULL,
+    license character varying(40) NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_repos_licenses OWNER TO gha_admin;
+
+--
+-- Name: gha_repos_owners; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_repos_owners (
+    repo_name character varying(160) NOT NULL,
+    path text NOT NULL,
+    pattern text NOT NULL,
+    owner character varying(160) NOT NULL,
+    role character varying(20) NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_repos_owners OWNER TO gha_admin;
+
+--
+-- Name: gha_repos_renames; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_repos_renames (
+    name character varying(160) NOT NULL,
+    new_name character varying(160) NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_repos_renames OWNER TO gha_admin;
+
 --
 -- Name: gha_skip_commits; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -685,6 +956,42 @@ CREATE TABLE gha_skip_commits (
 
 ALTER TABLE gha_skip_commits OWNER TO gha_admin;
 
+--
+-- Name: gha_slow_queries; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_slow_queries (
+    tool character varying(40) NOT NULL,
+    name character varying(200) NOT NULL,
+    hash character varying(40) NOT NULL,
+    query text NOT NULL,
+    took_ms bigint NOT NULL,
+    explain text NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_slow_queries OWNER TO gha_admin;
+
+--
+-- Name: gha_sync_status; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_sync_status (
+    project character varying(32) NOT NULL,
+    host character varying(255) NOT NULL,
+    last_start timestamp without time zone NOT NULL,
+    last_success timestamp without time zone,
+    last_error_dt timestamp without time zone,
+    last_error text,
+    took_ms bigint,
+    events bigint,
+    timeouts text
+);
+
+
+ALTER TABLE gha_sync_status OWNER TO gha_admin;
+
 --
 -- Name: gha_teams; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -737,6 +1044,19 @@ CREATE TABLE gha_texts (
 
 ALTER TABLE gha_texts OWNER TO gha_admin;
 
+--
+-- Name: gha_watermarks; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_watermarks (
+    tool character varying(40) NOT NULL,
+    event_id bigint NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_watermarks OWNER TO gha_admin;
+
 --
 -- Name: gha_logs id; Type: DEFAULT; Schema: public; Owner: gha_admin
 --
@@ -784,6 +1104,14 @@ ALTER TABLE ONLY gha_branches
     ADD CONSTRAINT gha_branches_pkey PRIMARY KEY (sha, event_id);
 
 
+--
+-- Name: gha_checkpoints gha_checkpoints_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_checkpoints
+    ADD CONSTRAINT gha_checkpoints_pkey PRIMARY KEY (dt, orgs_repos);
+
+
 --
 -- Name: gha_comments gha_comments_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -808,6 +1136,14 @@ ALTER TABLE ONLY gha_commits
     ADD CONSTRAINT gha_commits_pkey PRIMARY KEY (sha, event_id);
 
 
+--
+-- Name: gha_commits_stats gha_commits_stats_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_commits_stats
+    ADD CONSTRAINT gha_commits_stats_pkey PRIMARY KEY (sha);
+
+
 --
 -- Name: gha_companies gha_companies_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -832,6 +1168,22 @@ ALTER TABLE ONLY gha_events
     ADD CONSTRAINT gha_events_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: gha_files_churn gha_files_churn_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_files_churn
+    ADD CONSTRAINT gha_files_churn_pkey PRIMARY KEY (repo_name, path);
+
+
+--
+-- Name: gha_files_churn_repos gha_files_churn_repos_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_files_churn_repos
+    ADD CONSTRAINT gha_files_churn_repos_pkey PRIMARY KEY (repo_name);
+
+
 --
 -- Name: gha_forkees gha_forkees_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -840,6 +1192,14 @@ ALTER TABLE ONLY gha_forkees
     ADD CONSTRAINT gha_forkees_pkey PRIMARY KEY (id, event_id);
 
 
+--
+-- Name: gha_functions gha_functions_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_functions
+    ADD CONSTRAINT gha_functions_pkey PRIMARY KEY (name);
+
+
 --
 -- Name: gha_issues_assignees gha_issues_assignees_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -880,6 +1240,46 @@ ALTER TABLE ONLY gha_labels
     ADD CONSTRAINT gha_labels_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: gha_metric_cache gha_metric_cache_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_metric_cache
+    ADD CONSTRAINT gha_metric_cache_pkey PRIMARY KEY (hash, period, dt_from, dt_to);
+
+
+--
+-- Name: gha_metric_runs gha_metric_runs_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_metric_runs
+    ADD CONSTRAINT gha_metric_runs_pkey PRIMARY KEY (sql, series, period, dt);
+
+
+--
+-- Name: gha_metric_violations gha_metric_violations_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_metric_violations
+    ADD CONSTRAINT gha_metric_violations_pkey PRIMARY KEY (sql, series, period, point_dt, field, rule, dt);
+
+
+--
+-- Name: gha_metric_windows gha_metric_windows_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_metric_windows
+    ADD CONSTRAINT gha_metric_windows_pkey PRIMARY KEY (metric, series, period);
+
+
+--
+-- Name: gha_metrics_progress gha_metrics_progress_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_metrics_progress
+    ADD CONSTRAINT gha_metrics_progress_pkey PRIMARY KEY (metric);
+
+
 --
 -- Name: gha_milestones gha_milestones_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -904,6 +1304,14 @@ ALTER TABLE ONLY gha_pages
     ADD CONSTRAINT gha_pages_pkey PRIMARY KEY (sha, event_id, action, title);
 
 
+--
+-- Name: gha_parse_errors gha_parse_errors_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_parse_errors
+    ADD CONSTRAINT gha_parse_errors_pkey PRIMARY KEY (dt, line);
+
+
 --
 -- Name: gha_payloads gha_payloads_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -920,6 +1328,14 @@ ALTER TABLE ONLY gha_postprocess_scripts
     ADD CONSTRAINT gha_postprocess_scripts_pkey PRIMARY KEY (ord, path);
 
 
+--
+-- Name: gha_projects_control gha_projects_control_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_projects_control
+    ADD CONSTRAINT gha_projects_control_pkey PRIMARY KEY (project);
+
+
 --
 -- Name: gha_pull_requests_assignees gha_pull_requests_assignees_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -960,6 +1376,38 @@ ALTER TABLE ONLY gha_releases
     ADD CONSTRAINT gha_releases_pkey PRIMARY KEY (id, event_id);
 
 
+--
+-- Name: gha_repos_default_branches gha_repos_default_branches_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_repos_default_branches
+    ADD CONSTRAINT gha_repos_default_branches_pkey PRIMARY KEY (repo_name);
+
+
+--
+-- Name: gha_repos_languages gha_repos_languages_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_repos_languages
+    ADD CONSTRAINT gha_repos_languages_pkey PRIMARY KEY (repo_name, language, dt);
+
+
+--
+-- Name: gha_repos_licenses gha_repos_licenses_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_repos_licenses
+    ADD CONSTRAINT gha_repos_licenses_pkey PRIMARY KEY (repo_name, path);
+
+
+--
+-- Name: gha_repos_owners gha_repos_owners_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_repos_owners
+    ADD CONSTRAINT gha_repos_owners_pkey PRIMARY KEY (repo_name, path, pattern, owner, role);
+
+
 --
 -- Name: gha_repos gha_repos_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -968,6 +1416,14 @@ ALTER TABLE ONLY gha_repos
     ADD CONSTRAINT gha_repos_pkey PRIMARY KEY (id, name);
 
 
+--
+-- Name: gha_repos_renames gha_repos_renames_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_repos_renames
+    ADD CONSTRAINT gha_repos_renames_pkey PRIMARY KEY (name);
+
+
 --
 -- Name: gha_skip_commits gha_skip_commits_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -976,6 +1432,22 @@ ALTER TABLE ONLY gha_skip_commits
     ADD CONSTRAINT gha_skip_commits_pkey PRIMARY KEY (sha);
 
 
+--
+-- Name: gha_slow_queries gha_slow_queries_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_slow_queries
+    ADD CONSTRAINT gha_slow_queries_pkey PRIMARY KEY (tool, name, dt);
+
+
+--
+-- Name: gha_sync_status gha_sync_status_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_sync_status
+    ADD CONSTRAINT gha_sync_status_pkey PRIMARY KEY (project);
+
+
 --
 -- Name: gha_teams gha_teams_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -992,6 +1464,14 @@ ALTER TABLE ONLY gha_teams_repositories
     ADD CONSTRAINT gha_teams_repositories_pkey PRIMARY KEY (team_id, event_id, repository_id);
 
 
+--
+-- Name: gha_watermarks gha_watermarks_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_watermarks
+    ADD CONSTRAINT gha_watermarks_pkey PRIMARY KEY (tool);
+
+
 --
 -- Name: actors_affiliations_actor_id_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -1342,6 +1822,13 @@ CREATE INDEX commits_files_sha_idx ON gha_commits_files USING btree (sha);
 CREATE INDEX commits_files_size_idx ON gha_commits_files USING btree (size);
 
 
+--
+-- Name: commits_stats_dt_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX commits_stats_dt_idx ON gha_commits_stats USING btree (dt);
+
+
 --
 -- Name: events_actor_id_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -1447,6 +1934,13 @@ CREATE INDEX events_dup_repo_name_idx ON gha_events USING btree (dup_repo_name);
 CREATE INDEX events_forkee_id_idx ON gha_events USING btree (forkee_id);
 
 
+--
+-- Name: events_is_bot_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX events_is_bot_idx ON gha_events USING btree (is_bot);
+
+
 --
 -- Name: events_org_id_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -1454,6 +1948,13 @@ CREATE INDEX events_forkee_id_idx ON gha_events USING btree (forkee_id);
 CREATE INDEX events_org_id_idx ON gha_events USING btree (org_id);
 
 
+--
+-- Name: events_origin_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX events_origin_idx ON gha_events USING btree (origin);
+
+
 --
 -- Name: events_repo_id_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -1468,6 +1969,34 @@ CREATE INDEX events_repo_id_idx ON gha_events USING btree (repo_id);
 CREATE INDEX events_type_idx ON gha_events USING btree (type);
 
 
+--
+-- Name: files_churn_commits_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX files_churn_commits_idx ON gha_files_churn USING btree (commits);
+
+
+--
+-- Name: files_churn_last_author_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX files_churn_last_author_idx ON gha_files_churn USING btree (last_author);
+
+
+--
+-- Name: files_churn_last_dt_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX files_churn_last_dt_idx ON gha_files_churn USING btree (last_dt);
+
+
+--
+-- Name: files_churn_path_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX files_churn_path_idx ON gha_files_churn USING btree (path);
+
+
 --
 -- Name: forkees_created_at_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -1881,6 +2410,34 @@ CREATE INDEX logs_proj_idx ON gha_logs USING btree (proj);
 CREATE INDEX logs_run_dt_idx ON gha_logs USING btree (run_dt);
 
 
+--
+-- Name: metric_cache_dt_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX metric_cache_dt_idx ON gha_metric_cache USING btree (dt);
+
+
+--
+-- Name: metric_cache_dt_to_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX metric_cache_dt_to_idx ON gha_metric_cache USING btree (dt_to);
+
+
+--
+-- Name: metric_runs_dt_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX metric_runs_dt_idx ON gha_metric_runs USING btree (dt);
+
+
+--
+-- Name: metric_violations_dt_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX metric_violations_dt_idx ON gha_metric_violations USING btree (dt);
+
+
 --
 -- Name: milestones_created_at_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -2259,6 +2816,13 @@ CREATE INDEX pull_requests_merged_by_id_idx ON gha_pull_requests USING btree (me
 CREATE INDEX pull_requests_milestone_id_idx ON gha_pull_requests USING btree (milestone_id);
 
 
+--
+-- Name: pull_requests_origin_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX pull_requests_origin_idx ON gha_pull_requests USING btree (origin);
+
+
 --
 -- Name: pull_requests_state_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -2350,6 +2914,48 @@ CREATE INDEX releases_event_id_idx ON gha_releases USING btree (event_id);
 CREATE INDEX repos_alias_idx ON gha_repos USING btree (alias);
 
 
+--
+-- Name: repos_default_branches_branch_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_default_branches_branch_idx ON gha_repos_default_branches USING btree (branch);
+
+
+--
+-- Name: repos_languages_dt_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_languages_dt_idx ON gha_repos_languages USING btree (dt);
+
+
+--
+-- Name: repos_languages_language_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_languages_language_idx ON gha_repos_languages USING btree (language);
+
+
+--
+-- Name: repos_languages_repo_name_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_languages_repo_name_idx ON gha_repos_languages USING btree (repo_name);
+
+
+--
+-- Name: repos_licenses_dt_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_licenses_dt_idx ON gha_repos_licenses USING btree (dt);
+
+
+--
+-- Name: repos_licenses_license_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_licenses_license_idx ON gha_repos_licenses USING btree (license);
+
+
 --
 -- Name: repos_name_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -2371,6 +2977,41 @@ CREATE INDEX repos_org_id_idx ON gha_repos USING btree (org_id);
 CREATE INDEX repos_org_login_idx ON gha_repos USING btree (org_login);
 
 
+--
+-- Name: repos_owners_dt_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_owners_dt_idx ON gha_repos_owners USING btree (dt);
+
+
+--
+-- Name: repos_owners_owner_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_owners_owner_idx ON gha_repos_owners USING btree (owner);
+
+
+--
+-- Name: repos_owners_role_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_owners_role_idx ON gha_repos_owners USING btree (role);
+
+
+--
+-- Name: repos_renames_dt_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_renames_dt_idx ON gha_repos_renames USING btree (dt);
+
+
+--
+-- Name: repos_renames_new_name_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_renames_new_name_idx ON gha_repos_renames USING btree (new_name);
+
+
 --
 -- Name: repos_repo_group_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -2385,6 +3026,13 @@ CREATE INDEX repos_repo_group_idx ON gha_repos USING btree (repo_group);
 CREATE INDEX skip_commits_sha_idx ON gha_skip_commits USING btree (sha);
 
 
+--
+-- Name: slow_queries_dt_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX slow_queries_dt_idx ON gha_slow_queries USING btree (dt);
+
+
 --
 -- Name: teams_dup_actor_id_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -0,0 +1,194 @@
+package devstats
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SyncAllProjects - `depends_on` value meaning all other projects synced in the same run
+const SyncAllProjects = "*"
+
+// SyncPlan - projects synced by `devstats` in one run with their dependencies (projects.yaml `depends_on`)
+// Project starts after all its dependencies synced in the same run finished (successfully or not)
+// Projects using the same Postgres database never run at once
+type SyncPlan struct {
+	names []string
+	deps  map[string][]string
+	dbs   map[string]string
+}
+
+// NewSyncPlan creates plan of syncing projects `names` (in priority order)
+// Dependencies must be defined in `projects`, dependencies not synced in this run (disabled or not due) are not waited for
+// Returns error for unknown dependencies and dependency cycles
+func NewSyncPlan(projects *AllProjects, names []string) (*SyncPlan, error) {
+	p := &SyncPlan{names: names, deps: make(map[string][]string), dbs: make(map[string]string)}
+	planned := make(map[string]bool)
+	for _, name := range names {
+		planned[name] = true
+	}
+	for _, name := range names {
+		proj := projects.Projects[name]
+		p.dbs[name] = proj.PDB
+		added := make(map[string]bool)
+		add := func(dep string) {
+			if !added[dep] {
+				added[dep] = true
+				p.deps[name] = append(p.deps[name], dep)
+			}
+		}
+		for _, dep := range proj.DependsOn {
+			if dep == SyncAllProjects {
+				// Projects depending on all others don't wait for each other
+				for _, other := range names {
+					if other != name && !dependsOnAll(projects.Projects[other]) {
+						add(other)
+					}
+				}
+				continue
+			}
+			if _, ok := projects.Projects[dep]; !ok {
+				return nil, fmt.Errorf("project '%s' depends on unknown project '%s'", name, dep)
+			}
+			if dep == name {
+				return nil, fmt.Errorf("project '%s' depends on itself", name)
+			}
+			if planned[dep] {
+				add(dep)
+			}
+		}
+	}
+	// Check cycles
+	state := make(map[string]int)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("projects dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		for _, dep := range p.deps[name] {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// dependsOnAll - does project depend on all other projects?
+func dependsOnAll(proj Project) bool {
+	for _, dep := range proj.DependsOn {
+		if dep == SyncAllProjects {
+			return true
+		}
+	}
+	return false
+}
+
+// Deps returns projects that `name` waits for, sorted
+func (p *SyncPlan) Deps(name string) []string {
+	deps := append([]string{}, p.deps[name]...)
+	sort.Strings(deps)
+	return deps
+}
+
+// Run syncs all projects calling `sync` for each of them, up to `parallel` projects at once
+// Projects that are ready (all dependencies finished, their database is not used) start in priority order
+func (p *SyncPlan) Run(parallel int, sync func(name string)) {
+	if parallel < 1 {
+		parallel = 1
+	}
+	finished := make(map[string]bool)
+	started := make(map[string]bool)
+	busyDBs := make(map[string]bool)
+	done := make(chan string)
+	running := 0
+	for len(finished) < len(p.names) {
+		for _, name := range p.names {
+			if running >= parallel {
+				break
+			}
+			if started[name] || (p.dbs[name] != "" && busyDBs[p.dbs[name]]) {
+				continue
+			}
+			ready := true
+			for _, dep := range p.deps[name] {
+				if !finished[dep] {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				continue
+			}
+			started[name] = true
+			busyDBs[p.dbs[name]] = true
+			running++
+			go func(name string) {
+				sync(name)
+				done <- name
+			}(name)
+		}
+		name := <-done
+		finished[name] = true
+		busyDBs[p.dbs[name]] = false
+		running--
+	}
+}
+
+// OrderByStaleness returns project names sorted by hour of their last event, the most stale first
+// Projects with unknown last event are the first, projects equally stale keep their order
+func OrderByStaleness(names []string, lastEvents map[string]time.Time) []string {
+	sorted := append([]string{}, names...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return HourStart(lastEvents[sorted[i]]).Before(HourStart(lastEvents[sorted[j]]))
+	})
+	return sorted
+}
+
+// ProjectChanges describes changes between two versions of "projects.yaml", sorted by project name
+// Like "added grpc", "removed rkt", "disabled cni", "enabled envoy" or "changed kubernetes"
+func ProjectChanges(old, new *AllProjects) []string {
+	names := make(map[string]struct{})
+	for name := range old.Projects {
+		names[name] = struct{}{}
+	}
+	for name := range new.Projects {
+		names[name] = struct{}{}
+	}
+	sorted := []string{}
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	changes := []string{}
+	for _, name := range sorted {
+		oldProj, inOld := old.Projects[name]
+		newProj, inNew := new.Projects[name]
+		switch {
+		case !inOld:
+			changes = append(changes, "added "+name)
+		case !inNew:
+			changes = append(changes, "removed "+name)
+		case !oldProj.Disabled && newProj.Disabled:
+			changes = append(changes, "disabled "+name)
+		case oldProj.Disabled && !newProj.Disabled:
+			changes = append(changes, "enabled "+name)
+		case !reflect.DeepEqual(oldProj, newProj):
+			changes = append(changes, "changed "+name)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,197 @@
+package devstats
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestNewSyncPlan(t *testing.T) {
+	projects := lib.AllProjects{
+		Projects: map[string]lib.Project{
+			"kubernetes": {PDB: "gha"},
+			"prometheus": {PDB: "prometheus"},
+			"grpc":       {PDB: "grpc", DependsOn: []string{"prometheus"}},
+			"cncf":       {PDB: "cncf", DependsOn: []string{lib.SyncAllProjects}},
+			"all":        {PDB: "allprj", DependsOn: []string{lib.SyncAllProjects, "kubernetes"}},
+		},
+	}
+
+	// Test cases
+	var testCases = []struct {
+		names    []string
+		project  string
+		expected []string
+	}{
+		{names: []string{"kubernetes", "prometheus", "grpc"}, project: "grpc", expected: []string{"prometheus"}},
+		{names: []string{"kubernetes", "grpc"}, project: "grpc", expected: []string{}},
+		{names: []string{"kubernetes", "prometheus", "grpc", "cncf", "all"}, project: "cncf", expected: []string{"grpc", "kubernetes", "prometheus"}},
+		{names: []string{"kubernetes", "prometheus", "grpc", "cncf", "all"}, project: "all", expected: []string{"grpc", "kubernetes", "prometheus"}},
+		{names: []string{"cncf"}, project: "cncf", expected: []string{}},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		plan, err := lib.NewSyncPlan(&projects, test.names)
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		got := plan.Deps(test.project)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestNewSyncPlanErrors(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		projects map[string]lib.Project
+		expected string
+	}{
+		{
+			projects: map[string]lib.Project{"a": {DependsOn: []string{"x"}}},
+			expected: "depends on unknown project 'x'",
+		},
+		{
+			projects: map[string]lib.Project{"a": {DependsOn: []string{"a"}}},
+			expected: "depends on itself",
+		},
+		{
+			projects: map[string]lib.Project{"a": {DependsOn: []string{"b"}}, "b": {DependsOn: []string{"c"}}, "c": {DependsOn: []string{"a"}}},
+			expected: "dependency cycle",
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		projects := lib.AllProjects{Projects: test.projects}
+		_, err := lib.NewSyncPlan(&projects, []string{"a", "b", "c"}[:len(test.projects)])
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("test number %d, expected error containing '%s', got %v", index+1, test.expected, err)
+		}
+	}
+}
+
+func TestSyncPlanRun(t *testing.T) {
+	projects := lib.AllProjects{
+		Projects: map[string]lib.Project{
+			"kubernetes": {PDB: "gha"},
+			"prometheus": {PDB: "prometheus"},
+			"grpc":       {PDB: "grpc"},
+			"gha2":       {PDB: "gha"},
+			"cncf":       {PDB: "cncf", DependsOn: []string{lib.SyncAllProjects}},
+		},
+	}
+	names := []string{"kubernetes", "prometheus", "grpc", "gha2", "cncf"}
+	plan, err := lib.NewSyncPlan(&projects, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Track projects running at once
+	var (
+		mtx     sync.Mutex
+		running = make(map[string]bool)
+		maxRun  int
+		order   []string
+		errs    []string
+	)
+	plan.Run(3, func(name string) {
+		mtx.Lock()
+		for other := range running {
+			if projects.Projects[other].PDB == projects.Projects[name].PDB {
+				errs = append(errs, name+" runs with "+other)
+			}
+		}
+		if name == "cncf" && len(running) > 0 {
+			errs = append(errs, "cncf runs with others")
+		}
+		running[name] = true
+		if len(running) > maxRun {
+			maxRun = len(running)
+		}
+		mtx.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mtx.Lock()
+		delete(running, name)
+		order = append(order, name)
+		mtx.Unlock()
+	})
+	if len(errs) > 0 {
+		t.Errorf("unexpected parallel runs: %v", errs)
+	}
+	if maxRun != 3 {
+		t.Errorf("expected 3 projects running at once, got %d", maxRun)
+	}
+	if len(order) != len(names) || order[len(order)-1] != "cncf" {
+		t.Errorf("expected all projects synced and cncf last, got %v", order)
+	}
+}
+
+func TestOrderByStaleness(t *testing.T) {
+	ft := func(h int, m int) time.Time { return time.Date(2017, 8, 2, h, m, 0, 0, time.UTC) }
+	lastEvents := map[string]time.Time{
+		"kubernetes":  ft(10, 30),
+		"prometheus":  ft(7, 15),
+		"grpc":        ft(10, 5),
+		"opentracing": ft(3, 55),
+	}
+
+	// Test cases
+	var testCases = []struct {
+		names    []string
+		expected []string
+	}{
+		{names: []string{}, expected: []string{}},
+		{names: []string{"kubernetes", "prometheus", "opentracing"}, expected: []string{"opentracing", "prometheus", "kubernetes"}},
+		{names: []string{"kubernetes", "grpc"}, expected: []string{"kubernetes", "grpc"}},
+		{names: []string{"grpc", "kubernetes"}, expected: []string{"grpc", "kubernetes"}},
+		{names: []string{"kubernetes", "new", "prometheus"}, expected: []string{"new", "prometheus", "kubernetes"}},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.OrderByStaleness(test.names, lastEvents)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestProjectChanges(t *testing.T) {
+	old := lib.AllProjects{
+		Projects: map[string]lib.Project{
+			"kubernetes": {PDB: "gha", Order: 1},
+			"prometheus": {PDB: "prometheus", Order: 2},
+			"cni":        {PDB: "cni", Order: 3},
+			"envoy":      {PDB: "envoy", Order: 4, Disabled: true},
+		},
+	}
+
+	// Test cases
+	var testCases = []struct {
+		new      map[string]lib.Project
+		expected []string
+	}{
+		{new: old.Projects, expected: []string{}},
+		{
+			new: map[string]lib.Project{
+				"kubernetes": {PDB: "gha", Order: 1, SyncSchedule: "3h"},
+				"cni":        {PDB: "cni", Order: 3, Disabled: true},
+				"envoy":      {PDB: "envoy", Order: 4},
+				"grpc":       {PDB: "grpc", Order: 5},
+			},
+			expected: []string{"disabled cni", "enabled envoy", "added grpc", "changed kubernetes", "removed prometheus"},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.ProjectChanges(&old, &lib.AllProjects{Projects: test.new})
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
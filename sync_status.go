@@ -0,0 +1,125 @@
+package devstats
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"time"
+)
+
+// SyncStatus - `gha_sync_status` row, freshness of project's data saved by `gha2db_sync`
+// It is kept in `devstats` database (like logs), so all projects can be queried at once
+type SyncStatus struct {
+	Project     string          `json:"project"`
+	Host        string          `json:"host"`
+	LastStart   *time.Time      `json:"last_start"`
+	LastSuccess *time.Time      `json:"last_success"`
+	LastErrorDt *time.Time      `json:"last_error_dt"`
+	LastError   *string         `json:"last_error"`
+	TookMs      *int64          `json:"took_ms"`
+	Events      *int64          `json:"events"`
+	Timeouts    *string         `json:"timeouts"`
+	Running     bool            `json:"running"`
+	Control     *ProjectControl `json:"control"` // Set by `devstats status` when project is paused or forced
+}
+
+// syncStatusConn - connects to `devstats` database
+func syncStatusConn(ctx *Ctx) (*sql.DB, *Ctx) {
+	sctx := *ctx
+	sctx.PgDB = Devstats
+	return PgConn(&sctx), &sctx
+}
+
+// SyncStarted saves start of `ctx.Project` sync
+func SyncStarted(ctx *Ctx, dtStart time.Time) error {
+	con, sctx := syncStatusConn(ctx)
+	host, _ := os.Hostname()
+	_, err := ExecSQL(
+		con,
+		sctx,
+		"insert into gha_sync_status(project, host, last_start) "+NValues(3)+
+			" on conflict (project) do update set host = excluded.host, last_start = excluded.last_start",
+		ctx.Project,
+		host,
+		dtStart,
+	)
+	return err
+}
+
+// SyncSucceeded saves successful end of `ctx.Project` sync started at `dtStart`, `events` is number of new events
+// `timeouts` are metrics (with periods) that exceeded their timeout and were skipped, they are cleared when there are none
+func SyncSucceeded(ctx *Ctx, dtStart time.Time, events int64, timeouts []string) error {
+	con, sctx := syncStatusConn(ctx)
+	var timeoutsStr *string
+	if len(timeouts) > 0 {
+		str := TruncToBytes(strings.Join(timeouts, ", "), 0x1000)
+		timeoutsStr = &str
+	}
+	_, err := ExecSQL(
+		con,
+		sctx,
+		"update gha_sync_status set last_success = "+NValue(1)+", took_ms = "+NValue(2)+", events = "+NValue(3)+
+			", timeouts = "+NValue(4)+" where project = "+NValue(5),
+		time.Now(),
+		int64(time.Now().Sub(dtStart)/time.Millisecond),
+		events,
+		timeoutsStr,
+		ctx.Project,
+	)
+	return err
+}
+
+// SyncFailed saves error of `ctx.Project` sync
+func SyncFailed(ctx *Ctx, syncErr string) error {
+	con, sctx := syncStatusConn(ctx)
+	_, err := ExecSQL(
+		con,
+		sctx,
+		"update gha_sync_status set last_error_dt = "+NValue(1)+", last_error = "+NValue(2)+" where project = "+NValue(3),
+		time.Now(),
+		TruncToBytes(syncErr, 0x1000),
+		ctx.Project,
+	)
+	return err
+}
+
+// SyncFailedOnPanic must be deferred by `gha2db_sync` main, it saves error of a failed sync (error passed to FatalOnError) and panics again
+func SyncFailedOnPanic(ctx *Ctx) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	err := SyncFailed(ctx, fatalErrorOr(r))
+	if err != nil {
+		Printf("Cannot save sync status: %v\n", err)
+	}
+	panic(r)
+}
+
+// SyncStatuses returns sync status of all projects, sync is running when it started after its last success and last error
+func SyncStatuses(ctx *Ctx) ([]SyncStatus, error) {
+	con, sctx := syncStatusConn(ctx)
+	rows, err := QuerySQL(
+		con,
+		sctx,
+		"select project, host, last_start, last_success, last_error_dt, last_error, took_ms, events, timeouts "+
+			"from gha_sync_status order by project",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	statuses := []SyncStatus{}
+	for rows.Next() {
+		var s SyncStatus
+		err = rows.Scan(&s.Project, &s.Host, &s.LastStart, &s.LastSuccess, &s.LastErrorDt, &s.LastError, &s.TookMs, &s.Events, &s.Timeouts)
+		if err != nil {
+			return nil, err
+		}
+		s.Running = s.LastStart != nil &&
+			(s.LastSuccess == nil || s.LastStart.After(*s.LastSuccess)) &&
+			(s.LastErrorDt == nil || s.LastStart.After(*s.LastErrorDt))
+		statuses = append(statuses, s)
+	}
+	return statuses, rows.Err()
+}
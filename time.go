@@ -39,6 +39,7 @@ func ProgressInfo(i, n int, start time.Time, last *time.Time, period time.Durati
 // annotation ranges are calculated:
 // from last release to now - every 2 hours
 // for past ranges only once (calculation is marked as computed) at 2 AM
+// ranges between releases (period "r") - every 2 hours
 // weekly ranges are calculated at hours: 0, 4, 8, 12, 16, 20
 // monthly, quarterly, yearly ranges are calculated at midnight
 func ComputePeriodAtThisDate(period string, to time.Time) bool {
@@ -58,6 +59,8 @@ func ComputePeriodAtThisDate(period string, to time.Time) bool {
 			return to.Hour()%2 == 0
 		}
 		return to.Hour() == 2
+	} else if period == ReleasePeriod {
+		return to.Hour()%2 == 0
 	} else if periodStart == "w" {
 		return to.Hour()%4 == 0
 	} else if periodStart == "m" || periodStart == "q" || periodStart == "y" {
@@ -208,9 +211,9 @@ func PrevYearStart(dt time.Time) time.Time {
 	return YearStart(dt).AddDate(-1, 0, 0)
 }
 
-// TimeParseAny - attempts to parse time from string YYYY-MM-DD HH:MI:SS
-// Skipping parts from right until only YYYY id left
-func TimeParseAny(dtStr string) time.Time {
+// TimeParseAnyWithErr - attempts to parse time from string YYYY-MM-DD HH:MI:SS
+// Skipping parts from right until only YYYY id left, returns error when no format matches
+func TimeParseAnyWithErr(dtStr string) (time.Time, error) {
 	formats := []string{
 		"2006-01-02 15:04:05",
 		"2006-01-02 15:04",
@@ -222,13 +225,22 @@ func TimeParseAny(dtStr string) time.Time {
 	for _, format := range formats {
 		t, e := time.Parse(format, dtStr)
 		if e == nil {
-			return t
+			return t, nil
 		}
 	}
-	Printf("Error:\nCannot parse date: '%v'\n", dtStr)
-	fmt.Fprintf(os.Stdout, "Error:\nCannot parse date: '%v'\n", dtStr)
-	os.Exit(1)
-	return time.Now()
+	return time.Now(), fmt.Errorf("cannot parse date: '%v'", dtStr)
+}
+
+// TimeParseAny - attempts to parse time from string YYYY-MM-DD HH:MI:SS
+// Skipping parts from right until only YYYY id left
+func TimeParseAny(dtStr string) time.Time {
+	t, err := TimeParseAnyWithErr(dtStr)
+	if err != nil {
+		Printf("Error:\nCannot parse date: '%v'\n", dtStr)
+		fmt.Fprintf(os.Stdout, "Error:\nCannot parse date: '%v'\n", dtStr)
+		os.Exit(1)
+	}
+	return t
 }
 
 // TimeParseIDB - parse InfluxDB time output string into time.Time
@@ -388,3 +400,29 @@ func GetIntervalFunctions(intervalAbbr string, allowUnknown bool) (interval stri
 	}
 	return
 }
+
+// InTimeZone returns interval function `f` (like DayStart or NextWeekStart) working on calendar of time zone `loc`
+// So days start at midnight of `loc` instead of UTC midnight, returned times are in UTC
+func InTimeZone(f func(time.Time) time.Time, loc *time.Location) func(time.Time) time.Time {
+	if loc == nil || loc == time.UTC {
+		return f
+	}
+	return func(dt time.Time) time.Time {
+		// Interval functions round UTC wall clock, so they get `loc`'s wall clock and their result is read as `loc`'s
+		local := dt.In(loc)
+		dt = f(time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), time.UTC))
+		return time.Date(dt.Year(), dt.Month(), dt.Day(), dt.Hour(), dt.Minute(), dt.Second(), dt.Nanosecond(), loc).UTC()
+	}
+}
+
+// GetIntervalFunctionsIn - GetIntervalFunctions with intervals starting in time zone `loc` (see InTimeZone)
+func GetIntervalFunctionsIn(intervalAbbr string, allowUnknown bool, loc *time.Location) (interval string, n int, intervalStart, nextIntervalStart, prevIntervalStart func(time.Time) time.Time) {
+	interval, n, intervalStart, nextIntervalStart, prevIntervalStart = GetIntervalFunctions(intervalAbbr, allowUnknown)
+	if intervalStart == nil {
+		return
+	}
+	intervalStart = InTimeZone(intervalStart, loc)
+	nextIntervalStart = InTimeZone(nextIntervalStart, loc)
+	prevIntervalStart = InTimeZone(prevIntervalStart, loc)
+	return
+}
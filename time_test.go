@@ -17,6 +17,7 @@ func TestComputePeriodAtThisDate(t *testing.T) {
 	// annotation ranges are calculated:
 	// from last release to now - every 2 hours
 	// for past ranges only once (calculation is marked as computed) at 2 AM
+	// ranges between releases - every 2 hours
 	// weekly ranges are calculated at hours: 0, 4, 8, 12, 16, 20
 	// monthly, quarterly, yearly ranges are calculated at midnight
 	ft := testlib.YMDHMS
@@ -47,6 +48,9 @@ func TestComputePeriodAtThisDate(t *testing.T) {
 		{period: "anno_0_1", dt: ft(2017, 12, 19, 1), expected: false},
 		{period: "anno_10_11", dt: ft(2017, 12, 19, 2, 11), expected: true},
 		{period: "anno_10_11", dt: ft(2017, 12, 19, 4, 11), expected: false},
+		{period: "r", dt: ft(2017, 12, 19), expected: true},
+		{period: "r", dt: ft(2017, 12, 19, 1), expected: false},
+		{period: "r", dt: ft(2017, 12, 19, 14, 11), expected: true},
 		{period: "w", dt: ft(2017, 12, 19), expected: true},
 		{period: "w", dt: ft(2017, 12, 19, 1), expected: false},
 		{period: "w", dt: ft(2017, 12, 19, 20, 13), expected: true},
@@ -766,3 +770,51 @@ func TestGetIntervalFunctions(t *testing.T) {
 		}
 	}
 }
+
+func TestGetIntervalFunctionsIn(t *testing.T) {
+	ft := testlib.YMDHMS
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Test cases
+	var testCases = []struct {
+		periodAbbr    string
+		loc           *time.Location
+		time          time.Time
+		expectedN     int
+		expectedStart time.Time
+		expectedNext  time.Time
+		expectedPrev  time.Time
+	}{
+		// UTC (default) intervals are not changed
+		{periodAbbr: "d", loc: time.UTC, time: ft(2018, 3, 14, 23, 30), expectedN: 1, expectedStart: ft(2018, 3, 14), expectedNext: ft(2018, 3, 15), expectedPrev: ft(2018, 3, 13)},
+		// 23:30 UTC is already the next day in Warsaw (UTC+1)
+		{periodAbbr: "d", loc: warsaw, time: ft(2018, 3, 14, 23, 30), expectedN: 1, expectedStart: ft(2018, 3, 14, 23), expectedNext: ft(2018, 3, 15, 23), expectedPrev: ft(2018, 3, 13, 23)},
+		// Daylight saving time starts on 2018-03-25 in Warsaw, that day has 23 hours
+		{periodAbbr: "d7", loc: warsaw, time: ft(2018, 3, 25, 12), expectedN: 7, expectedStart: ft(2018, 3, 24, 23), expectedNext: ft(2018, 3, 25, 22), expectedPrev: ft(2018, 3, 23, 23)},
+		// Monday 2018-03-12 03:00 UTC is still Sunday in New York (UTC-4)
+		{periodAbbr: "w", loc: newYork, time: ft(2018, 3, 12, 3), expectedN: 1, expectedStart: ft(2018, 3, 5, 5), expectedNext: ft(2018, 3, 12, 4), expectedPrev: ft(2018, 2, 26, 5)},
+		{periodAbbr: "m", loc: newYork, time: ft(2018, 4, 1, 2), expectedN: 1, expectedStart: ft(2018, 3, 1, 5), expectedNext: ft(2018, 4, 1, 4), expectedPrev: ft(2018, 2, 1, 5)},
+		{periodAbbr: "y", loc: warsaw, time: ft(2017, 12, 31, 23, 30), expectedN: 1, expectedStart: ft(2017, 12, 31, 23), expectedNext: ft(2018, 12, 31, 23), expectedPrev: ft(2016, 12, 31, 23)},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		_, n, start, next, prev := lib.GetIntervalFunctionsIn(test.periodAbbr, false, test.loc)
+		if n != test.expectedN {
+			t.Errorf("test number %d, expected n %d, got %d", index+1, test.expectedN, n)
+		}
+		got := []time.Time{start(test.time), next(test.time), prev(test.time)}
+		expected := []time.Time{test.expectedStart, test.expectedNext, test.expectedPrev}
+		for i := range got {
+			if got[i] != expected[i] {
+				t.Errorf("test number %d, function %d, expected %v, got %v", index+1, i+1, expected[i], got[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package devstats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	client "github.com/influxdata/influxdb/client/v2"
+)
+
+// SeriesUnitsSeries - InfluxDB series with unit and value type of every series written by metrics that define them,
+// `series` tag is series name, fields are `unit`, `grafana_unit` and `value_type`
+const SeriesUnitsSeries = "series_units"
+
+// Value types of metric's values, all values are saved as floats, integers are displayed without decimals
+const (
+	ValueTypeFloat   = "float"
+	ValueTypeInteger = "integer"
+)
+
+// grafanaUnits - metric units and their Grafana unit (panel's format) ids
+var grafanaUnits = map[string]string{
+	"count":   "short",
+	"seconds": "s",
+	"hours":   "h",
+	"days":    "d",
+	"percent": "percent",
+	"ratio":   "percentunit",
+	"bytes":   "bytes",
+	"none":    "none",
+}
+
+// seriesUnitsTime - all units points have the same time, so every write replaces series' unit
+var seriesUnitsTime = time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SeriesUnit - unit (like "count", "seconds" or "percent") and value type ("float" or "integer") of metric's values
+type SeriesUnit struct {
+	Unit      string
+	ValueType string
+}
+
+// ParseSeriesUnit returns unit from metrics.yaml `unit` and `value_type`, value type defaults to "float"
+func ParseSeriesUnit(unit, valueType string) (SeriesUnit, error) {
+	unit = strings.ToLower(strings.TrimSpace(unit))
+	valueType = strings.ToLower(strings.TrimSpace(valueType))
+	if unit == "" {
+		return SeriesUnit{}, fmt.Errorf("unit is required when value type is set")
+	}
+	if _, ok := grafanaUnits[unit]; !ok {
+		units := []string{}
+		for u := range grafanaUnits {
+			units = append(units, u)
+		}
+		sort.Strings(units)
+		return SeriesUnit{}, fmt.Errorf("unknown unit '%s', use one of: %s", unit, strings.Join(units, ", "))
+	}
+	if valueType == "" {
+		valueType = ValueTypeFloat
+	}
+	if valueType != ValueTypeFloat && valueType != ValueTypeInteger {
+		return SeriesUnit{}, fmt.Errorf("unknown value type '%s', use %s or %s", valueType, ValueTypeFloat, ValueTypeInteger)
+	}
+	return SeriesUnit{Unit: unit, ValueType: valueType}, nil
+}
+
+// GrafanaUnit returns Grafana unit id (panel's y axis format) of the unit
+func (u SeriesUnit) GrafanaUnit() string {
+	return grafanaUnits[u.Unit]
+}
+
+// Decimals returns number of decimals Grafana should display, -1 - automatic
+func (u SeriesUnit) Decimals() int {
+	if u.ValueType == ValueTypeInteger {
+		return 0
+	}
+	return -1
+}
+
+// WriteSeriesUnits saves `unit` of all series `names` in "series_units" InfluxDB series
+func WriteSeriesUnits(ic client.Client, ctx *Ctx, names []string, unit SeriesUnit) error {
+	var pts IDBBatchPointsN
+	bp := IDBBatchPoints(ctx, &ic)
+	pts.NPoints = 0
+	pts.Points = &bp
+	fields := map[string]interface{}{"unit": unit.Unit, "grafana_unit": unit.GrafanaUnit(), "value_type": unit.ValueType}
+	for _, name := range names {
+		pt := IDBNewPointWithErr(SeriesUnitsSeries, map[string]string{"series": name}, fields, seriesUnitsTime)
+		IDBAddPointN(ctx, &ic, &pts, pt)
+	}
+	return IDBWritePointsN(ctx, &ic, &pts)
+}
+
+// GetSeriesUnits returns units of series from "series_units" InfluxDB series, by series name
+// Dashboards generation and API use them to label axes and format values
+func GetSeriesUnits(ic client.Client, ctx *Ctx) map[string]SeriesUnit {
+	units := make(map[string]SeriesUnit)
+	res := QueryIDB(ic, ctx, "select series, unit, value_type from "+SeriesUnitsSeries)
+	if len(res) < 1 || len(res[0].Series) < 1 {
+		return units
+	}
+	for _, row := range res[0].Series[0].Values {
+		name, _ := row[1].(string)
+		unit, _ := row[2].(string)
+		valueType, _ := row[3].(string)
+		units[name] = SeriesUnit{Unit: unit, ValueType: valueType}
+	}
+	return units
+}
@@ -0,0 +1,48 @@
+package devstats
+
+import (
+	"testing"
+
+	lib "devstats"
+)
+
+func TestParseSeriesUnit(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		unit      string
+		valueType string
+		expected  lib.SeriesUnit
+		grafana   string
+		decimals  int
+		err       bool
+	}{
+		{unit: "count", valueType: "integer", expected: lib.SeriesUnit{Unit: "count", ValueType: "integer"}, grafana: "short", decimals: 0},
+		{unit: " Seconds ", expected: lib.SeriesUnit{Unit: "seconds", ValueType: "float"}, grafana: "s", decimals: -1},
+		{unit: "hours", valueType: "Float", expected: lib.SeriesUnit{Unit: "hours", ValueType: "float"}, grafana: "h", decimals: -1},
+		{unit: "percent", expected: lib.SeriesUnit{Unit: "percent", ValueType: "float"}, grafana: "percent", decimals: -1},
+		{unit: "ratio", expected: lib.SeriesUnit{Unit: "ratio", ValueType: "float"}, grafana: "percentunit", decimals: -1},
+		{unit: "", valueType: "integer", err: true},
+		{unit: "parsecs", err: true},
+		{unit: "count", valueType: "string", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.ParseSeriesUnit(test.unit, test.valueType)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got %+v", index+1, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if got != test.expected || got.GrafanaUnit() != test.grafana || got.Decimals() != test.decimals {
+			t.Errorf(
+				"test number %d, expected %+v (%s, %d), got %+v (%s, %d)",
+				index+1, test.expected, test.grafana, test.decimals, got, got.GrafanaUnit(), got.Decimals(),
+			)
+		}
+	}
+}
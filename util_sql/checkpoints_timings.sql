@@ -0,0 +1,11 @@
+/*
+alter table gha_checkpoints drop column if exists rows;
+alter table gha_checkpoints drop column if exists download_ms;
+alter table gha_checkpoints drop column if exists parse_ms;
+alter table gha_checkpoints drop column if exists save_ms;
+*/
+
+ALTER TABLE gha_checkpoints ADD COLUMN rows integer;
+ALTER TABLE gha_checkpoints ADD COLUMN download_ms integer;
+ALTER TABLE gha_checkpoints ADD COLUMN parse_ms integer;
+ALTER TABLE gha_checkpoints ADD COLUMN save_ms integer;
@@ -0,0 +1,8 @@
+/*
+alter table gha_events drop column if exists is_bot;
+*/
+
+ALTER TABLE gha_events ADD COLUMN is_bot boolean DEFAULT false NOT NULL;
+CREATE INDEX events_is_bot_idx ON gha_events USING btree (is_bot);
+-- Flag already ingested events using default bots.yaml list, gha2db flags new events itself
+UPDATE gha_events SET is_bot = true WHERE lower(dup_actor_login) IN ('googlebot', 'coveralls', 'rktbot') OR dup_actor_login ~* '(^k8s-|-bot$|-robot$|^bot-|^robot-|\[bot\]|-jenkins$|-ci.*bot|-testing$|^codecov-)';
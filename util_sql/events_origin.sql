@@ -0,0 +1,9 @@
+/*
+alter table gha_events drop column if exists origin;
+alter table gha_pull_requests drop column if exists origin;
+*/
+
+ALTER TABLE gha_events ADD COLUMN origin character varying(16) DEFAULT 'github'::character varying NOT NULL;
+ALTER TABLE gha_pull_requests ADD COLUMN origin character varying(16) DEFAULT 'github'::character varying NOT NULL;
+CREATE INDEX events_origin_idx ON gha_events USING btree (origin);
+CREATE INDEX pull_requests_origin_idx ON gha_pull_requests USING btree (origin);
@@ -0,0 +1,5 @@
+/*
+alter table gha_payloads drop column if exists overflow;
+*/
+
+ALTER TABLE gha_payloads ADD COLUMN overflow jsonb;
@@ -0,0 +1,5 @@
+/*
+alter table gha_sync_status drop column if exists timeouts;
+*/
+
+ALTER TABLE gha_sync_status ADD COLUMN timeouts text;
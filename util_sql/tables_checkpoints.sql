@@ -0,0 +1,19 @@
+/*
+drop table if exists gha_checkpoints;
+*/
+
+CREATE TABLE gha_checkpoints (
+    dt timestamp without time zone NOT NULL,
+    orgs_repos text NOT NULL,
+    jsons integer NOT NULL,
+    found integer NOT NULL,
+    events integer NOT NULL,
+    started timestamp without time zone NOT NULL,
+    finished timestamp without time zone,
+    rows integer,
+    download_ms integer,
+    parse_ms integer,
+    save_ms integer
+);
+ALTER TABLE gha_checkpoints OWNER TO gha_admin;
+ALTER TABLE ONLY gha_checkpoints ADD CONSTRAINT gha_checkpoints_pkey PRIMARY KEY (dt, orgs_repos);
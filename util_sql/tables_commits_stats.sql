@@ -0,0 +1,14 @@
+/*
+drop table if exists gha_commits_stats;
+*/
+
+CREATE TABLE gha_commits_stats (
+    sha character varying(40) NOT NULL,
+    added bigint NOT NULL,
+    removed bigint NOT NULL,
+    files integer NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_commits_stats OWNER TO gha_admin;
+ALTER TABLE ONLY gha_commits_stats ADD CONSTRAINT gha_commits_stats_pkey PRIMARY KEY (sha);
+CREATE INDEX commits_stats_dt_idx ON gha_commits_stats USING btree (dt);
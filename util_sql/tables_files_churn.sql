@@ -0,0 +1,29 @@
+/*
+drop table if exists gha_files_churn;
+drop table if exists gha_files_churn_repos;
+*/
+
+CREATE TABLE gha_files_churn (
+    repo_name character varying(160) NOT NULL,
+    path text NOT NULL,
+    commits integer NOT NULL,
+    added bigint NOT NULL,
+    removed bigint NOT NULL,
+    last_author character varying(160) NOT NULL,
+    last_email character varying(160) NOT NULL,
+    last_dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_files_churn OWNER TO gha_admin;
+ALTER TABLE ONLY gha_files_churn ADD CONSTRAINT gha_files_churn_pkey PRIMARY KEY (repo_name, path);
+CREATE INDEX files_churn_path_idx ON gha_files_churn USING btree (path);
+CREATE INDEX files_churn_commits_idx ON gha_files_churn USING btree (commits);
+CREATE INDEX files_churn_last_author_idx ON gha_files_churn USING btree (last_author);
+CREATE INDEX files_churn_last_dt_idx ON gha_files_churn USING btree (last_dt);
+
+CREATE TABLE gha_files_churn_repos (
+    repo_name character varying(160) NOT NULL,
+    sha character varying(40) NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_files_churn_repos OWNER TO gha_admin;
+ALTER TABLE ONLY gha_files_churn_repos ADD CONSTRAINT gha_files_churn_repos_pkey PRIMARY KEY (repo_name);
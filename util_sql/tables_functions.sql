@@ -0,0 +1,12 @@
+/*
+drop table if exists gha_functions;
+*/
+
+CREATE TABLE gha_functions (
+    name character varying(200) NOT NULL,
+    version integer NOT NULL,
+    hash character varying(40) NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_functions OWNER TO gha_admin;
+ALTER TABLE ONLY gha_functions ADD CONSTRAINT gha_functions_pkey PRIMARY KEY (name);
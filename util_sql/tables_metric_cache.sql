@@ -0,0 +1,17 @@
+/*
+drop table if exists gha_metric_cache;
+*/
+
+CREATE TABLE gha_metric_cache (
+    hash character varying(40) NOT NULL,
+    period character varying(20) NOT NULL,
+    dt_from timestamp without time zone NOT NULL,
+    dt_to timestamp without time zone NOT NULL,
+    result text NOT NULL,
+    dt timestamp without time zone NOT NULL,
+    expires_at timestamp without time zone
+);
+ALTER TABLE gha_metric_cache OWNER TO gha_admin;
+ALTER TABLE ONLY gha_metric_cache ADD CONSTRAINT gha_metric_cache_pkey PRIMARY KEY (hash, period, dt_from, dt_to);
+CREATE INDEX metric_cache_dt_to_idx ON gha_metric_cache USING btree (dt_to);
+CREATE INDEX metric_cache_dt_idx ON gha_metric_cache USING btree (dt);
@@ -0,0 +1,16 @@
+/*
+drop table if exists gha_metric_runs;
+*/
+
+CREATE TABLE gha_metric_runs (
+    sql character varying(200) NOT NULL,
+    series character varying(200) NOT NULL,
+    period character varying(20) NOT NULL,
+    hist boolean NOT NULL,
+    rows bigint NOT NULL,
+    took_ms bigint NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_metric_runs OWNER TO gha_admin;
+ALTER TABLE ONLY gha_metric_runs ADD CONSTRAINT gha_metric_runs_pkey PRIMARY KEY (sql, series, period, dt);
+CREATE INDEX metric_runs_dt_idx ON gha_metric_runs USING btree (dt);
@@ -0,0 +1,19 @@
+/*
+drop table if exists gha_metric_violations;
+*/
+
+CREATE TABLE gha_metric_violations (
+    sql character varying(200) NOT NULL,
+    series character varying(200) NOT NULL,
+    period character varying(20) NOT NULL,
+    point_dt timestamp without time zone NOT NULL,
+    field character varying(200) NOT NULL,
+    rule character varying(40) NOT NULL,
+    value double precision NOT NULL,
+    prev double precision,
+    blocked boolean NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_metric_violations OWNER TO gha_admin;
+ALTER TABLE ONLY gha_metric_violations ADD CONSTRAINT gha_metric_violations_pkey PRIMARY KEY (sql, series, period, point_dt, field, rule, dt);
+CREATE INDEX metric_violations_dt_idx ON gha_metric_violations USING btree (dt);
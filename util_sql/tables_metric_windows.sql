@@ -0,0 +1,15 @@
+/*
+drop table if exists gha_metric_windows;
+*/
+
+CREATE TABLE gha_metric_windows (
+    metric character varying(200) NOT NULL,
+    series character varying(200) NOT NULL,
+    period character varying(20) NOT NULL,
+    dt_from timestamp without time zone NOT NULL,
+    dt_to timestamp without time zone NOT NULL,
+    backfilled_at timestamp without time zone NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_metric_windows OWNER TO gha_admin;
+ALTER TABLE ONLY gha_metric_windows ADD CONSTRAINT gha_metric_windows_pkey PRIMARY KEY (metric, series, period);
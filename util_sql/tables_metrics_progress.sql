@@ -0,0 +1,13 @@
+/*
+drop table if exists gha_metrics_progress;
+*/
+
+CREATE TABLE gha_metrics_progress (
+    metric character varying(200) NOT NULL,
+    run_key character varying(40) NOT NULL,
+    window_from timestamp without time zone NOT NULL,
+    window_to timestamp without time zone NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_metrics_progress OWNER TO gha_admin;
+ALTER TABLE ONLY gha_metrics_progress ADD CONSTRAINT gha_metrics_progress_pkey PRIMARY KEY (metric);
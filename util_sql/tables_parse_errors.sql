@@ -0,0 +1,13 @@
+/*
+drop table if exists gha_parse_errors;
+*/
+
+CREATE TABLE gha_parse_errors (
+    dt timestamp without time zone NOT NULL,
+    line integer NOT NULL,
+    json text NOT NULL,
+    error text NOT NULL,
+    created_at timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_parse_errors OWNER TO gha_admin;
+ALTER TABLE ONLY gha_parse_errors ADD CONSTRAINT gha_parse_errors_pkey PRIMARY KEY (dt, line);
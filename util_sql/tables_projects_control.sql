@@ -0,0 +1,12 @@
+/*
+drop table if exists gha_projects_control;
+*/
+
+CREATE TABLE gha_projects_control (
+    project character varying(32) NOT NULL,
+    state character varying(10) NOT NULL,
+    reason text NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_projects_control OWNER TO gha_admin;
+ALTER TABLE ONLY gha_projects_control ADD CONSTRAINT gha_projects_control_pkey PRIMARY KEY (project);
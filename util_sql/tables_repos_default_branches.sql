@@ -0,0 +1,12 @@
+/*
+drop table if exists gha_repos_default_branches;
+*/
+
+CREATE TABLE gha_repos_default_branches (
+    repo_name character varying(160) NOT NULL,
+    branch character varying(200) NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_repos_default_branches OWNER TO gha_admin;
+ALTER TABLE ONLY gha_repos_default_branches ADD CONSTRAINT gha_repos_default_branches_pkey PRIMARY KEY (repo_name);
+CREATE INDEX repos_default_branches_branch_idx ON gha_repos_default_branches USING btree (branch);
@@ -0,0 +1,15 @@
+/*
+drop table if exists gha_repos_languages;
+*/
+
+CREATE TABLE gha_repos_languages (
+    repo_name character varying(160) NOT NULL,
+    language character varying(80) NOT NULL,
+    bytes bigint NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_repos_languages OWNER TO gha_admin;
+ALTER TABLE ONLY gha_repos_languages ADD CONSTRAINT gha_repos_languages_pkey PRIMARY KEY (repo_name, language, dt);
+CREATE INDEX repos_languages_repo_name_idx ON gha_repos_languages USING btree (repo_name);
+CREATE INDEX repos_languages_language_idx ON gha_repos_languages USING btree (language);
+CREATE INDEX repos_languages_dt_idx ON gha_repos_languages USING btree (dt);
@@ -0,0 +1,14 @@
+/*
+drop table if exists gha_repos_licenses;
+*/
+
+CREATE TABLE gha_repos_licenses (
+    repo_name character varying(160) NOT NULL,
+    path text NOT NULL,
+    license character varying(40) NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_repos_licenses OWNER TO gha_admin;
+ALTER TABLE ONLY gha_repos_licenses ADD CONSTRAINT gha_repos_licenses_pkey PRIMARY KEY (repo_name, path);
+CREATE INDEX repos_licenses_license_idx ON gha_repos_licenses USING btree (license);
+CREATE INDEX repos_licenses_dt_idx ON gha_repos_licenses USING btree (dt);
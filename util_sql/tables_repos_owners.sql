@@ -0,0 +1,17 @@
+/*
+drop table if exists gha_repos_owners;
+*/
+
+CREATE TABLE gha_repos_owners (
+    repo_name character varying(160) NOT NULL,
+    path text NOT NULL,
+    pattern text NOT NULL,
+    owner character varying(160) NOT NULL,
+    role character varying(20) NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_repos_owners OWNER TO gha_admin;
+ALTER TABLE ONLY gha_repos_owners ADD CONSTRAINT gha_repos_owners_pkey PRIMARY KEY (repo_name, path, pattern, owner, role);
+CREATE INDEX repos_owners_owner_idx ON gha_repos_owners USING btree (owner);
+CREATE INDEX repos_owners_role_idx ON gha_repos_owners USING btree (role);
+CREATE INDEX repos_owners_dt_idx ON gha_repos_owners USING btree (dt);
@@ -0,0 +1,13 @@
+/*
+drop table if exists gha_repos_renames;
+*/
+
+CREATE TABLE gha_repos_renames (
+    name character varying(160) NOT NULL,
+    new_name character varying(160) NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_repos_renames OWNER TO gha_admin;
+ALTER TABLE ONLY gha_repos_renames ADD CONSTRAINT gha_repos_renames_pkey PRIMARY KEY (name);
+CREATE INDEX repos_renames_new_name_idx ON gha_repos_renames USING btree (new_name);
+CREATE INDEX repos_renames_dt_idx ON gha_repos_renames USING btree (dt);
@@ -0,0 +1,16 @@
+/*
+drop table if exists gha_slow_queries;
+*/
+
+CREATE TABLE gha_slow_queries (
+    tool character varying(40) NOT NULL,
+    name character varying(200) NOT NULL,
+    hash character varying(40) NOT NULL,
+    query text NOT NULL,
+    took_ms bigint NOT NULL,
+    explain text NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_slow_queries OWNER TO gha_admin;
+ALTER TABLE ONLY gha_slow_queries ADD CONSTRAINT gha_slow_queries_pkey PRIMARY KEY (tool, name, dt);
+CREATE INDEX slow_queries_dt_idx ON gha_slow_queries USING btree (dt);
@@ -0,0 +1,17 @@
+/*
+drop table if exists gha_sync_status;
+*/
+
+CREATE TABLE gha_sync_status (
+    project character varying(32) NOT NULL,
+    host character varying(255) NOT NULL,
+    last_start timestamp without time zone NOT NULL,
+    last_success timestamp without time zone,
+    last_error_dt timestamp without time zone,
+    last_error text,
+    took_ms bigint,
+    events bigint,
+    timeouts text
+);
+ALTER TABLE gha_sync_status OWNER TO gha_admin;
+ALTER TABLE ONLY gha_sync_status ADD CONSTRAINT gha_sync_status_pkey PRIMARY KEY (project);
@@ -0,0 +1,11 @@
+/*
+drop table if exists gha_watermarks;
+*/
+
+CREATE TABLE gha_watermarks (
+    tool character varying(40) NOT NULL,
+    event_id bigint NOT NULL,
+    dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_watermarks OWNER TO gha_admin;
+ALTER TABLE ONLY gha_watermarks ADD CONSTRAINT gha_watermarks_pkey PRIMARY KEY (tool);
@@ -0,0 +1,96 @@
+package devstats
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Metric values validation rules (metrics.yaml `validate`), values are checked before they are written
+const (
+	ValidateNonNegative = "non_negative" // value is never negative
+	ValidateMonotonic   = "monotonic"    // value never decreases, for cumulative series
+	ValidateMaxDelta    = "max_delta"    // value differs from the previous one by at most N, or N% of it: "max_delta:50%"
+)
+
+// ValidationRule - sanity constraint of metric's values, `MaxDelta` (and `Relative`) are only used by ValidateMaxDelta
+type ValidationRule struct {
+	Name     string
+	MaxDelta float64
+	Relative bool
+}
+
+// ParseValidationRules parses comma separated rules, like "non_negative,max_delta:50%"
+func ParseValidationRules(s string) ([]ValidationRule, error) {
+	rules := []ValidationRule{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		switch {
+		case item == "":
+			continue
+		case item == ValidateNonNegative, item == ValidateMonotonic:
+			rules = append(rules, ValidationRule{Name: item})
+		case strings.HasPrefix(item, ValidateMaxDelta+":"):
+			arg := strings.TrimSpace(item[len(ValidateMaxDelta)+1:])
+			rule := ValidationRule{Name: ValidateMaxDelta, Relative: strings.HasSuffix(arg, "%")}
+			delta, err := strconv.ParseFloat(strings.TrimSuffix(arg, "%"), 64)
+			if err != nil || delta <= 0 || math.IsInf(delta, 0) {
+				return nil, fmt.Errorf("invalid rule '%s', maximum delta must be a positive number, like max_delta:100 or max_delta:50%%", item)
+			}
+			rule.MaxDelta = delta
+			rules = append(rules, rule)
+		default:
+			return nil, fmt.Errorf("invalid rule '%s', use: non_negative, monotonic or max_delta:N[%%]", item)
+		}
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no validation rules in '%s'", s)
+	}
+	return rules, nil
+}
+
+// String returns rule as it is parsed, like "max_delta:50%"
+func (r ValidationRule) String() string {
+	if r.Name != ValidateMaxDelta {
+		return r.Name
+	}
+	s := r.Name + ":" + strconv.FormatFloat(r.MaxDelta, 'f', -1, 64)
+	if r.Relative {
+		s += "%"
+	}
+	return s
+}
+
+// NeedsPrevious - does rule compare value with the previous one?
+func (r ValidationRule) NeedsPrevious() bool {
+	return r.Name != ValidateNonNegative
+}
+
+// Check returns description of rule's violation by `value`, "" when it is valid
+// `prev` is the previous value of the same series (nil - none), rules comparing with it pass without it
+// Relative maximum delta also passes when the previous value is 0
+func (r ValidationRule) Check(value float64, prev *float64) string {
+	switch r.Name {
+	case ValidateNonNegative:
+		if value < 0 {
+			return fmt.Sprintf("%v is negative", value)
+		}
+	case ValidateMonotonic:
+		if prev != nil && value < *prev {
+			return fmt.Sprintf("%v is less than previous %v", value, *prev)
+		}
+	case ValidateMaxDelta:
+		if prev == nil {
+			return ""
+		}
+		delta := math.Abs(value - *prev)
+		if !r.Relative && delta > r.MaxDelta {
+			return fmt.Sprintf("%v differs from previous %v by %v, more than %v", value, *prev, delta, r.MaxDelta)
+		}
+		if r.Relative && *prev != 0 && delta/math.Abs(*prev)*100 > r.MaxDelta {
+			return fmt.Sprintf("%v differs from previous %v by %.2f%%, more than %v%%", value, *prev, delta/math.Abs(*prev)*100, r.MaxDelta)
+		}
+	}
+	return ""
+}
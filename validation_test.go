@@ -0,0 +1,89 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestParseValidationRules(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		s        string
+		expected []lib.ValidationRule
+		strs     []string
+		err      bool
+	}{
+		{
+			s:        "non_negative",
+			expected: []lib.ValidationRule{{Name: lib.ValidateNonNegative}},
+			strs:     []string{"non_negative"},
+		},
+		{
+			s:        " Monotonic, max_delta:50% ,,max_delta:1000",
+			expected: []lib.ValidationRule{{Name: lib.ValidateMonotonic}, {Name: lib.ValidateMaxDelta, MaxDelta: 50, Relative: true}, {Name: lib.ValidateMaxDelta, MaxDelta: 1000}},
+			strs:     []string{"monotonic", "max_delta:50%", "max_delta:1000"},
+		},
+		{s: "", err: true},
+		{s: "max_delta", err: true},
+		{s: "max_delta:0", err: true},
+		{s: "max_delta:-5%", err: true},
+		{s: "positive", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.ParseValidationRules(test.s)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error for '%s', got %+v", index+1, test.s, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %+v, got %+v", index+1, test.expected, got)
+			continue
+		}
+		for i, rule := range got {
+			if rule.String() != test.strs[i] {
+				t.Errorf("test number %d, expected '%s', got '%s'", index+1, test.strs[i], rule.String())
+			}
+		}
+	}
+}
+
+func TestValidationRuleCheck(t *testing.T) {
+	prev := func(value float64) *float64 { return &value }
+
+	// Test cases
+	var testCases = []struct {
+		rule     lib.ValidationRule
+		value    float64
+		prev     *float64
+		violated bool
+	}{
+		{rule: lib.ValidationRule{Name: lib.ValidateNonNegative}, value: 0},
+		{rule: lib.ValidationRule{Name: lib.ValidateNonNegative}, value: -1, violated: true},
+		{rule: lib.ValidationRule{Name: lib.ValidateMonotonic}, value: 5},
+		{rule: lib.ValidationRule{Name: lib.ValidateMonotonic}, value: 5, prev: prev(5)},
+		{rule: lib.ValidationRule{Name: lib.ValidateMonotonic}, value: 4, prev: prev(5), violated: true},
+		{rule: lib.ValidationRule{Name: lib.ValidateMaxDelta, MaxDelta: 10}, value: 100},
+		{rule: lib.ValidationRule{Name: lib.ValidateMaxDelta, MaxDelta: 10}, value: 110, prev: prev(100)},
+		{rule: lib.ValidationRule{Name: lib.ValidateMaxDelta, MaxDelta: 10}, value: 89, prev: prev(100), violated: true},
+		{rule: lib.ValidationRule{Name: lib.ValidateMaxDelta, MaxDelta: 50, Relative: true}, value: 150, prev: prev(100)},
+		{rule: lib.ValidationRule{Name: lib.ValidateMaxDelta, MaxDelta: 50, Relative: true}, value: 0, prev: prev(100), violated: true},
+		{rule: lib.ValidationRule{Name: lib.ValidateMaxDelta, MaxDelta: 50, Relative: true}, value: -10, prev: prev(-100), violated: true},
+		{rule: lib.ValidationRule{Name: lib.ValidateMaxDelta, MaxDelta: 50, Relative: true}, value: 1000, prev: prev(0)},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := test.rule.Check(test.value, test.prev)
+		if (got != "") != test.violated {
+			t.Errorf("test number %d, expected violation: %v, got '%s'", index+1, test.violated, got)
+		}
+	}
+}
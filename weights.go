@@ -0,0 +1,35 @@
+package devstats
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Event weight modes (metrics.yaml `weight`), {{weight}} in metric's SQL is replaced with one of them
+// SQL passes it to devstats_event_weight (functions/weights.sql), usually via {{event_weight(ev.id, ev.type)}} macro
+const (
+	WeightCount    = "count"     // every event weighs 1, default
+	WeightLines    = "lines"     // lines added and removed
+	WeightLogLines = "log_lines" // 1 + ln(1 + lines), huge changes (like vendored code) don't dominate
+	WeightFiles    = "files"     // files changed
+)
+
+// CheckWeight returns error when `weight` is not a known weight mode
+func CheckWeight(weight string) error {
+	switch weight {
+	case WeightCount, WeightLines, WeightLogLines, WeightFiles:
+		return nil
+	}
+	return fmt.Errorf("unknown weight '%s', use: count, lines, log_lines or files", weight)
+}
+
+// ApplyWeight replaces {{weight}} in `sql` with `weight` mode, "" means WeightCount
+func ApplyWeight(sql, weight string) (string, error) {
+	if weight == "" {
+		weight = WeightCount
+	}
+	if err := CheckWeight(weight); err != nil {
+		return "", err
+	}
+	return strings.Replace(sql, "{{weight}}", weight, -1), nil
+}
@@ -0,0 +1,40 @@
+package devstats
+
+import (
+	"testing"
+
+	lib "devstats"
+)
+
+func TestApplyWeight(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		sql      string
+		weight   string
+		expected string
+		err      bool
+	}{
+		{sql: "select 1", weight: "", expected: "select 1"},
+		{sql: "sum(devstats_event_weight(e.id, e.type, '{{weight}}'))", weight: "", expected: "sum(devstats_event_weight(e.id, e.type, 'count'))"},
+		{sql: "'{{weight}}', '{{weight}}'", weight: lib.WeightLogLines, expected: "'log_lines', 'log_lines'"},
+		{sql: "'{{weight}}'", weight: lib.WeightFiles, expected: "'files'"},
+		{sql: "'{{weight}}'", weight: "bytes", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.ApplyWeight(test.sql, test.weight)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got '%s'", index+1, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("test number %d, expected '%s', got '%s'", index+1, test.expected, got)
+		}
+	}
+}